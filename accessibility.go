@@ -0,0 +1,229 @@
+package astisub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Vars
+var (
+	// Speaker labels in upper case starting a line, such as "JOHN:"
+	accessibilityRegexpSpeakerLabel = regexp.MustCompile(`^\s*-?\s*\p{Lu}[\p{Lu}\d .'-]*:`)
+	// Default upper bounds of reading speed buckets, in characters per second
+	defaultAccessibilityReadingSpeedBounds = []float64{12, 15, 17, 20}
+)
+
+// AccessibilityOptions represents accessibility options
+type AccessibilityOptions struct {
+	// Duration of the media the subtitles are delivered with. Defaults to the end of the last item, in which case the
+	// end of the media is considered as captioned.
+	MediaDuration time.Duration
+	// Upper bounds, in characters per second, of the buckets items are counted in depending on their reading speed.
+	// A last bucket gathers items read faster than the last bound. Defaults to 12, 15, 17 and 20.
+	ReadingSpeedBounds []float64
+	// Defaults to the tokenizer of each item's language
+	Tokenizer Tokenizer
+}
+
+// AccessibilityGap represents a time range of the media during which no caption is displayed
+type AccessibilityGap struct {
+	EndAt   time.Duration
+	StartAt time.Duration
+}
+
+// Duration returns the duration of the gap
+func (g AccessibilityGap) Duration() time.Duration {
+	return g.EndAt - g.StartAt
+}
+
+// ReadingSpeedBucket represents the number of items whose reading speed is within a range
+type ReadingSpeedBucket struct {
+	Count int
+	Max   float64 // Characters per second, inclusive. 0 for the last bucket, which has no upper bound.
+	Min   float64 // Characters per second, exclusive, unless 0
+}
+
+// AccessibilitySDH represents the SDH elements, i.e. the elements making subtitles accessible to the deaf and hard
+// of hearing, found in subtitles
+type AccessibilitySDH struct {
+	MusicItems   int // Items with music notes or describing music
+	SoundItems   int // Items with sound descriptions between brackets or parentheses, such as "[door slams]"
+	SpeakerItems int // Items identifying their speakers with a voice or a label, such as "JOHN:"
+}
+
+// Present checks whether subtitles contain SDH elements
+func (s AccessibilitySDH) Present() bool {
+	return s.MusicItems+s.SoundItems+s.SpeakerItems > 0
+}
+
+// AccessibilityReport represents accessibility-relevant metrics of subtitles
+type AccessibilityReport struct {
+	CaptionedDuration   time.Duration // Duration of the media during which at least one caption is displayed
+	Captions            int           // Number of items holding text, audio description cues excluded
+	Coverage            float64       // Percentage of the media duration that is captioned, between 0 and 100
+	LongestGap          AccessibilityGap
+	MaxReadingSpeed     float64 // Characters per second
+	MediaDuration       time.Duration
+	MedianReadingSpeed  float64 // Characters per second
+	ReadingSpeedBuckets []ReadingSpeedBucket
+	SDH                 AccessibilitySDH
+}
+
+// String implements the Stringer interface
+func (r AccessibilityReport) String() string {
+	var ss = []string{
+		fmt.Sprintf("coverage: %.1f%% (%s of %s)", r.Coverage, r.CaptionedDuration, r.MediaDuration),
+		fmt.Sprintf("longest gap: %s (from %s to %s)", r.LongestGap.Duration(), r.LongestGap.StartAt, r.LongestGap.EndAt),
+		fmt.Sprintf("sdh: %t (%d music items, %d sound items, %d speaker items)", r.SDH.Present(), r.SDH.MusicItems, r.SDH.SoundItems, r.SDH.SpeakerItems),
+		fmt.Sprintf("reading speed: %.1f cps median, %.1f cps max", r.MedianReadingSpeed, r.MaxReadingSpeed),
+	}
+	for _, b := range r.ReadingSpeedBuckets {
+		if b.Max > 0 {
+			ss = append(ss, fmt.Sprintf("  %.1f-%.1f cps: %d", b.Min, b.Max, b.Count))
+		} else {
+			ss = append(ss, fmt.Sprintf("  >%.1f cps: %d", b.Min, b.Count))
+		}
+	}
+	return strings.Join(ss, "\n")
+}
+
+// Accessibility builds an accessibility report with default options
+func (s Subtitles) Accessibility() AccessibilityReport {
+	return s.AccessibilityWithOptions(AccessibilityOptions{})
+}
+
+// AccessibilityWithOptions builds a report summarizing the metrics compliance teams check when verifying
+// deliverables: how much of the media is captioned, the longest time range without caption, whether SDH elements
+// are present and how fast captions are read. Audio description cues and items without text are not considered as
+// captions.
+func (s Subtitles) AccessibilityWithOptions(o AccessibilityOptions) (r AccessibilityReport) {
+	// Default options
+	if len(o.ReadingSpeedBounds) == 0 {
+		o.ReadingSpeedBounds = defaultAccessibilityReadingSpeedBounds
+	}
+
+	// Init buckets
+	var min float64
+	for _, b := range o.ReadingSpeedBounds {
+		r.ReadingSpeedBuckets = append(r.ReadingSpeedBuckets, ReadingSpeedBucket{Max: b, Min: min})
+		min = b
+	}
+	r.ReadingSpeedBuckets = append(r.ReadingSpeedBuckets, ReadingSpeedBucket{Min: min})
+
+	// Loop through items
+	var captions []*Item
+	var speeds []float64
+	for _, i := range s.Items {
+		// Item is not a caption
+		if s.isAudioDescription(i) || len(strings.TrimSpace(i.String())) == 0 {
+			continue
+		}
+		captions = append(captions, i)
+
+		// Reading speed
+		if d := i.EndAt - i.StartAt; d > 0 {
+			var cps = float64(qualityCharacterCount(i, itemTokenizer(o.Tokenizer, i))) / d.Seconds()
+			speeds = append(speeds, cps)
+			for idx := range r.ReadingSpeedBuckets {
+				if b := &r.ReadingSpeedBuckets[idx]; b.Max == 0 || cps <= b.Max {
+					b.Count++
+					break
+				}
+			}
+		}
+
+		// SDH
+		if accessibilityHasMusic(i) {
+			r.SDH.MusicItems++
+		}
+		if itemKindRegexpDescriptions.MatchString(i.String()) {
+			r.SDH.SoundItems++
+		}
+		for _, l := range i.Lines {
+			if len(l.VoiceName) > 0 || accessibilityRegexpSpeakerLabel.MatchString(l.String()) {
+				r.SDH.SpeakerItems++
+				break
+			}
+		}
+	}
+	r.Captions = len(captions)
+
+	// Reading speed statistics
+	if len(speeds) > 0 {
+		sort.Float64s(speeds)
+		r.MaxReadingSpeed = speeds[len(speeds)-1]
+		r.MedianReadingSpeed = speeds[len(speeds)/2]
+		if len(speeds)%2 == 0 {
+			r.MedianReadingSpeed = (speeds[len(speeds)/2-1] + speeds[len(speeds)/2]) / 2
+		}
+	}
+
+	// Get media duration
+	r.MediaDuration = o.MediaDuration
+	if r.MediaDuration <= 0 {
+		for _, i := range captions {
+			if i.EndAt > r.MediaDuration {
+				r.MediaDuration = i.EndAt
+			}
+		}
+	}
+
+	// Sort captions by start time so that overlapping captions are only counted once
+	sort.SliceStable(captions, func(a, b int) bool { return captions[a].StartAt < captions[b].StartAt })
+
+	// Coverage and gaps
+	var cursor time.Duration
+	var gap = func(endAt time.Duration) {
+		if endAt > r.MediaDuration {
+			endAt = r.MediaDuration
+		}
+		if endAt-cursor > r.LongestGap.Duration() {
+			r.LongestGap = AccessibilityGap{EndAt: endAt, StartAt: cursor}
+		}
+	}
+	for _, i := range captions {
+		// Caption is outside the media or already covered
+		var startAt, endAt = i.StartAt, i.EndAt
+		if startAt < cursor {
+			startAt = cursor
+		}
+		if endAt > r.MediaDuration {
+			endAt = r.MediaDuration
+		}
+		if endAt <= startAt {
+			continue
+		}
+
+		// Add gap and captioned duration
+		gap(startAt)
+		r.CaptionedDuration += endAt - startAt
+		cursor = endAt
+	}
+	gap(r.MediaDuration)
+	if r.MediaDuration > 0 {
+		r.Coverage = 100 * float64(r.CaptionedDuration) / float64(r.MediaDuration)
+	}
+	return
+}
+
+// accessibilityHasMusic checks whether an item has music notes or describes music
+func accessibilityHasMusic(i *Item) bool {
+	if i.Kind == ItemKindMusic || itemKind(i) == ItemKindMusic {
+		return true
+	}
+	var t = i.String()
+	if strings.ContainsAny(t, "♪♫") {
+		return true
+	}
+	for _, m := range itemKindRegexpDescriptions.FindAllString(t, -1) {
+		for _, w := range itemKindMusicWords {
+			if strings.Contains(strings.ToLower(m), w) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Accessibility(t *testing.T) {
+	// Init
+	var item = func(startAt, endAt time.Duration, texts ...string) *astisub.Item {
+		var i = &astisub.Item{EndAt: endAt, StartAt: startAt}
+		for _, t := range texts {
+			i.Lines = append(i.Lines, astisub.Line{Items: []astisub.LineItem{{Text: t}}})
+		}
+		return i
+	}
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		item(2*time.Second, 4*time.Second, "JOHN: Hello"),
+		item(3*time.Second, 5*time.Second, "[door slams]"),
+		item(10*time.Second, 11*time.Second, "♪ Happy birthday to you ♪"),
+		item(12*time.Second, 13*time.Second),
+		{AudioDescription: &astisub.AudioDescription{}, EndAt: 20 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "She leaves"}}}}, StartAt: 12 * time.Second},
+		item(14*time.Second, 16*time.Second, "Bye"),
+	}}
+
+	// Default options
+	r := s.Accessibility()
+	assert.Equal(t, 4, r.Captions)
+	assert.Equal(t, 16*time.Second, r.MediaDuration)
+	assert.Equal(t, 6*time.Second, r.CaptionedDuration)
+	assert.Equal(t, 37.5, r.Coverage)
+	assert.Equal(t, astisub.AccessibilityGap{EndAt: 10 * time.Second, StartAt: 5 * time.Second}, r.LongestGap)
+	assert.Equal(t, astisub.AccessibilitySDH{MusicItems: 1, SoundItems: 1, SpeakerItems: 1}, r.SDH)
+	assert.True(t, r.SDH.Present())
+	assert.Equal(t, 25.0, r.MaxReadingSpeed)
+	assert.Equal(t, 5.75, r.MedianReadingSpeed)
+	assert.Equal(t, []astisub.ReadingSpeedBucket{
+		{Count: 3, Max: 12},
+		{Max: 15, Min: 12},
+		{Max: 17, Min: 15},
+		{Max: 20, Min: 17},
+		{Count: 1, Min: 20},
+	}, r.ReadingSpeedBuckets)
+	assert.Equal(t, "coverage: 37.5% (6s of 16s)\nlongest gap: 5s (from 5s to 10s)\nsdh: true (1 music items, 1 sound items, 1 speaker items)\nreading speed: 5.8 cps median, 25.0 cps max\n  0.0-12.0 cps: 3\n  12.0-15.0 cps: 0\n  15.0-17.0 cps: 0\n  17.0-20.0 cps: 0\n  >20.0 cps: 1", r.String())
+
+	// Media duration
+	r = s.AccessibilityWithOptions(astisub.AccessibilityOptions{MediaDuration: 30 * time.Second, ReadingSpeedBounds: []float64{10}})
+	assert.Equal(t, 20.0, r.Coverage)
+	assert.Equal(t, astisub.AccessibilityGap{EndAt: 30 * time.Second, StartAt: 16 * time.Second}, r.LongestGap)
+	assert.Equal(t, []astisub.ReadingSpeedBucket{{Count: 3, Max: 10}, {Count: 1, Min: 10}}, r.ReadingSpeedBuckets)
+}
@@ -0,0 +1,321 @@
+package astisub
+
+import (
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrNoAlignedWords = errors.New("astisub: no aligned words")
+)
+
+// Default align options
+const (
+	defaultAlignMaxDuration = 7 * time.Second
+	defaultAlignMaxGap      = time.Second
+)
+
+// Number of words looked ahead when transcript words and aligned words don't match
+const alignLookahead = 10
+
+// AlignedWord represents a word timed by a forced aligner or a speech recognition engine. Words the aligner couldn't
+// align have no time boundaries.
+type AlignedWord struct {
+	Confidence *float64 // Between 0 and 1
+	EndAt      time.Duration
+	StartAt    time.Duration
+	Text       string
+}
+
+// isAligned checks whether the word has time boundaries
+func (w AlignedWord) isAligned() bool {
+	return w.EndAt > 0
+}
+
+// AlignOptions represents align options
+type AlignOptions struct {
+	Language string // BCP-47 tag of the transcript
+	// Defaults to the limits of TargetStreaming
+	Limits Limits
+	// Items are not longer than this, unless made of a single word. Defaults to 7s.
+	MaxDuration time.Duration
+	// Silences between words longer than this start a new item. Defaults to 1s.
+	MaxGap     time.Duration
+	Provenance *Provenance // Set on items and line items
+}
+
+// alignToken represents a transcript word being aligned
+type alignToken struct {
+	confidence  *float64
+	endAt       time.Duration
+	normalized  string
+	sentenceEnd bool
+	spaceBefore bool
+	startAt     time.Duration
+	text        string
+	timed       bool
+}
+
+// NewSubtitlesFromAlignment builds subtitles out of a plain transcript and of the word timings an aligner derived
+// from the audio. Texts come from the transcript whereas timings come from the aligner, words being matched
+// regardless of their case and punctuation. Punctuation is attached back to the word it belongs to. Transcript words
+// that could not be aligned are timed by interpolating their neighbours' timings and get a confidence of 0 so that
+// they can be reviewed. Each word is a timed line item, and items are made of sentences split on silences and limits.
+func NewSubtitlesFromAlignment(transcript string, ws []AlignedWord, o AlignOptions) (s *Subtitles, err error) {
+	// Default options
+	if o.Limits == (Limits{}) {
+		o.Limits = TargetStreaming.Limits()
+	}
+	if o.MaxDuration <= 0 {
+		o.MaxDuration = defaultAlignMaxDuration
+	}
+	if o.MaxGap <= 0 {
+		o.MaxGap = defaultAlignMaxGap
+	}
+	var t = o.Limits.Tokenizer
+	if t == nil {
+		t = TokenizerForLanguage(o.Language)
+	}
+
+	// Init
+	s = NewSubtitles()
+	if len(o.Language) > 0 {
+		s.Metadata = &Metadata{Language: o.Language}
+	}
+
+	// Tokenize transcript
+	var ts = alignTokens(transcript, t)
+	if len(ts) == 0 {
+		return
+	}
+
+	// Match words
+	if !alignMatch(ts, ws) {
+		err = ErrNoAlignedWords
+		return
+	}
+
+	// Interpolate words that could not be aligned
+	alignInterpolate(ts)
+
+	// Build items
+	s.Items = alignItems(ts, o, t)
+	return
+}
+
+// alignNormalize returns the lower case letters and digits of a text so that words can be matched regardless of
+// their case and punctuation
+func alignNormalize(i string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(i) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// alignTokens splits a transcript into words. Punctuation separated from words by a white space, such as in French,
+// is attached to the previous word of the sentence, unless it's opening punctuation or there are none, in which case
+// it's attached to the next word.
+func alignTokens(transcript string, t Tokenizer) (ts []alignToken) {
+	// Loop through sentences
+	for _, sentence := range t.Sentences(transcript) {
+		// Loop through words
+		var first = len(ts)
+		var prefix string
+		for _, w := range t.Words(sentence) {
+			// Punctuation
+			if len(alignNormalize(w.Text)) == 0 {
+				var r, _ = utf8.DecodeRuneInString(w.Text)
+				if len(ts) > first && !strings.ContainsRune(tokenizerOpening, r) {
+					if w.SpaceBefore {
+						ts[len(ts)-1].text += " "
+					}
+					ts[len(ts)-1].text += w.Text
+				} else {
+					if len(prefix) > 0 && w.SpaceBefore {
+						prefix += " "
+					}
+					prefix += w.Text
+				}
+				continue
+			}
+
+			// Add word
+			var tk = alignToken{
+				normalized:  alignNormalize(w.Text),
+				spaceBefore: w.SpaceBefore || len(ts) == first,
+				text:        w.Text,
+			}
+			if len(prefix) > 0 {
+				if w.SpaceBefore {
+					prefix += " "
+				}
+				tk.text, prefix = prefix+tk.text, ""
+			}
+			ts = append(ts, tk)
+		}
+
+		// Sentence is only made of punctuation
+		if len(ts) == first {
+			continue
+		}
+
+		// Punctuation ending the sentence
+		if len(prefix) > 0 {
+			ts[len(ts)-1].text += " " + prefix
+		}
+		ts[len(ts)-1].sentenceEnd = true
+	}
+	return
+}
+
+// alignMatch matches transcript words with aligned words and copies their timings. When words don't match, the
+// closest match within the next words is looked for: transcript words are skipped if the aligner missed them and
+// aligned words are skipped if they're not in the transcript. Words are otherwise considered as misrecognized and
+// matched anyway. ok is false if no word has been timed.
+func alignMatch(ts []alignToken, ws []AlignedWord) (ok bool) {
+	// Normalize aligned words
+	var as []AlignedWord
+	var ns []string
+	for _, w := range ws {
+		if n := alignNormalize(w.Text); len(n) > 0 {
+			as = append(as, w)
+			ns = append(ns, n)
+		}
+	}
+
+	// Loop through words
+	for i, j := 0, 0; i < len(ts) && j < len(as); {
+		// Words don't match
+		if ts[i].normalized != ns[j] {
+			// Look for the closest match
+			var skipTranscript, skipAligned int
+			for k := 1; k <= alignLookahead && skipTranscript+skipAligned == 0; k++ {
+				if i+k < len(ts) && ts[i+k].normalized == ns[j] {
+					skipTranscript = k
+				} else if j+k < len(as) && ns[j+k] == ts[i].normalized {
+					skipAligned = k
+				}
+			}
+			i += skipTranscript
+			j += skipAligned
+		}
+
+		// Copy timings
+		if as[j].isAligned() {
+			ts[i].confidence = as[j].Confidence
+			ts[i].endAt = as[j].EndAt
+			ts[i].startAt = as[j].StartAt
+			ts[i].timed = true
+			ok = true
+		}
+		i++
+		j++
+	}
+	return
+}
+
+// alignInterpolate times words that have not been timed by sharing the time between their timed neighbours
+// proportionally to their number of characters
+func alignInterpolate(ts []alignToken) {
+	for i := 0; i < len(ts); i++ {
+		// Word is timed
+		if ts[i].timed {
+			continue
+		}
+
+		// Get untimed words
+		var j = i
+		var total int
+		for ; j < len(ts) && !ts[j].timed; j++ {
+			total += utf8.RuneCountInString(ts[j].normalized)
+		}
+
+		// Get boundaries
+		var startAt, endAt time.Duration
+		switch {
+		case i > 0 && j < len(ts):
+			startAt, endAt = ts[i-1].endAt, ts[j].startAt
+		case i > 0:
+			startAt, endAt = ts[i-1].endAt, ts[i-1].endAt
+		default:
+			startAt, endAt = ts[j].startAt, ts[j].startAt
+		}
+		if endAt < startAt {
+			endAt = startAt
+		}
+
+		// Share time
+		var n int
+		for k := i; k < j; k++ {
+			ts[k].confidence = astiptr.Float(0)
+			ts[k].startAt = startAt + time.Duration(int64(endAt-startAt)*int64(n)/int64(total))
+			n += utf8.RuneCountInString(ts[k].normalized)
+			ts[k].endAt = startAt + time.Duration(int64(endAt-startAt)*int64(n)/int64(total))
+		}
+		i = j
+	}
+}
+
+// alignItems builds items out of timed words. A new item starts after the end of a sentence, after a silence, or
+// when the item would exceed limits. Words that are not separated by a white space, such as in Chinese, share a line
+// item.
+func alignItems(ts []alignToken, o AlignOptions, t Tokenizer) (is []*Item) {
+	// Get max number of characters
+	var maxChars int
+	if o.Limits.MaxCharactersPerLine > 0 && o.Limits.MaxLines > 0 {
+		maxChars = o.Limits.MaxCharactersPerLine * o.Limits.MaxLines
+	}
+
+	// Loop through words
+	var i *Item
+	var chars int
+	for idx, tk := range ts {
+		// Start a new item
+		var length = utf8.RuneCountInString(tk.text)
+		if i == nil || ts[idx-1].sentenceEnd || tk.startAt-i.EndAt > o.MaxGap || tk.endAt-i.StartAt > o.MaxDuration ||
+			(maxChars > 0 && chars+1+length > maxChars) {
+			i = &Item{Lines: []Line{{}}, Provenance: o.Provenance, StartAt: tk.startAt}
+			is = append(is, i)
+			chars = 0
+		} else if tk.spaceBefore {
+			chars++
+		}
+		chars += length
+
+		// Words not separated by a white space share a line item
+		var l = &i.Lines[0]
+		if last := len(l.Items) - 1; last >= 0 && !tk.spaceBefore {
+			l.Items[last].Text += tk.text
+			l.Items[last].EndAt = tk.endAt
+			if tk.confidence != nil && (l.Items[last].Confidence == nil || *tk.confidence < *l.Items[last].Confidence) {
+				l.Items[last].Confidence = tk.confidence
+			}
+		} else {
+			l.Items = append(l.Items, LineItem{
+				Confidence: tk.confidence,
+				EndAt:      tk.endAt,
+				Provenance: o.Provenance,
+				StartAt:    tk.startAt,
+				Text:       tk.text,
+			})
+		}
+		if tk.endAt > i.EndAt {
+			i.EndAt = tk.endAt
+		}
+	}
+
+	// Wrap lines
+	for _, i := range is {
+		i.Lines = reflowLines(i.Lines, o.Limits.MaxCharactersPerLine, t, "")
+	}
+	return
+}
@@ -0,0 +1,77 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSubtitlesFromAlignment(t *testing.T) {
+	// Unaligned and extra words
+	p := &astisub.Provenance{Engine: "aligner"}
+	s, err := astisub.NewSubtitlesFromAlignment("Hello, my friend. How are you today?", []astisub.AlignedWord{
+		{EndAt: 500 * time.Millisecond, Text: "hello"},
+		{EndAt: 800 * time.Millisecond, StartAt: 600 * time.Millisecond, Text: "my"},
+		{EndAt: 2700 * time.Millisecond, StartAt: 2500 * time.Millisecond, Text: "how"},
+		{EndAt: 2900 * time.Millisecond, StartAt: 2700 * time.Millisecond, Text: "are"},
+		{EndAt: 3 * time.Second, StartAt: 2900 * time.Millisecond, Text: "uh"},
+		{EndAt: 3200 * time.Millisecond, StartAt: 3 * time.Second, Text: "you"},
+		{Confidence: astiptr.Float(0.8), EndAt: 3800 * time.Millisecond, StartAt: 3200 * time.Millisecond, Text: "today"},
+	}, astisub.AlignOptions{Provenance: p})
+	assert.NoError(t, err)
+	assert.Equal(t, []*astisub.Item{
+		{EndAt: 2500 * time.Millisecond, Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{EndAt: 500 * time.Millisecond, Provenance: p, Text: "Hello,"},
+			{EndAt: 800 * time.Millisecond, Provenance: p, StartAt: 600 * time.Millisecond, Text: "my"},
+			{Confidence: astiptr.Float(0), EndAt: 2500 * time.Millisecond, Provenance: p, StartAt: 800 * time.Millisecond, Text: "friend."},
+		}}}, Provenance: p},
+		{EndAt: 3800 * time.Millisecond, Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{EndAt: 2700 * time.Millisecond, Provenance: p, StartAt: 2500 * time.Millisecond, Text: "How"},
+			{EndAt: 2900 * time.Millisecond, Provenance: p, StartAt: 2700 * time.Millisecond, Text: "are"},
+			{EndAt: 3200 * time.Millisecond, Provenance: p, StartAt: 3 * time.Second, Text: "you"},
+			{Confidence: astiptr.Float(0.8), EndAt: 3800 * time.Millisecond, Provenance: p, StartAt: 3200 * time.Millisecond, Text: "today?"},
+		}}}, Provenance: p, StartAt: 2500 * time.Millisecond},
+	}, s.Items)
+	assert.Equal(t, []*astisub.Item{s.Items[0]}, s.LowConfidenceItems(0.5))
+
+	// Punctuation separated by white spaces
+	s, err = astisub.NewSubtitlesFromAlignment("— Oui ? Non !", []astisub.AlignedWord{
+		{EndAt: 1500 * time.Millisecond, StartAt: time.Second, Text: "Oui"},
+		{EndAt: 3500 * time.Millisecond, StartAt: 3 * time.Second, Text: "non"},
+	}, astisub.AlignOptions{Language: "fr"})
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, "— Oui ?", s.Items[0].String())
+	assert.Equal(t, "Non !", s.Items[1].String())
+	assert.Equal(t, "fr", s.Metadata.Language)
+
+	// Words not separated by white spaces
+	s, err = astisub.NewSubtitlesFromAlignment("你好。", []astisub.AlignedWord{
+		{EndAt: 200 * time.Millisecond, Text: "你"},
+		{EndAt: 400 * time.Millisecond, StartAt: 200 * time.Millisecond, Text: "好"},
+	}, astisub.AlignOptions{Language: "zh"})
+	assert.NoError(t, err)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{EndAt: 400 * time.Millisecond, Text: "你好。"}}}}, s.Items[0].Lines)
+
+	// Silences and limits
+	s, err = astisub.NewSubtitlesFromAlignment("one two three four", []astisub.AlignedWord{
+		{EndAt: 500 * time.Millisecond, Text: "one"},
+		{EndAt: time.Second, StartAt: 500 * time.Millisecond, Text: "two"},
+		{EndAt: 3 * time.Second, StartAt: 2500 * time.Millisecond, Text: "three"},
+		{EndAt: 3500 * time.Millisecond, StartAt: 3 * time.Second, Text: "four"},
+	}, astisub.AlignOptions{Limits: astisub.Limits{MaxCharactersPerLine: 5, MaxLines: 2}})
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, "one - two", s.Items[0].String())
+	assert.Equal(t, "three - four", s.Items[1].String())
+
+	// No aligned words
+	_, err = astisub.NewSubtitlesFromAlignment("test", nil, astisub.AlignOptions{})
+	assert.ErrorIs(t, err, astisub.ErrNoAlignedWords)
+	s, err = astisub.NewSubtitlesFromAlignment("", nil, astisub.AlignOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, s.Items)
+}
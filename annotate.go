@@ -0,0 +1,86 @@
+package astisub
+
+import (
+	"bytes"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default annotation template
+const defaultAnnotationTemplate = "#{{.Index}} {{.StartTimecode}} - {{.EndTimecode}}"
+
+// AnnotateTimecodesOptions represents annotate timecodes options
+type AnnotateTimecodesOptions struct {
+	// If true, the annotation is added as the last line of each item. Otherwise it's added as the first line.
+	Append bool
+	// Framerate of the timecodes. Defaults to the metadata framerate, then to 25.
+	Framerate int
+	// Template of the annotation, executed with an AnnotationData. Defaults to
+	// "#{{.Index}} {{.StartTimecode}} - {{.EndTimecode}}".
+	Template string
+}
+
+// AnnotationData represents the data the annotation template is executed with
+type AnnotationData struct {
+	Duration      time.Duration
+	EndAt         time.Duration
+	EndTimecode   string // HH:MM:SS:FF
+	Index         int    // Starts at 1
+	StartAt       time.Duration
+	StartTimecode string // HH:MM:SS:FF
+}
+
+// AnnotateTimecodes adds the index and timecodes of each item as a new line, producing review copies (a.k.a.
+// spotting subtitles) that are burnt in for approval passes
+func (s *Subtitles) AnnotateTimecodes() error {
+	return s.AnnotateTimecodesWithOptions(AnnotateTimecodesOptions{})
+}
+
+// AnnotateTimecodesWithOptions adds the index and timecodes of each item as a new line with options
+func (s *Subtitles) AnnotateTimecodesWithOptions(o AnnotateTimecodesOptions) (err error) {
+	// Default options
+	if len(o.Template) == 0 {
+		o.Template = defaultAnnotationTemplate
+	}
+	if o.Framerate <= 0 && s.Metadata != nil {
+		o.Framerate = s.Metadata.Framerate
+	}
+	if o.Framerate <= 0 {
+		o.Framerate = defaultFramerate
+	}
+
+	// Parse template
+	var t *template.Template
+	if t, err = template.New("annotation").Parse(o.Template); err != nil {
+		err = errors.Wrapf(err, "astisub: parsing template %s failed", o.Template)
+		return
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		// Execute template
+		var buf = &bytes.Buffer{}
+		if err = t.Execute(buf, AnnotationData{
+			Duration:      i.EndAt - i.StartAt,
+			EndAt:         i.EndAt,
+			EndTimecode:   NewTimecode(i.EndAt, float64(o.Framerate)).String(),
+			Index:         idx + 1,
+			StartAt:       i.StartAt,
+			StartTimecode: NewTimecode(i.StartAt, float64(o.Framerate)).String(),
+		}); err != nil {
+			err = errors.Wrapf(err, "astisub: executing template for item #%d failed", idx+1)
+			return
+		}
+
+		// Add line
+		var l = Line{Items: []LineItem{{Text: buf.String()}}}
+		if o.Append {
+			i.Lines = append(i.Lines, l)
+		} else {
+			i.Lines = append([]Line{l}, i.Lines...)
+		}
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_AnnotateTimecodes(t *testing.T) {
+	// Default options
+	var s = mockSubtitles()
+	s.Items[1].EndAt = time.Hour + 2*time.Minute + 3*time.Second + 520*time.Millisecond
+	err := s.AnnotateTimecodes()
+	assert.NoError(t, err)
+	assert.Equal(t, "#1 00:00:01:00 - 00:00:03:00 - subtitle-1", s.Items[0].String())
+	assert.Equal(t, "#2 00:00:03:00 - 01:02:03:13 - subtitle-2", s.Items[1].String())
+
+	// Custom options
+	s = mockSubtitles()
+	err = s.AnnotateTimecodesWithOptions(astisub.AnnotateTimecodesOptions{
+		Append:    true,
+		Framerate: 30,
+		Template:  "[{{.Index}}] {{.StartTimecode}} ({{.Duration}})",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "subtitle-1 - [1] 00:00:01:00 (2s)", s.Items[0].String())
+
+	// Invalid template
+	err = s.AnnotateTimecodesWithOptions(astisub.AnnotateTimecodesOptions{Template: "{{.Invalid"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,160 @@
+package astisubdash
+
+import (
+	"encoding/xml"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Codecs
+const (
+	CodecSTPP = "stpp" // TTML in fragmented MP4
+	CodecWVTT = "wvtt" // WebVTT in fragmented MP4
+)
+
+// Roles
+// https://dashif.org/identifiers/roles/
+const (
+	RoleCaption        = "caption"
+	RoleForcedSubtitle = "forced-subtitle"
+	RoleSubtitle       = "subtitle"
+)
+
+// Defaults
+const (
+	defaultBandwidth   = 1000
+	defaultStartNumber = 1
+	defaultTimescale   = 1000
+)
+
+// Errors
+var (
+	ErrInvalidCodec           = errors.New("astisubdash: invalid codec")
+	ErrInvalidSegmentDuration = errors.New("astisubdash: invalid segment duration")
+	ErrNoMedia                = errors.New("astisubdash: no media")
+)
+
+// Language tags indexed by astisub language
+var languageTags = map[string]string{
+	astisub.LanguageEnglish: "en",
+	astisub.LanguageFrench:  "fr",
+}
+
+// AdaptationSet represents the adaptation set of a segmented subtitle output
+type AdaptationSet struct {
+	Bandwidth       int // bits/s, defaults to 1000
+	Codec           string
+	ID              string // Representation ID, defaults to the codec
+	Initialization  string // e.g. "$RepresentationID$/init.mp4"
+	Language        string // Either an astisub language or a RFC 5646 tag
+	Media           string // e.g. "$RepresentationID$/$Number$.m4s"
+	Role            string // Defaults to RoleSubtitle
+	SegmentDuration time.Duration
+	StartNumber     int // Number of the first segment, defaults to 1
+	Timescale       int // Defaults to 1000
+}
+
+// XML returns the AdaptationSet snippet that can be spliced into the Period of a MPD
+func (a AdaptationSet) XML() (o []byte, err error) {
+	// Validate
+	if a.Codec != CodecSTPP && a.Codec != CodecWVTT {
+		err = ErrInvalidCodec
+		return
+	}
+	if len(a.Media) == 0 {
+		err = ErrNoMedia
+		return
+	}
+	if a.SegmentDuration <= 0 {
+		err = ErrInvalidSegmentDuration
+		return
+	}
+
+	// Defaults
+	var bandwidth, id, role, startNumber, timescale = a.Bandwidth, a.ID, a.Role, a.StartNumber, a.Timescale
+	if bandwidth <= 0 {
+		bandwidth = defaultBandwidth
+	}
+	if len(id) == 0 {
+		id = a.Codec
+	}
+	if len(role) == 0 {
+		role = RoleSubtitle
+	}
+	if startNumber <= 0 {
+		startNumber = defaultStartNumber
+	}
+	if timescale <= 0 {
+		timescale = defaultTimescale
+	}
+
+	// Get language
+	var lang = a.Language
+	if t, ok := languageTags[lang]; ok {
+		lang = t
+	}
+
+	// Marshal
+	if o, err = xml.MarshalIndent(xmlAdaptationSet{
+		ContentType: "text",
+		Lang:        lang,
+		MimeType:    "application/mp4",
+		Representation: xmlRepresentation{
+			Bandwidth: bandwidth,
+			Codecs:    a.Codec,
+			ID:        id,
+		},
+		Role: xmlDescriptor{
+			SchemeIDURI: "urn:mpeg:dash:role:2011",
+			Value:       role,
+		},
+		SegmentAlignment: true,
+		SegmentTemplate: xmlSegmentTemplate{
+			Duration:       int64(a.SegmentDuration) * int64(timescale) / int64(time.Second),
+			Initialization: a.Initialization,
+			Media:          a.Media,
+			StartNumber:    startNumber,
+			Timescale:      timescale,
+		},
+	}, "", "    "); err != nil {
+		err = errors.Wrap(err, "astisubdash: marshaling failed")
+		return
+	}
+	return
+}
+
+// xmlAdaptationSet represents an XML AdaptationSet
+type xmlAdaptationSet struct {
+	XMLName          xml.Name           `xml:"AdaptationSet"`
+	ContentType      string             `xml:"contentType,attr"`
+	Lang             string             `xml:"lang,attr,omitempty"`
+	MimeType         string             `xml:"mimeType,attr"`
+	SegmentAlignment bool               `xml:"segmentAlignment,attr"`
+	Role             xmlDescriptor      `xml:"Role"`
+	SegmentTemplate  xmlSegmentTemplate `xml:"SegmentTemplate"`
+	Representation   xmlRepresentation  `xml:"Representation"`
+}
+
+// xmlDescriptor represents an XML descriptor
+type xmlDescriptor struct {
+	SchemeIDURI string `xml:"schemeIdUri,attr"`
+	Value       string `xml:"value,attr"`
+}
+
+// xmlSegmentTemplate represents an XML SegmentTemplate
+type xmlSegmentTemplate struct {
+	Duration       int64  `xml:"duration,attr"`
+	Initialization string `xml:"initialization,attr,omitempty"`
+	Media          string `xml:"media,attr"`
+	StartNumber    int    `xml:"startNumber,attr"`
+	Timescale      int    `xml:"timescale,attr"`
+}
+
+// xmlRepresentation represents an XML Representation
+type xmlRepresentation struct {
+	Bandwidth int    `xml:"bandwidth,attr"`
+	Codecs    string `xml:"codecs,attr"`
+	ID        string `xml:"id,attr"`
+}
@@ -0,0 +1,35 @@
+package astisubdash_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubdash"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptationSet(t *testing.T) {
+	// Errors
+	_, err := astisubdash.AdaptationSet{Codec: "invalid"}.XML()
+	assert.Equal(t, astisubdash.ErrInvalidCodec, err)
+	_, err = astisubdash.AdaptationSet{Codec: astisubdash.CodecWVTT}.XML()
+	assert.Equal(t, astisubdash.ErrNoMedia, err)
+	_, err = astisubdash.AdaptationSet{Codec: astisubdash.CodecWVTT, Media: "m"}.XML()
+	assert.Equal(t, astisubdash.ErrInvalidSegmentDuration, err)
+
+	// Success
+	b, err := astisubdash.AdaptationSet{
+		Codec:           astisubdash.CodecSTPP,
+		Initialization:  "$RepresentationID$/init.mp4",
+		Language:        astisub.LanguageFrench,
+		Media:           "$RepresentationID$/$Number$.m4s",
+		SegmentDuration: 4 * time.Second,
+	}.XML()
+	assert.NoError(t, err)
+	assert.Equal(t, `<AdaptationSet contentType="text" lang="fr" mimeType="application/mp4" segmentAlignment="true">
+    <Role schemeIdUri="urn:mpeg:dash:role:2011" value="subtitle"></Role>
+    <SegmentTemplate duration="4000" initialization="$RepresentationID$/init.mp4" media="$RepresentationID$/$Number$.m4s" startNumber="1" timescale="1000"></SegmentTemplate>
+    <Representation bandwidth="1000" codecs="stpp" id="stpp"></Representation>
+</AdaptationSet>`, string(b))
+}
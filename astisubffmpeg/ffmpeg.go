@@ -0,0 +1,262 @@
+package astisubffmpeg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Binaries
+// They can be overwritten if ffmpeg and ffprobe are not in the PATH
+var (
+	FFMpegPath  = "ffmpeg"
+	FFProbePath = "ffprobe"
+)
+
+// Errors
+var (
+	ErrBitmapStream     = errors.New("astisubffmpeg: bitmap streams can't be extracted as text")
+	ErrStreamNotFound   = errors.New("astisubffmpeg: stream not found")
+	ErrUnsupportedCodec = errors.New("astisubffmpeg: unsupported codec")
+)
+
+// Bitmap codecs
+var bitmapCodecs = map[string]bool{
+	"dvb_subtitle":      true,
+	"dvd_subtitle":      true,
+	"hdmv_pgs_subtitle": true,
+	"xsub":              true,
+}
+
+// Stream represents a subtitle stream of a media
+type Stream struct {
+	Codec           string
+	Default         bool
+	Forced          bool
+	HearingImpaired bool
+	Index           int // Index of the stream among all streams of the media
+	Language        string
+	Title           string
+	Track           int // Index of the stream among subtitle streams of the media
+}
+
+// IsBitmap returns whether the stream contains images instead of text
+func (s Stream) IsBitmap() bool {
+	return bitmapCodecs[s.Codec]
+}
+
+// Streams lists the subtitle streams of a media
+func Streams(path string) (ss []Stream, err error) {
+	// Probe
+	var b []byte
+	if b, err = run(FFProbePath, "-v", "error", "-select_streams", "s", "-show_streams", "-of", "json", path); err != nil {
+		err = errors.Wrapf(err, "astisubffmpeg: probing %s failed", path)
+		return
+	}
+
+	// Parse
+	if ss, err = parseStreams(b); err != nil {
+		err = errors.Wrapf(err, "astisubffmpeg: parsing streams of %s failed", path)
+		return
+	}
+	return
+}
+
+// OpenFromMedia extracts the subtitle stream of a media whose track is provided and parses it
+// The track is the index of the stream among subtitle streams of the media, as listed by Streams
+func OpenFromMedia(path string, track int) (s *astisub.Subtitles, err error) {
+	// Get streams
+	var ss []Stream
+	if ss, err = Streams(path); err != nil {
+		return
+	}
+
+	// Get stream
+	var st *Stream
+	for idx := range ss {
+		if ss[idx].Track == track {
+			st = &ss[idx]
+			break
+		}
+	}
+	if st == nil {
+		err = ErrStreamNotFound
+		return
+	}
+	return openStream(path, *st)
+}
+
+// OpenSetFromMedia extracts every text subtitle stream of a media, such as a MKV or a MP4, and parses them in a set
+// Bitmap streams and streams whose codec is not supported are skipped
+func OpenSetFromMedia(path string) (s *astisub.SubtitleSet, err error) {
+	// Get streams
+	var ss []Stream
+	if ss, err = Streams(path); err != nil {
+		return
+	}
+
+	// Loop through streams
+	s = astisub.NewSubtitleSet()
+	for _, st := range ss {
+		// Stream can't be extracted
+		if _, _, errFormats := formats(st); errFormats != nil {
+			continue
+		}
+
+		// Open
+		var t = &astisub.Track{
+			Kind:     trackKind(st),
+			Language: st.Language,
+			Title:    st.Title,
+		}
+		if t.Subtitles, err = openStream(path, st); err != nil {
+			return
+		}
+
+		// Add language
+		if l, ok := astisub.LanguageFromISO6392(st.Language); ok {
+			t.Language = l
+		}
+		s.Add(t)
+	}
+	return
+}
+
+// trackKind returns the track kind matching a stream
+func trackKind(s Stream) astisub.TrackKind {
+	switch {
+	case s.Forced:
+		return astisub.TrackKindForced
+	case s.HearingImpaired:
+		return astisub.TrackKindSDH
+	case s.Codec == "eia_608":
+		return astisub.TrackKindCaptions
+	}
+	return astisub.TrackKindSubtitles
+}
+
+// openStream extracts a subtitle stream of a media and parses it
+func openStream(path string, st Stream) (s *astisub.Subtitles, err error) {
+	// Get formats
+	var muxer string
+	var f astisub.Format
+	if muxer, f, err = formats(st); err != nil {
+		return
+	}
+
+	// Extract
+	var b []byte
+	if b, err = run(FFMpegPath, "-v", "error", "-i", path, "-map", fmt.Sprintf("0:s:%d", st.Track), "-f", muxer, "-"); err != nil {
+		err = errors.Wrapf(err, "astisubffmpeg: extracting track %d of %s failed", st.Track, path)
+		return
+	}
+
+	// Parse
+	if s, err = astisub.Read(bytes.NewReader(b), f, astisub.Options{}); err != nil {
+		err = errors.Wrapf(err, "astisubffmpeg: parsing track %d of %s failed", st.Track, path)
+		return
+	}
+
+	// Add language and kind
+	if s.Metadata == nil {
+		s.Metadata = &astisub.Metadata{}
+	}
+	if l, ok := astisub.LanguageFromISO6392(st.Language); ok && len(s.Metadata.Language) == 0 {
+		s.Metadata.Language = l
+	}
+	if len(s.Metadata.Kind) == 0 {
+		s.Metadata.Kind = trackKind(st)
+	}
+
+	// Every item of a forced stream is forced
+	if st.Forced {
+		for _, i := range s.Items {
+			i.Forced = true
+		}
+	}
+	return
+}
+
+// formats returns the ffmpeg muxer the stream should be extracted with as well as the matching astisub format
+// Styling is kept whenever the codec supports it
+func formats(s Stream) (muxer string, f astisub.Format, err error) {
+	switch s.Codec {
+	case "ass", "ssa":
+		return "ass", astisub.FormatSSA, nil
+	case "webvtt":
+		return "webvtt", astisub.FormatWebVTT, nil
+	case "eia_608", "jacosub", "microdvd", "mov_text", "mpl2", "pjs", "realtext", "sami", "stl", "subrip", "subviewer",
+		"subviewer1", "text", "ttml", "vplayer":
+		return "srt", astisub.FormatSRT, nil
+	}
+	if s.IsBitmap() {
+		err = ErrBitmapStream
+		return
+	}
+	err = errors.Wrapf(ErrUnsupportedCodec, "astisubffmpeg: codec %s", s.Codec)
+	return
+}
+
+// run runs a binary and returns its standard output
+func run(name string, args ...string) (o []byte, err error) {
+	var stdout, stderr = &bytes.Buffer{}, &bytes.Buffer{}
+	var cmd = exec.Command(name, args...)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Run(); err != nil {
+		if m := strings.TrimSpace(stderr.String()); len(m) > 0 {
+			err = errors.Wrapf(err, "astisubffmpeg: running %s failed: %s", name, m)
+		} else {
+			err = errors.Wrapf(err, "astisubffmpeg: running %s failed", name)
+		}
+		return
+	}
+	o = stdout.Bytes()
+	return
+}
+
+// ffprobe output
+type ffprobeOutput struct {
+	Streams []ffprobeStream `json:"streams"`
+}
+
+type ffprobeStream struct {
+	CodecName   string            `json:"codec_name"`
+	CodecType   string            `json:"codec_type"`
+	Disposition map[string]int    `json:"disposition"`
+	Index       int               `json:"index"`
+	Tags        map[string]string `json:"tags"`
+}
+
+// parseStreams parses ffprobe's json output
+func parseStreams(b []byte) (ss []Stream, err error) {
+	// Unmarshal
+	var o ffprobeOutput
+	if err = json.Unmarshal(b, &o); err != nil {
+		err = errors.Wrap(err, "astisubffmpeg: unmarshaling failed")
+		return
+	}
+
+	// Loop through streams
+	for _, s := range o.Streams {
+		if len(s.CodecType) > 0 && s.CodecType != "subtitle" {
+			continue
+		}
+		ss = append(ss, Stream{
+			Codec:           s.CodecName,
+			Default:         s.Disposition["default"] == 1,
+			Forced:          s.Disposition["forced"] == 1,
+			HearingImpaired: s.Disposition["hearing_impaired"] == 1,
+			Index:           s.Index,
+			Language:        s.Tags["language"],
+			Title:           s.Tags["title"],
+			Track:           len(ss),
+		})
+	}
+	return
+}
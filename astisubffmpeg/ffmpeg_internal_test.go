@@ -0,0 +1,42 @@
+package astisubffmpeg
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStreams(t *testing.T) {
+	ss, err := parseStreams([]byte(`{"streams":[{"index":2,"codec_name":"subrip","codec_type":"subtitle","disposition":{"default":1,"forced":0},"tags":{"language":"eng","title":"English"}},{"index":3,"codec_name":"hdmv_pgs_subtitle","codec_type":"subtitle","disposition":{"default":0,"forced":1},"tags":{"language":"fre"}}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []Stream{
+		{Codec: "subrip", Default: true, Index: 2, Language: "eng", Title: "English"},
+		{Codec: "hdmv_pgs_subtitle", Forced: true, Index: 3, Language: "fre", Track: 1},
+	}, ss)
+	assert.True(t, ss[1].IsBitmap())
+	_, err = parseStreams([]byte("invalid"))
+	assert.Error(t, err)
+}
+
+func TestFormats(t *testing.T) {
+	m, f, err := formats(Stream{Codec: "ass"})
+	assert.NoError(t, err)
+	assert.Equal(t, "ass", m)
+	assert.Equal(t, astisub.FormatSSA, f)
+	m, f, err = formats(Stream{Codec: "mov_text"})
+	assert.NoError(t, err)
+	assert.Equal(t, "srt", m)
+	assert.Equal(t, astisub.FormatSRT, f)
+	_, _, err = formats(Stream{Codec: "dvd_subtitle"})
+	assert.Equal(t, ErrBitmapStream, err)
+	_, _, err = formats(Stream{Codec: "invalid"})
+	assert.Error(t, err)
+}
+
+func TestTrackKind(t *testing.T) {
+	assert.Equal(t, astisub.TrackKindForced, trackKind(Stream{Forced: true}))
+	assert.Equal(t, astisub.TrackKindSDH, trackKind(Stream{HearingImpaired: true}))
+	assert.Equal(t, astisub.TrackKindCaptions, trackKind(Stream{Codec: "eia_608"}))
+	assert.Equal(t, astisub.TrackKindSubtitles, trackKind(Stream{Codec: "subrip"}))
+}
@@ -0,0 +1,154 @@
+package astisubhls
+
+import (
+	"strings"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// DefaultGroupID is the group ID used when none is provided
+const DefaultGroupID = "subs"
+
+// Errors
+var (
+	ErrDuplicateName    = errors.New("astisubhls: duplicate name")
+	ErrNoNameOrLanguage = errors.New("astisubhls: no name or language")
+	ErrNoURI            = errors.New("astisubhls: no uri")
+	ErrSeveralDefaults  = errors.New("astisubhls: several default renditions")
+)
+
+// Language tags indexed by astisub language
+var languageTags = map[string]string{
+	astisub.LanguageEnglish: "en",
+	astisub.LanguageFrench:  "fr",
+}
+
+// Characteristics indexed by track kind
+var kindCharacteristics = map[astisub.TrackKind][]string{
+	astisub.TrackKindCaptions:     {"public.accessibility.transcribes-spoken-dialog", "public.accessibility.describes-music-and-sound"},
+	astisub.TrackKindDescriptions: {"public.accessibility.describes-video"},
+	astisub.TrackKindSDH:          {"public.accessibility.transcribes-spoken-dialog", "public.accessibility.describes-music-and-sound"},
+}
+
+// Rendition represents a subtitle rendition, i.e. the media playlist of a segmented WebVTT output
+type Rendition struct {
+	Characteristics []string // e.g. "public.accessibility.transcribes-spoken-dialog". Defaults to the kind's characteristics
+	Default         bool
+	Forced          bool
+	Kind            astisub.TrackKind // Forced kinds imply Forced
+	Language        string            // Either an astisub language or a RFC 5646 tag
+	Name            string            // Defaults to the language
+	URI             string
+}
+
+// Group represents a group of subtitle renditions
+type Group struct {
+	ID         string // Defaults to DefaultGroupID
+	Renditions []Rendition
+}
+
+// id returns the group ID
+func (g Group) id() string {
+	if len(g.ID) > 0 {
+		return g.ID
+	}
+	return DefaultGroupID
+}
+
+// Lines returns the EXT-X-MEDIA lines of the group's renditions, to be added to a master playlist
+// https://tools.ietf.org/html/rfc8216#section-4.3.4.1
+func (g Group) Lines() (ls []string, err error) {
+	// Loop through renditions
+	var names = make(map[string]bool)
+	var hasDefault bool
+	for idx, r := range g.Renditions {
+		// Validate URI
+		if len(r.URI) == 0 {
+			err = errors.Wrapf(ErrNoURI, "astisubhls: rendition #%d", idx+1)
+			return
+		}
+
+		// Get language
+		var lang = r.Language
+		if t, ok := languageTags[lang]; ok {
+			lang = t
+		}
+
+		// Get name
+		var name = r.Name
+		if len(name) == 0 {
+			name = r.Language
+		}
+		if len(name) == 0 {
+			err = errors.Wrapf(ErrNoNameOrLanguage, "astisubhls: rendition #%d", idx+1)
+			return
+		}
+
+		// Names must be unique within a group
+		if names[name] {
+			err = errors.Wrapf(ErrDuplicateName, "astisubhls: rendition #%d", idx+1)
+			return
+		}
+		names[name] = true
+
+		// Only one rendition can be the default one
+		if r.Default {
+			if hasDefault {
+				err = errors.Wrapf(ErrSeveralDefaults, "astisubhls: rendition #%d", idx+1)
+				return
+			}
+			hasDefault = true
+		}
+
+		// Build attributes
+		var as = []string{
+			"TYPE=SUBTITLES",
+			"GROUP-ID=" + quote(g.id()),
+			"NAME=" + quote(name),
+		}
+		if len(lang) > 0 {
+			as = append(as, "LANGUAGE="+quote(lang))
+		}
+		as = append(as, "DEFAULT="+yesNo(r.Default), "AUTOSELECT=YES", "FORCED="+yesNo(r.Forced || r.Kind == astisub.TrackKindForced))
+		var cs = r.Characteristics
+		if len(cs) == 0 {
+			cs = kindCharacteristics[r.Kind]
+		}
+		if len(cs) > 0 {
+			as = append(as, "CHARACTERISTICS="+quote(strings.Join(cs, ",")))
+		}
+		as = append(as, "URI="+quote(r.URI))
+
+		// Append line
+		ls = append(ls, "#EXT-X-MEDIA:"+strings.Join(as, ","))
+	}
+	return
+}
+
+// String returns the EXT-X-MEDIA lines joined with line breaks
+func (g Group) String() string {
+	ls, err := g.Lines()
+	if err != nil {
+		return ""
+	}
+	return strings.Join(ls, "\n")
+}
+
+// StreamInfAttribute returns the attribute to add to the EXT-X-STREAM-INF tags that should use the group
+func (g Group) StreamInfAttribute() string {
+	return "SUBTITLES=" + quote(g.id())
+}
+
+// quote returns a quoted string. Quoted strings can't contain double quotes nor line breaks.
+func quote(s string) string {
+	return "\"" + strings.NewReplacer("\"", "'", "\n", " ", "\r", " ").Replace(s) + "\""
+}
+
+// yesNo returns an enumerated string
+func yesNo(b bool) string {
+	if b {
+		return "YES"
+	}
+	return "NO"
+}
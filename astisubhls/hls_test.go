@@ -0,0 +1,41 @@
+package astisubhls_test
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubhls"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	g := astisubhls.Group{Renditions: []astisubhls.Rendition{
+		{Default: true, Language: astisub.LanguageFrench, Name: "Français", URI: "fr/index.m3u8"},
+		{Characteristics: []string{"public.accessibility.transcribes-spoken-dialog", "public.accessibility.describes-music-and-sound"}, Language: "en", Name: "English \"SDH\"", URI: "en-sdh/index.m3u8"},
+		{Forced: true, Language: "en", URI: "en-forced/index.m3u8"},
+		{Kind: astisub.TrackKindDescriptions, Language: "en", Name: "English AD", URI: "en-ad/index.m3u8"},
+		{Kind: astisub.TrackKindForced, Language: "fr", Name: "Français forcé", URI: "fr-forced/index.m3u8"},
+	}}
+	ls, err := g.Lines()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Français",LANGUAGE="fr",DEFAULT=YES,AUTOSELECT=YES,FORCED=NO,URI="fr/index.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English 'SDH'",LANGUAGE="en",DEFAULT=NO,AUTOSELECT=YES,FORCED=NO,CHARACTERISTICS="public.accessibility.transcribes-spoken-dialog,public.accessibility.describes-music-and-sound",URI="en-sdh/index.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="en",LANGUAGE="en",DEFAULT=NO,AUTOSELECT=YES,FORCED=YES,URI="en-forced/index.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="English AD",LANGUAGE="en",DEFAULT=NO,AUTOSELECT=YES,FORCED=NO,CHARACTERISTICS="public.accessibility.describes-video",URI="en-ad/index.m3u8"`,
+		`#EXT-X-MEDIA:TYPE=SUBTITLES,GROUP-ID="subs",NAME="Français forcé",LANGUAGE="fr",DEFAULT=NO,AUTOSELECT=YES,FORCED=YES,URI="fr-forced/index.m3u8"`,
+	}, ls)
+	assert.Equal(t, `SUBTITLES="subs"`, g.StreamInfAttribute())
+	assert.Equal(t, `SUBTITLES="cc"`, astisubhls.Group{ID: "cc"}.StreamInfAttribute())
+
+	// Errors
+	_, err = astisubhls.Group{Renditions: []astisubhls.Rendition{{Language: "en"}}}.Lines()
+	assert.Equal(t, astisubhls.ErrNoURI, errors.Cause(err))
+	_, err = astisubhls.Group{Renditions: []astisubhls.Rendition{{URI: "a"}}}.Lines()
+	assert.Equal(t, astisubhls.ErrNoNameOrLanguage, errors.Cause(err))
+	_, err = astisubhls.Group{Renditions: []astisubhls.Rendition{{Language: "en", URI: "a"}, {Language: "en", URI: "b"}}}.Lines()
+	assert.Equal(t, astisubhls.ErrDuplicateName, errors.Cause(err))
+	_, err = astisubhls.Group{Renditions: []astisubhls.Rendition{{Default: true, Language: "en", URI: "a"}, {Default: true, Language: "fr", URI: "b"}}}.Lines()
+	assert.Equal(t, astisubhls.ErrSeveralDefaults, errors.Cause(err))
+}
@@ -0,0 +1,167 @@
+package astisubhttp
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Default max upload size
+const defaultMaxUploadSize = 32 << 20
+
+// Form file name
+const formFileName = "file"
+
+// Content types
+var contentTypes = map[string]string{
+	"ass":  "text/x-ssa; charset=utf-8",
+	"json": "application/json; charset=utf-8",
+	"sbv":  "text/plain; charset=utf-8",
+	"srt":  "application/x-subrip; charset=utf-8",
+	"ssa":  "text/x-ssa; charset=utf-8",
+	"stl":  "application/octet-stream",
+	"sub":  "text/plain; charset=utf-8",
+	"ttml": "application/ttml+xml; charset=utf-8",
+	"vtt":  "text/vtt; charset=utf-8",
+}
+
+// Profile adapts subtitles before they are written
+type Profile func(s *astisub.Subtitles) error
+
+// DefaultProfiles are the profiles available when none have been provided
+var DefaultProfiles = map[string]Profile{
+	"optimize": func(s *astisub.Subtitles) error {
+		s.Optimize()
+		return nil
+	},
+	"plain": func(s *astisub.Subtitles) error {
+		s.RemoveStyling()
+		return nil
+	},
+	"unfragment": func(s *astisub.Subtitles) error {
+		s.Unfragment()
+		return nil
+	},
+}
+
+// ConvertHandler is an http.Handler that converts an uploaded subtitle to the format requested in the "format"
+// query param.
+// The subtitle can either be uploaded as the "file" field of a multipart form, in which case its format is
+// guessed from its filename, or as the raw request body, in which case the "from" query param is mandatory.
+// The optional "profile" query param indicates which profile should be applied before writing.
+type ConvertHandler struct {
+	MaxUploadSize int64
+	Profiles      map[string]Profile
+	Teletext      astisub.TeletextOptions
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h ConvertHandler) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	// Only POST and PUT are allowed
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		rw.Header().Set("Allow", "POST, PUT")
+		http.Error(rw, "astisubhttp: method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Get output format
+	var q = r.URL.Query()
+	var to = strings.ToLower(q.Get("format"))
+	if _, ok := contentTypes[to]; !ok {
+		http.Error(rw, fmt.Sprintf("astisubhttp: invalid format %s", to), http.StatusBadRequest)
+		return
+	}
+	toFormat, _ := astisub.FormatFromExtension("." + to)
+
+	// Get profile
+	var p Profile
+	if n := q.Get("profile"); len(n) > 0 {
+		var ps = h.Profiles
+		if ps == nil {
+			ps = DefaultProfiles
+		}
+		var ok bool
+		if p, ok = ps[n]; !ok {
+			http.Error(rw, fmt.Sprintf("astisubhttp: invalid profile %s", n), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Limit upload size
+	var max = h.MaxUploadSize
+	if max <= 0 {
+		max = defaultMaxUploadSize
+	}
+	r.Body = http.MaxBytesReader(rw, r.Body, max)
+
+	// Get input
+	var i io.Reader
+	var from, name = strings.ToLower(q.Get("from")), "subtitles"
+	if mt, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mt == "multipart/form-data" {
+		// Get form file
+		f, fh, err := r.FormFile(formFileName)
+		if err != nil {
+			http.Error(rw, fmt.Sprintf("astisubhttp: getting form file %s failed: %s", formFileName, err), http.StatusBadRequest)
+			return
+		}
+		defer f.Close()
+		i = f
+
+		// Get input format and name
+		var ext = filepath.Ext(fh.Filename)
+		if len(from) == 0 {
+			from = strings.ToLower(strings.TrimPrefix(ext, "."))
+		}
+		if n := strings.TrimSuffix(filepath.Base(fh.Filename), ext); len(n) > 0 && n != "." {
+			name = n
+		}
+	} else {
+		i = r.Body
+	}
+
+	// Get input format
+	fromFormat, err := astisub.FormatFromExtension("." + from)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Read
+	s, err := astisub.Read(i, fromFormat, astisub.Options{Teletext: h.Teletext})
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Apply profile
+	if p != nil {
+		if err = p(s); err != nil {
+			http.Error(rw, errors.Wrap(err, "astisubhttp: applying profile failed").Error(), http.StatusUnprocessableEntity)
+			return
+		}
+	}
+
+	// Write
+	// We write in a buffer first so that errors can still be reported properly
+	var buf = &bytes.Buffer{}
+	if err = s.WriteToFormat(buf, toFormat); err != nil {
+		var code = http.StatusInternalServerError
+		if err == astisub.ErrNoSubtitlesToWrite {
+			code = http.StatusUnprocessableEntity
+		}
+		http.Error(rw, err.Error(), code)
+		return
+	}
+
+	// Stream
+	rw.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + "." + to}))
+	rw.Header().Set("Content-Type", contentTypes[to])
+	buf.WriteTo(rw)
+}
@@ -0,0 +1,75 @@
+package astisubhttp_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubhttp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertHandler(t *testing.T) {
+	// Init
+	var h = astisubhttp.ConvertHandler{}
+	i, err := ioutil.ReadFile("../testdata/example-in.srt")
+	assert.NoError(t, err)
+	s, err := astisub.ReadFromSRT(bytes.NewReader(i))
+	assert.NoError(t, err)
+	var e = &bytes.Buffer{}
+	assert.NoError(t, s.WriteToWebVTT(e))
+
+	// Invalid method
+	rw := httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/?format=vtt", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+
+	// Invalid format
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?format=invalid&from=srt", bytes.NewReader(i)))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+	// Invalid profile
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?format=vtt&from=srt&profile=invalid", bytes.NewReader(i)))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+
+	// Unsupported input format
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?format=vtt&from=invalid", bytes.NewReader(i)))
+	assert.Equal(t, http.StatusUnsupportedMediaType, rw.Code)
+
+	// Raw body
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?format=vtt&from=srt", bytes.NewReader(i)))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "text/vtt; charset=utf-8", rw.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=subtitles.vtt", rw.Header().Get("Content-Disposition"))
+	assert.Equal(t, e.String(), rw.Body.String())
+
+	// JSON
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/?format=json&from=srt", bytes.NewReader(i)))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "application/json; charset=utf-8", rw.Header().Get("Content-Type"))
+
+	// Multipart form
+	var b = &bytes.Buffer{}
+	mw := multipart.NewWriter(b)
+	fw, err := mw.CreateFormFile("file", "example-in.srt")
+	assert.NoError(t, err)
+	_, err = fw.Write(i)
+	assert.NoError(t, err)
+	assert.NoError(t, mw.Close())
+	r := httptest.NewRequest(http.MethodPost, "/?format=vtt&profile=optimize", b)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	rw = httptest.NewRecorder()
+	h.ServeHTTP(rw, r)
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, "attachment; filename=example-in.vtt", rw.Header().Get("Content-Disposition"))
+	assert.Equal(t, e.String(), rw.Body.String())
+}
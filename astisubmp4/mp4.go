@@ -0,0 +1,217 @@
+package astisubmp4
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrSampleDurationOverflow = errors.New("astisubmp4: sample duration overflows 32 bits")
+)
+
+// Sample entry types
+const (
+	SampleEntryTypeTX3G = "tx3g"
+	SampleEntryTypeWVTT = "wvtt"
+)
+
+// Default tx3g values
+const (
+	tx3gDefaultFontID   = 1
+	tx3gDefaultFontName = "Serif"
+	tx3gDefaultFontSize = 18
+)
+
+// Track represents a caption track ready to be muxed: samples are contiguous, start at 0 and gaps between items are
+// filled with empty samples, which is what most muxers and players expect
+type Track struct {
+	SampleEntry     []byte // Serialized sample entry box, to be added to the stsd box
+	SampleEntryType string
+	Samples         []Sample
+	Timescale       uint32
+}
+
+// Sample represents a track sample
+type Sample struct {
+	Data     []byte
+	Duration uint32 // In timescale units
+}
+
+// TX3G builds a 3GPP timed text track
+// Items overlapping each other are shown in the same sample since tx3g can't display several samples at once
+// https://www.etsi.org/deliver/etsi_ts/126200_126299/126245/
+func TX3G(s *astisub.Subtitles, timescale uint32) (t Track, err error) {
+	// Init
+	t = Track{
+		SampleEntry:     tx3gSampleEntry(),
+		SampleEntryType: SampleEntryTypeTX3G,
+		Timescale:       timescale,
+	}
+
+	// Build samples
+	t.Samples, err = samples(s, timescale, func(is []*astisub.Item) []byte {
+		// Get text
+		var ls []string
+		for _, i := range is {
+			for _, l := range i.Lines {
+				ls = append(ls, l.String())
+			}
+		}
+		var b = []byte(strings.Join(ls, "\n"))
+
+		// Text is prefixed by its length
+		var o = make([]byte, 2, 2+len(b))
+		binary.BigEndian.PutUint16(o, uint16(len(b)))
+		return append(o, b...)
+	})
+	return
+}
+
+// WVTT builds an ISO/IEC 14496-30 WebVTT track
+// Items overlapping each other are added as several cues of the same sample
+func WVTT(s *astisub.Subtitles, timescale uint32) (t Track, err error) {
+	// Init
+	t = Track{
+		SampleEntry:     wvttSampleEntry(),
+		SampleEntryType: SampleEntryTypeWVTT,
+		Timescale:       timescale,
+	}
+
+	// Build samples
+	t.Samples, err = samples(s, timescale, func(is []*astisub.Item) (o []byte) {
+		// Empty sample
+		if len(is) == 0 {
+			return box("vtte")
+		}
+
+		// Loop through items
+		for _, i := range is {
+			// Get text
+			var ls []string
+			for _, l := range i.Lines {
+				var t = l.String()
+				if len(l.VoiceName) > 0 {
+					t = "<v " + l.VoiceName + ">" + t
+				}
+				ls = append(ls, t)
+			}
+
+			// Append cue
+			o = append(o, box("vttc", box("payl", []byte(strings.Join(ls, "\n"))))...)
+		}
+		return
+	})
+	return
+}
+
+// samples splits the subtitles' timeline into intervals during which the items displayed don't change and creates a
+// sample for each of them
+func samples(s *astisub.Subtitles, timescale uint32, fn func(is []*astisub.Item) []byte) (ss []Sample, err error) {
+	// No subtitles to write
+	if s == nil || len(s.Items) == 0 {
+		err = astisub.ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Get boundaries
+	var m = map[time.Duration]bool{0: true}
+	for _, i := range s.Items {
+		if i.EndAt <= i.StartAt || i.EndAt <= 0 {
+			continue
+		}
+		if i.StartAt > 0 {
+			m[i.StartAt] = true
+		}
+		m[i.EndAt] = true
+	}
+	var bs []time.Duration
+	for b := range m {
+		bs = append(bs, b)
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+
+	// Loop through intervals
+	for idx := 0; idx < len(bs)-1; idx++ {
+		// Get items displayed during the interval
+		var is []*astisub.Item
+		for _, i := range s.Items {
+			if i.StartAt <= bs[idx] && i.EndAt >= bs[idx+1] {
+				is = append(is, i)
+			}
+		}
+
+		// Durations are computed from rounded boundaries so that errors don't add up. Boundaries are computed on 64
+		// bits so that they don't wrap around in long subtitles, however the trun box stores sample durations on 32
+		// bits.
+		var d = toTimescale(bs[idx+1], timescale) - toTimescale(bs[idx], timescale)
+		if d == 0 {
+			continue
+		} else if d > math.MaxUint32 {
+			err = errors.Wrapf(ErrSampleDurationOverflow, "astisubmp4: sample from %s to %s", bs[idx], bs[idx+1])
+			return
+		}
+
+		// Append sample
+		ss = append(ss, Sample{
+			Data:     fn(is),
+			Duration: uint32(d),
+		})
+	}
+	return
+}
+
+// toTimescale converts a positive duration to timescale units. Seconds and nanoseconds are converted separately so
+// that the product doesn't overflow.
+func toTimescale(d time.Duration, timescale uint32) uint64 {
+	return uint64(d/time.Second)*uint64(timescale) + (uint64(d%time.Second)*uint64(timescale)+uint64(time.Second)/2)/uint64(time.Second)
+}
+
+// box serializes a box
+func box(typ string, payloads ...[]byte) (o []byte) {
+	var l = 8
+	for _, p := range payloads {
+		l += len(p)
+	}
+	o = make([]byte, 8, l)
+	binary.BigEndian.PutUint32(o, uint32(l))
+	copy(o[4:], typ)
+	for _, p := range payloads {
+		o = append(o, p...)
+	}
+	return
+}
+
+// sampleEntryHeader returns the header shared by all sample entries: 6 reserved bytes and the data reference index
+func sampleEntryHeader() []byte {
+	return []byte{0, 0, 0, 0, 0, 0, 0, 1}
+}
+
+// tx3gSampleEntry builds a tx3g sample entry displaying white text centered at the bottom
+func tx3gSampleEntry() []byte {
+	var b = []byte{
+		0, 0, 0, 0, // Display flags
+		1,          // Horizontal justification: center
+		0xff,       // Vertical justification: bottom
+		0, 0, 0, 0, // Background color
+		0, 0, 0, 0, 0, 0, 0, 0, // Default text box
+		0, 0, 0, 0, // Start and end chars
+		0, tx3gDefaultFontID, // Font ID
+		0,                      // Face style flags
+		tx3gDefaultFontSize,    // Font size
+		0xff, 0xff, 0xff, 0xff, // Text color
+	}
+	var ftab = append([]byte{0, 1, 0, tx3gDefaultFontID, uint8(len(tx3gDefaultFontName))}, tx3gDefaultFontName...)
+	return box(SampleEntryTypeTX3G, sampleEntryHeader(), b, box("ftab", ftab))
+}
+
+// wvttSampleEntry builds a wvtt sample entry
+func wvttSampleEntry() []byte {
+	return box(SampleEntryTypeWVTT, sampleEntryHeader(), box("vttC", []byte("WEBVTT")))
+}
@@ -0,0 +1,65 @@
+package astisubmp4_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubmp4"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+var mockSubtitles = &astisub.Subtitles{Items: []*astisub.Item{
+	{StartAt: time.Second, EndAt: 3 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "ab"}}}}},
+	{StartAt: 2 * time.Second, EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "c"}}, VoiceName: "d"}}},
+}}
+
+func TestTX3G(t *testing.T) {
+	_, err := astisubmp4.TX3G(&astisub.Subtitles{}, 1000)
+	assert.Equal(t, astisub.ErrNoSubtitlesToWrite, err)
+	tr, err := astisubmp4.TX3G(mockSubtitles, 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, astisubmp4.SampleEntryTypeTX3G, tr.SampleEntryType)
+	assert.Equal(t, uint32(1000), tr.Timescale)
+	assert.Equal(t, "tx3g", string(tr.SampleEntry[4:8]))
+	assert.Equal(t, len(tr.SampleEntry), int(tr.SampleEntry[3]))
+	assert.Equal(t, []astisubmp4.Sample{
+		{Data: []byte{0, 0}, Duration: 1000},
+		{Data: []byte{0, 2, 'a', 'b'}, Duration: 1000},
+		{Data: []byte{0, 4, 'a', 'b', '\n', 'c'}, Duration: 1000},
+		{Data: []byte{0, 1, 'c'}, Duration: 1000},
+	}, tr.Samples)
+}
+
+func TestWVTT(t *testing.T) {
+	tr, err := astisubmp4.WVTT(mockSubtitles, 90000)
+	assert.NoError(t, err)
+	assert.Equal(t, astisubmp4.SampleEntryTypeWVTT, tr.SampleEntryType)
+	assert.Equal(t, []byte{0, 0, 0, 0x1e, 'w', 'v', 't', 't', 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0xe, 'v', 't', 't', 'C', 'W', 'E', 'B', 'V', 'T', 'T'}, tr.SampleEntry)
+	assert.Len(t, tr.Samples, 4)
+	assert.Equal(t, []byte{0, 0, 0, 8, 'v', 't', 't', 'e'}, tr.Samples[0].Data)
+	assert.Equal(t, uint32(90000), tr.Samples[0].Duration)
+	assert.Equal(t, []byte{0, 0, 0, 0x12, 'v', 't', 't', 'c', 0, 0, 0, 0xa, 'p', 'a', 'y', 'l', 'a', 'b'}, tr.Samples[1].Data)
+	assert.Equal(t, 0x12+0x16, len(tr.Samples[2].Data))
+	assert.Equal(t, "<v d>c", string(tr.Samples[3].Data[16:]))
+}
+
+func TestSamplesLong(t *testing.T) {
+	// Boundaries overflowing 32 bits
+	tr, err := astisubmp4.WVTT(&astisub.Subtitles{Items: []*astisub.Item{
+		{StartAt: 0, EndAt: 10 * time.Hour, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a"}}}}},
+		{StartAt: 10 * time.Hour, EndAt: 20 * time.Hour, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "b"}}}}},
+		{StartAt: 20 * time.Hour, EndAt: 20*time.Hour + time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "c"}}}}},
+	}}, 90000)
+	assert.NoError(t, err)
+	assert.Len(t, tr.Samples, 3)
+	assert.Equal(t, uint32(10*3600*90000), tr.Samples[1].Duration)
+	assert.Equal(t, uint32(90000), tr.Samples[2].Duration)
+
+	// Sample duration overflowing 32 bits
+	_, err = astisubmp4.WVTT(&astisub.Subtitles{Items: []*astisub.Item{
+		{StartAt: 14 * time.Hour, EndAt: 14*time.Hour + time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "a"}}}}},
+	}}, 90000)
+	assert.Equal(t, astisubmp4.ErrSampleDurationOverflow, errors.Cause(err))
+}
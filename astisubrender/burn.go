@@ -0,0 +1,237 @@
+package astisubrender
+
+import (
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Default burn plan font name
+const defaultBurnFontName = "Arial"
+
+// BurnPlanOptions represents burn plan options
+type BurnPlanOptions struct {
+	Color    color.Color // Defaults to white
+	FontName string      // Defaults to Arial
+	FontSize float64     // pixels, defaults to 5% of the height
+	Height   int
+	Margin   int // Defaults to 5% of the height
+	Width    int
+}
+
+func (o *BurnPlanOptions) defaults() {
+	if o.Color == nil {
+		o.Color = color.White
+	}
+	if len(o.FontName) == 0 {
+		o.FontName = defaultBurnFontName
+	}
+	if o.FontSize <= 0 {
+		o.FontSize = float64(o.Height) * 5 / 100
+	}
+	if o.Margin <= 0 {
+		o.Margin = o.Height * 5 / 100
+	}
+}
+
+// BurnPlan represents what to burn, and where, in a video of a specific resolution. Styles and positions are fully
+// resolved so that encoding farms can burn subtitles with their own text renderer instead of a full ASS renderer.
+type BurnPlan struct {
+	Cues   []BurnCue
+	Height int
+	Width  int
+}
+
+// BurnCue represents a burn plan cue
+type BurnCue struct {
+	Alignment int // Uses the numpad layout, e.g. 1 is bottom left and 9 is top right
+	EndAt     time.Duration
+	Lines     []BurnLine
+	StartAt   time.Duration
+	Text      string // Lines are separated by "\n"
+	X, Y      int    // Anchor point of the cue in the video, the alignment telling which point of the text box it is
+}
+
+// BurnLine represents a burn plan line
+type BurnLine struct {
+	Runs []BurnRun
+}
+
+// BurnRun represents a burn plan run, which is a piece of text sharing the same style
+type BurnRun struct {
+	Bold      bool
+	Color     string // e.g. "#ffffff"
+	FontName  string
+	FontSize  float64 // pixels
+	Italic    bool
+	Text      string
+	Underline bool
+}
+
+// NewBurnPlan resolves the style and position of every item for a target resolution. SSA coordinates and font sizes
+// are scaled from the script's resolution.
+func NewBurnPlan(s *astisub.Subtitles, o BurnPlanOptions) (p *BurnPlan, err error) {
+	// Validate resolution
+	if o.Width <= 0 || o.Height <= 0 {
+		err = ErrInvalidResolution
+		return
+	}
+
+	// Init
+	o.defaults()
+	p = &BurnPlan{
+		Height: o.Height,
+		Width:  o.Width,
+	}
+
+	// Get scale
+	var resX, resY = s.Metadata.SSAPlayRes()
+	var scaleX, scaleY = float64(o.Width) / float64(resX), float64(o.Height) / float64(resY)
+
+	// Loop through items
+	for _, i := range s.Items {
+		// Init cue
+		var c = BurnCue{
+			EndAt:   i.EndAt,
+			StartAt: i.StartAt,
+		}
+		c.X, c.Y, c.Alignment = burnPosition(i, o, scaleX, scaleY)
+
+		// Loop through lines
+		var texts []string
+		for _, l := range i.Lines {
+			texts = append(texts, l.String())
+			var bl BurnLine
+			for idx, li := range l.Items {
+				// Add space
+				var r = burnRun(i, li, o, scaleY)
+				if idx > 0 {
+					r.Text = " " + r.Text
+				}
+				bl.Runs = append(bl.Runs, r)
+			}
+			c.Lines = append(c.Lines, bl)
+		}
+		c.Text = strings.Join(texts, "\n")
+		p.Cues = append(p.Cues, c)
+	}
+	return
+}
+
+// burnRun resolves the style of a line item
+func burnRun(i *astisub.Item, li astisub.LineItem, o BurnPlanOptions, scaleY float64) (r BurnRun) {
+	// Init
+	var c = color.RGBAModel.Convert(textColor(i, li, Options{Color: o.Color})).(color.RGBA)
+	r = BurnRun{
+		Color:    fmt.Sprintf("#%.2x%.2x%.2x", c.R, c.G, c.B),
+		FontName: o.FontName,
+		FontSize: o.FontSize,
+		Text:     li.Text,
+	}
+
+	// Loop through style attributes by order of precedence
+	var bold, italic, underline, fontName, fontSize bool
+	for _, sa := range []*astisub.StyleAttributes{li.InlineStyle, styleAttributes(li.Style), i.InlineStyle, styleAttributes(i.Style)} {
+		if sa == nil {
+			continue
+		}
+		var g = sa.GenericAttributes(astisub.FormatSSA)
+		if !bold && g.Bold != nil {
+			r.Bold, bold = *g.Bold, true
+		}
+		if !italic && g.Italics != nil {
+			r.Italic, italic = *g.Italics, true
+		}
+		if !underline && g.Underline != nil {
+			r.Underline, underline = *g.Underline, true
+		}
+		if !fontName && len(sa.SSAFontName) > 0 {
+			r.FontName, fontName = sa.SSAFontName, true
+		}
+		if !fontSize && sa.SSAFontSize != nil {
+			r.FontSize, fontSize = math.Round(*sa.SSAFontSize*scaleY*100)/100, true
+		}
+	}
+	return
+}
+
+// burnPosition resolves the anchor point and the alignment of an item. SSA positions come first, then WebVTT
+// percentages, and items are otherwise aligned against the margins.
+func burnPosition(i *astisub.Item, o BurnPlanOptions, scaleX, scaleY float64) (x, y, alignment int) {
+	// Get SSA attributes
+	alignment = 2
+	var marginLeft, marginRight, marginVertical = o.Margin, o.Margin, o.Margin
+	var ssaAlignment bool
+	for _, sa := range []*astisub.StyleAttributes{styleAttributes(i.Style), i.InlineStyle} {
+		if sa == nil {
+			continue
+		}
+		if sa.SSAAlignment != nil && *sa.SSAAlignment >= 1 && *sa.SSAAlignment <= 9 {
+			alignment, ssaAlignment = *sa.SSAAlignment, true
+		}
+		// Margins equal to 0 mean the default margins are used
+		if sa.SSAMarginLeft != nil && *sa.SSAMarginLeft > 0 {
+			marginLeft = int(math.Round(float64(*sa.SSAMarginLeft) * scaleX))
+		}
+		if sa.SSAMarginRight != nil && *sa.SSAMarginRight > 0 {
+			marginRight = int(math.Round(float64(*sa.SSAMarginRight) * scaleX))
+		}
+		if sa.SSAMarginVertical != nil && *sa.SSAMarginVertical > 0 {
+			marginVertical = int(math.Round(float64(*sa.SSAMarginVertical) * scaleY))
+		}
+	}
+
+	// SSA position
+	if i.InlineStyle != nil && i.InlineStyle.SSAPosition != nil {
+		return int(math.Round(i.InlineStyle.SSAPosition.X * scaleX)), int(math.Round(i.InlineStyle.SSAPosition.Y * scaleY)), alignment
+	}
+
+	// WebVTT
+	if i.InlineStyle != nil {
+		if a, ok := i.InlineStyle.WebVTTAnchor(); ok && a.Position != nil {
+			if !ssaAlignment {
+				alignment = a.Alignment
+			}
+			return int(math.Round(*a.Position * float64(o.Width) / 100)), int(math.Round(a.Line * float64(o.Height) / 100)), alignment
+		}
+	}
+
+	// Horizontal alignment
+	switch (alignment - 1) % 3 {
+	case 0:
+		x = marginLeft
+	case 2:
+		x = o.Width - marginRight
+	default:
+		x = o.Width / 2
+	}
+
+	// Vertical alignment
+	switch (alignment - 1) / 3 {
+	case 1:
+		y = o.Height / 2
+	case 2:
+		y = marginVertical
+	default:
+		y = o.Height - marginVertical
+	}
+	return
+}
+
+// WriteJSON writes the burn plan in JSON
+func (p *BurnPlan) WriteJSON(w io.Writer) (err error) {
+	var e = json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err = e.Encode(p); err != nil {
+		err = errors.Wrap(err, "astisubrender: encoding json failed")
+		return
+	}
+	return
+}
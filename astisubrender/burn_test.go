@@ -0,0 +1,103 @@
+package astisubrender_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubrender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBurnPlan(t *testing.T) {
+	// Invalid resolution
+	_, err := astisubrender.NewBurnPlan(&astisub.Subtitles{}, astisubrender.BurnPlanOptions{})
+	assert.Equal(t, astisubrender.ErrInvalidResolution, err)
+
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[Script Info]
+ScriptType: v4.00+
+PlayResX: 384
+PlayResY: 288
+
+[V4+ Styles]
+Format: Name, Fontname, Fontsize, PrimaryColour, Alignment, MarginL, MarginR, MarginV
+Style: s,f,20,&H000000FF,2,10,10,8
+
+[Events]
+Format: Start, End, Style, Text
+Dialogue: 0:00:01.00,0:00:02.00,s,{\pos(192,144)\b1}1{\i1}2
+Dialogue: 0:00:02.00,0:00:03.00,s,3`))
+	assert.NoError(t, err)
+	s.Items = append(s.Items, &astisub.Item{
+		EndAt:       4 * time.Second,
+		InlineStyle: &astisub.StyleAttributes{WebVTTAlign: "left", WebVTTLine: "10%", WebVTTPosition: "20%"},
+		Lines:       []astisub.Line{{Items: []astisub.LineItem{{Text: "4"}}}},
+		StartAt:     3 * time.Second,
+	}, &astisub.Item{
+		EndAt:   5 * time.Second,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "5"}}}, {Items: []astisub.LineItem{{Text: "6"}}}},
+		StartAt: 4 * time.Second,
+	})
+
+	// Plan
+	p, err := astisubrender.NewBurnPlan(s, astisubrender.BurnPlanOptions{Height: 1080, Width: 1920})
+	assert.NoError(t, err)
+	assert.Equal(t, &astisubrender.BurnPlan{
+		Cues: []astisubrender.BurnCue{
+			{
+				Alignment: 2,
+				EndAt:     2 * time.Second,
+				Lines: []astisubrender.BurnLine{{Runs: []astisubrender.BurnRun{
+					{Bold: true, Color: "#ff0000", FontName: "f", FontSize: 75, Text: "1"},
+					{Bold: true, Color: "#ff0000", FontName: "f", FontSize: 75, Italic: true, Text: " 2"},
+				}}},
+				StartAt: time.Second,
+				Text:    "1 2",
+				X:       960,
+				Y:       540,
+			},
+			{
+				Alignment: 2,
+				EndAt:     3 * time.Second,
+				Lines:     []astisubrender.BurnLine{{Runs: []astisubrender.BurnRun{{Color: "#ff0000", FontName: "f", FontSize: 75, Text: "3"}}}},
+				StartAt:   2 * time.Second,
+				Text:      "3",
+				X:         960,
+				Y:         1050,
+			},
+			{
+				Alignment: 7,
+				EndAt:     4 * time.Second,
+				Lines:     []astisubrender.BurnLine{{Runs: []astisubrender.BurnRun{{Color: "#ffffff", FontName: "Arial", FontSize: 54, Text: "4"}}}},
+				StartAt:   3 * time.Second,
+				Text:      "4",
+				X:         384,
+				Y:         108,
+			},
+			{
+				Alignment: 2,
+				EndAt:     5 * time.Second,
+				Lines: []astisubrender.BurnLine{
+					{Runs: []astisubrender.BurnRun{{Color: "#ffffff", FontName: "Arial", FontSize: 54, Text: "5"}}},
+					{Runs: []astisubrender.BurnRun{{Color: "#ffffff", FontName: "Arial", FontSize: 54, Text: "6"}}},
+				},
+				StartAt: 4 * time.Second,
+				Text:    "5\n6",
+				X:       960,
+				Y:       1026,
+			},
+		},
+		Height: 1080,
+		Width:  1920,
+	}, p)
+
+	// JSON
+	w := &bytes.Buffer{}
+	err = p.WriteJSON(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `"Alignment": 7,`)
+	assert.Contains(t, w.String(), `"Color": "#ff0000",`)
+}
@@ -0,0 +1,313 @@
+package astisubrender
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+)
+
+// Package filenames
+const (
+	PackageFilenameBDNXML = "index.xml"
+	PackageFilenameJSON   = "index.json"
+)
+
+// Default package framerate
+const defaultPackageFramerate = 25
+
+// PackageOptions represents package options
+type PackageOptions struct {
+	Framerate float64 // Defaults to 25
+	Render    Options
+}
+
+// Package represents an image-based subtitle package: one image per item plus an index of timings and positions,
+// which is what DVD, Blu-ray and DVB bitmap authoring tools expect as input
+type Package struct {
+	Events    []PackageEvent
+	Framerate float64
+	Height    int
+	Language  string
+	Title     string
+	Width     int
+}
+
+// PackageEvent represents a package event
+type PackageEvent struct {
+	EndAt    time.Duration
+	Filename string
+	Image    image.Image `json:"-"`
+	StartAt  time.Duration
+	X, Y     int // Position of the image's top left corner in the video
+}
+
+// NewPackage rasterizes every item and crops the resulting images to their visible content
+// Items without visible content are skipped
+func NewPackage(s *astisub.Subtitles, o PackageOptions) (p *Package, err error) {
+	// Init
+	p = &Package{
+		Framerate: o.Framerate,
+		Height:    o.Render.Height,
+		Width:     o.Render.Width,
+	}
+	if p.Framerate <= 0 {
+		p.Framerate = defaultPackageFramerate
+	}
+	if s.Metadata != nil {
+		p.Language = s.Metadata.Language
+		p.Title = s.Metadata.Title
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		// Render
+		var img *image.RGBA
+		if img, err = Render(i, o.Render); err != nil {
+			err = errors.Wrap(err, "astisubrender: rendering failed")
+			return
+		}
+
+		// Crop
+		var r = visibleBounds(img)
+		if r.Empty() {
+			continue
+		}
+
+		// Append event
+		p.Events = append(p.Events, PackageEvent{
+			EndAt:    i.EndAt,
+			Filename: fmt.Sprintf("%04d.png", len(p.Events)+1),
+			Image:    img.SubImage(r),
+			StartAt:  i.StartAt,
+			X:        r.Min.X,
+			Y:        r.Min.Y,
+		})
+	}
+	return
+}
+
+// visibleBounds returns the bounds of the non transparent pixels of an image
+func visibleBounds(img *image.RGBA) (r image.Rectangle) {
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			if img.RGBAAt(x, y).A > 0 {
+				r = r.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+	return
+}
+
+// WriteToDir writes images, the BDN XML index and the JSON index in a directory
+func (p *Package) WriteToDir(dir string) (err error) {
+	// Write images
+	for _, e := range p.Events {
+		if err = writeFile(filepath.Join(dir, e.Filename), func(w io.Writer) error { return png.Encode(w, e.Image) }); err != nil {
+			err = errors.Wrapf(err, "astisubrender: writing %s failed", e.Filename)
+			return
+		}
+	}
+
+	// Write indexes
+	if err = writeFile(filepath.Join(dir, PackageFilenameBDNXML), p.WriteBDNXML); err != nil {
+		err = errors.Wrapf(err, "astisubrender: writing %s failed", PackageFilenameBDNXML)
+		return
+	}
+	if err = writeFile(filepath.Join(dir, PackageFilenameJSON), p.WriteJSON); err != nil {
+		err = errors.Wrapf(err, "astisubrender: writing %s failed", PackageFilenameJSON)
+		return
+	}
+	return
+}
+
+// writeFile creates a file and writes in it
+func writeFile(path string, fn func(w io.Writer) error) (err error) {
+	// Create file
+	var f *os.File
+	if f, err = os.Create(path); err != nil {
+		err = errors.Wrapf(err, "astisubrender: creating %s failed", path)
+		return
+	}
+	defer f.Close()
+
+	// Write
+	if err = fn(f); err != nil {
+		return
+	}
+	return
+}
+
+// WriteJSON writes the package's JSON index
+func (p *Package) WriteJSON(w io.Writer) (err error) {
+	var e = json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	if err = e.Encode(p); err != nil {
+		err = errors.Wrap(err, "astisubrender: encoding json failed")
+		return
+	}
+	return
+}
+
+// WriteBDNXML writes the package's BDN XML index, the de facto standard of Blu-ray and DVD authoring tools
+func (p *Package) WriteBDNXML(w io.Writer) (err error) {
+	// Init
+	var b = bdn{
+		Description: bdnDescription{
+			Format: bdnFormat{
+				DropFrame:   "False",
+				FrameRate:   formatFramerate(p.Framerate),
+				VideoFormat: videoFormat(p.Height),
+			},
+			Language: bdnLanguage{Code: bdnLanguages[p.Language]},
+			Name:     bdnName{Title: p.Title},
+		},
+		Version: "0.93",
+	}
+	if len(b.Description.Language.Code) == 0 {
+		b.Description.Language.Code = "und"
+	}
+
+	// Loop through events
+	var contentOut time.Duration
+	for _, e := range p.Events {
+		var r = e.Image.Bounds()
+		b.Events = append(b.Events, bdnEvent{
+			Forced: "False",
+			Graphic: bdnGraphic{
+				Filename: e.Filename,
+				Height:   r.Dy(),
+				Width:    r.Dx(),
+				X:        e.X,
+				Y:        e.Y,
+			},
+			InTC:  p.timecode(e.StartAt),
+			OutTC: p.timecode(e.EndAt),
+		})
+		if e.EndAt > contentOut {
+			contentOut = e.EndAt
+		}
+	}
+
+	// Add events description
+	b.Description.Events = bdnEventsDescription{
+		ContentInTC:    p.timecode(0),
+		ContentOutTC:   p.timecode(contentOut),
+		NumberOfEvents: len(p.Events),
+		Type:           "Graphic",
+	}
+	if len(p.Events) > 0 {
+		b.Description.Events.FirstEventInTC = p.timecode(p.Events[0].StartAt)
+		b.Description.Events.LastEventOutTC = p.timecode(p.Events[len(p.Events)-1].EndAt)
+	}
+
+	// Write header
+	if _, err = io.WriteString(w, xml.Header); err != nil {
+		err = errors.Wrap(err, "astisubrender: writing header failed")
+		return
+	}
+
+	// Encode
+	var e = xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err = e.Encode(b); err != nil {
+		err = errors.Wrap(err, "astisubrender: encoding xml failed")
+		return
+	}
+	return
+}
+
+// timecode formats a duration as a non-drop-frame HH:MM:SS:FF timecode. NTSC rates such as 23.976 count frames at
+// their exact rate.
+func (p *Package) timecode(d time.Duration) string {
+	return astisub.NewTimecode(d, p.Framerate).String()
+}
+
+// formatFramerate formats a framerate the way BDN XML expects it
+func formatFramerate(f float64) string {
+	if f == math.Trunc(f) {
+		return fmt.Sprintf("%d", int(f))
+	}
+	return fmt.Sprintf("%.3f", f)
+}
+
+// videoFormat returns the BDN XML video format matching a height
+func videoFormat(height int) string {
+	switch height {
+	case 480:
+		return "480i"
+	case 576:
+		return "576i"
+	case 720:
+		return "720p"
+	}
+	return "1080p"
+}
+
+// BDN XML languages indexed by astisub language
+var bdnLanguages = map[string]string{
+	astisub.LanguageEnglish: "eng",
+	astisub.LanguageFrench:  "fra",
+}
+
+type bdn struct {
+	XMLName     xml.Name       `xml:"BDN"`
+	Version     string         `xml:"Version,attr"`
+	Description bdnDescription `xml:"Description"`
+	Events      []bdnEvent     `xml:"Events>Event"`
+}
+
+type bdnDescription struct {
+	Name     bdnName              `xml:"Name"`
+	Language bdnLanguage          `xml:"Language"`
+	Format   bdnFormat            `xml:"Format"`
+	Events   bdnEventsDescription `xml:"Events"`
+}
+
+type bdnName struct {
+	Content string `xml:"Content,attr"`
+	Title   string `xml:"Title,attr"`
+}
+
+type bdnLanguage struct {
+	Code string `xml:"Code,attr"`
+}
+
+type bdnFormat struct {
+	DropFrame   string `xml:"DropFrame,attr"`
+	FrameRate   string `xml:"FrameRate,attr"`
+	VideoFormat string `xml:"VideoFormat,attr"`
+}
+
+type bdnEventsDescription struct {
+	ContentInTC    string `xml:"ContentInTC,attr"`
+	ContentOutTC   string `xml:"ContentOutTC,attr"`
+	FirstEventInTC string `xml:"FirstEventInTC,attr"`
+	LastEventOutTC string `xml:"LastEventOutTC,attr"`
+	NumberOfEvents int    `xml:"NumberofEvents,attr"`
+	Type           string `xml:"Type,attr"`
+}
+
+type bdnEvent struct {
+	Forced  string     `xml:"Forced,attr"`
+	InTC    string     `xml:"InTC,attr"`
+	OutTC   string     `xml:"OutTC,attr"`
+	Graphic bdnGraphic `xml:"Graphic"`
+}
+
+type bdnGraphic struct {
+	Filename string `xml:",chardata"`
+	Height   int    `xml:"Height,attr"`
+	Width    int    `xml:"Width,attr"`
+	X        int    `xml:"X,attr"`
+	Y        int    `xml:"Y,attr"`
+}
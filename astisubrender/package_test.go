@@ -0,0 +1,61 @@
+package astisubrender_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubrender"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackage(t *testing.T) {
+	// Init
+	var s = &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}}, StartAt: time.Second},
+			{EndAt: 3 * time.Second, StartAt: 2 * time.Second},
+			{EndAt: 4*time.Second + 400*time.Millisecond, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}}, StartAt: 3 * time.Second},
+		},
+		Metadata: &astisub.Metadata{Language: astisub.LanguageFrench, Title: "title"},
+	}
+
+	// New package
+	p, err := astisubrender.NewPackage(s, astisubrender.PackageOptions{Render: astisubrender.Options{Height: 720, Width: 1280}})
+	assert.NoError(t, err)
+	assert.Len(t, p.Events, 2)
+	assert.Equal(t, "0002.png", p.Events[1].Filename)
+	var r = p.Events[0].Image.Bounds()
+	assert.Equal(t, r.Min.X, p.Events[0].X)
+	assert.True(t, r.Dx() > 0 && r.Dx() < 100)
+	assert.True(t, p.Events[0].Y > 600)
+
+	// BDN XML
+	var w = &bytes.Buffer{}
+	assert.NoError(t, p.WriteBDNXML(w))
+	assert.Contains(t, w.String(), `<Language Code="fra"></Language>`)
+	assert.Contains(t, w.String(), `<Format DropFrame="False" FrameRate="25" VideoFormat="720p"></Format>`)
+	assert.Contains(t, w.String(), `<Events ContentInTC="00:00:00:00" ContentOutTC="00:00:04:10" FirstEventInTC="00:00:01:00" LastEventOutTC="00:00:04:10" NumberofEvents="2" Type="Graphic"></Events>`)
+	assert.Contains(t, w.String(), `<Event Forced="False" InTC="00:00:03:00" OutTC="00:00:04:10">`)
+	assert.Contains(t, w.String(), `">0002.png</Graphic>`)
+
+	// NTSC framerate
+	p.Framerate = 23.976
+	w.Reset()
+	assert.NoError(t, p.WriteBDNXML(w))
+	assert.Contains(t, w.String(), `<Event Forced="False" InTC="00:00:02:23" OutTC="00:00:04:09">`)
+
+	// Directory
+	dir, err := ioutil.TempDir("", "astisubrender")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, p.WriteToDir(dir))
+	for _, n := range []string{"0001.png", "0002.png", astisubrender.PackageFilenameBDNXML, astisubrender.PackageFilenameJSON} {
+		_, err = os.Stat(filepath.Join(dir, n))
+		assert.NoError(t, err)
+	}
+}
@@ -0,0 +1,228 @@
+package astisubrender
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Errors
+var (
+	ErrInvalidResolution = errors.New("astisubrender: invalid resolution")
+)
+
+// Options represents render options
+type Options struct {
+	BackgroundColor color.Color // If nil, no box is drawn behind lines
+	Color           color.Color // Defaults to white
+	Face            font.Face   // Defaults to basicfont.Face7x13
+	Height          int
+	Margin          int         // Defaults to 5% of the height
+	OutlineColor    color.Color // Defaults to black
+	OutlineWidth    int         // Defaults to 1
+	Width           int
+}
+
+func (o *Options) defaults() {
+	if o.Color == nil {
+		o.Color = color.White
+	}
+	if o.Face == nil {
+		o.Face = basicfont.Face7x13
+	}
+	if o.Margin <= 0 {
+		o.Margin = o.Height * 5 / 100
+	}
+	if o.OutlineColor == nil {
+		o.OutlineColor = color.Black
+	}
+	if o.OutlineWidth <= 0 {
+		o.OutlineWidth = 1
+	}
+}
+
+// Alignments
+const (
+	alignBottom = iota
+	alignMiddle
+	alignTop
+)
+
+const (
+	alignCenter = iota
+	alignLeft
+	alignRight
+)
+
+// Render rasterizes an item on a transparent image
+// Lines are centered at the bottom of the image unless the item's styles indicate otherwise
+func Render(i *astisub.Item, o Options) (img *image.RGBA, err error) {
+	// Validate resolution
+	if o.Width <= 0 || o.Height <= 0 {
+		err = ErrInvalidResolution
+		return
+	}
+
+	// Init
+	o.defaults()
+	img = image.NewRGBA(image.Rect(0, 0, o.Width, o.Height))
+
+	// Draw
+	drawItem(img, i, o)
+	return
+}
+
+// RenderAt rasterizes all items displayed at a specific time on a transparent image, which comes in handy for
+// thumbnail previews
+func RenderAt(s *astisub.Subtitles, t time.Duration, o Options) (img *image.RGBA, err error) {
+	// Validate resolution
+	if o.Width <= 0 || o.Height <= 0 {
+		err = ErrInvalidResolution
+		return
+	}
+
+	// Init
+	o.defaults()
+	img = image.NewRGBA(image.Rect(0, 0, o.Width, o.Height))
+
+	// Loop through items
+	for _, i := range s.Items {
+		if i.StartAt <= t && i.EndAt > t {
+			drawItem(img, i, o)
+		}
+	}
+	return
+}
+
+// drawItem draws an item on an image
+func drawItem(img *image.RGBA, i *astisub.Item, o Options) {
+	// Get alignments
+	var h, v = alignments(i)
+
+	// Get metrics
+	var m = o.Face.Metrics()
+	var lineHeight = m.Height.Ceil()
+	var height = lineHeight * len(i.Lines)
+
+	// Get first baseline
+	var y int
+	switch v {
+	case alignTop:
+		y = o.Margin + m.Ascent.Ceil()
+	case alignMiddle:
+		y = (o.Height-height)/2 + m.Ascent.Ceil()
+	default:
+		y = o.Height - o.Margin - height + m.Ascent.Ceil()
+	}
+
+	// Loop through lines
+	for _, l := range i.Lines {
+		// Get width
+		var width fixed.Int26_6
+		for idx, li := range l.Items {
+			if idx > 0 {
+				width += font.MeasureString(o.Face, " ")
+			}
+			width += font.MeasureString(o.Face, li.Text)
+		}
+
+		// Get x
+		var x int
+		switch h {
+		case alignLeft:
+			x = o.Margin
+		case alignRight:
+			x = o.Width - o.Margin - width.Ceil()
+		default:
+			x = (o.Width - width.Ceil()) / 2
+		}
+
+		// Draw background
+		if o.BackgroundColor != nil {
+			draw.Draw(img, image.Rect(x-o.OutlineWidth, y-m.Ascent.Ceil(), x+width.Ceil()+o.OutlineWidth, y-m.Ascent.Ceil()+lineHeight), image.NewUniform(o.BackgroundColor), image.ZP, draw.Over)
+		}
+
+		// Loop through line items
+		var dot = fixed.P(x, y)
+		for idx, li := range l.Items {
+			// Add space
+			var text = li.Text
+			if idx > 0 {
+				text = " " + text
+			}
+
+			// Draw outline
+			for dx := -o.OutlineWidth; dx <= o.OutlineWidth; dx++ {
+				for dy := -o.OutlineWidth; dy <= o.OutlineWidth; dy++ {
+					if dx == 0 && dy == 0 {
+						continue
+					}
+					(&font.Drawer{Dst: img, Src: image.NewUniform(o.OutlineColor), Face: o.Face, Dot: dot.Add(fixed.P(dx, dy))}).DrawString(text)
+				}
+			}
+
+			// Draw text
+			var d = &font.Drawer{Dst: img, Src: image.NewUniform(textColor(i, li, o)), Face: o.Face, Dot: dot}
+			d.DrawString(text)
+			dot = d.Dot
+		}
+
+		// Next line
+		y += lineHeight
+	}
+}
+
+// alignments returns the horizontal and vertical alignments of an item based on its styles
+func alignments(i *astisub.Item) (h, v int) {
+	// Get SSA alignment, which uses the numpad layout
+	var a *int
+	for _, sa := range []*astisub.StyleAttributes{i.InlineStyle, styleAttributes(i.Style)} {
+		if sa != nil && sa.SSAAlignment != nil {
+			a = sa.SSAAlignment
+			break
+		}
+	}
+	if a == nil || *a < 1 || *a > 9 {
+		return
+	}
+	switch (*a - 1) % 3 {
+	case 0:
+		h = alignLeft
+	case 2:
+		h = alignRight
+	}
+	switch (*a - 1) / 3 {
+	case 1:
+		v = alignMiddle
+	case 2:
+		v = alignTop
+	}
+	return
+}
+
+// textColor returns the color of a line item based on its styles
+func textColor(i *astisub.Item, li astisub.LineItem, o Options) color.Color {
+	for _, sa := range []*astisub.StyleAttributes{li.InlineStyle, styleAttributes(li.Style), i.InlineStyle, styleAttributes(i.Style)} {
+		if sa == nil {
+			continue
+		}
+		if c := sa.GenericAttributes(astisub.FormatSSA).Color; c != nil {
+			return color.RGBA{R: c.Red, G: c.Green, B: c.Blue, A: 0xff}
+		}
+	}
+	return o.Color
+}
+
+func styleAttributes(s *astisub.Style) *astisub.StyleAttributes {
+	if s == nil {
+		return nil
+	}
+	return s.InlineStyle
+}
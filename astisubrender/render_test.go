@@ -0,0 +1,69 @@
+package astisubrender_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubrender"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+// opaque returns whether a rectangle of the image contains opaque pixels
+func opaque(img *image.RGBA, r image.Rectangle) bool {
+	for x := r.Min.X; x < r.Max.X; x++ {
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			if img.RGBAAt(x, y).A > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasColor returns whether the image contains a pixel of a specific color
+func hasColor(img *image.RGBA, c color.RGBA) bool {
+	for x := 0; x < img.Bounds().Dx(); x++ {
+		for y := 0; y < img.Bounds().Dy(); y++ {
+			if img.RGBAAt(x, y) == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestRender(t *testing.T) {
+	// Invalid resolution
+	var i = &astisub.Item{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}}}
+	_, err := astisubrender.Render(i, astisubrender.Options{})
+	assert.Equal(t, astisubrender.ErrInvalidResolution, err)
+
+	// Bottom center
+	img, err := astisubrender.Render(i, astisubrender.Options{Height: 100, Width: 100})
+	assert.NoError(t, err)
+	assert.True(t, opaque(img, image.Rect(30, 70, 70, 95)))
+	assert.False(t, opaque(img, image.Rect(0, 0, 100, 70)))
+	assert.True(t, hasColor(img, color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}))
+	assert.True(t, hasColor(img, color.RGBA{A: 0xff}))
+
+	// Top left
+	i.InlineStyle = &astisub.StyleAttributes{SSAAlignment: astiptr.Int(7)}
+	img, err = astisubrender.Render(i, astisubrender.Options{Height: 100, Width: 100})
+	assert.NoError(t, err)
+	assert.True(t, opaque(img, image.Rect(0, 0, 40, 30)))
+	assert.False(t, opaque(img, image.Rect(0, 30, 100, 100)))
+}
+
+func TestRenderAt(t *testing.T) {
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}}}}}
+	img, err := astisubrender.RenderAt(s, 0, astisubrender.Options{Height: 100, Width: 100})
+	assert.NoError(t, err)
+	assert.True(t, opaque(img, img.Bounds()))
+	img, err = astisubrender.RenderAt(s, time.Second, astisubrender.Options{Height: 100, Width: 100})
+	assert.NoError(t, err)
+	assert.False(t, opaque(img, img.Bounds()))
+}
@@ -0,0 +1,6 @@
+// Package astisubtesseract provides an astisub.OCR relying on tesseract through gosseract.
+//
+// Since gosseract requires cgo and the tesseract libraries, it's only built when the gosseract build tag is provided:
+//
+//	go build -tags gosseract
+package astisubtesseract
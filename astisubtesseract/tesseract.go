@@ -0,0 +1,89 @@
+//go:build gosseract
+// +build gosseract
+
+package astisubtesseract
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"strings"
+
+	"github.com/asticode/go-astisub"
+	"github.com/otiai10/gosseract/v2"
+	"github.com/pkg/errors"
+)
+
+// Tesseract languages indexed by astisub language, for languages whose traineddata isn't named after their ISO 639-2
+// code
+var languages = map[string]string{
+	astisub.LanguageChinese: "chi_sim",
+}
+
+// tesseractLanguage returns the tesseract language of an astisub language
+func tesseractLanguage(language string) (l string, ok bool) {
+	if l, ok = languages[language]; ok {
+		return
+	}
+	return astisub.LanguageISO6392(language)
+}
+
+// OCR is an astisub.OCR relying on tesseract
+type OCR struct {
+	// Tesseract languages used when the language is unknown. Defaults to "eng".
+	DefaultLanguages []string
+}
+
+// Recognize implements the astisub.OCR interface
+func (o OCR) Recognize(ctx context.Context, img image.Image, language string) (r astisub.OCRResult, err error) {
+	// Encode image
+	var buf = &bytes.Buffer{}
+	if err = png.Encode(buf, img); err != nil {
+		err = errors.Wrap(err, "astisubtesseract: encoding png failed")
+		return
+	}
+
+	// Create client
+	var c = gosseract.NewClient()
+	defer c.Close()
+
+	// Set language
+	var ls = o.DefaultLanguages
+	if l, ok := tesseractLanguage(language); ok {
+		ls = []string{l}
+	} else if len(ls) == 0 {
+		ls = []string{"eng"}
+	}
+	if err = c.SetLanguage(ls...); err != nil {
+		err = errors.Wrap(err, "astisubtesseract: setting language failed")
+		return
+	}
+
+	// Set image
+	if err = c.SetImageFromBytes(buf.Bytes()); err != nil {
+		err = errors.Wrap(err, "astisubtesseract: setting image failed")
+		return
+	}
+
+	// Get text
+	if r.Text, err = c.Text(); err != nil {
+		err = errors.Wrap(err, "astisubtesseract: getting text failed")
+		return
+	}
+	r.Text = strings.TrimSpace(r.Text)
+
+	// Get confidence, which is the average confidence of words
+	var bs []gosseract.BoundingBox
+	if bs, err = c.GetBoundingBoxes(gosseract.RIL_WORD); err != nil {
+		err = errors.Wrap(err, "astisubtesseract: getting bounding boxes failed")
+		return
+	}
+	if len(bs) > 0 {
+		for _, b := range bs {
+			r.Confidence += b.Confidence
+		}
+		r.Confidence /= float64(len(bs)) * 100
+	}
+	return
+}
@@ -0,0 +1,263 @@
+package astisubts
+
+import (
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astits"
+	"github.com/pkg/errors"
+)
+
+// Stream types
+const (
+	StreamTypeDVB      = "dvb"
+	StreamTypeTeletext = "teletext"
+)
+
+// Teletext types
+// http://www.etsi.org/deliver/etsi_en/300400_300499/300468/01.11.01_60/en_300468v011101p.pdf
+const (
+	teletextTypeSubtitlePage                = 0x2
+	teletextTypeSubtitlePageHearingImpaired = 0x5
+)
+
+// languageTag converts an ISO 639-2 code to a BCP-47 language tag, the code itself if its language is unknown
+func languageTag(code string) string {
+	if l, ok := astisub.LanguageFromISO6392(code); ok {
+		return astisub.LanguageTag(l)
+	}
+	return code
+}
+
+// setItemsLanguage sets the language of every item based on the ISO 639-2 code of their stream
+func setItemsLanguage(s *astisub.Subtitles, code string) {
+	if len(code) == 0 {
+		return
+	}
+	for _, i := range s.Items {
+		i.Language = languageTag(code)
+	}
+}
+
+// Stream represents a subtitle stream of a transport stream
+type Stream struct {
+	HearingImpaired bool
+	Language        string // ISO 639-2 code
+	Page            int    // Only for teletext streams
+	PID             uint16
+	Type            string
+}
+
+// Streams lists the teletext and DVB subtitle streams of a PMT
+func Streams(pmt *astits.PMTData) (ss []Stream) {
+	for _, es := range pmt.ElementaryStreams {
+		for _, d := range es.ElementaryStreamDescriptors {
+			switch d.Tag {
+			case astits.DescriptorTagSubtitling:
+				if d.Subtitling == nil {
+					continue
+				}
+				for _, i := range d.Subtitling.Items {
+					ss = append(ss, Stream{
+						// Subtitling types 0x20 to 0x24 are meant for the hard of hearing
+						HearingImpaired: i.Type >= 0x20 && i.Type <= 0x24,
+						Language:        string(i.Language),
+						PID:             es.ElementaryPID,
+						Type:            StreamTypeDVB,
+					})
+				}
+			case astits.DescriptorTagTeletext, astits.DescriptorTagVBITeletext:
+				var t = d.Teletext
+				if d.Tag == astits.DescriptorTagVBITeletext {
+					t = d.VBITeletext
+				}
+				if t == nil {
+					continue
+				}
+				for _, i := range t.Items {
+					if i.Type != teletextTypeSubtitlePage && i.Type != teletextTypeSubtitlePageHearingImpaired {
+						continue
+					}
+					ss = append(ss, Stream{
+						HearingImpaired: i.Type == teletextTypeSubtitlePageHearingImpaired,
+						Language:        string(i.Language),
+						Page:            teletextPage(i.Magazine, i.Page),
+						PID:             es.ElementaryPID,
+						Type:            StreamTypeTeletext,
+					})
+				}
+			}
+		}
+	}
+	return
+}
+
+// teletextPage converts a magazine number and a BCD page number to a page
+// Magazine 0 stands for magazine 8
+func teletextPage(magazine, page uint8) int {
+	if magazine == 0 {
+		magazine = 8
+	}
+	return int(magazine)*100 + int(page>>4)*10 + int(page&0xf)
+}
+
+// ReadTeletext extracts teletext subtitles from an existing demuxer in a single pass: the demuxer is never rewound.
+// If the PID option is not indicated, the first teletext subtitle stream found in the first PMT is used and teletext
+// data received before that PMT is buffered. ErrNoValidTeletextPID is returned as soon as that PMT turns out to list
+// no teletext subtitle stream. If the page option is not indicated either, the page of that stream is used.
+func ReadTeletext(dmx *astits.Demuxer, o astisub.TeletextOptions) (s *astisub.Subtitles, err error) {
+	// Read
+	var ts []*teletextTrack
+	if ts, err = readTeletext(dmx.NextData, o, false); err != nil {
+		return
+	}
+
+	// Get subtitles
+	s = ts[0].decoder.Subtitles()
+
+	// Add language
+	if ts[0].stream != nil {
+		if l, ok := astisub.LanguageFromISO6392(ts[0].stream.Language); ok {
+			s.Metadata = &astisub.Metadata{Language: l}
+		}
+		setItemsLanguage(s, ts[0].stream.Language)
+	}
+	return
+}
+
+// ReadTeletextSet extracts every teletext subtitle stream listed in the first PMT from an existing demuxer in a single
+// pass, which is how multi-language broadcasts carry their subtitles.
+func ReadTeletextSet(dmx *astits.Demuxer) (s *astisub.SubtitleSet, err error) {
+	// Read
+	var ts []*teletextTrack
+	if ts, err = readTeletext(dmx.NextData, astisub.TeletextOptions{}, true); err != nil {
+		return
+	}
+
+	// Loop through tracks
+	s = astisub.NewSubtitleSet()
+	for _, t := range ts {
+		// Create track
+		var tr = &astisub.Track{
+			Kind:      astisub.TrackKindSubtitles,
+			Language:  t.stream.Language,
+			Subtitles: t.decoder.Subtitles(),
+		}
+		if t.stream.HearingImpaired {
+			tr.Kind = astisub.TrackKindSDH
+		}
+
+		// Add language
+		if l, ok := astisub.LanguageFromISO6392(t.stream.Language); ok {
+			tr.Language = l
+			tr.Subtitles.Metadata = &astisub.Metadata{Language: l}
+		}
+		setItemsLanguage(tr.Subtitles, t.stream.Language)
+		s.Add(tr)
+	}
+	return
+}
+
+// teletextTrack represents a teletext stream being decoded
+type teletextTrack struct {
+	decoder *astisub.TeletextDecoder
+	pid     uint16
+	stream  *Stream // Nil if the PID option has been indicated
+}
+
+// readTeletext decodes teletext streams of a demuxer in a single pass
+// If all is true, every teletext subtitle stream of the first PMT is decoded. Otherwise only the stream matching the
+// options is.
+func readTeletext(next func() (*astits.Data, error), o astisub.TeletextOptions, all bool) (ts []*teletextTrack, err error) {
+	// PID is known
+	if !all && o.PID > 0 {
+		ts = append(ts, &teletextTrack{
+			decoder: astisub.NewTeletextDecoder(o.Page),
+			pid:     uint16(o.PID),
+		})
+	}
+
+	// Loop in data
+	var buf []*astits.Data
+	var d *astits.Data
+	for {
+		// Fetch next data
+		if d, err = next(); err != nil {
+			if err == astits.ErrNoMorePackets {
+				err = nil
+				break
+			}
+			err = errors.Wrap(err, "astisubts: fetching next data failed")
+			return
+		}
+
+		// PIDs are still unknown
+		if len(ts) == 0 {
+			// Buffer PES data that may be teletext until we know which PIDs to use
+			if d.PES != nil {
+				if d.PES.Header != nil && d.PES.Header.StreamID == astits.StreamIDPrivateStream1 {
+					buf = append(buf, d)
+				}
+				continue
+			}
+
+			// Not a PMT
+			if d.PMT == nil {
+				continue
+			}
+
+			// Get teletext streams
+			for _, v := range Streams(d.PMT) {
+				if v.Type != StreamTypeTeletext {
+					continue
+				}
+
+				// Get page
+				var st = v
+				var page = st.Page
+				if !all && o.Page > 0 {
+					page = o.Page
+				}
+
+				// Create track
+				ts = append(ts, &teletextTrack{
+					decoder: astisub.NewTeletextDecoder(page),
+					pid:     st.PID,
+					stream:  &st,
+				})
+				if !all {
+					break
+				}
+			}
+
+			// PMT has no teletext stream
+			if len(ts) == 0 {
+				break
+			}
+
+			// Decode buffered data, which drops data of PIDs the PMT doesn't announce as teletext
+			for _, b := range buf {
+				decodeTeletext(ts, b)
+			}
+			buf = nil
+			continue
+		}
+
+		// Decode
+		decodeTeletext(ts, d)
+	}
+
+	// No teletext stream
+	if len(ts) == 0 {
+		err = astisub.ErrNoValidTeletextPID
+		return
+	}
+	return
+}
+
+// decodeTeletext decodes data with the tracks whose PID matches
+func decodeTeletext(ts []*teletextTrack, d *astits.Data) {
+	for _, t := range ts {
+		if t.pid == d.PID {
+			t.decoder.Decode(d)
+		}
+	}
+}
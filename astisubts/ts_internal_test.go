@@ -0,0 +1,59 @@
+package astisubts
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetItemsLanguage(t *testing.T) {
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{}, {}}}
+	setItemsLanguage(s, "fre")
+	assert.Equal(t, "fr", s.Items[0].Language)
+	assert.Equal(t, "fr", s.Items[1].Language)
+	setItemsLanguage(s, "pol")
+	assert.Equal(t, "pol", s.Items[0].Language)
+}
+
+func TestReadTeletextPMT(t *testing.T) {
+	// Init
+	var next = func(ds []*astits.Data) func() (*astits.Data, error) {
+		return func() (d *astits.Data, err error) {
+			if len(ds) == 0 {
+				return nil, astits.ErrNoMorePackets
+			}
+			d, ds = ds[0], ds[1:]
+			return
+		}
+	}
+	var pes = func(pid uint16, streamID uint8) *astits.Data {
+		return &astits.Data{PES: &astits.PESData{Header: &astits.PESHeader{StreamID: streamID}}, PID: pid}
+	}
+	var video = &astits.PMTElementaryStream{ElementaryPID: 1, StreamType: astits.StreamTypeH264Video}
+
+	// PMT without teletext stream
+	var read int
+	var n = next([]*astits.Data{pes(1, 0xe0), {PMT: &astits.PMTData{ElementaryStreams: []*astits.PMTElementaryStream{video}}}, pes(1, 0xe0)})
+	_, err := readTeletext(func() (*astits.Data, error) {
+		read++
+		return n()
+	}, astisub.TeletextOptions{}, false)
+	assert.Equal(t, astisub.ErrNoValidTeletextPID, err)
+	assert.Equal(t, 2, read)
+
+	// PMT with teletext stream
+	ts, err := readTeletext(next([]*astits.Data{
+		pes(1, 0xe0),
+		pes(2, astits.StreamIDPrivateStream1),
+		{PMT: &astits.PMTData{ElementaryStreams: []*astits.PMTElementaryStream{video, {
+			ElementaryPID:               2,
+			ElementaryStreamDescriptors: []*astits.Descriptor{{Tag: astits.DescriptorTagTeletext, Teletext: &astits.DescriptorTeletext{Items: []*astits.DescriptorTeletextItem{{Language: []byte("fre"), Magazine: 8, Page: 0x88, Type: teletextTypeSubtitlePage}}}}},
+			StreamType:                  astits.StreamTypePrivateData,
+		}}}},
+	}), astisub.TeletextOptions{}, false)
+	assert.NoError(t, err)
+	assert.Len(t, ts, 1)
+	assert.Equal(t, uint16(2), ts[0].pid)
+}
@@ -0,0 +1,47 @@
+package astisubts_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astisub/astisubts"
+	"github.com/asticode/go-astits"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreams(t *testing.T) {
+	ss := astisubts.Streams(&astits.PMTData{ElementaryStreams: []*astits.PMTElementaryStream{
+		{ElementaryPID: 1, StreamType: astits.StreamTypeH264Video},
+		{ElementaryPID: 2, StreamType: astits.StreamTypePrivateData, ElementaryStreamDescriptors: []*astits.Descriptor{{
+			Tag: astits.DescriptorTagTeletext,
+			Teletext: &astits.DescriptorTeletext{Items: []*astits.DescriptorTeletextItem{
+				{Language: []byte("fre"), Magazine: 1, Type: 0x1},
+				{Language: []byte("fre"), Magazine: 0, Page: 0x88, Type: 0x2},
+				{Language: []byte("eng"), Magazine: 7, Page: 0x77, Type: 0x5},
+			}},
+		}}},
+		{ElementaryPID: 3, StreamType: astits.StreamTypePrivateData, ElementaryStreamDescriptors: []*astits.Descriptor{{
+			Tag: astits.DescriptorTagSubtitling,
+			Subtitling: &astits.DescriptorSubtitling{Items: []*astits.DescriptorSubtitlingItem{
+				{Language: []byte("eng"), Type: 0x20},
+			}},
+		}}},
+	}})
+	assert.Equal(t, []astisubts.Stream{
+		{Language: "fre", Page: 888, PID: 2, Type: astisubts.StreamTypeTeletext},
+		{HearingImpaired: true, Language: "eng", Page: 777, PID: 2, Type: astisubts.StreamTypeTeletext},
+		{HearingImpaired: true, Language: "eng", PID: 3, Type: astisubts.StreamTypeDVB},
+	}, ss)
+}
+
+func TestReadTeletext(t *testing.T) {
+	_, err := astisubts.ReadTeletext(astits.New(context.Background(), bytes.NewReader([]byte{})), astisub.TeletextOptions{})
+	assert.Equal(t, astisub.ErrNoValidTeletextPID, err)
+}
+
+func TestReadTeletextSet(t *testing.T) {
+	_, err := astisubts.ReadTeletextSet(astits.New(context.Background(), bytes.NewReader([]byte{})))
+	assert.Equal(t, astisub.ErrNoValidTeletextPID, err)
+}
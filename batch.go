@@ -0,0 +1,215 @@
+package astisub
+
+import (
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNoBatchOutput is returned when running a batch without output
+var ErrNoBatchOutput = errors.New("astisub: no batch output")
+
+// BatchOutput returns the writer a converted file is written to. p is the path of the input file, relative to its
+// file system, whose extension has been replaced with the output format's.
+type BatchOutput func(p string) (io.WriteCloser, error)
+
+// Batch converts many files concurrently with shared options
+type Batch struct {
+	Format Format // Output format
+	// Called after each conversion, from a single goroutine
+	OnProgress func(p BatchProgress)
+	Options    Options     // Shared options. Filename is ignored.
+	Output     BatchOutput // Mandatory
+	// Called on each file's subtitles before they're written, e.g. to optimize or retime them
+	Transform func(p string, s *Subtitles) error
+	Workers   int // Defaults to the number of CPUs
+}
+
+// BatchProgress represents the progress of a batch conversion
+type BatchProgress struct {
+	Done   int
+	Result BatchResult // Result of the last conversion
+	Total  int
+}
+
+// BatchResult represents the result of the conversion of a file
+type BatchResult struct {
+	Err    error
+	Input  string
+	Output string
+}
+
+// Run converts files of a file system matching glob patterns. Files matching several patterns are converted once.
+// Errors occurring while converting a file don't stop the batch and are reported in its result, results being
+// ordered by input path. Files that have not been converted when the context is cancelled are reported with the
+// context's error.
+func (b Batch) Run(ctx context.Context, fsys fs.FS, patterns ...string) (rs []BatchResult, err error) {
+	// No output
+	if b.Output == nil {
+		err = ErrNoBatchOutput
+		return
+	}
+
+	// Get inputs
+	var ps []string
+	var m = make(map[string]bool)
+	for _, pattern := range patterns {
+		var ms []string
+		if ms, err = fs.Glob(fsys, pattern); err != nil {
+			err = errors.Wrapf(err, "astisub: globbing %s failed", pattern)
+			return
+		}
+		for _, p := range ms {
+			if !m[p] {
+				m[p] = true
+				ps = append(ps, p)
+			}
+		}
+	}
+	sort.Strings(ps)
+
+	// Get number of workers
+	var workers = b.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Start workers
+	rs = make([]BatchResult, len(ps))
+	var idxs = make(chan int)
+	var done = make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for idx := range idxs {
+				rs[idx] = b.convert(ctx, fsys, ps[idx])
+				done <- idx
+			}
+		}()
+	}
+
+	// Dispatch inputs
+	go func() {
+		defer close(idxs)
+		for idx := range ps {
+			select {
+			case idxs <- idx:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Wait for workers
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	// Report progress
+	var n int
+	for idx := range done {
+		n++
+		if b.OnProgress != nil {
+			b.OnProgress(BatchProgress{Done: n, Result: rs[idx], Total: len(ps)})
+		}
+	}
+
+	// Report files that have not been converted
+	for idx, r := range rs {
+		if len(r.Input) == 0 {
+			rs[idx] = BatchResult{Err: ctx.Err(), Input: ps[idx]}
+		}
+	}
+	return
+}
+
+// convert converts a file
+func (b Batch) convert(ctx context.Context, fsys fs.FS, p string) (r BatchResult) {
+	// Init
+	r.Input = p
+	r.Output = strings.TrimSuffix(p, path.Ext(p)) + b.Format.Extension()
+
+	// Context is done
+	if r.Err = ctx.Err(); r.Err != nil {
+		return
+	}
+
+	// Convert
+	if r.Err = b.convertFile(fsys, r.Input, r.Output); r.Err != nil {
+		r.Err = errors.Wrapf(r.Err, "astisub: converting %s failed", p)
+	}
+	return
+}
+
+// convertFile converts a file to a path
+func (b Batch) convertFile(fsys fs.FS, src, dst string) (err error) {
+	// Read
+	var s *Subtitles
+	if s, err = b.read(fsys, src); err != nil {
+		return
+	}
+
+	// Transform
+	if b.Transform != nil {
+		if err = b.Transform(src, s); err != nil {
+			err = errors.Wrap(err, "astisub: transforming failed")
+			return
+		}
+	}
+
+	// Create output
+	var o io.WriteCloser
+	if o, err = b.Output(dst); err != nil {
+		err = errors.Wrapf(err, "astisub: creating output %s failed", dst)
+		return
+	}
+
+	// Write
+	if err = s.WriteToFormatWithOptions(o, b.Format, b.Options); err != nil {
+		o.Close()
+		err = errors.Wrapf(err, "astisub: writing %s failed", dst)
+		return
+	}
+
+	// Close output
+	if err = o.Close(); err != nil {
+		err = errors.Wrapf(err, "astisub: closing output %s failed", dst)
+		return
+	}
+	return
+}
+
+// read reads a file. The file is closed once read so that outputs can replace it, which happens when converting
+// files to their own format in place.
+func (b Batch) read(fsys fs.FS, p string) (s *Subtitles, err error) {
+	// Get format
+	var f Format
+	if f, err = FormatFromExtension(path.Ext(p)); err != nil {
+		return
+	}
+
+	// Open input
+	var i fs.File
+	if i, err = fsys.Open(p); err != nil {
+		err = errors.Wrapf(err, "astisub: opening %s failed", p)
+		return
+	}
+	defer i.Close()
+
+	// Read
+	if s, err = Read(i, f, b.Options); err != nil {
+		err = errors.Wrapf(err, "astisub: reading %s failed", p)
+		return
+	}
+	return
+}
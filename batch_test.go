@@ -0,0 +1,89 @@
+package astisub_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+type batchBuffer struct {
+	*bytes.Buffer
+}
+
+func (b batchBuffer) Close() error { return nil }
+
+func TestBatch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.srt":          {Data: []byte("1\n00:00:01,000 --> 00:00:02,000\nA\n")},
+		"dir/b.srt":      {Data: []byte("1\n00:00:03,000 --> 00:00:04,000\nB\n")},
+		"dir/invalid.xx": {Data: []byte("invalid")},
+	}
+	var m sync.Mutex
+	outputs := make(map[string]*bytes.Buffer)
+	var ps []astisub.BatchProgress
+	b := astisub.Batch{
+		Format: astisub.FormatWebVTT,
+		OnProgress: func(p astisub.BatchProgress) {
+			ps = append(ps, p)
+		},
+		Output: func(p string) (io.WriteCloser, error) {
+			m.Lock()
+			defer m.Unlock()
+			outputs[p] = &bytes.Buffer{}
+			return batchBuffer{Buffer: outputs[p]}, nil
+		},
+		Transform: func(p string, s *astisub.Subtitles) error {
+			s.Items[0].Lines[0].Items[0].Text += "!"
+			return nil
+		},
+		Workers: 2,
+	}
+	rs, err := b.Run(context.Background(), fsys, "*.srt", "dir/*", "dir/b.srt")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 3)
+	assert.Equal(t, "a.srt", rs[0].Input)
+	assert.Equal(t, "a.vtt", rs[0].Output)
+	assert.NoError(t, rs[0].Err)
+	assert.Equal(t, "dir/b.vtt", rs[1].Output)
+	assert.NoError(t, rs[1].Err)
+	assert.Equal(t, "dir/invalid.xx", rs[2].Input)
+	assert.Error(t, rs[2].Err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nA!\n", outputs["a.vtt"].String())
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:03.000 --> 00:00:04.000\nB!\n", outputs["dir/b.vtt"].String())
+	assert.Len(t, ps, 3)
+	assert.Equal(t, 3, ps[2].Done)
+	assert.Equal(t, 3, ps[2].Total)
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rs, err = b.Run(ctx, fsys, "*.srt")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	assert.Equal(t, context.Canceled, rs[0].Err)
+
+	// No output
+	b.Output = nil
+	_, err = b.Run(context.Background(), fsys, "*.srt")
+	assert.Equal(t, astisub.ErrNoBatchOutput, err)
+}
+
+func TestBatchInPlace(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a.srt"), []byte("1\n00:00:01,000 --> 00:00:02,000\nA\n"), 0644))
+	rs, err := astisub.Batch{Format: astisub.FormatSRT, Output: astisub.BatchOutputToDir(dir)}.Run(context.Background(), os.DirFS(dir), "*.srt")
+	assert.NoError(t, err)
+	assert.Len(t, rs, 1)
+	assert.NoError(t, rs[0].Err)
+	b, err := os.ReadFile(filepath.Join(dir, "a.srt"))
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\nA\n", string(b))
+}
@@ -0,0 +1,710 @@
+package astisub
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/asticode/go-astits"
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrNoValidCEA708PID = errors.New("astisub: no valid cea-708 PID")
+)
+
+// CEA-708 captions are carried in the picture user data of video streams as ATSC A/53 cc_data
+// https://en.wikipedia.org/wiki/CEA-708
+const (
+	cea708CCTypeDTVCCData     = 0x2
+	cea708CCTypeDTVCCStart    = 0x3
+	cea708CCTypeNTSCField1    = 0x0
+	cea708Columns             = 42
+	cea708PenWhite            = 0x3f
+	cea708Rows                = 15
+	cea708SEIPayloadTypeT35   = 4
+	cea708StreamTypeH265Video = 0x24
+	cea708UserDataTypeCCData  = 0x3
+	cea708Windows             = 8
+)
+
+// Vars
+var (
+	cea708T35Header = []byte{0xb5, 0x0, 0x31, 'G', 'A', '9', '4', cea708UserDataTypeCCData}
+	// CEA-708 G2 characters, other characters of the set being replaced with spaces
+	cea708G2Characters = map[byte]rune{
+		0x25: '…', 0x2a: 'Š', 0x2c: 'Œ', 0x30: '█', 0x31: '‘', 0x32: '’', 0x33: '“', 0x34: '”', 0x35: '•', 0x39: '™',
+		0x3a: 'š', 0x3c: 'œ', 0x3d: '℠', 0x3f: 'Ÿ', 0x76: '⅛', 0x77: '⅜', 0x78: '⅝', 0x79: '⅞', 0x7a: '│', 0x7b: '┐',
+		0x7c: '└', 0x7d: '─', 0x7e: '┘', 0x7f: '┌',
+	}
+)
+
+// CEA708Options represents CEA-708 options
+type CEA708Options struct {
+	// Decodes the CEA-608 captions embedded in the CEA-708 data instead of a CEA-708 service. Only the first caption
+	// channel (CC1) is decoded.
+	CEA608  bool
+	PID     int
+	Service int // Defaults to 1, the primary caption service
+}
+
+// ReadFromCEA708 parses the CEA-708 captions, or the CEA-608 captions they embed, of the H.264 or H.265 video stream
+// of a transport stream. If the PID option is not indicated, the first video stream is used.
+func ReadFromCEA708(r io.Reader, o CEA708Options) (s *Subtitles, err error) {
+	// Init
+	var dmx = astits.New(context.Background(), r)
+
+	// Get the video PID
+	var pid = uint16(o.PID)
+	if pid == 0 {
+		var ok bool
+		if pid, ok, err = pmtPID(dmx, func(es *astits.PMTElementaryStream) bool {
+			return es.StreamType == astits.StreamTypeH264Video || es.StreamType == cea708StreamTypeH265Video
+		}); err != nil {
+			err = errors.Wrap(err, "astisub: getting cea-708 PID failed")
+			return
+		} else if !ok {
+			err = ErrNoValidCEA708PID
+			return
+		}
+		astilog.Debugf("astisub: no cea-708 pid specified, using pid %d", pid)
+	}
+
+	// Create decoder
+	var cd = NewCEA708Decoder(o)
+
+	// Loop in data
+	var d *astits.Data
+	for {
+		// Fetch next data
+		if d, err = dmx.NextData(); err != nil {
+			if err == astits.ErrNoMorePackets {
+				err = nil
+				break
+			}
+			err = errors.Wrap(err, "astisub: fetching next data failed")
+			return
+		}
+
+		// This data is not of interest to us
+		if d.PID != pid {
+			continue
+		}
+
+		// Decode
+		cd.Decode(d)
+	}
+
+	// Get subtitles
+	s = cd.Subtitles()
+	return
+}
+
+// CEA708Decoder decodes the captions of a video stream one demuxed data at a time. Filtering data on the video PID is
+// up to the caller. Since pictures are not demuxed in presentation order, caption data is buffered until Subtitles is
+// called.
+type CEA708Decoder struct {
+	ds                  []cea708Data
+	firstTime, lastTime time.Time
+	o                   CEA708Options
+	previousTime        time.Time
+	rollovers           int
+}
+
+// cea708Data represents the cc_data triplets of a picture
+type cea708Data struct {
+	t  time.Time
+	ts [][3]byte
+}
+
+// NewCEA708Decoder creates a new CEA-708 decoder
+func NewCEA708Decoder(o CEA708Options) *CEA708Decoder {
+	if o.Service <= 0 {
+		o.Service = 1
+	}
+	return &CEA708Decoder{o: o}
+}
+
+// Decode decodes a demuxed data
+func (cd *CEA708Decoder) Decode(d *astits.Data) {
+	// We only parse PES data
+	if d.PES == nil || d.PES.Header == nil {
+		return
+	}
+
+	// Get time
+	t := teletextDataTime(d)
+	if t.IsZero() {
+		return
+	}
+	t = unwrapClockTime(t, &cd.previousTime, &cd.rollovers)
+
+	// First and last time
+	if cd.firstTime.IsZero() || cd.firstTime.After(t) {
+		cd.firstTime = t
+	}
+	if cd.lastTime.IsZero() || cd.lastTime.Before(t) {
+		cd.lastTime = t
+	}
+
+	// Get triplets
+	if ts := cea708Triplets(d.PES.Data); len(ts) > 0 {
+		cd.ds = append(cd.ds, cea708Data{t: t, ts: ts})
+	}
+}
+
+// Subtitles decodes the caption data in presentation order and returns the subtitles. It should be called once all
+// data has been decoded. Times are relative to the first time decoded.
+func (cd *CEA708Decoder) Subtitles() (s *Subtitles) {
+	// Order data
+	sort.SliceStable(cd.ds, func(a, b int) bool { return cd.ds[a].t.Before(cd.ds[b].t) })
+
+	// Init
+	s = NewSubtitles()
+	s.Metadata = &Metadata{Kind: TrackKindCaptions}
+	var r608 = &sccReader{channel: 1, o: s, row: sccRows - 1}
+	var r708 = newCEA708Service(s)
+
+	// Loop through data
+	var p []byte
+	for _, d := range cd.ds {
+		// Loop through triplets
+		r608.time = d.t.Sub(cd.firstTime)
+		r708.time = r608.time
+		for _, t := range d.ts {
+			var valid, typ = t[0]&0x4 > 0, t[0] & 0x3
+			switch {
+			case !valid:
+				continue
+			case typ == cea708CCTypeNTSCField1:
+				if cd.o.CEA608 {
+					r608.decode(uint16(t[1])<<8 | uint16(t[2]))
+				}
+			case typ == cea708CCTypeDTVCCStart:
+				// Previous packet is incomplete
+				if len(p) > 0 && !cd.o.CEA608 {
+					r708.parsePacket(p, cd.o.Service)
+				}
+				p = []byte{t[1], t[2]}
+			case typ == cea708CCTypeDTVCCData && len(p) > 0:
+				p = append(p, t[1], t[2])
+			}
+
+			// Packet is complete
+			if len(p) > 0 && len(p) >= cea708PacketSize(p[0]) {
+				if !cd.o.CEA608 {
+					r708.parsePacket(p[:cea708PacketSize(p[0])], cd.o.Service)
+				}
+				p = nil
+			}
+		}
+
+		// Update displayed item
+		if cd.o.CEA608 {
+			r608.update()
+		} else {
+			r708.update()
+		}
+	}
+
+	// Caption is still displayed
+	r608.time = cd.lastTime.Sub(cd.firstTime)
+	r708.time = r608.time
+	if r608.item != nil {
+		r608.endItem()
+	}
+	if r708.item != nil {
+		r708.item.EndAt = r708.time
+	}
+	cd.ds = nil
+	return
+}
+
+// cea708PacketSize returns the size of a DTVCC packet, header included, based on its header
+func cea708PacketSize(header byte) int {
+	if n := int(header & 0x3f); n > 0 {
+		return n * 2
+	}
+	return 128
+}
+
+// cea708Triplets returns the cc_data triplets found in the SEI NAL units of H.264 or H.265 video data
+func cea708Triplets(i []byte) (ts [][3]byte) {
+	// Loop through NAL units
+	for _, n := range bytes.Split(i, []byte{0x0, 0x0, 0x1}) {
+		// Get SEI payload
+		var sei []byte
+		switch {
+		case len(n) > 1 && n[0]&0x1f == 6:
+			// H.264 SEI
+			sei = n[1:]
+		case len(n) > 2 && (n[0]>>1)&0x3f >= 39 && (n[0]>>1)&0x3f <= 40:
+			// H.265 prefix or suffix SEI
+			sei = n[2:]
+		default:
+			continue
+		}
+		sei = bytes.Replace(sei, []byte{0x0, 0x0, 0x3}, []byte{0x0, 0x0}, -1)
+
+		// Loop through SEI messages
+		for len(sei) > 1 && sei[0] != 0x80 {
+			// Get payload type and size
+			var typ, size int
+			for len(sei) > 0 && sei[0] == 0xff {
+				typ += 0xff
+				sei = sei[1:]
+			}
+			if len(sei) == 0 {
+				break
+			}
+			typ += int(sei[0])
+			sei = sei[1:]
+			for len(sei) > 0 && sei[0] == 0xff {
+				size += 0xff
+				sei = sei[1:]
+			}
+			if len(sei) == 0 {
+				break
+			}
+			size += int(sei[0])
+			sei = sei[1:]
+			if size > len(sei) {
+				break
+			}
+			var b = sei[:size]
+			sei = sei[size:]
+
+			// Not caption data
+			if typ != cea708SEIPayloadTypeT35 || !bytes.HasPrefix(b, cea708T35Header) || len(b) < len(cea708T35Header)+2 {
+				continue
+			}
+			b = b[len(cea708T35Header):]
+
+			// Caption data is not processed
+			if b[0]&0x40 == 0 {
+				continue
+			}
+
+			// Loop through triplets
+			var count = int(b[0] & 0x1f)
+			for b = b[2:]; count > 0 && len(b) >= 3; count-- {
+				ts = append(ts, [3]byte{b[0], b[1], b[2]})
+				b = b[3:]
+			}
+		}
+	}
+	return
+}
+
+// cea708Service represents the decoder of a CEA-708 caption service
+type cea708Service struct {
+	current int // -1 if no window is selected
+	item    *Item
+	o       *Subtitles
+	shown   []Line
+	time    time.Duration
+	ws      [cea708Windows]*cea708Window
+}
+
+// cea708Window represents a CEA-708 window
+type cea708Window struct {
+	anchorHorizontal, anchorVertical int
+	cells                            [][]sccCell
+	column, row                      int
+	columns, rows                    int
+	pen                              cea708Pen
+	priority                         int
+	relative                         bool // Whether anchors are percentages
+	visible                          bool
+}
+
+// cea708Pen represents the pen attributes used to write characters
+type cea708Pen struct {
+	color     int // 2 bits per component, red being the most significant
+	italics   bool
+	underline bool
+}
+
+// newCEA708Service creates a new CEA-708 service decoder
+func newCEA708Service(o *Subtitles) *cea708Service {
+	return &cea708Service{current: -1, o: o}
+}
+
+// parsePacket parses a DTVCC packet and decodes the blocks of the service
+func (s *cea708Service) parsePacket(p []byte, service int) {
+	// Loop through service blocks
+	for p = p[1:]; len(p) > 0; {
+		// Get header
+		var n, size = int(p[0] >> 5), int(p[0] & 0x1f)
+		p = p[1:]
+		if n == 0 {
+			return
+		}
+		if n == 7 {
+			if len(p) == 0 {
+				return
+			}
+			n = int(p[0] & 0x3f)
+			p = p[1:]
+		}
+		if size > len(p) {
+			return
+		}
+
+		// Decode block
+		if n == service {
+			s.decode(p[:size])
+		}
+		p = p[size:]
+	}
+}
+
+// decode decodes a service block
+func (s *cea708Service) decode(b []byte) {
+	for len(b) > 0 {
+		var c = b[0]
+		var n = 1
+		switch {
+		case c == 0x10:
+			// Extended character sets
+			n = s.decodeExtended(b[1:]) + 1
+		case c < 0x20:
+			n = s.decodeC0(c)
+		case c < 0x7f:
+			s.write(rune(c))
+		case c == 0x7f:
+			s.write('♪')
+		case c < 0xa0:
+			n = s.decodeC1(c, b[1:])
+		default:
+			// G1 is Latin-1
+			s.write(rune(c))
+		}
+		if n > len(b) {
+			return
+		}
+		b = b[n:]
+	}
+}
+
+// decodeC0 decodes a C0 code and returns the number of bytes it uses
+func (s *cea708Service) decodeC0(c byte) int {
+	var w = s.window()
+	switch {
+	case c >= 0x18:
+		return 3
+	case c >= 0x11:
+		return 2
+	case w == nil:
+		return 1
+	case c == 0x08:
+		// Backspace
+		if w.column > 0 {
+			w.column--
+			w.set(sccCell{})
+		}
+	case c == 0x0c:
+		// Form feed
+		w.clear()
+	case c == 0x0d:
+		// Carriage return
+		w.column = 0
+		if w.row++; w.row >= w.rows {
+			w.cells = append(w.cells[1:], make([]sccCell, w.columns))
+			w.row = w.rows - 1
+		}
+	case c == 0x0e:
+		// Horizontal carriage return
+		w.column = 0
+		w.cells[w.row] = make([]sccCell, w.columns)
+	}
+	return 1
+}
+
+// decodeExtended decodes a code of the extended character sets and returns the number of bytes it uses
+func (s *cea708Service) decodeExtended(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	switch c := b[0]; {
+	case c < 0x08:
+		return 1
+	case c < 0x10:
+		return 2
+	case c < 0x18:
+		return 3
+	case c < 0x20:
+		return 4
+	case c < 0x80:
+		if r, ok := cea708G2Characters[c]; ok {
+			s.write(r)
+		} else {
+			s.write(' ')
+		}
+		return 1
+	case c < 0x88:
+		return 5
+	case c < 0x90:
+		return 6
+	case c < 0xa0:
+		// Variable length codes
+		if len(b) < 2 {
+			return len(b)
+		}
+		return int(b[1]&0x1f) + 2
+	case c == 0xa0:
+		// Closed caption icon
+		s.write('㏄')
+	}
+	return 1
+}
+
+// decodeC1 decodes a C1 code and returns the number of bytes it uses
+func (s *cea708Service) decodeC1(c byte, b []byte) (n int) {
+	// Get number of bytes
+	switch {
+	case c <= 0x87 || c == 0x8e || c == 0x8f || (c >= 0x93 && c <= 0x96):
+		n = 1
+	case c <= 0x8d:
+		n = 2
+	case c == 0x90 || c == 0x92:
+		n = 3
+	case c == 0x91:
+		n = 4
+	case c == 0x97:
+		n = 5
+	default:
+		n = 7
+	}
+	if len(b) < n-1 {
+		return
+	}
+
+	// Switch on code
+	var w = s.window()
+	switch {
+	case c <= 0x87:
+		// Set current window
+		if s.ws[c&0x7] != nil {
+			s.current = int(c & 0x7)
+		}
+	case c >= 0x88 && c <= 0x8c:
+		// Window commands
+		for idx := 0; idx < cea708Windows; idx++ {
+			if x := s.ws[idx]; x != nil && b[0]&(1<<uint(idx)) > 0 {
+				switch c {
+				case 0x88:
+					x.clear()
+				case 0x89:
+					x.visible = true
+				case 0x8a:
+					x.visible = false
+				case 0x8b:
+					x.visible = !x.visible
+				case 0x8c:
+					s.ws[idx] = nil
+					if s.current == idx {
+						s.current = -1
+					}
+				}
+			}
+		}
+	case c == 0x8f:
+		// Reset
+		s.current = -1
+		s.ws = [cea708Windows]*cea708Window{}
+	case c == 0x90 && w != nil:
+		// Set pen attributes
+		w.pen.italics = b[1]&0x80 > 0
+		w.pen.underline = b[1]&0x40 > 0
+	case c == 0x91 && w != nil:
+		// Set pen color, opacity being ignored
+		w.pen.color = int(b[0] & 0x3f)
+	case c == 0x92 && w != nil:
+		// Set pen location
+		w.row, w.column = int(b[0]&0xf), int(b[1]&0x3f)
+		if w.row >= w.rows {
+			w.row = w.rows - 1
+		}
+	case c >= 0x98:
+		s.defineWindow(int(c&0x7), b)
+	}
+	return
+}
+
+// defineWindow defines a window and makes it the current window. Content of existing windows is kept.
+func (s *cea708Service) defineWindow(idx int, b []byte) {
+	// Get dimensions
+	var rows, columns = int(b[3]&0xf) + 1, int(b[4]&0x3f) + 1
+	if rows > cea708Rows {
+		rows = cea708Rows
+	}
+	if columns > cea708Columns {
+		columns = cea708Columns
+	}
+
+	// Get window
+	var w = s.ws[idx]
+	if w == nil || w.rows != rows || w.columns != columns {
+		w = &cea708Window{columns: columns, pen: cea708Pen{color: cea708PenWhite}, rows: rows}
+		w.clear()
+		s.ws[idx] = w
+	}
+	s.current = idx
+
+	// Update attributes
+	w.anchorHorizontal = int(b[2])
+	w.anchorVertical = int(b[1] & 0x7f)
+	w.priority = int(b[0] & 0x7)
+	w.relative = b[1]&0x80 > 0
+	w.visible = b[0]&0x20 > 0
+}
+
+// window returns the current window
+func (s *cea708Service) window() *cea708Window {
+	if s.current < 0 {
+		return nil
+	}
+	return s.ws[s.current]
+}
+
+// write writes a character at the pen location of the current window
+func (s *cea708Service) write(r rune) {
+	if w := s.window(); w != nil {
+		w.set(sccCell{r: r, style: w.pen.style()})
+		if w.column < w.columns {
+			w.column++
+		}
+	}
+}
+
+// clear clears the window and moves the pen to its origin
+func (w *cea708Window) clear() {
+	w.cells = make([][]sccCell, w.rows)
+	for idx := range w.cells {
+		w.cells[idx] = make([]sccCell, w.columns)
+	}
+	w.column, w.row = 0, 0
+}
+
+// set sets the cell at the pen location, characters written past the last column being ignored
+func (w *cea708Window) set(c sccCell) {
+	if w.column < w.columns {
+		w.cells[w.row][w.column] = c
+	}
+}
+
+// style converts the pen attributes to a cell style, the color being stored as its index in a 64 colors palette
+func (p cea708Pen) style() sccStyle {
+	var color int
+	if p.color != cea708PenWhite {
+		color = p.color + 1
+	}
+	return sccStyle{color: color, italics: p.italics, underline: p.underline}
+}
+
+// update ends the item being displayed and starts a new one if the visible windows have changed
+func (s *cea708Service) update() {
+	// Get visible windows, ordered from top to bottom
+	var ws []*cea708Window
+	for _, w := range s.ws {
+		if w != nil && w.visible {
+			ws = append(ws, w)
+		}
+	}
+	sort.SliceStable(ws, func(a, b int) bool { return ws[a].verticalPosition() < ws[b].verticalPosition() })
+
+	// Get lines
+	var ls []Line
+	for _, w := range ws {
+		for _, row := range w.cells {
+			if l := cea708Line(row); len(l.Items) > 0 {
+				ls = append(ls, l)
+			}
+		}
+	}
+
+	// Nothing changed
+	if reflect.DeepEqual(ls, s.shown) {
+		return
+	}
+	s.shown = ls
+
+	// End item
+	if s.item != nil {
+		s.item.EndAt = s.time
+		s.item = nil
+	}
+
+	// Start item
+	if len(ls) > 0 {
+		s.item = &Item{Lines: ls, StartAt: s.time}
+		if row := ws[0].verticalPosition()*sccRows/100 + 1; row <= sccRows {
+			s.item.InlineStyle = &StyleAttributes{SCCRow: astiptr.Int(row)}
+			s.item.InlineStyle.propagateSCCAttributes()
+		}
+		s.o.Items = append(s.o.Items, s.item)
+	}
+}
+
+// verticalPosition returns the vertical position of the window's anchor as a percentage
+func (w *cea708Window) verticalPosition() int {
+	if w.relative {
+		return w.anchorVertical
+	}
+	// Absolute anchors range from 0 to 74
+	return w.anchorVertical * 100 / 75
+}
+
+// cea708Line converts a row of cells into a line whose line items group characters sharing the same style
+func cea708Line(row []sccCell) (l Line) {
+	var text []rune
+	var style sccStyle
+	var flush = func() {
+		if t := strings.Join(strings.Fields(string(text)), " "); len(t) > 0 {
+			l.Items = append(l.Items, LineItem{InlineStyle: style.cea708StyleAttributes(), Text: t})
+		}
+		text = nil
+	}
+	for _, c := range row {
+		if c.r == 0 {
+			text = append(text, ' ')
+			continue
+		}
+		if c.style != style && len(strings.TrimSpace(string(text))) > 0 {
+			flush()
+		}
+		style = c.style
+		text = append(text, c.r)
+	}
+	flush()
+	return
+}
+
+// cea708StyleAttributes returns the style attributes of a CEA-708 pen style, which are stored as SCC attributes since
+// both are caption formats
+func (s sccStyle) cea708StyleAttributes() (o *StyleAttributes) {
+	if s == (sccStyle{}) {
+		return
+	}
+	o = &StyleAttributes{}
+	if s.color > 0 {
+		var c = s.color - 1
+		o.SCCColor = &Color{Blue: uint8(c&0x3) * 85, Green: uint8(c>>2&0x3) * 85, Red: uint8(c>>4&0x3) * 85}
+	}
+	if s.italics {
+		o.SCCItalics = astiptr.Bool(true)
+	}
+	if s.underline {
+		o.SCCUnderline = astiptr.Bool(true)
+	}
+	o.propagateSCCAttributes()
+	return
+}
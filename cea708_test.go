@@ -0,0 +1,85 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astits"
+	"github.com/stretchr/testify/assert"
+)
+
+func cea708Data(at time.Duration, ts ...[3]byte) *astits.Data {
+	// Create cc_data
+	var p = []byte{0xb5, 0x0, 0x31, 'G', 'A', '9', '4', 0x3, 0x40 | byte(len(ts)), 0xff}
+	for _, t := range ts {
+		p = append(p, t[0], t[1], t[2])
+	}
+	p = append(p, 0xff)
+
+	// Create PES data holding an access unit delimiter and a SEI
+	var b = []byte{0x0, 0x0, 0x0, 0x1, 0x9, 0xf0, 0x0, 0x0, 0x1, 0x6, 0x4, byte(len(p))}
+	b = append(b, p...)
+	b = append(b, 0x80)
+	return &astits.Data{PES: &astits.PESData{
+		Data: b,
+		Header: &astits.PESHeader{
+			OptionalHeader: &astits.PESOptionalHeader{PTS: &astits.ClockReference{Base: int64(at / time.Second * 90000)}},
+			StreamID:       0xe0,
+		},
+	}}
+}
+
+func cea708Packet(bs ...byte) (ts [][3]byte) {
+	for idx := 0; idx < len(bs); idx += 2 {
+		var t = [3]byte{0xfe, bs[idx], 0x0}
+		if idx == 0 {
+			t[0] = 0xff
+		}
+		if idx+1 < len(bs) {
+			t[2] = bs[idx+1]
+		}
+		ts = append(ts, t)
+	}
+	return
+}
+
+func TestCEA708Decoder(t *testing.T) {
+	// CEA-708
+	cd := astisub.NewCEA708Decoder(astisub.CEA708Options{})
+	// Pictures are not demuxed in presentation order
+	cd.Decode(cea708Data(3*time.Second, cea708Packet(0x42, 0x22, 0x88, 0x1)...))
+	cd.Decode(cea708Data(time.Second, cea708Packet(
+		0xa, 0x32,
+		0x98, 0x20, 0xda, 0x32, 0x71, 0x1f, 0x9, // Define visible window 0 with 2 rows at 90%
+		0x91, 0x3c, 0x0, 0x0, // Yellow pen
+		'H', 'i', 0xd, // Text then carriage return
+		0x90, 0x5, 0x80, '!', // Italics
+	)...))
+	s := cd.Subtitles()
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, 2*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "Hi - !", s.Items[0].String())
+	assert.Equal(t, 14, *s.Items[0].InlineStyle.SCCRow)
+	assert.Equal(t, &astisub.Color{Green: 255, Red: 255}, s.Items[0].Lines[0].Items[0].InlineStyle.SCCColor)
+	assert.Nil(t, s.Items[0].Lines[0].Items[0].InlineStyle.SCCItalics)
+	assert.True(t, *s.Items[0].Lines[1].Items[0].InlineStyle.SCCItalics)
+
+	// CEA-608
+	cd = astisub.NewCEA708Decoder(astisub.CEA708Options{CEA608: true})
+	cd.Decode(cea708Data(time.Second, [3]byte{0xfc, 0x94, 0x20}, [3]byte{0xfc, 0x94, 0x20}, [3]byte{0xfc, 'O', 'K'}, [3]byte{0xfc, 0x94, 0x2f}))
+	cd.Decode(cea708Data(2*time.Second, [3]byte{0xfc, 0x94, 0x2c}))
+	cd.Decode(cea708Data(4*time.Second, [3]byte{0xfc, 0x80, 0x80}))
+	s = cd.Subtitles()
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "OK", s.Items[0].String())
+}
+
+func TestReadFromCEA708(t *testing.T) {
+	_, err := astisub.ReadFromCEA708(bytes.NewReader([]byte{}), astisub.CEA708Options{})
+	assert.Equal(t, astisub.ErrNoValidCEA708PID, err)
+}
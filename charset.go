@@ -0,0 +1,440 @@
+package astisub
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+// Charset represents the character encoding of a text content
+type Charset string
+
+// Charsets
+const (
+	CharsetISO88591    Charset = "iso-8859-1"
+	CharsetISO885915   Charset = "iso-8859-15"
+	CharsetISO88592    Charset = "iso-8859-2"
+	CharsetUTF16BE     Charset = "utf-16be"
+	CharsetUTF16LE     Charset = "utf-16le"
+	CharsetUTF8        Charset = "utf-8"
+	CharsetWindows1250 Charset = "windows-1250"
+	CharsetWindows1252 Charset = "windows-1252"
+)
+
+// Errors
+var (
+	ErrUnrepresentableCharacter = errors.New("astisub: unrepresentable character")
+	ErrUnsupportedCharset       = errors.New("astisub: unsupported charset")
+)
+
+// Vars
+var (
+	bytesBOMUTF16BE = []byte{0xfe, 0xff}
+	bytesBOMUTF16LE = []byte{0xff, 0xfe}
+	// Charsets single byte contents are detected among, by order of preference
+	charsetCandidates = []Charset{CharsetWindows1252, CharsetWindows1250, CharsetISO88592}
+	// Characters of the upper half of single byte charsets, ISO-8859-1 being left out since its characters are the
+	// code points of its bytes
+	charsetWindows1250 = &[128]rune{
+		0x20ac, 0xfffd, 0x201a, 0xfffd, 0x201e, 0x2026, 0x2020, 0x2021,
+		0xfffd, 0x2030, 0x0160, 0x2039, 0x015a, 0x0164, 0x017d, 0x0179,
+		0xfffd, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0xfffd, 0x2122, 0x0161, 0x203a, 0x015b, 0x0165, 0x017e, 0x017a,
+		0x00a0, 0x02c7, 0x02d8, 0x0141, 0x00a4, 0x0104, 0x00a6, 0x00a7,
+		0x00a8, 0x00a9, 0x015e, 0x00ab, 0x00ac, 0x00ad, 0x00ae, 0x017b,
+		0x00b0, 0x00b1, 0x02db, 0x0142, 0x00b4, 0x00b5, 0x00b6, 0x00b7,
+		0x00b8, 0x0105, 0x015f, 0x00bb, 0x013d, 0x02dd, 0x013e, 0x017c,
+		0x0154, 0x00c1, 0x00c2, 0x0102, 0x00c4, 0x0139, 0x0106, 0x00c7,
+		0x010c, 0x00c9, 0x0118, 0x00cb, 0x011a, 0x00cd, 0x00ce, 0x010e,
+		0x0110, 0x0143, 0x0147, 0x00d3, 0x00d4, 0x0150, 0x00d6, 0x00d7,
+		0x0158, 0x016e, 0x00da, 0x0170, 0x00dc, 0x00dd, 0x0162, 0x00df,
+		0x0155, 0x00e1, 0x00e2, 0x0103, 0x00e4, 0x013a, 0x0107, 0x00e7,
+		0x010d, 0x00e9, 0x0119, 0x00eb, 0x011b, 0x00ed, 0x00ee, 0x010f,
+		0x0111, 0x0144, 0x0148, 0x00f3, 0x00f4, 0x0151, 0x00f6, 0x00f7,
+		0x0159, 0x016f, 0x00fa, 0x0171, 0x00fc, 0x00fd, 0x0163, 0x02d9,
+	}
+	charsetWindows1252 = &[128]rune{
+		0x20ac, 0xfffd, 0x201a, 0x0192, 0x201e, 0x2026, 0x2020, 0x2021,
+		0x02c6, 0x2030, 0x0160, 0x2039, 0x0152, 0xfffd, 0x017d, 0xfffd,
+		0xfffd, 0x2018, 0x2019, 0x201c, 0x201d, 0x2022, 0x2013, 0x2014,
+		0x02dc, 0x2122, 0x0161, 0x203a, 0x0153, 0xfffd, 0x017e, 0x0178,
+		0x00a0, 0x00a1, 0x00a2, 0x00a3, 0x00a4, 0x00a5, 0x00a6, 0x00a7,
+		0x00a8, 0x00a9, 0x00aa, 0x00ab, 0x00ac, 0x00ad, 0x00ae, 0x00af,
+		0x00b0, 0x00b1, 0x00b2, 0x00b3, 0x00b4, 0x00b5, 0x00b6, 0x00b7,
+		0x00b8, 0x00b9, 0x00ba, 0x00bb, 0x00bc, 0x00bd, 0x00be, 0x00bf,
+		0x00c0, 0x00c1, 0x00c2, 0x00c3, 0x00c4, 0x00c5, 0x00c6, 0x00c7,
+		0x00c8, 0x00c9, 0x00ca, 0x00cb, 0x00cc, 0x00cd, 0x00ce, 0x00cf,
+		0x00d0, 0x00d1, 0x00d2, 0x00d3, 0x00d4, 0x00d5, 0x00d6, 0x00d7,
+		0x00d8, 0x00d9, 0x00da, 0x00db, 0x00dc, 0x00dd, 0x00de, 0x00df,
+		0x00e0, 0x00e1, 0x00e2, 0x00e3, 0x00e4, 0x00e5, 0x00e6, 0x00e7,
+		0x00e8, 0x00e9, 0x00ea, 0x00eb, 0x00ec, 0x00ed, 0x00ee, 0x00ef,
+		0x00f0, 0x00f1, 0x00f2, 0x00f3, 0x00f4, 0x00f5, 0x00f6, 0x00f7,
+		0x00f8, 0x00f9, 0x00fa, 0x00fb, 0x00fc, 0x00fd, 0x00fe, 0x00ff,
+	}
+	charsetISO88592 = &[128]rune{
+		0x0080, 0x0081, 0x0082, 0x0083, 0x0084, 0x0085, 0x0086, 0x0087,
+		0x0088, 0x0089, 0x008a, 0x008b, 0x008c, 0x008d, 0x008e, 0x008f,
+		0x0090, 0x0091, 0x0092, 0x0093, 0x0094, 0x0095, 0x0096, 0x0097,
+		0x0098, 0x0099, 0x009a, 0x009b, 0x009c, 0x009d, 0x009e, 0x009f,
+		0x00a0, 0x0104, 0x02d8, 0x0141, 0x00a4, 0x013d, 0x015a, 0x00a7,
+		0x00a8, 0x0160, 0x015e, 0x0164, 0x0179, 0x00ad, 0x017d, 0x017b,
+		0x00b0, 0x0105, 0x02db, 0x0142, 0x00b4, 0x013e, 0x015b, 0x02c7,
+		0x00b8, 0x0161, 0x015f, 0x0165, 0x017a, 0x02dd, 0x017e, 0x017c,
+		0x0154, 0x00c1, 0x00c2, 0x0102, 0x00c4, 0x0139, 0x0106, 0x00c7,
+		0x010c, 0x00c9, 0x0118, 0x00cb, 0x011a, 0x00cd, 0x00ce, 0x010e,
+		0x0110, 0x0143, 0x0147, 0x00d3, 0x00d4, 0x0150, 0x00d6, 0x00d7,
+		0x0158, 0x016e, 0x00da, 0x0170, 0x00dc, 0x00dd, 0x0162, 0x00df,
+		0x0155, 0x00e1, 0x00e2, 0x0103, 0x00e4, 0x013a, 0x0107, 0x00e7,
+		0x010d, 0x00e9, 0x0119, 0x00eb, 0x011b, 0x00ed, 0x00ee, 0x010f,
+		0x0111, 0x0144, 0x0148, 0x00f3, 0x00f4, 0x0151, 0x00f6, 0x00f7,
+		0x0159, 0x016f, 0x00fa, 0x0171, 0x00fc, 0x00fd, 0x0163, 0x02d9,
+	}
+	charsetISO885915 = &[128]rune{
+		0x0080, 0x0081, 0x0082, 0x0083, 0x0084, 0x0085, 0x0086, 0x0087,
+		0x0088, 0x0089, 0x008a, 0x008b, 0x008c, 0x008d, 0x008e, 0x008f,
+		0x0090, 0x0091, 0x0092, 0x0093, 0x0094, 0x0095, 0x0096, 0x0097,
+		0x0098, 0x0099, 0x009a, 0x009b, 0x009c, 0x009d, 0x009e, 0x009f,
+		0x00a0, 0x00a1, 0x00a2, 0x00a3, 0x20ac, 0x00a5, 0x0160, 0x00a7,
+		0x0161, 0x00a9, 0x00aa, 0x00ab, 0x00ac, 0x00ad, 0x00ae, 0x00af,
+		0x00b0, 0x00b1, 0x00b2, 0x00b3, 0x017d, 0x00b5, 0x00b6, 0x00b7,
+		0x017e, 0x00b9, 0x00ba, 0x00bb, 0x0152, 0x0153, 0x0178, 0x00bf,
+		0x00c0, 0x00c1, 0x00c2, 0x00c3, 0x00c4, 0x00c5, 0x00c6, 0x00c7,
+		0x00c8, 0x00c9, 0x00ca, 0x00cb, 0x00cc, 0x00cd, 0x00ce, 0x00cf,
+		0x00d0, 0x00d1, 0x00d2, 0x00d3, 0x00d4, 0x00d5, 0x00d6, 0x00d7,
+		0x00d8, 0x00d9, 0x00da, 0x00db, 0x00dc, 0x00dd, 0x00de, 0x00df,
+		0x00e0, 0x00e1, 0x00e2, 0x00e3, 0x00e4, 0x00e5, 0x00e6, 0x00e7,
+		0x00e8, 0x00e9, 0x00ea, 0x00eb, 0x00ec, 0x00ed, 0x00ee, 0x00ef,
+		0x00f0, 0x00f1, 0x00f2, 0x00f3, 0x00f4, 0x00f5, 0x00f6, 0x00f7,
+		0x00f8, 0x00f9, 0x00fa, 0x00fb, 0x00fc, 0x00fd, 0x00fe, 0x00ff,
+	}
+	charsetTables = map[Charset]*[128]rune{
+		CharsetISO88591:    nil,
+		CharsetISO885915:   charsetISO885915,
+		CharsetISO88592:    charsetISO88592,
+		CharsetWindows1250: charsetWindows1250,
+		CharsetWindows1252: charsetWindows1252,
+	}
+	// Charset aliases, lower cased, as found in XML declarations for instance
+	charsetAliases = map[string]Charset{
+		"cp1250":     CharsetWindows1250,
+		"cp1252":     CharsetWindows1252,
+		"iso_8859-1": CharsetISO88591,
+		"iso_8859-2": CharsetISO88592,
+		"latin1":     CharsetISO88591,
+		"latin2":     CharsetISO88592,
+		"latin9":     CharsetISO885915,
+		"utf8":       CharsetUTF8,
+	}
+)
+
+// charsetFromLabel returns the charset matching a label such as "ISO-8859-1" or "latin1"
+func charsetFromLabel(label string) (c Charset, err error) {
+	// Get charset
+	c = Charset(strings.ToLower(strings.TrimSpace(label)))
+	if v, ok := charsetAliases[string(c)]; ok {
+		c = v
+	}
+
+	// Check charset
+	if _, ok := charsetTables[c]; !ok && c != CharsetUTF8 && c != CharsetUTF16BE && c != CharsetUTF16LE {
+		err = errors.Wrapf(ErrUnsupportedCharset, "astisub: charset %s is not supported", label)
+		return
+	}
+	return
+}
+
+// DetectCharset detects the charset of the beginning of a content. BOMs are looked for first, then UTF-16 is
+// detected from the zero bytes of ASCII characters, then UTF-8 is detected if the content is valid UTF-8. Otherwise
+// the single byte charset among Windows-1252, Windows-1250 and ISO-8859-2 whose non-ASCII characters are the most
+// often letters when next to ASCII letters is returned. This is a heuristic: the charset should be provided when
+// known.
+func DetectCharset(b []byte) Charset {
+	// BOMs
+	switch {
+	case bytes.HasPrefix(b, BytesBOM):
+		return CharsetUTF8
+	case bytes.HasPrefix(b, bytesBOMUTF16BE):
+		return CharsetUTF16BE
+	case bytes.HasPrefix(b, bytesBOMUTF16LE):
+		return CharsetUTF16LE
+	}
+
+	// UTF-16 without BOM, ASCII characters having either their first or second byte set to 0
+	var zeros [2]int
+	for idx, v := range b {
+		if v == 0 {
+			zeros[idx%2]++
+		}
+	}
+	if n := len(b) / 2; n > 0 {
+		if zeros[0] > n/2 {
+			return CharsetUTF16BE
+		} else if zeros[1] > n/2 {
+			return CharsetUTF16LE
+		}
+	}
+
+	// UTF-8
+	if charsetValidUTF8(b) {
+		return CharsetUTF8
+	}
+
+	// Single byte charsets
+	var c, best = charsetCandidates[0], 0
+	for idx, v := range charsetCandidates {
+		if s := charsetScore(b, charsetTables[v]); idx == 0 || s > best {
+			c, best = v, s
+		}
+	}
+	return c
+}
+
+// charsetValidUTF8 checks whether a content is valid UTF-8, its last character possibly being truncated
+func charsetValidUTF8(b []byte) bool {
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			return !utf8.FullRune(b)
+		}
+		b = b[size:]
+	}
+	return true
+}
+
+// charsetRune returns the character of a byte in a single byte charset
+func charsetRune(v byte, t *[128]rune) rune {
+	if v < 0x80 || t == nil {
+		return rune(v)
+	}
+	return t[v-0x80]
+}
+
+// charsetScore scores how plausible a single byte charset is for a content. Non-ASCII characters next to ASCII
+// letters are expected to be letters too while control and undefined characters are not expected at all.
+func charsetScore(b []byte, t *[128]rune) (s int) {
+	var isLetter = func(idx int) bool {
+		return idx >= 0 && idx < len(b) && b[idx] < 0x80 && unicode.IsLetter(rune(b[idx]))
+	}
+	for idx, v := range b {
+		// ASCII
+		if v < 0x80 {
+			continue
+		}
+
+		// Score
+		var r = charsetRune(v, t)
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+			s -= 2
+		case !isLetter(idx-1) && !isLetter(idx+1):
+		case unicode.IsLetter(r):
+			s++
+		default:
+			s--
+		}
+	}
+	return
+}
+
+// decodeCharset converts a content from a charset to UTF-8. The BOM of the charset, if any, is removed.
+func decodeCharset(b []byte, c Charset) (o []byte, err error) {
+	switch c {
+	case CharsetUTF8:
+		o = bytes.TrimPrefix(b, BytesBOM)
+	case CharsetUTF16BE, CharsetUTF16LE:
+		// Get byte order
+		var bo binary.ByteOrder = binary.BigEndian
+		if c == CharsetUTF16LE {
+			bo = binary.LittleEndian
+		}
+
+		// Remove BOM
+		if bytes.HasPrefix(b, bytesBOMUTF16BE) || bytes.HasPrefix(b, bytesBOMUTF16LE) {
+			b = b[2:]
+		}
+
+		// Decode
+		var us = make([]uint16, len(b)/2)
+		for idx := range us {
+			us[idx] = bo.Uint16(b[2*idx:])
+		}
+		o = []byte(string(utf16.Decode(us)))
+	default:
+		// Get table
+		t, ok := charsetTables[c]
+		if !ok {
+			err = errors.Wrapf(ErrUnsupportedCharset, "astisub: charset %s is not supported", c)
+			return
+		}
+
+		// Decode
+		var buf = make([]rune, len(b))
+		for idx, v := range b {
+			buf[idx] = charsetRune(v, t)
+		}
+		o = []byte(string(buf))
+	}
+	return
+}
+
+// charsetBytes indexes the bytes of the non-ASCII characters of a single byte charset
+func charsetBytes(t *[128]rune) (m map[rune]byte) {
+	m = make(map[rune]byte)
+	for v := 0x80; v <= 0xff; v++ {
+		m[charsetRune(byte(v), t)] = byte(v)
+	}
+	delete(m, utf8.RuneError)
+	return
+}
+
+// charsetRepresentable returns a function checking whether a character exists in a charset
+func charsetRepresentable(c Charset) (fn func(r rune) bool, err error) {
+	// Unicode
+	switch c {
+	case "", CharsetUTF8, CharsetUTF16BE, CharsetUTF16LE:
+		return func(r rune) bool { return true }, nil
+	}
+
+	// Get table
+	t, ok := charsetTables[c]
+	if !ok {
+		err = errors.Wrapf(ErrUnsupportedCharset, "astisub: charset %s is not supported", c)
+		return
+	}
+
+	// Create function
+	var m = charsetBytes(t)
+	fn = func(r rune) bool {
+		if r < 0x80 {
+			return true
+		}
+		_, ok := m[r]
+		return ok
+	}
+	return
+}
+
+// encodeCharset converts an UTF-8 content to a charset, adding the BOM of the charset, if any, when bom is true.
+// ErrUnrepresentableCharacter is returned if a character doesn't exist in the charset.
+func encodeCharset(b []byte, c Charset, bom bool) (o []byte, err error) {
+	switch c {
+	case "", CharsetUTF8:
+		if bom {
+			o = append(o, BytesBOM...)
+		}
+		o = append(o, b...)
+	case CharsetUTF16BE, CharsetUTF16LE:
+		// Get byte order
+		var bo binary.ByteOrder = binary.BigEndian
+		var bomBytes = bytesBOMUTF16BE
+		if c == CharsetUTF16LE {
+			bo, bomBytes = binary.LittleEndian, bytesBOMUTF16LE
+		}
+
+		// Add BOM
+		if bom {
+			o = append(o, bomBytes...)
+		}
+
+		// Encode
+		for _, u := range utf16.Encode([]rune(string(b))) {
+			var buf = make([]byte, 2)
+			bo.PutUint16(buf, u)
+			o = append(o, buf...)
+		}
+	default:
+		// Get table
+		t, ok := charsetTables[c]
+		if !ok {
+			err = errors.Wrapf(ErrUnsupportedCharset, "astisub: charset %s is not supported", c)
+			return
+		}
+
+		// Encode
+		var m = charsetBytes(t)
+		for idx, r := range string(b) {
+			if r < 0x80 {
+				o = append(o, byte(r))
+			} else if v, ok := m[r]; ok {
+				o = append(o, v)
+			} else {
+				err = errors.Wrapf(ErrUnrepresentableCharacter, "astisub: character %q at offset %d doesn't exist in %s", r, idx, c)
+				return
+			}
+		}
+	}
+	return
+}
+
+// transcodeToUTF8 reads a content in a charset, which is detected if empty, and returns a reader of its UTF-8
+// conversion
+func transcodeToUTF8(i io.Reader, c Charset) (o io.Reader, err error) {
+	// Read
+	var b []byte
+	if b, err = ioutil.ReadAll(i); err != nil {
+		err = errors.Wrap(err, "astisub: reading failed")
+		return
+	}
+
+	// Detect charset
+	if len(c) == 0 {
+		c = DetectCharset(b)
+	}
+
+	// Decode
+	if c != CharsetUTF8 {
+		if b, err = decodeCharset(b, c); err != nil {
+			err = errors.Wrapf(err, "astisub: decoding %s failed", c)
+			return
+		}
+	}
+	return bytes.NewReader(b), nil
+}
+
+// writeToFormatInCharset writes subtitles in a specific text format to any writer, converting the content to the
+// charset set in the options. Non UTF-8 TTML contents start with an XML declaration of their charset.
+func (s Subtitles) writeToFormatInCharset(o io.Writer, f Format, opts Options) (err error) {
+	// Get representable characters
+	var representable func(r rune) bool
+	if representable, err = charsetRepresentable(opts.WriteCharset); err != nil {
+		return
+	}
+
+	// Apply glyph policy
+	if s, err = s.applyGlyphPolicy(representable, opts.GlyphPolicy, opts.GlyphPlaceholder); err != nil {
+		err = errors.Wrap(err, "astisub: applying glyph policy failed")
+		return
+	}
+
+	// Write
+	var buf = &bytes.Buffer{}
+	if err = s.writeToFormat(buf, f, opts); err != nil {
+		return
+	}
+
+	// Add XML declaration
+	var b = bytes.TrimPrefix(buf.Bytes(), BytesBOM)
+	if f == FormatTTML && len(opts.WriteCharset) > 0 && opts.WriteCharset != CharsetUTF8 {
+		b = append([]byte(`<?xml version="1.0" encoding="`+string(opts.WriteCharset)+`"?>`+"\n"), b...)
+	}
+
+	// Encode
+	if b, err = encodeCharset(b, opts.WriteCharset, opts.WriteBOM); err != nil {
+		err = errors.Wrapf(err, "astisub: encoding to %s failed", opts.WriteCharset)
+		return
+	}
+
+	// Write
+	if _, err = o.Write(b); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
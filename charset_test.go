@@ -0,0 +1,99 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCharset(t *testing.T) {
+	for _, v := range []struct {
+		c astisub.Charset
+		i []byte
+	}{
+		{c: astisub.CharsetUTF8, i: []byte("\ufeffcafé")},
+		{c: astisub.CharsetUTF8, i: []byte("café")},
+		{c: astisub.CharsetUTF8, i: []byte("café")[:4]},
+		{c: astisub.CharsetUTF16LE, i: []byte{0xff, 0xfe, 'a', 0x0}},
+		{c: astisub.CharsetUTF16BE, i: []byte{0xfe, 0xff, 0x0, 'a'}},
+		{c: astisub.CharsetUTF16LE, i: []byte{'a', 0x0, 'b', 0x0, 'c', 0x0}},
+		{c: astisub.CharsetUTF16BE, i: []byte{0x0, 'a', 0x0, 'b', 0x0, 'c'}},
+		{c: astisub.CharsetWindows1252, i: []byte("caf\xe9 \x93quoted\x94")},
+		{c: astisub.CharsetISO88592, i: []byte("Dzi\xeakuj\xea bardzo, pi\xeakna \xb1ka, \xb3\xf3d\xbc")},
+		{c: astisub.CharsetWindows1250, i: []byte("Dzi\xeakuj\xea, \xb9ka, \x9cwi\xeato")},
+	} {
+		assert.Equal(t, v.c, astisub.DetectCharset(v.i), "%q", v.i)
+	}
+}
+
+func TestCharsetRead(t *testing.T) {
+	// Windows-1252
+	s, err := astisub.Read(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nD\xe9j\xe0 vu\n")), astisub.FormatSRT, astisub.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Déjà vu", s.Items[0].String())
+
+	// Provided charset
+	s, err = astisub.Read(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\n\xa9a\n")), astisub.FormatSRT, astisub.Options{Charset: astisub.CharsetISO88592})
+	assert.NoError(t, err)
+	assert.Equal(t, "Ša", s.Items[0].String())
+
+	// UTF-16
+	var b = []byte{0xff, 0xfe}
+	for _, r := range "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nŁódź\n" {
+		b = append(b, byte(r), byte(r>>8))
+	}
+	f, i, err := astisub.DetectFormat(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.FormatWebVTT, f)
+	s, err = astisub.Read(i, f, astisub.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Łódź", s.Items[0].String())
+
+	// TTML declaration
+	s, err = astisub.Read(bytes.NewReader([]byte(`<?xml version="1.0" encoding="ISO-8859-1"?><tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="00:00:01.000" end="00:00:02.000">Gar`+"\xe7"+`on</p></div></body></tt>`)), astisub.FormatTTML, astisub.Options{})
+	assert.NoError(t, err)
+	assert.Equal(t, "Garçon", s.Items[0].String())
+}
+
+func TestCharsetWrite(t *testing.T) {
+	s, err := astisub.Read(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nDéjà vu\n")), astisub.FormatSRT, astisub.Options{})
+	assert.NoError(t, err)
+
+	// Windows-1252
+	w := &bytes.Buffer{}
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{WriteCharset: astisub.CharsetWindows1252})
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:02,000\nD\xe9j\xe0 vu\n", w.String())
+
+	// UTF-16 with BOM
+	w.Reset()
+	err = s.WriteToFormatWithOptions(w, astisub.FormatWebVTT, astisub.Options{WriteBOM: true, WriteCharset: astisub.CharsetUTF16BE})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xfe, 0xff, 0x0, 'W'}, w.Bytes()[:4])
+
+	// UTF-8 without BOM
+	w.Reset()
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{WriteCharset: astisub.CharsetUTF8})
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:02,000\nDéjà vu\n", w.String())
+
+	// TTML
+	w.Reset()
+	err = s.WriteToFormatWithOptions(w, astisub.FormatTTML, astisub.Options{WriteCharset: astisub.CharsetISO88591})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<?xml version="1.0" encoding="iso-8859-1"?>`)
+	s2, err := astisub.ReadFromTTML(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "Déjà vu", s2.Items[0].String())
+
+	// Unrepresentable character
+	s.Items[0].Lines[0].Items[0].Text = "Ł"
+	w.Reset()
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{WriteCharset: astisub.CharsetWindows1252})
+	assert.NoError(t, err)
+	assert.Equal(t, "1\n00:00:01,000 --> 00:00:02,000\nL\n", w.String())
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{GlyphPolicy: astisub.GlyphPolicyError, WriteCharset: astisub.CharsetWindows1252})
+	assert.Error(t, err)
+}
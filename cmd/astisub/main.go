@@ -0,0 +1,326 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/flag"
+)
+
+// Flags
+var (
+	fragmentDuration = flag.Duration("f", 0, "the fragment duration")
+	inputPath        = astiflag.Strings{}
+	mediaDuration    = flag.Duration("media-duration", 0, "the media duration, defaulting to the end of the last item")
+	outputFormat     = flag.String("format", "", "the output format, required when writing to the standard output")
+	teletextPage     = flag.Int("p", 0, "the teletext page")
+	teletextPID      = flag.Int("pid", 0, "the teletext PID")
+	outputPath       = flag.String("o", "", "the output path, - being the standard output")
+	profile          = flag.String("profile", "", "the validation profile: amazon, bbc, ebu, fcc or netflix")
+	scaleFactor      = flag.Float64("x", 0, "the scale factor")
+	syncDuration     = flag.Duration("s", 0, "the sync duration")
+)
+
+func main() {
+	// Init
+	var s = astiflag.Subcommand()
+	flag.Var(&inputPath, "i", "the input paths, - being the standard input")
+	flag.DurationVar(fragmentDuration, "duration", 0, "alias of -f")
+	flag.DurationVar(syncDuration, "offset", 0, "alias of -s")
+	flag.Usage = usage
+	flag.Parse()
+	astilog.SetLogger(astilog.New(astilog.FlagConfig()))
+
+	// Validate input path
+	if len(inputPath) == 0 {
+		astilog.Fatal("Use -i to provide at least one input path")
+	}
+
+	// Open first input path
+	var sub *astisub.Subtitles
+	var f astisub.Format
+	var err error
+	if sub, f, err = open(inputPath[0]); err != nil {
+		astilog.Fatalf("%s while opening %s", err, inputPath[0])
+	}
+
+	// Switch on subcommand
+	switch s {
+	case "accessibility":
+		// Accessibility
+		fmt.Println(sub.AccessibilityWithOptions(astisub.AccessibilityOptions{MediaDuration: *mediaDuration}))
+	case "convert":
+		// Write
+		write(sub)
+	case "detect":
+		// Detect
+		detect(f, sub)
+	case "fragment":
+		// Validate fragment duration
+		if *fragmentDuration <= 0 {
+			astilog.Fatal("Use -f or --duration to provide a fragment duration")
+		}
+
+		// Fragment
+		sub.Fragment(*fragmentDuration)
+
+		// Write
+		write(sub)
+	case "merge":
+		// Validate second input path
+		if len(inputPath) == 1 {
+			astilog.Fatal("Use -i to provide at least two input paths")
+		}
+
+		// Loop through other input paths
+		for _, p := range inputPath[1:] {
+			// Open
+			var sub2 *astisub.Subtitles
+			if sub2, _, err = open(p); err != nil {
+				astilog.Fatalf("%s while opening %s", err, p)
+			}
+
+			// Merge
+			sub.Merge(sub2)
+		}
+
+		// Write
+		write(sub)
+	case "optimize":
+		// Optimize
+		sub.Optimize()
+
+		// Write
+		write(sub)
+	case "scale":
+		// Validate scale factor
+		if *scaleFactor <= 0 {
+			astilog.Fatal("Use -x to provide a positive scale factor")
+		}
+
+		// Scale
+		sub.Scale(*scaleFactor)
+
+		// Write
+		write(sub)
+	case "shift", "sync":
+		// Validate sync duration
+		if *syncDuration == 0 {
+			astilog.Fatal("Use -s or --offset to provide a sync duration")
+		}
+
+		// Shift
+		sub.Add(*syncDuration)
+
+		// Write
+		write(sub)
+	case "stats":
+		// Stats
+		stats(sub)
+	case "unfragment":
+		// Unfragment
+		sub.Unfragment()
+
+		// Write
+		write(sub)
+	case "validate":
+		// Validate
+		if !validate(sub) {
+			astilog.Fatalf("%s is invalid", inputPath[0])
+		}
+	default:
+		astilog.Fatalf("Invalid subcommand %s", s)
+	}
+}
+
+// usage prints the usage
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: astisub <subcommand> -i <input> [flags]
+
+Subcommands:
+  accessibility  prints the caption coverage, longest gap, SDH elements and reading speeds of the input
+  convert        converts the input to the output format
+  detect         prints the detected format, language, framerate and title of the input
+  fragment       fragments the input, e.g. --duration 4s
+  merge          merges the inputs
+  optimize       removes unused regions and styles
+  scale          scales the input timings, e.g. -x 1.04
+  sync           shifts the input timings, e.g. --offset 1.5s (alias: shift)
+  stats          prints statistics about the input
+  unfragment     merges contiguous items with the same text
+  validate       prints the issues found in the input, e.g. -profile netflix
+
+Examples:
+  astisub convert -i in.srt -o out.vtt
+  astisub sync -i in.srt --offset 1.5s -o out.srt
+  cat in.srt | astisub convert -i - -o - -format webvtt
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+// open opens an input path using the teletext flags and returns the format of its content. The format is detected
+// from the content, the extension of the path being used if no format is detected.
+func open(path string) (s *astisub.Subtitles, f astisub.Format, err error) {
+	// Get reader
+	var i io.Reader = os.Stdin
+	if path != "-" {
+		var fl *os.File
+		if fl, err = os.Open(path); err != nil {
+			return
+		}
+		defer fl.Close()
+		i = fl
+	}
+
+	// Detect format
+	var r io.Reader
+	if f, r, err = astisub.DetectFormat(i); err != nil {
+		// Fall back to the extension
+		if err != astisub.ErrInvalidFormat || path == "-" {
+			return
+		}
+		if f, err = astisub.FormatFromExtension(filepath.Ext(path)); err != nil {
+			return
+		}
+	}
+
+	// Read
+	s, err = astisub.Read(r, f, astisub.Options{Filename: path, Teletext: astisub.TeletextOptions{Page: *teletextPage, PID: *teletextPID}})
+	return
+}
+
+// write writes the subtitles to the output path, in the output format if set
+func write(sub *astisub.Subtitles) {
+	// Validate output path
+	if len(*outputPath) <= 0 {
+		astilog.Fatal("Use -o to provide an output path")
+	}
+
+	// Get format
+	var f = astisub.Format(*outputFormat)
+	if len(f) == 0 {
+		if *outputPath == "-" {
+			astilog.Fatal("Use -format to provide the format of the standard output")
+		}
+		var err error
+		if f, err = astisub.FormatFromExtension(filepath.Ext(*outputPath)); err != nil {
+			astilog.Fatalf("%s while getting format of %s", err, *outputPath)
+		}
+	}
+
+	// Get writer
+	var w io.Writer = os.Stdout
+	if *outputPath != "-" {
+		fl, err := os.Create(*outputPath)
+		if err != nil {
+			astilog.Fatalf("%s while creating %s", err, *outputPath)
+		}
+		defer fl.Close()
+		w = fl
+	}
+
+	// Write
+	if err := sub.WriteToFormat(w, f); err != nil {
+		astilog.Fatalf("%s while writing to %s", err, *outputPath)
+	}
+}
+
+// detect prints what has been detected in the input
+func detect(f astisub.Format, sub *astisub.Subtitles) {
+	fmt.Printf("Format: %s\n", f)
+	sub.DetectLanguage()
+	if sub.Metadata != nil {
+		if len(sub.Metadata.Language) > 0 {
+			fmt.Printf("Language: %s\n", sub.Metadata.Language)
+		}
+		if sub.Metadata.Framerate > 0 {
+			fmt.Printf("Framerate: %d\n", sub.Metadata.Framerate)
+		}
+		if len(sub.Metadata.Title) > 0 {
+			fmt.Printf("Title: %s\n", sub.Metadata.Title)
+		}
+	}
+}
+
+// stats prints statistics about the subtitles
+func stats(sub *astisub.Subtitles) {
+	// Loop through items
+	var lines, maxLines, maxCharacters int
+	for _, i := range sub.Items {
+		lines += len(i.Lines)
+		if len(i.Lines) > maxLines {
+			maxLines = len(i.Lines)
+		}
+		for _, l := range i.Lines {
+			if c := len([]rune(l.String())); c > maxCharacters {
+				maxCharacters = c
+			}
+		}
+	}
+
+	// Print
+	fmt.Printf("Items: %d\n", len(sub.Items))
+	fmt.Printf("Duration: %s\n", sub.Duration())
+	fmt.Printf("Lines: %d\n", lines)
+	fmt.Printf("Max lines per item: %d\n", maxLines)
+	fmt.Printf("Max characters per line: %d\n", maxCharacters)
+	fmt.Printf("Regions: %d\n", len(sub.Regions))
+	fmt.Printf("Styles: %d\n", len(sub.Styles))
+}
+
+// validate prints the issues found in the subtitles and returns whether they are valid
+func validate(sub *astisub.Subtitles) (valid bool) {
+	valid = true
+	for idx, i := range sub.Items {
+		// Invalid time boundaries
+		if i.StartAt < 0 || i.EndAt <= i.StartAt {
+			fmt.Printf("Item #%d: invalid time boundaries %s --> %s\n", idx+1, i.StartAt, i.EndAt)
+			valid = false
+		}
+
+		// Unordered items
+		if idx > 0 && i.StartAt < sub.Items[idx-1].StartAt {
+			fmt.Printf("Item #%d: starts before item #%d\n", idx+1, idx)
+			valid = false
+		}
+
+		// No text
+		if len(strings.TrimSpace(i.String())) == 0 {
+			fmt.Printf("Item #%d: no text\n", idx+1)
+			valid = false
+		}
+	}
+
+	// Profile
+	if len(*profile) > 0 {
+		var p astisub.ValidationProfile
+		switch *profile {
+		case "amazon":
+			p = astisub.PresetAmazon
+		case "bbc":
+			p = astisub.PresetBBC
+		case "ebu":
+			p = astisub.PresetEBU
+		case "fcc":
+			p = astisub.PresetFCC
+		case "netflix":
+			p = astisub.PresetNetflix
+		default:
+			astilog.Fatalf("Invalid profile %s", *profile)
+		}
+		for _, i := range sub.Validate(p) {
+			fmt.Printf("Item #%d: %s\n", i.Item+1, i.Message)
+			valid = false
+		}
+		fmt.Printf("Quality score: %.1f\n", sub.Check(p).Score)
+	}
+	return
+}
@@ -0,0 +1,113 @@
+package astisub
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Vars
+var (
+	regexpCompareTag = regexp.MustCompile("<[^>]*>")
+)
+
+// CompareRenderingOptions represents compare rendering options
+type CompareRenderingOptions struct {
+	// If true, texts are compared case insensitively
+	IgnoreCase bool
+	// If true, texts are compared regardless of the way they are broken into lines
+	IgnoreLineBreaks bool
+	// Maximum difference between times of matching items. Defaults to 1 frame at 25 fps.
+	TimeTolerance time.Duration
+}
+
+// RenderingMismatch represents a difference between the renderings of two tracks
+type RenderingMismatch struct {
+	IndexA  int // Index of the item in the first track, -1 if the item is missing from it
+	IndexB  int // Index of the item in the second track, -1 if the item is missing from it
+	Message string
+}
+
+// CompareRendering compares two tracks meant to be equivalent with default options
+func CompareRendering(a, b *Subtitles) []RenderingMismatch {
+	return CompareRenderingWithOptions(a, b, CompareRenderingOptions{})
+}
+
+// CompareRenderingWithOptions compares two tracks meant to be equivalent, such as deliverables of the same asset in
+// different formats, and returns their mismatches. Items are matched on their start times, and matching items must
+// have the same text and end times within the tolerance. Markup and redundant white spaces are ignored when comparing
+// texts.
+func CompareRenderingWithOptions(a, b *Subtitles, o CompareRenderingOptions) (ms []RenderingMismatch) {
+	// Default options
+	if o.TimeTolerance <= 0 {
+		o.TimeTolerance = time.Second / defaultFramerate
+	}
+
+	// Sort items by start time
+	var ia, ib = compareIndexes(a), compareIndexes(b)
+
+	// Loop through items
+	for len(ia) > 0 || len(ib) > 0 {
+		// Item is missing from the second track
+		if len(ib) == 0 || (len(ia) > 0 && a.Items[ia[0]].StartAt < b.Items[ib[0]].StartAt-o.TimeTolerance) {
+			var i = a.Items[ia[0]]
+			ms = append(ms, RenderingMismatch{IndexA: ia[0], IndexB: -1, Message: fmt.Sprintf("item %s --> %s %q is missing from the second track", i.StartAt, i.EndAt, compareText(i, o))})
+			ia = ia[1:]
+			continue
+		}
+
+		// Item is missing from the first track
+		if len(ia) == 0 || b.Items[ib[0]].StartAt < a.Items[ia[0]].StartAt-o.TimeTolerance {
+			var i = b.Items[ib[0]]
+			ms = append(ms, RenderingMismatch{IndexA: -1, IndexB: ib[0], Message: fmt.Sprintf("item %s --> %s %q is missing from the first track", i.StartAt, i.EndAt, compareText(i, o))})
+			ib = ib[1:]
+			continue
+		}
+
+		// Compare matching items
+		var i, j = a.Items[ia[0]], b.Items[ib[0]]
+		if d := i.EndAt - j.EndAt; d > o.TimeTolerance || -d > o.TimeTolerance {
+			ms = append(ms, RenderingMismatch{IndexA: ia[0], IndexB: ib[0], Message: fmt.Sprintf("end times differ: %s != %s", i.EndAt, j.EndAt)})
+		}
+		if ta, tb := compareText(i, o), compareText(j, o); ta != tb {
+			ms = append(ms, RenderingMismatch{IndexA: ia[0], IndexB: ib[0], Message: fmt.Sprintf("texts differ: %q != %q", ta, tb)})
+		}
+		ia, ib = ia[1:], ib[1:]
+	}
+	return
+}
+
+// compareIndexes returns the indexes of the items sorted by start time
+func compareIndexes(s *Subtitles) (idxs []int) {
+	if s == nil {
+		return
+	}
+	idxs = make([]int, len(s.Items))
+	for idx := range idxs {
+		idxs[idx] = idx
+	}
+	sort.SliceStable(idxs, func(x, y int) bool { return s.Items[idxs[x]].StartAt < s.Items[idxs[y]].StartAt })
+	return
+}
+
+// compareText returns the rendered text of an item, lines being separated by "\n"
+func compareText(i *Item, o CompareRenderingOptions) string {
+	var ls []string
+	for _, l := range i.Lines {
+		var t = strings.Join(strings.Fields(regexpCompareTag.ReplaceAllString(l.String(), "")), " ")
+		if len(t) > 0 {
+			ls = append(ls, t)
+		}
+	}
+	var sep = "\n"
+	if o.IgnoreLineBreaks {
+		sep = " "
+	}
+	var t = strings.Join(ls, sep)
+	if o.IgnoreCase {
+		t = strings.ToLower(t)
+	}
+	return t
+}
@@ -0,0 +1,32 @@
+package astisub_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareRendering(t *testing.T) {
+	// Same asset in different formats
+	a, err := astisub.ReadFromSRT(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i>  world\n\n2\n00:00:03,000 --> 00:00:04,000\nFoo\nbar\n\n3\n00:00:05,000 --> 00:00:06,000\nOnly in SRT\n"))
+	assert.NoError(t, err)
+	b, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="00:00:01.020" end="00:00:02.000"><span>Hello world</span></p><p begin="00:00:03.000" end="00:00:04.500"><span>foo bar</span></p><p begin="00:00:07.000" end="00:00:08.000">Only in TTML</p></div></body></tt>`))
+	assert.NoError(t, err)
+
+	// Default options
+	assert.Equal(t, []astisub.RenderingMismatch{
+		{IndexA: 1, IndexB: 1, Message: "end times differ: 4s != 4.5s"},
+		{IndexA: 1, IndexB: 1, Message: "texts differ: \"Foo\\nbar\" != \"foo bar\""},
+		{IndexA: 2, IndexB: -1, Message: "item 5s --> 6s \"Only in SRT\" is missing from the second track"},
+		{IndexA: -1, IndexB: 2, Message: "item 7s --> 8s \"Only in TTML\" is missing from the first track"},
+	}, astisub.CompareRendering(a, b))
+
+	// Custom options
+	assert.Equal(t, []astisub.RenderingMismatch{
+		{IndexA: 2, IndexB: -1, Message: "item 5s --> 6s \"only in srt\" is missing from the second track"},
+		{IndexA: -1, IndexB: 2, Message: "item 7s --> 8s \"only in ttml\" is missing from the first track"},
+	}, astisub.CompareRenderingWithOptions(a, b, astisub.CompareRenderingOptions{IgnoreCase: true, IgnoreLineBreaks: true, TimeTolerance: time.Second}))
+}
@@ -0,0 +1,36 @@
+package astisub
+
+// Provenance represents the origin of an item or a line item transcribed by a speech recognition engine
+type Provenance struct {
+	Engine  string // e.g. "whisper"
+	Model   string
+	Version string
+}
+
+// LowestConfidence returns the lowest confidence among the item and its line items. ok is false if none of them has
+// a confidence.
+func (i Item) LowestConfidence() (c float64, ok bool) {
+	var fn = func(v *float64) {
+		if v != nil && (!ok || *v < c) {
+			c, ok = *v, true
+		}
+	}
+	fn(i.Confidence)
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			fn(li.Confidence)
+		}
+	}
+	return
+}
+
+// LowConfidenceItems returns the items whose lowest confidence is less than min, for instance to flag them for human
+// review. Items without confidence are not returned.
+func (s Subtitles) LowConfidenceItems(min float64) (is []*Item) {
+	for _, i := range s.Items {
+		if c, ok := i.LowestConfidence(); ok && c < min {
+			is = append(is, i)
+		}
+	}
+	return
+}
@@ -0,0 +1,48 @@
+package astisub_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfidence(t *testing.T) {
+	var p = &astisub.Provenance{Engine: "whisper", Model: "large-v3"}
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		{Confidence: astiptr.Float(0.9), EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{Confidence: astiptr.Float(0.95), Provenance: p, Text: "Hello"},
+			{Confidence: astiptr.Float(0.6), Provenance: p, Text: " wordl"},
+		}}}, Provenance: p, StartAt: time.Second},
+		{Confidence: astiptr.Float(0.8), EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "How are you?"}}}}, StartAt: 2 * time.Second},
+		{EndAt: 6 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Fine"}}}}, StartAt: 4 * time.Second},
+	}}
+
+	// Lowest confidence
+	c, ok := s.Items[0].LowestConfidence()
+	assert.True(t, ok)
+	assert.Equal(t, 0.6, c)
+	_, ok = s.Items[2].LowestConfidence()
+	assert.False(t, ok)
+
+	// Filter
+	assert.Equal(t, []*astisub.Item{s.Items[0]}, s.LowConfidenceItems(0.7))
+	assert.Len(t, s.LowConfidenceItems(0.85), 2)
+
+	// Quality
+	r := s.QualityWithOptions(astisub.QualityOptions{MinConfidence: 0.7})
+	assert.Equal(t, astisub.QualityCategoryScore{Checked: 2, Failed: 1, Score: 50, Weight: 1}, *r.Categories[astisub.QualityCategoryConfidence])
+	assert.Contains(t, r.Issues, astisub.QualityIssue{Category: astisub.QualityCategoryConfidence, Item: 0, Message: "item #1 has a confidence of 0.60 which is less than 0.70"})
+	_, ok = s.Quality().Categories[astisub.QualityCategoryConfidence]
+	assert.False(t, ok)
+
+	// JSON
+	b, err := json.Marshal(s.Items[0])
+	assert.NoError(t, err)
+	var i astisub.Item
+	assert.NoError(t, json.Unmarshal(b, &i))
+	assert.Equal(t, *s.Items[0], i)
+}
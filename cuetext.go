@@ -0,0 +1,120 @@
+package astisub
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// CueTextOptions represents the way cue texts are transformed on write, regardless of the format
+type CueTextOptions struct {
+	// Rules dialogue lines are formatted with before the template is executed
+	DialogueStyle DialogueStyle
+	// Template executed for each line with CueTextData, whose output replaces the line's text. If the output contains
+	// the line's text, the line's styles are kept.
+	Template *template.Template
+}
+
+// CueTextData represents the data cue text templates are executed with
+type CueTextData struct {
+	Dialogue   bool // If true, the cue is spoken by several speakers
+	Item       *Item
+	LineIndex  int
+	NewSpeaker bool // If true, the line starts a speaker's turn
+	Speaker    string
+	Text       string // Text of the line, dialogue style rules being applied
+}
+
+// FormatCueTexts returns subtitles whose cue texts have been transformed, items being copied before being modified so
+// that the input subtitles are left untouched
+func (s Subtitles) FormatCueTexts(o CueTextOptions) (f Subtitles, err error) {
+	// Loop through items
+	f = s
+	f.Items = make([]*Item, 0, len(s.Items))
+	for idxItem, i := range s.Items {
+		// Count turns
+		var turns int
+		for idx := range i.Lines {
+			if lineStartsTurn(i.Lines, idx) {
+				turns++
+			}
+		}
+		var dialogue = turns > 1
+
+		// Loop through lines
+		var c = *i
+		c.Lines = make([]Line, 0, len(i.Lines))
+		for idx, l := range i.Lines {
+			// Build data
+			var d = CueTextData{
+				Dialogue:   dialogue,
+				Item:       i,
+				LineIndex:  idx,
+				NewSpeaker: lineStartsTurn(i.Lines, idx),
+				Speaker:    l.VoiceName,
+				Text:       l.String(),
+			}
+
+			// Apply dialogue style
+			var prefix string
+			if d.NewSpeaker {
+				if dialogue && (idx > 0 || !o.DialogueStyle.DashSkipFirstSpeaker) {
+					prefix += o.DialogueStyle.Dash
+				}
+				if len(o.DialogueStyle.SpeakerFormat) > 0 && len(l.VoiceName) > 0 {
+					prefix += fmt.Sprintf(o.DialogueStyle.SpeakerFormat, l.VoiceName)
+				}
+			}
+			d.Text = prefix + d.Text
+			var text = d.Text
+
+			// Execute template
+			if o.Template != nil {
+				var buf = &bytes.Buffer{}
+				if err = o.Template.Execute(buf, d); err != nil {
+					err = errors.Wrapf(err, "astisub: executing template on line %d of item %d failed", idx+1, idxItem+1)
+					return
+				}
+				text = buf.String()
+			}
+
+			// Add line
+			c.Lines = append(c.Lines, cueTextLine(l, text))
+		}
+		f.Items = append(f.Items, &c)
+	}
+	return
+}
+
+// cueTextLine returns the line whose text has been replaced. If the text contains the line's text, what surrounds it
+// is added to the first and last line items so that their styles are kept. Otherwise the text is set on the first
+// line item.
+func cueTextLine(l Line, text string) (o Line) {
+	// Nothing to do
+	o = l
+	var original = l.String()
+	if text == original {
+		return
+	}
+
+	// Copy line items
+	o.Items = append([]LineItem{}, l.Items...)
+	if len(o.Items) == 0 {
+		o.Items = []LineItem{{}}
+	}
+
+	// Surround line items
+	if idx := strings.Index(text, original); idx >= 0 && len(original) > 0 {
+		o.Items[0].Text = text[:idx] + o.Items[0].Text
+		o.Items[len(o.Items)-1].Text += text[idx+len(original):]
+		return
+	}
+
+	// Replace line items
+	o.Items = []LineItem{o.Items[0]}
+	o.Items[0].Text = text
+	return
+}
@@ -0,0 +1,56 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_FormatCueTexts(t *testing.T) {
+	s := astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "Where"}, {InlineStyle: &astisub.StyleAttributes{SRTItalics: astiptr.Bool(true)}, Text: "were you?"}}, VoiceName: "Anna"},
+			{Items: []astisub.LineItem{{Text: "Home."}}, VoiceName: "Bob"},
+			{Items: []astisub.LineItem{{Text: "Why?"}}, VoiceName: "Bob"},
+		}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Alone"}}, VoiceName: "Anna"}}},
+	}}
+
+	// Dialogue style
+	f, err := s.FormatCueTexts(astisub.CueTextOptions{DialogueStyle: astisub.DialogueStyle{Dash: "- "}})
+	assert.NoError(t, err)
+	assert.Equal(t, "- Where", f.Items[0].Lines[0].Items[0].Text)
+	assert.Equal(t, "were you?", f.Items[0].Lines[0].Items[1].Text)
+	assert.True(t, *f.Items[0].Lines[0].Items[1].InlineStyle.SRTItalics)
+	assert.Equal(t, "- Home.", f.Items[0].Lines[1].String())
+	assert.Equal(t, "Why?", f.Items[0].Lines[2].String())
+	assert.Equal(t, "Alone", f.Items[1].Lines[0].String())
+	assert.Equal(t, "Where", s.Items[0].Lines[0].Items[0].Text)
+	f, err = s.FormatCueTexts(astisub.CueTextOptions{DialogueStyle: astisub.DialogueStyle{Dash: "- ", DashSkipFirstSpeaker: true, SpeakerFormat: "%s: "}})
+	assert.NoError(t, err)
+	assert.Equal(t, "Anna: Where were you?", f.Items[0].Lines[0].String())
+	assert.Equal(t, "- Bob: Home.", f.Items[0].Lines[1].String())
+	assert.Equal(t, "Anna: Alone", f.Items[1].Lines[0].String())
+
+	// Template
+	f, err = s.FormatCueTexts(astisub.CueTextOptions{
+		DialogueStyle: astisub.DialogueStyle{SpeakerFormat: "- %s - "},
+		Template:      template.Must(template.New("").Parse("{{if not .Dialogue}}<{{.Text}}>{{else}}{{.LineIndex}}|{{.Speaker}}{{end}}")),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "0|Anna", f.Items[0].Lines[0].String())
+	assert.Len(t, f.Items[0].Lines[0].Items, 1)
+	assert.Equal(t, "<- Anna - Alone>", f.Items[1].Lines[0].String())
+	_, err = s.FormatCueTexts(astisub.CueTextOptions{Template: template.Must(template.New("").Parse("{{.Invalid}}"))})
+	assert.Error(t, err)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSBV, astisub.Options{CueText: astisub.CueTextOptions{DialogueStyle: astisub.DialogueStyle{Dash: "-"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "0:00:00.000,0:00:00.000\n-Where were you?\n-Home.\nWhy?\n\n0:00:00.000,0:00:00.000\nAlone\n", w.String())
+}
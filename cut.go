@@ -0,0 +1,78 @@
+package astisub
+
+import "time"
+
+// Cut represents an edit of the content, expressed in the timeline of the subtitles before the edit: either the
+// content between From and To is removed or, if Insert is true, new content lasting To - From is inserted at From
+type Cut struct {
+	From   time.Duration
+	Insert bool
+	To     time.Duration
+}
+
+// ApplyCutList conforms the subtitles to a re-edited content, as described by an edit decision list. Items within
+// removed content are removed, items partially within removed content are trimmed, items spanning inserted content
+// are ended where it's inserted and items after an edit are retimed.
+func (s *Subtitles) ApplyCutList(cuts []Cut) {
+	// Map times
+	var mapTime = func(d time.Duration, isEnd bool) time.Duration {
+		var o = d
+		for _, c := range cuts {
+			if c.Insert {
+				// Times at the insertion point are shifted only when content starts there
+				if d > c.From || (d == c.From && !isEnd) {
+					o += c.To - c.From
+				}
+			} else if d > c.From {
+				// Times within removed content are moved to its start
+				if d < c.To {
+					o -= d - c.From
+				} else {
+					o -= c.To - c.From
+				}
+			}
+		}
+		return o
+	}
+
+	// Map markers
+	for idx, m := range s.Markers {
+		s.Markers[idx].At = mapTime(m.At, false)
+	}
+
+	// Loop through items
+	var items []*Item
+	for _, i := range s.Items {
+		// Items can't span inserted content
+		var endAt = i.EndAt
+		for _, c := range cuts {
+			if c.Insert && i.StartAt < c.From && c.From < endAt {
+				endAt = c.From
+			}
+		}
+
+		// Map times
+		i.StartAt, i.EndAt = mapTime(i.StartAt, false), mapTime(endAt, true)
+
+		// Item has been entirely removed
+		if i.EndAt <= i.StartAt {
+			continue
+		}
+
+		// Map line and line item times
+		for idxLine, l := range i.Lines {
+			if l.isTimed() {
+				i.Lines[idxLine].StartAt = mapTime(l.StartAt, false)
+				i.Lines[idxLine].EndAt = mapTime(l.EndAt, true)
+			}
+			for idxItem, li := range l.Items {
+				if li.isTimed() {
+					i.Lines[idxLine].Items[idxItem].StartAt = mapTime(li.StartAt, false)
+					i.Lines[idxLine].Items[idxItem].EndAt = mapTime(li.EndAt, true)
+				}
+			}
+		}
+		items = append(items, i)
+	}
+	s.Items = items
+}
@@ -0,0 +1,31 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_ApplyCutList(t *testing.T) {
+	var item = func(start, end time.Duration, text string) *astisub.Item {
+		return &astisub.Item{EndAt: end * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}, StartAt: start * time.Second}
+	}
+	var s = &astisub.Subtitles{Items: []*astisub.Item{item(1, 3, "1"), item(3, 7, "2"), item(8, 10, "3"), item(12, 14, "4"), item(16, 17, "5")}}
+	s.Items[1].Lines[0].Items[0].StartAt = 4 * time.Second
+	s.Items[1].Lines[0].Items[0].EndAt = 5 * time.Second
+	s.ApplyCutList([]astisub.Cut{
+		{From: 2 * time.Second, To: 4 * time.Second},
+		{From: 9 * time.Second, Insert: true, To: 14 * time.Second},
+		{From: 11 * time.Second, To: 15 * time.Second},
+	})
+	assert.Len(t, s.Items, 4)
+	for idx, v := range [][2]time.Duration{{1, 2}, {2, 5}, {6, 7}, {15, 16}} {
+		assert.Equal(t, v[0]*time.Second, s.Items[idx].StartAt)
+		assert.Equal(t, v[1]*time.Second, s.Items[idx].EndAt)
+	}
+	assert.Equal(t, "5", s.Items[3].String())
+	assert.Equal(t, 2*time.Second, s.Items[1].Lines[0].Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].Lines[0].Items[0].EndAt)
+}
@@ -0,0 +1,43 @@
+package astisub
+
+import "fmt"
+
+// AudioDescription represents the audio description attributes of an item, i.e. a cue of an audio description
+// script that is meant to be voiced rather than displayed
+type AudioDescription struct {
+	Source string // URI of a prerecorded clip of the description, empty if the description is synthesized
+}
+
+// isAudioDescription checks whether an item of the subtitles is an audio description cue
+func (s Subtitles) isAudioDescription(i *Item) bool {
+	return i.AudioDescription != nil || (s.Metadata != nil && s.Metadata.Kind == TrackKindDescriptions)
+}
+
+// AudioDescriptionItems returns the audio description cues of the subtitles
+func (s Subtitles) AudioDescriptionItems() (is []*Item) {
+	for _, i := range s.Items {
+		if s.isAudioDescription(i) {
+			is = append(is, i)
+		}
+	}
+	return
+}
+
+// CheckAudioDescriptionPlacement returns a warning for each audio description cue overlapping a dialogue cue, since
+// descriptions must be voiced in the gaps of the dialogue
+func (s Subtitles) CheckAudioDescriptionPlacement(dialogue Subtitles) (ws []Warning) {
+	for idx, i := range s.Items {
+		// Item is not an audio description
+		if !s.isAudioDescription(i) {
+			continue
+		}
+
+		// Loop through dialogue items
+		for idxDialogue, d := range dialogue.Items {
+			if i.StartAt < d.EndAt && d.StartAt < i.EndAt {
+				ws = append(ws, Warning{Message: fmt.Sprintf("audio description item #%d (%s --> %s) overlaps dialogue item #%d (%s --> %s)", idx+1, i.StartAt, i.EndAt, idxDialogue+1, d.StartAt, d.EndAt)})
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,28 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_CheckAudioDescriptionPlacement(t *testing.T) {
+	var ad = &astisub.Subtitles{Items: []*astisub.Item{
+		{AudioDescription: &astisub.AudioDescription{}, EndAt: 3 * time.Second, StartAt: time.Second},
+		{AudioDescription: &astisub.AudioDescription{Source: "ad2.wav"}, EndAt: 6 * time.Second, StartAt: 4 * time.Second},
+		{EndAt: 10 * time.Second, StartAt: 5 * time.Second},
+	}}
+	var dialogue = astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 4 * time.Second, StartAt: 3 * time.Second},
+		{EndAt: 8 * time.Second, StartAt: 5500 * time.Millisecond},
+	}}
+	assert.Len(t, ad.AudioDescriptionItems(), 2)
+	assert.Equal(t, []astisub.Warning{{Message: "audio description item #2 (4s --> 6s) overlaps dialogue item #2 (5.5s --> 8s)"}}, ad.CheckAudioDescriptionPlacement(dialogue))
+
+	// Every item of a descriptions track is an audio description
+	ad.Metadata = &astisub.Metadata{Kind: astisub.TrackKindDescriptions}
+	assert.Len(t, ad.AudioDescriptionItems(), 3)
+	assert.Len(t, ad.CheckAudioDescriptionPlacement(dialogue), 2)
+}
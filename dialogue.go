@@ -0,0 +1,121 @@
+package astisub
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DialogueStyle represents the rules dialogue lines are formatted with. A speaker's turn starts on lines whose voice
+// name changes or which are flagged with NewSpeaker, such as lines read with dialogue dashes.
+type DialogueStyle struct {
+	// Added before the first line of each turn in cues spoken by several speakers, such as "-" or "- " following
+	// European conventions, or "– " following French and Scandinavian conventions
+	Dash string
+	// If true, the first turn of a cue isn't dashed, as some broadcasters require
+	DashSkipFirstSpeaker bool
+	// Format speaker names are written with before the first line of each turn, such as "%s: " or "- %s - ".
+	// Speaker names are not written if empty or if the line has no voice name.
+	SpeakerFormat string
+}
+
+// Dialogue styles
+var (
+	// En dash followed by a space, such as "– Hello"
+	DialogueStyleEnDash = DialogueStyle{Dash: "– "}
+	// Hyphen without space, such as "-Hello", common in US and Dutch subtitles
+	DialogueStyleHyphen = DialogueStyle{Dash: "-"}
+	// Hyphen followed by a space, such as "- Hello", common in European subtitles
+	DialogueStyleHyphenSpace = DialogueStyle{Dash: "- "}
+)
+
+// Vars
+var (
+	// Dashes starting a line, digits being excluded so that negative numbers are not mistaken for dashes
+	dialogueRegexpLineDash = regexp.MustCompile(`^\s*[-‐–—]+\s*([^-‐–—\d\s]|$)`)
+	// Dashes starting a turn within a line, after the end of the previous sentence
+	dialogueRegexpInlineDash = regexp.MustCompile(`([.!?…"»)\]])\s+[-‐–—]+\s*([^-‐–—\d\s])`)
+)
+
+// lineStartsTurn returns whether a line starts a speaker's turn
+func lineStartsTurn(ls []Line, idx int) bool {
+	if ls[idx].NewSpeaker {
+		return true
+	}
+	if len(ls[idx].VoiceName) == 0 {
+		return false
+	}
+	return idx == 0 || ls[idx-1].VoiceName != ls[idx].VoiceName
+}
+
+// DetectDialogueDashes detects dialogue dashes in items spoken by several speakers, such as "- Hi.\n- Hello." or
+// "Hi.\n-Hello.", regardless of their style. Dashes are removed and each turn gets lines of its own flagged with
+// NewSpeaker so that dashes can be written back in any style with a DialogueStyle. Turns sharing a line, such as
+// "- Hi. - Hello.", are split into separate lines. Items with less than 2 turns are left untouched.
+func (s *Subtitles) DetectDialogueDashes() {
+	for _, i := range s.Items {
+		// Loop through lines
+		var ls []Line
+		var dashed bool
+		for idx, l := range i.Lines {
+			// Dash starting the line
+			var nl = l
+			nl.Items = append([]LineItem{}, l.Items...)
+			if len(nl.Items) > 0 {
+				if m := dialogueRegexpLineDash.FindStringSubmatchIndex(nl.Items[0].Text); m != nil {
+					nl.Items[0].Text = nl.Items[0].Text[m[2]:]
+					nl.NewSpeaker = true
+					dashed = true
+				}
+			}
+
+			// Dashes within the line
+			var split = splitDialogueLine(nl)
+			if len(split) > 1 {
+				dashed = true
+			}
+
+			// The first line of an item whose other turns are dashed starts a turn as well
+			if idx == 0 {
+				split[0].NewSpeaker = true
+			}
+			ls = append(ls, split...)
+		}
+
+		// Count turns
+		var turns int
+		for idx := range ls {
+			if lineStartsTurn(ls, idx) {
+				turns++
+			}
+		}
+		if !dashed || turns < 2 {
+			continue
+		}
+		i.Lines = ls
+	}
+}
+
+// splitDialogueLine splits a line on dashes starting turns within its line items
+func splitDialogueLine(l Line) (ls []Line) {
+	var current = l
+	current.Items = nil
+	for _, li := range l.Items {
+		for {
+			// No dash
+			var m = dialogueRegexpInlineDash.FindStringSubmatchIndex(li.Text)
+			if m == nil {
+				break
+			}
+
+			// Split line item
+			var before = li
+			before.Text = strings.TrimSpace(li.Text[:m[3]])
+			current.Items = append(current.Items, before)
+			ls = append(ls, current)
+			current = Line{NewSpeaker: true, VoiceName: l.VoiceName}
+			li.Text = li.Text[m[4]:]
+		}
+		current.Items = append(current.Items, li)
+	}
+	return append(ls, current)
+}
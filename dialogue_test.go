@@ -0,0 +1,43 @@
+package astisub_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_DetectDialogueDashes(t *testing.T) {
+	s, err := astisub.ReadFromSRT(strings.NewReader("1\n00:00:01,000 --> 00:00:02,000\n- Where were you?\n– Home.\n\n2\n00:00:02,000 --> 00:00:03,000\nWhy?\n-Because.\n\n3\n00:00:03,000 --> 00:00:04,000\n-Hi. -Hello.\n\n4\n00:00:04,000 --> 00:00:05,000\nIt was -5 degrees.\nA well-known fact.\n\n5\n00:00:05,000 --> 00:00:06,000\n- Alone\n"))
+	assert.NoError(t, err)
+	s.DetectDialogueDashes()
+	var lines = func(i *astisub.Item) (ls []string) {
+		for _, l := range i.Lines {
+			ls = append(ls, l.String())
+		}
+		return
+	}
+	assert.Equal(t, []string{"Where were you?", "Home."}, lines(s.Items[0]))
+	assert.True(t, s.Items[0].Lines[1].NewSpeaker)
+	assert.Equal(t, []string{"Why?", "Because."}, lines(s.Items[1]))
+	assert.Equal(t, []string{"Hi.", "Hello."}, lines(s.Items[2]))
+	assert.True(t, s.Items[2].Lines[1].NewSpeaker)
+	assert.Equal(t, []string{"It was -5 degrees.", "A well-known fact."}, lines(s.Items[3]))
+	assert.False(t, s.Items[3].Lines[0].NewSpeaker)
+	assert.Equal(t, []string{"- Alone"}, lines(s.Items[4]))
+
+	// Write dashes back in another style
+	s.Items = s.Items[:3]
+	w := &bytes.Buffer{}
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSBV, astisub.Options{CueText: astisub.CueTextOptions{DialogueStyle: astisub.DialogueStyleEnDash}})
+	assert.NoError(t, err)
+	assert.Equal(t, "0:00:01.000,0:00:02.000\n– Where were you?\n– Home.\n\n0:00:02.000,0:00:03.000\n– Why?\n– Because.\n\n0:00:03.000,0:00:04.000\n– Hi.\n– Hello.\n", w.String())
+	w.Reset()
+	var ds = astisub.DialogueStyleHyphen
+	ds.DashSkipFirstSpeaker = true
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSBV, astisub.Options{CueText: astisub.CueTextOptions{DialogueStyle: ds}})
+	assert.NoError(t, err)
+	assert.Equal(t, "0:00:01.000,0:00:02.000\nWhere were you?\n-Home.\n\n0:00:02.000,0:00:03.000\nWhy?\n-Because.\n\n0:00:03.000,0:00:04.000\nHi.\n-Hello.\n", w.String())
+}
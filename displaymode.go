@@ -0,0 +1,177 @@
+package astisub
+
+import (
+	"strings"
+	"time"
+)
+
+// DisplayMode represents the way the text of an item is presented
+type DisplayMode int
+
+// Display modes
+const (
+	// The text appears all at once. It's the default display mode.
+	DisplayModePopOn DisplayMode = iota
+	// The text appears as it's being written, without the display being erased first. Lines can be timed.
+	DisplayModePaintOn
+	// Lines appear at the bottom of the display and scroll previous lines up, the way live captions do. Items are
+	// snapshots of the display and lines are timed.
+	DisplayModeRollUp
+)
+
+// Default display mode conversion values
+const (
+	defaultPopOnMaxLines = 2
+	defaultRollUpRows    = 3
+)
+
+// displayedLine represents a line displayed by successive roll-up or paint-on items
+type displayedLine struct {
+	endAt, startAt time.Duration
+	item           *Item // Last item the line has been displayed by
+	line           Line  // Line as it was last displayed
+}
+
+// ToPopOn converts roll-up and paint-on items, whose text builds up over time, into readable pop-on items of at most
+// maxLines lines, which defaults to 2. Each line is kept once, in its final state, and items start when their first
+// line appeared. Pop-on items are left untouched.
+func (s *Subtitles) ToPopOn(maxLines int) {
+	// Default max lines
+	if maxLines <= 0 {
+		maxLines = defaultPopOnMaxLines
+	}
+
+	// Loop through items
+	var items []*Item
+	var ls []*displayedLine
+	var previous *Item
+	for _, i := range s.Items {
+		// Pop-on item
+		if i.DisplayMode == DisplayModePopOn {
+			items = append(items, popOnItems(ls, maxLines)...)
+			items = append(items, i)
+			ls, previous = nil, nil
+			continue
+		}
+
+		// Lines can't be grouped across an erased display
+		var contiguous = previous != nil && previous.EndAt == i.StartAt
+		if !contiguous {
+			items = append(items, popOnItems(ls, maxLines)...)
+			ls = nil
+		}
+
+		// Loop through lines
+		var used = make(map[*displayedLine]bool)
+		for _, l := range i.Lines {
+			// Get time boundaries
+			var startAt, endAt = i.StartAt, i.EndAt
+			if l.isTimed() {
+				startAt, endAt = l.StartAt, l.EndAt
+			}
+
+			// Lines displayed by the previous item can be extended when the display hasn't been erased in between
+			var dl *displayedLine
+			if contiguous {
+				var t = l.String()
+				for _, c := range ls {
+					if c.item == previous && !used[c] && strings.HasPrefix(t, c.line.String()) {
+						dl = c
+						break
+					}
+				}
+			}
+
+			// New line
+			if dl == nil {
+				dl = &displayedLine{startAt: startAt}
+				ls = append(ls, dl)
+			}
+
+			// Update line
+			used[dl] = true
+			dl.endAt, dl.item, dl.line = endAt, i, l
+		}
+		previous = i
+	}
+	s.Items = append(items, popOnItems(ls, maxLines)...)
+}
+
+// popOnItems groups displayed lines into pop-on items of at most maxLines lines that don't overlap
+func popOnItems(ls []*displayedLine, maxLines int) (items []*Item) {
+	for idx := 0; idx < len(ls); idx += maxLines {
+		// Get lines
+		var end = idx + maxLines
+		if end > len(ls) {
+			end = len(ls)
+		}
+
+		// Create item based on the last item the lines have been displayed by
+		var i = *ls[end-1].item
+		i.DisplayMode, i.EndAt, i.Lines, i.RollUpRows, i.StartAt = DisplayModePopOn, 0, nil, 0, ls[idx].startAt
+		for _, dl := range ls[idx:end] {
+			var l = dl.line
+			l.EndAt, l.StartAt = 0, 0
+			i.Lines = append(i.Lines, l)
+			if dl.endAt > i.EndAt {
+				i.EndAt = dl.endAt
+			}
+		}
+
+		// Item ends when the next one starts
+		if end < len(ls) && ls[end].startAt > i.StartAt && ls[end].startAt < i.EndAt {
+			i.EndAt = ls[end].startAt
+		}
+		items = append(items, &i)
+	}
+	return
+}
+
+// ToRollUp converts items into roll-up items displaying at most rows lines, which defaults to 3, for live-style
+// output. Lines of an item roll in evenly spread over its duration and the display is erased when there's a gap
+// between items.
+func (s *Subtitles) ToRollUp(rows int) {
+	// Default rows
+	if rows <= 0 {
+		rows = defaultRollUpRows
+	}
+
+	// Loop through items
+	var items []*Item
+	var displayed []Line
+	for idx, i := range s.Items {
+		// Display is erased when there's a gap between items
+		var erased = idx == 0 || i.StartAt > s.Items[idx-1].EndAt
+		if erased {
+			displayed = nil
+		}
+
+		// Loop through lines
+		for idxLine, l := range i.Lines {
+			// Roll line in
+			var startAt = i.StartAt + time.Duration(idxLine)*(i.EndAt-i.StartAt)/time.Duration(len(i.Lines))
+			l.EndAt, l.StartAt = 0, startAt
+			if displayed = append(displayed, l); len(displayed) > rows {
+				displayed = displayed[len(displayed)-rows:]
+			}
+
+			// Previous snapshot ends when the display changes
+			if (idxLine > 0 || !erased) && len(items) > 0 {
+				items[len(items)-1].EndAt = startAt
+			}
+
+			// Add snapshot
+			var c = *i
+			c.DisplayMode, c.Lines, c.RollUpRows, c.StartAt = DisplayModeRollUp, append([]Line(nil), displayed...), rows, startAt
+			items = append(items, &c)
+		}
+	}
+
+	// Lines are displayed until their snapshot ends
+	for _, i := range items {
+		for idx := range i.Lines {
+			i.Lines[idx].EndAt = i.EndAt
+		}
+	}
+	s.Items = items
+}
@@ -0,0 +1,90 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_ToPopOn(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.scc")
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.DisplayModePopOn, s.Items[0].DisplayMode)
+	assert.Equal(t, astisub.DisplayModeRollUp, s.Items[2].DisplayMode)
+	assert.Equal(t, 2, s.Items[2].RollUpRows)
+
+	// Convert
+	s.ToPopOn(0)
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, "Hello world ♪", s.Items[0].String())
+	assert.Equal(t, astisub.DisplayModePopOn, s.Items[2].DisplayMode)
+	assert.Equal(t, 6006*time.Millisecond, s.Items[2].StartAt)
+	assert.Equal(t, 8008*time.Millisecond, s.Items[2].EndAt)
+	assert.Equal(t, "Roll - up", s.Items[2].String())
+	assert.Equal(t, time.Duration(0), s.Items[2].Lines[0].EndAt)
+
+	// Lines building up are kept once in their final state and the display is erased on gaps
+	var line = func(start, end time.Duration, text string) astisub.Line {
+		return astisub.Line{EndAt: end * time.Second, Items: []astisub.LineItem{{Text: text}}, StartAt: start * time.Second}
+	}
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{DisplayMode: astisub.DisplayModeRollUp, EndAt: 2 * time.Second, Lines: []astisub.Line{line(1, 2, "Hel")}, StartAt: time.Second},
+		{DisplayMode: astisub.DisplayModeRollUp, EndAt: 3 * time.Second, Lines: []astisub.Line{line(1, 3, "Hello")}, StartAt: 2 * time.Second},
+		{DisplayMode: astisub.DisplayModeRollUp, EndAt: 4 * time.Second, Lines: []astisub.Line{line(1, 4, "Hello"), line(3, 4, "my")}, StartAt: 3 * time.Second},
+		{DisplayMode: astisub.DisplayModeRollUp, EndAt: 6 * time.Second, Lines: []astisub.Line{line(3, 6, "my friend"), line(4, 6, "how")}, StartAt: 4 * time.Second},
+		{DisplayMode: astisub.DisplayModeRollUp, EndAt: 9 * time.Second, Lines: []astisub.Line{line(8, 9, "are you")}, StartAt: 8 * time.Second},
+	}}
+	s.ToPopOn(2)
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, "Hello - my friend", s.Items[0].String())
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "how", s.Items[1].String())
+	assert.Equal(t, 4*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 6*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, "are you", s.Items[2].String())
+	assert.Equal(t, 8*time.Second, s.Items[2].StartAt)
+}
+
+func TestSubtitles_ToRollUp(t *testing.T) {
+	var item = func(start, end time.Duration, texts ...string) *astisub.Item {
+		var i = &astisub.Item{EndAt: end * time.Second, StartAt: start * time.Second}
+		for _, text := range texts {
+			i.Lines = append(i.Lines, astisub.Line{Items: []astisub.LineItem{{Text: text}}})
+		}
+		return i
+	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{item(1, 3, "a", "b"), item(3, 5, "c"), item(10, 12, "d")}}
+	s.ToRollUp(2)
+	assert.Len(t, s.Items, 4)
+	for idx, v := range []struct {
+		end, start time.Duration
+		text       string
+	}{
+		{end: 2, start: 1, text: "a"},
+		{end: 3, start: 2, text: "a - b"},
+		{end: 5, start: 3, text: "b - c"},
+		{end: 12, start: 10, text: "d"},
+	} {
+		assert.Equal(t, astisub.DisplayModeRollUp, s.Items[idx].DisplayMode)
+		assert.Equal(t, 2, s.Items[idx].RollUpRows)
+		assert.Equal(t, v.start*time.Second, s.Items[idx].StartAt)
+		assert.Equal(t, v.end*time.Second, s.Items[idx].EndAt)
+		assert.Equal(t, v.text, s.Items[idx].String())
+	}
+	assert.Equal(t, 2*time.Second, s.Items[2].Lines[0].StartAt)
+	assert.Equal(t, 5*time.Second, s.Items[2].Lines[0].EndAt)
+
+	// Round trip
+	s.ToPopOn(2)
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, "a - b", s.Items[0].String())
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, "c", s.Items[1].String())
+	assert.Equal(t, 3*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 5*time.Second, s.Items[1].EndAt)
+}
@@ -0,0 +1,748 @@
+package astisub
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"io"
+	"time"
+
+	"github.com/asticode/go-astilog"
+	"github.com/asticode/go-astits"
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrNoValidDVBPID = errors.New("astisub: no valid dvb PID")
+)
+
+// DVB syntax values
+const (
+	dvbObjectCodingPixels    = 0x0
+	dvbPESDataIdentifier     = 0x20
+	dvbPESSubtitleStreamID   = 0x0
+	dvbPageRegionLength      = 6
+	dvbPageStateModeChange   = 0x2
+	dvbRegionDepth2Bit       = 0x1
+	dvbRegionDepth4Bit       = 0x2
+	dvbRegionDepth8Bit       = 0x3
+	dvbRegionObjectLength    = 6
+	dvbRegionObjectTypeChar  = 0x1
+	dvbRegionObjectTypeChars = 0x2
+	dvbSegmentHeaderLength   = 6
+	dvbSegmentSyncByte       = 0x0f
+)
+
+// DVB segment types
+const (
+	dvbSegmentTypeCLUTDefinition    = 0x12
+	dvbSegmentTypeEndOfDisplaySet   = 0x80
+	dvbSegmentTypeObjectData        = 0x13
+	dvbSegmentTypePageComposition   = 0x10
+	dvbSegmentTypeRegionComposition = 0x11
+)
+
+// DVB pixel data types
+const (
+	dvbPixelDataType2BitCodeString = 0x10
+	dvbPixelDataType4BitCodeString = 0x11
+	dvbPixelDataType8BitCodeString = 0x12
+	dvbPixelDataTypeEndOfLine      = 0xf0
+	dvbPixelDataTypeMapTable2To4   = 0x20
+	dvbPixelDataTypeMapTable2To8   = 0x21
+	dvbPixelDataTypeMapTable4To8   = 0x22
+)
+
+// Default map tables used when pixel codes have less bits than their region
+var (
+	dvbMapTable2To4 = [4]uint8{0x0, 0x7, 0x8, 0xf}
+	dvbMapTable2To8 = [4]uint8{0x00, 0x77, 0x88, 0xff}
+	dvbMapTable4To8 = [16]uint8{0x00, 0x11, 0x22, 0x33, 0x44, 0x55, 0x66, 0x77, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff}
+)
+
+// DVBOptions represents DVB options
+type DVBOptions struct {
+	OCR OCROptions // Items keep their bitmap if no engine is provided
+	PID int
+}
+
+// ReadFromDVB parses DVB bitmap subtitles of a transport stream. Items hold a bitmap positioned by their inline style,
+// unless an OCR engine is provided in which case they hold the recognized text instead.
+// https://www.etsi.org/deliver/etsi_en/300700_300799/300743/01.06.01_60/en_300743v010601p.pdf
+func ReadFromDVB(r io.Reader, o DVBOptions) (s *Subtitles, err error) {
+	// Init
+	var dmx = astits.New(context.Background(), r)
+
+	// Get the DVB PID
+	var pid = uint16(o.PID)
+	if pid == 0 {
+		var ok bool
+		if pid, ok, err = pmtPID(dmx, pmtStreamHasDescriptor(astits.DescriptorTagSubtitling)); err != nil {
+			err = errors.Wrap(err, "astisub: getting dvb PID failed")
+			return
+		} else if !ok {
+			err = ErrNoValidDVBPID
+			return
+		}
+		astilog.Debugf("astisub: no dvb pid specified, using pid %d", pid)
+	}
+
+	// Create decoder
+	var dd = NewDVBDecoder()
+
+	// Loop in data
+	var d *astits.Data
+	for {
+		// Fetch next data
+		if d, err = dmx.NextData(); err != nil {
+			if err == astits.ErrNoMorePackets {
+				err = nil
+				break
+			}
+			err = errors.Wrap(err, "astisub: fetching next data failed")
+			return
+		}
+
+		// This data is not of interest to us
+		if d.PID != pid {
+			continue
+		}
+
+		// Decode
+		dd.Decode(d)
+	}
+
+	// Get subtitles
+	s = dd.Subtitles()
+
+	// Recognize bitmaps
+	if o.OCR.Engine != nil {
+		if err = s.RecognizeBitmaps(context.Background(), o.OCR); err != nil {
+			err = errors.Wrap(err, "astisub: recognizing bitmaps failed")
+			return
+		}
+	}
+	return
+}
+
+// DVBDecoder decodes DVB bitmap subtitles one demuxed data at a time. Filtering data on the DVB PID is up to the
+// caller. Composition and ancillary pages are decoded alike.
+type DVBDecoder struct {
+	cluts               map[uint8]*dvbCLUT
+	current             *dvbDisplay // Display whose end is unknown yet
+	ds                  []*dvbDisplay
+	firstTime, lastTime time.Time
+	page                *dvbPage // Page whose display set is being received
+	previousTime        time.Time
+	regions             map[uint8]*dvbRegion
+	rollovers           int
+}
+
+// dvbDisplay represents a bitmap displayed between 2 times
+type dvbDisplay struct {
+	end, start time.Time
+	img        image.Image
+	position   *Position
+}
+
+// dvbPage represents a page composition
+type dvbPage struct {
+	regions []dvbPageRegion
+	t       time.Time
+	timeout time.Duration
+}
+
+// dvbPageRegion represents a region displayed in a page
+type dvbPageRegion struct {
+	id   uint8
+	x, y int
+}
+
+// dvbRegion represents a region and its pixel codes
+type dvbRegion struct {
+	clutID        uint8
+	depth         uint8
+	height, width int
+	objects       []dvbRegionObject
+	pixels        []uint8
+}
+
+// dvbRegionObject represents an object displayed in a region
+type dvbRegionObject struct {
+	id   uint16
+	x, y int
+}
+
+// dvbCLUT represents a colour look-up table for each pixel depth
+type dvbCLUT struct {
+	c2 [4]color.NRGBA
+	c4 [16]color.NRGBA
+	c8 [256]color.NRGBA
+}
+
+// NewDVBDecoder creates a new DVB decoder
+func NewDVBDecoder() *DVBDecoder {
+	return &DVBDecoder{
+		cluts:   make(map[uint8]*dvbCLUT),
+		regions: make(map[uint8]*dvbRegion),
+	}
+}
+
+// Decode decodes a demuxed data
+func (dd *DVBDecoder) Decode(d *astits.Data) {
+	// We only parse PES data
+	if d.PES == nil || d.PES.Header == nil || d.PES.Header.StreamID != astits.StreamIDPrivateStream1 {
+		return
+	}
+
+	// Get time
+	t := teletextDataTime(d)
+	if t.IsZero() {
+		return
+	}
+	t = unwrapClockTime(t, &dd.previousTime, &dd.rollovers)
+
+	// First and last time
+	if dd.firstTime.IsZero() || dd.firstTime.After(t) {
+		dd.firstTime = t
+	}
+	if dd.lastTime.IsZero() || dd.lastTime.Before(t) {
+		dd.lastTime = t
+	}
+
+	// Not a DVB subtitle stream
+	var i = d.PES.Data
+	if len(i) < 2 || i[0] != dvbPESDataIdentifier || i[1] != dvbPESSubtitleStreamID {
+		return
+	}
+	i = i[2:]
+
+	// Loop through segments
+	for len(i) >= dvbSegmentHeaderLength && i[0] == dvbSegmentSyncByte {
+		// Get segment
+		var l = int(i[4])<<8 | int(i[5])
+		if len(i) < dvbSegmentHeaderLength+l {
+			break
+		}
+		var b = i[dvbSegmentHeaderLength : dvbSegmentHeaderLength+l]
+
+		// Parse segment
+		switch i[1] {
+		case dvbSegmentTypeCLUTDefinition:
+			dd.parseCLUTDefinition(b)
+		case dvbSegmentTypeEndOfDisplaySet:
+			dd.display()
+		case dvbSegmentTypeObjectData:
+			dd.parseObjectData(b)
+		case dvbSegmentTypePageComposition:
+			dd.parsePageComposition(b, t)
+		case dvbSegmentTypeRegionComposition:
+			dd.parseRegionComposition(b)
+		}
+		i = i[dvbSegmentHeaderLength+l:]
+	}
+}
+
+// Subtitles displays the page being received and returns the subtitles decoded so far. It should be called once all
+// data has been decoded. Times are relative to the first time decoded.
+func (dd *DVBDecoder) Subtitles() (s *Subtitles) {
+	// Display page being received
+	dd.display()
+
+	// Loop through displays
+	s = &Subtitles{}
+	for _, d := range dd.ds {
+		// Get end
+		var end = d.end
+		if end.IsZero() {
+			end = dd.lastTime
+		}
+		if !end.After(d.start) {
+			continue
+		}
+
+		// Append item
+		s.Items = append(s.Items, &Item{
+			Bitmap:      d.img,
+			EndAt:       end.Sub(dd.firstTime),
+			InlineStyle: &StyleAttributes{Position: d.position},
+			StartAt:     d.start.Sub(dd.firstTime),
+		})
+	}
+	return
+}
+
+// parsePageComposition parses a page composition segment, which starts a new display set
+func (dd *DVBDecoder) parsePageComposition(i []byte, t time.Time) {
+	// Invalid length
+	if len(i) < 2 {
+		return
+	}
+
+	// Display set has not been ended
+	dd.display()
+
+	// Mode change resets the decoder state
+	if (i[1]>>2)&0x3 == dvbPageStateModeChange {
+		dd.cluts = make(map[uint8]*dvbCLUT)
+		dd.regions = make(map[uint8]*dvbRegion)
+	}
+
+	// Create page
+	dd.page = &dvbPage{
+		t:       t,
+		timeout: time.Duration(i[0]) * time.Second,
+	}
+
+	// Loop through regions
+	for i = i[2:]; len(i) >= dvbPageRegionLength; i = i[dvbPageRegionLength:] {
+		dd.page.regions = append(dd.page.regions, dvbPageRegion{
+			id: i[0],
+			x:  int(i[2])<<8 | int(i[3]),
+			y:  int(i[4])<<8 | int(i[5]),
+		})
+	}
+}
+
+// parseRegionComposition parses a region composition segment
+func (dd *DVBDecoder) parseRegionComposition(i []byte) {
+	// Invalid length
+	if len(i) < 10 {
+		return
+	}
+
+	// Get region
+	var width, height = int(i[2])<<8 | int(i[3]), int(i[4])<<8 | int(i[5])
+	r, ok := dd.regions[i[0]]
+	if !ok || r.width != width || r.height != height {
+		r = &dvbRegion{
+			height: height,
+			pixels: make([]uint8, width*height),
+			width:  width,
+		}
+		dd.regions[i[0]] = r
+	}
+	r.clutID = i[7]
+	r.depth = (i[6] >> 2) & 0x7
+
+	// Fill region
+	if i[1]&0x8 > 0 {
+		var c = i[8]
+		switch r.depth {
+		case dvbRegionDepth2Bit:
+			c = (i[9] >> 2) & 0x3
+		case dvbRegionDepth4Bit:
+			c = i[9] >> 4
+		}
+		for idx := range r.pixels {
+			r.pixels[idx] = c
+		}
+	}
+
+	// Loop through objects
+	r.objects = []dvbRegionObject{}
+	for i = i[10:]; len(i) >= dvbRegionObjectLength; i = i[dvbRegionObjectLength:] {
+		r.objects = append(r.objects, dvbRegionObject{
+			id: uint16(i[0])<<8 | uint16(i[1]),
+			x:  int(i[2]&0xf)<<8 | int(i[3]),
+			y:  int(i[4]&0xf)<<8 | int(i[5]),
+		})
+
+		// Character objects have foreground and background pixel codes
+		if t := i[2] >> 6; (t == dvbRegionObjectTypeChar || t == dvbRegionObjectTypeChars) && len(i) >= dvbRegionObjectLength+2 {
+			i = i[2:]
+		}
+	}
+}
+
+// parseCLUTDefinition parses a CLUT definition segment
+func (dd *DVBDecoder) parseCLUTDefinition(i []byte) {
+	// Invalid length
+	if len(i) < 2 {
+		return
+	}
+
+	// Get CLUT
+	c, ok := dd.cluts[i[0]]
+	if !ok {
+		c = newDVBCLUT()
+		dd.cluts[i[0]] = c
+	}
+
+	// Loop through entries
+	for i = i[2:]; len(i) >= 2; {
+		// Get color
+		var id, flags = i[0], i[1]
+		var y, cr, cb, t uint8
+		if flags&0x1 > 0 {
+			if len(i) < 6 {
+				return
+			}
+			y, cr, cb, t = i[2], i[3], i[4], i[5]
+			i = i[6:]
+		} else {
+			if len(i) < 4 {
+				return
+			}
+			y = i[2] & 0xfc
+			cr = (i[2]&0x3)<<6 | (i[3]>>6)<<4
+			cb = ((i[3] >> 2) & 0xf) << 4
+			t = (i[3] & 0x3) << 6
+			i = i[4:]
+		}
+		var v = dvbColor(y, cr, cb, t)
+
+		// Set entries
+		if flags&0x80 > 0 && id < 4 {
+			c.c2[id] = v
+		}
+		if flags&0x40 > 0 && id < 16 {
+			c.c4[id] = v
+		}
+		if flags&0x20 > 0 {
+			c.c8[id] = v
+		}
+	}
+}
+
+// dvbColor converts a Y, Cr, Cb and transparency value to a color. A Y value of 0 means full transparency.
+func dvbColor(y, cr, cb, t uint8) color.NRGBA {
+	if y == 0 {
+		return color.NRGBA{}
+	}
+	r, g, b := color.YCbCrToRGB(y, cb, cr)
+	return color.NRGBA{A: 255 - t, B: b, G: g, R: r}
+}
+
+// newDVBCLUT creates a CLUT holding the default colors
+func newDVBCLUT() (c *dvbCLUT) {
+	// 2-bit entries are transparent, white, black and grey
+	c = &dvbCLUT{}
+	c.c2 = [4]color.NRGBA{{}, {A: 255, B: 255, G: 255, R: 255}, {A: 255}, {A: 255, B: 127, G: 127, R: 127}}
+
+	// 4-bit entries are bright colors then half bright colors
+	for idx := 1; idx < 16; idx++ {
+		var l = uint8(255)
+		if idx&0x8 > 0 {
+			l = 127
+		}
+		c.c4[idx] = color.NRGBA{A: 255, B: uint8(idx>>2&1) * l, G: uint8(idx>>1&1) * l, R: uint8(idx&1) * l}
+	}
+
+	// 8-bit entries
+	for idx := 1; idx < 256; idx++ {
+		var r, g, b = uint8(idx & 1), uint8(idx >> 1 & 1), uint8(idx >> 2 & 1)
+		var rh, gh, bh = uint8(idx >> 4 & 1), uint8(idx >> 5 & 1), uint8(idx >> 6 & 1)
+		switch idx & 0x88 {
+		case 0x0:
+			if idx&0x70 == 0 {
+				c.c8[idx] = color.NRGBA{A: 64, B: b * 255, G: g * 255, R: r * 255}
+			} else {
+				c.c8[idx] = color.NRGBA{A: 255, B: b*85 + bh*170, G: g*85 + gh*170, R: r*85 + rh*170}
+			}
+		case 0x8:
+			c.c8[idx] = color.NRGBA{A: 128, B: b*85 + bh*170, G: g*85 + gh*170, R: r*85 + rh*170}
+		case 0x80:
+			c.c8[idx] = color.NRGBA{A: 255, B: 127 + b*43 + bh*85, G: 127 + g*43 + gh*85, R: 127 + r*43 + rh*85}
+		default:
+			c.c8[idx] = color.NRGBA{A: 255, B: b*43 + bh*85, G: g*43 + gh*85, R: r*43 + rh*85}
+		}
+	}
+	return
+}
+
+// color returns the color of a pixel code depending on the depth
+func (c *dvbCLUT) color(depth, code uint8) color.NRGBA {
+	switch depth {
+	case dvbRegionDepth2Bit:
+		return c.c2[code&0x3]
+	case dvbRegionDepth4Bit:
+		return c.c4[code&0xf]
+	}
+	return c.c8[code]
+}
+
+// parseObjectData parses an object data segment and draws the object in the regions displaying it
+func (dd *DVBDecoder) parseObjectData(i []byte) {
+	// Only pixel objects are supported
+	if len(i) < 7 || (i[2]>>2)&0x3 != dvbObjectCodingPixels {
+		return
+	}
+
+	// Get fields
+	var id = uint16(i[0])<<8 | uint16(i[1])
+	var topLength, bottomLength = int(i[3])<<8 | int(i[4]), int(i[5])<<8 | int(i[6])
+	if len(i) < 7+topLength+bottomLength {
+		return
+	}
+	var top = i[7 : 7+topLength]
+	var bottom = i[7+topLength : 7+topLength+bottomLength]
+	if bottomLength == 0 {
+		bottom = top
+	}
+
+	// Loop through regions
+	for _, r := range dd.regions {
+		for _, o := range r.objects {
+			if o.id == id {
+				r.parsePixelData(top, o.x, o.y)
+				r.parsePixelData(bottom, o.x, o.y+1)
+			}
+		}
+	}
+}
+
+// parsePixelData parses the pixel data of an object's field and draws it in the region, lines of a field being
+// interlaced
+func (r *dvbRegion) parsePixelData(i []byte, x0, y0 int) {
+	var m24, m28, m48 = dvbMapTable2To4, dvbMapTable2To8, dvbMapTable4To8
+	var br = &dvbBitReader{b: i}
+	var x, y = x0, y0
+	for br.offset() < len(i) {
+		var depth uint8
+		var read func() (n int, c uint8, end bool)
+		switch br.read(8) {
+		case dvbPixelDataType2BitCodeString:
+			depth, read = dvbRegionDepth2Bit, br.read2BitCode
+		case dvbPixelDataType4BitCodeString:
+			depth, read = dvbRegionDepth4Bit, br.read4BitCode
+		case dvbPixelDataType8BitCodeString:
+			depth, read = dvbRegionDepth8Bit, br.read8BitCode
+		case dvbPixelDataTypeEndOfLine:
+			x = x0
+			y += 2
+			continue
+		case dvbPixelDataTypeMapTable2To4:
+			for idx := range m24 {
+				m24[idx] = br.read(4)
+			}
+			continue
+		case dvbPixelDataTypeMapTable2To8:
+			for idx := range m28 {
+				m28[idx] = br.read(8)
+			}
+			continue
+		case dvbPixelDataTypeMapTable4To8:
+			for idx := range m48 {
+				m48[idx] = br.read(8)
+			}
+			continue
+		default:
+			return
+		}
+
+		// Loop through codes
+		for {
+			n, c, end := read()
+			if end {
+				break
+			}
+
+			// Map code to the region depth
+			switch {
+			case depth == dvbRegionDepth2Bit && r.depth == dvbRegionDepth4Bit:
+				c = m24[c]
+			case depth == dvbRegionDepth2Bit && r.depth == dvbRegionDepth8Bit:
+				c = m28[c]
+			case depth == dvbRegionDepth4Bit && r.depth == dvbRegionDepth8Bit:
+				c = m48[c]
+			}
+
+			// Draw
+			r.set(x, y, n, c)
+			x += n
+		}
+		br.align()
+	}
+}
+
+// set sets n consecutive pixel codes of the region, pixels outside of the region being ignored
+func (r *dvbRegion) set(x, y, n int, c uint8) {
+	if y < 0 || y >= r.height {
+		return
+	}
+	for k := x; k < x+n && k < r.width; k++ {
+		if k >= 0 {
+			r.pixels[y*r.width+k] = c
+		}
+	}
+}
+
+// display ends the display set being received: the bitmap being displayed is removed and the page's regions are
+// displayed instead
+func (dd *DVBDecoder) display() {
+	// No display set
+	if dd.page == nil {
+		return
+	}
+	var p = dd.page
+	dd.page = nil
+
+	// Remove current bitmap
+	if dd.current != nil {
+		if dd.current.end.IsZero() || dd.current.end.After(p.t) {
+			dd.current.end = p.t
+		}
+		dd.current = nil
+	}
+
+	// Get bounding box
+	var rect image.Rectangle
+	for _, pr := range p.regions {
+		if r, ok := dd.regions[pr.id]; ok {
+			rect = rect.Union(image.Rect(pr.x, pr.y, pr.x+r.width, pr.y+r.height))
+		}
+	}
+	if rect.Empty() {
+		return
+	}
+
+	// Draw regions
+	var img = image.NewNRGBA(rect)
+	var visible bool
+	for _, pr := range p.regions {
+		// Get region
+		r, ok := dd.regions[pr.id]
+		if !ok {
+			continue
+		}
+
+		// Get CLUT
+		c, ok := dd.cluts[r.clutID]
+		if !ok {
+			c = newDVBCLUT()
+		}
+
+		// Loop through pixels
+		for y := 0; y < r.height; y++ {
+			for x := 0; x < r.width; x++ {
+				if v := c.color(r.depth, r.pixels[y*r.width+x]); v.A > 0 {
+					img.SetNRGBA(pr.x+x, pr.y+y, v)
+					visible = true
+				}
+			}
+		}
+	}
+
+	// Nothing is visible
+	if !visible {
+		return
+	}
+
+	// Add display
+	dd.current = &dvbDisplay{
+		img:      img,
+		position: &Position{X1: rect.Min.X, X2: rect.Max.X, Y1: rect.Min.Y, Y2: rect.Max.Y},
+		start:    p.t,
+	}
+	if p.timeout > 0 {
+		dd.current.end = p.t.Add(p.timeout)
+	}
+	dd.ds = append(dd.ds, dd.current)
+}
+
+// dvbBitReader reads bits MSB first, bits past the end being zeros
+type dvbBitReader struct {
+	b []byte
+	n int // Number of bits read
+}
+
+// read reads up to 8 bits
+func (br *dvbBitReader) read(n int) (v uint8) {
+	for ; n > 0; n-- {
+		v <<= 1
+		if br.n/8 < len(br.b) {
+			v |= (br.b[br.n/8] >> uint(7-br.n%8)) & 1
+		}
+		br.n++
+	}
+	return
+}
+
+// align skips bits until the next byte
+func (br *dvbBitReader) align() {
+	br.n = (br.n + 7) / 8 * 8
+}
+
+// offset returns the offset of the byte being read
+func (br *dvbBitReader) offset() int {
+	return br.n / 8
+}
+
+// read2BitCode reads a run of 2-bit pixel codes
+func (br *dvbBitReader) read2BitCode() (n int, c uint8, end bool) {
+	if c = br.read(2); c != 0 {
+		return 1, c, false
+	}
+	if br.read(1) == 1 {
+		n = 3 + int(br.read(3))
+		c = br.read(2)
+		return
+	}
+	if br.read(1) == 1 {
+		return 1, 0, false
+	}
+	switch br.read(2) {
+	case 0:
+		end = true
+	case 1:
+		n = 2
+	case 2:
+		n = 12 + int(br.read(4))
+		c = br.read(2)
+	default:
+		n = 29 + int(br.read(8))
+		c = br.read(2)
+	}
+	return
+}
+
+// read4BitCode reads a run of 4-bit pixel codes
+func (br *dvbBitReader) read4BitCode() (n int, c uint8, end bool) {
+	if c = br.read(4); c != 0 {
+		return 1, c, false
+	}
+	if br.read(1) == 0 {
+		if n = int(br.read(3)); n == 0 {
+			end = true
+		} else {
+			n += 2
+		}
+		return
+	}
+	if br.read(1) == 0 {
+		n = 4 + int(br.read(2))
+		c = br.read(4)
+		return
+	}
+	switch br.read(2) {
+	case 0:
+		n = 1
+	case 1:
+		n = 2
+	case 2:
+		n = 9 + int(br.read(4))
+		c = br.read(4)
+	default:
+		n = 25 + int(br.read(8))
+		c = br.read(4)
+	}
+	return
+}
+
+// read8BitCode reads a run of 8-bit pixel codes
+func (br *dvbBitReader) read8BitCode() (n int, c uint8, end bool) {
+	if c = br.read(8); c != 0 {
+		return 1, c, false
+	}
+	if br.read(1) == 0 {
+		if n = int(br.read(7)); n == 0 {
+			end = true
+		}
+		return
+	}
+	n = int(br.read(7))
+	c = br.read(8)
+	return
+}
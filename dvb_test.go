@@ -0,0 +1,69 @@
+package astisub_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astits"
+	"github.com/stretchr/testify/assert"
+)
+
+func dvbData(at time.Duration, segments ...[]byte) *astits.Data {
+	var b = []byte{0x20, 0x0}
+	for _, s := range segments {
+		b = append(b, s...)
+	}
+	b = append(b, 0xff)
+	return &astits.Data{PES: &astits.PESData{
+		Data: b,
+		Header: &astits.PESHeader{
+			OptionalHeader: &astits.PESOptionalHeader{PTS: &astits.ClockReference{Base: int64(at / time.Second * 90000)}},
+			StreamID:       astits.StreamIDPrivateStream1,
+		},
+	}}
+}
+
+func dvbSegment(t uint8, data ...byte) []byte {
+	return append([]byte{0x0f, t, 0x0, 0x1, uint8(len(data) >> 8), uint8(len(data))}, data...)
+}
+
+func TestDVBDecoder(t *testing.T) {
+	var pageComposition = dvbSegment(0x10, 0x5, 0x0, 0x0, 0x0, 0x0, 0xa, 0x0, 0x14)
+	dd := astisub.NewDVBDecoder()
+	dd.Decode(dvbData(time.Second,
+		pageComposition,
+		dvbSegment(0x11, 0x0, 0x8, 0x0, 0x4, 0x0, 0x2, 0x28, 0x0, 0x0, 0x0, 0x0, 0x0, 0x0, 0x1, 0x0, 0x0),
+		dvbSegment(0x12, 0x0, 0x0, 0x1, 0x41, 235, 128, 128, 0x0),
+		dvbSegment(0x13, 0x0, 0x0, 0x0, 0x0, 0x5, 0x0, 0x0, 0x11, 0x11, 0x10, 0x00, 0xf0),
+		dvbSegment(0x80),
+	))
+	dd.Decode(dvbData(3*time.Second, dvbSegment(0x10, 0x5, 0x0), dvbSegment(0x80)))
+	dd.Decode(dvbData(11*time.Second, pageComposition))
+	s := dd.Subtitles()
+
+	var img = image.NewNRGBA(image.Rect(10, 20, 14, 22))
+	for x := 11; x < 14; x++ {
+		for y := 20; y < 22; y++ {
+			img.SetNRGBA(x, y, color.NRGBA{A: 255, B: 235, G: 235, R: 235})
+		}
+	}
+	assert.Len(t, s.Items, 2)
+	for idx, i := range []*astisub.Item{
+		{EndAt: 2 * time.Second},
+		{EndAt: 15 * time.Second, StartAt: 10 * time.Second},
+	} {
+		assert.Equal(t, img, s.Items[idx].Bitmap)
+		assert.Equal(t, i.EndAt, s.Items[idx].EndAt)
+		assert.Equal(t, &astisub.Position{X1: 10, X2: 14, Y1: 20, Y2: 22}, s.Items[idx].InlineStyle.Position)
+		assert.Equal(t, i.StartAt, s.Items[idx].StartAt)
+	}
+}
+
+func TestReadFromDVB(t *testing.T) {
+	_, err := astisub.ReadFromDVB(bytes.NewReader([]byte{}), astisub.DVBOptions{})
+	assert.Equal(t, astisub.ErrNoValidDVBPID, err)
+}
@@ -0,0 +1,90 @@
+package astisub
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// The only file of the package relying on the file system: everything else only deals with io.Reader and
+// io.Writer so that the package compiles to targets without one (e.g. js/wasm)
+
+// Open opens a subtitle reader based on options
+func Open(o Options) (s *Subtitles, err error) {
+	// Get format
+	var f Format
+	if f, err = FormatFromExtension(filepath.Ext(o.Filename)); err != nil {
+		return
+	}
+
+	// Open the file
+	var fl *os.File
+	if fl, err = os.Open(o.Filename); err != nil {
+		err = errors.Wrapf(err, "astisub: opening %s failed", o.Filename)
+		return
+	}
+	defer fl.Close()
+
+	// Parse the content
+	s, err = Read(fl, f, o)
+	return
+}
+
+// OpenFile opens a file regardless of other options
+func OpenFile(filename string) (*Subtitles, error) {
+	return Open(Options{Filename: filename})
+}
+
+// Write writes subtitles to a file
+func (s Subtitles) Write(dst string) (err error) {
+	// Get format
+	var f Format
+	if f, err = FormatFromExtension(filepath.Ext(dst)); err != nil {
+		return
+	}
+
+	// Create the file
+	var fl *os.File
+	if fl, err = os.Create(dst); err != nil {
+		err = errors.Wrapf(err, "astisub: creating %s failed", dst)
+		return
+	}
+	defer fl.Close()
+
+	// Write the content
+	err = s.WriteToFormat(fl, f)
+	return
+}
+
+// WriteToDir writes every track of the set to a directory in a specific format
+// Filenames are the ones returned by Filenames
+func (s SubtitleSet) WriteToDir(dir string, f Format) (err error) {
+	for idx, fn := range s.Filenames(f) {
+		if err = s.Tracks[idx].Subtitles.Write(filepath.Join(dir, fn)); err != nil {
+			err = errors.Wrapf(err, "astisub: writing %s failed", fn)
+			return
+		}
+	}
+	return
+}
+
+// BatchOutputToDir returns a batch output writing converted files to a directory, keeping their relative paths
+func BatchOutputToDir(dir string) BatchOutput {
+	return func(p string) (w io.WriteCloser, err error) {
+		// Create directory
+		var dst = filepath.Join(dir, filepath.FromSlash(p))
+		if err = os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			err = errors.Wrapf(err, "astisub: creating directory of %s failed", dst)
+			return
+		}
+
+		// Create the file
+		if w, err = os.Create(dst); err != nil {
+			err = errors.Wrapf(err, "astisub: creating %s failed", dst)
+			return
+		}
+		return
+	}
+}
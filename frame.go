@@ -0,0 +1,79 @@
+package astisub
+
+import (
+	"math"
+	"time"
+)
+
+// Rounding represents the way a time is rounded to a frame boundary
+type Rounding int
+
+// Roundings
+const (
+	// Time is left untouched
+	RoundingNone Rounding = iota
+	// Time is rounded down to the previous frame boundary
+	RoundingFloor
+	// Time is rounded up to the next frame boundary
+	RoundingCeil
+	// Time is rounded to the nearest frame boundary
+	RoundingNearest
+)
+
+// FrameRounding represents the way cue times are rounded to frame boundaries when writing
+type FrameRounding struct {
+	// Defaults to the metadata framerate, then to 25. Fractional framerates such as 29.97 are supported.
+	Framerate float64
+	// Rounding of start times
+	In Rounding
+	// Rounding of end times
+	Out Rounding
+}
+
+// round rounds a time to a frame boundary
+func (r Rounding) round(d time.Duration, framerate float64) time.Duration {
+	// Get number of frames
+	// A small tolerance is applied so that times already on a frame boundary are not moved because of float errors
+	var frameDuration = float64(time.Second) / framerate
+	var frames = float64(d) / frameDuration
+	switch r {
+	case RoundingCeil:
+		frames = math.Ceil(frames - 1e-6)
+	case RoundingFloor:
+		frames = math.Floor(frames + 1e-6)
+	case RoundingNearest:
+		frames = math.Floor(frames + 0.5)
+	default:
+		return d
+	}
+	return time.Duration(math.Round(frames * frameDuration))
+}
+
+// applyFrameRounding returns subtitles whose cue times have been rounded to frame boundaries. Items are copied before
+// being modified so that the input subtitles are left untouched.
+func (s Subtitles) applyFrameRounding(r FrameRounding) (o Subtitles) {
+	// Nothing to round
+	o = s
+	if r.In == RoundingNone && r.Out == RoundingNone {
+		return
+	}
+
+	// Get framerate
+	var framerate = r.Framerate
+	if framerate <= 0 && s.Metadata != nil {
+		framerate = float64(s.Metadata.Framerate)
+	}
+	if framerate <= 0 {
+		framerate = defaultFramerate
+	}
+
+	// Loop through items
+	o.Items = make([]*Item, 0, len(s.Items))
+	for _, i := range s.Items {
+		var c = *i
+		c.StartAt = r.In.round(c.StartAt, framerate)
+		c.EndAt = r.Out.round(c.EndAt, framerate)
+		o.Items = append(o.Items, &c)
+	}
+	return
+}
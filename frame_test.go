@@ -0,0 +1,50 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFrameRounding(t *testing.T) {
+	var s = astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt:   2*time.Second + 13*time.Millisecond,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}},
+		StartAt: time.Second + 27*time.Millisecond,
+	}}}
+
+	// No rounding
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01.027 --> 00:00:02.013\n")
+
+	// Floor in, ceil out
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{FrameRounding: astisub.FrameRounding{In: astisub.RoundingFloor, Out: astisub.RoundingCeil}})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01.000 --> 00:00:02.040\n")
+	assert.Equal(t, time.Second+27*time.Millisecond, s.Items[0].StartAt)
+
+	// Nearest
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{FrameRounding: astisub.FrameRounding{In: astisub.RoundingNearest, Out: astisub.RoundingNearest}})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01,040 --> 00:00:02,000\n")
+
+	// Fractional framerate
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{FrameRounding: astisub.FrameRounding{Framerate: 29.97, In: astisub.RoundingNearest, Out: astisub.RoundingFloor}})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01,034 --> 00:00:02,002\n")
+
+	// Times already on frame boundaries are left untouched
+	s.Items[0].StartAt = time.Second + 40*time.Millisecond
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{FrameRounding: astisub.FrameRounding{In: astisub.RoundingCeil}})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01,040 --> 00:00:02,013\n")
+}
@@ -0,0 +1,161 @@
+package astisub
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Default glyph placeholder
+const defaultGlyphPlaceholder = "?"
+
+// GlyphPolicy represents the way writers handle characters, such as emojis, their target can't represent
+type GlyphPolicy int
+
+// Glyph policies
+const (
+	// Characters are replaced with their closest representable characters, e.g. "“" with "\"" or "ő" with "o", or
+	// with the placeholder if there are none
+	GlyphPolicyTransliterate GlyphPolicy = iota
+	// Characters are replaced with the placeholder
+	GlyphPolicyPlaceholder
+	// Characters are removed
+	GlyphPolicyDrop
+	// Writing fails with ErrUnrepresentableCharacter, located by its item, line and column
+	GlyphPolicyError
+)
+
+// Vars
+var glyphTransliterations = map[rune]string{
+	'‘': "'", '’': "'", '‚': "'", '‛': "'", '′': "'",
+	'“': "\"", '”': "\"", '„': "\"", '‟': "\"", '″': "\"", '«': "\"", '»': "\"",
+	'‐': "-", '‑': "-", '‒': "-", '–': "-", '—': "-", '―': "-", '−': "-",
+	'…': "...", '•': "*", '·': ".", '¦': "|",
+	'€': "EUR", '©': "(c)", '®': "(R)", '™': "TM",
+	'♪': "#", '♫': "#", '🎵': "#", '🎶': "#",
+	'Æ': "AE", 'æ': "ae", 'Œ': "OE", 'œ': "oe", 'ß': "ss", 'Ø': "O", 'ø': "o", 'Ł': "L", 'ł': "l",
+	'Đ': "D", 'đ': "d", 'Þ': "Th", 'þ': "th", 'ð': "d", 'ı': "i",
+}
+
+// glyphIgnorable checks whether a character only alters the previous one, such as variation selectors, emoji
+// modifiers and zero width joiners, in which case it's removed along with it rather than handled on its own
+func glyphIgnorable(r rune) bool {
+	return r == '\u200c' || r == '\u200d' || (r >= '\ufe00' && r <= '\ufe0f') || (r >= 0x1f3fb && r <= 0x1f3ff)
+}
+
+// glyphRepresentable checks whether every character of a text is representable
+func glyphRepresentable(t string, representable func(r rune) bool) bool {
+	for _, r := range t {
+		if !representable(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// transliterateGlyph returns the closest representable characters of a character
+func transliterateGlyph(r rune, representable func(r rune) bool) (t string, ok bool) {
+	// Known transliteration
+	if t, ok = glyphTransliterations[r]; ok && glyphRepresentable(t, representable) {
+		return
+	}
+
+	// White space
+	if unicode.IsSpace(r) && representable(' ') {
+		return " ", true
+	}
+
+	// Remove diacritics
+	var b strings.Builder
+	for _, c := range norm.NFD.String(string(r)) {
+		if !unicode.Is(unicode.Mn, c) {
+			b.WriteRune(c)
+		}
+	}
+	if t = b.String(); t != string(r) && glyphRepresentable(t, representable) {
+		return t, true
+	}
+	return "", false
+}
+
+// applyGlyphPolicy returns subtitles whose line item characters that are not representable have been handled
+// according to the policy. Texts are normalized to their composed form first. Items are copied before being modified
+// so that the input subtitles are left untouched.
+func (s Subtitles) applyGlyphPolicy(representable func(r rune) bool, p GlyphPolicy, placeholder string) (o Subtitles, err error) {
+	// Default placeholder
+	if len(placeholder) == 0 {
+		placeholder = defaultGlyphPlaceholder
+	}
+
+	// Loop through items
+	o = s
+	o.Items = make([]*Item, 0, len(s.Items))
+	for idx, i := range s.Items {
+		var c *Item
+		for idxLine, l := range i.Lines {
+			var column int
+			for idxLineItem, li := range l.Items {
+				// Loop through characters
+				var b strings.Builder
+				var joined, previous bool
+				for _, r := range norm.NFC.String(li.Text) {
+					// Character is representable
+					column++
+					if representable(r) {
+						b.WriteRune(r)
+						joined, previous = false, true
+						continue
+					}
+
+					// Character alters or is joined to a character that was not representable
+					if !previous && (joined || glyphIgnorable(r)) {
+						joined = r == '\u200d'
+						continue
+					}
+					previous = false
+
+					// Switch on policy
+					switch p {
+					case GlyphPolicyDrop:
+					case GlyphPolicyPlaceholder:
+						b.WriteString(placeholder)
+					case GlyphPolicyTransliterate:
+						if t, ok := transliterateGlyph(r, representable); ok {
+							b.WriteString(t)
+						} else {
+							b.WriteString(placeholder)
+						}
+					default:
+						err = errors.Wrapf(ErrUnrepresentableCharacter, "astisub: character %q at column %d of line #%d of item #%d can't be represented", r, column, idxLine+1, idx+1)
+						return
+					}
+				}
+				column++ // Line items are separated by a space
+
+				// Text has not changed
+				if b.String() == li.Text {
+					continue
+				}
+
+				// Copy item
+				if c == nil {
+					c = &Item{}
+					*c = *i
+					c.Lines = make([]Line, len(i.Lines))
+					for k := range i.Lines {
+						c.Lines[k] = i.Lines[k]
+						c.Lines[k].Items = append([]LineItem(nil), i.Lines[k].Items...)
+					}
+				}
+				c.Lines[idxLine].Items[idxLineItem].Text = b.String()
+			}
+		}
+		if c == nil {
+			c = i
+		}
+		o.Items = append(o.Items, c)
+	}
+	return
+}
@@ -0,0 +1,58 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGlyphPolicy(t *testing.T) {
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Déjà vu"}}}}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "Nice"}}},
+			{Items: []astisub.LineItem{{Text: "“Ciao”"}, {Text: "👍🏽 Őr 👨‍👩 $5"}}},
+		}, StartAt: 3 * time.Second},
+	}}
+
+	// STL
+	for _, v := range []struct {
+		e string
+		p astisub.GlyphPolicy
+	}{
+		{e: "\xaaCiao\xba * \xcdOr * \xa45", p: astisub.GlyphPolicyTransliterate},
+		{e: "\xaaCiao\xba * \xcdOr * \xa45", p: astisub.GlyphPolicyPlaceholder},
+		{e: "\xaaCiao\xba  \xcdOr  \xa45", p: astisub.GlyphPolicyDrop},
+	} {
+		w := &bytes.Buffer{}
+		err := s.WriteToSTLWithOptions(w, astisub.STLOptions{GlyphPlaceholder: "*", GlyphPolicy: v.p})
+		assert.NoError(t, err)
+		assert.Contains(t, w.String(), "D\xc2ej\xc1a vu")
+		assert.Contains(t, w.String(), "Nice\x8a"+v.e+"\x8f")
+	}
+	w := &bytes.Buffer{}
+	err := s.WriteToFormatWithOptions(w, astisub.FormatSTL, astisub.Options{GlyphPolicy: astisub.GlyphPolicyTransliterate})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Nice\x8a\xaaCiao\xba ? \xcdOr ? \xa45")
+	w.Reset()
+	err = s.WriteToSTL(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Nice\x8a\xaaCiao\xba ? \xcdOr ? \xa45")
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{GlyphPolicy: astisub.GlyphPolicyError})
+	assert.Equal(t, astisub.ErrUnrepresentableCharacter, errors.Cause(err))
+	assert.Contains(t, err.Error(), "character '👍' at column 8 of line #2 of item #2 can't be represented")
+	assert.Equal(t, "👍🏽 Őr 👨‍👩 $5", s.Items[1].Lines[1].Items[1].Text)
+
+	// Charset
+	w.Reset()
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{GlyphPolicy: astisub.GlyphPolicyTransliterate, WriteCharset: astisub.CharsetISO88591})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "D\xe9j\xe0 vu\n")
+	assert.Contains(t, w.String(), "\"Ciao\" ? Or ? $5\n")
+	err = s.WriteToFormatWithOptions(w, astisub.FormatSRT, astisub.Options{GlyphPolicy: astisub.GlyphPolicyError, WriteCharset: astisub.CharsetISO88591})
+	assert.Equal(t, astisub.ErrUnrepresentableCharacter, errors.Cause(err))
+}
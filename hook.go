@@ -0,0 +1,77 @@
+package astisub
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ItemHook is called for each item while reading or writing subtitles, which allows adjusting items without an
+// additional pass over them. Returning an error stops reading or writing.
+type ItemHook func(i *Item) error
+
+// itemHooker calls a hook on items as they're read, each item being hooked once and only once it's complete
+type itemHooker struct {
+	h ItemHook
+	n int // Number of items the hook has been called on
+}
+
+// hook calls the hook on the items it hasn't been called on yet. Readers call it once all items are complete, which
+// is usually when a new item starts and once the content has been parsed.
+func (h *itemHooker) hook(is []*Item) (err error) {
+	for ; h.n < len(is); h.n++ {
+		if h.h == nil {
+			continue
+		}
+		if err = h.h(is[h.n]); err != nil {
+			err = errors.Wrapf(err, "astisub: hook on item #%d failed", h.n+1)
+			return
+		}
+	}
+	return
+}
+
+// withItemHook returns a fill function calling a hook for each item it returns
+func withItemHook(fill func() ([]*Item, error), h ItemHook) func() ([]*Item, error) {
+	if h == nil {
+		return fill
+	}
+	var n int
+	return func() (is []*Item, err error) {
+		if is, err = fill(); err != nil {
+			return
+		}
+		for _, i := range is {
+			n++
+			if err = h(i); err != nil {
+				err = errors.Wrapf(err, "astisub: hook on item #%d failed", n)
+				return
+			}
+		}
+		return
+	}
+}
+
+// hookedItem returns a copy of an item a hook has been called on right before the item is written, so that the
+// input item is left untouched. Lines and line items are copied as well. The item is returned as is if there's no
+// hook.
+func hookedItem(i *Item, idx int, h ItemHook) (o *Item, err error) {
+	// No hook
+	if h == nil {
+		return i, nil
+	}
+
+	// Copy
+	var c = *i
+	c.Lines = make([]Line, len(i.Lines))
+	for idx, l := range i.Lines {
+		c.Lines[idx] = l
+		c.Lines[idx].Items = append([]LineItem(nil), l.Items...)
+	}
+
+	// Hook
+	if err = h(&c); err != nil {
+		err = errors.Wrapf(err, "astisub: hook on item #%d failed", idx+1)
+		return
+	}
+	o = &c
+	return
+}
@@ -0,0 +1,65 @@
+package astisub_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemHooks(t *testing.T) {
+	// Read
+	const c = "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nWorld\n"
+	var count int
+	o := astisub.Options{AfterReadItem: func(i *astisub.Item) error {
+		assert.Len(t, i.Lines, 1)
+		count++
+		i.Comments = append(i.Comments, "read")
+		return nil
+	}}
+	r, err := astisub.NewReader(strings.NewReader(c), astisub.FormatSRT, o)
+	assert.NoError(t, err)
+	for {
+		i, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"read"}, i.Comments)
+	}
+	assert.Equal(t, 2, count)
+	s, err := astisub.Read(strings.NewReader(c), astisub.FormatSRT, o)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read"}, s.Items[1].Comments)
+	_, err = astisub.Read(strings.NewReader(c), astisub.FormatSRT, astisub.Options{AfterReadItem: func(i *astisub.Item) error { return errors.New("invalid") }})
+	assert.EqualError(t, err, "astisub: hook on item #1 failed: invalid")
+	_, err = astisub.Read(strings.NewReader(c), astisub.FormatSRT, astisub.Options{AfterReadItem: func(i *astisub.Item) error {
+		if i.String() == "World" {
+			return errors.New("invalid")
+		}
+		return nil
+	}})
+	assert.EqualError(t, err, "astisub: hook on item #2 failed: invalid")
+
+	// Write
+	w := &bytes.Buffer{}
+	assert.NoError(t, s.WriteToFormatWithOptions(w, astisub.FormatSBV, astisub.Options{BeforeWriteItem: func(i *astisub.Item) error {
+		i.Lines[0].Items[0].Text += " ©"
+		return nil
+	}}))
+	assert.Equal(t, "0:00:01.000,0:00:02.000\nHello ©\n\n0:00:03.000,0:00:04.000\nWorld ©\n", w.String())
+	assert.Equal(t, "Hello", s.Items[0].String())
+	w.Reset()
+	assert.NoError(t, s.WriteToFormatWithOptions(w, astisub.FormatSTL, astisub.Options{BeforeWriteItem: func(i *astisub.Item) error {
+		i.Comments = append(i.Comments, "written")
+		return nil
+	}}))
+	s2, err := astisub.ReadFromSTL(w)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"read", "written"}, s2.Items[1].Comments)
+	assert.Error(t, s.WriteToFormatWithOptions(w, astisub.FormatSBV, astisub.Options{BeforeWriteItem: func(i *astisub.Item) error { return errors.New("invalid") }}))
+}
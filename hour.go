@@ -0,0 +1,164 @@
+package astisub
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default framerate of frame based formats
+const defaultFramerate = 25
+
+// ErrTimeOutOfRange is returned when a time can't be represented by a format
+var ErrTimeOutOfRange = errors.New("astisub: time out of range")
+
+// HourPolicy represents the way writers handle times exceeding the maximum time representable by their format
+type HourPolicy int
+
+// Hour policies
+const (
+	// Times are written as is, even though some readers may reject them
+	HourPolicyNone HourPolicy = iota
+	// Writing fails with ErrTimeOutOfRange
+	HourPolicyError
+	// Times roll over to 0, the way timecodes do after 24h. Items spanning the maximum time are split.
+	HourPolicyWrap
+	// Times are clamped to the maximum time
+	HourPolicyClamp
+)
+
+// timeBounds returns the exclusive upper bound of the times a format can represent as well as its precision. A bound
+// of 0 means times are unbounded.
+func timeBounds(f Format, framerate int) (bound, precision time.Duration) {
+	switch f {
+	case FormatSSA:
+		// H:MM:SS.cc
+		return 10 * time.Hour, 10 * time.Millisecond
+	case FormatSTL:
+		return timecodeBounds(framerate)
+	}
+	return
+}
+
+// timecodeBounds returns the bounds of HH:MM:SS:FF timecodes
+func timecodeBounds(framerate int) (bound, precision time.Duration) {
+	if framerate <= 0 {
+		framerate = defaultFramerate
+	}
+	return 24 * time.Hour, time.Second / time.Duration(framerate)
+}
+
+// MaxTime returns the maximum time a format can represent, 0 if times are unbounded. The framerate is only used by
+// frame based formats and defaults to 25.
+func (f Format) MaxTime(framerate int) time.Duration {
+	var bound, precision = timeBounds(f, framerate)
+	if bound == 0 {
+		return 0
+	}
+	return bound - precision
+}
+
+// ValidateTimes checks that every time of the subtitles can be represented by a format. The framerate is retrieved
+// from the metadata.
+func (s Subtitles) ValidateTimes(f Format) error {
+	var framerate int
+	if s.Metadata != nil {
+		framerate = s.Metadata.Framerate
+	}
+	var bound, precision = timeBounds(f, framerate)
+	_, err := s.applyHourPolicy(bound, precision, HourPolicyError)
+	return err
+}
+
+// applyHourPolicy returns subtitles whose times exceeding the bound have been handled according to the policy.
+// Items are copied before being modified so that the input subtitles are left untouched.
+func (s Subtitles) applyHourPolicy(bound, precision time.Duration, p HourPolicy) (o Subtitles, err error) {
+	// Times are unbounded or written as is
+	o = s
+	if bound == 0 || p == HourPolicyNone {
+		return
+	}
+
+	// Loop through items
+	o.Items = make([]*Item, 0, len(s.Items))
+	for idx, i := range s.Items {
+		// Times are within bounds
+		if i.StartAt < bound && i.EndAt < bound {
+			o.Items = append(o.Items, i)
+			continue
+		}
+
+		// Switch on policy
+		var c = *i
+		switch p {
+		case HourPolicyClamp:
+			if c.StartAt >= bound {
+				c.StartAt = bound - precision
+			}
+			if c.EndAt >= bound {
+				c.EndAt = bound - precision
+			}
+		case HourPolicyWrap:
+			splitItemAtBounds(i, bound, precision, func(_ int, c *Item) { o.Items = append(o.Items, c) })
+			continue
+		default:
+			err = errors.Wrapf(ErrTimeOutOfRange, "astisub: item #%d (%s --> %s) exceeds %s", idx+1, i.StartAt, i.EndAt, bound-precision)
+			return
+		}
+		o.Items = append(o.Items, &c)
+	}
+	return
+}
+
+// SplitAtMaxTime splits subtitles whose times exceed the maximum time a format can represent into consecutive parts
+// that can be written separately, e.g. one part per 24h of a live channel dump for timecode based formats. Times of
+// each part are relative to its start and items spanning several parts are split. The framerate is retrieved from the
+// metadata.
+func (s Subtitles) SplitAtMaxTime(f Format) (ps []*Subtitles) {
+	// Get bounds
+	var framerate int
+	if s.Metadata != nil {
+		framerate = s.Metadata.Framerate
+	}
+	var bound, precision = timeBounds(f, framerate)
+
+	// Times are unbounded
+	if bound == 0 || len(s.Items) == 0 {
+		return []*Subtitles{&s}
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		splitItemAtBounds(i, bound, precision, func(idx int, c *Item) {
+			// Add parts
+			for len(ps) <= idx {
+				var p = s
+				p.Items = nil
+				ps = append(ps, &p)
+			}
+
+			// Add item
+			ps[idx].Items = append(ps[idx].Items, c)
+		})
+	}
+	return
+}
+
+// splitItemAtBounds splits an item into copies, one per multiple of the bound it spans, whose times are relative to
+// the multiple they belong to
+func splitItemAtBounds(i *Item, bound, precision time.Duration, fn func(idx int, c *Item)) {
+	for idx := int(i.StartAt / bound); time.Duration(idx)*bound < i.EndAt; idx++ {
+		var c, offset = *i, time.Duration(idx) * bound
+		if c.StartAt < offset {
+			c.StartAt = offset
+		}
+		if c.EndAt >= offset+bound {
+			c.EndAt = offset + bound - precision
+		}
+		c.StartAt -= offset
+		c.EndAt -= offset
+		if c.EndAt > c.StartAt {
+			fn(idx, &c)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHourPolicy(t *testing.T) {
+	// Max time
+	assert.Equal(t, 24*time.Hour-40*time.Millisecond, astisub.FormatSTL.MaxTime(25))
+	assert.Equal(t, 10*time.Hour-10*time.Millisecond, astisub.FormatSSA.MaxTime(0))
+	assert.Equal(t, time.Duration(0), astisub.FormatSRT.MaxTime(0))
+
+	// Validate
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}, StartAt: time.Second},
+		{EndAt: 24*time.Hour + 2*time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "2"}}}}, StartAt: 23*time.Hour + 59*time.Minute + 59*time.Second},
+	}}
+	assert.NoError(t, s.ValidateTimes(astisub.FormatSRT))
+	assert.Equal(t, astisub.ErrTimeOutOfRange, errors.Cause(s.ValidateTimes(astisub.FormatSTL)))
+	assert.Equal(t, astisub.ErrTimeOutOfRange, errors.Cause(s.ValidateTimes(astisub.FormatSSA)))
+
+	// None
+	w := &bytes.Buffer{}
+	err := s.WriteToScenarist(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "0001\t00:00:01:00\t00:00:02:00\t1\n0002\t23:59:59:00\t24:00:02:00\t2\n", w.String())
+	assert.NoError(t, s.WriteToSTL(w))
+	assert.NoError(t, s.WriteToSSA(w))
+
+	// Error
+	err = s.WriteToScenaristWithOptions(w, astisub.ScenaristOptions{HourPolicy: astisub.HourPolicyError})
+	assert.Equal(t, astisub.ErrTimeOutOfRange, errors.Cause(err))
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{HourPolicy: astisub.HourPolicyError})
+	assert.Equal(t, astisub.ErrTimeOutOfRange, errors.Cause(err))
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{HourPolicy: astisub.HourPolicyError})
+	assert.Equal(t, astisub.ErrTimeOutOfRange, errors.Cause(err))
+
+	// Wrap
+	w.Reset()
+	err = s.WriteToScenaristWithOptions(w, astisub.ScenaristOptions{HourPolicy: astisub.HourPolicyWrap})
+	assert.NoError(t, err)
+	assert.Equal(t, "0001\t00:00:01:00\t00:00:02:00\t1\n0002\t23:59:59:00\t23:59:59:24\t2\n0003\t00:00:00:00\t00:00:02:00\t2\n", w.String())
+	assert.Equal(t, 24*time.Hour+2*time.Second, s.Items[1].EndAt)
+
+	// Clamp
+	w.Reset()
+	err = s.WriteToScenaristWithOptions(w, astisub.ScenaristOptions{HourPolicy: astisub.HourPolicyClamp})
+	assert.NoError(t, err)
+	assert.Equal(t, "0001\t00:00:01:00\t00:00:02:00\t1\n0002\t23:59:59:00\t23:59:59:24\t2\n", w.String())
+	w.Reset()
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{HourPolicy: astisub.HourPolicyClamp})
+	assert.NoError(t, err)
+	o, err := astisub.ReadFromSTL(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 24*time.Hour-40*time.Millisecond, o.Items[1].EndAt)
+}
+
+func TestLongContent(t *testing.T) {
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 10*time.Hour + 2*time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}, StartAt: 9*time.Hour + 59*time.Minute + 59*time.Second},
+		{EndAt: 100*time.Hour + 2*time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "2"}}}}, StartAt: 100*time.Hour + time.Second},
+	}}
+
+	// SRT
+	w := &bytes.Buffer{}
+	err := s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n09:59:59,000 --> 10:00:02,000\n1\n\n2\n100:00:01,000 --> 100:00:02,000\n2\n", w.String())
+	o, err := astisub.ReadFromSRT(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 100*time.Hour+2*time.Second, o.Items[1].EndAt)
+
+	// WebVTT
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n100:00:01.000 --> 100:00:02.000\n")
+	o, err = astisub.ReadFromWebVTT(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 100*time.Hour+2*time.Second, o.Items[1].EndAt)
+
+	// TTML
+	w.Reset()
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	o, err = astisub.ReadFromTTML(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, 100*time.Hour+2*time.Second, o.Items[1].EndAt)
+
+	// Split
+	assert.Len(t, s.SplitAtMaxTime(astisub.FormatSRT), 1)
+	ps := s.SplitAtMaxTime(astisub.FormatSSA)
+	assert.Len(t, ps, 11)
+	assert.Len(t, ps[0].Items, 1)
+	assert.Equal(t, 9*time.Hour+59*time.Minute+59*time.Second, ps[0].Items[0].StartAt)
+	assert.Equal(t, 10*time.Hour-10*time.Millisecond, ps[0].Items[0].EndAt)
+	assert.Len(t, ps[1].Items, 1)
+	assert.Equal(t, time.Duration(0), ps[1].Items[0].StartAt)
+	assert.Equal(t, 2*time.Second, ps[1].Items[0].EndAt)
+	assert.Empty(t, ps[5].Items)
+	assert.Len(t, ps[10].Items, 1)
+	assert.Equal(t, time.Second, ps[10].Items[0].StartAt)
+	assert.Equal(t, "2", ps[10].Items[0].String())
+	for _, p := range ps {
+		assert.NoError(t, p.ValidateTimes(astisub.FormatSSA))
+	}
+	ps = s.SplitAtMaxTime(astisub.FormatSTL)
+	assert.Len(t, ps, 5)
+	assert.Equal(t, 4*time.Hour+time.Second, ps[4].Items[0].StartAt)
+}
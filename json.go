@@ -0,0 +1,373 @@
+package astisub
+
+import (
+	"encoding/json"
+	"io"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrUnsupportedJSONVersion = errors.New("astisub: unsupported json version")
+	ErrUnknownJSONReference   = errors.New("astisub: unknown json reference")
+)
+
+// Version of the JSON schema, bumped whenever a change would break existing consumers
+const jsonVersion = 1
+
+// jsonSubtitles represents JSON subtitles
+type jsonSubtitles struct {
+	Items     []jsonItem
+	Markers   []Marker      `json:",omitempty"`
+	Metadata  *jsonMetadata `json:",omitempty"`
+	Preserved *Preserved    `json:",omitempty"`
+	Regions   []jsonRegion  `json:",omitempty"`
+	Styles    []jsonStyle   `json:",omitempty"`
+	Version   int
+}
+
+// jsonItem represents a JSON item
+type jsonItem struct {
+	AudioDescription *AudioDescription    `json:",omitempty"`
+	Comments         []string             `json:",omitempty"`
+	Confidence       *float64             `json:",omitempty"`
+	DisplayMode      DisplayMode          `json:",omitempty"`
+	EndAt            time.Duration        `json:",omitempty"`
+	Forced           bool                 `json:",omitempty"`
+	ID               string               `json:",omitempty"`
+	InlineStyle      *jsonStyleAttributes `json:",omitempty"`
+	Kind             ItemKind             `json:",omitempty"`
+	Language         string               `json:",omitempty"`
+	Lines            []jsonLine           `json:",omitempty"`
+	Preserved        *Preserved           `json:",omitempty"`
+	Provenance       *Provenance          `json:",omitempty"`
+	Region           string               `json:",omitempty"` // Region ID
+	RollUpRows       int                  `json:",omitempty"`
+	StartAt          time.Duration        `json:",omitempty"`
+	Style            string               `json:",omitempty"` // Style ID
+	Thumbnail        *Thumbnail           `json:",omitempty"`
+}
+
+// jsonLine represents a JSON line
+type jsonLine struct {
+	EndAt      time.Duration  `json:",omitempty"`
+	Items      []jsonLineItem `json:",omitempty"`
+	NewSpeaker bool           `json:",omitempty"`
+	StartAt    time.Duration  `json:",omitempty"`
+	VoiceName  string         `json:",omitempty"`
+}
+
+// jsonLineItem represents a JSON line item
+type jsonLineItem struct {
+	Confidence  *float64             `json:",omitempty"`
+	EndAt       time.Duration        `json:",omitempty"`
+	InlineStyle *jsonStyleAttributes `json:",omitempty"`
+	Karaoke     KaraokeEffect        `json:",omitempty"`
+	Language    string               `json:",omitempty"`
+	Provenance  *Provenance          `json:",omitempty"`
+	StartAt     time.Duration        `json:",omitempty"`
+	Style       string               `json:",omitempty"` // Style ID
+	Text        string
+}
+
+// jsonRegion represents a JSON region
+type jsonRegion struct {
+	ID          string
+	InlineStyle *jsonStyleAttributes `json:",omitempty"`
+	Style       string               `json:",omitempty"` // Style ID
+}
+
+// jsonStyle represents a JSON style
+type jsonStyle struct {
+	ID          string
+	InlineStyle *jsonStyleAttributes `json:",omitempty"`
+	Style       string               `json:",omitempty"` // Parent style ID
+}
+
+// jsonMetadata represents JSON metadata. Fields equal to their zero value are omitted.
+type jsonMetadata Metadata
+
+// MarshalJSON implements the json.Marshaler interface
+func (m *jsonMetadata) MarshalJSON() ([]byte, error) {
+	return marshalJSONNonZeroFields(m)
+}
+
+// jsonStyleAttributes represents JSON style attributes. Fields equal to their zero value are omitted.
+type jsonStyleAttributes StyleAttributes
+
+// MarshalJSON implements the json.Marshaler interface
+func (sa *jsonStyleAttributes) MarshalJSON() ([]byte, error) {
+	return marshalJSONNonZeroFields(sa)
+}
+
+// marshalJSONNonZeroFields marshals the fields of a struct that are not equal to their zero value
+func marshalJSONNonZeroFields(i interface{}) ([]byte, error) {
+	var v = reflect.ValueOf(i).Elem()
+	var m = make(map[string]interface{})
+	for idx := 0; idx < v.NumField(); idx++ {
+		var f = v.Field(idx)
+		if !reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+			m[v.Type().Field(idx).Name] = f.Interface()
+		}
+	}
+	return json.Marshal(m)
+}
+
+// ReadFromJSON parses a JSON content written by WriteToJSON
+func ReadFromJSON(i io.Reader) (o *Subtitles, err error) {
+	return readFromJSON(i, nil)
+}
+
+// readFromJSON parses a JSON content, calling a hook for each item once it's complete
+func readFromJSON(i io.Reader, h ItemHook) (o *Subtitles, err error) {
+	// Unmarshal
+	var js jsonSubtitles
+	if err = json.NewDecoder(i).Decode(&js); err != nil {
+		err = errors.Wrap(err, "astisub: decoding json failed")
+		return
+	}
+
+	// Check version
+	if js.Version > jsonVersion {
+		err = errors.Wrapf(ErrUnsupportedJSONVersion, "astisub: version %d is not supported", js.Version)
+		return
+	}
+
+	// Init
+	o = NewSubtitles()
+	o.Markers = js.Markers
+	o.Metadata = (*Metadata)(js.Metadata)
+	o.Preserved = js.Preserved
+
+	// Create styles first so that they can reference each other regardless of their order
+	for _, s := range js.Styles {
+		o.Styles[s.ID] = &Style{ID: s.ID, InlineStyle: (*StyleAttributes)(s.InlineStyle)}
+	}
+	for _, s := range js.Styles {
+		if o.Styles[s.ID].Style, err = o.jsonStyle(s.Style); err != nil {
+			return
+		}
+	}
+
+	// Loop through regions
+	for _, r := range js.Regions {
+		var s *Style
+		if s, err = o.jsonStyle(r.Style); err != nil {
+			return
+		}
+		o.Regions[r.ID] = &Region{ID: r.ID, InlineStyle: (*StyleAttributes)(r.InlineStyle), Style: s}
+	}
+
+	// Loop through items
+	var hk = &itemHooker{h: h}
+	for _, ji := range js.Items {
+		// Init item
+		var i = &Item{
+			AudioDescription: ji.AudioDescription,
+			Comments:         ji.Comments,
+			Confidence:       ji.Confidence,
+			DisplayMode:      ji.DisplayMode,
+			EndAt:            ji.EndAt,
+			Forced:           ji.Forced,
+			ID:               ji.ID,
+			InlineStyle:      (*StyleAttributes)(ji.InlineStyle),
+			Kind:             ji.Kind,
+			Language:         ji.Language,
+			Preserved:        ji.Preserved,
+			Provenance:       ji.Provenance,
+			RollUpRows:       ji.RollUpRows,
+			StartAt:          ji.StartAt,
+			Thumbnail:        ji.Thumbnail,
+		}
+		if i.Style, err = o.jsonStyle(ji.Style); err != nil {
+			return
+		}
+		if len(ji.Region) > 0 {
+			var ok bool
+			if i.Region, ok = o.Regions[ji.Region]; !ok {
+				err = errors.Wrapf(ErrUnknownJSONReference, "astisub: region %s doesn't exist", ji.Region)
+				return
+			}
+		}
+
+		// Loop through lines
+		for _, jl := range ji.Lines {
+			var l = Line{
+				EndAt:      jl.EndAt,
+				NewSpeaker: jl.NewSpeaker,
+				StartAt:    jl.StartAt,
+				VoiceName:  jl.VoiceName,
+			}
+			for _, jli := range jl.Items {
+				var li = LineItem{
+					Confidence:  jli.Confidence,
+					EndAt:       jli.EndAt,
+					InlineStyle: (*StyleAttributes)(jli.InlineStyle),
+					Karaoke:     jli.Karaoke,
+					Language:    jli.Language,
+					Provenance:  jli.Provenance,
+					StartAt:     jli.StartAt,
+					Text:        jli.Text,
+				}
+				if li.Style, err = o.jsonStyle(jli.Style); err != nil {
+					return
+				}
+				l.Items = append(l.Items, li)
+			}
+			i.Lines = append(i.Lines, l)
+		}
+		o.Items = append(o.Items, i)
+		if err = hk.hook(o.Items); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// jsonStyle returns the style referenced by an ID, nil if the ID is empty
+func (s Subtitles) jsonStyle(id string) (st *Style, err error) {
+	if len(id) == 0 {
+		return
+	}
+	var ok bool
+	if st, ok = s.Styles[id]; !ok {
+		err = errors.Wrapf(ErrUnknownJSONReference, "astisub: style %s doesn't exist", id)
+		return
+	}
+	return
+}
+
+// WriteToJSON writes subtitles in JSON, which allows consuming and producing the full model without implementing a
+// subtitle format.
+//
+// The schema is stable: its Version field is only bumped on breaking changes. Keys are the names of the fields of
+// the model, fields equal to their zero value being omitted. Times are in nanoseconds and colors are objects with
+// Alpha, Blue, Green and Red keys. Styles and regions are listed once, ordered by ID, and referenced by their ID by
+// items, line items, regions and parent styles, which is why styles that are referenced but not listed in the
+// subtitles' styles are listed as well. Bitmaps and warnings are not written.
+func (s Subtitles) WriteToJSON(o io.Writer) error {
+	return s.writeToJSON(o, nil)
+}
+
+// writeToJSON writes subtitles in JSON, calling a hook for each item right before it's written
+func (s Subtitles) writeToJSON(o io.Writer, h ItemHook) (err error) {
+	// Init
+	var js = jsonSubtitles{
+		Markers:   s.Markers,
+		Metadata:  (*jsonMetadata)(s.Metadata),
+		Preserved: s.Preserved,
+		Version:   jsonVersion,
+	}
+	var styles = make(map[string]*Style)
+	var addStyle func(st *Style) string
+	addStyle = func(st *Style) string {
+		if st == nil {
+			return ""
+		}
+		if _, ok := styles[st.ID]; !ok {
+			styles[st.ID] = st
+			addStyle(st.Style)
+		}
+		return st.ID
+	}
+	var regions = make(map[string]*Region)
+	for _, st := range s.Styles {
+		addStyle(st)
+	}
+	for _, r := range s.Regions {
+		regions[r.ID] = r
+	}
+
+	// Loop through items
+	js.Items = make([]jsonItem, 0, len(s.Items))
+	for idx, i := range s.Items {
+		// Hook
+		if i, err = hookedItem(i, idx, h); err != nil {
+			return
+		}
+
+		// Init item
+		var ji = jsonItem{
+			AudioDescription: i.AudioDescription,
+			Comments:         i.Comments,
+			Confidence:       i.Confidence,
+			DisplayMode:      i.DisplayMode,
+			EndAt:            i.EndAt,
+			Forced:           i.Forced,
+			ID:               i.ID,
+			InlineStyle:      (*jsonStyleAttributes)(i.InlineStyle),
+			Kind:             i.Kind,
+			Language:         i.Language,
+			Preserved:        i.Preserved,
+			Provenance:       i.Provenance,
+			RollUpRows:       i.RollUpRows,
+			StartAt:          i.StartAt,
+			Style:            addStyle(i.Style),
+			Thumbnail:        i.Thumbnail,
+		}
+		if i.Region != nil {
+			ji.Region = i.Region.ID
+			if _, ok := regions[i.Region.ID]; !ok {
+				regions[i.Region.ID] = i.Region
+			}
+		}
+
+		// Loop through lines
+		for _, l := range i.Lines {
+			var jl = jsonLine{
+				EndAt:      l.EndAt,
+				NewSpeaker: l.NewSpeaker,
+				StartAt:    l.StartAt,
+				VoiceName:  l.VoiceName,
+			}
+			for _, li := range l.Items {
+				jl.Items = append(jl.Items, jsonLineItem{
+					Confidence:  li.Confidence,
+					EndAt:       li.EndAt,
+					InlineStyle: (*jsonStyleAttributes)(li.InlineStyle),
+					Karaoke:     li.Karaoke,
+					Language:    li.Language,
+					Provenance:  li.Provenance,
+					StartAt:     li.StartAt,
+					Style:       addStyle(li.Style),
+					Text:        li.Text,
+				})
+			}
+			ji.Lines = append(ji.Lines, jl)
+		}
+		js.Items = append(js.Items, ji)
+	}
+
+	// Add regions
+	for _, r := range regions {
+		js.Regions = append(js.Regions, jsonRegion{
+			ID:          r.ID,
+			InlineStyle: (*jsonStyleAttributes)(r.InlineStyle),
+			Style:       addStyle(r.Style),
+		})
+	}
+	sort.Slice(js.Regions, func(a, b int) bool { return js.Regions[a].ID < js.Regions[b].ID })
+
+	// Add styles
+	for _, st := range styles {
+		js.Styles = append(js.Styles, jsonStyle{
+			ID:          st.ID,
+			InlineStyle: (*jsonStyleAttributes)(st.InlineStyle),
+			Style:       addStyle(st.Style),
+		})
+	}
+	sort.Slice(js.Styles, func(a, b int) bool { return js.Styles[a].ID < js.Styles[b].ID })
+
+	// Marshal
+	var e = json.NewEncoder(o)
+	e.SetIndent("", "  ")
+	if err = e.Encode(js); err != nil {
+		err = errors.Wrap(err, "astisub: encoding json failed")
+		return
+	}
+	return
+}
@@ -0,0 +1,146 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	// Init
+	var parent = &astisub.Style{ID: "parent", InlineStyle: &astisub.StyleAttributes{SSAFontSize: astiptr.Float(20)}}
+	var child = &astisub.Style{ID: "child", InlineStyle: &astisub.StyleAttributes{SSABold: astiptr.Bool(true)}, Style: parent}
+	var s = astisub.NewSubtitles()
+	s.Items = []*astisub.Item{{
+		EndAt:  2 * time.Second,
+		Lines:  []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{WebVTTColor: astisub.ColorRed}, Style: child, Text: "Hello"}}, VoiceName: "Bob"}},
+		Region: &astisub.Region{ID: "top", InlineStyle: &astisub.StyleAttributes{WebVTTLine: "10%"}},
+	}}
+	s.Metadata = &astisub.Metadata{Language: "en"}
+	s.Styles["child"] = child
+
+	// Write
+	w := &bytes.Buffer{}
+	err := s.WriteToJSON(w)
+	assert.NoError(t, err)
+	assert.Equal(t, `{
+  "Items": [
+    {
+      "EndAt": 2000000000,
+      "Lines": [
+        {
+          "Items": [
+            {
+              "InlineStyle": {
+                "WebVTTColor": {
+                  "Alpha": 0,
+                  "Blue": 0,
+                  "Green": 0,
+                  "Red": 255
+                }
+              },
+              "Style": "child",
+              "Text": "Hello"
+            }
+          ],
+          "VoiceName": "Bob"
+        }
+      ],
+      "Region": "top"
+    }
+  ],
+  "Metadata": {
+    "Language": "en"
+  },
+  "Regions": [
+    {
+      "ID": "top",
+      "InlineStyle": {
+        "WebVTTLine": "10%"
+      }
+    }
+  ],
+  "Styles": [
+    {
+      "ID": "child",
+      "InlineStyle": {
+        "SSABold": true
+      },
+      "Style": "parent"
+    },
+    {
+      "ID": "parent",
+      "InlineStyle": {
+        "SSAFontSize": 20
+      }
+    }
+  ],
+  "Version": 1
+}
+`, w.String())
+
+	// Read
+	s2, err := astisub.ReadFromJSON(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, s.Items, s2.Items)
+	assert.Equal(t, s.Metadata, s2.Metadata)
+	assert.Equal(t, map[string]*astisub.Style{"child": child, "parent": parent}, s2.Styles)
+	assert.Same(t, s2.Styles["parent"], s2.Styles["child"].Style)
+	assert.Same(t, s2.Styles["child"], s2.Items[0].Lines[0].Items[0].Style)
+	assert.Same(t, s2.Regions["top"], s2.Items[0].Region)
+
+	// Errors
+	_, err = astisub.ReadFromJSON(strings.NewReader(`{"Items":[{"Style":"invalid"}],"Version":1}`))
+	assert.Error(t, err)
+	_, err = astisub.ReadFromJSON(strings.NewReader(`{"Version":2}`))
+	assert.Error(t, err)
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	// Create temp dir
+	dir, err := ioutil.TempDir("", "astisub")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Loop through formats
+	for _, ext := range []string{"srt", "ssa", "ttml", "vtt"} {
+		s, err := astisub.OpenFile("./testdata/example-in." + ext)
+		assert.NoError(t, err, ext)
+		s.Warnings = nil
+
+		// Write
+		var dst = filepath.Join(dir, ext+".json")
+		err = s.Write(dst)
+		assert.NoError(t, err, ext)
+
+		// Detect
+		fl, err := os.Open(dst)
+		assert.NoError(t, err, ext)
+		f, _, err := astisub.DetectFormat(fl)
+		fl.Close()
+		assert.NoError(t, err, ext)
+		assert.Equal(t, astisub.FormatJSON, f, ext)
+
+		// Open
+		s2, err := astisub.OpenFile(dst)
+		assert.NoError(t, err, ext)
+
+		// Subtitles are written the same way
+		f, err = astisub.FormatFromExtension("." + ext)
+		assert.NoError(t, err, ext)
+		for _, f := range []astisub.Format{astisub.FormatJSON, f} {
+			w1, w2 := &bytes.Buffer{}, &bytes.Buffer{}
+			assert.NoError(t, s.WriteToFormat(w1, f), ext)
+			assert.NoError(t, s2.WriteToFormat(w2, f), ext)
+			assert.Equal(t, w1.String(), w2.String(), ext)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package astisub
+
+// KaraokeEffect represents the way a karaoke syllable is highlighted while it's sung
+type KaraokeEffect string
+
+// Karaoke effects
+const (
+	// The syllable is filled progressively from left to right (\kf and \K in SSA)
+	KaraokeEffectFill KaraokeEffect = "kf"
+	// The syllable is highlighted at once when it starts (\k in SSA)
+	KaraokeEffectInstant KaraokeEffect = "k"
+	// The syllable's outline is removed at once when it starts (\ko in SSA)
+	KaraokeEffectOutline KaraokeEffect = "ko"
+)
+
+// isKaraoke checks whether the line item is a karaoke syllable
+func (li LineItem) isKaraoke() bool {
+	return len(li.Karaoke) > 0
+}
+
+// KaraokeLineItems returns the karaoke syllables of the item, in order
+func (i Item) KaraokeLineItems() (lis []LineItem) {
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			if li.isKaraoke() {
+				lis = append(lis, li)
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,86 @@
+package astisub
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ItemKind represents what an item transcribes, which SDH tracks tell apart so that outlets can style or remove
+// non-dialogue items
+type ItemKind string
+
+// Item kinds
+const (
+	ItemKindDialogue ItemKind = ""
+	ItemKindMusic    ItemKind = "music"
+	ItemKindSFX      ItemKind = "sfx"
+)
+
+// Sound descriptions between brackets or parentheses, such as "[door slams]"
+const itemKindPatternDescription = `\[[^\]]*\]|\([^)]*\)`
+
+// Vars
+var (
+	// Texts made only of descriptions between brackets or parentheses, possibly surrounded by music notes
+	itemKindRegexpDescription = regexp.MustCompile(`^(\s*(` + itemKindPatternDescription + `|[♪♫]))+\s*$`)
+	// Descriptions between brackets or parentheses anywhere in a text
+	itemKindRegexpDescriptions = regexp.MustCompile(itemKindPatternDescription)
+	// Words describing music in descriptions
+	itemKindMusicWords = []string{"music", "musik", "musique", "música", "musica", "muziek", "song", "singing"}
+)
+
+// itemKind returns the kind of an item detected from its text
+func itemKind(i *Item) ItemKind {
+	// No text
+	var t = strings.TrimSpace(i.String())
+	if len(t) == 0 {
+		return ItemKindDialogue
+	}
+
+	// Lyrics
+	if strings.IndexAny(t, "♪♫") == 0 || strings.HasSuffix(t, "♪") || strings.HasSuffix(t, "♫") {
+		return ItemKindMusic
+	}
+
+	// Descriptions
+	if itemKindRegexpDescription.MatchString(t) {
+		var l = strings.ToLower(t)
+		for _, w := range itemKindMusicWords {
+			if strings.Contains(l, w) {
+				return ItemKindMusic
+			}
+		}
+		return ItemKindSFX
+	}
+	return ItemKindDialogue
+}
+
+// DetectItemKinds sets the kind of items made only of music notes, such as "♪ Happy birthday ♪", or of sound
+// descriptions between brackets or parentheses, such as "[door slams]", descriptions of music, such as
+// "[upbeat music]", being music items. Other items are considered as dialogue.
+func (s *Subtitles) DetectItemKinds() {
+	for _, i := range s.Items {
+		i.Kind = itemKind(i)
+	}
+}
+
+// ItemHasKind returns a predicate matching items of a kind, for instance to style music items with ApplyInlineStyle
+func ItemHasKind(k ItemKind) func(i *Item) bool {
+	return func(i *Item) bool {
+		return i.Kind == k
+	}
+}
+
+// RemoveItems removes the items matching the predicate, for instance sound effect items with ItemHasKind
+func (s *Subtitles) RemoveItems(predicate func(i *Item) bool) {
+	var is = s.Items[:0]
+	for _, i := range s.Items {
+		if !predicate(i) {
+			is = append(is, i)
+		}
+	}
+	for idx := len(is); idx < len(s.Items); idx++ {
+		s.Items[idx] = nil
+	}
+	s.Items = is
+}
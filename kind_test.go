@@ -0,0 +1,36 @@
+package astisub_test
+
+import (
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_DetectItemKinds(t *testing.T) {
+	// Detect
+	s, err := astisub.OpenFile("./testdata/example-in.srt")
+	assert.NoError(t, err)
+	s.Items = append(s.Items,
+		&astisub.Item{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "♪ Happy birthday"}}}, {Items: []astisub.LineItem{{Text: "to you ♪"}}}}},
+		&astisub.Item{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "[upbeat music]"}}}}},
+		&astisub.Item{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "MAN: (laughs)"}}}}},
+	)
+	s.DetectItemKinds()
+	var kinds []astisub.ItemKind
+	for _, i := range s.Items {
+		kinds = append(kinds, i.Kind)
+	}
+	assert.Equal(t, []astisub.ItemKind{astisub.ItemKindSFX, astisub.ItemKindDialogue, astisub.ItemKindDialogue, astisub.ItemKindDialogue, astisub.ItemKindDialogue, astisub.ItemKindSFX, astisub.ItemKindMusic, astisub.ItemKindMusic, astisub.ItemKindDialogue}, kinds)
+
+	// Style
+	s.ApplyInlineStyle(&astisub.StyleAttributes{SRTItalics: astiptr.Bool(true)}, astisub.ItemHasKind(astisub.ItemKindMusic))
+	assert.True(t, *s.Items[6].InlineStyle.SRTItalics)
+	assert.Nil(t, s.Items[8].InlineStyle)
+
+	// Remove
+	s.RemoveItems(astisub.ItemHasKind(astisub.ItemKindSFX))
+	assert.Len(t, s.Items, 7)
+	assert.Equal(t, "MAN: - How did we end up here?", s.Items[0].String())
+}
@@ -1,7 +1,262 @@
 package astisub
 
+import (
+	"strings"
+	"unicode"
+)
+
 // Languages
 const (
-	LanguageEnglish = "english"
-	LanguageFrench  = "french"
+	LanguageArabic     = "arabic"
+	LanguageChinese    = "chinese"
+	LanguageDutch      = "dutch"
+	LanguageEnglish    = "english"
+	LanguageFrench     = "french"
+	LanguageGerman     = "german"
+	LanguageGreek      = "greek"
+	LanguageHebrew     = "hebrew"
+	LanguageItalian    = "italian"
+	LanguageJapanese   = "japanese"
+	LanguageKorean     = "korean"
+	LanguagePortuguese = "portuguese"
+	LanguageRussian    = "russian"
+	LanguageSpanish    = "spanish"
+	LanguageThai       = "thai"
 )
+
+// Primary subtags of the BCP-47 tags of languages
+var languageSubtags = map[string]string{
+	LanguageArabic:     "ar",
+	LanguageChinese:    "zh",
+	LanguageDutch:      "nl",
+	LanguageEnglish:    "en",
+	LanguageFrench:     "fr",
+	LanguageGerman:     "de",
+	LanguageGreek:      "el",
+	LanguageHebrew:     "he",
+	LanguageItalian:    "it",
+	LanguageJapanese:   "ja",
+	LanguageKorean:     "ko",
+	LanguagePortuguese: "pt",
+	LanguageRussian:    "ru",
+	LanguageSpanish:    "es",
+	LanguageThai:       "th",
+}
+
+// ISO 639-2 terminology codes of languages
+var languageISO6392Codes = map[string]string{
+	LanguageArabic:     "ara",
+	LanguageChinese:    "zho",
+	LanguageDutch:      "nld",
+	LanguageEnglish:    "eng",
+	LanguageFrench:     "fra",
+	LanguageGerman:     "deu",
+	LanguageGreek:      "ell",
+	LanguageHebrew:     "heb",
+	LanguageItalian:    "ita",
+	LanguageJapanese:   "jpn",
+	LanguageKorean:     "kor",
+	LanguagePortuguese: "por",
+	LanguageRussian:    "rus",
+	LanguageSpanish:    "spa",
+	LanguageThai:       "tha",
+}
+
+// ISO 639-2 terminology codes indexed by the bibliographic codes that differ from them
+var languageISO6392BibliographicCodes = map[string]string{
+	"chi": "zho",
+	"dut": "nld",
+	"fre": "fra",
+	"ger": "deu",
+	"gre": "ell",
+}
+
+// LanguageFromISO6392 returns the language of an ISO 639-2 code, such as the codes used by containers. Both
+// bibliographic codes, such as "fre", and terminology codes, such as "fra", are supported.
+func LanguageFromISO6392(code string) (language string, ok bool) {
+	code = strings.ToLower(code)
+	if c, okBibliographic := languageISO6392BibliographicCodes[code]; okBibliographic {
+		code = c
+	}
+	for l, c := range languageISO6392Codes {
+		if c == code {
+			return l, true
+		}
+	}
+	return
+}
+
+// LanguageISO6392 returns the ISO 639-2 terminology code of a language
+func LanguageISO6392(language string) (code string, ok bool) {
+	code, ok = languageISO6392Codes[language]
+	return
+}
+
+// languageFromTag returns the language of a BCP-47 tag such as "en-US", the tag itself if its language is unknown
+func languageFromTag(tag string) string {
+	var subtag = tag
+	if idx := strings.IndexAny(tag, "-_"); idx >= 0 {
+		subtag = tag[:idx]
+	}
+	subtag = strings.ToLower(subtag)
+	for l, t := range languageSubtags {
+		if t == subtag {
+			return l
+		}
+	}
+	return tag
+}
+
+// LanguageTag returns the BCP-47 tag of a language, the language itself if it's not an astisub language, which is
+// the case of languages that are already tags
+func LanguageTag(language string) string {
+	if t, ok := languageSubtags[language]; ok {
+		return t
+	}
+	return language
+}
+
+// languageScripts are the languages detected by their script alone
+var languageScripts = []struct {
+	language string
+	table    *unicode.RangeTable
+}{
+	{language: LanguageArabic, table: unicode.Arabic},
+	{language: LanguageGreek, table: unicode.Greek},
+	{language: LanguageHebrew, table: unicode.Hebrew},
+	{language: LanguageKorean, table: unicode.Hangul},
+	{language: LanguageRussian, table: unicode.Cyrillic},
+	{language: LanguageThai, table: unicode.Thai},
+}
+
+// languageWords are the most frequent words of languages written in the latin script, whose trigrams make the
+// profiles texts are compared to
+var languageWords = map[string]string{
+	LanguageDutch:      "de het een en van ik je dat niet is op te zijn met voor er maar wat die dit hij ze heb hebben naar ook nog wel geen kan",
+	LanguageEnglish:    "the and you that was for are with his they this have from what not but all were when your can there been one would will know just here",
+	LanguageFrench:     "les des est que une pas pour dans qui sur avec vous nous mais tout elle sont cette comme bien fait être était aussi très ça il je le la et",
+	LanguageGerman:     "der die und das ist nicht ich sie mit den ein eine auch auf sich dem es wir was wie aber noch hat sind mir für bin schon nur kann",
+	LanguageItalian:    "che non il di per una sono con gli della del ma questo come anche io hai mi ti cosa tutto bene sei è perché molto siamo lo nel",
+	LanguagePortuguese: "que não os uma com para é um de do da em se por mais você isso está muito ele ela eu tem foi como bem aqui obrigado são",
+	LanguageSpanish:    "que los las del por una con para es no el la lo se su más pero como muy está todo esta bien qué yo eso tiene hay gracias",
+}
+
+// languageProfiles are the trigram profiles of languages written in the latin script
+var languageProfiles = func() (ps map[string]map[string]bool) {
+	ps = make(map[string]map[string]bool)
+	for l, ws := range languageWords {
+		ps[l] = make(map[string]bool)
+		for _, w := range strings.Fields(ws) {
+			for _, t := range languageTrigrams(w) {
+				ps[l][t] = true
+			}
+		}
+	}
+	return
+}()
+
+// languageTrigrams returns the trigrams of a word padded with spaces
+func languageTrigrams(w string) (ts []string) {
+	var rs = []rune(" " + w + " ")
+	for idx := 0; idx+3 <= len(rs); idx++ {
+		ts = append(ts, string(rs[idx:idx+3]))
+	}
+	return
+}
+
+// DetectLanguage detects the language of the items' text, an empty string if it can't be detected. Languages written
+// in a script of their own are detected by their script, Chinese and Japanese being told apart by kana, whereas
+// languages written in the latin script are detected by comparing the text's trigrams to the ones of their most
+// frequent words. Metadata.Language is set with the detected language when empty.
+func (s *Subtitles) DetectLanguage() (language string) {
+	// Loop through words
+	var scripts = make(map[string]int)
+	var han, kana, latin, letters int
+	var trigrams = make(map[string]int)
+	for _, i := range s.Items {
+		for _, w := range strings.FieldsFunc(strings.ToLower(i.String()), func(r rune) bool { return !unicode.IsLetter(r) }) {
+			// Count scripts
+			var isLatin = true
+			for _, r := range w {
+				letters++
+				switch {
+				case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+					kana++
+				case unicode.Is(unicode.Han, r):
+					han++
+				case unicode.Is(unicode.Latin, r):
+					latin++
+					continue
+				default:
+					for _, ls := range languageScripts {
+						if unicode.Is(ls.table, r) {
+							scripts[ls.language]++
+							break
+						}
+					}
+				}
+				isLatin = false
+			}
+
+			// Count trigrams
+			if isLatin {
+				for _, t := range languageTrigrams(w) {
+					trigrams[t]++
+				}
+			}
+		}
+	}
+
+	// Script
+	switch {
+	case letters == 0:
+		return
+	case 2*(han+kana) > letters:
+		language = LanguageChinese
+		if 10*kana > han+kana {
+			language = LanguageJapanese
+		}
+	case 2*latin > letters:
+		language = detectLatinLanguage(trigrams)
+	default:
+		for _, ls := range languageScripts {
+			if 2*scripts[ls.language] > letters {
+				language = ls.language
+			}
+		}
+	}
+
+	// Update metadata
+	if len(language) > 0 {
+		if s.Metadata == nil {
+			s.Metadata = &Metadata{}
+		}
+		if len(s.Metadata.Language) == 0 {
+			s.Metadata.Language = language
+		}
+	}
+	return
+}
+
+// detectLatinLanguage returns the language whose profile matches the most trigrams, an empty string if languages
+// can't be told apart
+func detectLatinLanguage(trigrams map[string]int) (language string) {
+	var best, second int
+	for l, p := range languageProfiles {
+		var score int
+		for t, c := range trigrams {
+			if p[t] {
+				score += c
+			}
+		}
+		if score > best {
+			language, best, second = l, score, best
+		} else if score > second {
+			second = score
+		}
+	}
+	if best == second {
+		language = ""
+	}
+	return
+}
@@ -0,0 +1,71 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_DetectLanguage(t *testing.T) {
+	// Latin script
+	s, err := astisub.OpenFile("./testdata/example-in.srt")
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.LanguageEnglish, s.DetectLanguage())
+	assert.Equal(t, astisub.LanguageEnglish, s.Metadata.Language)
+	var subtitles = func(texts ...string) *astisub.Subtitles {
+		s := &astisub.Subtitles{}
+		for idx, text := range texts {
+			s.Items = append(s.Items, &astisub.Item{EndAt: time.Duration(idx+1) * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}, StartAt: time.Duration(idx) * time.Second})
+		}
+		return s
+	}
+	for language, texts := range map[string][]string{
+		astisub.LanguageDutch:      {"Ik weet niet wat je bedoelt.", "Het is voor hem."},
+		astisub.LanguageFrench:     {"Je ne sais pas ce que vous voulez.", "C'est pour elle et nous."},
+		astisub.LanguageGerman:     {"Ich weiß nicht, was du meinst.", "Das ist auch für sie."},
+		astisub.LanguageItalian:    {"Non so cosa vuoi dire.", "Questo è molto bene per te."},
+		astisub.LanguagePortuguese: {"Eu não sei o que você quer.", "Isso é muito bom, obrigado."},
+		astisub.LanguageSpanish:    {"No sé qué quieres decir.", "Todo está muy bien, gracias."},
+	} {
+		assert.Equal(t, language, subtitles(texts...).DetectLanguage(), language)
+	}
+
+	// Script
+	assert.Equal(t, astisub.LanguageJapanese, subtitles("私は学生です。", "ありがとう").DetectLanguage())
+	assert.Equal(t, astisub.LanguageChinese, subtitles("我是学生。", "谢谢你").DetectLanguage())
+	assert.Equal(t, astisub.LanguageRussian, subtitles("Я не знаю, что ты имеешь в виду.").DetectLanguage())
+	assert.Equal(t, astisub.LanguageKorean, subtitles("감사합니다").DetectLanguage())
+
+	// Metadata language is kept
+	s = subtitles("Je ne sais pas ce que vous voulez.")
+	s.Metadata = &astisub.Metadata{Language: astisub.LanguageEnglish}
+	assert.Equal(t, astisub.LanguageFrench, s.DetectLanguage())
+	assert.Equal(t, astisub.LanguageEnglish, s.Metadata.Language)
+
+	// Undetected
+	s = subtitles("♪ ♪", "123")
+	assert.Equal(t, "", s.DetectLanguage())
+	assert.Nil(t, s.Metadata)
+}
+
+func TestLanguageISO6392(t *testing.T) {
+	for code, language := range map[string]string{
+		"eng": astisub.LanguageEnglish,
+		"fra": astisub.LanguageFrench,
+		"fre": astisub.LanguageFrench,
+		"GER": astisub.LanguageGerman,
+	} {
+		l, ok := astisub.LanguageFromISO6392(code)
+		assert.True(t, ok, code)
+		assert.Equal(t, language, l, code)
+	}
+	_, ok := astisub.LanguageFromISO6392("pol")
+	assert.False(t, ok)
+	c, ok := astisub.LanguageISO6392(astisub.LanguageFrench)
+	assert.True(t, ok)
+	assert.Equal(t, "fra", c)
+	assert.Equal(t, "fr", astisub.LanguageTag(astisub.LanguageFrench))
+	assert.Equal(t, "pl", astisub.LanguageTag("pl"))
+}
@@ -0,0 +1,372 @@
+package astisub
+
+import (
+	"context"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Default live options
+const defaultLiveCorrectionDuration = time.Second
+
+// LiveCue represents a cue emitted by a live source such as a speech-to-text engine
+type LiveCue struct {
+	Confidence *float64 // Between 0 and 1
+	EndAt      time.Duration
+	Final      bool   // A final cue won't be revised anymore
+	ID         string // Cues sharing the same ID are revisions of the same cue
+	Lines      []Line
+	Provenance *Provenance
+	StartAt    time.Duration
+}
+
+// CueSource represents a live source of cues
+// NextCue must return io.EOF once there are no more cues
+type CueSource interface {
+	NextCue() (LiveCue, error)
+}
+
+// CueSink represents a live destination of cues
+type CueSink interface {
+	PushCue(c LiveCue) error
+}
+
+// CueSourceFunc allows using a function as a CueSource
+type CueSourceFunc func() (LiveCue, error)
+
+// NextCue implements the CueSource interface
+func (f CueSourceFunc) NextCue() (LiveCue, error) {
+	return f()
+}
+
+// CueSinkFunc allows using a function as a CueSink
+type CueSinkFunc func(c LiveCue) error
+
+// PushCue implements the CueSink interface
+func (f CueSinkFunc) PushCue(c LiveCue) error {
+	return f(c)
+}
+
+// ChanCueSource allows using a channel as a CueSource. Closing the channel ends the source.
+type ChanCueSource <-chan LiveCue
+
+// NextCue implements the CueSource interface
+func (s ChanCueSource) NextCue() (LiveCue, error) {
+	c, ok := <-s
+	if !ok {
+		return LiveCue{}, io.EOF
+	}
+	return c, nil
+}
+
+// PipeCues pushes cues from a source to a sink until the source is done or the context is cancelled
+func PipeCues(ctx context.Context, src CueSource, dst CueSink) (err error) {
+	for {
+		// Check context
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
+		// Next cue
+		var c LiveCue
+		if c, err = src.NextCue(); err != nil {
+			if err == io.EOF {
+				err = nil
+				return
+			}
+			err = errors.Wrap(err, "astisub: fetching next cue failed")
+			return
+		}
+
+		// Push cue
+		if err = dst.PushCue(c); err != nil {
+			err = errors.Wrap(err, "astisub: pushing cue failed")
+			return
+		}
+	}
+}
+
+// StreamWriter represents a writer writing items one at a time
+type StreamWriter interface {
+	WriteItem(i *Item) error
+}
+
+// WebVTTStreamWriter writes items as WebVTT cues as soon as they're received
+type WebVTTStreamWriter struct {
+	count int
+	w     io.Writer
+}
+
+// NewWebVTTStreamWriter creates a new WebVTT stream writer
+func NewWebVTTStreamWriter(w io.Writer) *WebVTTStreamWriter {
+	return &WebVTTStreamWriter{w: w}
+}
+
+// WriteItem implements the StreamWriter interface
+func (w *WebVTTStreamWriter) WriteItem(i *Item) (err error) {
+	// Add header
+	var c []byte
+	if w.count == 0 {
+		c = append(c, []byte("WEBVTT\n\n")...)
+	}
+
+	// Add item
+	c = append(c, webVTTItemBytes(strconv.Itoa(w.count+1), i, nil, WebVTTOptions{})...)
+
+	// Write
+	if _, err = w.w.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	w.count++
+	return
+}
+
+// SCCStreamWriter writes items as CEA-608 pop-on captions, in the SCC format, as soon as they're received.
+// An item is erased when it ends, unless the next item starts before, in which case it's replaced. Close must be
+// called so that the last item is erased. Only texts are written, on the bottom rows, and items exceeding the limits
+// of TargetCEA608 are wrapped and split since decoders truncate them.
+type SCCStreamWriter struct {
+	count     int
+	displayed bool
+	endAt     time.Duration
+	w         io.Writer
+}
+
+// NewSCCStreamWriter creates a new SCC stream writer
+func NewSCCStreamWriter(w io.Writer) *SCCStreamWriter {
+	return &SCCStreamWriter{w: w}
+}
+
+// WriteItem implements the StreamWriter interface
+func (w *SCCStreamWriter) WriteItem(i *Item) (err error) {
+	for _, li := range limitItems([]*Item{i}, TargetFromFormat(FormatSCC).Limits()) {
+		if err = w.writeItem(li); err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (w *SCCStreamWriter) writeItem(i *Item) (err error) {
+	// Add header
+	var c string
+	if w.count == 0 {
+		c += sccHeader + "\n\n"
+	}
+
+	// Erase displayed item
+	if w.displayed && w.endAt < i.StartAt {
+		c += sccLine(w.endAt, []uint16{sccWord(0x14, 0x2c), sccWord(0x14, 0x2c)})
+	}
+
+	// Add item
+	c += sccLine(i.StartAt, sccPopOnWords(i))
+
+	// Write
+	if _, err = io.WriteString(w.w, c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	w.count++
+	w.displayed = true
+	w.endAt = i.EndAt
+	return
+}
+
+// Close erases the displayed item
+func (w *SCCStreamWriter) Close() (err error) {
+	// Nothing is displayed
+	if !w.displayed {
+		return
+	}
+
+	// Write
+	if _, err = io.WriteString(w.w, sccLine(w.endAt, []uint16{sccWord(0x14, 0x2c), sccWord(0x14, 0x2c)})); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	w.displayed = false
+	return
+}
+
+// LiveOptions represents live options
+type LiveOptions struct {
+	// Minimum duration of cues correcting cues that have already been written. Defaults to 1s.
+	CorrectionDuration time.Duration
+	// Maximum duration a partial cue can be held before being written. If 0, partial cues are held until they're final.
+	MaxLatency time.Duration
+}
+
+// LiveWriter is a CueSink writing cues to a StreamWriter.
+// Partial cues are held until they're final or until they've been held for longer than the max latency, in which
+// case they're written as is. Cues are written in chronological order: a final cue is only written once pending cues
+// starting before it have been written. If a revision of a cue that has already been written is received later on and its
+// text has changed, it's written as a new correction cue starting when the written cue ends.
+// Flush should be called periodically so that partial cues don't exceed the max latency when no cues are received.
+type LiveWriter struct {
+	lastStartAt time.Duration
+	o           LiveOptions
+	pending     map[string]*livePendingCue
+	w           StreamWriter
+	written     map[string]*Item
+}
+
+type livePendingCue struct {
+	c     LiveCue
+	since time.Time
+}
+
+// NewLiveWriter creates a new live writer
+func NewLiveWriter(w StreamWriter, o LiveOptions) *LiveWriter {
+	if o.CorrectionDuration <= 0 {
+		o.CorrectionDuration = defaultLiveCorrectionDuration
+	}
+	return &LiveWriter{
+		o:       o,
+		pending: make(map[string]*livePendingCue),
+		w:       w,
+		written: make(map[string]*Item),
+	}
+}
+
+// PushCue implements the CueSink interface
+func (lw *LiveWriter) PushCue(c LiveCue) (err error) {
+	// Update pending cue
+	if p, ok := lw.pending[c.ID]; ok {
+		p.c = c
+	} else {
+		lw.pending[c.ID] = &livePendingCue{c: c, since: Now()}
+	}
+
+	// Final cues are written right away, after pending cues starting before them
+	if c.Final {
+		if err = lw.commitPending(func(p *livePendingCue) bool { return p.c.ID == c.ID || p.c.StartAt < c.StartAt }); err != nil {
+			return
+		}
+	}
+
+	// Flush
+	if err = lw.Flush(); err != nil {
+		err = errors.Wrap(err, "astisub: flushing failed")
+		return
+	}
+	return
+}
+
+// Flush writes partial cues that have been held for longer than the max latency
+func (lw *LiveWriter) Flush() error {
+	if lw.o.MaxLatency <= 0 {
+		return nil
+	}
+	return lw.commitPending(func(p *livePendingCue) bool { return Now().Sub(p.since) >= lw.o.MaxLatency })
+}
+
+// Close writes all pending cues and closes the stream writer if it implements io.Closer
+func (lw *LiveWriter) Close() (err error) {
+	// Commit pending cues
+	if err = lw.commitPending(func(p *livePendingCue) bool { return true }); err != nil {
+		return
+	}
+	lw.written = make(map[string]*Item)
+
+	// Close stream writer
+	if c, ok := lw.w.(io.Closer); ok {
+		if err = c.Close(); err != nil {
+			err = errors.Wrap(err, "astisub: closing stream writer failed")
+			return
+		}
+	}
+	return
+}
+
+// commitPending commits pending cues matching the filter in chronological order
+func (lw *LiveWriter) commitPending(fn func(p *livePendingCue) bool) (err error) {
+	// Get ids
+	var ids []string
+	for id, p := range lw.pending {
+		if fn(p) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if lw.pending[ids[i]].c.StartAt == lw.pending[ids[j]].c.StartAt {
+			return ids[i] < ids[j]
+		}
+		return lw.pending[ids[i]].c.StartAt < lw.pending[ids[j]].c.StartAt
+	})
+
+	// Commit
+	for _, id := range ids {
+		if err = lw.commit(id); err != nil {
+			err = errors.Wrapf(err, "astisub: committing cue %s failed", id)
+			return
+		}
+	}
+	return
+}
+
+// commit writes a pending cue
+func (lw *LiveWriter) commit(id string) (err error) {
+	// Get pending cue
+	p, ok := lw.pending[id]
+	if !ok {
+		return
+	}
+	delete(lw.pending, id)
+
+	// Create item
+	var i = &Item{
+		Confidence: p.c.Confidence,
+		EndAt:      p.c.EndAt,
+		Lines:      p.c.Lines,
+		Provenance: p.c.Provenance,
+		StartAt:    p.c.StartAt,
+	}
+
+	// Cue has already been written
+	if w, ok := lw.written[id]; ok {
+		// Text has not changed
+		if w.String() == i.String() {
+			if p.c.Final {
+				delete(lw.written, id)
+			}
+			return
+		}
+
+		// Correction starts when the written cue ends
+		if i.StartAt < w.EndAt {
+			i.StartAt = w.EndAt
+		}
+		if i.EndAt < i.StartAt+lw.o.CorrectionDuration {
+			i.EndAt = i.StartAt + lw.o.CorrectionDuration
+		}
+	}
+
+	// Items can't start before items that have already been written
+	if i.StartAt < lw.lastStartAt {
+		i.StartAt = lw.lastStartAt
+		if i.EndAt < i.StartAt+lw.o.CorrectionDuration {
+			i.EndAt = i.StartAt + lw.o.CorrectionDuration
+		}
+	}
+
+	// Write
+	if err = lw.w.WriteItem(i); err != nil {
+		err = errors.Wrap(err, "astisub: writing item failed")
+		return
+	}
+
+	// Keep track of partial cues that have been written
+	lw.lastStartAt = i.StartAt
+	if p.c.Final {
+		delete(lw.written, id)
+	} else {
+		lw.written[id] = i
+	}
+	return
+}
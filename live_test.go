@@ -0,0 +1,100 @@
+package astisub_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func liveCue(id string, start, end time.Duration, text string, final bool) astisub.LiveCue {
+	return astisub.LiveCue{
+		EndAt:   end,
+		Final:   final,
+		ID:      id,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}},
+		StartAt: start,
+	}
+}
+
+func TestLiveWriter(t *testing.T) {
+	// Mock now
+	var now = time.Unix(0, 0)
+	var previousNow = astisub.Now
+	defer func() { astisub.Now = previousNow }()
+	astisub.Now = func() time.Time { return now }
+
+	// Init
+	var w = &bytes.Buffer{}
+	var lw = astisub.NewLiveWriter(astisub.NewWebVTTStreamWriter(w), astisub.LiveOptions{MaxLatency: 2 * time.Second})
+
+	// Partial cues are held until max latency
+	assert.NoError(t, lw.PushCue(liveCue("1", time.Second, 2*time.Second, "hel", false)))
+	now = now.Add(time.Second)
+	assert.NoError(t, lw.PushCue(liveCue("1", time.Second, 3*time.Second, "hello", false)))
+	assert.NoError(t, lw.PushCue(liveCue("2", 5*time.Second, 6*time.Second, "final", true)))
+	now = now.Add(time.Second)
+	assert.NoError(t, lw.PushCue(liveCue("3", 6*time.Second, 7*time.Second, "partial", false)))
+
+	// Corrections are written once final
+	assert.NoError(t, lw.PushCue(liveCue("1", time.Second, 3*time.Second, "hello world", true)))
+
+	// Pending cues are written when closing, and cues are written in chronological order
+	assert.NoError(t, lw.Close())
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\nhello\n\n2\n00:00:05.000 --> 00:00:06.000\nfinal\n\n3\n00:00:05.000 --> 00:00:06.000\nhello world\n\n4\n00:00:06.000 --> 00:00:07.000\npartial\n\n", w.String())
+}
+
+func TestSCCStreamWriter(t *testing.T) {
+	// Write
+	var w = &bytes.Buffer{}
+	var lw = astisub.NewLiveWriter(astisub.NewSCCStreamWriter(w), astisub.LiveOptions{})
+	assert.NoError(t, lw.PushCue(liveCue("1", time.Second, 2*time.Second, "Hi*", true)))
+	assert.NoError(t, lw.PushCue(liveCue("2", 3*time.Second, 5*time.Second, "Ça va — ♪", true)))
+	assert.NoError(t, lw.PushCue(liveCue("3", 4*time.Second, 6*time.Second, "Yes", true)))
+	assert.NoError(t, lw.Close())
+	assert.Equal(t, "Scenarist_SCC V1.0\n\n00:00:00;29\t9420 9420 94ae 94ae 94e0 94e0 c8e9 2080 92a8 92a8 942f 942f\n\n00:00:01;29\t942c 942c\n\n00:00:02;29\t9420 9420 94ae 94ae 94e0 94e0 4380 9232 9232 6120 7661 20ad 922a 922a 2080 9137 9137 942f 942f\n\n00:00:03;29\t9420 9420 94ae 94ae 94e0 94e0 d9e5 7380 942f 942f\n\n00:00:05;29\t942c 942c\n\n", w.String())
+
+	// Read
+	s, err := astisub.ReadFromSCC(w)
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, "Hi*", s.Items[0].String())
+	assert.Equal(t, "Ça va — ♪", s.Items[1].String())
+	assert.Equal(t, "Yes", s.Items[2].String())
+}
+
+func TestPipeCues(t *testing.T) {
+	var ch = make(chan astisub.LiveCue, 2)
+	ch <- liveCue("1", 0, time.Second, "1", true)
+	ch <- liveCue("2", time.Second, 2*time.Second, "2", true)
+	close(ch)
+	var ids []string
+	assert.NoError(t, astisub.PipeCues(context.Background(), astisub.ChanCueSource(ch), astisub.CueSinkFunc(func(c astisub.LiveCue) error {
+		ids = append(ids, c.ID)
+		return nil
+	})))
+	assert.Equal(t, []string{"1", "2"}, ids)
+}
+
+type itemsStreamWriter []*astisub.Item
+
+func (w *itemsStreamWriter) WriteItem(i *astisub.Item) error {
+	*w = append(*w, i)
+	return nil
+}
+
+func TestLiveWriterConfidence(t *testing.T) {
+	var w = &itemsStreamWriter{}
+	var lw = astisub.NewLiveWriter(w, astisub.LiveOptions{})
+	var c = liveCue("1", time.Second, 2*time.Second, "Hello", true)
+	c.Confidence = astiptr.Float(0.5)
+	c.Provenance = &astisub.Provenance{Engine: "engine"}
+	assert.NoError(t, lw.PushCue(c))
+	assert.Len(t, *w, 1)
+	assert.Equal(t, c.Confidence, (*w)[0].Confidence)
+	assert.Equal(t, c.Provenance, (*w)[0].Provenance)
+}
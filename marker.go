@@ -0,0 +1,90 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Marker represents a named point of the content's timeline, such as the end of a recap or the start of the credits.
+// Markers are retimed along with items.
+type Marker struct {
+	At   time.Duration
+	Name string
+}
+
+// SetMarker sets the time of a marker, adding it if needed. Markers are kept ordered by time.
+func (s *Subtitles) SetMarker(name string, at time.Duration) {
+	s.RemoveMarker(name)
+	s.Markers = append(s.Markers, Marker{At: at, Name: name})
+	sort.SliceStable(s.Markers, func(a, b int) bool { return s.Markers[a].At < s.Markers[b].At })
+}
+
+// Marker returns a marker by name
+func (s Subtitles) Marker(name string) (m Marker, ok bool) {
+	for _, v := range s.Markers {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return
+}
+
+// RemoveMarker removes a marker by name
+func (s *Subtitles) RemoveMarker(name string) {
+	for idx := 0; idx < len(s.Markers); idx++ {
+		if s.Markers[idx].Name == name {
+			s.Markers = append(s.Markers[:idx], s.Markers[idx+1:]...)
+			idx--
+		}
+	}
+}
+
+// ChaptersFromMarkers returns a chapters track where each marker starts a chapter, named after it, that lasts until
+// the next marker or, for the last one, until the end of the subtitles
+func (s Subtitles) ChaptersFromMarkers() (o *Subtitles) {
+	o = NewSubtitles()
+	o.Metadata = &Metadata{Kind: TrackKindChapters}
+	for idx, m := range s.Markers {
+		var end = s.Duration()
+		if idx+1 < len(s.Markers) {
+			end = s.Markers[idx+1].At
+		}
+		if end <= m.At {
+			continue
+		}
+		o.Items = append(o.Items, newPlaceholderItem(m.At, end, m.Name))
+	}
+	return
+}
+
+// MetadataFromMarkers returns a metadata track holding a cue per marker, whose payload is the marker's name and which
+// lasts one frame
+func (s Subtitles) MetadataFromMarkers() (o *Subtitles) {
+	o = NewSubtitles()
+	o.Metadata = &Metadata{Kind: TrackKindMetadata}
+	for _, m := range s.Markers {
+		o.Items = append(o.Items, newPlaceholderItem(m.At, m.At+time.Second/defaultFramerate, m.Name))
+	}
+	return
+}
+
+// WriteChapters writes markers as an OGM chapter file, which most muxers import
+func (s Subtitles) WriteChapters(o io.Writer) (err error) {
+	// Loop through markers
+	var c []byte
+	for idx, m := range s.Markers {
+		c = append(c, []byte(fmt.Sprintf("CHAPTER%.2d=%s\n", idx+1, formatDurationWebVTT(m.At)))...)
+		c = append(c, []byte(fmt.Sprintf("CHAPTER%.2dNAME=%s\n", idx+1, m.Name))...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
@@ -0,0 +1,49 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Markers(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 10 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}, StartAt: time.Second}}}
+	s.SetMarker("credits_start", 8*time.Second)
+	s.SetMarker("recap_end", 2*time.Second)
+	assert.Equal(t, []astisub.Marker{{At: 2 * time.Second, Name: "recap_end"}, {At: 8 * time.Second, Name: "credits_start"}}, s.Markers)
+	s.SetMarker("recap_end", 3*time.Second)
+	m, ok := s.Marker("recap_end")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, m.At)
+	assert.Len(t, s.Markers, 2)
+
+	// Retiming
+	s.Add(time.Second)
+	s.Scale(2)
+	assert.Equal(t, []astisub.Marker{{At: 8 * time.Second, Name: "recap_end"}, {At: 18 * time.Second, Name: "credits_start"}}, s.Markers)
+	s.ApplyCutList([]astisub.Cut{{From: 0, To: 4 * time.Second}})
+	assert.Equal(t, []astisub.Marker{{At: 4 * time.Second, Name: "recap_end"}, {At: 14 * time.Second, Name: "credits_start"}}, s.Markers)
+
+	// Chapters
+	w := &bytes.Buffer{}
+	err := s.ChaptersFromMarkers().WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\nKind: chapters\n\n1\n00:00:04.000 --> 00:00:14.000\nrecap_end\n\n2\n00:00:14.000 --> 00:00:18.000\ncredits_start\n", w.String())
+	w.Reset()
+	err = s.WriteChapters(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "CHAPTER01=00:00:04.000\nCHAPTER01NAME=recap_end\nCHAPTER02=00:00:14.000\nCHAPTER02NAME=credits_start\n", w.String())
+
+	// Metadata
+	md := s.MetadataFromMarkers()
+	assert.Len(t, md.Items, 2)
+	assert.Equal(t, 14*time.Second+40*time.Millisecond, md.Items[1].EndAt)
+
+	// Remove
+	s.RemoveMarker("recap_end")
+	_, ok = s.Marker("recap_end")
+	assert.False(t, ok)
+}
@@ -0,0 +1,293 @@
+package astisub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/pkg/errors"
+)
+
+// MicroDVD
+// Each line holds the start and end frames of an item followed by its text, whose lines are separated by "|":
+// {123}{456}First line|Second line
+// Control codes such as {y:i} apply to a line, or to all lines when uppercase such as {Y:i}, and a line starting with
+// "/" is italic. The framerate can be provided by a first item such as {1}{1}23.976.
+
+// Vars
+var (
+	microDVDRegexpCode = regexp.MustCompile(`^\{([A-Za-z]):([^}]*)\}`)
+	microDVDRegexpLine = regexp.MustCompile(`^\{(\d+)\}\{(\d+)\}(.*)$`)
+)
+
+// MicroDVDOptions represents MicroDVD options
+type MicroDVDOptions struct {
+	// Framerate frames are converted with. When reading, it defaults to the framerate of the {1}{1} header, then to
+	// 25. When writing, it defaults to the metadata framerate, then to 25.
+	Framerate float64
+	// If true, the framerate is written in a {1}{1} header, which most players read
+	Header bool
+}
+
+// ReadFromMicroDVD parses a MicroDVD content
+func ReadFromMicroDVD(i io.Reader) (*Subtitles, error) {
+	return ReadFromMicroDVDWithOptions(i, MicroDVDOptions{})
+}
+
+// ReadFromMicroDVDWithOptions parses a MicroDVD content with options
+func ReadFromMicroDVDWithOptions(i io.Reader, opts MicroDVDOptions) (o *Subtitles, err error) {
+	return readFromMicroDVD(i, opts, nil)
+}
+
+// readFromMicroDVD parses a MicroDVD content with options, calling a hook for each item once it's complete
+func readFromMicroDVD(i io.Reader, opts MicroDVDOptions, h ItemHook) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var scanner = bufio.NewScanner(i)
+	var framerate = opts.Framerate
+
+	// Loop through lines
+	var frames [][2]int
+	var line int
+	for scanner.Scan() {
+		// Fetch line
+		line++
+		var t = strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			t = strings.TrimPrefix(t, string(BytesBOM))
+		}
+		if len(t) == 0 {
+			continue
+		}
+
+		// Parse frames
+		var m = microDVDRegexpLine.FindStringSubmatch(t)
+		if m == nil {
+			o.Warnings = append(o.Warnings, Warning{Line: line, Message: fmt.Sprintf("invalid line %s skipped", t)})
+			continue
+		}
+		var start, end int
+		if start, err = strconv.Atoi(m[1]); err != nil {
+			err = errors.Wrapf(err, "astisub: atoi of %s failed", m[1])
+			return
+		}
+		if end, err = strconv.Atoi(m[2]); err != nil {
+			err = errors.Wrapf(err, "astisub: atoi of %s failed", m[2])
+			return
+		}
+
+		// Framerate header
+		if len(o.Items) == 0 && start <= 1 && end <= 1 {
+			if f, errParse := strconv.ParseFloat(strings.TrimSpace(m[3]), 64); errParse == nil && f > 0 {
+				if o.Metadata == nil {
+					o.Metadata = &Metadata{}
+				}
+				o.Metadata.Framerate = int(math.Round(f))
+				o.Metadata.MicroDVDFramerate = f
+				if framerate <= 0 {
+					framerate = f
+				}
+				continue
+			}
+		}
+
+		// Add item
+		var i = &Item{}
+		i.InlineStyle, i.Lines = parseMicroDVDText(m[3])
+		o.Items = append(o.Items, i)
+		frames = append(frames, [2]int{start, end})
+	}
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "astisub: scanning failed")
+		return
+	}
+
+	// Convert frames now that the framerate is known
+	if framerate <= 0 {
+		framerate = defaultFramerate
+	}
+	var hk = &itemHooker{h: h}
+	for idx, i := range o.Items {
+		i.StartAt = microDVDFramesToDuration(frames[idx][0], framerate)
+		i.EndAt = microDVDFramesToDuration(frames[idx][1], framerate)
+		if err = hk.hook(o.Items[:idx+1]); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// microDVDFramesToDuration converts a number of frames to a duration
+func microDVDFramesToDuration(frames int, framerate float64) time.Duration {
+	return time.Duration(math.Round(float64(frames) * float64(time.Second) / framerate))
+}
+
+// parseMicroDVDText parses the text of an item into lines. Attributes of uppercase control codes starting the text
+// apply to the whole item.
+func parseMicroDVDText(t string) (sa *StyleAttributes, ls []Line) {
+	// Loop through lines
+	for idx, s := range strings.Split(t, "|") {
+		// Parse codes
+		var lsa *StyleAttributes
+		for m := microDVDRegexpCode.FindStringSubmatch(s); m != nil; m = microDVDRegexpCode.FindStringSubmatch(s) {
+			s = s[len(m[0]):]
+			if strings.ToUpper(m[1]) == m[1] && idx == 0 {
+				sa = microDVDCodeAttributes(sa, m[1], m[2])
+			} else {
+				lsa = microDVDCodeAttributes(lsa, m[1], m[2])
+			}
+		}
+
+		// Line starting with "/" is italic
+		if strings.HasPrefix(s, "/") {
+			s = s[1:]
+			lsa = microDVDCodeAttributes(lsa, "y", "i")
+		}
+
+		// Add line
+		ls = append(ls, Line{Items: []LineItem{{InlineStyle: lsa, Text: strings.TrimSpace(s)}}})
+	}
+	return
+}
+
+// microDVDCodeAttributes updates attributes with a control code. Unsupported control codes are ignored.
+func microDVDCodeAttributes(sa *StyleAttributes, code, value string) *StyleAttributes {
+	if sa == nil {
+		sa = &StyleAttributes{}
+	}
+	switch strings.ToLower(code) {
+	case "c":
+		if c, err := newColorFromString(strings.TrimPrefix(strings.TrimSpace(value), "$"), 16); err == nil {
+			sa.MicroDVDColor = c
+		}
+	case "y":
+		for _, v := range strings.Split(strings.ToLower(value), ",") {
+			switch strings.TrimSpace(v) {
+			case "b":
+				sa.MicroDVDBold = astiptr.Bool(true)
+			case "i":
+				sa.MicroDVDItalics = astiptr.Bool(true)
+			case "s":
+				sa.MicroDVDStrikeout = astiptr.Bool(true)
+			case "u":
+				sa.MicroDVDUnderline = astiptr.Bool(true)
+			}
+		}
+	}
+	return sa
+}
+
+// WriteToMicroDVD writes subtitles in MicroDVD format
+func (s Subtitles) WriteToMicroDVD(o io.Writer) error {
+	return s.WriteToMicroDVDWithOptions(o, MicroDVDOptions{})
+}
+
+// WriteToMicroDVDWithOptions writes subtitles in MicroDVD format with options
+func (s Subtitles) WriteToMicroDVDWithOptions(o io.Writer, opts MicroDVDOptions) error {
+	return s.writeToMicroDVD(o, opts, nil)
+}
+
+// writeToMicroDVD writes subtitles in MicroDVD format with options, calling a hook for each item right before it's
+// written
+func (s Subtitles) writeToMicroDVD(o io.Writer, opts MicroDVDOptions, h ItemHook) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Get framerate
+	var framerate = opts.Framerate
+	if framerate <= 0 && s.Metadata != nil {
+		if framerate = s.Metadata.MicroDVDFramerate; framerate <= 0 {
+			framerate = float64(s.Metadata.Framerate)
+		}
+	}
+	if framerate <= 0 {
+		framerate = defaultFramerate
+	}
+
+	// Add header
+	var c []byte
+	if opts.Header {
+		c = append(c, []byte("{1}{1}"+strconv.FormatFloat(framerate, 'f', -1, 64))...)
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		// Hook
+		if i, err = hookedItem(i, idx, h); err != nil {
+			return
+		}
+
+		// Get lines
+		var ls []string
+		for _, l := range i.Lines {
+			ls = append(ls, microDVDLine(l, i))
+		}
+
+		// Add item
+		c = append(c, []byte(fmt.Sprintf("{%d}{%d}%s", microDVDDurationToFrames(i.StartAt, framerate), microDVDDurationToFrames(i.EndAt, framerate), strings.Join(ls, "|")))...)
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
+
+// microDVDDurationToFrames converts a duration to a number of frames
+func microDVDDurationToFrames(d time.Duration, framerate float64) int {
+	return int(math.Round(d.Seconds() * framerate))
+}
+
+// microDVDLine returns the text of a line preceded by the control codes of its first line item's attributes, line
+// item attributes taking precedence over item attributes, since control codes apply to whole lines
+func microDVDLine(l Line, i *Item) (o string) {
+	// Merge attributes
+	var sa = mergeStyleAttributes(i.InlineStyle, ssaItemStyleAttributes(i))
+	if len(l.Items) > 0 {
+		var lsa = l.Items[0].InlineStyle
+		if l.Items[0].Style != nil {
+			lsa = mergeStyleAttributes(lsa, l.Items[0].Style.InlineStyle)
+		}
+		sa = mergeStyleAttributes(lsa, sa)
+	}
+
+	// Add codes
+	if sa != nil {
+		var g = sa.GenericAttributes(FormatMicroDVD)
+		var ys []string
+		if g.Bold != nil && *g.Bold {
+			ys = append(ys, "b")
+		}
+		if g.Italics != nil && *g.Italics {
+			ys = append(ys, "i")
+		}
+		if g.Strikeout != nil && *g.Strikeout {
+			ys = append(ys, "s")
+		}
+		if g.Underline != nil && *g.Underline {
+			ys = append(ys, "u")
+		}
+		if len(ys) > 0 {
+			o += "{y:" + strings.Join(ys, ",") + "}"
+		}
+		if g.Color != nil {
+			o += "{c:$" + g.Color.String(16, false) + "}"
+		}
+	}
+
+	// Add text, "|" being reserved to line breaks
+	return o + strings.Replace(l.String(), "|", "/", -1)
+}
@@ -0,0 +1,61 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMicroDVD(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.sub")
+	assert.NoError(t, err)
+	assertSubtitleItems(t, s)
+	assert.Equal(t, 25, s.Metadata.Framerate)
+	assert.True(t, *s.Items[1].InlineStyle.MicroDVDBold)
+	assert.True(t, *s.Items[1].InlineStyle.GenericAttributes(astisub.FormatSRT).Bold)
+	assert.True(t, *s.Items[1].Lines[1].Items[0].InlineStyle.MicroDVDItalics)
+	assert.Equal(t, &astisub.Color{Red: 0xff}, s.Items[1].Lines[1].Items[0].InlineStyle.MicroDVDColor)
+	assert.True(t, *s.Items[3].Lines[0].Items[0].InlineStyle.MicroDVDItalics)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToMicroDVD(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.sub")
+	assert.NoError(t, err)
+	err = s.WriteToMicroDVD(w)
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+}
+
+func TestMicroDVDFramerate(t *testing.T) {
+	// Header
+	s, err := astisub.ReadFromMicroDVD(strings.NewReader("{1}{1}23.976\n{24}{48}Hello\ninvalid\n"))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, []astisub.Warning{{Line: 3, Message: "invalid line invalid skipped"}}, s.Warnings)
+	assert.Equal(t, 24, s.Metadata.Framerate)
+	assert.Equal(t, 23.976, s.Metadata.MicroDVDFramerate)
+	assert.Equal(t, 1001001001*time.Nanosecond, s.Items[0].StartAt)
+	w := &bytes.Buffer{}
+	err = s.WriteToMicroDVDWithOptions(w, astisub.MicroDVDOptions{Header: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "{1}{1}23.976\n{24}{48}Hello\n", w.String())
+
+	// Option
+	s, err = astisub.ReadFromMicroDVDWithOptions(strings.NewReader("{1}{1}23.976\n{24}{48}Hello\n"), astisub.MicroDVDOptions{Framerate: 24})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	w.Reset()
+	err = s.WriteToMicroDVDWithOptions(w, astisub.MicroDVDOptions{Framerate: 50})
+	assert.NoError(t, err)
+	assert.Equal(t, "{50}{100}Hello\n", w.String())
+}
@@ -0,0 +1,101 @@
+package astisub
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Errors
+var (
+	ErrOCRConfidenceTooLow = errors.New("astisub: ocr confidence too low")
+)
+
+// OCRResult represents the result of an OCR
+type OCRResult struct {
+	Confidence float64 // Between 0 and 1
+	Text       string  // Lines are separated by "\n"
+}
+
+// OCR represents an engine capable of recognizing text in images. Bitmap subtitle readers rely on it to convert
+// bitmaps to text so that the engine is pluggable.
+type OCR interface {
+	Recognize(ctx context.Context, img image.Image, language string) (OCRResult, error)
+}
+
+// OCRFunc allows using a function as an OCR
+type OCRFunc func(ctx context.Context, img image.Image, language string) (OCRResult, error)
+
+// Recognize implements the OCR interface
+func (f OCRFunc) Recognize(ctx context.Context, img image.Image, language string) (OCRResult, error) {
+	return f(ctx, img, language)
+}
+
+// OCROptions represents OCR options
+type OCROptions struct {
+	Engine        OCR
+	Language      string
+	MinConfidence float64 // Results whose confidence is below this value are rejected
+}
+
+// NewItemFromImage recognizes the text of a bitmap shown between 2 time boundaries and converts it to an item
+func NewItemFromImage(ctx context.Context, img image.Image, startAt, endAt time.Duration, o OCROptions) (i *Item, err error) {
+	// Recognize
+	var r OCRResult
+	if r, err = o.Engine.Recognize(ctx, img, o.Language); err != nil {
+		err = errors.Wrap(err, "astisub: recognizing failed")
+		return
+	}
+
+	// Check confidence
+	if r.Confidence < o.MinConfidence {
+		err = ErrOCRConfidenceTooLow
+		return
+	}
+
+	// Create item
+	i = &Item{
+		EndAt:   endAt,
+		StartAt: startAt,
+	}
+
+	// Loop through lines
+	for _, l := range strings.Split(r.Text, "\n") {
+		if l = strings.TrimSpace(l); len(l) > 0 {
+			i.Lines = append(i.Lines, Line{Items: []LineItem{{Text: l}}})
+		}
+	}
+	return
+}
+
+// RecognizeBitmaps converts items holding a bitmap to text items. Items whose text is recognized with a too low
+// confidence keep their bitmap and a warning is added.
+func (s *Subtitles) RecognizeBitmaps(ctx context.Context, o OCROptions) (err error) {
+	for idx, i := range s.Items {
+		// No bitmap
+		if i.Bitmap == nil {
+			continue
+		}
+
+		// Recognize
+		var r *Item
+		if r, err = NewItemFromImage(ctx, i.Bitmap, i.StartAt, i.EndAt, o); err != nil {
+			if err == ErrOCRConfidenceTooLow {
+				s.Warnings = append(s.Warnings, Warning{Message: fmt.Sprintf("text of item #%d has been recognized with a too low confidence, its bitmap has been kept", idx+1)})
+				err = nil
+				continue
+			}
+			err = errors.Wrapf(err, "astisub: recognizing bitmap of item #%d failed", idx+1)
+			return
+		}
+
+		// Update item
+		i.Bitmap = nil
+		i.Lines = r.Lines
+	}
+	return
+}
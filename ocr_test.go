@@ -0,0 +1,58 @@
+package astisub_test
+
+import (
+	"context"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewItemFromImage(t *testing.T) {
+	var img = image.NewGray(image.Rect(0, 0, 1, 1))
+	var o = astisub.OCROptions{
+		Engine: astisub.OCRFunc(func(ctx context.Context, i image.Image, language string) (astisub.OCRResult, error) {
+			assert.Equal(t, img, i)
+			assert.Equal(t, astisub.LanguageFrench, language)
+			return astisub.OCRResult{Confidence: 0.8, Text: " line 1\n\nline 2 \n"}, nil
+		}),
+		Language:      astisub.LanguageFrench,
+		MinConfidence: 0.5,
+	}
+	i, err := astisub.NewItemFromImage(context.Background(), img, time.Second, 2*time.Second, o)
+	assert.NoError(t, err)
+	assert.Equal(t, &astisub.Item{
+		EndAt:   2 * time.Second,
+		Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "line 1"}}}, {Items: []astisub.LineItem{{Text: "line 2"}}}},
+		StartAt: time.Second,
+	}, i)
+	o.MinConfidence = 0.9
+	_, err = astisub.NewItemFromImage(context.Background(), img, time.Second, 2*time.Second, o)
+	assert.Equal(t, astisub.ErrOCRConfidenceTooLow, err)
+}
+
+func TestSubtitles_RecognizeBitmaps(t *testing.T) {
+	var img1, img2 = image.NewGray(image.Rect(0, 0, 1, 1)), image.NewGray(image.Rect(0, 0, 2, 2))
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{Bitmap: img1, EndAt: time.Second},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "text"}}}}},
+		{Bitmap: img2, EndAt: 2 * time.Second},
+	}}
+	err := s.RecognizeBitmaps(context.Background(), astisub.OCROptions{
+		Engine: astisub.OCRFunc(func(ctx context.Context, i image.Image, language string) (astisub.OCRResult, error) {
+			if i == img2 {
+				return astisub.OCRResult{Confidence: 0.1}, nil
+			}
+			return astisub.OCRResult{Confidence: 0.9, Text: "bitmap"}, nil
+		}),
+		MinConfidence: 0.5,
+	})
+	assert.NoError(t, err)
+	assert.Nil(t, s.Items[0].Bitmap)
+	assert.Equal(t, "bitmap", s.Items[0].String())
+	assert.Equal(t, "text", s.Items[1].String())
+	assert.Equal(t, img2, s.Items[2].Bitmap)
+	assert.Equal(t, []astisub.Warning{{Message: "text of item #3 has been recognized with a too low confidence, its bitmap has been kept"}}, s.Warnings)
+}
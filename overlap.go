@@ -0,0 +1,100 @@
+package astisub
+
+import (
+	"sort"
+	"time"
+)
+
+// Overlap represents 2 items whose time ranges intersect
+type Overlap struct {
+	Duration time.Duration // Duration both items are displayed at the same time
+	Indexes  [2]int        // Indexes of the items in the subtitles
+	Items    [2]*Item      // Items ordered by start time
+}
+
+// OverlapStrategy represents the way overlaps are resolved
+type OverlapStrategy int
+
+// Overlap strategies
+const (
+	// Overlapping items are merged into one item displayed from the first start to the last end, lines being ordered
+	// by start time
+	OverlapStrategyMerge OverlapStrategy = iota
+	// Later items are shifted so that they start when earlier items end, their duration being kept
+	OverlapStrategyShiftLater
+	// Earlier items end when later items start. Items starting at the same time are merged since trimming them would
+	// leave nothing to display.
+	OverlapStrategyTrimEarlier
+)
+
+// Overlaps returns the pairs of items whose time ranges intersect, ordered by start time
+func (s Subtitles) Overlaps() (os []Overlap) {
+	// Sort item indexes by start time
+	var idxs = make([]int, len(s.Items))
+	for idx := range idxs {
+		idxs[idx] = idx
+	}
+	sort.SliceStable(idxs, func(a, b int) bool { return s.Items[idxs[a]].StartAt < s.Items[idxs[b]].StartAt })
+
+	// Loop through items
+	for k, idx := range idxs {
+		var i = s.Items[idx]
+		for _, idxNext := range idxs[k+1:] {
+			// Next items start after the item ends
+			var n = s.Items[idxNext]
+			if n.StartAt >= i.EndAt {
+				break
+			}
+
+			// Add overlap
+			var end = i.EndAt
+			if n.EndAt < end {
+				end = n.EndAt
+			}
+			if end <= n.StartAt {
+				continue
+			}
+			os = append(os, Overlap{
+				Duration: end - n.StartAt,
+				Indexes:  [2]int{idx, idxNext},
+				Items:    [2]*Item{i, n},
+			})
+		}
+	}
+	return
+}
+
+// ResolveOverlaps orders items by start time and resolves their overlaps with a strategy so that no items intersect
+func (s *Subtitles) ResolveOverlaps(strategy OverlapStrategy) {
+	// Order
+	s.Order()
+
+	// Loop through items
+	var is []*Item
+	for _, i := range s.Items {
+		// No overlap
+		if len(is) == 0 || i.StartAt >= is[len(is)-1].EndAt {
+			is = append(is, i)
+			continue
+		}
+
+		// Resolve
+		var p = is[len(is)-1]
+		switch {
+		case strategy == OverlapStrategyShiftLater:
+			var d = p.EndAt - i.StartAt
+			i.StartAt += d
+			i.EndAt += d
+			is = append(is, i)
+		case strategy == OverlapStrategyTrimEarlier && p.StartAt < i.StartAt:
+			p.EndAt = i.StartAt
+			is = append(is, i)
+		default:
+			p.Lines = append(p.Lines, i.Lines...)
+			if i.EndAt > p.EndAt {
+				p.EndAt = i.EndAt
+			}
+		}
+	}
+	s.Items = is
+}
@@ -0,0 +1,62 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func overlappingSubtitles() *astisub.Subtitles {
+	var item = func(start, end time.Duration, text string) *astisub.Item {
+		return &astisub.Item{EndAt: end * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}, StartAt: start * time.Second}
+	}
+	return &astisub.Subtitles{Items: []*astisub.Item{
+		item(4, 6, "3"),
+		item(0, 3, "1"),
+		item(2, 5, "2"),
+		item(7, 8, "4"),
+	}}
+}
+
+func TestSubtitles_Overlaps(t *testing.T) {
+	s := overlappingSubtitles()
+	os := s.Overlaps()
+	assert.Len(t, os, 2)
+	assert.Equal(t, [2]int{1, 2}, os[0].Indexes)
+	assert.Equal(t, time.Second, os[0].Duration)
+	assert.Equal(t, [2]*astisub.Item{s.Items[1], s.Items[2]}, os[0].Items)
+	assert.Equal(t, [2]int{2, 0}, os[1].Indexes)
+	assert.Equal(t, time.Second, os[1].Duration)
+}
+
+func TestSubtitles_ResolveOverlaps(t *testing.T) {
+	var boundaries = func(s *astisub.Subtitles) (bs [][2]time.Duration) {
+		for _, i := range s.Items {
+			bs = append(bs, [2]time.Duration{i.StartAt / time.Second, i.EndAt / time.Second})
+		}
+		return
+	}
+
+	// Merge
+	s := overlappingSubtitles()
+	s.ResolveOverlaps(astisub.OverlapStrategyMerge)
+	assert.Equal(t, [][2]time.Duration{{0, 6}, {7, 8}}, boundaries(s))
+	assert.Equal(t, "1 - 2 - 3", s.Items[0].String())
+	assert.Empty(t, s.Overlaps())
+
+	// Shift later
+	s = overlappingSubtitles()
+	s.ResolveOverlaps(astisub.OverlapStrategyShiftLater)
+	assert.Equal(t, [][2]time.Duration{{0, 3}, {3, 6}, {6, 8}, {8, 9}}, boundaries(s))
+	assert.Empty(t, s.Overlaps())
+
+	// Trim earlier
+	s = overlappingSubtitles()
+	s.Items = append(s.Items, &astisub.Item{EndAt: 9 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "5"}}}}, StartAt: 7 * time.Second})
+	s.ResolveOverlaps(astisub.OverlapStrategyTrimEarlier)
+	assert.Equal(t, [][2]time.Duration{{0, 2}, {2, 4}, {4, 6}, {7, 9}}, boundaries(s))
+	assert.Equal(t, "4 - 5", s.Items[3].String())
+	assert.Empty(t, s.Overlaps())
+}
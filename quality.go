@@ -0,0 +1,217 @@
+package astisub
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// QualityCategory represents a category of quality checks
+type QualityCategory string
+
+// Quality categories
+const (
+	QualityCategoryConfidence  QualityCategory = "confidence"
+	QualityCategoryGaps        QualityCategory = "gaps"
+	QualityCategoryLineLengths QualityCategory = "line_lengths"
+	QualityCategoryOverlaps    QualityCategory = "overlaps"
+	QualityCategoryReadability QualityCategory = "readability"
+	QualityCategorySpelling    QualityCategory = "spelling"
+)
+
+// QualityOptions represents quality options
+type QualityOptions struct {
+	// Line lengths are checked against these limits. Defaults to the limits of TargetStreaming.
+	Limits *Limits
+	// Items read faster than this number of characters per second are considered unreadable. Defaults to 17.
+	MaxCharactersPerSecond float64
+	// Items whose lowest confidence is less than this value are reported for human review. The confidence category
+	// is skipped if 0.
+	MinConfidence float64
+	// Items displayed for less than this duration are considered unreadable. Defaults to 1s.
+	MinDuration time.Duration
+	// Gaps between consecutive items shorter than this duration are reported, since they make items flicker.
+	// Defaults to 2 frames at 25 fps.
+	MinGap time.Duration
+	// Returns whether a word is spelled correctly. The spelling category is skipped if nil.
+	SpellCheck func(word string) bool
+	// Weights of categories in the overall score. Categories missing from the map have a weight of 1, a weight of 0
+	// excludes a category from the overall score.
+	Weights map[QualityCategory]float64
+}
+
+// Categories of validation rules
+var qualityRuleCategories = map[string]QualityCategory{
+	ValidationRuleCharactersPerLine:   QualityCategoryLineLengths,
+	ValidationRuleCharactersPerSecond: QualityCategoryReadability,
+	ValidationRuleLinesPerItem:        QualityCategoryLineLengths,
+	ValidationRuleMaxDuration:         QualityCategoryReadability,
+	ValidationRuleMinDuration:         QualityCategoryReadability,
+	ValidationRuleMinGap:              QualityCategoryGaps,
+	ValidationRuleOverlap:             QualityCategoryOverlaps,
+	ValidationRuleWordsPerMinute:      QualityCategoryReadability,
+}
+
+// qualityItemCheck represents the last item checked in a category
+type qualityItemCheck struct {
+	failed bool
+	idx    int
+}
+
+// QualityIssue represents an issue found while checking quality
+type QualityIssue struct {
+	Category QualityCategory
+	Item     int // Index of the item
+	Message  string
+}
+
+// QualityCategoryScore represents the score of a category of quality checks
+type QualityCategoryScore struct {
+	Checked int     // Number of checked elements, i.e. items, pairs of consecutive items or words
+	Failed  int     // Number of checked elements that failed
+	Score   float64 // Between 0 and 100
+	Weight  float64
+}
+
+// QualityReport represents the result of quality checks
+type QualityReport struct {
+	Categories map[QualityCategory]*QualityCategoryScore
+	Issues     []QualityIssue
+	Score      float64 // Weighted average of category scores, between 0 and 100
+}
+
+// Quality runs quality checks with default options
+func (s Subtitles) Quality() QualityReport {
+	return s.QualityWithOptions(QualityOptions{})
+}
+
+// QualityWithOptions runs quality checks on readability, gaps, overlaps, line lengths and, if a spell checker or a
+// min confidence is provided, spelling or confidence, and aggregates them into a weighted score. Each category score
+// is the percentage of its checked elements that passed.
+func (s Subtitles) QualityWithOptions(o QualityOptions) (r QualityReport) {
+	// Default options
+	if o.Limits == nil {
+		var l = TargetStreaming.Limits()
+		o.Limits = &l
+	}
+	if o.MaxCharactersPerSecond <= 0 {
+		o.MaxCharactersPerSecond = 17
+	}
+	if o.MinDuration <= 0 {
+		o.MinDuration = time.Second
+	}
+	if o.MinGap <= 0 {
+		o.MinGap = 2 * time.Second / defaultFramerate
+	}
+	return s.quality(ValidationProfile{
+		MaxCharactersPerLine:   o.Limits.MaxCharactersPerLine,
+		MaxCharactersPerSecond: o.MaxCharactersPerSecond,
+		MaxLines:               o.Limits.MaxLines,
+		MinDuration:            o.MinDuration,
+		MinGap:                 o.MinGap,
+		Tokenizer:              o.Limits.Tokenizer,
+	}, o)
+}
+
+// Check runs quality checks against the limits of a preset, such as PresetNetflix, and aggregates them into a
+// weighted score. Limits the preset disables are not checked and its custom rules are not run.
+func (s Subtitles) Check(p ValidationProfile) QualityReport {
+	return s.quality(p, QualityOptions{})
+}
+
+// quality runs quality checks against the limits of a profile, options only providing the confidence, spelling and
+// weight settings
+func (s Subtitles) quality(p ValidationProfile, o QualityOptions) (r QualityReport) {
+	// Init
+	r.Categories = make(map[QualityCategory]*QualityCategoryScore)
+	var fail = func(c QualityCategory, idx int, format string, args ...interface{}) {
+		r.Categories[c].Failed++
+		r.Issues = append(r.Issues, QualityIssue{Category: c, Item: idx, Message: fmt.Sprintf(format, args...)})
+	}
+	var check = func(c QualityCategory, idx int, ok bool, format string, args ...interface{}) {
+		if _, exists := r.Categories[c]; !exists {
+			r.Categories[c] = &QualityCategoryScore{}
+		}
+		r.Categories[c].Checked++
+		if !ok {
+			fail(c, idx, format, args...)
+		}
+	}
+
+	// Run validation checks. An item is checked once per category, its first failing check being reported.
+	var last = make(map[QualityCategory]qualityItemCheck)
+	s.check(p, func(rule string, idx int, ok bool, format string, args ...interface{}) {
+		var c = qualityRuleCategories[rule]
+		if l, exists := last[c]; exists && l.idx == idx {
+			if !ok && !l.failed {
+				last[c] = qualityItemCheck{failed: true, idx: idx}
+				fail(c, idx, format, args...)
+			}
+			return
+		}
+		last[c] = qualityItemCheck{failed: !ok, idx: idx}
+		check(c, idx, ok, format, args...)
+	})
+
+	// Loop through items
+	for idx, i := range s.Items {
+		// Confidence
+		if o.MinConfidence > 0 {
+			if c, ok := i.LowestConfidence(); ok {
+				check(QualityCategoryConfidence, idx, c >= o.MinConfidence, "item #%d has a confidence of %.2f which is less than %.2f", idx+1, c, o.MinConfidence)
+			}
+		}
+
+		// Spelling
+		if o.SpellCheck != nil {
+			var t = itemTokenizer(p.Tokenizer, i)
+			for _, l := range i.Lines {
+				for _, tw := range t.Words(l.String()) {
+					for _, w := range strings.FieldsFunc(tw.Text, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' }) {
+						if w = strings.Trim(w, "'"); len(w) > 0 {
+							check(QualityCategorySpelling, idx, o.SpellCheck(w), "item #%d contains misspelled word %q", idx+1, w)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	// Categories that always apply are reported even when nothing has been checked
+	for _, c := range []QualityCategory{QualityCategoryGaps, QualityCategoryLineLengths, QualityCategoryOverlaps, QualityCategoryReadability} {
+		if _, ok := r.Categories[c]; !ok {
+			r.Categories[c] = &QualityCategoryScore{}
+		}
+	}
+
+	// Compute scores
+	var total, weights float64
+	for c, cs := range r.Categories {
+		cs.Score = 100
+		if cs.Checked > 0 {
+			cs.Score = 100 * float64(cs.Checked-cs.Failed) / float64(cs.Checked)
+		}
+		cs.Weight = 1
+		if w, ok := o.Weights[c]; ok {
+			cs.Weight = w
+		}
+		total += cs.Score * cs.Weight
+		weights += cs.Weight
+	}
+	r.Score = 100
+	if weights > 0 {
+		r.Score = total / weights
+	}
+	return
+}
+
+// qualityCharacterCount returns the number of characters read by viewers, i.e. the characters of words and the
+// white spaces separating them
+func qualityCharacterCount(i *Item, t Tokenizer) (n int) {
+	for _, l := range i.Lines {
+		n += utf8.RuneCountInString(joinWords(t.Words(l.String())))
+	}
+	return
+}
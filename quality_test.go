@@ -0,0 +1,57 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Quality(t *testing.T) {
+	var line = func(t string) astisub.Line { return astisub.Line{Items: []astisub.LineItem{{Text: t}}} }
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 3 * time.Second, Lines: []astisub.Line{line("Hello world")}, StartAt: time.Second},
+		{EndAt: 5 * time.Second, Lines: []astisub.Line{line("Helo")}, StartAt: 3*time.Second + 40*time.Millisecond},
+		{EndAt: 6 * time.Second, Lines: []astisub.Line{line("This line is way too long to be read in half a second at all")}, StartAt: 4500 * time.Millisecond},
+	}}
+
+	// Default options
+	r := s.Quality()
+	assert.Len(t, r.Categories, 4)
+	assert.Equal(t, astisub.QualityCategoryScore{Checked: 3, Failed: 1, Score: 100 * 2 / 3., Weight: 1}, *r.Categories[astisub.QualityCategoryReadability])
+	assert.Equal(t, astisub.QualityCategoryScore{Checked: 3, Failed: 1, Score: 100 * 2 / 3., Weight: 1}, *r.Categories[astisub.QualityCategoryLineLengths])
+	assert.Equal(t, astisub.QualityCategoryScore{Checked: 2, Failed: 1, Score: 50, Weight: 1}, *r.Categories[astisub.QualityCategoryOverlaps])
+	assert.Equal(t, astisub.QualityCategoryScore{Checked: 1, Failed: 1, Score: 0, Weight: 1}, *r.Categories[astisub.QualityCategoryGaps])
+	assert.InDelta(t, (200/3.+200/3.+50)/4, r.Score, 1e-9)
+	assert.Equal(t, []astisub.QualityIssue{
+		{Category: astisub.QualityCategoryReadability, Item: 2, Message: "item #3 is read at 40.0 characters per second which is more than 17.0"},
+		{Category: astisub.QualityCategoryLineLengths, Item: 2, Message: "line #1 of item #3 has 60 characters which is more than 42"},
+		{Category: astisub.QualityCategoryGaps, Item: 1, Message: "gap of 40ms between items #1 and #2 is less than 80ms"},
+		{Category: astisub.QualityCategoryOverlaps, Item: 2, Message: "item #3 overlaps item #2 by 500ms"},
+	}, r.Issues)
+
+	// Spell check and weights
+	r = s.QualityWithOptions(astisub.QualityOptions{
+		SpellCheck: func(w string) bool { return w != "Helo" },
+		Weights:    map[astisub.QualityCategory]float64{astisub.QualityCategoryGaps: 0, astisub.QualityCategorySpelling: 2},
+	})
+	assert.Len(t, r.Categories, 5)
+	assert.Equal(t, 18, r.Categories[astisub.QualityCategorySpelling].Checked)
+	assert.Equal(t, 1, r.Categories[astisub.QualityCategorySpelling].Failed)
+	assert.InDelta(t, (200/3.+200/3.+50+2*1700/18.)/5, r.Score, 1e-9)
+
+	// Preset
+	r = s.Check(astisub.PresetBBC)
+	assert.Equal(t, []astisub.QualityIssue{
+		{Category: astisub.QualityCategoryReadability, Item: 2, Message: "item #3 is read at 600 words per minute which is more than 180"},
+		{Category: astisub.QualityCategoryLineLengths, Item: 2, Message: "line #1 of item #3 has 60 characters which is more than 37"},
+		{Category: astisub.QualityCategoryGaps, Item: 1, Message: "gap of 40ms between items #1 and #2 is less than 80ms"},
+		{Category: astisub.QualityCategoryOverlaps, Item: 2, Message: "item #3 overlaps item #2 by 500ms"},
+	}, r.Issues)
+
+	// Empty subtitles
+	r = astisub.Subtitles{}.Quality()
+	assert.Equal(t, 100., r.Score)
+	assert.Empty(t, r.Issues)
+}
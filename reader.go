@@ -0,0 +1,126 @@
+package astisub
+
+import (
+	"context"
+	"io"
+
+	"github.com/asticode/go-astits"
+	"github.com/pkg/errors"
+)
+
+// Reader reads items one at a time
+// Teletext items are decoded incrementally, which keeps memory usage low while reading multi-hour captures. Other
+// formats are parsed entirely before their first item is returned.
+type Reader struct {
+	fill  func() ([]*Item, error) // Returns io.EOF once there are no more items
+	items []*Item
+}
+
+// NewReader creates a new reader
+func NewReader(i io.Reader, f Format, o Options) (r *Reader, err error) {
+	switch f {
+	case FormatJSON, FormatMicroDVD, FormatSBV, FormatSCC, FormatSRT, FormatSSA, FormatSTL, FormatTTML, FormatWebVTT:
+		r = &Reader{fill: newParsingFill(i, f, o)}
+	case FormatTeletext:
+		var fill func() ([]*Item, error)
+		if fill, err = newTeletextFill(i, o.Teletext); err != nil {
+			return
+		}
+		r = &Reader{fill: withItemHook(fill, o.AfterReadItem)}
+	default:
+		err = ErrInvalidFormat
+	}
+	return
+}
+
+// Next returns the next item. io.EOF is returned once there are no more items.
+func (r *Reader) Next() (i *Item, err error) {
+	// Fill items
+	for len(r.items) == 0 {
+		if r.items, err = r.fill(); err != nil {
+			return
+		}
+	}
+
+	// Shift item
+	i = r.items[0]
+	r.items[0] = nil
+	r.items = r.items[1:]
+	return
+}
+
+// newParsingFill returns a fill function parsing the whole content and returning all its items at once
+func newParsingFill(i io.Reader, f Format, o Options) func() ([]*Item, error) {
+	var done bool
+	return func() (is []*Item, err error) {
+		// Content has already been parsed
+		if done {
+			err = io.EOF
+			return
+		}
+		done = true
+
+		// Parse
+		var s *Subtitles
+		if s, err = Read(i, f, o); err != nil {
+			err = errors.Wrap(err, "astisub: reading failed")
+			return
+		}
+		is = s.Items
+		return
+	}
+}
+
+// newTeletextFill returns a fill function decoding teletext data until at least one page has been fully received
+func newTeletextFill(r io.Reader, o TeletextOptions) (fn func() ([]*Item, error), err error) {
+	// Init
+	var dmx = astits.New(context.Background(), r)
+
+	// Get the teletext PID
+	var pid uint16
+	if pid, err = teletextPID(dmx, o); err != nil {
+		if err != ErrNoValidTeletextPID {
+			err = errors.Wrap(err, "astisub: getting teletext PID failed")
+		}
+		return
+	}
+
+	// Create fill function
+	var td = NewTeletextDecoder(o.Page)
+	var done bool
+	fn = func() (is []*Item, err error) {
+		// All data has been decoded
+		if done {
+			err = io.EOF
+			return
+		}
+
+		// Loop in data
+		var d *astits.Data
+		for {
+			// Fetch next data
+			if d, err = dmx.NextData(); err != nil {
+				if err == astits.ErrNoMorePackets {
+					err = nil
+					done = true
+					is = td.Subtitles().Items
+					return
+				}
+				err = errors.Wrap(err, "astisub: fetching next data failed")
+				return
+			}
+
+			// This data is not of interest to us
+			if d.PID != pid {
+				continue
+			}
+
+			// Decode
+			td.Decode(d)
+			if is = td.Items(); len(is) > 0 {
+				return
+			}
+		}
+	}
+	return
+}
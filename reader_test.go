@@ -0,0 +1,36 @@
+package astisub_test
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	// Invalid format
+	_, err := astisub.NewReader(strings.NewReader(""), astisub.Format("invalid"), astisub.Options{})
+	assert.Equal(t, astisub.ErrInvalidFormat, err)
+
+	// Read
+	f, err := os.Open("./testdata/example-in.srt")
+	assert.NoError(t, err)
+	defer f.Close()
+	r, err := astisub.NewReader(f, astisub.FormatSRT, astisub.Options{})
+	assert.NoError(t, err)
+	s, err := astisub.OpenFile("./testdata/example-in.srt")
+	assert.NoError(t, err)
+	for _, e := range s.Items {
+		i, err := r.Next()
+		assert.NoError(t, err)
+		assert.Equal(t, e.String(), i.String())
+		assert.Equal(t, e.StartAt, i.StartAt)
+	}
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+}
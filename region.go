@@ -0,0 +1,121 @@
+package astisub
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Vertical positions, also used as base IDs of generated regions
+const (
+	verticalPositionBottom = "bottom"
+	verticalPositionMiddle = "middle"
+	verticalPositionTop    = "top"
+)
+
+// generateRegions returns subtitles where items that are positioned but have no region are assigned a region
+// generated from their vertical position. Input items and regions are not modified. Generated regions get an ID
+// based on their vertical position, suffixed with a number if the subtitles already have a region with that ID.
+func (s Subtitles) generateRegions() Subtitles {
+	// Copy regions
+	var o = s
+	var generated = make(map[string]*Region)
+	o.Items = make([]*Item, 0, len(s.Items))
+	o.Regions = make(map[string]*Region)
+	for id, r := range s.Regions {
+		o.Regions[id] = r
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		// Get vertical position
+		var p string
+		if i.Region == nil {
+			p = itemVerticalPosition(i)
+		}
+		if len(p) == 0 {
+			o.Items = append(o.Items, i)
+			continue
+		}
+
+		// Get region
+		r, ok := generated[p]
+		if !ok {
+			var id = p
+			for idx := 2; o.Regions[id] != nil; idx++ {
+				id = p + "-" + strconv.Itoa(idx)
+			}
+			r = newGeneratedRegion(id, p)
+			generated[p] = r
+			o.Regions[id] = r
+		}
+
+		// Copy item
+		var ni = &Item{}
+		*ni = *i
+		ni.Region = r
+		o.Items = append(o.Items, ni)
+	}
+	return o
+}
+
+// itemVerticalPosition returns the vertical position of an item based on its SSA alignment or WebVTT line setting.
+// An empty string is returned if the item is not positioned.
+func itemVerticalPosition(i *Item) string {
+	// SSA alignment uses the numpad layout
+	for _, sa := range []*StyleAttributes{i.InlineStyle, ssaItemStyleAttributes(i)} {
+		if sa == nil || !ssaValidAlignment(sa.SSAAlignment) {
+			continue
+		}
+		switch (*sa.SSAAlignment - 1) / 3 {
+		case 1:
+			return verticalPositionMiddle
+		case 2:
+			return verticalPositionTop
+		default:
+			return verticalPositionBottom
+		}
+	}
+
+	// WebVTT line
+	if i.InlineStyle != nil {
+		if line, ok := parseWebVTTPercentage(strings.Split(i.InlineStyle.WebVTTLine, ",")[0]); ok {
+			switch {
+			case line < 100.0/3:
+				return verticalPositionTop
+			case line < 200.0/3:
+				return verticalPositionMiddle
+			default:
+				return verticalPositionBottom
+			}
+		}
+	}
+	return ""
+}
+
+// newGeneratedRegion creates a region spanning 80% of the width at a vertical position, with TTML and WebVTT
+// attributes
+func newGeneratedRegion(id, p string) *Region {
+	var sa = &StyleAttributes{
+		TTMLExtent:  "80% 20%",
+		WebVTTLines: 3,
+		WebVTTWidth: "80%",
+	}
+	switch p {
+	case verticalPositionMiddle:
+		sa.TTMLDisplayAlign = "center"
+		sa.TTMLOrigin = "10% 40%"
+		sa.WebVTTRegionAnchor = "0%,50%"
+		sa.WebVTTViewportAnchor = "10%,50%"
+	case verticalPositionTop:
+		sa.TTMLDisplayAlign = "before"
+		sa.TTMLOrigin = "10% 5%"
+		sa.WebVTTRegionAnchor = "0%,0%"
+		sa.WebVTTViewportAnchor = "10%,5%"
+	default:
+		sa.TTMLDisplayAlign = "after"
+		sa.TTMLOrigin = "10% 75%"
+		sa.WebVTTRegionAnchor = "0%,100%"
+		sa.WebVTTViewportAnchor = "10%,95%"
+	}
+	return &Region{ID: id, InlineStyle: sa}
+}
@@ -0,0 +1,190 @@
+package astisub
+
+import (
+	"html"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SAMI regexps
+var (
+	samiRegexpAttribute = regexp.MustCompile(`([\w-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+	samiRegexpBreak     = regexp.MustCompile(`(?i)<br\s*/?>`)
+	samiRegexpClass     = regexp.MustCompile(`\.([\w-]+)\s*\{([^}]*)\}`)
+	samiRegexpParagraph = regexp.MustCompile(`(?i)<p(\s[^>]*)?>`)
+	samiRegexpSync      = regexp.MustCompile(`(?i)<sync(\s[^>]*)?>`)
+	samiRegexpTag       = regexp.MustCompile(`<[^>]*>`)
+	samiRegexpTitle     = regexp.MustCompile(`(?is)<title>(.*?)</title>`)
+)
+
+// samiClass represents a SAMI language class, i.e. a class declaring the language of the paragraphs using it
+type samiClass struct {
+	lang string
+	name string
+}
+
+// samiTrack represents the track being built for a class
+type samiTrack struct {
+	current *Item // Item displayed until the next synchronization of the class
+	t       *Track
+}
+
+// ReadSetFromSAMI parses a multi-language .smi content into a subtitle set holding a track per class, ordered by
+// first appearance. A class's language and title are the "lang" and "Name" properties declared in the STYLE block.
+// An item is displayed until the next synchronization holding a paragraph of its class, such as the usual
+// "&nbsp;" paragraphs clearing the screen. Formatting tags are dropped.
+func ReadSetFromSAMI(i io.Reader) (s *SubtitleSet, err error) {
+	// Read content
+	var b []byte
+	if b, err = ioutil.ReadAll(i); err != nil {
+		err = errors.Wrap(err, "astisub: reading failed")
+		return
+	}
+	var c = strings.TrimPrefix(string(b), string(BytesBOM))
+
+	// Split head and body
+	var head, body = c, ""
+	if idx := strings.Index(strings.ToLower(c), "<body"); idx >= 0 {
+		head, body = c[:idx], c[idx:]
+	}
+
+	// Parse classes
+	var classes = make(map[string]samiClass)
+	for _, m := range samiRegexpClass.FindAllStringSubmatch(head, -1) {
+		var sc samiClass
+		for _, d := range strings.Split(m[2], ";") {
+			var kv = strings.SplitN(d, ":", 2)
+			if len(kv) < 2 {
+				continue
+			}
+			switch strings.ToLower(strings.TrimSpace(kv[0])) {
+			case "lang":
+				sc.lang = strings.TrimSpace(kv[1])
+			case "name":
+				sc.name = strings.TrimSpace(kv[1])
+			}
+		}
+		classes[strings.ToLower(m[1])] = sc
+	}
+
+	// Get title
+	var title string
+	if m := samiRegexpTitle.FindStringSubmatch(head); m != nil {
+		title = strings.TrimSpace(html.UnescapeString(m[1]))
+	}
+
+	// Loop through synchronizations
+	s = NewSubtitleSet()
+	var last time.Duration
+	var tracks = make(map[string]*samiTrack)
+	var syncs = samiRegexpSync.FindAllStringSubmatchIndex(body, -1)
+	for idx, sm := range syncs {
+		// Parse attributes
+		var as = samiAttributes(body[sm[0]:sm[1]])
+		var start, end int
+		if start, err = strconv.Atoi(as["start"]); err != nil {
+			err = errors.Wrapf(err, "astisub: atoi of %s failed", as["start"])
+			return
+		}
+		if v, ok := as["end"]; ok {
+			if end, err = strconv.Atoi(v); err != nil {
+				err = errors.Wrapf(err, "astisub: atoi of %s failed", v)
+				return
+			}
+		}
+		var startAt = time.Duration(start) * time.Millisecond
+		if startAt > last {
+			last = startAt
+		}
+
+		// Get block
+		var block = body[sm[1]:]
+		if idx+1 < len(syncs) {
+			block = body[sm[1]:syncs[idx+1][0]]
+		}
+
+		// Loop through paragraphs
+		var ps = samiRegexpParagraph.FindAllStringSubmatchIndex(block, -1)
+		for pidx, pm := range ps {
+			// Get text
+			var text = block[pm[1]:]
+			if pidx+1 < len(ps) {
+				text = block[pm[1]:ps[pidx+1][0]]
+			}
+
+			// Get track
+			var class = strings.ToLower(samiAttributes(block[pm[0]:pm[1]])["class"])
+			var st, ok = tracks[class]
+			if !ok {
+				var sc = classes[class]
+				st = &samiTrack{t: &Track{
+					Language:  languageFromTag(sc.lang),
+					Subtitles: NewSubtitles(),
+					Title:     sc.name,
+				}}
+				st.t.Subtitles.Metadata = &Metadata{Language: st.t.Language, Title: title}
+				tracks[class] = st
+				s.Add(st.t)
+			}
+
+			// End the item being displayed
+			if st.current != nil {
+				st.current.EndAt = startAt
+				st.current = nil
+			}
+
+			// Get lines
+			var ls = samiLines(text)
+			if len(ls) == 0 {
+				continue
+			}
+
+			// Append item
+			var i = &Item{Lines: ls, StartAt: startAt}
+			if end > start {
+				i.EndAt = time.Duration(end) * time.Millisecond
+			} else {
+				st.current = i
+			}
+			st.t.Subtitles.Items = append(st.t.Subtitles.Items, i)
+		}
+	}
+
+	// Items are still displayed
+	for _, st := range tracks {
+		if st.current == nil {
+			continue
+		}
+		if st.current.EndAt = last; last <= st.current.StartAt {
+			st.t.Subtitles.Warnings = append(st.t.Subtitles.Warnings, Warning{Message: "last caption is never cleared"})
+		}
+	}
+	return
+}
+
+// samiAttributes returns the attributes of a tag indexed by their lower-case name
+func samiAttributes(tag string) (as map[string]string) {
+	as = make(map[string]string)
+	for _, m := range samiRegexpAttribute.FindAllStringSubmatch(tag, -1) {
+		as[strings.ToLower(m[1])] = m[2] + m[3] + m[4]
+	}
+	return
+}
+
+// samiLines returns the non-blank lines of a paragraph's text, breaks being the only line separators
+func samiLines(text string) (ls []Line) {
+	text = strings.Join(strings.Fields(text), " ")
+	for _, t := range samiRegexpBreak.Split(text, -1) {
+		t = html.UnescapeString(samiRegexpTag.ReplaceAllString(t, ""))
+		if t = strings.TrimSpace(strings.Replace(t, "\u00a0", " ", -1)); len(t) > 0 {
+			ls = append(ls, Line{Items: []LineItem{{Text: t}}})
+		}
+	}
+	return
+}
@@ -0,0 +1,48 @@
+package astisub_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadSetFromSAMI(t *testing.T) {
+	s, err := astisub.ReadSetFromSAMI(strings.NewReader(`<SAMI>
+<HEAD>
+<TITLE>Example</TITLE>
+<STYLE TYPE="text/css"><!--
+P { font-family: Arial; }
+.ENCC { Name: English; lang: en-US; }
+.FRCC { Name: Français; lang: fr-FR; }
+--></STYLE>
+</HEAD>
+<BODY>
+<SYNC Start=1000><P Class=ENCC>Hello<br><i>world</i>
+<P Class=FRCC>Bonjour &amp; bienvenue
+<SYNC Start=2500><P Class=ENCC>&nbsp;
+<SYNC Start=3000><P Class=FRCC>&nbsp;</P>
+<SYNC Start=4000 End=5000><P Class=ENCC>Bye</P>
+</BODY>
+</SAMI>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{astisub.LanguageEnglish, astisub.LanguageFrench}, s.Languages())
+
+	en := s.Track(astisub.LanguageEnglish, astisub.TrackKindSubtitles)
+	assert.Equal(t, "English", en.Title)
+	assert.Equal(t, &astisub.Metadata{Language: astisub.LanguageEnglish, Title: "Example"}, en.Subtitles.Metadata)
+	assert.Len(t, en.Subtitles.Items, 2)
+	assert.Equal(t, time.Second, en.Subtitles.Items[0].StartAt)
+	assert.Equal(t, 2500*time.Millisecond, en.Subtitles.Items[0].EndAt)
+	assert.Equal(t, "Hello - world", en.Subtitles.Items[0].String())
+	assert.Equal(t, 4*time.Second, en.Subtitles.Items[1].StartAt)
+	assert.Equal(t, 5*time.Second, en.Subtitles.Items[1].EndAt)
+
+	fr := s.Track(astisub.LanguageFrench, astisub.TrackKindSubtitles)
+	assert.Equal(t, "Français", fr.Title)
+	assert.Len(t, fr.Subtitles.Items, 1)
+	assert.Equal(t, 3*time.Second, fr.Subtitles.Items[0].EndAt)
+	assert.Equal(t, "Bonjour & bienvenue", fr.Subtitles.Items[0].String())
+}
@@ -0,0 +1,122 @@
+package astisub
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Vars
+var (
+	regexpCharacterReference = regexp.MustCompile(`^&([a-zA-Z][a-zA-Z0-9]*|#[0-9]+|#[xX][0-9a-fA-F]+);`)
+)
+
+// SanitizePolicy represents the way writers handle characters and markup that are illegal in their format
+type SanitizePolicy int
+
+// Sanitize policies
+const (
+	// Texts are written as is
+	SanitizePolicyKeep SanitizePolicy = iota
+	// Illegal characters are escaped when the format has an escaping mechanism, and stripped otherwise
+	SanitizePolicyEscape
+	// Illegal characters are removed
+	SanitizePolicyStrip
+)
+
+// sanitize returns subtitles whose texts can't break parsers of the format. Items are copied before being modified so
+// that the input subtitles are left untouched.
+func (s Subtitles) sanitize(f Format, p SanitizePolicy) (o Subtitles) {
+	// Nothing to sanitize
+	o = s
+	if p == SanitizePolicyKeep {
+		return
+	}
+
+	// Loop through items
+	o.Items = make([]*Item, 0, len(s.Items))
+	for _, i := range s.Items {
+		// Copy item
+		var c = *i
+		c.Comments = nil
+		c.Lines = nil
+
+		// Sanitize comments
+		for _, comment := range i.Comments {
+			c.Comments = append(c.Comments, sanitizeComment(comment, f))
+		}
+
+		// Sanitize lines
+		for _, l := range i.Lines {
+			var nl = l
+			nl.Items = make([]LineItem, 0, len(l.Items))
+			for _, li := range l.Items {
+				li.Text = sanitizeText(li.Text, f, p)
+				nl.Items = append(nl.Items, li)
+			}
+
+			// Empty lines would end the cue
+			if f != FormatTTML && strings.TrimSpace(nl.String()) == "" {
+				continue
+			}
+			c.Lines = append(c.Lines, nl)
+		}
+		o.Items = append(o.Items, &c)
+	}
+	return
+}
+
+// sanitizeComment makes sure a comment doesn't end its block prematurely
+func sanitizeComment(c string, f Format) string {
+	if f == FormatWebVTT {
+		c = strings.Replace(c, "-->", "->", -1)
+	}
+	return c
+}
+
+// sanitizeText makes sure a text is legal in a format
+func sanitizeText(t string, f Format, p SanitizePolicy) string {
+	// Remove control characters which are illegal in all formats. Newlines are replaced with spaces since a line item
+	// can't span several lines.
+	t = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\n' || r == '\r':
+			return ' '
+		case r < 0x20 && r != '\t', r == 0x7f, r == 0xfffe, r == 0xffff:
+			return -1
+		}
+		return r
+	}, t)
+
+	// Switch on format
+	switch f {
+	case FormatSRT:
+		// SRT has no escaping mechanism
+		t = strings.Replace(t, "-->", "->", -1)
+	case FormatWebVTT:
+		var b bytes.Buffer
+		for idx := 0; idx < len(t); idx++ {
+			switch t[idx] {
+			case '&':
+				// Character references are legal
+				if regexpCharacterReference.MatchString(t[idx:]) {
+					b.WriteByte('&')
+				} else if p == SanitizePolicyEscape {
+					b.WriteString("&amp;")
+				}
+			case '<':
+				if p == SanitizePolicyEscape {
+					b.WriteString("&lt;")
+				}
+			case '>':
+				if p == SanitizePolicyEscape {
+					b.WriteString("&gt;")
+				}
+			default:
+				b.WriteByte(t[idx])
+			}
+		}
+		t = b.String()
+	}
+	return t
+}
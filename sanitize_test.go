@@ -0,0 +1,56 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitize(t *testing.T) {
+	var s = astisub.Subtitles{Items: []*astisub.Item{{
+		Comments: []string{"a --> b"},
+		EndAt:    2 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "Tom & Jerry &amp; <b>friends</b> --> go\x07"}}},
+			{Items: []astisub.LineItem{{Text: "\n"}}},
+			{Items: []astisub.LineItem{{Text: "a\nb"}}},
+		},
+		StartAt: time.Second,
+	}}}
+
+	// Keep
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Tom & Jerry &amp; <b>friends</b> --> go\x07\n")
+
+	// WebVTT escape
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{Sanitize: astisub.SanitizePolicyEscape})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nNOTE a -> b\n\n1\n00:00:01.000 --> 00:00:02.000\nTom &amp; Jerry &amp; &lt;b&gt;friends&lt;/b&gt; --&gt; go\na b\n", w.String())
+	assert.Equal(t, "Tom & Jerry &amp; <b>friends</b> --> go\x07", s.Items[0].Lines[0].String())
+	assert.Len(t, s.Items[0].Lines, 3)
+
+	// WebVTT strip
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{Sanitize: astisub.SanitizePolicyStrip})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:02.000\nTom  Jerry &amp; bfriends/b -- go\na b\n")
+
+	// SRT
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{Sanitize: astisub.SanitizePolicyEscape})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:02,000\nTom & Jerry &amp; <b>friends</b> -> go\na b\n")
+
+	// TTML
+	w.Reset()
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Sanitize: astisub.SanitizePolicyStrip})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Tom &amp; Jerry &amp;amp; &lt;b&gt;friends&lt;/b&gt; --&gt; go<")
+	assert.NotContains(t, w.String(), "\ufffd")
+}
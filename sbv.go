@@ -0,0 +1,144 @@
+package astisub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SubViewer, as exported by YouTube
+// Each item starts with its time boundaries separated by a comma, followed by its lines, and items are separated by a
+// blank line:
+// 0:00:01.000,0:00:02.500
+// First line
+// Second line
+
+// Vars
+var sbvRegexpTimeBoundaries = regexp.MustCompile(`^(\d+:\d{2}:\d{2}\.\d+)\s*,\s*(\d+:\d{2}:\d{2}\.\d+)$`)
+
+// parseDurationSBV parses an .sbv duration
+func parseDurationSBV(i string) (time.Duration, error) {
+	return parseDuration(i, ".", 3)
+}
+
+// formatDurationSBV formats an .sbv duration, whose hours are not padded
+func formatDurationSBV(i time.Duration) string {
+	return strings.TrimPrefix(formatDuration(i, ".", 3), "0")
+}
+
+// ReadFromSBV parses an .sbv content
+func ReadFromSBV(i io.Reader) (o *Subtitles, err error) {
+	return readFromSBV(i, nil)
+}
+
+// readFromSBV parses an .sbv content, calling a hook for each item once it's complete
+func readFromSBV(i io.Reader, h ItemHook) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	var hk = &itemHooker{h: h}
+	var scanner = bufio.NewScanner(i)
+
+	// Loop through lines
+	var item *Item
+	var line int
+	for scanner.Scan() {
+		// Fetch line
+		line++
+		var t = strings.TrimRight(scanner.Text(), "\r")
+		if line == 1 {
+			t = strings.TrimPrefix(t, string(BytesBOM))
+		}
+
+		// Blank line ends the item
+		if len(strings.TrimSpace(t)) == 0 {
+			if err = hk.hook(o.Items); err != nil {
+				return
+			}
+			item = nil
+			continue
+		}
+
+		// Time boundaries start a new item
+		if item == nil {
+			var m = sbvRegexpTimeBoundaries.FindStringSubmatch(strings.TrimSpace(t))
+			if m == nil {
+				o.Warnings = append(o.Warnings, Warning{Line: line, Message: fmt.Sprintf("text %s without time boundaries skipped", t)})
+				continue
+			}
+			item = &Item{}
+			if item.StartAt, err = parseDurationSBV(m[1]); err != nil {
+				err = errors.Wrapf(err, "astisub: parsing sbv duration %s failed", m[1])
+				return
+			}
+			if item.EndAt, err = parseDurationSBV(m[2]); err != nil {
+				err = errors.Wrapf(err, "astisub: parsing sbv duration %s failed", m[2])
+				return
+			}
+			o.Items = append(o.Items, item)
+			continue
+		}
+
+		// Add text
+		item.Lines = append(item.Lines, Line{Items: []LineItem{{Text: t}}})
+	}
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "astisub: scanning failed")
+		return
+	}
+
+	// Hook last item
+	err = hk.hook(o.Items)
+	return
+}
+
+// WriteToSBV writes subtitles in .sbv format
+func (s Subtitles) WriteToSBV(o io.Writer) error {
+	return s.writeToSBV(o, nil)
+}
+
+// writeToSBV writes subtitles in .sbv format, calling a hook for each item right before it's written
+func (s Subtitles) writeToSBV(o io.Writer, h ItemHook) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Loop through items
+	var c []byte
+	for idx, i := range s.Items {
+		// Hook
+		if i, err = hookedItem(i, idx, h); err != nil {
+			return
+		}
+
+		// Add blank line
+		if idx > 0 {
+			c = append(c, bytesLineSeparator...)
+		}
+
+		// Add time boundaries
+		c = append(c, []byte(formatDurationSBV(i.StartAt)+","+formatDurationSBV(i.EndAt))...)
+		c = append(c, bytesLineSeparator...)
+
+		// Add lines, blank lines being skipped since they would end the item
+		for _, l := range i.Lines {
+			if t := l.String(); len(strings.TrimSpace(t)) > 0 {
+				c = append(c, []byte(t)...)
+				c = append(c, bytesLineSeparator...)
+			}
+		}
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
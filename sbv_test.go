@@ -0,0 +1,43 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSBV(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.sbv")
+	assert.NoError(t, err)
+	assertSubtitleItems(t, s)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToSBV(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.sbv")
+	assert.NoError(t, err)
+	err = s.WriteToSBV(w)
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+}
+
+func TestSBVLongDurations(t *testing.T) {
+	s, err := astisub.ReadFromSBV(strings.NewReader("\ufeffText\r\n12:00:01.500, 12:00:02.000\r\nHello\r\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []astisub.Warning{{Line: 1, Message: "text Text without time boundaries skipped"}}, s.Warnings)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, 12*time.Hour+time.Second+500*time.Millisecond, s.Items[0].StartAt)
+	w := &bytes.Buffer{}
+	err = s.WriteToSBV(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "12:00:01.500,12:00:02.000\nHello\n", w.String())
+}
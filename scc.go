@@ -0,0 +1,713 @@
+package astisub
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/pkg/errors"
+)
+
+// Scenarist Closed Caption
+// Each line holds a timecode followed by the CEA-608 byte pairs, in hexadecimal, sent from that timecode
+// https://en.wikipedia.org/wiki/EIA-608
+// Only the first caption channel (CC1) is read
+
+// Constants
+const (
+	sccColumns = 32
+	sccHeader  = "Scenarist_SCC V1.0"
+	sccRows    = 15
+)
+
+// SCC modes
+const (
+	sccModePopOn = iota
+	sccModePaintOn
+	sccModeRollUp
+)
+
+// SCC colors
+var sccColors = []*Color{ColorWhite, ColorLime, ColorBlue, ColorCyan, ColorRed, ColorYellow, ColorMagenta}
+
+// SCC PAC rows indexed by first byte, for second bytes below and above 0x60
+var sccPACRows = map[byte][2]int{
+	0x10: {11, 11},
+	0x11: {1, 2},
+	0x12: {3, 4},
+	0x13: {12, 13},
+	0x14: {14, 15},
+	0x15: {5, 6},
+	0x16: {7, 8},
+	0x17: {9, 10},
+}
+
+// SCC characters
+var (
+	sccBasicCharacters = map[byte]rune{
+		0x2a: 'á',
+		0x5c: 'é',
+		0x5e: 'í',
+		0x5f: 'ó',
+		0x60: 'ú',
+		0x7b: 'ç',
+		0x7c: '÷',
+		0x7d: 'Ñ',
+		0x7e: 'ñ',
+		0x7f: '█',
+	}
+	sccExtendedCharacters = map[byte][]rune{
+		0x12: []rune("ÁÉÓÚÜü‘¡*'—©℠•“”ÀÂÇÈÊËëÎÏïÔÙùÛ«»"),
+		0x13: []rune("ÃãÍÌìÒòÕõ{}\\^_|~ÄäÖöß¥¤│ÅåØø┌┐└┘"),
+	}
+	sccSpecialCharacters = []rune("®°½¿™¢£♪à èâêîôû")
+	// Codes indexed by character
+	sccBasicCodes, sccControlCodes = sccCharacterCodes()
+)
+
+// sccCharacterCodes returns the code of basic characters, sent as is, and the byte pair of special and extended
+// characters, sent as control codes
+func sccCharacterCodes() (basic map[rune]byte, control map[rune][2]byte) {
+	basic = make(map[rune]byte)
+	for b := byte(0x20); b < 0x80; b++ {
+		if r, ok := sccBasicCharacters[b]; ok {
+			basic[r] = b
+		} else {
+			basic[rune(b)] = b
+		}
+	}
+	control = make(map[rune][2]byte)
+	for idx, r := range sccSpecialCharacters {
+		if _, ok := basic[r]; !ok {
+			control[r] = [2]byte{0x11, 0x30 + byte(idx)}
+		}
+	}
+	for _, b1 := range []byte{0x12, 0x13} {
+		for idx, r := range sccExtendedCharacters[b1] {
+			if _, ok := basic[r]; !ok {
+				if _, ok = control[r]; !ok {
+					control[r] = [2]byte{b1, 0x20 + byte(idx)}
+				}
+			}
+		}
+	}
+	return
+}
+
+// sccStyle represents the style of a character
+type sccStyle struct {
+	color     int
+	italics   bool
+	underline bool
+}
+
+// sccCell represents a cell of the caption grid
+type sccCell struct {
+	r     rune
+	style sccStyle
+}
+
+// sccMemory represents a caption memory
+type sccMemory [sccRows][sccColumns]sccCell
+
+// isEmpty checks whether the memory holds no characters
+func (m sccMemory) isEmpty() bool {
+	return m == sccMemory{}
+}
+
+// sccReader represents an SCC reader
+type sccReader struct {
+	channel                 int
+	column, row             int
+	displayed, nonDisplayed sccMemory
+	item                    *Item
+	lastControl             uint16
+	mode, rollUpRows        int
+	o                       *Subtitles
+	shown                   sccMemory
+	style                   sccStyle
+	textMode                bool
+	time                    time.Duration
+	timedLines              bool // Whether lines of the item being displayed are timed
+}
+
+// ReadFromSCC parses a .scc content
+func ReadFromSCC(i io.Reader) (o *Subtitles, err error) {
+	return readFromSCC(i, nil)
+}
+
+// readFromSCC parses a .scc content, calling a hook for each item once it's been erased
+func readFromSCC(i io.Reader, h ItemHook) (o *Subtitles, err error) {
+	// Init
+	o = NewSubtitles()
+	o.Metadata = &Metadata{Kind: TrackKindCaptions}
+	var r = &sccReader{channel: 1, o: o, row: sccRows - 1}
+	var scanner = bufio.NewScanner(i)
+	var hk = &itemHooker{h: h}
+
+	// Loop through lines
+	var header bool
+	var line int
+	for scanner.Scan() {
+		// Fetch line
+		line++
+		var text = strings.TrimSpace(strings.TrimPrefix(scanner.Text(), string(BytesBOM)))
+		if len(text) == 0 {
+			continue
+		}
+
+		// Check header
+		if !header {
+			if text != sccHeader {
+				err = fmt.Errorf("astisub: invalid scc header %s", text)
+				return
+			}
+			header = true
+			continue
+		}
+
+		// Parse timecode
+		var fields = strings.Fields(text)
+		var t time.Duration
+		if t, err = parseDurationSCC(fields[0]); err != nil {
+			err = errors.Wrapf(err, "astisub: line %d: parsing scc timecode %s failed", line, fields[0])
+			return
+		}
+		r.time = t
+
+		// Loop through words
+		for _, f := range fields[1:] {
+			w, errParse := strconv.ParseUint(f, 16, 16)
+			if errParse != nil || len(f) != 4 {
+				o.Warnings = append(o.Warnings, Warning{Line: line, Message: fmt.Sprintf("invalid word %s skipped", f)})
+				continue
+			}
+			r.decode(uint16(w))
+		}
+
+		// Update displayed item
+		r.update()
+
+		// Hook erased items
+		var erased = o.Items
+		if r.item != nil {
+			erased = erased[:len(erased)-1]
+		}
+		if err = hk.hook(erased); err != nil {
+			return
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "astisub: scanning failed")
+		return
+	}
+
+	// Caption is still displayed
+	if r.item != nil {
+		o.Warnings = append(o.Warnings, Warning{Message: "last caption is never erased"})
+		r.endItem()
+	}
+
+	// Hook last item
+	err = hk.hook(o.Items)
+	return
+}
+
+// parseDurationSCC parses an SCC timecode. Frames are counted at 29.97 fps and drop-frame timecodes, which use ";"
+// as frames separator, skip frame numbers so that they stay close to the clock time.
+func parseDurationSCC(i string) (d time.Duration, err error) {
+	var t Timecode
+	if t, err = ParseTimecode(i, 29.97); err != nil {
+		err = errors.Wrap(err, "astisub: parsing timecode failed")
+		return
+	}
+	d = t.Duration()
+	return
+}
+
+// decode decodes a byte pair
+func (r *sccReader) decode(w uint16) {
+	// Remove parity bits
+	var b1, b2 = byte(w>>8) & 0x7f, byte(w) & 0x7f
+
+	// Padding
+	if b1 == 0 && b2 == 0 {
+		return
+	}
+
+	// Printable characters
+	if b1 >= 0x20 {
+		r.lastControl = 0
+		if r.channel == 1 {
+			r.writeBasicCharacter(b1)
+			r.writeBasicCharacter(b2)
+		}
+		return
+	}
+
+	// Control codes are usually sent twice
+	if b1 < 0x10 {
+		return
+	}
+	if r.lastControl == w {
+		r.lastControl = 0
+		return
+	}
+	r.lastControl = w
+
+	// Get channel
+	r.channel = 1
+	if b1 >= 0x18 {
+		r.channel = 2
+		b1 -= 0x08
+	}
+	if r.channel != 1 {
+		return
+	}
+
+	// Switch on code
+	switch {
+	case b2 >= 0x40:
+		r.preambleAddressCode(b1, b2)
+	case b1 == 0x11 && b2 >= 0x20 && b2 < 0x30:
+		// Mid-row code
+		r.style.underline = b2&0x1 > 0
+		if c := int(b2&0xe) >> 1; c < len(sccColors) {
+			r.style.color = c
+			r.style.italics = false
+		} else {
+			r.style.italics = true
+		}
+		r.write(' ')
+	case b1 == 0x11 && b2 >= 0x30:
+		r.write(sccSpecialCharacters[b2-0x30])
+	case (b1 == 0x12 || b1 == 0x13) && b2 >= 0x20:
+		// Extended characters replace the standard character preceding them
+		if r.column > 0 {
+			r.column--
+		}
+		r.write(sccExtendedCharacters[b1][b2-0x20])
+	case (b1 == 0x14 || b1 == 0x15) && b2 >= 0x20 && b2 < 0x30:
+		r.miscellaneousControlCode(b2)
+	case b1 == 0x17 && b2 >= 0x21 && b2 <= 0x23:
+		// Tab offset
+		r.column += int(b2 - 0x20)
+		if r.column >= sccColumns {
+			r.column = sccColumns - 1
+		}
+	}
+}
+
+// preambleAddressCode handles a preamble address code, which sets the cursor position and the style
+func (r *sccReader) preambleAddressCode(b1, b2 byte) {
+	// Set row
+	var idx int
+	if b2 >= 0x60 {
+		idx = 1
+	}
+	r.row = sccPACRows[b1][idx] - 1
+	r.column = 0
+
+	// Set style
+	var a = b2 & 0x1f
+	r.style = sccStyle{underline: a&0x1 > 0}
+	if a >= 0x10 {
+		r.column = int((a&0xe)>>1) * 4
+	} else if c := int(a) >> 1; c < len(sccColors) {
+		r.style.color = c
+	} else {
+		r.style.italics = true
+	}
+}
+
+// miscellaneousControlCode handles a miscellaneous control code
+func (r *sccReader) miscellaneousControlCode(b2 byte) {
+	switch b2 {
+	case 0x20:
+		// Resume caption loading
+		r.mode = sccModePopOn
+		r.textMode = false
+	case 0x21:
+		// Backspace
+		if r.column > 0 {
+			r.column--
+			r.memory()[r.row][r.column] = sccCell{}
+		}
+	case 0x24:
+		// Delete to end of row
+		for c := r.column; c < sccColumns; c++ {
+			r.memory()[r.row][c] = sccCell{}
+		}
+	case 0x25, 0x26, 0x27:
+		// Roll-up captions
+		if r.mode != sccModeRollUp {
+			r.displayed = sccMemory{}
+			r.nonDisplayed = sccMemory{}
+		}
+		r.mode = sccModeRollUp
+		r.rollUpRows = int(b2-0x25) + 2
+		r.textMode = false
+	case 0x29:
+		// Resume direct captioning
+		r.mode = sccModePaintOn
+		r.textMode = false
+	case 0x2a, 0x2b:
+		// Text restart and resume text display
+		r.textMode = true
+	case 0x2c:
+		// Erase displayed memory
+		r.displayed = sccMemory{}
+	case 0x2d:
+		// Carriage return
+		if r.mode == sccModeRollUp {
+			var top = r.row - r.rollUpRows + 1
+			if top < 0 {
+				top = 0
+			}
+			for row := top; row < r.row; row++ {
+				r.displayed[row] = r.displayed[row+1]
+			}
+			r.displayed[r.row] = [sccColumns]sccCell{}
+		}
+		r.column = 0
+	case 0x2e:
+		// Erase non-displayed memory
+		r.nonDisplayed = sccMemory{}
+	case 0x2f:
+		// End of caption
+		r.displayed, r.nonDisplayed = r.nonDisplayed, r.displayed
+		r.mode = sccModePopOn
+	}
+}
+
+// memory returns the memory characters are written to
+func (r *sccReader) memory() *sccMemory {
+	if r.mode == sccModePopOn {
+		return &r.nonDisplayed
+	}
+	return &r.displayed
+}
+
+// writeBasicCharacter writes a character of the basic character set
+func (r *sccReader) writeBasicCharacter(b byte) {
+	// Invalid character
+	if b < 0x20 {
+		return
+	}
+
+	// Write
+	if v, ok := sccBasicCharacters[b]; ok {
+		r.write(v)
+	} else {
+		r.write(rune(b))
+	}
+}
+
+// write writes a character at the cursor position
+func (r *sccReader) write(c rune) {
+	// Text mode characters are not captions
+	if r.textMode {
+		return
+	}
+
+	// Write
+	r.memory()[r.row][r.column] = sccCell{r: c, style: r.style}
+	if r.column < sccColumns-1 {
+		r.column++
+	}
+}
+
+// update ends the item being displayed and starts a new one if the displayed memory has changed
+func (r *sccReader) update() {
+	// Nothing changed
+	if r.displayed == r.shown {
+		return
+	}
+	r.shown = r.displayed
+
+	// End item
+	var previous = r.item
+	if r.item != nil {
+		r.endItem()
+		r.item = nil
+	}
+
+	// Start item
+	if !r.displayed.isEmpty() {
+		r.item = r.displayed.item()
+		r.item.StartAt = r.time
+		switch r.mode {
+		case sccModePaintOn:
+			r.item.DisplayMode = DisplayModePaintOn
+		case sccModeRollUp:
+			r.item.DisplayMode = DisplayModeRollUp
+			r.item.RollUpRows = r.rollUpRows
+		}
+		r.timedLines = r.mode != sccModePopOn
+		if r.timedLines {
+			r.timeLines(previous)
+		}
+		r.o.Items = append(r.o.Items, r.item)
+	}
+}
+
+// endItem ends the item being displayed
+func (r *sccReader) endItem() {
+	r.item.EndAt = r.time
+	if r.timedLines {
+		for idx := range r.item.Lines {
+			r.item.Lines[idx].EndAt = r.time
+		}
+	}
+}
+
+// timeLines sets the start time of the lines of the item being displayed when captions build up, as roll-up and
+// paint-on captions do, so that lines keep the time they first appeared at. Lines are matched with the lines of the
+// previous item they extend.
+func (r *sccReader) timeLines(previous *Item) {
+	var used = make(map[int]bool)
+	for idx := range r.item.Lines {
+		// Line is new
+		var l = &r.item.Lines[idx]
+		l.StartAt = r.time
+		if previous == nil {
+			continue
+		}
+
+		// Loop through previous lines
+		var t = l.String()
+		for idxPrevious, p := range previous.Lines {
+			if !used[idxPrevious] && strings.HasPrefix(t, p.String()) {
+				used[idxPrevious] = true
+				l.StartAt = previous.StartAt
+				if p.isTimed() {
+					l.StartAt = p.StartAt
+				}
+				break
+			}
+		}
+	}
+}
+
+// item converts the memory into an item
+func (m sccMemory) item() (i *Item) {
+	// Loop through rows
+	i = &Item{}
+	var firstRow, firstColumn = -1, sccColumns
+	for idxRow, row := range m {
+		// Loop through cells
+		var l Line
+		var text []rune
+		var style sccStyle
+		var flush = func() {
+			if t := strings.Join(strings.Fields(string(text)), " "); len(t) > 0 {
+				l.Items = append(l.Items, LineItem{InlineStyle: style.styleAttributes(), Text: t})
+			}
+			text = nil
+		}
+		for idxColumn, c := range row {
+			// Empty cell
+			if c.r == 0 {
+				text = append(text, ' ')
+				continue
+			}
+
+			// Update first column
+			if idxColumn < firstColumn {
+				firstColumn = idxColumn
+			}
+
+			// Style has changed
+			if c.style != style && len(strings.TrimSpace(string(text))) > 0 {
+				flush()
+			}
+			style = c.style
+			text = append(text, c.r)
+		}
+		flush()
+
+		// Add line
+		if len(l.Items) > 0 {
+			if firstRow < 0 {
+				firstRow = idxRow
+			}
+			i.Lines = append(i.Lines, l)
+		}
+	}
+
+	// Add position
+	if firstRow >= 0 {
+		i.InlineStyle = &StyleAttributes{
+			SCCColumn: astiptr.Int(firstColumn),
+			SCCRow:    astiptr.Int(firstRow + 1),
+		}
+		i.InlineStyle.propagateSCCAttributes()
+	}
+	return
+}
+
+// styleAttributes returns the style attributes of the style
+func (s sccStyle) styleAttributes() (o *StyleAttributes) {
+	if s == (sccStyle{}) {
+		return
+	}
+	o = &StyleAttributes{}
+	if s.color > 0 {
+		o.SCCColor = sccColors[s.color]
+	}
+	if s.italics {
+		o.SCCItalics = astiptr.Bool(true)
+	}
+	if s.underline {
+		o.SCCUnderline = astiptr.Bool(true)
+	}
+	o.propagateSCCAttributes()
+	return
+}
+
+// sccRepresentable checks whether a character can be sent
+func sccRepresentable(r rune) bool {
+	if _, ok := sccBasicCodes[r]; ok {
+		return true
+	}
+	_, ok := sccControlCodes[r]
+	return ok
+}
+
+// formatDurationSCC formats a duration as a drop-frame SCC timecode
+func formatDurationSCC(d time.Duration) string {
+	var t = NewTimecode(d, 29.97)
+	t.DropFrame = true
+	return t.String()
+}
+
+// sccEncoder encodes captions as CEA-608 byte pairs sent on the first caption channel (CC1)
+type sccEncoder struct {
+	characters []byte // Basic characters waiting to be paired
+	words      []uint16
+}
+
+// sccWord returns the byte pair with odd parity bits
+func sccWord(b1, b2 byte) uint16 {
+	var parity = func(b byte) byte {
+		if bits.OnesCount8(b)%2 == 0 {
+			return b | 0x80
+		}
+		return b
+	}
+	return uint16(parity(b1))<<8 | uint16(parity(b2))
+}
+
+// control adds a control code, sent twice
+func (e *sccEncoder) control(b1, b2 byte) {
+	e.flush()
+	var w = sccWord(b1, b2)
+	e.words = append(e.words, w, w)
+}
+
+// character adds a basic character
+func (e *sccEncoder) character(b byte) {
+	if e.characters = append(e.characters, b); len(e.characters) == 2 {
+		e.flush()
+	}
+}
+
+// flush adds basic characters waiting to be paired, padding them if needed
+func (e *sccEncoder) flush() {
+	if len(e.characters) == 0 {
+		return
+	}
+	if len(e.characters) == 1 {
+		e.characters = append(e.characters, 0)
+	}
+	e.words = append(e.words, sccWord(e.characters[0], e.characters[1]))
+	e.characters = nil
+}
+
+// write adds a character. Extended characters replace the standard character preceding them, which is sent as
+// well for decoders not supporting them.
+func (e *sccEncoder) write(r rune) {
+	if b, ok := sccBasicCodes[r]; ok {
+		e.character(b)
+	} else if c, ok := sccControlCodes[r]; ok {
+		if c[0] != 0x11 {
+			var fallback = byte(' ')
+			if t, ok := transliterateGlyph(r, func(r rune) bool { _, ok := sccBasicCodes[r]; return ok }); ok && len([]rune(t)) == 1 {
+				fallback = sccBasicCodes[[]rune(t)[0]]
+			}
+			e.character(fallback)
+		}
+		e.control(c[0], c[1])
+	}
+}
+
+// sccPreambleAddressCode returns the preamble address code placing the cursor at the beginning of a row, in white
+func sccPreambleAddressCode(row int) (b1, b2 byte) {
+	for b, rows := range sccPACRows {
+		if rows[0] == row {
+			return b, 0x40
+		} else if rows[1] == row {
+			return b, 0x60
+		}
+	}
+	return
+}
+
+// sccPopOnWords returns the byte pairs displaying an item as a pop-on caption on the bottom rows. Characters that
+// can't be sent are transliterated, and lines are truncated to the number of columns.
+func sccPopOnWords(i *Item) []uint16 {
+	// Resume caption loading and erase non-displayed memory
+	var e = &sccEncoder{}
+	e.control(0x14, 0x20)
+	e.control(0x14, 0x2e)
+
+	// Only the last rows that can be displayed are kept
+	var lines = i.Lines
+	if len(lines) > sccRows {
+		lines = lines[len(lines)-sccRows:]
+	}
+
+	// Loop through lines
+	for idx, l := range lines {
+		// Place cursor
+		e.control(sccPreambleAddressCode(sccRows - len(lines) + idx + 1))
+
+		// Get characters
+		var rs []rune
+		for _, r := range l.String() {
+			if sccRepresentable(r) {
+				rs = append(rs, r)
+			} else if t, ok := transliterateGlyph(r, sccRepresentable); ok {
+				rs = append(rs, []rune(t)...)
+			} else {
+				rs = append(rs, []rune(defaultGlyphPlaceholder)...)
+			}
+		}
+		if len(rs) > sccColumns {
+			rs = rs[:sccColumns]
+		}
+
+		// Write characters
+		for _, r := range rs {
+			e.write(r)
+		}
+	}
+
+	// End of caption
+	e.control(0x14, 0x2f)
+	return e.words
+}
+
+// sccLine returns an SCC line sending byte pairs at a given time
+func sccLine(d time.Duration, words []uint16) string {
+	var ws = make([]string, 0, len(words))
+	for _, w := range words {
+		ws = append(ws, fmt.Sprintf("%.4x", w))
+	}
+	return formatDurationSCC(d) + "\t" + strings.Join(ws, " ") + "\n\n"
+}
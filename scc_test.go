@@ -0,0 +1,63 @@
+package astisub_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSCC(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.scc")
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.TrackKindCaptions, s.Metadata.Kind)
+	assert.Empty(t, s.Warnings)
+	assert.Len(t, s.Items, 4)
+
+	// Pop-on
+	assert.Equal(t, 1001*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 3003*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, "Hello world ♪", s.Items[0].String())
+	assert.Equal(t, astiptr.Int(15), s.Items[0].InlineStyle.SCCRow)
+	assert.Equal(t, astiptr.Int(4), s.Items[0].InlineStyle.SCCColumn)
+	assert.Equal(t, "84%", s.Items[0].InlineStyle.WebVTTLine)
+	assert.Equal(t, 3003*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 5005*time.Millisecond, s.Items[1].EndAt)
+	assert.Len(t, s.Items[1].Lines, 2)
+	assert.Equal(t, "Line one", s.Items[1].Lines[0].String())
+	assert.Equal(t, []astisub.LineItem{
+		{Text: "Ça va"},
+		{InlineStyle: &astisub.StyleAttributes{SCCColor: astisub.ColorYellow}, Text: "yes"},
+	}, s.Items[1].Lines[1].Items)
+	assert.Equal(t, astiptr.Int(14), s.Items[1].InlineStyle.SCCRow)
+
+	// Roll-up
+	assert.Equal(t, 6006*time.Millisecond, s.Items[2].StartAt)
+	assert.Equal(t, 7007*time.Millisecond, s.Items[2].EndAt)
+	assert.Equal(t, "Roll", s.Items[2].String())
+	assert.Equal(t, 7007*time.Millisecond, s.Items[3].StartAt)
+	assert.Equal(t, 8008*time.Millisecond, s.Items[3].EndAt)
+	assert.Equal(t, "Roll - up", s.Items[3].String())
+	assert.Equal(t, astisub.Line{EndAt: 7007 * time.Millisecond, Items: []astisub.LineItem{{Text: "Roll"}}, StartAt: 6006 * time.Millisecond}, s.Items[2].Lines[0])
+	assert.Equal(t, 6006*time.Millisecond, s.Items[3].Lines[0].StartAt)
+	assert.Equal(t, 8008*time.Millisecond, s.Items[3].Lines[0].EndAt)
+	assert.Equal(t, 7007*time.Millisecond, s.Items[3].Lines[1].StartAt)
+	assert.Equal(t, 8008*time.Millisecond, s.Items[3].Lines[1].EndAt)
+	assert.Equal(t, time.Duration(0), s.Items[0].Lines[0].EndAt)
+
+	// Non-drop-frame timecodes
+	s, err = astisub.ReadFromSCC(strings.NewReader("Scenarist_SCC V1.0\n\n00:00:10:00\t9420 9420 94d0 94d0 c8e9 942f 942f\n\n00:00:20:00\t942c 942c zz\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 10010*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 20020*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, "Hi", s.Items[0].String())
+	assert.Equal(t, []astisub.Warning{{Line: 5, Message: "invalid word zz skipped"}}, s.Warnings)
+
+	// Invalid header
+	_, err = astisub.ReadFromSCC(strings.NewReader("WEBVTT\n"))
+	assert.EqualError(t, err, "astisub: invalid scc header WEBVTT")
+}
@@ -0,0 +1,89 @@
+package astisub
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Scenarist BD text script
+// Each event is written on a single line as "<number>\t<in>\t<out>\t<text>" where timecodes are HH:MM:SS:FF and
+// lines are separated by "|"
+
+// Default Scenarist framerate
+const scenaristDefaultFramerate = 25
+
+// formatDurationScenarist formats a Scenarist duration
+func formatDurationScenarist(d time.Duration, framerate int) string {
+	return NewTimecode(d, float64(framerate)).String()
+}
+
+// ScenaristOptions represents Scenarist options
+type ScenaristOptions struct {
+	// Cue times are rounded to frame boundaries when set
+	FrameRounding FrameRounding
+	// Policy applied to times exceeding 23:59:59:FF, the maximum timecode of the format. Times are written as is by
+	// default.
+	HourPolicy HourPolicy
+	// Items exceeding limits are wrapped and split instead of being written as is. See Target for the limits of
+	// delivery targets.
+	Limits Limits
+}
+
+// WriteToScenarist writes subtitles in Scenarist BD text script format
+// The framerate is retrieved from the metadata and defaults to 25
+func (s Subtitles) WriteToScenarist(o io.Writer) error {
+	return s.WriteToScenaristWithOptions(o, ScenaristOptions{})
+}
+
+// WriteToScenaristWithOptions writes subtitles in Scenarist BD text script format with options
+func (s Subtitles) WriteToScenaristWithOptions(o io.Writer, opts ScenaristOptions) (err error) {
+	// Do not write anything if no subtitles
+	if len(s.Items) == 0 {
+		err = ErrNoSubtitlesToWrite
+		return
+	}
+
+	// Get framerate
+	var framerate = scenaristDefaultFramerate
+	if s.Metadata != nil && s.Metadata.Framerate > 0 {
+		framerate = s.Metadata.Framerate
+	}
+
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Apply limits
+	s.Items = limitItems(s.Items, opts.Limits)
+
+	// Apply hour policy
+	var bound, precision = timecodeBounds(framerate)
+	if s, err = s.applyHourPolicy(bound, precision, opts.HourPolicy); err != nil {
+		err = errors.Wrap(err, "astisub: applying hour policy failed")
+		return
+	}
+
+	// Loop through items
+	var c []byte
+	for idx, i := range s.Items {
+		// Get lines
+		var ls []string
+		for _, l := range i.Lines {
+			ls = append(ls, strings.Replace(l.String(), "|", "/", -1))
+		}
+
+		// Add event
+		c = append(c, []byte(fmt.Sprintf("%04d\t%s\t%s\t%s", idx+1, formatDurationScenarist(i.StartAt, framerate), formatDurationScenarist(i.EndAt, framerate), strings.Join(ls, "|")))...)
+		c = append(c, bytesLineSeparator...)
+	}
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
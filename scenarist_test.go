@@ -0,0 +1,28 @@
+package astisub_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScenarist(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.srt")
+	assert.NoError(t, err)
+
+	// No subtitles to write
+	w := &bytes.Buffer{}
+	err = astisub.Subtitles{}.WriteToScenarist(w)
+	assert.EqualError(t, err, astisub.ErrNoSubtitlesToWrite.Error())
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.scenarist.txt")
+	assert.NoError(t, err)
+	err = s.WriteToScenarist(w)
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+}
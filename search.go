@@ -0,0 +1,106 @@
+package astisub
+
+import (
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Vars
+var (
+	// Sound descriptions between brackets or parentheses and music notes, which are not part of the transcript
+	searchRegexpNonSpeech = regexp.MustCompile(itemKindPatternDescription + `|[♪♫]`)
+)
+
+// SearchMatch represents a match of a search
+type SearchMatch struct {
+	End   int // Offset in characters of the end of the match in the line, exclusive
+	Item  int // Index of the item
+	Line  int // Index of the line in the item
+	Start int // Offset in characters of the start of the match in the line
+	Text  string
+}
+
+// Search returns the matches of a query in the lines of the items. Matching is case sensitive.
+func (s Subtitles) Search(query string) []SearchMatch {
+	if len(query) == 0 {
+		return nil
+	}
+	return s.SearchRegexp(regexp.MustCompile(regexp.QuoteMeta(query)))
+}
+
+// SearchRegexp returns the matches of a regexp in the lines of the items. Lines are searched as returned by
+// Line.String(), which means line items are separated by a space.
+func (s Subtitles) SearchRegexp(r *regexp.Regexp) (ms []SearchMatch) {
+	for idxItem, i := range s.Items {
+		for idxLine, l := range i.Lines {
+			var t = l.String()
+			for _, idxs := range r.FindAllStringIndex(t, -1) {
+				// Empty matches are not relevant
+				if idxs[0] == idxs[1] {
+					continue
+				}
+
+				// Add match, converting byte offsets to character offsets
+				ms = append(ms, SearchMatch{
+					End:   utf8.RuneCountInString(t[:idxs[1]]),
+					Item:  idxItem,
+					Line:  idxLine,
+					Start: utf8.RuneCountInString(t[:idxs[0]]),
+					Text:  t[idxs[0]:idxs[1]],
+				})
+			}
+		}
+	}
+	return
+}
+
+// IndexableSegment represents a time-addressable chunk of plain text
+type IndexableSegment struct {
+	EndAt   time.Duration
+	StartAt time.Duration
+	Text    string
+}
+
+// IndexableSegments returns the transcript of the items as consecutive segments of plain text, for instance to feed
+// search engines. Items starting less than window after the start of a segment are added to it, a window of 0
+// creating a segment per item. Texts are normalized: dialogue dashes, sound descriptions and music notes are removed,
+// lines are joined and white spaces are collapsed. Items are expected to be ordered and items without text are
+// skipped.
+func (s Subtitles) IndexableSegments(window time.Duration) (ss []IndexableSegment) {
+	var g *IndexableSegment
+	for _, i := range s.Items {
+		// Get text
+		var t = indexableText(i)
+		if len(t) == 0 {
+			continue
+		}
+
+		// Add to the current segment
+		if g != nil && i.StartAt < g.StartAt+window {
+			g.Text += " " + t
+			if i.EndAt > g.EndAt {
+				g.EndAt = i.EndAt
+			}
+			continue
+		}
+
+		// Create segment
+		ss = append(ss, IndexableSegment{EndAt: i.EndAt, StartAt: i.StartAt, Text: t})
+		g = &ss[len(ss)-1]
+	}
+	return
+}
+
+// indexableText returns the normalized plain text of an item
+func indexableText(i *Item) string {
+	var ws []string
+	for _, l := range i.Lines {
+		var t = dialogueRegexpLineDash.ReplaceAllString(l.String(), "$1")
+		t = dialogueRegexpInlineDash.ReplaceAllString(t, "$1 $2")
+		t = searchRegexpNonSpeech.ReplaceAllString(t, " ")
+		ws = append(ws, strings.Fields(t)...)
+	}
+	return strings.Join(ws, " ")
+}
@@ -0,0 +1,58 @@
+package astisub_test
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Search(t *testing.T) {
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Déjà vu,"}, {Text: "vu"}}}}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Nothing"}}}, {Items: []astisub.LineItem{{Text: "Vu"}}}}},
+	}}
+
+	// Query
+	assert.Equal(t, []astisub.SearchMatch{
+		{End: 7, Item: 0, Line: 0, Start: 5, Text: "vu"},
+		{End: 11, Item: 0, Line: 0, Start: 9, Text: "vu"},
+	}, s.Search("vu"))
+	assert.Nil(t, s.Search(""))
+	assert.Nil(t, s.Search("absent"))
+
+	// Regexp
+	assert.Equal(t, []astisub.SearchMatch{
+		{End: 7, Item: 0, Line: 0, Start: 5, Text: "vu"},
+		{End: 11, Item: 0, Line: 0, Start: 9, Text: "vu"},
+		{End: 2, Item: 1, Line: 1, Start: 0, Text: "Vu"},
+	}, s.SearchRegexp(regexp.MustCompile("(?i)vu")))
+}
+
+func TestSubtitles_IndexableSegments(t *testing.T) {
+	var item = func(start, end int, lines ...string) *astisub.Item {
+		var i = &astisub.Item{EndAt: time.Duration(end) * time.Second, StartAt: time.Duration(start) * time.Second}
+		for _, l := range lines {
+			i.Lines = append(i.Lines, astisub.Line{Items: []astisub.LineItem{{Text: l}}})
+		}
+		return i
+	}
+	var s = astisub.Subtitles{Items: []*astisub.Item{
+		item(0, 2, "- Hello.", "-  How are  you?"),
+		item(3, 5, "[door slams]"),
+		item(4, 6, "Fine. - And you?"),
+		item(12, 14, "♪ La la la ♪"),
+		item(15, 17, "(laughing) Great!"),
+	}}
+
+	// Window
+	assert.Equal(t, []astisub.IndexableSegment{
+		{EndAt: 6 * time.Second, StartAt: 0, Text: "Hello. How are you? Fine. And you?"},
+		{EndAt: 17 * time.Second, StartAt: 12 * time.Second, Text: "La la la Great!"},
+	}, s.IndexableSegments(10*time.Second))
+
+	// No window
+	assert.Len(t, s.IndexableSegments(0), 4)
+}
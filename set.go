@@ -0,0 +1,131 @@
+package astisub
+
+import (
+	"sort"
+	"strconv"
+)
+
+// TrackKind represents the kind of a track, i.e. what its timed text is meant for
+type TrackKind string
+
+// Track kinds
+const (
+	TrackKindCaptions     TrackKind = "captions"
+	TrackKindChapters     TrackKind = "chapters"
+	TrackKindDescriptions TrackKind = "descriptions"
+	TrackKindForced       TrackKind = "forced"
+	TrackKindMetadata     TrackKind = "metadata"
+	TrackKindSDH          TrackKind = "sdh"
+	TrackKindSubtitles    TrackKind = "subtitles"
+)
+
+// Undetermined language, used in filenames when a track has no language
+const languageUndetermined = "und"
+
+// Track represents a subtitles track of a set
+type Track struct {
+	Kind      TrackKind
+	Language  string // An astisub language such as LanguageEnglish if known, the container's language code otherwise
+	Subtitles *Subtitles
+	Title     string
+}
+
+// Forced checks whether the track is a forced track
+func (t Track) Forced() bool {
+	return t.Kind == TrackKindForced
+}
+
+// SubtitleSet represents several subtitles tracks, such as the ones muxed in a container, keyed by language and kind
+type SubtitleSet struct {
+	Tracks []*Track
+}
+
+// NewSubtitleSet creates a new subtitle set
+func NewSubtitleSet() *SubtitleSet {
+	return &SubtitleSet{}
+}
+
+// Add adds a track to the set. If the track has no kind, it is taken from the subtitles metadata and defaults to
+// TrackKindSubtitles.
+func (s *SubtitleSet) Add(t *Track) {
+	if len(t.Kind) == 0 && t.Subtitles != nil && t.Subtitles.Metadata != nil {
+		t.Kind = t.Subtitles.Metadata.Kind
+	}
+	if len(t.Kind) == 0 {
+		t.Kind = TrackKindSubtitles
+	}
+	s.Tracks = append(s.Tracks, t)
+}
+
+// AddForcedTracks adds, for each track that is not forced but contains forced items, a forced track containing
+// only those items so that they can be written to a separate file
+func (s *SubtitleSet) AddForcedTracks() {
+	for _, t := range s.Tracks {
+		// Track is already forced
+		if t.Kind == TrackKindForced || t.Subtitles == nil || s.Track(t.Language, TrackKindForced) != nil {
+			continue
+		}
+
+		// Get forced items
+		var f = t.Subtitles.ForcedItems()
+		if len(f.Items) == 0 {
+			continue
+		}
+
+		// Add track
+		s.Add(&Track{
+			Kind:      TrackKindForced,
+			Language:  t.Language,
+			Subtitles: f,
+			Title:     t.Title,
+		})
+	}
+}
+
+// Track returns the first track matching a language and a kind
+func (s SubtitleSet) Track(language string, kind TrackKind) *Track {
+	for _, t := range s.Tracks {
+		if t.Language == language && t.Kind == kind {
+			return t
+		}
+	}
+	return nil
+}
+
+// Languages returns the sorted languages of the set
+func (s SubtitleSet) Languages() (ls []string) {
+	var m = make(map[string]bool)
+	for _, t := range s.Tracks {
+		if !m[t.Language] {
+			m[t.Language] = true
+			ls = append(ls, t.Language)
+		}
+	}
+	sort.Strings(ls)
+	return
+}
+
+// Filenames returns the filenames of the set's tracks for a format, in the same order as the tracks
+// Filenames look like "<language>.<ext>" for subtitles and "<language>.<kind>.<ext>" for other kinds. A counter is
+// added when several tracks share the same language and kind.
+func (s SubtitleSet) Filenames(f Format) (fs []string) {
+	var counts = make(map[string]int)
+	for _, t := range s.Tracks {
+		// Get base name
+		var n = t.Language
+		if len(n) == 0 {
+			n = languageUndetermined
+		}
+		if t.Kind != TrackKindSubtitles && len(t.Kind) > 0 {
+			n += "." + string(t.Kind)
+		}
+
+		// Add counter
+		counts[n]++
+		if counts[n] > 1 {
+			n += "." + strconv.Itoa(counts[n])
+		}
+		fs = append(fs, n+f.Extension())
+	}
+	return
+}
@@ -0,0 +1,66 @@
+package astisub_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitleSet(t *testing.T) {
+	// Init
+	var s = astisub.NewSubtitleSet()
+	s.Add(&astisub.Track{Language: astisub.LanguageFrench, Subtitles: mockSubtitles()})
+	s.Add(&astisub.Track{Kind: astisub.TrackKindForced, Language: astisub.LanguageEnglish, Subtitles: mockSubtitles()})
+	s.Add(&astisub.Track{Language: astisub.LanguageFrench, Subtitles: mockSubtitles(), Title: "Commentary"})
+	s.Add(&astisub.Track{Kind: astisub.TrackKindSDH, Subtitles: mockSubtitles()})
+
+	// Getters
+	assert.Equal(t, astisub.TrackKindSubtitles, s.Tracks[0].Kind)
+	assert.Equal(t, s.Tracks[1], s.Track(astisub.LanguageEnglish, astisub.TrackKindForced))
+	assert.Equal(t, s.Tracks[0], s.Track(astisub.LanguageFrench, astisub.TrackKindSubtitles))
+	assert.Nil(t, s.Track(astisub.LanguageEnglish, astisub.TrackKindSubtitles))
+	assert.Equal(t, []string{"", astisub.LanguageEnglish, astisub.LanguageFrench}, s.Languages())
+	assert.Equal(t, []string{"french.vtt", "english.forced.vtt", "french.2.vtt", "und.sdh.vtt"}, s.Filenames(astisub.FormatWebVTT))
+
+	// Write to dir
+	dir, err := ioutil.TempDir("", "astisub")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	err = s.WriteToDir(dir, astisub.FormatSRT)
+	assert.NoError(t, err)
+	o, err := astisub.OpenFile(filepath.Join(dir, "english.forced.srt"))
+	assert.NoError(t, err)
+	assert.Len(t, o.Items, 2)
+
+	// Kind is taken from the metadata
+	var c = mockSubtitles()
+	c.Metadata = &astisub.Metadata{Kind: astisub.TrackKindCaptions}
+	s.Add(&astisub.Track{Language: astisub.LanguageEnglish, Subtitles: c})
+	assert.Equal(t, astisub.TrackKindCaptions, s.Tracks[4].Kind)
+}
+
+func TestSubtitleSet_AddForcedTracks(t *testing.T) {
+	// Init
+	var s = astisub.NewSubtitleSet()
+	var fr = mockSubtitles()
+	fr.Items[1].Forced = true
+	s.Add(&astisub.Track{Language: astisub.LanguageFrench, Subtitles: fr})
+	s.Add(&astisub.Track{Language: astisub.LanguageEnglish, Subtitles: mockSubtitles()})
+
+	// Add forced tracks
+	s.AddForcedTracks()
+	assert.Len(t, s.Tracks, 3)
+	var f = s.Track(astisub.LanguageFrench, astisub.TrackKindForced)
+	assert.NotNil(t, f)
+	assert.True(t, f.Forced())
+	assert.False(t, s.Tracks[0].Forced())
+	assert.Equal(t, []*astisub.Item{fr.Items[1]}, f.Subtitles.Items)
+
+	// Forced tracks are only added once
+	s.AddForcedTracks()
+	assert.Len(t, s.Tracks, 3)
+}
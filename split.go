@@ -0,0 +1,185 @@
+package astisub
+
+import (
+	"math"
+	"time"
+)
+
+// splitPoint returns the index of the token starting the second part of an item whose text is split as close as
+// possible to a fraction of its characters, and the fraction of characters of the first part. Line boundaries are
+// preferred when they're as close. -1 is returned if the item can't be split.
+func splitPoint(ts []wrapToken, fraction float64) (k int, ratio float64) {
+	// Count characters, line boundaries counting as white spaces
+	var prefix = make([]int, len(ts)+1)
+	for idx, tk := range ts {
+		prefix[idx+1] = prefix[idx] + tk.width
+		if tk.space || (tk.first && idx > 0) {
+			prefix[idx+1]++
+		}
+	}
+	var total = float64(prefix[len(ts)])
+
+	// Loop through boundaries
+	k = -1
+	var best = math.Inf(1)
+	for idx := 1; idx < len(ts); idx++ {
+		// Lines can't be broken here
+		if ts[idx].noBreakBefore && !ts[idx].first {
+			continue
+		}
+
+		// Closer boundary
+		var d = math.Abs(float64(prefix[idx])/total - fraction)
+		if d < best || (d == best && ts[idx].first) {
+			k, best = idx, d
+			ratio = float64(prefix[idx]) / total
+		}
+	}
+	return
+}
+
+// splitItemAt splits an item into 2 copies of the item whose text is split as close as possible to a fraction of its
+// characters. b is nil if the item can't be split.
+func splitItemAt(i *Item, fraction float64) (a, b *Item, ratio float64) {
+	// Get split point
+	var ts = lineTokens(i.Lines, itemTokenizer(nil, i))
+	var k int
+	if k, ratio = splitPoint(ts, fraction); k < 0 {
+		return i, nil, 1
+	}
+
+	// Split
+	a, b = &Item{}, &Item{}
+	*a, *b = *i, *i
+	a.Lines = tokenLines(i.Lines, ts[:k])
+	b.Comments = nil
+	b.Lines = tokenLines(i.Lines, ts[k:])
+	return
+}
+
+// Split splits the item at a time into 2 copies of the item. Its text is split at the word boundary whose position
+// in the text is the closest to the position of the time in the item. b is nil if the time is not within the item or
+// if the item has less than 2 words, a being the item itself.
+func (i *Item) Split(at time.Duration) (a, b *Item) {
+	// Time is not within the item
+	if at <= i.StartAt || at >= i.EndAt {
+		return i, nil
+	}
+
+	// Split
+	if a, b, _ = splitItemAt(i, float64(at-i.StartAt)/float64(i.EndAt-i.StartAt)); b == nil {
+		return
+	}
+	a.EndAt = at
+	b.StartAt = at
+	return
+}
+
+// SplitLongItems splits items displayed for more than maxDuration into as few items as possible whose texts have
+// similar lengths and whose durations are proportional to their text lengths. Items are only split at word
+// boundaries, therefore items with too few words may still be displayed for more than maxDuration.
+func (s *Subtitles) SplitLongItems(maxDuration time.Duration) {
+	// Nothing to do
+	if maxDuration <= 0 {
+		return
+	}
+
+	// Loop through items
+	var is []*Item
+	for _, i := range s.Items {
+		// Loop through numbers of parts
+		var ps = []*Item{i}
+		for n := int(math.Ceil(float64(i.EndAt-i.StartAt) / float64(maxDuration))); n > 1; n++ {
+			// Split
+			ps = splitItem(i, n)
+
+			// Parts are short enough or can't be split anymore
+			if len(ps) < n || !itemsLongerThan(ps, maxDuration) {
+				break
+			}
+		}
+		is = append(is, ps...)
+	}
+	s.Items = is
+}
+
+// itemsLongerThan checks whether at least one item is displayed for more than d
+func itemsLongerThan(is []*Item, d time.Duration) bool {
+	for _, i := range is {
+		if i.EndAt-i.StartAt > d {
+			return true
+		}
+	}
+	return false
+}
+
+// splitItem splits an item into at most n parts whose durations are proportional to their text lengths
+func splitItem(i *Item, n int) (ps []*Item) {
+	var rest = i
+	for ; n > 1; n-- {
+		// Split
+		var a, b, ratio = splitItemAt(rest, 1/float64(n))
+		if b == nil {
+			break
+		}
+
+		// Update times
+		a.EndAt = rest.StartAt + time.Duration(math.Round(float64(rest.EndAt-rest.StartAt)*ratio))
+		b.StartAt = a.EndAt
+		ps = append(ps, a)
+		rest = b
+	}
+	return append(ps, rest)
+}
+
+// MergeShortItems merges items displayed for less than minDuration with the closest of their previous and next items,
+// provided the gap between them is at most maxGap. Merged items are displayed from the first start to the last end,
+// their lines being concatenated. Items are expected to be ordered.
+func (s *Subtitles) MergeShortItems(minDuration, maxGap time.Duration) {
+	for idx := 0; idx < len(s.Items); idx++ {
+		// Item is long enough
+		var i = s.Items[idx]
+		if i.EndAt-i.StartAt >= minDuration {
+			continue
+		}
+
+		// Get closest neighbour
+		var gapPrevious, gapNext = time.Duration(math.MaxInt64), time.Duration(math.MaxInt64)
+		if idx > 0 {
+			gapPrevious = i.StartAt - s.Items[idx-1].EndAt
+		}
+		if idx+1 < len(s.Items) {
+			gapNext = s.Items[idx+1].StartAt - i.EndAt
+		}
+		var idxMerged = idx
+		if gapNext <= gapPrevious && gapNext <= maxGap {
+			idxMerged = idx + 1
+		} else if gapPrevious <= maxGap {
+			idxMerged = idx - 1
+		}
+		if idxMerged == idx {
+			continue
+		}
+
+		// Merge
+		var a, b = s.Items[idx], s.Items[idxMerged]
+		if idxMerged < idx {
+			a, b = b, a
+		}
+		a.Comments = append(a.Comments, b.Comments...)
+		a.Lines = append(a.Lines, b.Lines...)
+		if b.EndAt > a.EndAt {
+			a.EndAt = b.EndAt
+		}
+		var idxRemoved = idx
+		if idxMerged > idx {
+			idxRemoved = idxMerged
+		}
+		s.Items = append(s.Items[:idxRemoved], s.Items[idxRemoved+1:]...)
+
+		// Check the merged item again
+		if idx = idxRemoved - 2; idx < -1 {
+			idx = -1
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItem_Split(t *testing.T) {
+	var italics = &astisub.StyleAttributes{SRTItalics: astiptr.Bool(true)}
+	i := &astisub.Item{
+		Comments: []string{"comment"},
+		EndAt:    4 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "One two"}, {InlineStyle: italics, Text: "three four"}}, VoiceName: "Anna"},
+			{Items: []astisub.LineItem{{Text: "five six"}}},
+		},
+		StartAt: 2 * time.Second,
+	}
+
+	// Outside the item
+	a, b := i.Split(time.Second)
+	assert.Equal(t, i, a)
+	assert.Nil(t, b)
+
+	// Within a line
+	a, b = i.Split(2500 * time.Millisecond)
+	assert.Equal(t, &astisub.Item{
+		Comments: []string{"comment"},
+		EndAt:    2500 * time.Millisecond,
+		Lines:    []astisub.Line{{Items: []astisub.LineItem{{Text: "One two"}}, VoiceName: "Anna"}},
+		StartAt:  2 * time.Second,
+	}, a)
+	assert.Equal(t, &astisub.Item{
+		EndAt: 4 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{InlineStyle: italics, Text: "three four"}}, VoiceName: "Anna"},
+			{Items: []astisub.LineItem{{Text: "five six"}}},
+		},
+		StartAt: 2500 * time.Millisecond,
+	}, b)
+	assert.Len(t, i.Lines, 2)
+
+	// Line boundary
+	a, b = i.Split(3500 * time.Millisecond)
+	assert.Equal(t, "One two three four", a.String())
+	assert.Equal(t, "five six", b.String())
+}
+
+func TestSubtitles_SplitLongItems(t *testing.T) {
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 10 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Short one."}}}, {Items: []astisub.LineItem{{Text: "And then a much longer one."}}}}, StartAt: 0},
+		{EndAt: 12 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Fine"}}}}, StartAt: 10 * time.Second},
+		{EndAt: 30 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Alone"}}}}, StartAt: 12 * time.Second},
+	}}
+	s.SplitLongItems(4 * time.Second)
+	var parts [][3]interface{}
+	for _, i := range s.Items {
+		parts = append(parts, [3]interface{}{i.StartAt, i.EndAt, i.String()})
+	}
+	assert.Equal(t, [][3]interface{}{
+		{time.Duration(0), 3684210526 * time.Nanosecond, "Short one. - And"},
+		{3684210526 * time.Nanosecond, 6704805492 * time.Nanosecond, "then a much"},
+		{6704805492 * time.Nanosecond, 10 * time.Second, "longer one."},
+		{10 * time.Second, 12 * time.Second, "Fine"},
+		{12 * time.Second, 30 * time.Second, "Alone"},
+	}, parts)
+}
+
+func TestSubtitles_MergeShortItems(t *testing.T) {
+	var item = func(start, end time.Duration, text string) *astisub.Item {
+		return &astisub.Item{EndAt: end * time.Millisecond, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: text}}}}, StartAt: start * time.Millisecond}
+	}
+	s := &astisub.Subtitles{Items: []*astisub.Item{
+		item(0, 2000, "1"),
+		item(2100, 2400, "2"),
+		item(2400, 2600, "3"),
+		item(5000, 5300, "4"),
+		item(6000, 8000, "5"),
+	}}
+	s.MergeShortItems(time.Second, 200*time.Millisecond)
+	var parts []string
+	for _, i := range s.Items {
+		parts = append(parts, i.String())
+	}
+	assert.Equal(t, []string{"1 - 2 - 3", "4", "5"}, parts)
+	assert.Equal(t, 2600*time.Millisecond, s.Items[0].EndAt)
+}
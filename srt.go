@@ -2,22 +2,31 @@ package astisub
 
 import (
 	"bufio"
+	"fmt"
 	"io"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/asticode/go-astitools/ptr"
 	"github.com/pkg/errors"
 )
 
 // Constants
 const (
-	srtTimeBoundariesSeparator = " --> "
+	srtTimeBoundariesSeparator        = " --> "
+	srtTimeBoundariesSeparatorTrimmed = "-->"
 )
 
 // Vars
 var (
 	bytesSRTTimeBoundariesSeparator = []byte(srtTimeBoundariesSeparator)
+	srtRegexpFirstCue               = regexp.MustCompile(`^\d+[ \t]*\r?\n\d+:\d{2}:\d{2}([,.]\d+)?[ \t]*-->`)
+	srtRegexpFontColor              = regexp.MustCompile(`(?i)\bcolor\s*=\s*["']?([^"'\s>]+)`)
+	srtRegexpTag                    = regexp.MustCompile(`(?i)<(/?)(b|i|u|font)(\s[^>]*)?>`)
 )
 
 // parseDurationSRT parses an .srt duration
@@ -25,63 +34,360 @@ func parseDurationSRT(i string) (time.Duration, error) {
 	return parseDuration(i, ",", 3)
 }
 
+// srtReader holds the state of an .srt parsing
+type srtReader struct {
+	blank    bool // Whether a blank line has been met since the last text line
+	index    string
+	indexes  map[string]bool
+	item     *Item
+	o        *Subtitles
+	skipping bool        // Whether text lines are ignored until the next time boundaries
+	tags     srtTagState // Formatting tags opened in the current cue, which may span several lines
+}
+
+func (r *srtReader) warn(line int, format string, args ...interface{}) {
+	r.o.Warnings = append(r.o.Warnings, Warning{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
 // ReadFromSRT parses an .srt content
+// Common defects of real-world files are recovered from, in which case warnings are added to the subtitles
 func ReadFromSRT(i io.Reader) (o *Subtitles, err error) {
+	return readFromSRT(i, nil)
+}
+
+// readFromSRT parses an .srt content, calling a hook for each item once it's complete
+func readFromSRT(i io.Reader, h ItemHook) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
 	var scanner = bufio.NewScanner(i)
 
-	// Scan
-	var line string
-	var s = &Item{}
+	// Fetch lines
+	var lines []string
 	for scanner.Scan() {
-		// Fetch line
-		line = scanner.Text()
+		// Remove BOMs
+		var line = strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, string(BytesBOM))
+		}
+		if strings.Contains(line, string(BytesBOM)) {
+			o.Warnings = append(o.Warnings, Warning{Line: len(lines) + 1, Message: "stray bom removed"})
+			line = strings.Replace(line, string(BytesBOM), "", -1)
+		}
+		lines = append(lines, line)
+	}
+	if err = scanner.Err(); err != nil {
+		err = errors.Wrap(err, "astisub: scanning failed")
+		return
+	}
+
+	// Loop through lines
+	var r = &srtReader{indexes: make(map[string]bool), o: o}
+	var hk = &itemHooker{h: h}
+	for idx, line := range lines {
+		// Line is blank
+		var trimmed = strings.TrimSpace(line)
+		if len(trimmed) == 0 {
+			r.blank = true
+			continue
+		}
 
 		// Line contains time boundaries
-		if strings.Contains(line, srtTimeBoundariesSeparator) {
-			// Remove last item of previous subtitle since it's the index
-			s.Lines = s.Lines[:len(s.Lines)-1]
-
-			// Remove trailing empty lines
-			if len(s.Lines) > 0 {
-				for i := len(s.Lines) - 1; i >= 0; i-- {
-					if len(s.Lines[i].Items) > 0 {
-						for j := len(s.Lines[i].Items) - 1; j >= 0; j-- {
-							if len(s.Lines[i].Items[j].Text) == 0 {
-								s.Lines[i].Items = s.Lines[i].Items[:j]
-							} else {
-								break
-							}
-						}
-						if len(s.Lines[i].Items) == 0 {
-							s.Lines = s.Lines[:i]
-						}
+		if strings.Contains(line, srtTimeBoundariesSeparatorTrimmed) {
+			if err = hk.hook(o.Items); err != nil {
+				return
+			}
+			r.timeBoundaries(idx+1, line)
+			continue
+		}
 
-					}
+		// Line is an index since it's followed by time boundaries
+		if idx+1 < len(lines) && strings.Contains(lines[idx+1], srtTimeBoundariesSeparatorTrimmed) {
+			if _, errAtoi := strconv.Atoi(trimmed); errAtoi == nil {
+				if r.item != nil && !r.blank && !r.skipping {
+					r.warn(idx+1, "missing blank line before index %s", trimmed)
 				}
+				if r.indexes[trimmed] {
+					r.warn(idx+1, "duplicated index %s", trimmed)
+				}
+				r.index = trimmed
+				r.indexes[trimmed] = true
+				continue
+			} else if r.item == nil || r.blank || r.skipping {
+				r.warn(idx+1, "non-numeric index %s", trimmed)
+				continue
 			}
+			r.warn(idx+1, "missing index before time boundaries")
+		}
 
-			// Init subtitle
-			s = &Item{}
+		// Add text
+		r.text(idx+1, line)
+	}
 
-			// Fetch time boundaries
-			boundaries := strings.Split(line, srtTimeBoundariesSeparator)
-			if s.StartAt, err = parseDurationSRT(boundaries[0]); err != nil {
-				err = errors.Wrapf(err, "astisub: parsing srt duration %s failed", boundaries[0])
-				return
+	// Hook last item
+	err = hk.hook(o.Items)
+	return
+}
+
+// timeBoundaries parses an .srt time boundaries line and starts a new item
+func (r *srtReader) timeBoundaries(line int, text string) {
+	// Init
+	var id = r.index
+	r.blank = false
+	r.index = ""
+	r.item = nil
+	r.skipping = true
+
+	// Split time boundaries
+	var parts = strings.SplitN(text, srtTimeBoundariesSeparatorTrimmed, 2)
+	var fields = strings.Fields(parts[1])
+	if len(fields) == 0 {
+		r.warn(line, "missing end time, cue skipped")
+		return
+	}
+
+	// Parse time boundaries
+	var s = &Item{ID: id}
+	for _, v := range []struct {
+		d *time.Duration
+		t string
+	}{
+		{d: &s.StartAt, t: strings.TrimSpace(parts[0])},
+		{d: &s.EndAt, t: fields[0]},
+	} {
+		// Use "," as millisecond separator
+		if !strings.Contains(v.t, ",") && strings.Contains(v.t, ".") {
+			r.warn(line, "\".\" used as millisecond separator in %s", v.t)
+			v.t = strings.Replace(v.t, ".", ",", -1)
+		}
+
+		// Parse
+		var err error
+		if *v.d, err = parseDurationSRT(v.t); err != nil {
+			r.warn(line, "parsing duration %s failed, cue skipped: %s", v.t, err)
+			return
+		}
+	}
+
+	// Parse coordinates
+	if len(fields) > 1 {
+		var p = &Position{}
+		var ok = true
+		for _, f := range fields[1:] {
+			if !parseSRTCoordinate(f, p) {
+				r.warn(line, "invalid coordinate %s ignored", f)
+				ok = false
 			}
-			if s.EndAt, err = parseDurationSRT(boundaries[1]); err != nil {
-				err = errors.Wrapf(err, "astisub: parsing srt duration %s failed", boundaries[1])
-				return
+		}
+		if ok {
+			s.InlineStyle = &StyleAttributes{Position: p}
+		}
+	}
+
+	// Append item
+	r.item = s
+	r.o.Items = append(r.o.Items, s)
+	r.tags = srtTagState{}
+	r.skipping = false
+}
+
+// parseSRTCoordinate parses an .srt coordinate such as "X1:100" into a position
+func parseSRTCoordinate(i string, p *Position) bool {
+	// Split
+	var parts = strings.SplitN(i, ":", 2)
+	if len(parts) != 2 {
+		return false
+	}
+
+	// Parse value
+	v, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return false
+	}
+
+	// Update position
+	switch strings.ToUpper(parts[0]) {
+	case "X1":
+		p.X1 = v
+	case "X2":
+		p.X2 = v
+	case "Y1":
+		p.Y1 = v
+	case "Y2":
+		p.Y2 = v
+	default:
+		return false
+	}
+	return true
+}
+
+// text adds a text line to the current item
+func (r *srtReader) text(line int, text string) {
+	// Text is not part of a valid cue
+	if r.skipping {
+		return
+	} else if r.item == nil {
+		// Text located before the first cue is kept as a note
+		r.warn(line, "text outside of a cue kept as a comment")
+		if r.o.Metadata == nil {
+			r.o.Metadata = &Metadata{}
+		}
+		r.o.Metadata.Comments = append(r.o.Metadata.Comments, strings.TrimSpace(text))
+		return
+	}
+
+	// Blank line inside the cue
+	if r.blank {
+		r.warn(line, "blank line inside a cue")
+	}
+	r.blank = false
+
+	// Append line
+	if items := parseSRTLineItems(text, &r.tags); len(items) > 0 {
+		r.item.Lines = append(r.item.Lines, Line{Items: items})
+	}
+}
+
+// srtTagState represents the formatting tags opened at some point of a cue
+type srtTagState struct {
+	bold, italics, underline int
+	colors                   []*Color
+}
+
+// styleAttributes returns the style attributes of the state, nil if no formatting applies
+func (s srtTagState) styleAttributes() (sa *StyleAttributes) {
+	var color *Color
+	if len(s.colors) > 0 {
+		color = s.colors[len(s.colors)-1]
+	}
+	if s.bold == 0 && s.italics == 0 && s.underline == 0 && color == nil {
+		return
+	}
+	sa = &StyleAttributes{
+		SRTBold:      styleFlag(s.bold > 0),
+		SRTColor:     color,
+		SRTItalics:   styleFlag(s.italics > 0),
+		SRTUnderline: styleFlag(s.underline > 0),
+	}
+	return
+}
+
+// parseSRTLineItems splits a text line into line items wherever <b>, <i>, <u> or <font color> tags change the
+// formatting. Other tags are left untouched. The state holds the tags opened by previous lines of the cue and is
+// updated with the tags of the line.
+func parseSRTLineItems(text string, st *srtTagState) (items []LineItem) {
+	// No tags
+	var matches = srtRegexpTag.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return []LineItem{{InlineStyle: st.styleAttributes(), Text: text}}
+	}
+
+	// Loop through tags
+	var space = true // Whether the text preceding the tag ends with white space
+	var start int
+	for _, m := range append(matches, []int{len(text), len(text)}) {
+		// Add text preceding the tag. Text joined to the previous line item, such as a word split by a tag, is
+		// flagged so that no white space is added between them when writing.
+		var raw = text[start:m[0]]
+		if t := strings.TrimSpace(raw); len(t) > 0 {
+			var li = LineItem{InlineStyle: st.styleAttributes(), Text: t}
+			if r, _ := utf8.DecodeRuneInString(raw); len(items) > 0 && !space && !unicode.IsSpace(r) {
+				if li.InlineStyle == nil {
+					li.InlineStyle = &StyleAttributes{}
+				}
+				li.InlineStyle.SRTJoined = astiptr.Bool(true)
 			}
+			items = append(items, li)
+		}
+		if len(raw) > 0 {
+			var r, _ = utf8.DecodeLastRuneInString(raw)
+			space = unicode.IsSpace(r)
+		}
+		start = m[1]
+
+		// End of text
+		if len(m) == 2 {
+			break
+		}
 
-			// Append subtitle
-			o.Items = append(o.Items, s)
-		} else {
-			// Add text
-			s.Lines = append(s.Lines, Line{Items: []LineItem{{Text: line}}})
+		// Update state
+		var closing = m[3] > m[2]
+		var delta = 1
+		if closing {
+			delta = -1
 		}
+		switch strings.ToLower(text[m[4]:m[5]]) {
+		case "b":
+			st.bold = srtTagCount(st.bold, delta)
+		case "i":
+			st.italics = srtTagCount(st.italics, delta)
+		case "u":
+			st.underline = srtTagCount(st.underline, delta)
+		case "font":
+			if closing {
+				if len(st.colors) > 0 {
+					st.colors = st.colors[:len(st.colors)-1]
+				}
+			} else {
+				var c *Color
+				if m[6] >= 0 {
+					if cm := srtRegexpFontColor.FindStringSubmatch(text[m[6]:m[7]]); len(cm) > 1 {
+						c = ttmlColor(cm[1])
+					}
+				}
+				st.colors = append(st.colors, c)
+			}
+		}
+	}
+	return
+}
+
+// srtTagCount returns the number of opened tags once a tag is opened or closed, ignoring unbalanced closing tags
+func srtTagCount(count, delta int) int {
+	if count+delta < 0 {
+		return 0
+	}
+	return count + delta
+}
+
+// srtLine returns the text of a line where styled line items are wrapped in tags
+func srtLine(l Line, item *Item) string {
+	var o string
+	for idx, li := range l.Items {
+		if idx > 0 && (li.InlineStyle == nil || li.InlineStyle.SRTJoined == nil || !*li.InlineStyle.SRTJoined) {
+			o += " "
+		}
+		o += srtStyledText(li, item)
+	}
+	return o
+}
+
+// srtStyledText returns the text of a line item wrapped in the tags of its bold, italic, underline and color
+// attributes, line item attributes taking precedence over item attributes
+func srtStyledText(li LineItem, item *Item) (t string) {
+	// Merge attributes
+	var sa = li.InlineStyle
+	if li.Style != nil {
+		sa = mergeStyleAttributes(sa, li.Style.InlineStyle)
+	}
+	sa = mergeStyleAttributes(mergeStyleAttributes(sa, item.InlineStyle), ssaItemStyleAttributes(item))
+	t = li.Text
+	if sa == nil || len(t) == 0 {
+		return
+	}
+
+	// Add tags
+	var g = sa.GenericAttributes(FormatSRT)
+	if g.Underline != nil && *g.Underline {
+		t = "<u>" + t + "</u>"
+	}
+	if g.Italics != nil && *g.Italics {
+		t = "<i>" + t + "</i>"
+	}
+	if g.Bold != nil && *g.Bold {
+		t = "<b>" + t + "</b>"
+	}
+	if g.Color != nil && (g.Color.Red != ColorWhite.Red || g.Color.Green != ColorWhite.Green || g.Color.Blue != ColorWhite.Blue) {
+		t = fmt.Sprintf("<font color=\"#%.2x%.2x%.2x\">%s</font>", g.Color.Red, g.Color.Green, g.Color.Blue, t)
 	}
 	return
 }
@@ -91,31 +397,100 @@ func formatDurationSRT(i time.Duration) string {
 	return formatDuration(i, ",", 3)
 }
 
+// SRTOptions represents SRT write options
+type SRTOptions struct {
+	// If true, metadata comments are written as notes before the first cue. Most players skip them, but they make the
+	// file invalid, which is why they're not written by default.
+	Comments bool
+	// If true, the position of items is written after their time boundaries using the "X1:100 X2:600 Y1:400 Y2:460"
+	// extension found in DVD-ripped files
+	Coordinates bool
+	// Cue times are rounded to frame boundaries when set
+	FrameRounding FrameRounding
+	// Items exceeding limits are wrapped and split instead of being written as is
+	Limits Limits
+	// If true, items are numbered sequentially. Otherwise item IDs are used as indexes, unless some items have no ID
+	// or IDs are not strictly increasing integers.
+	Renumber bool
+	// Texts containing "-->", control characters or blank lines are sanitized unless set to SanitizePolicyKeep. SRT
+	// having no escaping mechanism, SanitizePolicyEscape behaves like SanitizePolicyStrip.
+	Sanitize SanitizePolicy
+}
+
+// srtValidIDs checks whether IDs are strictly increasing positive integers
+func srtValidIDs(ids []string) bool {
+	var previous int
+	for _, id := range ids {
+		v, err := strconv.Atoi(id)
+		if err != nil || v <= previous {
+			return false
+		}
+		previous = v
+	}
+	return true
+}
+
 // WriteToSRT writes subtitles in .srt format
-func (s Subtitles) WriteToSRT(o io.Writer) (err error) {
+func (s Subtitles) WriteToSRT(o io.Writer) error {
+	return s.WriteToSRTWithOptions(o, SRTOptions{})
+}
+
+// WriteToSRTWithOptions writes subtitles in .srt format with options
+func (s Subtitles) WriteToSRTWithOptions(o io.Writer, opts SRTOptions) error {
+	return s.writeToSRT(o, opts, nil)
+}
+
+// writeToSRT writes subtitles in .srt format with options, calling a hook for each item right before it's written
+func (s Subtitles) writeToSRT(o io.Writer, opts SRTOptions, h ItemHook) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
 		return
 	}
 
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Sanitize
+	s = s.sanitize(FormatSRT, opts.Sanitize)
+
 	// Add BOM header
 	var c []byte
 	c = append(c, BytesBOM...)
 
+	// Add notes
+	if opts.Comments && s.Metadata != nil && len(s.Metadata.Comments) > 0 {
+		for _, comment := range s.Metadata.Comments {
+			c = append(c, []byte(comment)...)
+			c = append(c, bytesLineSeparator...)
+		}
+		c = append(c, bytesLineSeparator...)
+	}
+
 	// Loop through subtitles
-	for k, v := range s.Items {
+	var is = limitItems(s.Items, opts.Limits)
+	var ids = itemIDs(is, opts.Renumber, srtValidIDs)
+	for k, v := range is {
+		// Hook
+		if v, err = hookedItem(v, k, h); err != nil {
+			return
+		}
+
 		// Add time boundaries
-		c = append(c, []byte(strconv.Itoa(k+1))...)
+		c = append(c, []byte(ids[k])...)
 		c = append(c, bytesLineSeparator...)
 		c = append(c, []byte(formatDurationSRT(v.StartAt))...)
 		c = append(c, bytesSRTTimeBoundariesSeparator...)
 		c = append(c, []byte(formatDurationSRT(v.EndAt))...)
+		if opts.Coordinates && v.InlineStyle != nil && v.InlineStyle.Position != nil {
+			var p = v.InlineStyle.Position
+			c = append(c, []byte(fmt.Sprintf(" X1:%d X2:%d Y1:%d Y2:%d", p.X1, p.X2, p.Y1, p.Y2))...)
+		}
 		c = append(c, bytesLineSeparator...)
 
 		// Loop through lines
 		for _, l := range v.Lines {
-			c = append(c, []byte(l.String())...)
+			c = append(c, []byte(srtLine(l, v))...)
 			c = append(c, bytesLineSeparator...)
 		}
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io/ioutil"
 	"testing"
+	"time"
 
 	"github.com/asticode/go-astisub"
 	"github.com/stretchr/testify/assert"
@@ -27,3 +28,141 @@ func TestSRT(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(c), w.String())
 }
+
+func TestSRTDefects(t *testing.T) {
+	s, err := astisub.ReadFromSRT(bytes.NewReader([]byte("\ufeff1\n00:00:01.000 --> 00:00:02,000\nmissing blank line\n2\n00:00:03,000 --> 00:00:04,000\n\ufeffstray bom\n\n2\n00:00:05,000 --> 00:00:06,000\nduplicated index\n\na\n00:00:07,000 --> invalid\nmalformed\n\n3\n100:00:00,000 --> 100:00:01,000\nhours\n\nafter blank line\n")))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 4)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, "missing blank line", s.Items[0].String())
+	assert.Equal(t, "stray bom", s.Items[1].String())
+	assert.Equal(t, "duplicated index", s.Items[2].String())
+	assert.Equal(t, 100*time.Hour, s.Items[3].StartAt)
+	assert.Equal(t, "hours - after blank line", s.Items[3].String())
+	var ws []string
+	for _, w := range s.Warnings {
+		ws = append(ws, w.String())
+	}
+	assert.Equal(t, []string{
+		"line 6: stray bom removed",
+		"line 2: \".\" used as millisecond separator in 00:00:01.000",
+		"line 4: missing blank line before index 2",
+		"line 8: duplicated index 2",
+		"line 12: non-numeric index a",
+		"line 13: parsing duration invalid failed, cue skipped: astisub: No hours, minutes or seconds detected in invalid",
+		"line 20: blank line inside a cue",
+	}, ws)
+}
+
+func TestSRTCoordinates(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000 X1:100 X2:600 Y1:400 Y2:460\ntext\n")))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, &astisub.Position{X1: 100, X2: 600, Y1: 400, Y2: 460}, s.Items[0].InlineStyle.Position)
+	assert.Empty(t, s.Warnings)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\ntext\n", w.String())
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{Coordinates: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000 X1:100 X2:600 Y1:400 Y2:460\ntext\n", w.String())
+}
+
+func TestSRTTags(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i> <B><font color=\"red\">big</font> world</b>\n<u>1 < 2</u>\n")))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, "Hello big world - 1 < 2", s.Items[0].String())
+	var lis = s.Items[0].Lines[0].Items
+	assert.Len(t, lis, 3)
+	assert.Equal(t, true, *lis[0].InlineStyle.SRTItalics)
+	assert.Nil(t, lis[0].InlineStyle.SRTBold)
+	assert.Equal(t, true, *lis[0].InlineStyle.GenericAttributes(astisub.FormatTTML).Italics)
+	assert.Equal(t, true, *lis[1].InlineStyle.SRTBold)
+	assert.Equal(t, astisub.ColorRed, lis[1].InlineStyle.SRTColor)
+	assert.Equal(t, astisub.ColorRed, lis[1].InlineStyle.GenericAttributes(astisub.FormatWebVTT).Color)
+	assert.Nil(t, lis[2].InlineStyle.SRTColor)
+	assert.Equal(t, true, *s.Items[0].Lines[1].Items[0].InlineStyle.SRTUnderline)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\n<i>Hello</i> <font color=\"#ff0000\"><b>big</b></font> <b>world</b>\n<u>1 < 2</u>\n", w.String())
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n<i>Hello</i> <c.red><b>big</b></c> <b>world</b>\n<u>1 < 2</u>\n")
+
+	// Tags joined to words
+	s, err = astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\nHel<b>lo</b> <i>world</i>!\n")))
+	assert.NoError(t, err)
+	w.Reset()
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\nHel<b>lo</b> <i>world</i>!\n", w.String())
+
+	// Tags spanning several lines of a cue
+	s, err = astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:02,000\n<i>Hello\nworld</i>\n\n2\n00:00:02,000 --> 00:00:03,000\n<b>Bye\n\n3\n00:00:03,000 --> 00:00:04,000\nplain\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, true, *s.Items[0].Lines[1].Items[0].InlineStyle.SRTItalics)
+	assert.Nil(t, s.Items[2].Lines[0].Items[0].InlineStyle)
+	w.Reset()
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "<i>Hello</i>\n<i>world</i>\n")
+
+	// Default white text is not wrapped in font tags
+	s, err = astisub.OpenFile("./testdata/example-in.ttml")
+	assert.NoError(t, err)
+	w.Reset()
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.NotContains(t, w.String(), "#ffffff")
+}
+
+func TestSRTLimits(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSRT(bytes.NewReader([]byte("1\n00:00:01,000 --> 00:00:04,000\none two three four five six\nseven eight\n\n2\n00:00:05,000 --> 00:00:06,000\nshort\n")))
+	assert.NoError(t, err)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{Limits: astisub.Limits{MaxCharactersPerLine: 14, MaxLines: 2}})
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:03,108\none two three\nfour five six\n\n2\n00:00:03,108 --> 00:00:04,000\nseven eight\n\n3\n00:00:05,000 --> 00:00:06,000\nshort\n", w.String())
+	assert.Len(t, s.Items, 2)
+}
+
+func TestSRTIDs(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSRT(bytes.NewReader([]byte("10\n00:00:01,000 --> 00:00:02,000\nfirst\n\n20\n00:00:03,000 --> 00:00:04,000\nsecond\n")))
+	assert.NoError(t, err)
+	assert.Equal(t, "10", s.Items[0].ID)
+	assert.Equal(t, "20", s.Items[1].ID)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff10\n00:00:01,000 --> 00:00:02,000\nfirst\n\n20\n00:00:03,000 --> 00:00:04,000\nsecond\n", w.String())
+
+	// Renumber
+	w.Reset()
+	err = s.WriteToSRTWithOptions(w, astisub.SRTOptions{Renumber: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\nfirst\n\n2\n00:00:03,000 --> 00:00:04,000\nsecond\n", w.String())
+
+	// IDs are not increasing
+	s.Items[1].ID = "5"
+	w.Reset()
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "\ufeff1\n00:00:01,000 --> 00:00:02,000\nfirst\n\n2\n00:00:03,000 --> 00:00:04,000\nsecond\n", w.String())
+}
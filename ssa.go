@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
 	"regexp"
 	"sort"
 	"strconv"
@@ -28,6 +29,64 @@ const (
 	ssaAlignmentTopTitle              = 4
 )
 
+// ssaAlignmentFromLegacy converts an SSA v4.00 alignment, where 1, 2 and 3 are bottom alignments to which 4 is added
+// for top alignments and 8 for middle alignments, to the numpad layout used by ASS scripts and by StyleAttributes.
+// Like VSFilter and libass, 4 and 8 are read as 11 and 3. nil is returned if the alignment is invalid.
+func ssaAlignmentFromLegacy(i *int) *int {
+	// Nothing to convert
+	if i == nil {
+		return nil
+	}
+
+	// VSFilter compatibility
+	var v = *i
+	switch v {
+	case ssaAlignmentTopTitle:
+		v = ssaAlignmentMidTitle + ssaAlignmentRight
+	case ssaAlignmentMidTitle:
+		v = ssaAlignmentRight
+	}
+
+	// Invalid
+	var h = v & 3
+	if v < 0 || v > ssaAlignmentMidTitle+ssaAlignmentRight || h == 0 {
+		return nil
+	}
+
+	// Convert
+	switch {
+	case v&ssaAlignmentTopTitle > 0:
+		return astiptr.Int(h + 6)
+	case v&ssaAlignmentMidTitle > 0:
+		return astiptr.Int(h + 3)
+	}
+	return astiptr.Int(h)
+}
+
+// ssaValidAlignment checks whether an alignment is a valid numpad alignment
+func ssaValidAlignment(i *int) bool {
+	return i != nil && *i >= 1 && *i <= 9
+}
+
+// ssaAlignmentToLegacy converts a numpad alignment to an SSA v4.00 alignment. nil is returned if the alignment is
+// invalid.
+func ssaAlignmentToLegacy(i *int) *int {
+	// Invalid
+	if !ssaValidAlignment(i) {
+		return nil
+	}
+
+	// Convert
+	var h = (*i-1)%3 + 1
+	switch (*i - 1) / 3 {
+	case 1:
+		return astiptr.Int(h + ssaAlignmentMidTitle)
+	case 2:
+		return astiptr.Int(h + ssaAlignmentTopTitle)
+	}
+	return astiptr.Int(h)
+}
+
 // SSA border styles
 const (
 	ssaBorderStyleOpaqueBox            = 3
@@ -67,21 +126,22 @@ const (
 
 // SSA script info names
 const (
-	ssaScriptInfoNameCollisions          = "Collisions"
-	ssaScriptInfoNameOriginalEditing     = "Original Editing"
-	ssaScriptInfoNameOriginalScript      = "Original Script"
-	ssaScriptInfoNameOriginalTiming      = "Original Timing"
-	ssaScriptInfoNameOriginalTranslation = "Original Translation"
-	ssaScriptInfoNamePlayDepth           = "PlayDepth"
-	ssaScriptInfoNamePlayResX            = "PlayResX"
-	ssaScriptInfoNamePlayResY            = "PlayResY"
-	ssaScriptInfoNameScriptType          = "ScriptType"
-	ssaScriptInfoNameScriptUpdatedBy     = "Script Updated By"
-	ssaScriptInfoNameSynchPoint          = "Synch Point"
-	ssaScriptInfoNameTimer               = "Timer"
-	ssaScriptInfoNameTitle               = "Title"
-	ssaScriptInfoNameUpdateDetails       = "Update Details"
-	ssaScriptInfoNameWrapStyle           = "WrapStyle"
+	ssaScriptInfoNameCollisions            = "Collisions"
+	ssaScriptInfoNameOriginalEditing       = "Original Editing"
+	ssaScriptInfoNameOriginalScript        = "Original Script"
+	ssaScriptInfoNameOriginalTiming        = "Original Timing"
+	ssaScriptInfoNameOriginalTranslation   = "Original Translation"
+	ssaScriptInfoNamePlayDepth             = "PlayDepth"
+	ssaScriptInfoNamePlayResX              = "PlayResX"
+	ssaScriptInfoNamePlayResY              = "PlayResY"
+	ssaScriptInfoNameScaledBorderAndShadow = "ScaledBorderAndShadow"
+	ssaScriptInfoNameScriptType            = "ScriptType"
+	ssaScriptInfoNameScriptUpdatedBy       = "Script Updated By"
+	ssaScriptInfoNameSynchPoint            = "Synch Point"
+	ssaScriptInfoNameTimer                 = "Timer"
+	ssaScriptInfoNameTitle                 = "Title"
+	ssaScriptInfoNameUpdateDetails         = "Update Details"
+	ssaScriptInfoNameWrapStyle             = "WrapStyle"
 )
 
 // SSA section names
@@ -92,6 +152,10 @@ const (
 	ssaSectionNameUnknown    = "unknown"
 )
 
+// SSA preserved key of script info lines that can't be modeled. Sections that can't be modeled are preserved with
+// their name as key.
+const ssaPreservedKeyScriptInfo = "Script Info"
+
 // SSA style format names
 const (
 	ssaStyleFormatNameAlignment       = "Alignment"
@@ -121,6 +185,61 @@ const (
 	ssaStyleFormatNameUnderline       = "Underline"
 )
 
+// Default resolution, used by renderers when PlayRes is not specified
+const (
+	ssaDefaultPlayResX = 384
+	ssaDefaultPlayResY = 288
+)
+
+// Libass defaults
+const (
+	ssaLibassDefaultPlayResX = 1920
+	ssaLibassDefaultPlayResY = 1080
+	ssaLibassDefaultStyleID  = "Default"
+	ssaLibassScriptType      = "v4.00+"
+)
+
+// Libass formats: every field is written so that nothing is left to the renderer's defaults
+var (
+	ssaLibassEventFormat = []string{
+		ssaEventFormatNameLayer,
+		ssaEventFormatNameStart,
+		ssaEventFormatNameEnd,
+		ssaEventFormatNameStyle,
+		ssaEventFormatNameName,
+		ssaEventFormatNameMarginL,
+		ssaEventFormatNameMarginR,
+		ssaEventFormatNameMarginV,
+		ssaEventFormatNameEffect,
+		ssaEventFormatNameText,
+	}
+	ssaLibassStyleFormat = []string{
+		ssaStyleFormatNameName,
+		ssaStyleFormatNameFontName,
+		ssaStyleFormatNameFontSize,
+		ssaStyleFormatNamePrimaryColour,
+		ssaStyleFormatNameSecondaryColour,
+		ssaStyleFormatNameOutlineColour,
+		ssaStyleFormatNameBackColour,
+		ssaStyleFormatNameBold,
+		ssaStyleFormatNameItalic,
+		ssaStyleFormatNameUnderline,
+		ssaStyleFormatNameStrikeout,
+		ssaStyleFormatNameScaleX,
+		ssaStyleFormatNameScaleY,
+		ssaStyleFormatNameSpacing,
+		ssaStyleFormatNameAngle,
+		ssaStyleFormatNameBorderStyle,
+		ssaStyleFormatNameOutline,
+		ssaStyleFormatNameShadow,
+		ssaStyleFormatNameAlignment,
+		ssaStyleFormatNameMarginL,
+		ssaStyleFormatNameMarginR,
+		ssaStyleFormatNameMarginV,
+		ssaStyleFormatNameEncoding,
+	}
+)
+
 // SSA wrap style
 const (
 	ssaWrapStyleEndOfLineWordWrapping                   = "1"
@@ -132,8 +251,16 @@ const (
 // SSA regexp
 var ssaRegexpEffect = regexp.MustCompile("\\{[^\\{]+\\}")
 
+// SSA fade regexp
+var ssaRegexpFade = regexp.MustCompile(`\\fade?\(([^\)]*)\)`)
+
 // ReadFromSSA parses an .ssa content
 func ReadFromSSA(i io.Reader) (o *Subtitles, err error) {
+	return readFromSSA(i, nil)
+}
+
+// readFromSSA parses an .ssa content, calling a hook for each item once it's complete
+func readFromSSA(i io.Reader, h ItemHook) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
 	var scanner = bufio.NewScanner(i)
@@ -142,7 +269,7 @@ func ReadFromSSA(i io.Reader) (o *Subtitles, err error) {
 	var es = []*ssaEvent{}
 
 	// Scan
-	var line, sectionName string
+	var line, sectionName, unknownSectionName string
 	var format map[int]string
 	for scanner.Scan() {
 		// Fetch line
@@ -165,16 +292,19 @@ func ReadFromSSA(i io.Reader) (o *Subtitles, err error) {
 				continue
 			case "v4 styles", "v4+ styles", "v4 styles+":
 				sectionName = ssaSectionNameStyles
+				si.v4PlusStyles = strings.Contains(line, "+")
 				format = make(map[int]string)
 				continue
 			default:
 				sectionName = ssaSectionNameUnknown
+				unknownSectionName = line[1 : len(line)-1]
 				continue
 			}
 		}
 
 		// Unknown section
 		if sectionName == ssaSectionNameUnknown {
+			addPreserved(&o.Preserved, FormatSSA, unknownSectionName, line)
 			continue
 		}
 
@@ -236,27 +366,48 @@ func ReadFromSSA(i io.Reader) (o *Subtitles, err error) {
 
 	// Set metadata
 	o.Metadata = si.metadata()
+	for _, v := range si.preserved {
+		addPreserved(&o.Preserved, FormatSSA, ssaPreservedKeyScriptInfo, v)
+	}
 
 	// Loop through styles
 	for _, s := range ss {
+		if si.legacyAlignment() {
+			s.alignment = ssaAlignmentFromLegacy(s.alignment)
+		}
 		var st = s.style()
 		o.Styles[st.ID] = st
 	}
 
 	// Loop through events
+	var comments []string
+	var hk = &itemHooker{h: h}
 	for _, e := range es {
-		// Only process dialogues
-		if e.category == ssaEventCategoryDialogue {
+		switch e.category {
+		case ssaEventCategoryComment:
+			// Comments are attached to the next dialogue
+			comments = append(comments, e.text)
+		case ssaEventCategoryDialogue:
 			// Build item
 			var item *Item
-			if item, err = e.item(o.Styles); err != nil {
+			if item, err = e.item(o.Styles, si); err != nil {
 				return
 			}
+			item.Comments = comments
+			comments = nil
 
 			// Append item
 			o.Items = append(o.Items, item)
+			if err = hk.hook(o.Items); err != nil {
+				return
+			}
 		}
 	}
+
+	// Comments located after the last dialogue are added to the metadata
+	if len(comments) > 0 {
+		o.Metadata.Comments = append(o.Metadata.Comments, comments...)
+	}
 	return
 }
 
@@ -284,21 +435,24 @@ func newSSAColorFromColor(i *Color) string {
 
 // ssaScriptInfo represents an SSA script info block
 type ssaScriptInfo struct {
-	collisions          string
-	comments            []string
-	originalEditing     string
-	originalScript      string
-	originalTiming      string
-	originalTranslation string
-	playDepth           *int
-	playResX, playResY  *int
-	scriptType          string
-	scriptUpdatedBy     string
-	synchPoint          string
-	timer               *float64
-	title               string
-	updateDetails       string
-	wrapStyle           string
+	collisions            string
+	comments              []string
+	originalEditing       string
+	originalScript        string
+	originalTiming        string
+	originalTranslation   string
+	playDepth             *int
+	playResX, playResY    *int
+	preserved             []string
+	scaledBorderAndShadow string
+	scriptType            string
+	scriptUpdatedBy       string
+	synchPoint            string
+	timer                 *float64
+	title                 string
+	updateDetails         string
+	v4PlusStyles          bool // If true, styles are in a [V4+ Styles] section
+	wrapStyle             string
 }
 
 // newSSAScriptInfo builds an SSA script info block based on metadata
@@ -324,10 +478,90 @@ func newSSAScriptInfo(m *Metadata) (o *ssaScriptInfo) {
 		o.title = m.Title
 		o.updateDetails = m.SSAUpdateDetails
 		o.wrapStyle = m.SSAWrapStyle
+		if len(o.wrapStyle) == 0 {
+			o.wrapStyle = ssaWrapStyleFromLineBreakPolicy(m.LineBreakPolicy)
+		}
 	}
 	return
 }
 
+// legacyAlignment returns whether the script uses SSA v4.00 alignments rather than the numpad layout of ASS scripts
+func (b *ssaScriptInfo) legacyAlignment() bool {
+	return !strings.HasSuffix(b.scriptType, "+") && !b.v4PlusStyles
+}
+
+// softLineBreaksAreHard returns whether \n breaks lines, which is the case in SSA scripts and in ASS scripts whose
+// lines are not wrapped by renderers. Otherwise renderers consider it as a white space.
+func (b *ssaScriptInfo) softLineBreaksAreHard() bool {
+	return !strings.HasSuffix(b.scriptType, "+") || b.wrapStyle == ssaWrapStyleNoWordWrapping
+}
+
+// playRes returns the resolution override tag coordinates are expressed in
+func (b *ssaScriptInfo) playRes() (x, y int) {
+	return ssaPlayRes(b.playResX, b.playResY)
+}
+
+// SSAPlayRes returns the resolution SSA coordinates and font sizes are expressed in. A missing dimension is deduced
+// from the other one assuming a 4:3 aspect ratio, like renderers do, and it defaults to 384x288.
+func (m *Metadata) SSAPlayRes() (x, y int) {
+	if m == nil {
+		return ssaDefaultPlayResX, ssaDefaultPlayResY
+	}
+	return ssaPlayRes(m.SSAPlayResX, m.SSAPlayResY)
+}
+
+// ssaPlayRes returns the resolution matching PlayResX and PlayResY values
+func ssaPlayRes(resX, resY *int) (x, y int) {
+	switch {
+	case resX != nil && resY != nil:
+		return *resX, *resY
+	case resX != nil:
+		return *resX, *resX * 3 / 4
+	case resY != nil:
+		return *resY * 4 / 3, *resY
+	}
+	return ssaDefaultPlayResX, ssaDefaultPlayResY
+}
+
+// lineBreak returns the line break written between lines, \N being required in ASS scripts since \n may not break
+// lines depending on the wrap style
+func (b *ssaScriptInfo) lineBreak() string {
+	if strings.HasSuffix(b.scriptType, "+") {
+		return "\\N"
+	}
+	return "\\n"
+}
+
+// ssaWrapStyleFromLineBreakPolicy returns the wrap style matching a line break policy
+func ssaWrapStyleFromLineBreakPolicy(p LineBreakPolicy) string {
+	switch p {
+	case LineBreakPolicyBottomWider:
+		return ssaWrapStyleSmartWrappingWithLowerLinesGettingWider
+	case LineBreakPolicyEndOfLine:
+		return ssaWrapStyleEndOfLineWordWrapping
+	case LineBreakPolicyNone:
+		return ssaWrapStyleNoWordWrapping
+	case LineBreakPolicyTopWider:
+		return ssaWrapStyleSmartWrapping
+	}
+	return ""
+}
+
+// lineBreakPolicyFromSSAWrapStyle returns the line break policy matching a wrap style
+func lineBreakPolicyFromSSAWrapStyle(s string) LineBreakPolicy {
+	switch s {
+	case ssaWrapStyleEndOfLineWordWrapping:
+		return LineBreakPolicyEndOfLine
+	case ssaWrapStyleNoWordWrapping:
+		return LineBreakPolicyNone
+	case ssaWrapStyleSmartWrapping:
+		return LineBreakPolicyTopWider
+	case ssaWrapStyleSmartWrappingWithLowerLinesGettingWider:
+		return LineBreakPolicyBottomWider
+	}
+	return ""
+}
+
 // parse parses a script info header/content
 func (b *ssaScriptInfo) parse(header, content string) (err error) {
 	switch header {
@@ -374,6 +608,9 @@ func (b *ssaScriptInfo) parse(header, content string) (err error) {
 			err = errors.Wrapf(err, "astisub: parseFloat of %s failed", content)
 		}
 		b.timer = astiptr.Float(v)
+	// Unknown
+	default:
+		b.preserved = append(b.preserved, header+": "+content)
 	}
 	return
 }
@@ -382,6 +619,7 @@ func (b *ssaScriptInfo) parse(header, content string) (err error) {
 func (b *ssaScriptInfo) metadata() *Metadata {
 	return &Metadata{
 		Comments:               b.comments,
+		LineBreakPolicy:        lineBreakPolicyFromSSAWrapStyle(b.wrapStyle),
 		SSACollisions:          b.collisions,
 		SSAOriginalEditing:     b.originalEditing,
 		SSAOriginalScript:      b.originalScript,
@@ -431,6 +669,9 @@ func (b *ssaScriptInfo) bytes() (o []byte) {
 	if b.playResY != nil {
 		o = appendStringToBytesWithNewLine(o, ssaScriptInfoNamePlayResY+": "+strconv.Itoa(*b.playResY))
 	}
+	if len(b.scaledBorderAndShadow) > 0 {
+		o = appendStringToBytesWithNewLine(o, ssaScriptInfoNameScaledBorderAndShadow+": "+b.scaledBorderAndShadow)
+	}
 	if len(b.scriptType) > 0 {
 		o = appendStringToBytesWithNewLine(o, ssaScriptInfoNameScriptType+": "+b.scriptType)
 	}
@@ -452,6 +693,34 @@ func (b *ssaScriptInfo) bytes() (o []byte) {
 	if len(b.wrapStyle) > 0 {
 		o = appendStringToBytesWithNewLine(o, ssaScriptInfoNameWrapStyle+": "+b.wrapStyle)
 	}
+	for _, p := range b.preserved {
+		o = appendStringToBytesWithNewLine(o, p)
+	}
+	return
+}
+
+// ssaPreservedSectionsBytes returns the sections that couldn't be modeled while reading
+func ssaPreservedSectionsBytes(p *Preserved) (o []byte) {
+	// Group lines by section
+	var names []string
+	var lines = make(map[string][]string)
+	for _, v := range p.values(FormatSSA) {
+		if v.Key == ssaPreservedKeyScriptInfo {
+			continue
+		}
+		if _, ok := lines[v.Key]; !ok {
+			names = append(names, v.Key)
+		}
+		lines[v.Key] = append(lines[v.Key], v.Value)
+	}
+
+	// Loop through sections
+	for _, n := range names {
+		o = append(o, []byte("\n["+n+"]\n")...)
+		for _, l := range lines[n] {
+			o = appendStringToBytesWithNewLine(o, l)
+		}
+	}
 	return
 }
 
@@ -485,31 +754,32 @@ type ssaStyle struct {
 
 // newSSAStyleFromStyle returns an SSA style based on a Style
 func newSSAStyleFromStyle(i Style) *ssaStyle {
+	var g = i.InlineStyle.GenericAttributes(FormatSSA)
 	return &ssaStyle{
 		alignment:       i.InlineStyle.SSAAlignment,
 		alphaLevel:      i.InlineStyle.SSAAlphaLevel,
 		angle:           i.InlineStyle.SSAAngle,
 		backColour:      i.InlineStyle.SSABackColour,
-		bold:            i.InlineStyle.SSABold,
+		bold:            g.Bold,
 		borderStyle:     i.InlineStyle.SSABorderStyle,
 		encoding:        i.InlineStyle.SSAEncoding,
 		fontName:        i.InlineStyle.SSAFontName,
 		fontSize:        i.InlineStyle.SSAFontSize,
-		italic:          i.InlineStyle.SSAItalic,
+		italic:          g.Italics,
 		outline:         i.InlineStyle.SSAOutline,
 		outlineColour:   i.InlineStyle.SSAOutlineColour,
 		marginLeft:      i.InlineStyle.SSAMarginLeft,
 		marginRight:     i.InlineStyle.SSAMarginRight,
 		marginVertical:  i.InlineStyle.SSAMarginVertical,
 		name:            i.ID,
-		primaryColour:   i.InlineStyle.SSAPrimaryColour,
+		primaryColour:   g.Color,
 		scaleX:          i.InlineStyle.SSAScaleX,
 		scaleY:          i.InlineStyle.SSAScaleY,
 		secondaryColour: i.InlineStyle.SSASecondaryColour,
 		shadow:          i.InlineStyle.SSAShadow,
 		spacing:         i.InlineStyle.SSASpacing,
-		strikeout:       i.InlineStyle.SSAStrikeout,
-		underline:       i.InlineStyle.SSAUnderline,
+		strikeout:       g.Strikeout,
+		underline:       g.Underline,
 	}
 }
 
@@ -881,7 +1151,7 @@ type ssaEvent struct {
 }
 
 // newSSAEventFromItem returns an SSA Event based on an input item
-func newSSAEventFromItem(i Item) (e *ssaEvent) {
+func newSSAEventFromItem(i Item, si *ssaScriptInfo) (e *ssaEvent) {
 	// Init
 	e = &ssaEvent{
 		category: ssaEventCategoryDialogue,
@@ -906,12 +1176,30 @@ func newSSAEventFromItem(i Item) (e *ssaEvent) {
 
 	// Text
 	var lines []string
+	var previous = make(map[string]string)
+	var karaoke = i.StartAt // End of the last karaoke syllable
 	for _, l := range i.Lines {
 		var items []string
 		for _, item := range l.Items {
-			var s string
+			// Overrides last until they're reset, even across lines
+			var current = ssaOverrideTags(item.InlineStyle)
+			var gap, k = ssaKaraokeOverride(item, &karaoke)
+			var overrides = ssaOverrides(previous, current) + k
+			previous = current
+
+			// Unsupported override tags are merged into the block
+			var s = gap
 			if item.InlineStyle != nil && len(item.InlineStyle.SSAEffect) > 0 {
-				s += item.InlineStyle.SSAEffect
+				if len(overrides) > 0 && strings.HasPrefix(item.InlineStyle.SSAEffect, "{\\") {
+					s += "{" + overrides + item.InlineStyle.SSAEffect[1:]
+				} else {
+					if len(overrides) > 0 {
+						s += "{" + overrides + "}"
+					}
+					s += item.InlineStyle.SSAEffect
+				}
+			} else if len(overrides) > 0 {
+				s += "{" + overrides + "}"
 			}
 			s += item.Text
 			items = append(items, s)
@@ -921,7 +1209,61 @@ func newSSAEventFromItem(i Item) (e *ssaEvent) {
 		}
 		lines = append(lines, strings.Join(items, ""))
 	}
-	e.text = strings.Join(lines, "\\n")
+	e.text = ssaPositionOverrides(i.InlineStyle, si.legacyAlignment()) + ssaFadeOverride(i.InlineStyle) + strings.Join(lines, si.lineBreak())
+	return
+}
+
+// ssaKaraokeOverride returns the karaoke override tag of a line item. cursor is the end of the previous syllable: if
+// the line item doesn't start right after it, a block containing an empty syllable filling the gap is returned as well.
+func ssaKaraokeOverride(li LineItem, cursor *time.Duration) (gap, tag string) {
+	// Not a karaoke syllable
+	if !li.isKaraoke() {
+		return
+	}
+
+	// Durations are in centiseconds
+	var cs = func(d time.Duration) string {
+		return strconv.FormatInt(int64((d+5*time.Millisecond)/(10*time.Millisecond)), 10)
+	}
+	if li.StartAt > *cursor {
+		gap = "{\\k" + cs(li.StartAt-*cursor) + "}"
+	}
+	tag = "\\" + string(li.Karaoke) + cs(li.EndAt-li.StartAt)
+	*cursor = li.EndAt
+	return
+}
+
+// ssaPositionOverrides returns the alignment and \pos override tags of an item, the alignment being written with \a
+// in scripts using SSA v4.00 alignments and with \an otherwise
+func ssaPositionOverrides(sa *StyleAttributes, legacyAlignment bool) string {
+	if sa == nil {
+		return ""
+	}
+	var ts []string
+	if legacyAlignment {
+		if a := ssaAlignmentToLegacy(sa.SSAAlignment); a != nil {
+			ts = append(ts, "\\a"+strconv.Itoa(*a))
+		}
+	} else if ssaValidAlignment(sa.SSAAlignment) {
+		ts = append(ts, "\\an"+strconv.Itoa(*sa.SSAAlignment))
+	}
+	if sa.SSAPosition != nil {
+		ts = append(ts, "\\pos("+strconv.FormatFloat(sa.SSAPosition.X, 'f', -1, 64)+","+strconv.FormatFloat(sa.SSAPosition.Y, 'f', -1, 64)+")")
+	}
+	if len(ts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(ts, "") + "}"
+}
+
+// newSSACommentEvents returns the comment events preceding a dialogue event
+func newSSACommentEvents(dialogue *ssaEvent, comments []string) (es []*ssaEvent) {
+	for _, c := range comments {
+		var e = *dialogue
+		e.category = ssaEventCategoryComment
+		e.text = strings.Replace(c, "\n", "\\N", -1)
+		es = append(es, &e)
+	}
 	return
 }
 
@@ -1014,8 +1356,10 @@ func newSSAEventFromString(header, content string, format map[int]string) (e *ss
 	return
 }
 
-// item converts an SSA event to an Item
-func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
+// item converts an SSA event to an Item. \N always breaks lines whereas \n only does if the script's soft line breaks
+// are hard, and is considered as a white space otherwise. Supported override tags are parsed into inline styles, the
+// other ones being kept in SSAEffect.
+func (e *ssaEvent) item(styles map[string]*Style, si *ssaScriptInfo) (i *Item, err error) {
 	// Init item
 	i = &Item{
 		EndAt: e.end,
@@ -1040,37 +1384,372 @@ func (e *ssaEvent) item(styles map[string]*Style) (i *Item, err error) {
 	}
 
 	// Loop through lines
-	for _, s := range strings.Split(e.text, "\\n") {
+	var text = strings.Replace(e.text, "\\n", " ", -1)
+	if si.softLineBreaksAreHard() {
+		text = strings.Replace(e.text, "\\n", "\\N", -1)
+	}
+	var overrides StyleAttributes // Line item overrides last until they're reset, even across lines
+	var karaoke = e.start         // End of the last karaoke syllable
+	for _, s := range strings.Split(text, "\\N") {
 		// Init
 		s = strings.TrimSpace(s)
 		var l = Line{VoiceName: e.name}
 
-		// Extract effects
+		// Text before the first override block
 		var matches = ssaRegexpEffect.FindAllStringIndex(s, -1)
+		var end = len(s)
 		if len(matches) > 0 {
-			// Loop through matches
-			var lineItem *LineItem
-			var previousEffectEndOffset int
-			for _, idxs := range matches {
-				if lineItem != nil {
-					lineItem.Text = s[previousEffectEndOffset:idxs[0]]
-					l.Items = append(l.Items, *lineItem)
-				}
-				previousEffectEndOffset = idxs[1]
-				lineItem = &LineItem{InlineStyle: &StyleAttributes{SSAEffect: s[idxs[0]:idxs[1]]}}
+			end = matches[0][0]
+		}
+		if end > 0 || len(matches) == 0 {
+			l.Items = append(l.Items, newSSALineItem(overrides, "", s[:end]))
+		}
+
+		// Loop through override blocks
+		for idx, m := range matches {
+			// Get text
+			end = len(s)
+			if idx+1 < len(matches) {
+				end = matches[idx+1][0]
 			}
-			lineItem.Text = s[previousEffectEndOffset:]
-			l.Items = append(l.Items, *lineItem)
-		} else {
-			l.Items = append(l.Items, LineItem{Text: s})
+
+			// Extract fades
+			var effect, f = ssaExtractFade(s[m[0]:m[1]], e.end-e.start)
+			if f != nil {
+				i.InlineStyle.Fade = f
+			}
+
+			// Parse override tags
+			var k ssaKaraoke
+			effect, k = ssaParseOverrides(effect, &overrides, i.InlineStyle)
+			var li = newSSALineItem(overrides, effect, s[m[1]:end])
+
+			// Karaoke syllables follow each other from the start of the event
+			if len(k.effect) > 0 {
+				li.Karaoke, li.StartAt = k.effect, karaoke
+				karaoke += k.duration
+				li.EndAt = karaoke
+			}
+			l.Items = append(l.Items, li)
 		}
 
 		// Add line
 		i.Lines = append(i.Lines, l)
 	}
+
+	// Propagate position
+	ssaPropagatePosition(i, si)
+	return
+}
+
+// SSAPosition represents the point an item is anchored to by the \pos override tag, in script pixels
+type SSAPosition struct {
+	X, Y float64
+}
+
+// ssaOverrideNames are the names of the line item override tags, in the order they're written
+var ssaOverrideNames = []string{"b", "i", "u", "s", "c", "2c", "3c", "4c", "fn", "fs"}
+
+// ssaRegexpOverrideColor matches colour override tags
+var ssaRegexpOverrideColor = regexp.MustCompile(`^([1-4]?)c&H([0-9a-fA-F]+)&?$`)
+
+// ssaSplitOverrideTags splits the content of an override block into tags starting with a backslash. Backslashes
+// between parentheses, such as in \t(\fs20), don't start new tags. Text preceding the first tag is returned as is.
+func ssaSplitOverrideTags(i string) (ts []string) {
+	var depth, start int
+	for idx, r := range i {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case '\\':
+			if depth == 0 && idx > start {
+				ts = append(ts, i[start:idx])
+				start = idx
+			}
+		}
+	}
+	if len(i) > start {
+		ts = append(ts, i[start:])
+	}
+	return
+}
+
+// ssaKaraoke represents the karaoke override tags of a block
+type ssaKaraoke struct {
+	duration time.Duration
+	effect   KaraokeEffect
+}
+
+// ssaRegexpOverrideKaraoke matches karaoke override tags, whose durations are in centiseconds
+var ssaRegexpOverrideKaraoke = regexp.MustCompile(`^(k|K|kf|ko)(\d+)$`)
+
+// ssaParseOverrides parses the override tags of a block. Line item overrides update overrides, \r resetting them,
+// \an and \pos update the item's inline style and karaoke tags are returned. Tags that are not supported are
+// returned in a block, which is empty if there are none.
+func ssaParseOverrides(block string, overrides, item *StyleAttributes) (effect string, k ssaKaraoke) {
+	// Empty
+	if len(block) < 2 {
+		return
+	}
+
+	// Loop through tags
+	var unsupported []string
+	for _, t := range ssaSplitOverrideTags(block[1 : len(block)-1]) {
+		if !ssaParseOverride(strings.TrimPrefix(t, "\\"), overrides, item, &k) {
+			unsupported = append(unsupported, t)
+		}
+	}
+	if len(unsupported) > 0 {
+		effect = "{" + strings.Join(unsupported, "") + "}"
+	}
+	return
+}
+
+// ssaParseOverride parses an override tag stripped of its backslash. ok is false if the tag is not supported.
+func ssaParseOverride(t string, overrides, item *StyleAttributes, k *ssaKaraoke) (ok bool) {
+	switch {
+	case t == "r":
+		*overrides = StyleAttributes{}
+	case strings.HasPrefix(t, "an"):
+		v, err := strconv.Atoi(t[2:])
+		if err != nil || v < 1 || v > 9 {
+			return
+		}
+		item.SSAAlignment = astiptr.Int(v)
+	case strings.HasPrefix(t, "a"):
+		v, err := strconv.Atoi(t[1:])
+		if err != nil {
+			return
+		}
+		if item.SSAAlignment = ssaAlignmentFromLegacy(&v); item.SSAAlignment == nil {
+			return
+		}
+	case strings.HasPrefix(t, "pos(") && strings.HasSuffix(t, ")"):
+		var split = strings.Split(t[4:len(t)-1], ",")
+		if len(split) != 2 {
+			return
+		}
+		x, err := strconv.ParseFloat(strings.TrimSpace(split[0]), 64)
+		if err != nil {
+			return
+		}
+		y, err := strconv.ParseFloat(strings.TrimSpace(split[1]), 64)
+		if err != nil {
+			return
+		}
+		item.SSAPosition = &SSAPosition{X: x, Y: y}
+	case ssaRegexpOverrideKaraoke.MatchString(t):
+		var m = ssaRegexpOverrideKaraoke.FindStringSubmatch(t)
+		v, err := strconv.Atoi(m[2])
+		if err != nil {
+			return
+		}
+		k.duration += time.Duration(v) * 10 * time.Millisecond
+		switch m[1] {
+		case "k":
+			k.effect = KaraokeEffectInstant
+		case "K", "kf":
+			k.effect = KaraokeEffectFill
+		case "ko":
+			k.effect = KaraokeEffectOutline
+		}
+	case strings.HasPrefix(t, "fn"):
+		overrides.SSAFontName = t[2:]
+	case strings.HasPrefix(t, "fs"):
+		v, err := strconv.ParseFloat(t[2:], 64)
+		if err != nil {
+			return
+		}
+		overrides.SSAFontSize = astiptr.Float(v)
+	case ssaRegexpOverrideColor.MatchString(t):
+		var m = ssaRegexpOverrideColor.FindStringSubmatch(t)
+		c, err := newColorFromSSAColor("&H" + m[2])
+		if err != nil {
+			return
+		}
+		switch m[1] {
+		case "", "1":
+			overrides.SSAPrimaryColour = c
+		case "2":
+			overrides.SSASecondaryColour = c
+		case "3":
+			overrides.SSAOutlineColour = c
+		case "4":
+			overrides.SSABackColour = c
+		}
+	case len(t) > 1 && strings.ContainsRune("bisu", rune(t[0])):
+		v, err := strconv.Atoi(t[1:])
+		if err != nil {
+			return
+		}
+		switch t[0] {
+		case 'b':
+			// Weights such as \b700 are supported as well
+			overrides.SSABold = astiptr.Bool(v == 1 || v >= 700)
+		case 'i':
+			overrides.SSAItalic = astiptr.Bool(v == 1)
+		case 's':
+			overrides.SSAStrikeout = astiptr.Bool(v == 1)
+		case 'u':
+			overrides.SSAUnderline = astiptr.Bool(v == 1)
+		}
+	default:
+		return
+	}
+	return true
+}
+
+// ssaOverrideTags returns the line item override tags of inline style attributes, indexed by name
+func ssaOverrideTags(sa *StyleAttributes) (ts map[string]string) {
+	ts = make(map[string]string)
+	if sa == nil {
+		return
+	}
+	var flag = func(name string, v *bool) {
+		if v == nil {
+			return
+		} else if *v {
+			ts[name] = "\\" + name + "1"
+		} else {
+			ts[name] = "\\" + name + "0"
+		}
+	}
+	var g = sa.GenericAttributes(FormatSSA)
+	flag("b", g.Bold)
+	flag("i", g.Italics)
+	flag("s", g.Strikeout)
+	flag("u", g.Underline)
+	for name, c := range map[string]*Color{"c": g.Color, "2c": sa.SSASecondaryColour, "3c": sa.SSAOutlineColour, "4c": sa.SSABackColour} {
+		if c != nil {
+			ts[name] = "\\" + name + "&H" + c.String(16, false) + "&"
+		}
+	}
+	if len(sa.SSAFontName) > 0 {
+		ts["fn"] = "\\fn" + sa.SSAFontName
+	}
+	if sa.SSAFontSize != nil {
+		ts["fs"] = "\\fs" + strconv.FormatFloat(*sa.SSAFontSize, 'f', -1, 64)
+	}
 	return
 }
 
+// ssaOverrides returns the override tags switching from the line item overrides of the previous line item to the
+// ones of the current line item. Since overrides can't be unset individually, \r is used if some of them are not
+// set anymore.
+func ssaOverrides(previous, current map[string]string) string {
+	// Check whether overrides need to be reset
+	var reset bool
+	for name := range previous {
+		if _, ok := current[name]; !ok {
+			reset = true
+			break
+		}
+	}
+
+	// Loop through names
+	var ts []string
+	if reset {
+		ts = append(ts, "\\r")
+	}
+	for _, name := range ssaOverrideNames {
+		if t, ok := current[name]; ok && (reset || t != previous[name]) {
+			ts = append(ts, t)
+		}
+	}
+	return strings.Join(ts, "")
+}
+
+// newSSALineItem returns a line item whose inline style is made of line item overrides and of unsupported override
+// tags
+func newSSALineItem(overrides StyleAttributes, effect, text string) (i LineItem) {
+	i.Text = text
+	if len(effect) > 0 {
+		i.InlineStyle = &StyleAttributes{SSAEffect: effect}
+	}
+	if len(ssaOverrideTags(&overrides)) > 0 {
+		i.InlineStyle = &overrides
+		i.InlineStyle.SSAEffect = effect
+		i.InlineStyle.propagateSSAAttributes()
+	}
+	return
+}
+
+// ssaPropagatePosition propagates the \pos override tag of an item to the WebVTT position settings, its coordinates
+// being expressed in the script's resolution. Items only aligned with \an are handled by the WebVTT writer.
+func ssaPropagatePosition(i *Item, si *ssaScriptInfo) {
+	// No position
+	var sa = i.InlineStyle
+	if sa.SSAPosition == nil {
+		return
+	}
+
+	// Get alignment, \pos being relative to the style's alignment if the item has none
+	var alignment = 2
+	if ssaValidAlignment(sa.SSAAlignment) {
+		alignment = *sa.SSAAlignment
+	} else if ssa := ssaItemStyleAttributes(i); ssa != nil && ssaValidAlignment(ssa.SSAAlignment) {
+		alignment = *ssa.SSAAlignment
+	}
+
+	// Propagate
+	var resX, resY = si.playRes()
+	sa.WebVTTAlign = []string{"left", "center", "right"}[(alignment-1)%3]
+	sa.WebVTTLine = ssaWebVTTPercentage(sa.SSAPosition.Y, resY) + []string{",end", ",center", ""}[(alignment-1)/3]
+	sa.WebVTTPosition = ssaWebVTTPercentage(sa.SSAPosition.X, resX)
+}
+
+// ssaWebVTTPercentage returns the WebVTT percentage of a coordinate
+func ssaWebVTTPercentage(v float64, res int) string {
+	return strconv.FormatFloat(math.Round(v*10000/float64(res))/100, 'f', -1, 64) + "%"
+}
+
+// ssaExtractFade extracts the \fad and \fade override tags of an override block. \fade tags are only extracted if they
+// can be modeled as a fade-in/fade-out. If the block is left empty, an empty string is returned.
+func ssaExtractFade(effect string, d time.Duration) (o string, f *Fade) {
+	o = ssaRegexpFade.ReplaceAllStringFunc(effect, func(tag string) string {
+		// Parse arguments
+		var vs []int
+		for _, a := range strings.Split(ssaRegexpFade.FindStringSubmatch(tag)[1], ",") {
+			v, err := strconv.Atoi(strings.TrimSpace(a))
+			if err != nil {
+				return tag
+			}
+			vs = append(vs, v)
+		}
+
+		// Switch on number of arguments
+		switch len(vs) {
+		case 2:
+			f = &Fade{In: time.Duration(vs[0]) * time.Millisecond, Out: time.Duration(vs[1]) * time.Millisecond}
+		case 7:
+			// Alpha must go from transparent to opaque to transparent over the whole item
+			if vs[0] != 255 || vs[1] != 0 || vs[2] != 255 || vs[3] != 0 || time.Duration(vs[6])*time.Millisecond != d {
+				return tag
+			}
+			f = &Fade{In: time.Duration(vs[4]) * time.Millisecond, Out: time.Duration(vs[6]-vs[5]) * time.Millisecond}
+		default:
+			return tag
+		}
+		return ""
+	})
+	if o == "{}" {
+		o = ""
+	}
+	return
+}
+
+// ssaFadeOverride returns the \fad override tag of a fade
+func ssaFadeOverride(sa *StyleAttributes) string {
+	if sa == nil || sa.Fade == nil {
+		return ""
+	}
+	return fmt.Sprintf("{\\fad(%d,%d)}", int64(sa.Fade.In/time.Millisecond), int64(sa.Fade.Out/time.Millisecond))
+}
+
 // updateFormat updates the format based on the non empty fields
 func (e ssaEvent) updateFormat(formatMap map[string]bool, format []string) []string {
 	if len(e.effect) > 0 {
@@ -1173,16 +1852,56 @@ func parseDurationSSA(i string) (time.Duration, error) {
 	return parseDuration(i, ".", 3)
 }
 
+// SSAOptions represents SSA write options
+type SSAOptions struct {
+	// Cue times are rounded to frame boundaries when set
+	FrameRounding FrameRounding
+	// Policy applied to times exceeding 9:59:59.99, the maximum time of the format. Times are written as is by default.
+	HourPolicy HourPolicy
+	// If true, a fully specified ASS content optimized for libass (and therefore ffmpeg) burn-in is written: explicit
+	// PlayRes, complete styles and \an/\pos overrides converted from the styles of other formats, so that what is
+	// burnt matches what has been modeled
+	Libass bool
+	// Resolution of the video the subtitles will be burnt in. Defaults to the metadata PlayRes, then to 1920x1080.
+	PlayResX, PlayResY int
+}
+
 // WriteToSSA writes subtitles in .ssa format
-func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
+func (s Subtitles) WriteToSSA(o io.Writer) error {
+	return s.WriteToSSAWithOptions(o, SSAOptions{})
+}
+
+// WriteToSSAWithOptions writes subtitles in .ssa format with options
+func (s Subtitles) WriteToSSAWithOptions(o io.Writer, opts SSAOptions) error {
+	return s.writeToSSA(o, opts, nil)
+}
+
+// writeToSSA writes subtitles in .ssa format with options, calling a hook for each item right before it's written
+func (s Subtitles) writeToSSA(o io.Writer, opts SSAOptions, h ItemHook) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
 		return
 	}
 
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Apply hour policy
+	var bound, precision = timeBounds(FormatSSA, 0)
+	if s, err = s.applyHourPolicy(bound, precision, opts.HourPolicy); err != nil {
+		err = errors.Wrap(err, "astisub: applying hour policy failed")
+		return
+	}
+
+	// Libass
+	if opts.Libass {
+		return s.writeToSSALibass(o, opts, h)
+	}
+
 	// Write Script Info block
 	var si = newSSAScriptInfo(s.Metadata)
+	si.preserved = s.Preserved.valuesByKey(FormatSSA, ssaPreservedKeyScriptInfo)
 	if _, err = o.Write(si.bytes()); err != nil {
 		err = errors.Wrap(err, "astisub: writing script info block failed")
 		return
@@ -1200,6 +1919,9 @@ func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
 		var styleNames []string
 		for _, s := range s.Styles {
 			var ss = newSSAStyleFromStyle(*s)
+			if si.legacyAlignment() {
+				ss.alignment = ssaAlignmentToLegacy(ss.alignment)
+			}
 			format = ss.updateFormat(formatMap, format)
 			styles[ss.name] = ss
 			styleNames = append(styleNames, ss.name)
@@ -1231,9 +1953,13 @@ func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
 			ssaEventFormatNameEnd,
 		}
 		var events []*ssaEvent
-		for _, i := range s.Items {
-			var e = newSSAEventFromItem(*i)
+		for idx, i := range s.Items {
+			if i, err = hookedItem(i, idx, h); err != nil {
+				return
+			}
+			var e = newSSAEventFromItem(*i, si)
 			format = e.updateFormat(formatMap, format)
+			events = append(events, newSSACommentEvents(e, i.Comments)...)
 			events = append(events, e)
 		}
 		format = append(format, ssaEventFormatNameText)
@@ -1241,7 +1967,7 @@ func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
 
 		// Styles
 		for _, e := range events {
-			b = append(b, []byte(ssaEventCategoryDialogue+": "+e.string(format)+"\n")...)
+			b = append(b, []byte(e.category+": "+e.string(format)+"\n")...)
 		}
 
 		// Write
@@ -1250,5 +1976,288 @@ func (s Subtitles) WriteToSSA(o io.Writer) (err error) {
 			return
 		}
 	}
+
+	// Write preserved sections
+	if _, err = o.Write(ssaPreservedSectionsBytes(s.Preserved)); err != nil {
+		err = errors.Wrap(err, "astisub: writing preserved sections failed")
+		return
+	}
+	return
+}
+
+// writeToSSALibass writes subtitles in a fully specified .ass format optimized for libass
+func (s Subtitles) writeToSSALibass(o io.Writer, opts SSAOptions, h ItemHook) (err error) {
+	// Get resolution
+	var resX, resY = opts.PlayResX, opts.PlayResY
+	if s.Metadata != nil && s.Metadata.SSAPlayResX != nil && s.Metadata.SSAPlayResY != nil && (resX <= 0 || resY <= 0) {
+		resX, resY = *s.Metadata.SSAPlayResX, *s.Metadata.SSAPlayResY
+	}
+	if resX <= 0 || resY <= 0 {
+		resX, resY = ssaLibassDefaultPlayResX, ssaLibassDefaultPlayResY
+	}
+
+	// Build script info block
+	var si = newSSAScriptInfo(s.Metadata)
+	si.playResX = astiptr.Int(resX)
+	si.playResY = astiptr.Int(resY)
+	si.scaledBorderAndShadow = "yes"
+	si.scriptType = ssaLibassScriptType
+	for _, p := range s.Preserved.valuesByKey(FormatSSA, ssaPreservedKeyScriptInfo) {
+		if !strings.HasPrefix(p, ssaScriptInfoNameScaledBorderAndShadow+":") {
+			si.preserved = append(si.preserved, p)
+		}
+	}
+	var b = si.bytes()
+
+	// Get styles
+	var styles = make(map[string]*ssaStyle)
+	var styleNames []string
+	for _, v := range s.Styles {
+		var ss = newSSAStyleFromStyle(*v)
+		ss.complete(resX, resY)
+		styles[ss.name] = ss
+		styleNames = append(styleNames, ss.name)
+	}
+
+	// Items without style use the default style
+	for _, i := range s.Items {
+		if i.Style == nil {
+			if _, ok := styles[ssaLibassDefaultStyleID]; !ok {
+				var ss = &ssaStyle{name: ssaLibassDefaultStyleID}
+				ss.complete(resX, resY)
+				styles[ss.name] = ss
+				styleNames = append(styleNames, ss.name)
+			}
+			break
+		}
+	}
+
+	// Add styles block
+	sort.Strings(styleNames)
+	b = append(b, []byte("\n[V4+ Styles]\nFormat: "+strings.Join(ssaLibassStyleFormat, ", ")+"\n")...)
+	for _, n := range styleNames {
+		b = append(b, []byte("Style: "+styles[n].string(ssaLibassStyleFormat[1:])+"\n")...)
+	}
+
+	// Add events block
+	b = append(b, []byte("\n[Events]\nFormat: "+strings.Join(ssaLibassEventFormat, ", ")+"\n")...)
+	for idx, i := range s.Items {
+		if i, err = hookedItem(i, idx, h); err != nil {
+			return
+		}
+		var e = newSSALibassEventFromItem(*i, resX, resY)
+		for _, c := range newSSACommentEvents(e, i.Comments) {
+			b = append(b, []byte(c.category+": "+c.string(ssaLibassEventFormat)+"\n")...)
+		}
+		b = append(b, []byte(e.category+": "+e.string(ssaLibassEventFormat)+"\n")...)
+	}
+
+	// Add preserved sections
+	b = append(b, ssaPreservedSectionsBytes(s.Preserved)...)
+
+	// Write
+	if _, err = o.Write(b); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
 	return
 }
+
+// complete sets every empty field of a style to libass defaults scaled to the resolution
+func (s *ssaStyle) complete(resX, resY int) {
+	var scaleX, scaleY = float64(resX) / ssaDefaultPlayResX, float64(resY) / ssaDefaultPlayResY
+	if s.alignment == nil {
+		s.alignment = astiptr.Int(2)
+	}
+	if s.angle == nil {
+		s.angle = astiptr.Float(0)
+	}
+	if s.backColour == nil {
+		s.backColour = &Color{Alpha: 0x80}
+	}
+	if s.bold == nil {
+		s.bold = astiptr.Bool(false)
+	}
+	if s.borderStyle == nil {
+		s.borderStyle = astiptr.Int(ssaBorderStyleOutlineAndDropShadow)
+	}
+	if s.encoding == nil {
+		s.encoding = astiptr.Int(1)
+	}
+	if len(s.fontName) == 0 {
+		s.fontName = "Arial"
+	}
+	if s.fontSize == nil {
+		s.fontSize = astiptr.Float(math.Round(18 * scaleY))
+	}
+	if s.italic == nil {
+		s.italic = astiptr.Bool(false)
+	}
+	if s.marginLeft == nil {
+		s.marginLeft = astiptr.Int(int(math.Round(10 * scaleX)))
+	}
+	if s.marginRight == nil {
+		s.marginRight = astiptr.Int(int(math.Round(10 * scaleX)))
+	}
+	if s.marginVertical == nil {
+		s.marginVertical = astiptr.Int(int(math.Round(10 * scaleY)))
+	}
+	if s.outline == nil {
+		s.outline = astiptr.Int(int(math.Max(1, math.Round(scaleY))))
+	}
+	if s.outlineColour == nil {
+		s.outlineColour = &Color{}
+	}
+	if s.primaryColour == nil {
+		s.primaryColour = &Color{Blue: 0xff, Green: 0xff, Red: 0xff}
+	}
+	if s.scaleX == nil {
+		s.scaleX = astiptr.Float(100)
+	}
+	if s.scaleY == nil {
+		s.scaleY = astiptr.Float(100)
+	}
+	if s.secondaryColour == nil {
+		s.secondaryColour = &Color{Red: 0xff}
+	}
+	if s.shadow == nil {
+		s.shadow = astiptr.Int(0)
+	}
+	if s.spacing == nil {
+		s.spacing = astiptr.Int(0)
+	}
+	if s.strikeout == nil {
+		s.strikeout = astiptr.Bool(false)
+	}
+	if s.underline == nil {
+		s.underline = astiptr.Bool(false)
+	}
+}
+
+// newSSALibassEventFromItem returns a fully specified SSA event based on an input item where styles of other formats
+// have been converted to override tags
+func newSSALibassEventFromItem(i Item, resX, resY int) (e *ssaEvent) {
+	// Init
+	e = &ssaEvent{
+		category:       ssaEventCategoryDialogue,
+		end:            i.EndAt,
+		layer:          astiptr.Int(0),
+		marginLeft:     astiptr.Int(0),
+		marginRight:    astiptr.Int(0),
+		marginVertical: astiptr.Int(0),
+		start:          i.StartAt,
+		style:          ssaLibassDefaultStyleID,
+	}
+
+	// Style
+	if i.Style != nil {
+		e.style = i.Style.ID
+	}
+
+	// Inline style
+	var overrides string
+	if i.InlineStyle != nil {
+		e.effect = i.InlineStyle.SSAEffect
+		if i.InlineStyle.SSALayer != nil {
+			e.layer = i.InlineStyle.SSALayer
+		}
+		if i.InlineStyle.SSAMarginLeft != nil {
+			e.marginLeft = i.InlineStyle.SSAMarginLeft
+		}
+		if i.InlineStyle.SSAMarginRight != nil {
+			e.marginRight = i.InlineStyle.SSAMarginRight
+		}
+		if i.InlineStyle.SSAMarginVertical != nil {
+			e.marginVertical = i.InlineStyle.SSAMarginVertical
+		}
+		overrides = ssaLibassPositionOverrides(*i.InlineStyle, resX, resY) + ssaFadeOverride(i.InlineStyle)
+	}
+
+	// Text
+	var lines []string
+	var karaoke = i.StartAt // End of the last karaoke syllable
+	for _, l := range i.Lines {
+		var items []string
+		for _, item := range l.Items {
+			var gap, k = ssaKaraokeOverride(item, &karaoke)
+			if len(k) > 0 {
+				gap += "{" + k + "}"
+			}
+			items = append(items, gap+ssaLibassLineItemText(item))
+		}
+		if len(l.VoiceName) > 0 {
+			e.name = l.VoiceName
+		}
+		lines = append(lines, strings.Join(items, ""))
+	}
+	e.text = overrides + strings.Join(lines, "\\N")
+	return
+}
+
+// ssaLibassPositionOverrides returns the \an and \pos override tags matching the position of an item
+func ssaLibassPositionOverrides(sa StyleAttributes, resX, resY int) string {
+	// WebVTT
+	a, ok := sa.WebVTTAnchor()
+	if !ok {
+		// SSA alignment
+		if ssaValidAlignment(sa.SSAAlignment) {
+			return fmt.Sprintf("{\\an%d}", *sa.SSAAlignment)
+		}
+		return ""
+	}
+
+	// SSA alignment
+	if ssaValidAlignment(sa.SSAAlignment) {
+		a.Alignment = *sa.SSAAlignment
+	}
+	if a.Position != nil {
+		return fmt.Sprintf("{\\an%d\\pos(%d,%d)}", a.Alignment, int(math.Round(*a.Position*float64(resX)/100)), int(math.Round(a.Line*float64(resY)/100)))
+	}
+	return fmt.Sprintf("{\\an%d}", a.Alignment)
+}
+
+// parseWebVTTPercentage parses a WebVTT percentage such as "10%"
+func parseWebVTTPercentage(i string) (f float64, ok bool) {
+	if !strings.HasSuffix(i, "%") {
+		return
+	}
+	var err error
+	if f, err = strconv.ParseFloat(strings.TrimSuffix(i, "%"), 64); err != nil {
+		return
+	}
+	ok = true
+	return
+}
+
+// ssaLibassLineItemText returns the text of a line item where styles of other formats have been converted to
+// override tags
+func ssaLibassLineItemText(i LineItem) string {
+	// No inline style
+	if i.InlineStyle == nil {
+		return i.Text
+	}
+
+	// Build overrides
+	var g = i.InlineStyle.GenericAttributes(FormatSSA)
+	var os []string
+	if g.Bold != nil && *g.Bold {
+		os = append(os, "\\b1")
+	}
+	if g.Italics != nil && *g.Italics {
+		os = append(os, "\\i1")
+	}
+	if g.Underline != nil && *g.Underline {
+		os = append(os, "\\u1")
+	}
+	if g.Color != nil {
+		os = append(os, "\\c&H"+g.Color.String(16, false)+"&")
+	}
+
+	// No overrides, SSA effects being already override tags
+	if len(os) == 0 {
+		return i.InlineStyle.SSAEffect + i.Text
+	}
+
+	// Overrides are reset afterwards
+	return "{" + strings.Join(os, "") + "}" + i.InlineStyle.SSAEffect + i.Text + "{\\r}"
+}
@@ -3,7 +3,9 @@ package astisub_test
 import (
 	"bytes"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/asticode/go-astisub"
 	"github.com/asticode/go-astitools/ptr"
@@ -78,18 +80,20 @@ func TestSSA(t *testing.T) {
 	assert.Equal(t, &astisub.Metadata{Comments: []string{"Comment 1", "Comment 2"}, SSACollisions: "Normal", SSAOriginalScript: "asticode", SSAPlayDepth: astiptr.Int(0), SSAPlayResY: astiptr.Int(600), SSAScriptType: "v4.00", SSAScriptUpdatedBy: "version 2.8.01", SSATimer: astiptr.Float(100), Title: "SSA test"}, s.Metadata)
 	// Styles
 	assert.Equal(t, 3, len(s.Styles))
-	assertSSAStyle(t, astisub.Style{ID: "1", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(7), SSAAlphaLevel: astiptr.Float(0.1), SSABackColour: &astisub.Color{Alpha: 128, Red: 8}, SSABold: astiptr.Bool(true), SSABorderStyle: astiptr.Int(7), SSAFontName: "f1", SSAFontSize: astiptr.Float(4), SSAOutline: astiptr.Int(1), SSAOutlineColour: &astisub.Color{Green: 255, Red: 255}, SSAMarginLeft: astiptr.Int(1), SSAMarginRight: astiptr.Int(4), SSAMarginVertical: astiptr.Int(7), SSAPrimaryColour: &astisub.Color{Green: 255, Red: 255}, SSASecondaryColour: &astisub.Color{Green: 255, Red: 255}, SSAShadow: astiptr.Int(4)}}, *s.Styles["1"])
-	assertSSAStyle(t, astisub.Style{ID: "2", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(8), SSAAlphaLevel: astiptr.Float(0.2), SSABackColour: &astisub.Color{Blue: 15, Green: 15, Red: 15}, SSABold: astiptr.Bool(true), SSABorderStyle: astiptr.Int(8), SSAEncoding: astiptr.Int(1), SSAFontName: "f2", SSAFontSize: astiptr.Float(5), SSAOutline: astiptr.Int(2), SSAOutlineColour: &astisub.Color{Green: 255, Red: 255}, SSAMarginLeft: astiptr.Int(2), SSAMarginRight: astiptr.Int(5), SSAMarginVertical: astiptr.Int(8), SSAPrimaryColour: &astisub.Color{Blue: 239, Green: 239, Red: 239}, SSASecondaryColour: &astisub.Color{Green: 255, Red: 255}, SSAShadow: astiptr.Int(5)}}, *s.Styles["2"])
-	assertSSAStyle(t, astisub.Style{ID: "3", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(9), SSAAlphaLevel: astiptr.Float(0.3), SSABackColour: &astisub.Color{Red: 8}, SSABorderStyle: astiptr.Int(9), SSAEncoding: astiptr.Int(2), SSAFontName: "f3", SSAFontSize: astiptr.Float(6), SSAOutline: astiptr.Int(3), SSAOutlineColour: &astisub.Color{Red: 8}, SSAMarginLeft: astiptr.Int(3), SSAMarginRight: astiptr.Int(6), SSAMarginVertical: astiptr.Int(9), SSAPrimaryColour: &astisub.Color{Blue: 180, Green: 252, Red: 252}, SSASecondaryColour: &astisub.Color{Blue: 180, Green: 252, Red: 252}, SSAShadow: astiptr.Int(6)}}, *s.Styles["3"])
+	assertSSAStyle(t, astisub.Style{ID: "1", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(9), SSAAlphaLevel: astiptr.Float(0.1), SSABackColour: &astisub.Color{Alpha: 128, Red: 8}, SSABold: astiptr.Bool(true), SSABorderStyle: astiptr.Int(7), SSAFontName: "f1", SSAFontSize: astiptr.Float(4), SSAOutline: astiptr.Int(1), SSAOutlineColour: &astisub.Color{Green: 255, Red: 255}, SSAMarginLeft: astiptr.Int(1), SSAMarginRight: astiptr.Int(4), SSAMarginVertical: astiptr.Int(7), SSAPrimaryColour: &astisub.Color{Green: 255, Red: 255}, SSASecondaryColour: &astisub.Color{Green: 255, Red: 255}, SSAShadow: astiptr.Int(4)}}, *s.Styles["1"])
+	assertSSAStyle(t, astisub.Style{ID: "2", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(3), SSAAlphaLevel: astiptr.Float(0.2), SSABackColour: &astisub.Color{Blue: 15, Green: 15, Red: 15}, SSABold: astiptr.Bool(true), SSABorderStyle: astiptr.Int(8), SSAEncoding: astiptr.Int(1), SSAFontName: "f2", SSAFontSize: astiptr.Float(5), SSAOutline: astiptr.Int(2), SSAOutlineColour: &astisub.Color{Green: 255, Red: 255}, SSAMarginLeft: astiptr.Int(2), SSAMarginRight: astiptr.Int(5), SSAMarginVertical: astiptr.Int(8), SSAPrimaryColour: &astisub.Color{Blue: 239, Green: 239, Red: 239}, SSASecondaryColour: &astisub.Color{Green: 255, Red: 255}, SSAShadow: astiptr.Int(5)}}, *s.Styles["2"])
+	assertSSAStyle(t, astisub.Style{ID: "3", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(4), SSAAlphaLevel: astiptr.Float(0.3), SSABackColour: &astisub.Color{Red: 8}, SSABorderStyle: astiptr.Int(9), SSAEncoding: astiptr.Int(2), SSAFontName: "f3", SSAFontSize: astiptr.Float(6), SSAOutline: astiptr.Int(3), SSAOutlineColour: &astisub.Color{Red: 8}, SSAMarginLeft: astiptr.Int(3), SSAMarginRight: astiptr.Int(6), SSAMarginVertical: astiptr.Int(9), SSAPrimaryColour: &astisub.Color{Blue: 180, Green: 252, Red: 252}, SSASecondaryColour: &astisub.Color{Blue: 180, Green: 252, Red: 252}, SSAShadow: astiptr.Int(6)}}, *s.Styles["3"])
 	// Items
 	assertSSAStyleAttributes(t, astisub.StyleAttributes{SSAEffect: "test", SSAMarked: astiptr.Bool(false), SSAMarginLeft: astiptr.Int(1234), SSAMarginRight: astiptr.Int(2345), SSAMarginVertical: astiptr.Int(3456)}, *s.Items[0].InlineStyle)
 	assert.Equal(t, s.Styles["1"], s.Items[0].Style)
-	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SSAEffect: "{\\pos(400,570)}"}, Text: "(deep rumbling)"}}, VoiceName: "Cher"}}, s.Items[0].Lines)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: "(deep rumbling)"}}, VoiceName: "Cher"}}, s.Items[0].Lines)
+	assert.Equal(t, &astisub.SSAPosition{X: 400, Y: 570}, s.Items[0].InlineStyle.SSAPosition)
 	assert.Equal(t, s.Styles["2"], s.Items[1].Style)
 	assert.Equal(t, s.Styles["3"], s.Items[2].Style)
 	assert.Equal(t, s.Styles["1"], s.Items[3].Style)
 	assert.Equal(t, s.Styles["2"], s.Items[4].Style)
 	assert.Equal(t, s.Styles["3"], s.Items[5].Style)
+	assert.Equal(t, &astisub.Preserved{Format: astisub.FormatSSA, Values: []astisub.PreservedValue{{Key: "Unknown", Value: "Unknown"}}}, s.Preserved)
 
 	// No subtitles to write
 	w := &bytes.Buffer{}
@@ -103,3 +107,271 @@ func TestSSA(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(c), w.String())
 }
+
+func TestSSALibass(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.vtt")
+	assert.NoError(t, err)
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out-libass.ass")
+	assert.NoError(t, err)
+	w := &bytes.Buffer{}
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true})
+	assert.NoError(t, err)
+	assert.Equal(t, string(c), w.String())
+
+	// Overrides
+	s = &astisub.Subtitles{Items: []*astisub.Item{{
+		InlineStyle: &astisub.StyleAttributes{WebVTTAlign: "left", WebVTTLine: "10%", WebVTTPosition: "20%"},
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{InlineStyle: &astisub.StyleAttributes{STLItalics: astiptr.Bool(true), TeletextColor: astisub.ColorYellow}, Text: "1"},
+			{Text: "2"},
+		}}},
+	}}}
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true, PlayResX: 1280, PlayResY: 720})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "PlayResX: 1280\nPlayResY: 720\n")
+	assert.Contains(t, w.String(), "Dialogue: 0,00:00:00.00,00:00:00.00,Default,,0,0,0,,{\\an7\\pos(256,72)}{\\i1\\c&H00ffff&}1{\\r}2\n")
+}
+
+func TestSSAFade(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[Events]
+Format: Start, End, Text
+Dialogue: 0:00:00.00,0:00:01.00,{\fad(200,300)}1
+Dialogue: 0:00:01.00,0:00:02.00,{\i1\fade(255,0,255,0,100,900,1000)}2
+Dialogue: 0:00:02.00,0:00:03.00,{\fade(255,0,128,0,100,900,1000)}3`))
+	assert.NoError(t, err)
+	assert.Equal(t, &astisub.Fade{In: 200 * time.Millisecond, Out: 300 * time.Millisecond}, s.Items[0].InlineStyle.Fade)
+	assert.Equal(t, []astisub.LineItem{{Text: "1"}}, s.Items[0].Lines[0].Items)
+	assert.Equal(t, &astisub.Fade{In: 100 * time.Millisecond, Out: 100 * time.Millisecond}, s.Items[1].InlineStyle.Fade)
+	assert.Equal(t, astiptr.Bool(true), s.Items[1].Lines[0].Items[0].InlineStyle.SSAItalic)
+	assert.Nil(t, s.Items[2].InlineStyle.Fade)
+	assert.Equal(t, "{\\fade(255,0,128,0,100,900,1000)}", s.Items[2].Lines[0].Items[0].InlineStyle.SSAEffect)
+
+	// Fidelity warnings
+	assert.Empty(t, s.FidelityWarnings(astisub.FormatSSA))
+	assert.Equal(t, []astisub.Warning{
+		{Message: "fade of item #1 is not supported by webvtt and will be dropped"},
+		{Message: "fade of item #2 is not supported by webvtt and will be dropped"},
+	}, s.FidelityWarnings(astisub.FormatWebVTT))
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Dialogue: 00:00:00.00,00:00:01.00,{\\fad(200,300)}1\n")
+	assert.Contains(t, w.String(), "Dialogue: 00:00:01.00,00:00:02.00,{\\fad(100,100)}{\\i1}2\n")
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",{\\fad(200,300)}1\n")
+}
+
+func TestSSAOverrides(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[Script Info]
+PlayResX: 384
+PlayResY: 288
+
+[Events]
+Format: Start, End, Text
+Dialogue: 0:00:00.00,0:00:01.00,{\an7\pos(192,144)\b1\c&H0000FF&}1{\i1}2\N3{\r\blur2\u1}4`))
+	assert.NoError(t, err)
+	assert.Equal(t, astiptr.Int(7), s.Items[0].InlineStyle.SSAAlignment)
+	assert.Equal(t, &astisub.SSAPosition{X: 192, Y: 144}, s.Items[0].InlineStyle.SSAPosition)
+	assert.Equal(t, "left", s.Items[0].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "50%", s.Items[0].InlineStyle.WebVTTLine)
+	assert.Equal(t, "50%", s.Items[0].InlineStyle.WebVTTPosition)
+	assert.Len(t, s.Items[0].Lines, 2)
+	var lis = append(s.Items[0].Lines[0].Items, s.Items[0].Lines[1].Items...)
+	assert.Len(t, lis, 4)
+	assert.Equal(t, astiptr.Bool(true), lis[0].InlineStyle.SSABold)
+	assert.Equal(t, &astisub.Color{Red: 255}, lis[0].InlineStyle.SSAPrimaryColour)
+	assert.Nil(t, lis[0].InlineStyle.SSAItalic)
+	assert.Equal(t, astiptr.Bool(true), lis[0].InlineStyle.GenericAttributes(astisub.FormatWebVTT).Bold)
+	assert.Equal(t, astiptr.Bool(true), lis[1].InlineStyle.SSAItalic)
+	assert.Equal(t, "3", lis[2].Text)
+	assert.Equal(t, astiptr.Bool(true), lis[2].InlineStyle.SSABold)
+	assert.Equal(t, astiptr.Bool(true), lis[2].InlineStyle.SSAItalic)
+	assert.Nil(t, lis[3].InlineStyle.SSABold)
+	assert.Equal(t, astiptr.Bool(true), lis[3].InlineStyle.SSAUnderline)
+	assert.Equal(t, "{\\blur2}", lis[3].InlineStyle.SSAEffect)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",{\\a5\\pos(192,144)}{\\b1\\c&H0000ff&}1{\\i1}2\\n3{\\r\\u1\\blur2}4\n")
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:00.000 --> 00:00:01.000 align:left line:50% position:50%\n")
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true, PlayResX: 1280, PlayResY: 720})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",{\\an7\\pos(640,360)}{\\b1\\c&H0000ff&}1{\\r}")
+}
+
+func TestSSAKaraoke(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[Events]
+Format: Start, End, Text
+Dialogue: 0:00:01.00,0:00:05.00,{\k50}Ka{\kf25}ra{\K25}o{\ko100}ke {\k50}song`))
+	assert.NoError(t, err)
+	assert.Equal(t, []astisub.LineItem{
+		{EndAt: 1500 * time.Millisecond, Karaoke: astisub.KaraokeEffectInstant, StartAt: time.Second, Text: "Ka"},
+		{EndAt: 1750 * time.Millisecond, Karaoke: astisub.KaraokeEffectFill, StartAt: 1500 * time.Millisecond, Text: "ra"},
+		{EndAt: 2 * time.Second, Karaoke: astisub.KaraokeEffectFill, StartAt: 1750 * time.Millisecond, Text: "o"},
+		{EndAt: 3 * time.Second, Karaoke: astisub.KaraokeEffectOutline, StartAt: 2 * time.Second, Text: "ke "},
+		{EndAt: 3500 * time.Millisecond, Karaoke: astisub.KaraokeEffectInstant, StartAt: 3 * time.Second, Text: "song"},
+	}, s.Items[0].Lines[0].Items)
+	assert.Len(t, s.Items[0].KaraokeLineItems(), 5)
+	assert.Equal(t, []astisub.Warning{{Message: "karaoke of item #1 is not supported by srt and will be dropped"}}, s.FidelityWarnings(astisub.FormatSRT))
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",{\\k50}Ka{\\kf25}ra{\\kf25}o{\\ko100}ke {\\k50}song\n")
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:00:01.000 --> 00:00:05.000\nKa<00:00:01.500>ra<00:00:01.750>o<00:00:02.000>ke <00:00:03.000>song\n")
+
+	// Gaps between syllables are filled with empty syllables
+	s.Items[0].Lines[0].Items[4].StartAt += 500 * time.Millisecond
+	s.Items[0].Lines[0].Items[4].EndAt += 500 * time.Millisecond
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ",{\\k50}Ka{\\kf25}ra{\\kf25}o{\\ko100}ke {\\k50}{\\k50}song\n")
+}
+
+func TestSSAStyleConversion(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[V4+ Styles]
+Format: Name, PrimaryColour, Bold, Italic, Alignment
+Style: Default,&H0000FFFF,-1,-1,1
+
+[Events]
+Format: Start, End, Style, Text
+Dialogue: 0:00:00.00,0:00:01.00,Default,1`))
+	assert.NoError(t, err)
+
+	// Write to WebVTT
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n<c.yellow><b><i>1</i></b></c>\n")
+
+	// Write to TTML
+	w.Reset()
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `tts:color="#ffff00"`)
+	assert.Contains(t, w.String(), `tts:fontStyle="italic"`)
+	assert.Contains(t, w.String(), `tts:fontWeight="bold"`)
+	assert.Contains(t, w.String(), `tts:textAlign="left"`)
+}
+
+func TestSSAWrapStyle(t *testing.T) {
+	var ass = func(wrapStyle string) string {
+		return "[Script Info]\nScriptType: v4.00+\nWrapStyle: " + wrapStyle + "\n\n[Events]\nFormat: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\nDialogue: 0,0:00:01.00,0:00:02.00,,,0,0,0,,First\\nsecond\\Nthird\n"
+	}
+	var lines = func(s *astisub.Subtitles) (ls []string) {
+		for _, l := range s.Items[0].Lines {
+			ls = append(ls, l.String())
+		}
+		return
+	}
+
+	// Soft line breaks are white spaces
+	s, err := astisub.ReadFromSSA(strings.NewReader(ass("0")))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"First second", "third"}, lines(s))
+	assert.Equal(t, astisub.LineBreakPolicyTopWider, s.Metadata.LineBreakPolicy)
+
+	// Soft line breaks are hard line breaks
+	s, err = astisub.ReadFromSSA(strings.NewReader(ass("2")))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"First", "second", "third"}, lines(s))
+	assert.Equal(t, astisub.LineBreakPolicyNone, s.Metadata.LineBreakPolicy)
+
+	// Write
+	s.Metadata.SSAWrapStyle = ""
+	s.Metadata.LineBreakPolicy = astisub.LineBreakPolicyBottomWider
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "WrapStyle: 3\n")
+	assert.Contains(t, w.String(), "First\\Nsecond\\Nthird\n")
+
+	// Wrap
+	s = &astisub.Subtitles{
+		Items:    []*astisub.Item{{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "aa bb cc dd ee"}}}}}},
+		Metadata: &astisub.Metadata{LineBreakPolicy: astisub.LineBreakPolicyBottomWider},
+	}
+	s.Wrap(10, 2)
+	assert.Equal(t, []string{"aa bb", "cc dd ee"}, lines(s))
+	s.Metadata.LineBreakPolicy = astisub.LineBreakPolicyTopWider
+	s.Wrap(10, 2)
+	assert.Equal(t, []string{"aa bb cc", "dd ee"}, lines(s))
+	s.Items[0].Lines = []astisub.Line{{Items: []astisub.LineItem{{Text: "aa bb cc dd ee ff"}}}}
+	s.Wrap(12, 2)
+	assert.Equal(t, []string{"aa bb cc", "dd ee ff"}, lines(s))
+	s.Metadata.LineBreakPolicy = astisub.LineBreakPolicyEndOfLine
+	s.Wrap(12, 2)
+	assert.Equal(t, []string{"aa bb cc dd", "ee ff"}, lines(s))
+}
+
+func TestSSALegacyAlignment(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromSSA(strings.NewReader(`[Script Info]
+ScriptType: v4.00
+PlayResX: 400
+PlayResY: 300
+
+[V4 Styles]
+Format: Name, Alignment
+Style: Default,10
+Style: Invalid,0
+Style: Top,6
+
+[Events]
+Format: Start, End, Style, Text
+Dialogue: 0:00:00.00,0:00:01.00,Default,{\pos(10,10)}1
+Dialogue: 0:00:01.00,0:00:02.00,Invalid,{\pos(10,10)}2
+Dialogue: 0:00:02.00,0:00:03.00,Default,{\a9}3`))
+	assert.NoError(t, err)
+	assert.Equal(t, astiptr.Int(5), s.Styles["Default"].InlineStyle.SSAAlignment)
+	assert.Nil(t, s.Styles["Invalid"].InlineStyle.SSAAlignment)
+	assert.Equal(t, astiptr.Int(8), s.Styles["Top"].InlineStyle.SSAAlignment)
+	assert.Equal(t, "center", s.Items[0].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "3.33%,center", s.Items[0].InlineStyle.WebVTTLine)
+	assert.Equal(t, "center", s.Items[1].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "3.33%,end", s.Items[1].InlineStyle.WebVTTLine)
+	assert.Equal(t, astiptr.Int(4), s.Items[2].InlineStyle.SSAAlignment)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToSSA(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "Style: Default,10\n")
+	assert.Contains(t, w.String(), "Style: Top,6\n")
+	assert.Contains(t, w.String(), ",{\\a9}3\n")
+	w.Reset()
+	err = s.WriteToSSAWithOptions(w, astisub.SSAOptions{Libass: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "{\\an4}3\n")
+}
+
+func TestMetadataSSAPlayRes(t *testing.T) {
+	var m *astisub.Metadata
+	x, y := m.SSAPlayRes()
+	assert.Equal(t, []int{384, 288}, []int{x, y})
+	x, y = (&astisub.Metadata{SSAPlayResY: astiptr.Int(720)}).SSAPlayRes()
+	assert.Equal(t, []int{960, 720}, []int{x, y})
+}
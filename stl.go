@@ -5,15 +5,17 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"math"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/asticode/go-astitools/byte"
 	"github.com/asticode/go-astitools/map"
 	"github.com/asticode/go-astitools/ptr"
 	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -90,9 +92,40 @@ var (
 			Set(0xf4, "ħ").Set(0xf5, "ı").Set(0xf6, "ĳ").Set(0xf7, "ŀ").
 			Set(0xf8, "ł").Set(0xf9, "ø").Set(0xfa, "œ").Set(0xfb, "ß").
 			Set(0xfc, "þ").Set(0xfd, "ŧ").Set(0xfe, "ŋ").Set(0xff, string([]byte{0xC2, 0xAD})),
+		stlCharacterCodeTableNumberLatinArabic:   newSTLISO8859CharacterCodeTable(charmap.ISO8859_6),
+		stlCharacterCodeTableNumberLatinCyrillic: newSTLISO8859CharacterCodeTable(charmap.ISO8859_5),
+		stlCharacterCodeTableNumberLatinGreek:    newSTLISO8859CharacterCodeTable(charmap.ISO8859_7),
+		stlCharacterCodeTableNumberLatinHebrew:   newSTLISO8859CharacterCodeTable(charmap.ISO8859_8),
 	}
 )
 
+// newSTLISO8859CharacterCodeTable builds a character code table whose characters are the ones of an ISO 8859 part,
+// control codes excluded
+func newSTLISO8859CharacterCodeTable(c *charmap.Charmap) (m *astimap.Map) {
+	m = astimap.NewMap(0x0, "")
+	for k := 0x20; k <= 0xff; k++ {
+		if k >= 0x7f && k < 0xa0 {
+			continue
+		}
+		if r := c.DecodeByte(byte(k)); r != utf8.RuneError {
+			m.Set(k, string(r))
+		}
+	}
+	return
+}
+
+// STLCharacterCodeTable represents the character code table written in the GSI block
+type STLCharacterCodeTable uint16
+
+// STL character code tables
+const (
+	STLCharacterCodeTableLatin         = STLCharacterCodeTable(stlCharacterCodeTableNumberLatin)
+	STLCharacterCodeTableLatinArabic   = STLCharacterCodeTable(stlCharacterCodeTableNumberLatinArabic)
+	STLCharacterCodeTableLatinCyrillic = STLCharacterCodeTable(stlCharacterCodeTableNumberLatinCyrillic)
+	STLCharacterCodeTableLatinGreek    = STLCharacterCodeTable(stlCharacterCodeTableNumberLatinGreek)
+	STLCharacterCodeTableLatinHebrew   = STLCharacterCodeTable(stlCharacterCodeTableNumberLatinHebrew)
+)
+
 // STL code page numbers
 const (
 	stlCodePageNumberCanadaFrench uint32 = 3683891
@@ -128,6 +161,16 @@ const (
 	stlDisplayStandardCodeLevel2Teletext = "2"
 )
 
+// STLDisplayStandard represents the display standard code written in the GSI block
+type STLDisplayStandard string
+
+// STL display standards
+const (
+	STLDisplayStandardLevel1Teletext STLDisplayStandard = stlDisplayStandardCodeLevel1Teletext
+	STLDisplayStandardLevel2Teletext STLDisplayStandard = stlDisplayStandardCodeLevel2Teletext
+	STLDisplayStandardOpenSubtitling STLDisplayStandard = stlDisplayStandardCodeOpenSubtitling
+)
+
 // STL framerate mapping
 var stlFramerateMapping = astimap.NewMap("STL25.01", 25).
 	Set("STL25.01", 25).
@@ -160,8 +203,19 @@ const (
 // TTI Special Extension Block Number
 const extensionBlockNumberReservedUserData = 0xfe
 
+// STL preserved keys
+const (
+	stlPreservedKeyUserData        = "user data"
+	stlPreservedKeyUserDefinedArea = "user defined area"
+)
+
 // ReadFromSTL parses an .stl content
 func ReadFromSTL(i io.Reader) (o *Subtitles, err error) {
+	return readFromSTL(i, nil)
+}
+
+// readFromSTL parses an .stl content, calling a hook for each item once it's complete
+func readFromSTL(i io.Reader, h ItemHook) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
 
@@ -193,8 +247,13 @@ func ReadFromSTL(i io.Reader) (o *Subtitles, err error) {
 		STLPublisher: g.publisher,
 		Title:        g.originalProgramTitle,
 	}
+	if len(g.userDefinedArea) > 0 {
+		addPreserved(&o.Preserved, FormatSTL, stlPreservedKeyUserDefinedArea, g.userDefinedArea)
+	}
 
 	// Parse Text and Timing Information (TTI) blocks.
+	var comments, userData []string
+	var hk = &itemHooker{h: h}
 	for {
 		// Read TTI block
 		if b, err = readNBytes(i, stlBlockSizeTTI); err != nil {
@@ -210,6 +269,12 @@ func ReadFromSTL(i io.Reader) (o *Subtitles, err error) {
 
 		if t.extensionBlockNumber != extensionBlockNumberReservedUserData {
 
+			// Comments are attached to the next item
+			if t.commentFlag == stlCommentFlagTextContainsCommentsNotIntendedForTransmission {
+				comments = append(comments, parseSTLComments(ch, t.text)...)
+				continue
+			}
+
 			// Create item
 			var i = &Item{
 				EndAt:   t.timecodeOut - g.timecodeStartOfProgramme,
@@ -221,11 +286,48 @@ func ReadFromSTL(i io.Reader) (o *Subtitles, err error) {
 				parseTeletextRow(i, ch, func() styler { return newSTLStyler() }, text)
 			}
 
+			// Add comments
+			i.Comments = comments
+			comments = nil
+
+			// User data is attached to the next item
+			for _, d := range userData {
+				addPreserved(&i.Preserved, FormatSTL, stlPreservedKeyUserData, d)
+			}
+			userData = nil
+
 			// Append item
 			o.Items = append(o.Items, i)
+			if err = hk.hook(o.Items); err != nil {
+				return
+			}
+		} else {
+			userData = append(userData, string(t.text))
 		}
 
 	}
+
+	// Comments located after the last item are added to the metadata
+	o.Metadata.Comments = comments
+
+	// User data located after the last item is added to the subtitles
+	for _, d := range userData {
+		addPreserved(&o.Preserved, FormatSTL, stlPreservedKeyUserData, d)
+	}
+	return
+}
+
+// parseSTLComments parses the text field of a comment TTI block
+func parseSTLComments(ch *stlCharacterHandler, text []byte) (cs []string) {
+	for _, row := range bytes.Split(text, []byte{0x8a}) {
+		var b []byte
+		for _, v := range row {
+			b = append(b, ch.decode(v)...)
+		}
+		if c := strings.TrimSpace(string(b)); len(c) > 0 {
+			cs = append(cs, c)
+		}
+	}
 	return
 }
 
@@ -300,7 +402,8 @@ func newGSIBlock(s Subtitles) (g *gsiBlock) {
 		totalNumberOfDisks:                               1,
 		totalNumberOfSubtitleGroups:                      1,
 		totalNumberOfSubtitles:                           len(s.Items),
-		totalNumberOfTTIBlocks:                           len(s.Items),
+		totalNumberOfTTIBlocks:                           len(s.Items) + numberOfComments(s.Items) + numberOfUserDataBlocks(s),
+		userDefinedArea:                                  strings.Join(s.Preserved.valuesByKey(FormatSTL, stlPreservedKeyUserDefinedArea), ""),
 	}
 
 	// Add metadata
@@ -474,7 +577,8 @@ func (b gsiBlock) bytes() (o []byte) {
 	o = append(o, astibyte.ToLength([]byte(b.publisher), ' ', 32)...)                                                                               // Publisher
 	o = append(o, astibyte.ToLength([]byte(b.editorName), ' ', 32)...)                                                                              // Editor's name
 	o = append(o, astibyte.ToLength([]byte(b.editorContactDetails), ' ', 32)...)                                                                    // Editor's contact details
-	o = append(o, astibyte.ToLength([]byte{}, ' ', 75+576)...)                                                                                      // Spare bytes + user defined area                                                                                           //                                                                                                                      // Editor's contact details
+	o = append(o, astibyte.ToLength([]byte{}, ' ', 75)...)                                                                                          // Spare bytes
+	o = append(o, astibyte.ToLength([]byte(b.userDefinedArea), ' ', 576)...)                                                                        // User defined area
 	return
 }
 
@@ -509,43 +613,14 @@ func parseDurationSTL(i string, framerate int) (d time.Duration, err error) {
 	}
 
 	// Set duration
-	d = time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute + time.Duration(seconds)*time.Second + time.Duration(1e9*frames/framerate)*time.Nanosecond
+	d = newTimecodeFromComponents(hours, minutes, seconds, frames, float64(framerate), false).Duration()
 	return
 }
 
 // formatDurationSTL formats a STL duration
-func formatDurationSTL(d time.Duration, framerate int) (o string) {
-	// Add hours
-	if d.Hours() < 10 {
-		o += "0"
-	}
-	var delta = int(math.Floor(d.Hours()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Hour
-
-	// Add minutes
-	if d.Minutes() < 10 {
-		o += "0"
-	}
-	delta = int(math.Floor(d.Minutes()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Minute
-
-	// Add seconds
-	if d.Seconds() < 10 {
-		o += "0"
-	}
-	delta = int(math.Floor(d.Seconds()))
-	o += strconv.Itoa(delta)
-	d -= time.Duration(delta) * time.Second
-
-	// Add frames
-	var frames = int(int(d.Nanoseconds()) * framerate / 1e9)
-	if frames < 10 {
-		o += "0"
-	}
-	o += strconv.Itoa(frames)
-	return
+func formatDurationSTL(d time.Duration, framerate int) string {
+	var h, m, sec, f = NewTimecode(d, float64(framerate)).components()
+	return fmt.Sprintf("%.2d%.2d%.2d%.2d", h, m, sec, f)
 }
 
 // ttiBlock represents a TTI block
@@ -562,6 +637,23 @@ type ttiBlock struct {
 	verticalPosition     int
 }
 
+// numberOfComments returns the total number of comments of items
+func numberOfComments(is []*Item) (n int) {
+	for _, i := range is {
+		n += len(i.Comments)
+	}
+	return
+}
+
+// numberOfUserDataBlocks returns the total number of user data blocks of subtitles
+func numberOfUserDataBlocks(s Subtitles) (n int) {
+	n = len(s.Preserved.valuesByKey(FormatSTL, stlPreservedKeyUserData))
+	for _, i := range s.Items {
+		n += len(i.Preserved.valuesByKey(FormatSTL, stlPreservedKeyUserData))
+	}
+	return
+}
+
 // newTTIBlock builds an item TTI block
 func newTTIBlock(i *Item, idx int) (t *ttiBlock) {
 	// Init
@@ -586,6 +678,22 @@ func newTTIBlock(i *Item, idx int) (t *ttiBlock) {
 	return
 }
 
+// newCommentTTIBlock builds a TTI block containing a comment not intended for transmission
+func newCommentTTIBlock(i *Item, idx int, comment string) (t *ttiBlock) {
+	t = newTTIBlock(i, idx)
+	t.commentFlag = stlCommentFlagTextContainsCommentsNotIntendedForTransmission
+	t.text = []byte(comment)
+	return
+}
+
+// newUserDataTTIBlock builds a TTI block containing user data
+func newUserDataTTIBlock(i *Item, idx int, data string) (t *ttiBlock) {
+	t = newTTIBlock(i, idx)
+	t.extensionBlockNumber = extensionBlockNumberReservedUserData
+	t.text = []byte(data)
+	return
+}
+
 // parseTTIBlock parses a TTI block
 func parseTTIBlock(p []byte, framerate int) *ttiBlock {
 	return &ttiBlock{
@@ -607,44 +715,33 @@ func (t *ttiBlock) bytes(g *gsiBlock) (o []byte) {
 	o = append(o, byte(uint8(t.subtitleGroupNumber))) // Subtitle group number
 	var b = make([]byte, 2)
 	binary.LittleEndian.PutUint16(b, uint16(t.subtitleNumber))
-	o = append(o, b...)                                                             // Subtitle number
-	o = append(o, byte(uint8(t.extensionBlockNumber)))                              // Extension block number
-	o = append(o, t.cumulativeStatus)                                               // Cumulative status
-	o = append(o, formatDurationSTLBytes(t.timecodeIn, g.framerate)...)             // Timecode in
-	o = append(o, formatDurationSTLBytes(t.timecodeOut, g.framerate)...)            // Timecode out
-	o = append(o, byte(uint8(t.verticalPosition)))                                  // Vertical position
-	o = append(o, t.justificationCode)                                              // Justification code
-	o = append(o, t.commentFlag)                                                    // Comment flag
-	o = append(o, astibyte.ToLength(encodeTextSTL(string(t.text)), '\x8f', 112)...) // Text field
+	o = append(o, b...)                                                  // Subtitle number
+	o = append(o, byte(uint8(t.extensionBlockNumber)))                   // Extension block number
+	o = append(o, t.cumulativeStatus)                                    // Cumulative status
+	o = append(o, formatDurationSTLBytes(t.timecodeIn, g.framerate)...)  // Timecode in
+	o = append(o, formatDurationSTLBytes(t.timecodeOut, g.framerate)...) // Timecode out
+	o = append(o, byte(uint8(t.verticalPosition)))                       // Vertical position
+	o = append(o, t.justificationCode)                                   // Justification code
+	o = append(o, t.commentFlag)                                         // Comment flag
+
+	// User data is written as is
+	if t.extensionBlockNumber == extensionBlockNumberReservedUserData {
+		o = append(o, astibyte.ToLength(t.text, '\x8f', 112)...) // Text field
+		return
+	}
+	o = append(o, astibyte.ToLength(encodeTextSTL(string(t.text), g.characterCodeTableNumber), '\x8f', 112)...) // Text field
 	return
 }
 
 // formatDurationSTLBytes formats a STL duration in bytes
-func formatDurationSTLBytes(d time.Duration, framerate int) (o []byte) {
-	// Add hours
-	var hours = int(math.Floor(d.Hours()))
-	o = append(o, byte(uint8(hours)))
-	d -= time.Duration(hours) * time.Hour
-
-	// Add minutes
-	var minutes = int(math.Floor(d.Minutes()))
-	o = append(o, byte(uint8(minutes)))
-	d -= time.Duration(minutes) * time.Minute
-
-	// Add seconds
-	var seconds = int(math.Floor(d.Seconds()))
-	o = append(o, byte(uint8(seconds)))
-	d -= time.Duration(seconds) * time.Second
-
-	// Add frames
-	var frames = int(int(d.Nanoseconds()) * framerate / 1e9)
-	o = append(o, byte(uint8(frames)))
-	return
+func formatDurationSTLBytes(d time.Duration, framerate int) []byte {
+	var h, m, sec, f = NewTimecode(d, float64(framerate)).components()
+	return []byte{byte(uint8(h)), byte(uint8(m)), byte(uint8(sec)), byte(uint8(f))}
 }
 
 // parseDurationSTLBytes parses a STL duration in bytes
 func parseDurationSTLBytes(b []byte, framerate int) time.Duration {
-	return time.Duration(uint8(b[0]))*time.Hour + time.Duration(uint8(b[1]))*time.Minute + time.Duration(uint8(b[2]))*time.Second + time.Duration(1e9*int(uint8(b[3]))/framerate)*time.Nanosecond
+	return newTimecodeFromComponents(int(uint8(b[0])), int(uint8(b[1])), int(uint8(b[2])), int(uint8(b[3])), float64(framerate), false).Duration()
 }
 
 type stlCharacterHandler struct {
@@ -737,28 +834,144 @@ func (s *stlStyler) update(sa *StyleAttributes) {
 	}
 }
 
+// STLOptions represents STL options
+type STLOptions struct {
+	// Character code table of the GSI block, used to encode texts. Defaults to STLCharacterCodeTableLatin.
+	CharacterCodeTable STLCharacterCodeTable
+	// 3-letter code of the country of origin of the GSI block. Defaults to "FRA".
+	CountryOfOrigin string
+	// Display standard code of the GSI block. Defaults to STLDisplayStandardLevel1Teletext.
+	DisplayStandard STLDisplayStandard
+	// Cue times are rounded to frame boundaries when set
+	FrameRounding FrameRounding
+	// Replaces characters that can't be represented when the glyph policy requires it. Defaults to "?".
+	GlyphPlaceholder string
+	// Policy applied to characters the character code table can't represent, such as emojis. Defaults to
+	// GlyphPolicyTransliterate.
+	GlyphPolicy GlyphPolicy
+	// Policy applied to times exceeding 23:59:59:FF, the maximum timecode of the format. Times are written as is by
+	// default.
+	HourPolicy HourPolicy
+	// Items exceeding limits are wrapped and split instead of being written as is, since decoders truncate them.
+	// Defaults to the limits of TargetBroadcast.
+	Limits Limits
+	// Timecode start of programme of the GSI block. It is added to cue times, which is the way readers expect them.
+	TimecodeStartOfProgramme time.Duration
+}
+
 // WriteToSTL writes subtitles in .stl format
-func (s Subtitles) WriteToSTL(o io.Writer) (err error) {
+func (s Subtitles) WriteToSTL(o io.Writer) error {
+	return s.WriteToSTLWithOptions(o, STLOptions{})
+}
+
+// WriteToSTLWithOptions writes subtitles in .stl format with options
+func (s Subtitles) WriteToSTLWithOptions(o io.Writer, opts STLOptions) error {
+	return s.writeToSTL(o, opts, nil)
+}
+
+// writeToSTL writes subtitles in .stl format with options, calling a hook for each item right before it's written.
+// TTI blocks are built before the GSI block is written since the number of blocks depends on the hooked items.
+func (s Subtitles) writeToSTL(o io.Writer, opts STLOptions, h ItemHook) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
 		return
 	}
 
-	// Write GSI block
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Apply limits
+	if opts.Limits == (Limits{}) {
+		opts.Limits = TargetFromFormat(FormatSTL).Limits()
+	}
+	s.Items = limitItems(s.Items, opts.Limits)
+
+	// Get character code table
+	var characterCodeTable = stlCharacterCodeTableNumberLatin
+	if opts.CharacterCodeTable > 0 {
+		characterCodeTable = uint16(opts.CharacterCodeTable)
+	}
+
+	// Apply glyph policy
+	var representable func(r rune) bool
+	if representable, err = stlRepresentable(characterCodeTable); err != nil {
+		return
+	}
+	if s, err = s.applyGlyphPolicy(representable, opts.GlyphPolicy, opts.GlyphPlaceholder); err != nil {
+		err = errors.Wrap(err, "astisub: applying glyph policy failed")
+		return
+	}
+
+	// Offset times by the timecode start of programme
+	s = s.offsetSTLTimes(opts.TimecodeStartOfProgramme)
+
+	// Build GSI block
 	var g = newGSIBlock(s)
-	if _, err = o.Write(g.bytes()); err != nil {
-		err = errors.Wrap(err, "astisub: writing gsi block failed")
+	g.characterCodeTableNumber = characterCodeTable
+	g.timecodeStartOfProgramme = opts.TimecodeStartOfProgramme
+	if len(opts.CountryOfOrigin) > 0 {
+		g.countryOfOrigin = opts.CountryOfOrigin
+	}
+	if len(opts.DisplayStandard) > 0 {
+		g.displayStandardCode = string(opts.DisplayStandard)
+	}
+
+	// Apply hour policy
+	var bound, precision = timeBounds(FormatSTL, g.framerate)
+	if s, err = s.applyHourPolicy(bound, precision, opts.HourPolicy); err != nil {
+		err = errors.Wrap(err, "astisub: applying hour policy failed")
 		return
 	}
 
 	// Loop through items
+	var b []byte
+	var last *Item
+	var ttiBlocks int
 	for idx, item := range s.Items {
-		// Write tti block
-		if _, err = o.Write(newTTIBlock(item, idx+1).bytes(g)); err != nil {
-			err = errors.Wrapf(err, "astisub: writing tti block #%d failed", idx+1)
+		// Hook
+		if item, err = hookedItem(item, idx, h); err != nil {
 			return
 		}
+		if idx == 0 {
+			g.timecodeFirstInCue = item.StartAt
+		}
+		last = item
+
+		// Add user data tti blocks
+		for _, d := range item.Preserved.valuesByKey(FormatSTL, stlPreservedKeyUserData) {
+			b = append(b, newUserDataTTIBlock(item, idx+1, d).bytes(g)...)
+			ttiBlocks++
+		}
+
+		// Add comment tti blocks
+		for _, c := range item.Comments {
+			b = append(b, newCommentTTIBlock(item, idx+1, c).bytes(g)...)
+			ttiBlocks++
+		}
+
+		// Add tti block
+		b = append(b, newTTIBlock(item, idx+1).bytes(g)...)
+		ttiBlocks++
+	}
+
+	// Add trailing user data tti blocks
+	for _, d := range s.Preserved.valuesByKey(FormatSTL, stlPreservedKeyUserData) {
+		b = append(b, newUserDataTTIBlock(last, len(s.Items), d).bytes(g)...)
+		ttiBlocks++
+	}
+
+	// Write GSI block
+	g.totalNumberOfTTIBlocks = ttiBlocks
+	if _, err = o.Write(g.bytes()); err != nil {
+		err = errors.Wrap(err, "astisub: writing gsi block failed")
+		return
+	}
+
+	// Write tti blocks
+	if _, err = o.Write(b); err != nil {
+		err = errors.Wrap(err, "astisub: writing tti blocks failed")
+		return
 	}
 	return
 }
@@ -784,6 +997,7 @@ var stlUnicodeDiacritic = astimap.NewMap(byte('\x00'), "\x00").
 // STL unicode mapping
 var stlUnicodeMapping = astimap.NewMap(byte('\x00'), "\x00").
 	Set(byte('\x8a'), "\u000a"). // Line break
+	Set(byte('\xa4'), "\u0024"). // $
 	Set(byte('\xa8'), "\u00a4"). // ¤
 	Set(byte('\xa9'), "\u2018"). // ‘
 	Set(byte('\xaa'), "\u201C"). // “
@@ -844,8 +1058,70 @@ var stlUnicodeMapping = astimap.NewMap(byte('\x00'), "\x00").
 	Set(byte('\xfe'), "\u014B"). // ŋ
 	Set(byte('\xff'), "\u00AD")  // Soft hyphen
 
-// encodeTextSTL encodes the STL text
-func encodeTextSTL(i string) (o []byte) {
+// offsetSTLTimes returns subtitles whose times have been offset. Items are copied before being modified so that the
+// input subtitles are left untouched.
+func (s Subtitles) offsetSTLTimes(d time.Duration) (o Subtitles) {
+	// Nothing to offset
+	o = s
+	if d == 0 {
+		return
+	}
+
+	// Loop through items
+	o.Items = make([]*Item, 0, len(s.Items))
+	for _, i := range s.Items {
+		var c = *i
+		c.StartAt += d
+		c.EndAt += d
+		o.Items = append(o.Items, &c)
+	}
+	return
+}
+
+// stlRepresentable returns a function checking whether a character is written properly, i.e. whether it's read back
+// as is using a character code table
+func stlRepresentable(characterCodeTable uint16) (fn func(r rune) bool, err error) {
+	// Create character handler
+	var h *stlCharacterHandler
+	if h, err = newSTLCharacterHandler(characterCodeTable); err != nil {
+		err = errors.Wrap(err, "astisub: creating stl character handler failed")
+		return
+	}
+
+	// Create function
+	fn = func(r rune) bool {
+		// Diacritics are only written along with the character they're applied to
+		if unicode.Is(unicode.Mn, r) {
+			return false
+		}
+
+		// Encode and decode
+		var o []byte
+		h.accent = ""
+		for _, b := range encodeTextSTL(string(r), characterCodeTable) {
+			o = append(o, h.decode(b)...)
+		}
+		return string(o) == string(r)
+	}
+	return
+}
+
+// encodeTextSTL encodes the STL text using a character code table
+func encodeTextSTL(i string, characterCodeTable uint16) (o []byte) {
+	// Tables other than the latin one map characters to bytes
+	if characterCodeTable != stlCharacterCodeTableNumberLatin {
+		var m = stlCharacterCodeTables[characterCodeTable]
+		for _, c := range norm.NFC.String(i) {
+			if c == '\n' {
+				o = append(o, 0x8a)
+			} else if m != nil && m.InB(string(c)) {
+				o = append(o, byte(m.A(string(c)).(int)))
+			}
+		}
+		return
+	}
+
+	// Latin
 	i = string(norm.NFD.Bytes([]byte(i)))
 	for _, c := range i {
 		if stlUnicodeMapping.InB(string(c)) {
@@ -36,6 +36,18 @@ func TestSTLCharacterHandler(t *testing.T) {
 	assert.Equal(t, []byte("è"), o)
 }
 
+func TestSTLCharacterHandlerCyrillic(t *testing.T) {
+	h, err := newSTLCharacterHandler(stlCharacterCodeTableNumberLatinCyrillic)
+	assert.NoError(t, err)
+	o := h.decode(0x65)
+	assert.Equal(t, []byte("e"), o)
+	o = h.decode(0xbf)
+	assert.Equal(t, []byte("П"), o)
+	o = h.decode(0xc1)
+	assert.Equal(t, []byte("С"), o)
+	assert.Equal(t, []byte{0xbf, 0xef, 0x8a, 'a'}, encodeTextSTL("Пя\na", stlCharacterCodeTableNumberLatinCyrillic))
+}
+
 func TestSTLCharacterHandlerUmlaut(t *testing.T) {
 	h, err := newSTLCharacterHandler(stlCharacterCodeTableNumberLatin)
 	assert.NoError(t, err)
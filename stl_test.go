@@ -37,3 +37,74 @@ func TestSTL(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(c), w.String())
 }
+
+func TestSTLPreserved(t *testing.T) {
+	// Write
+	var s = &astisub.Subtitles{
+		Items: []*astisub.Item{{
+			EndAt:     2 * time.Second,
+			Lines:     []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}},
+			Preserved: &astisub.Preserved{Format: astisub.FormatSTL, Values: []astisub.PreservedValue{{Key: "user data", Value: "item"}}},
+			StartAt:   time.Second,
+		}},
+		Metadata:  &astisub.Metadata{Framerate: 25, Language: astisub.LanguageFrench},
+		Preserved: &astisub.Preserved{Format: astisub.FormatSTL, Values: []astisub.PreservedValue{{Key: "user defined area", Value: "uda"}, {Key: "user data", Value: "trailing"}}},
+	}
+	w := &bytes.Buffer{}
+	err := s.WriteToSTL(w)
+	assert.NoError(t, err)
+
+	// Read
+	s2, err := astisub.ReadFromSTL(w)
+	assert.NoError(t, err)
+	assert.Len(t, s2.Items, 1)
+	assert.Equal(t, &astisub.Preserved{Format: astisub.FormatSTL, Values: []astisub.PreservedValue{{Key: "user data", Value: "item" + string(bytes.Repeat([]byte{0x8f}, 108))}}}, s2.Items[0].Preserved)
+	assert.Equal(t, &astisub.Preserved{Format: astisub.FormatSTL, Values: []astisub.PreservedValue{{Key: "user defined area", Value: "uda"}, {Key: "user data", Value: "trailing" + string(bytes.Repeat([]byte{0x8f}, 104))}}}, s2.Preserved)
+}
+
+func TestSTLGSIOptions(t *testing.T) {
+	// Write
+	var s = &astisub.Subtitles{
+		Items: []*astisub.Item{{
+			EndAt:   2 * time.Second,
+			Lines:   []astisub.Line{{Items: []astisub.LineItem{{Text: "test"}}}},
+			StartAt: time.Second,
+		}},
+		Metadata: &astisub.Metadata{Framerate: 25, Language: astisub.LanguageFrench},
+	}
+	w := &bytes.Buffer{}
+	err := s.WriteToSTLWithOptions(w, astisub.STLOptions{
+		CharacterCodeTable:       astisub.STLCharacterCodeTableLatinCyrillic,
+		CountryOfOrigin:          "CHE",
+		DisplayStandard:          astisub.STLDisplayStandardOpenSubtitling,
+		TimecodeStartOfProgramme: 10 * time.Hour,
+	})
+	assert.NoError(t, err)
+	var b = w.Bytes()
+	assert.Equal(t, "0", string(b[11]))
+	assert.Equal(t, "01", string(b[12:14]))
+	assert.Equal(t, "1000000010000100", string(b[256:272]))
+	assert.Equal(t, "CHE", string(b[274:277]))
+	assert.Equal(t, []byte{10, 0, 1, 0, 10, 0, 2, 0}, b[1024+5:1024+13])
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+
+	// Timecode start of programme is removed when reading
+	w.Reset()
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{TimecodeStartOfProgramme: 10 * time.Hour})
+	assert.NoError(t, err)
+	s2, err := astisub.ReadFromSTL(w)
+	assert.NoError(t, err)
+	assert.Len(t, s2.Items, 1)
+	assert.Equal(t, time.Second, s2.Items[0].StartAt)
+	assert.Equal(t, 2*time.Second, s2.Items[0].EndAt)
+
+	// Texts are encoded using the character code table
+	s.Items[0].Lines = []astisub.Line{{Items: []astisub.LineItem{{Text: "Привет"}}}, {Items: []astisub.LineItem{{Text: "мир!"}}}}
+	w.Reset()
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{CharacterCodeTable: astisub.STLCharacterCodeTableLatinCyrillic})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\xbf\xe0\xd8\xd2\xd5\xe2\x8a\xdc\xd8\xe0!\x8f")
+	w.Reset()
+	err = s.WriteToSTLWithOptions(w, astisub.STLOptions{CharacterCodeTable: 12345})
+	assert.Error(t, err)
+}
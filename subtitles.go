@@ -1,14 +1,19 @@
 package astisub
 
 import (
+	"bytes"
 	"fmt"
+	"image"
+	"io"
 	"math"
-	"os"
-	"path/filepath"
+	"reflect"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
+	"github.com/asticode/go-astitools/ptr"
 	"github.com/pkg/errors"
 )
 
@@ -41,7 +46,9 @@ var (
 
 // Errors
 var (
+	ErrInvalidAnchors     = errors.New("astisub: invalid anchors")
 	ErrInvalidExtension   = errors.New("astisub: invalid extension")
+	ErrInvalidFormat      = errors.New("astisub: invalid format")
 	ErrNoSubtitlesToWrite = errors.New("astisub: no subtitles to write")
 )
 
@@ -52,51 +59,316 @@ var Now = func() time.Time {
 
 // Options represents open or write options
 type Options struct {
+	AfterReadItem ItemHook // Called for each item once it has been read
+	// Only used when writing. Called for each item on a copy of it right before it's written, i.e. once the item has
+	// been transformed by the other options.
+	BeforeWriteItem ItemHook
+	// Only used when reading text formats other than TTML, whose charset is declared by its XML declaration. Contents
+	// are converted to UTF-8 before being parsed, their charset being detected if empty.
+	Charset  Charset
+	CueText  CueTextOptions // Only used when writing
 	Filename string
+	// Only used when writing. Replaces characters that can't be represented when the glyph policy requires it.
+	// Defaults to "?".
+	GlyphPlaceholder string
+	// Only used when writing STL, or text formats converted to WriteCharset. Policy applied to characters the target
+	// can't represent, such as emojis. Defaults to GlyphPolicyTransliterate.
+	GlyphPolicy GlyphPolicy
+	// Only used when writing. Items exceeding limits are wrapped and split before being written.
+	Limits   Limits
+	MicroDVD MicroDVDOptions
 	Teletext TeletextOptions
+	TTML     TTMLOptions
+	// Only used when writing text formats. If either WriteBOM or WriteCharset is set, contents are converted to
+	// WriteCharset, which defaults to UTF-8, and start with the BOM of the charset if and only if WriteBOM is true.
+	WriteBOM     bool
+	WriteCharset Charset
 }
 
-// Open opens a subtitle reader based on options
-func Open(o Options) (s *Subtitles, err error) {
-	// Open the file
-	var f *os.File
-	if f, err = os.Open(o.Filename); err != nil {
-		err = errors.Wrapf(err, "astisub: opening %s failed", o.Filename)
-		return
-	}
-	defer f.Close()
+// Format represents a subtitle format
+type Format string
+
+// Formats
+const (
+	FormatJSON     Format = "json"
+	FormatMicroDVD Format = "microdvd"
+	FormatSBV      Format = "sbv"
+	FormatSCC      Format = "scc"
+	FormatSRT      Format = "srt"
+	FormatSSA      Format = "ssa"
+	FormatSTL      Format = "stl"
+	FormatTeletext Format = "teletext"
+	FormatTTML     Format = "ttml"
+	FormatWebVTT   Format = "webvtt"
+)
 
-	// Parse the content
-	switch filepath.Ext(o.Filename) {
+// FormatFromExtension returns the format matching an extension such as ".srt"
+func FormatFromExtension(ext string) (f Format, err error) {
+	switch strings.ToLower(ext) {
+	case ".json":
+		f = FormatJSON
+	case ".sub":
+		f = FormatMicroDVD
+	case ".sbv":
+		f = FormatSBV
+	case ".scc":
+		f = FormatSCC
 	case ".srt":
-		s, err = ReadFromSRT(f)
+		f = FormatSRT
 	case ".ssa", ".ass":
-		s, err = ReadFromSSA(f)
+		f = FormatSSA
 	case ".stl":
-		s, err = ReadFromSTL(f)
+		f = FormatSTL
 	case ".ts":
-		s, err = ReadFromTeletext(f, o.Teletext)
+		f = FormatTeletext
 	case ".ttml":
-		s, err = ReadFromTTML(f)
+		f = FormatTTML
 	case ".vtt":
-		s, err = ReadFromWebVTT(f)
+		f = FormatWebVTT
 	default:
 		err = ErrInvalidExtension
 	}
 	return
 }
 
-// OpenFile opens a file regardless of other options
-func OpenFile(filename string) (*Subtitles, error) {
-	return Open(Options{Filename: filename})
+// Extension returns the extension of the format such as ".srt"
+func (f Format) Extension() string {
+	switch f {
+	case FormatJSON:
+		return ".json"
+	case FormatMicroDVD:
+		return ".sub"
+	case FormatSBV:
+		return ".sbv"
+	case FormatSCC:
+		return ".scc"
+	case FormatSRT:
+		return ".srt"
+	case FormatSSA:
+		return ".ssa"
+	case FormatSTL:
+		return ".stl"
+	case FormatTeletext:
+		return ".ts"
+	case FormatTTML:
+		return ".ttml"
+	case FormatWebVTT:
+		return ".vtt"
+	}
+	return ""
+}
+
+// isText checks whether the format is a text format
+func (f Format) isText() bool {
+	return f != FormatSTL && f != FormatTeletext
+}
+
+// formatDetectionSize is the number of bytes DetectFormat sniffs
+const formatDetectionSize = 4096
+
+// DetectFormat sniffs the beginning of a content to detect its format. Since sniffed bytes are consumed, the returned
+// reader must be used to read the content, for instance with Read. ErrInvalidFormat is returned if no format has been
+// detected. Subtitles can then be written back in the same format with WriteToFormat.
+func DetectFormat(i io.Reader) (f Format, o io.Reader, err error) {
+	// Sniff
+	var b = make([]byte, formatDetectionSize)
+	var n int
+	if n, err = io.ReadFull(i, b); err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		err = errors.Wrap(err, "astisub: reading failed")
+		return
+	}
+	err = nil
+	b = b[:n]
+	o = io.MultiReader(bytes.NewReader(b), i)
+
+	// Binary formats
+	switch {
+	case len(b) >= 11 && string(b[3:6]) == "STL":
+		f = FormatSTL
+		return
+	case len(b) > 0 && b[0] == 0x47 && (len(b) <= 188 || b[188] == 0x47):
+		f = FormatTeletext
+		return
+	}
+
+	// Text formats, UTF-16 contents being converted first
+	var t = string(bytes.TrimPrefix(b, BytesBOM))
+	if c := DetectCharset(b); c == CharsetUTF16BE || c == CharsetUTF16LE {
+		var d []byte
+		if d, err = decodeCharset(b[:len(b)&^1], c); err != nil {
+			err = errors.Wrapf(err, "astisub: decoding %s failed", c)
+			return
+		}
+		t = string(d)
+	}
+	t = strings.TrimLeftFunc(t, unicode.IsSpace)
+	switch {
+	case strings.HasPrefix(t, "WEBVTT"):
+		f = FormatWebVTT
+	case strings.HasPrefix(t, "Scenarist_SCC"):
+		f = FormatSCC
+	case strings.HasPrefix(strings.ToLower(t), "[script info]"):
+		f = FormatSSA
+	case strings.HasPrefix(t, "<") && (strings.Contains(t, "<tt ") || strings.Contains(t, "<tt>") || strings.Contains(t, ":tt ")):
+		f = FormatTTML
+	case sbvRegexpTimeBoundaries.MatchString(strings.TrimSpace(strings.SplitN(t, "\n", 2)[0])):
+		f = FormatSBV
+	case srtRegexpFirstCue.MatchString(t):
+		f = FormatSRT
+	case microDVDRegexpLine.MatchString(strings.SplitN(t, "\n", 2)[0]):
+		f = FormatMicroDVD
+	case strings.HasPrefix(t, "{"):
+		f = FormatJSON
+	default:
+		err = ErrInvalidFormat
+	}
+	return
+}
+
+// Read parses a content based on its format
+func Read(i io.Reader, f Format, o Options) (s *Subtitles, err error) {
+	// Convert text formats to UTF-8
+	if f.isText() && f != FormatTTML {
+		if i, err = transcodeToUTF8(i, o.Charset); err != nil {
+			err = errors.Wrap(err, "astisub: transcoding to utf-8 failed")
+			return
+		}
+	}
+
+	// Read
+	switch f {
+	case FormatJSON:
+		s, err = readFromJSON(i, o.AfterReadItem)
+	case FormatMicroDVD:
+		s, err = readFromMicroDVD(i, o.MicroDVD, o.AfterReadItem)
+	case FormatSBV:
+		s, err = readFromSBV(i, o.AfterReadItem)
+	case FormatSCC:
+		s, err = readFromSCC(i, o.AfterReadItem)
+	case FormatSRT:
+		s, err = readFromSRT(i, o.AfterReadItem)
+	case FormatSSA:
+		s, err = readFromSSA(i, o.AfterReadItem)
+	case FormatSTL:
+		s, err = readFromSTL(i, o.AfterReadItem)
+	case FormatTeletext:
+		s, err = readFromTeletext(i, o.Teletext, o.AfterReadItem)
+	case FormatTTML:
+		s, _, err = readFromTTML(i, o.TTML, o.AfterReadItem)
+	case FormatWebVTT:
+		s, err = readFromWebVTT(i, o.AfterReadItem)
+	default:
+		err = ErrInvalidFormat
+	}
+	return
 }
 
 // Subtitles represents an ordered list of items with formatting
 type Subtitles struct {
-	Items    []*Item
-	Metadata *Metadata
-	Regions  map[string]*Region
-	Styles   map[string]*Style
+	Items     []*Item
+	Markers   []Marker
+	Metadata  *Metadata
+	Preserved *Preserved
+	Regions   map[string]*Region
+	Styles    map[string]*Style
+	Warnings  []Warning
+}
+
+// Warning represents a defect that has been recovered from while parsing or a feature that will be lost while
+// writing
+type Warning struct {
+	Line    int // Starts at 1, 0 if the warning is not related to a line
+	Message string
+}
+
+// String implements the Stringer interface
+func (w Warning) String() string {
+	if w.Line == 0 {
+		return w.Message
+	}
+	return fmt.Sprintf("line %d: %s", w.Line, w.Message)
+}
+
+// FidelityWarnings returns the features that will be dropped when writing subtitles in a specific format
+func (s Subtitles) FidelityWarnings(f Format) (ws []Warning) {
+	for idx, i := range s.Items {
+		if i.InlineStyle != nil && i.InlineStyle.Fade != nil && f != FormatSSA {
+			ws = append(ws, Warning{Message: fmt.Sprintf("fade of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if i.AudioDescription != nil && f != FormatTTML {
+			ws = append(ws, Warning{Message: fmt.Sprintf("audio description of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if i.Bitmap != nil {
+			ws = append(ws, Warning{Message: fmt.Sprintf("bitmap of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if f != FormatSSA && f != FormatWebVTT && len(i.KaraokeLineItems()) > 0 {
+			ws = append(ws, Warning{Message: fmt.Sprintf("karaoke of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if i.Thumbnail != nil && f != FormatWebVTT {
+			ws = append(ws, Warning{Message: fmt.Sprintf("thumbnail of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if i.Region != nil && (f == FormatMicroDVD || f == FormatSBV || f == FormatSRT) {
+			ws = append(ws, Warning{Message: fmt.Sprintf("region of item #%d is not supported by %s and will be dropped", idx+1, f)})
+		}
+		if f == FormatSBV && itemHasStyles(i) {
+			ws = append(ws, Warning{Message: fmt.Sprintf("styles of item #%d are not supported by %s and will be dropped", idx+1, f)})
+		}
+	}
+	return
+}
+
+// itemHasStyles checks whether an item or one of its line items is styled
+func itemHasStyles(i *Item) bool {
+	if i.InlineStyle != nil || i.Style != nil {
+		return true
+	}
+	for _, l := range i.Lines {
+		for _, li := range l.Items {
+			if li.InlineStyle != nil || li.Style != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Preserved represents raw constructs a reader couldn't model. Only writers targeting the format they've been read
+// from re-emit them, which allows lossless round trips.
+type Preserved struct {
+	Format Format
+	Values []PreservedValue
+}
+
+// PreservedValue represents a raw construct. Its key depends on the format.
+type PreservedValue struct {
+	Key   string
+	Value string
+}
+
+// addPreserved adds a raw construct to a preservation bag, creating it if needed
+func addPreserved(p **Preserved, f Format, key, value string) {
+	if *p == nil {
+		*p = &Preserved{Format: f}
+	}
+	(*p).Values = append((*p).Values, PreservedValue{Key: key, Value: value})
+}
+
+// values returns the raw constructs of a format, regardless of their key
+func (p *Preserved) values(f Format) (vs []PreservedValue) {
+	if p == nil || p.Format != f {
+		return
+	}
+	return p.Values
+}
+
+// valuesByKey returns the raw constructs of a format with a specific key
+func (p *Preserved) valuesByKey(f Format, key string) (vs []string) {
+	for _, v := range p.values(f) {
+		if v.Key == key {
+			vs = append(vs, v.Value)
+		}
+	}
+	return
 }
 
 // NewSubtitles creates new subtitles
@@ -109,13 +381,54 @@ func NewSubtitles() *Subtitles {
 
 // Item represents a text to show between 2 time boundaries with formatting
 type Item struct {
-	Comments    []string
-	EndAt       time.Duration
-	InlineStyle *StyleAttributes
-	Lines       []Line
-	Region      *Region
-	StartAt     time.Duration
-	Style       *Style
+	AudioDescription *AudioDescription // Set when the item is an audio description cue
+	Bitmap           image.Image       // Set when the item is read from a bitmap format, positioned by its inline style
+	Comments         []string
+	Confidence       *float64 // Between 0 and 1, only set when the item comes from a speech recognition import
+	DisplayMode      DisplayMode
+	EndAt            time.Duration
+	Forced           bool   // Item must be displayed even when subtitles are disabled, e.g. forced narrative
+	ID               string // Cue identifier, such as a WebVTT cue identifier or an SRT index, if specified by the format
+	InlineStyle      *StyleAttributes
+	Kind             ItemKind // Empty for dialogue, set by DetectItemKinds
+	Language         string   // BCP-47 tag, only set when the item's language is specified by the format
+	Lines            []Line
+	Preserved        *Preserved
+	Provenance       *Provenance // Only set when the item comes from a speech recognition import
+	Region           *Region
+	RollUpRows       int // Only set for roll-up items
+	StartAt          time.Duration
+	Style            *Style
+	Thumbnail        *Thumbnail // Set when the item is a cue of a thumbnail track, in which case it has no lines
+}
+
+// isContinued checks whether the item holds the continuation marker
+func (i Item) isContinued() bool {
+	return len(i.Comments) > 0 && i.Comments[len(i.Comments)-1] == ContinuationMarker
+}
+
+// itemIDs returns the IDs items must be written with. Item IDs are used unless renumber is true, some items have no
+// ID or IDs are not valid for the format, in which case items are numbered sequentially.
+func itemIDs(is []*Item, renumber bool, valid func(ids []string) bool) (ids []string) {
+	// Use item IDs
+	if !renumber {
+		for _, i := range is {
+			if len(i.ID) == 0 {
+				break
+			}
+			ids = append(ids, i.ID)
+		}
+		if len(ids) == len(is) && valid(ids) {
+			return
+		}
+	}
+
+	// Renumber
+	ids = make([]string, len(is))
+	for idx := range is {
+		ids[idx] = strconv.Itoa(idx + 1)
+	}
+	return
 }
 
 // String implements the Stringer interface
@@ -164,9 +477,41 @@ func (c *Color) String(base int, showAlpha bool) string {
 	return strconv.Itoa(int(i))
 }
 
+// Fade represents a fade-in/fade-out applied to a whole item
+type Fade struct {
+	In  time.Duration // Starts at the beginning of the item
+	Out time.Duration // Ends at the end of the item
+}
+
+// Position represents the box an item is displayed in, in pixels of the video frame
+type Position struct {
+	X1, X2, Y1, Y2 int
+}
+
 // StyleAttributes represents style attributes
 type StyleAttributes struct {
-	SSAAlignment         *int
+	Alignment            *int // Numpad layout, e.g. 7 is top left. Generic attributes are described in GenericAttributes.
+	Bold                 *bool
+	Color                *Color
+	Fade                 *Fade
+	Italics              *bool
+	MicroDVDBold         *bool
+	MicroDVDColor        *Color
+	MicroDVDItalics      *bool
+	MicroDVDStrikeout    *bool
+	MicroDVDUnderline    *bool
+	Position             *Position
+	SCCColor             *Color
+	SCCColumn            *int // Starts at 0
+	SCCItalics           *bool
+	SCCRow               *int // Starts at 1
+	SCCUnderline         *bool
+	SRTBold              *bool
+	SRTColor             *Color
+	SRTItalics           *bool
+	SRTJoined            *bool // Whether the line item is written right after the previous one, without white space
+	SRTUnderline         *bool
+	SSAAlignment         *int // Numpad layout, e.g. 7 is top left. SSA v4.00 alignments are converted when reading and writing.
 	SSAAlphaLevel        *float64
 	SSAAngle             *float64 // degrees
 	SSABackColour        *Color
@@ -184,6 +529,7 @@ type StyleAttributes struct {
 	SSAMarked            *bool
 	SSAOutline           *int // pixels
 	SSAOutlineColour     *Color
+	SSAPosition          *SSAPosition
 	SSAPrimaryColour     *Color
 	SSAScaleX            *float64 // %
 	SSAScaleY            *float64 // %
@@ -195,6 +541,7 @@ type StyleAttributes struct {
 	STLBoxing            *bool
 	STLItalics           *bool
 	STLUnderline         *bool
+	Strikeout            *bool
 	TeletextColor        *Color
 	TeletextDoubleHeight *bool
 	TeletextDoubleSize   *bool
@@ -226,25 +573,39 @@ type StyleAttributes struct {
 	TTMLWrapOption       string
 	TTMLWritingMode      string
 	TTMLZIndex           int
+	Underline            *bool
 	WebVTTAlign          string
+	WebVTTBold           *bool
+	WebVTTColor          *Color
+	WebVTTClasses        []string // Classes of <c> spans that can't be mapped to styles or colors
+	WebVTTCSS            string   // Declarations of ::cue rules that can't be mapped to other attributes, separated by ";"
+	WebVTTItalics        *bool
 	WebVTTLine           string
 	WebVTTLines          int
 	WebVTTPosition       string
 	WebVTTRegionAnchor   string
 	WebVTTScroll         string
 	WebVTTSize           string
+	WebVTTUnderline      *bool
 	WebVTTVertical       string
 	WebVTTViewportAnchor string
 	WebVTTWidth          string
 }
 
+func (sa *StyleAttributes) propagateSCCAttributes() {
+	if sa.SCCRow != nil {
+		// Rows are spread over the 80% high safe area
+		sa.WebVTTLine = strconv.Itoa(10+(*sa.SCCRow-1)*80/sccRows) + "%"
+	}
+}
+
 func (sa *StyleAttributes) propagateSSAAttributes() {}
 
 func (sa *StyleAttributes) propagateSTLAttributes() {}
 
 func (sa *StyleAttributes) propagateTeletextAttributes() {
 	if sa.TeletextColor != nil {
-		sa.TTMLColor = "#" + sa.TeletextColor.String(16, false)
+		sa.TTMLColor = fmt.Sprintf("#%.2x%.2x%.2x", sa.TeletextColor.Red, sa.TeletextColor.Green, sa.TeletextColor.Blue)
 	}
 }
 
@@ -252,12 +613,133 @@ func (sa *StyleAttributes) propagateTTMLAttributes() {}
 
 func (sa *StyleAttributes) propagateWebVTTAttributes() {}
 
+// Formats generic attributes are derived from, by order of precedence
+var genericAttributesFormats = []Format{FormatSSA, FormatTTML, FormatWebVTT, FormatSRT, FormatMicroDVD, FormatSTL, FormatSCC, FormatTeletext}
+
+// GenericAttributes returns the attributes formats have in common, i.e. Alignment, Bold, Color, Italics, Strikeout and
+// Underline, so that styles survive conversions. Attributes of the preferred format take precedence over generic
+// attributes, which take precedence over attributes of other formats.
+func (sa *StyleAttributes) GenericAttributes(preferred Format) (o StyleAttributes) {
+	// Nothing to do
+	if sa == nil {
+		return
+	}
+
+	// Merge attributes
+	var g = sa.formatGenericAttributes(preferred)
+	g = mergeStyleAttributes(g, &StyleAttributes{
+		Alignment: sa.Alignment,
+		Bold:      sa.Bold,
+		Color:     sa.Color,
+		Italics:   sa.Italics,
+		Strikeout: sa.Strikeout,
+		Underline: sa.Underline,
+	})
+	for _, f := range genericAttributesFormats {
+		if f != preferred {
+			g = mergeStyleAttributes(g, sa.formatGenericAttributes(f))
+		}
+	}
+	return *g
+}
+
+// formatGenericAttributes returns the generic attributes derived from the attributes of a format
+func (sa *StyleAttributes) formatGenericAttributes(f Format) (o *StyleAttributes) {
+	o = &StyleAttributes{}
+	switch f {
+	case FormatMicroDVD:
+		o.Bold = sa.MicroDVDBold
+		o.Color = sa.MicroDVDColor
+		o.Italics = sa.MicroDVDItalics
+		o.Strikeout = sa.MicroDVDStrikeout
+		o.Underline = sa.MicroDVDUnderline
+	case FormatSCC:
+		o.Color = sa.SCCColor
+		o.Italics = sa.SCCItalics
+		o.Underline = sa.SCCUnderline
+	case FormatSRT:
+		o.Bold = sa.SRTBold
+		o.Color = sa.SRTColor
+		o.Italics = sa.SRTItalics
+		o.Underline = sa.SRTUnderline
+	case FormatSSA:
+		if ssaValidAlignment(sa.SSAAlignment) {
+			o.Alignment = sa.SSAAlignment
+		}
+		o.Bold = sa.SSABold
+		o.Color = sa.SSAPrimaryColour
+		o.Italics = sa.SSAItalic
+		o.Strikeout = sa.SSAStrikeout
+		o.Underline = sa.SSAUnderline
+	case FormatSTL:
+		o.Italics = sa.STLItalics
+		o.Underline = sa.STLUnderline
+	case FormatTeletext:
+		o.Color = sa.TeletextColor
+	case FormatTTML:
+		o.Alignment = textAlignAlignment(sa.TTMLTextAlign)
+		if sa.TTMLFontWeight == "bold" {
+			o.Bold = astiptr.Bool(true)
+		}
+		o.Color = ttmlColor(sa.TTMLColor)
+		if sa.TTMLFontStyle == "italic" || sa.TTMLFontStyle == "oblique" {
+			o.Italics = astiptr.Bool(true)
+		}
+		// "noLineThrough" and "noUnderline" don't contain "lineThrough" and "underline" since case differs
+		if strings.Contains(sa.TTMLTextDecoration, "lineThrough") {
+			o.Strikeout = astiptr.Bool(true)
+		}
+		if strings.Contains(sa.TTMLTextDecoration, "underline") {
+			o.Underline = astiptr.Bool(true)
+		}
+	case FormatWebVTT:
+		o.Alignment = textAlignAlignment(sa.WebVTTAlign)
+		o.Bold = sa.WebVTTBold
+		o.Color = sa.WebVTTColor
+		o.Italics = sa.WebVTTItalics
+		o.Underline = sa.WebVTTUnderline
+	}
+	return
+}
+
+// textAlignAlignment returns the bottom row numpad alignment of a CSS-like text alignment, nil if it's unknown
+func textAlignAlignment(i string) *int {
+	switch i {
+	case "left", "start":
+		return astiptr.Int(1)
+	case "center":
+		return astiptr.Int(2)
+	case "end", "right":
+		return astiptr.Int(3)
+	}
+	return nil
+}
+
+// alignmentTextAlign returns the CSS-like text alignment of a numpad alignment, empty if it's not valid
+func alignmentTextAlign(i *int) string {
+	if !ssaValidAlignment(i) {
+		return ""
+	}
+	return []string{"left", "center", "right"}[(*i-1)%3]
+}
+
+// styleFlag returns a pointer to true if the flag is set, nil otherwise
+func styleFlag(b bool) *bool {
+	if !b {
+		return nil
+	}
+	return astiptr.Bool(true)
+}
+
 // Metadata represents metadata
 // TODO Merge attributes
 type Metadata struct {
 	Comments                 []string
 	Framerate                int
+	Kind                     TrackKind
 	Language                 string
+	LineBreakPolicy          LineBreakPolicy
+	MicroDVDFramerate        float64 // Exact framerate, such as 23.976, since Framerate is an integer
 	SSACollisions            string
 	SSAOriginalEditing       string
 	SSAOriginalScript        string
@@ -290,10 +772,64 @@ type Style struct {
 	Style       *Style
 }
 
+// Resolve returns the attributes of the region merged with the ones of its style chain, the region's inline
+// attributes taking precedence. Returned attributes can be modified safely.
+func (r *Region) Resolve() *StyleAttributes {
+	if r == nil {
+		return &StyleAttributes{}
+	}
+	return resolveStyleAttributes(r.InlineStyle, r.Style)
+}
+
+// Resolve returns the attributes of the style merged with the ones of its parent styles, the attributes of a style
+// taking precedence over the ones of its parent. Returned attributes can be modified safely.
+func (s *Style) Resolve() *StyleAttributes {
+	return resolveStyleAttributes(nil, s)
+}
+
+// resolveStyleAttributes merges attributes with the ones of a style chain, attributes coming first taking
+// precedence. Style cycles are ignored.
+func resolveStyleAttributes(sa *StyleAttributes, s *Style) (o *StyleAttributes) {
+	// Copy attributes
+	o = &StyleAttributes{}
+	if sa != nil {
+		*o = *sa
+	}
+
+	// Loop through styles
+	var visited = make(map[*Style]bool)
+	for ; s != nil && !visited[s]; s = s.Style {
+		visited[s] = true
+		o = mergeStyleAttributes(o, s.InlineStyle)
+	}
+	return
+}
+
+// ResolvedStyleAttributes returns the attributes applying to the item, in order of precedence: its inline
+// attributes, the ones of its style chain, then the ones of its region. Returned attributes can be modified safely.
+func (i Item) ResolvedStyleAttributes() *StyleAttributes {
+	return mergeStyleAttributes(resolveStyleAttributes(i.InlineStyle, i.Style), i.Region.Resolve())
+}
+
+// ResolvedStyleAttributes returns the attributes applying to the line item of an item, in order of precedence: its
+// inline attributes, the ones of its style chain, then the item's resolved attributes. Returned attributes can be
+// modified safely.
+func (li LineItem) ResolvedStyleAttributes(i Item) *StyleAttributes {
+	return mergeStyleAttributes(resolveStyleAttributes(li.InlineStyle, li.Style), i.ResolvedStyleAttributes())
+}
+
 // Line represents a set of formatted line items
 type Line struct {
-	Items     []LineItem
-	VoiceName string
+	EndAt      time.Duration // Only set for timed lines, such as roll-up captions building up
+	Items      []LineItem
+	NewSpeaker bool          // If true, the line starts a new speaker's turn, such as a line starting with a dialogue dash
+	StartAt    time.Duration // Only set for timed lines, such as roll-up captions building up
+	VoiceName  string
+}
+
+// isTimed checks whether the line has its own time boundaries
+func (l Line) isTimed() bool {
+	return l.EndAt > 0
 }
 
 // String implement the Stringer interface
@@ -307,31 +843,215 @@ func (l Line) String() string {
 
 // LineItem represents a formatted line item
 type LineItem struct {
+	Confidence  *float64      // Between 0 and 1, only set when the line item comes from a speech recognition import
+	EndAt       time.Duration // Only set for timed line items, such as words coming from a speech recognition import
 	InlineStyle *StyleAttributes
+	Karaoke     KaraokeEffect // Only set for karaoke syllables, StartAt and EndAt being when they're sung
+	Language    string        // BCP-47 tag, only set when it differs from the item's language
+	Provenance  *Provenance   // Only set when the line item comes from a speech recognition import
+	StartAt     time.Duration // Only set for timed line items, such as words coming from a speech recognition import
 	Style       *Style
 	Text        string
 }
 
+// isTimed checks whether the line item has its own time boundaries
+func (li LineItem) isTimed() bool {
+	return li.EndAt > 0
+}
+
+// TransformTimes replaces the time boundaries of each item, as well as the ones of its timed lines and line items,
+// with the ones returned by the function. Markers are retimed as well. It's the primitive other retiming methods build
+// on and can be used to implement custom retiming such as variable-speed conforms.
+func (s *Subtitles) TransformTimes(fn func(start, end time.Duration) (time.Duration, time.Duration)) {
+	for idx, m := range s.Markers {
+		s.Markers[idx].At, _ = fn(m.At, m.At)
+	}
+	for _, v := range s.Items {
+		v.StartAt, v.EndAt = fn(v.StartAt, v.EndAt)
+		for idxLine, l := range v.Lines {
+			if l.isTimed() {
+				v.Lines[idxLine].StartAt, v.Lines[idxLine].EndAt = fn(l.StartAt, l.EndAt)
+			}
+			for idxItem, li := range l.Items {
+				if li.isTimed() {
+					v.Lines[idxLine].Items[idxItem].StartAt, v.Lines[idxLine].Items[idxItem].EndAt = fn(li.StartAt, li.EndAt)
+				}
+			}
+		}
+	}
+}
+
 // Add adds a duration to each time boundaries. As in the time package, duration can be negative.
 func (s *Subtitles) Add(d time.Duration) {
+	s.TransformTimes(func(start, end time.Duration) (time.Duration, time.Duration) {
+		return start + d, end + d
+	})
+}
+
+// AddOptions represents Add options
+type AddOptions struct {
+	// If true, negative time boundaries are set to 0
+	ClampAtZero bool
+	// If true, items starting before 0 are removed. If ClampAtZero is true as well, only items ending before 0 are.
+	DropNegative bool
+}
+
+// AddReport represents the items affected by AddWithOptions
+type AddReport struct {
+	Clamped []*Item
+	Dropped []*Item
+}
+
+// AddWithOptions adds a duration to each time boundaries and handles items that end up before 0 as requested.
+// As a rule of thumb, shifting earlier than 0 should use at least one option so that writers don't get negative
+// time boundaries.
+func (s *Subtitles) AddWithOptions(d time.Duration, o AddOptions) (r AddReport) {
+	// Add
+	s.Add(d)
+
+	// Loop through items
+	var items []*Item
 	for _, v := range s.Items {
-		v.EndAt += d
-		v.StartAt += d
+		// Item is valid
+		if v.StartAt >= 0 {
+			items = append(items, v)
+			continue
+		}
+
+		// Drop
+		if o.DropNegative && (!o.ClampAtZero || v.EndAt <= 0) {
+			r.Dropped = append(r.Dropped, v)
+			continue
+		}
+
+		// Clamp
+		if o.ClampAtZero {
+			v.StartAt = 0
+			if v.EndAt < 0 {
+				v.EndAt = 0
+			}
+			r.Clamped = append(r.Clamped, v)
+		}
+		items = append(items, v)
 	}
+	s.Items = items
+
+	// Clamp markers
+	if o.ClampAtZero {
+		for idx, m := range s.Markers {
+			if m.At < 0 {
+				s.Markers[idx].At = 0
+			}
+		}
+	}
+	return
 }
 
-// Duration returns the subtitles duration
-func (s Subtitles) Duration() time.Duration {
-	if len(s.Items) == 0 {
-		return time.Duration(0)
+// Scale multiplies each time boundaries by a factor. It comes in handy when subtitles have been timed against a
+// different framerate (e.g. 25/23.976 when going from 23.976 fps to 25 fps content).
+func (s *Subtitles) Scale(f float64) {
+	s.TransformTimes(func(start, end time.Duration) (time.Duration, time.Duration) {
+		return time.Duration(float64(start) * f), time.Duration(float64(end) * f)
+	})
+}
+
+// Sync retimes each time boundaries using a linear transform: times are multiplied by a factor, then the offset is
+// added. It fixes drifts such as subtitles timed against 23.976 fps content played along 25 fps content.
+func (s *Subtitles) Sync(offset time.Duration, factor float64) {
+	s.TransformTimes(func(start, end time.Duration) (time.Duration, time.Duration) {
+		return time.Duration(math.Round(float64(start)*factor)) + offset, time.Duration(math.Round(float64(end)*factor)) + offset
+	})
+}
+
+// SyncWithAnchors retimes each time boundaries using the linear transform mapping 2 source times to 2 destination
+// times, e.g. the times at which the first and last lines are spoken in the subtitles and in the content
+func (s *Subtitles) SyncWithAnchors(a1Src, a1Dst, a2Src, a2Dst time.Duration) error {
+	// Anchors must be distinct
+	if a1Src == a2Src {
+		return errors.Wrapf(ErrInvalidAnchors, "astisub: source anchors are both %s", a1Src)
+	}
+
+	// Sync
+	var factor = float64(a2Dst-a1Dst) / float64(a2Src-a1Src)
+	var fn = func(d time.Duration) time.Duration {
+		return a1Dst + time.Duration(math.Round(float64(d-a1Src)*factor))
+	}
+	s.TransformTimes(func(start, end time.Duration) (time.Duration, time.Duration) {
+		return fn(start), fn(end)
+	})
+	return nil
+}
+
+// Duration returns the subtitles duration, which is the biggest end at of its items, whether they're ordered or not
+func (s Subtitles) Duration() (d time.Duration) {
+	for _, i := range s.Items {
+		if i.EndAt > d {
+			d = i.EndAt
+		}
+	}
+	return
+}
+
+// MinStart returns the smallest start at of the items, whether they're ordered or not
+func (s Subtitles) MinStart() (d time.Duration) {
+	for idx, i := range s.Items {
+		if idx == 0 || i.StartAt < d {
+			d = i.StartAt
+		}
 	}
-	return s.Items[len(s.Items)-1].EndAt
+	return
+}
+
+// TimeRange returns the smallest start at and the biggest end at of the items
+func (s Subtitles) TimeRange() (start, end time.Duration) {
+	return s.MinStart(), s.Duration()
+}
+
+// Default placeholder
+const (
+	defaultPlaceholderDuration = time.Millisecond
+	defaultPlaceholderText     = "..."
+)
+
+// ForceDurationOptions represents ForceDuration options
+type ForceDurationOptions struct {
+	// If true, the last item's end is extended instead of adding a placeholder item, unless there are no items
+	ExtendLastItem bool
+	// If true and the first item doesn't start at 0, a placeholder item is added at 0 as well
+	PlaceholderAtStart  bool
+	PlaceholderDuration time.Duration // Defaults to 1ms
+	PlaceholderText     string        // Defaults to "..."
 }
 
 // ForceDuration updates the subtitles duration.
 // If requested duration is bigger, then we create a dummy item.
 // If requested duration is smaller, then we remove useless items and we cut the last item or add a dummy item.
 func (s *Subtitles) ForceDuration(d time.Duration) {
+	s.ForceDurationWithOptions(d, ForceDurationOptions{})
+}
+
+// ForceDurationWithOptions updates the subtitles duration with options
+func (s *Subtitles) ForceDurationWithOptions(d time.Duration, o ForceDurationOptions) {
+	// Default options
+	if o.PlaceholderDuration <= 0 {
+		o.PlaceholderDuration = defaultPlaceholderDuration
+	}
+	if len(o.PlaceholderText) == 0 {
+		o.PlaceholderText = defaultPlaceholderText
+	}
+
+	// Add placeholder at start
+	if o.PlaceholderAtStart && (len(s.Items) == 0 || s.Items[0].StartAt > 0) {
+		var endAt = o.PlaceholderDuration
+		if len(s.Items) > 0 && s.Items[0].StartAt < endAt {
+			endAt = s.Items[0].StartAt
+		}
+		if endAt > d {
+			endAt = d
+		}
+		s.Items = append([]*Item{newPlaceholderItem(0, endAt, o.PlaceholderText)}, s.Items...)
+	}
+
 	// Requested duration is the same as the subtitles'one
 	if s.Duration() == d {
 		return
@@ -357,14 +1077,108 @@ func (s *Subtitles) ForceDuration(d time.Duration) {
 		}
 	}
 
-	// Add dummy item with the minimum duration possible
+	// Subtitles are still too short
 	if s.Duration() < d {
-		s.Items = append(s.Items, &Item{EndAt: d, Lines: []Line{{Items: []LineItem{{Text: "..."}}}}, StartAt: d - time.Millisecond})
+		// Extend last item
+		if o.ExtendLastItem && len(s.Items) > 0 {
+			s.Items[len(s.Items)-1].EndAt = d
+			return
+		}
+
+		// Add placeholder item
+		var startAt = d - o.PlaceholderDuration
+		if startAt < s.Duration() {
+			startAt = s.Duration()
+		}
+		s.Items = append(s.Items, newPlaceholderItem(startAt, d, o.PlaceholderText))
+	}
+}
+
+// newPlaceholderItem creates a new placeholder item
+func newPlaceholderItem(startAt, endAt time.Duration, text string) *Item {
+	return &Item{EndAt: endAt, Lines: []Line{{Items: []LineItem{{Text: text}}}}, StartAt: startAt}
+}
+
+// ApplyLeadInOut starts items leadIn earlier and ends them leadOut later without creating overlaps. When an item's
+// lead-in and the previous item's lead-out compete for the same gap, the lead-in wins. If respectGaps is true, items
+// that are not contiguous are kept at least 2 frames apart, as quality guidelines require, unless they already were
+// closer. Frames are retrieved from the metadata framerate, which defaults to 25. Otherwise gaps may be closed
+// entirely.
+func (s *Subtitles) ApplyLeadInOut(leadIn, leadOut time.Duration, respectGaps bool) {
+	// Sort items by start time
+	var is = append([]*Item{}, s.Items...)
+	sort.SliceStable(is, func(i, j int) bool { return is[i].StartAt < is[j].StartAt })
+
+	// Get min gap
+	var minGap time.Duration
+	if respectGaps {
+		var framerate = defaultFramerate
+		if s.Metadata != nil && s.Metadata.Framerate > 0 {
+			framerate = s.Metadata.Framerate
+		}
+		minGap = 2 * time.Second / time.Duration(framerate)
+	}
+	var keepGap = func(gap time.Duration) time.Duration {
+		if gap < minGap {
+			return gap
+		}
+		return minGap
+	}
+
+	// Apply lead-ins, items not starting before the end of previous items
+	var previousEndAt time.Duration
+	for idx, i := range is {
+		var startAt = i.StartAt - leadIn
+		if startAt < 0 {
+			startAt = 0
+		}
+		if idx > 0 {
+			if limit := previousEndAt + keepGap(i.StartAt-previousEndAt); startAt < limit {
+				startAt = limit
+			}
+		}
+		if i.EndAt > previousEndAt {
+			previousEndAt = i.EndAt
+		}
+		if startAt < i.StartAt {
+			i.StartAt = startAt
+		}
+	}
+
+	// Apply lead-outs, items not ending after the start of next items
+	for idx, i := range is {
+		var endAt = i.EndAt + leadOut
+		if idx+1 < len(is) {
+			var nextStartAt = is[idx+1].StartAt
+			if limit := nextStartAt - keepGap(nextStartAt-i.EndAt); endAt > limit {
+				endAt = limit
+			}
+		}
+		if endAt > i.EndAt {
+			i.EndAt = endAt
+		}
 	}
 }
 
+// ContinuationMarker is the comment added to fragmented items that are continued by the next item. Since it's an item
+// comment, it survives being written to and read from JSON, SSA, STL, TTML and WebVTT segments. Other formats, SRT
+// included, drop item comments, in which case Unfragment can't re-join items based on the marker.
+const ContinuationMarker = "astisub:continued"
+
+// FragmentOptions represents Fragment options
+type FragmentOptions struct {
+	// If true, items that are split are tagged with the continuation marker so that Unfragment re-joins them, and only
+	// them, even when texts legitimately repeat. See ContinuationMarker for the formats keeping markers.
+	ContinuationMarkers bool
+}
+
 // Fragment fragments subtitles with a specific fragment duration
 func (s *Subtitles) Fragment(f time.Duration) {
+	s.FragmentWithOptions(f, FragmentOptions{})
+}
+
+// FragmentWithOptions fragments subtitles with a specific fragment duration and options
+func (s *Subtitles) FragmentWithOptions(f time.Duration, o FragmentOptions) {
 	// Nothing to fragment
 	if len(s.Items) == 0 {
 		return
@@ -405,6 +1219,11 @@ func (s *Subtitles) Fragment(f time.Duration) {
 				continue
 			}
 
+			// Add continuation marker
+			if o.ContinuationMarkers {
+				newSub.Comments = append(append([]string{}, sub.Comments...), ContinuationMarker)
+			}
+
 			// Insert new sub
 			s.Items = append(s.Items[:i], append([]*Item{newSub}, s.Items[i:]...)...)
 		}
@@ -418,6 +1237,21 @@ func (s *Subtitles) Fragment(f time.Duration) {
 	s.Order()
 }
 
+// ForcedItems returns subtitles containing only the forced items. Metadata, regions and styles are shared.
+func (s Subtitles) ForcedItems() (o *Subtitles) {
+	o = &Subtitles{
+		Metadata: s.Metadata,
+		Regions:  s.Regions,
+		Styles:   s.Styles,
+	}
+	for _, i := range s.Items {
+		if i.Forced {
+			o.Items = append(o.Items, i)
+		}
+	}
+	return
+}
+
 // IsEmpty returns whether the subtitles are empty
 func (s Subtitles) IsEmpty() bool {
 	return len(s.Items) == 0
@@ -425,23 +1259,187 @@ func (s Subtitles) IsEmpty() bool {
 
 // Merge merges subtitles i into subtitles
 func (s *Subtitles) Merge(i *Subtitles) {
-	// Append items
-	s.Items = append(s.Items, i.Items...)
-	s.Order()
+	s.MergeWithOptions(i, MergeOptions{})
+}
+
+// MergeMode represents the way colliding items of merged subtitles are handled
+type MergeMode int
+
+// Merge modes
+const (
+	// Items are overlaid
+	MergeModeOverlay MergeMode = iota
+	// Items of the secondary subtitles colliding with items of the primary subtitles are removed
+	MergeModePreferPrimary
+	// Items of the secondary subtitles colliding with items of the primary subtitles are delayed until there's no
+	// collision, keeping their duration
+	MergeModeShiftSecondary
+	// Colliding items are split on their time boundaries so that lines of the secondary subtitles are displayed
+	// below lines of the primary subtitles
+	MergeModeStack
+)
 
+// MergeOptions represents merge options
+type MergeOptions struct {
+	Mode MergeMode
+	// If true, regions and styles of the secondary subtitles whose ID is already used by a different region or style
+	// of the primary subtitles are renamed instead of being ignored. Renaming updates the secondary subtitles.
+	RenameConflicts bool
+}
+
+// MergeWithOptions merges subtitles i into subtitles s with options, s being the primary subtitles
+func (s *Subtitles) MergeWithOptions(i *Subtitles, o MergeOptions) {
 	// Add regions
+	if s.Regions == nil {
+		s.Regions = make(map[string]*Region)
+	}
 	for _, region := range i.Regions {
+		if r, ok := s.Regions[region.ID]; ok && r != region && o.RenameConflicts {
+			region.ID = mergeID(region.ID, func(id string) bool { _, ok := s.Regions[id]; return ok })
+		}
 		if _, ok := s.Regions[region.ID]; !ok {
 			s.Regions[region.ID] = region
 		}
 	}
 
 	// Add styles
+	if s.Styles == nil {
+		s.Styles = make(map[string]*Style)
+	}
 	for _, style := range i.Styles {
+		if st, ok := s.Styles[style.ID]; ok && st != style && o.RenameConflicts {
+			style.ID = mergeID(style.ID, func(id string) bool { _, ok := s.Styles[id]; return ok })
+		}
 		if _, ok := s.Styles[style.ID]; !ok {
 			s.Styles[style.ID] = style
 		}
 	}
+
+	// Switch on mode
+	switch o.Mode {
+	case MergeModePreferPrimary:
+		for _, v := range i.Items {
+			if !itemCollides(v.StartAt, v.EndAt, s.Items) {
+				s.Items = append(s.Items, v)
+			}
+		}
+	case MergeModeShiftSecondary:
+		var primary = append([]*Item{}, s.Items...)
+		for _, v := range i.Items {
+			// Delay the item until it doesn't collide anymore
+			var d = v.EndAt - v.StartAt
+			for {
+				var delayed bool
+				for _, p := range primary {
+					if v.StartAt < p.EndAt && p.StartAt < v.EndAt {
+						v.StartAt = p.EndAt
+						v.EndAt = v.StartAt + d
+						delayed = true
+					}
+				}
+				if !delayed {
+					break
+				}
+			}
+			s.Items = append(s.Items, v)
+		}
+	case MergeModeStack:
+		s.Items = stackItems(s.Items, i.Items)
+	default:
+		s.Items = append(s.Items, i.Items...)
+	}
+	s.Order()
+}
+
+// mergeID returns the first ID based on an ID that is not used
+func mergeID(id string, used func(id string) bool) string {
+	for idx := 2; ; idx++ {
+		if n := id + "_" + strconv.Itoa(idx); !used(n) {
+			return n
+		}
+	}
+}
+
+// itemCollides checks whether time boundaries collide with an item
+func itemCollides(startAt, endAt time.Duration, is []*Item) bool {
+	for _, i := range is {
+		if startAt < i.EndAt && i.StartAt < endAt {
+			return true
+		}
+	}
+	return false
+}
+
+// stackItems splits items on their time boundaries so that, in each resulting interval, lines of all the items
+// being displayed are stacked, primary items first. Items that don't collide are kept untouched.
+func stackItems(primary, secondary []*Item) (o []*Item) {
+	// Get time boundaries
+	var all = append(append([]*Item{}, primary...), secondary...)
+	var m = make(map[time.Duration]bool)
+	var bs []time.Duration
+	for _, i := range all {
+		for _, b := range []time.Duration{i.StartAt, i.EndAt} {
+			if !m[b] {
+				m[b] = true
+				bs = append(bs, b)
+			}
+		}
+	}
+	sort.Slice(bs, func(i, j int) bool { return bs[i] < bs[j] })
+
+	// Loop through intervals
+	var previous []*Item
+	var previousItem *Item
+	for idx := 0; idx < len(bs)-1; idx++ {
+		// Get items being displayed, primary items first
+		var active []*Item
+		for _, i := range all {
+			if i.StartAt <= bs[idx] && i.EndAt >= bs[idx+1] {
+				active = append(active, i)
+			}
+		}
+
+		// Items are the same as the previous interval's
+		if previousItem != nil && len(active) == len(previous) && previousItem.EndAt == bs[idx] {
+			var same = true
+			for k := range active {
+				if active[k] != previous[k] {
+					same = false
+					break
+				}
+			}
+			if same {
+				previousItem.EndAt = bs[idx+1]
+				continue
+			}
+		}
+		previous = active
+
+		// No items
+		if len(active) == 0 {
+			previousItem = nil
+			continue
+		}
+
+		// Only one item
+		if len(active) == 1 && active[0].StartAt == bs[idx] && active[0].EndAt == bs[idx+1] {
+			previousItem = nil
+			o = append(o, active[0])
+			continue
+		}
+
+		// Create item
+		previousItem = &Item{}
+		*previousItem = *active[0]
+		previousItem.StartAt = bs[idx]
+		previousItem.EndAt = bs[idx+1]
+		previousItem.Lines = nil
+		for _, i := range active {
+			previousItem.Lines = append(previousItem.Lines, i.Lines...)
+		}
+		o = append(o, previousItem)
+	}
+	return
 }
 
 // Optimize optimizes subtitles
@@ -493,6 +1491,15 @@ func (s *Subtitles) removeUnusedRegionsAndStyles() {
 		}
 	}
 
+	// Parents of used styles are used as well
+	for id := range usedStyles {
+		if st, ok := s.Styles[id]; ok {
+			for p := st.Style; p != nil && !usedStyles[p.ID]; p = p.Style {
+				usedStyles[p.ID] = true
+			}
+		}
+	}
+
 	// Loop through style
 	for id, style := range s.Styles {
 		if _, ok := usedStyles[style.ID]; !ok {
@@ -501,25 +1508,374 @@ func (s *Subtitles) removeUnusedRegionsAndStyles() {
 	}
 }
 
-// Order orders items
+// NormalizeStylesOptions represents normalize styles options
+// Inlining and factoring are mostly useful for TTML output where a style and the same inline attributes are
+// equivalent. Other formats such as SSA only read some attributes from styles and others from inline attributes.
+type NormalizeStylesOptions struct {
+	// If true, inline attributes of line items used at least twice are factored into shared styles
+	FactorInlineStyles bool
+	// If true, styles referenced by a single item or line item, and by no region or style, are inlined
+	InlineSingleUseStyles bool
+}
+
+// NormalizeStyles merges identical styles and removes unused regions and styles
+func (s *Subtitles) NormalizeStyles() {
+	s.NormalizeStylesWithOptions(NormalizeStylesOptions{})
+}
+
+// NormalizeStylesWithOptions merges identical styles, inlines or factors styles depending on the options, and removes
+// unused regions and styles
+func (s *Subtitles) NormalizeStylesWithOptions(o NormalizeStylesOptions) {
+	// Merge identical styles
+	s.mergeIdenticalStyles()
+
+	// Inline single use styles
+	if o.InlineSingleUseStyles {
+		s.inlineSingleUseStyles()
+	}
+
+	// Factor inline styles
+	if o.FactorInlineStyles {
+		s.factorInlineStyles()
+	}
+
+	// Remove unused regions and styles
+	s.removeUnusedRegionsAndStyles()
+}
+
+// styleReferences returns every reference to a style
+func (s *Subtitles) styleReferences() (rs []**Style) {
+	for _, i := range s.Items {
+		rs = append(rs, &i.Style)
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				rs = append(rs, &i.Lines[idxLine].Items[idxLineItem].Style)
+			}
+		}
+	}
+	for _, r := range s.Regions {
+		rs = append(rs, &r.Style)
+	}
+	for _, st := range s.Styles {
+		rs = append(rs, &st.Style)
+	}
+	return
+}
+
+// mergeIdenticalStyles replaces styles by the first identical style, by ID
+func (s *Subtitles) mergeIdenticalStyles() {
+	// Merging styles may make their children identical, therefore we loop until nothing is merged
+	for {
+		// Get sorted IDs
+		var ids []string
+		for id := range s.Styles {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		// Find identical styles
+		var replacements = make(map[*Style]*Style)
+		for idx, id := range ids {
+			if _, ok := replacements[s.Styles[id]]; ok {
+				continue
+			}
+			for _, other := range ids[idx+1:] {
+				if _, ok := replacements[s.Styles[other]]; !ok && s.Styles[id].Style == s.Styles[other].Style &&
+					equalStyleAttributes(s.Styles[id].InlineStyle, s.Styles[other].InlineStyle) {
+					replacements[s.Styles[other]] = s.Styles[id]
+				}
+			}
+		}
+
+		// Nothing to merge
+		if len(replacements) == 0 {
+			return
+		}
+
+		// Replace references
+		for _, r := range s.styleReferences() {
+			if v, ok := replacements[*r]; ok {
+				*r = v
+			}
+		}
+		for st := range replacements {
+			delete(s.Styles, st.ID)
+		}
+	}
+}
+
+// inlineSingleUseStyles inlines styles referenced by a single item or line item, and by no region or style
+func (s *Subtitles) inlineSingleUseStyles() {
+	// Count references
+	var counts = make(map[*Style]int)
+	for _, r := range s.Regions {
+		if r.Style != nil {
+			counts[r.Style] += 2
+		}
+	}
+	for _, st := range s.Styles {
+		if st.Style != nil {
+			counts[st.Style] += 2
+		}
+	}
+	for _, i := range s.Items {
+		if i.Style != nil {
+			counts[i.Style]++
+		}
+		for _, l := range i.Lines {
+			for _, li := range l.Items {
+				if li.Style != nil {
+					counts[li.Style]++
+				}
+			}
+		}
+	}
+
+	// Inline styles
+	for _, i := range s.Items {
+		if i.Style != nil && counts[i.Style] == 1 {
+			i.InlineStyle, i.Style = mergeStyleAttributes(i.InlineStyle, i.Style.InlineStyle), i.Style.Style
+		}
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				var li = &i.Lines[idxLine].Items[idxLineItem]
+				if li.Style != nil && counts[li.Style] == 1 {
+					li.InlineStyle, li.Style = mergeStyleAttributes(li.InlineStyle, li.Style.InlineStyle), li.Style.Style
+				}
+			}
+		}
+	}
+}
+
+// factorInlineStyles factors inline attributes of line items used at least twice into shared styles
+func (s *Subtitles) factorInlineStyles() {
+	// Group line items
+	type group struct {
+		lineItems []*LineItem
+		sa        *StyleAttributes
+		style     *Style
+	}
+	var groups []*group
+	for _, i := range s.Items {
+		for idxLine := range i.Lines {
+			for idxLineItem := range i.Lines[idxLine].Items {
+				// Get line item
+				var li = &i.Lines[idxLine].Items[idxLineItem]
+				if li.InlineStyle == nil || equalStyleAttributes(li.InlineStyle, nil) {
+					continue
+				}
+
+				// Find group
+				var g *group
+				for _, v := range groups {
+					if v.style == li.Style && equalStyleAttributes(v.sa, li.InlineStyle) {
+						g = v
+						break
+					}
+				}
+				if g == nil {
+					g = &group{sa: li.InlineStyle, style: li.Style}
+					groups = append(groups, g)
+				}
+				g.lineItems = append(g.lineItems, li)
+			}
+		}
+	}
+
+	// Create styles
+	if s.Styles == nil {
+		s.Styles = make(map[string]*Style)
+	}
+	var idx int
+	for _, g := range groups {
+		// Inline attributes are only used once
+		if len(g.lineItems) < 2 {
+			continue
+		}
+
+		// Get ID
+		var id string
+		for {
+			idx++
+			if id = "style_" + strconv.Itoa(idx); s.Styles[id] == nil {
+				break
+			}
+		}
+
+		// Add style
+		var st = &Style{ID: id, InlineStyle: g.sa, Style: g.style}
+		s.Styles[id] = st
+		for _, li := range g.lineItems {
+			li.InlineStyle = nil
+			li.Style = st
+		}
+	}
+}
+
+// equalStyleAttributes checks whether style attributes are equal, nil being equal to empty attributes
+func equalStyleAttributes(a, b *StyleAttributes) bool {
+	if a == nil {
+		a = &StyleAttributes{}
+	}
+	if b == nil {
+		b = &StyleAttributes{}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// mergeStyleAttributes returns style attributes where empty attributes of i are set to the ones of parent
+func mergeStyleAttributes(i, parent *StyleAttributes) (o *StyleAttributes) {
+	// Nothing to merge
+	if parent == nil {
+		return i
+	}
+
+	// Copy attributes
+	o = &StyleAttributes{}
+	if i != nil {
+		*o = *i
+	}
+
+	// Loop through fields
+	var ov, pv = reflect.ValueOf(o).Elem(), reflect.ValueOf(parent).Elem()
+	for idx := 0; idx < ov.NumField(); idx++ {
+		var f = ov.Field(idx)
+		if reflect.DeepEqual(f.Interface(), reflect.Zero(f.Type()).Interface()) {
+			f.Set(pv.Field(idx))
+		}
+	}
+	return
+}
+
+// OrderKey represents a key items are ordered by
+type OrderKey int
+
+// Order keys
+const (
+	// Items ending first come first
+	OrderKeyEndAt OrderKey = iota
+	// Items with the lowest SSA layer come first, items without layer being on layer 0
+	OrderKeyLayer
+	// Items are ordered by region ID, items without region coming first
+	OrderKeyRegion
+)
+
+// OrderOptions represents Order options
+type OrderOptions struct {
+	// Comparator used when start times and secondary keys are equal. It must return a negative number when a comes
+	// before b, a positive number when b comes before a, and 0 when their order doesn't matter.
+	Compare func(a, b *Item) int
+	// Keys used, in order, when start times are equal
+	SecondaryKeys []OrderKey
+}
+
+// Order orders items by start time. Items starting at the same time keep their relative order.
 func (s *Subtitles) Order() {
-	// Nothing to do if less than 1 element
-	if len(s.Items) <= 1 {
-		return
+	s.OrderWithOptions(OrderOptions{})
+}
+
+// OrderWithOptions orders items by start time, then by secondary keys and custom comparator. Items that are still
+// equal keep their relative order so that output is deterministic.
+func (s *Subtitles) OrderWithOptions(o OrderOptions) {
+	sort.SliceStable(s.Items, func(i, j int) bool {
+		return compareItems(s.Items[i], s.Items[j], o) < 0
+	})
+}
+
+// compareItems compares 2 items according to order options
+func compareItems(a, b *Item, o OrderOptions) int {
+	// Start at
+	if a.StartAt != b.StartAt {
+		return compareDurations(a.StartAt, b.StartAt)
 	}
 
-	// Order
-	var swapped = true
-	for swapped {
-		swapped = false
-		for index := 1; index < len(s.Items); index++ {
-			if s.Items[index-1].StartAt > s.Items[index].StartAt {
-				var tmp = s.Items[index-1]
-				s.Items[index-1] = s.Items[index]
-				s.Items[index] = tmp
-				swapped = true
+	// Loop through secondary keys
+	for _, k := range o.SecondaryKeys {
+		var c int
+		switch k {
+		case OrderKeyEndAt:
+			c = compareDurations(a.EndAt, b.EndAt)
+		case OrderKeyLayer:
+			c = itemLayer(a) - itemLayer(b)
+		case OrderKeyRegion:
+			c = strings.Compare(itemRegionID(a), itemRegionID(b))
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	// Custom comparator
+	if o.Compare != nil {
+		return o.Compare(a, b)
+	}
+	return 0
+}
+
+// compareDurations compares 2 durations
+func compareDurations(a, b time.Duration) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// itemLayer returns the SSA layer of an item
+func itemLayer(i *Item) int {
+	if i.InlineStyle != nil && i.InlineStyle.SSALayer != nil {
+		return *i.InlineStyle.SSALayer
+	}
+	return 0
+}
+
+// itemRegionID returns the region ID of an item
+func itemRegionID(i *Item) string {
+	if i.Region != nil {
+		return i.Region.ID
+	}
+	return ""
+}
+
+// ItemHasVoiceName returns a predicate matching items with at least one line spoken by a voice
+func ItemHasVoiceName(name string) func(i *Item) bool {
+	return func(i *Item) bool {
+		for _, l := range i.Lines {
+			if l.VoiceName == name {
+				return true
 			}
 		}
+		return false
+	}
+}
+
+// ApplyStyle sets the style of the items matching the predicate and adds it to the subtitles styles. A nil predicate
+// matches every item.
+func (s *Subtitles) ApplyStyle(style *Style, predicate func(i *Item) bool) {
+	// Add style
+	if s.Styles == nil {
+		s.Styles = make(map[string]*Style)
+	}
+	s.Styles[style.ID] = style
+
+	// Loop through items
+	for _, i := range s.Items {
+		if predicate == nil || predicate(i) {
+			i.Style = style
+		}
+	}
+}
+
+// ApplyInlineStyle merges attributes into the inline style of the items matching the predicate, non-empty attributes
+// overriding the items' ones. A nil predicate matches every item.
+func (s *Subtitles) ApplyInlineStyle(attrs *StyleAttributes, predicate func(i *Item) bool) {
+	for _, i := range s.Items {
+		if predicate == nil || predicate(i) {
+			var c = *attrs
+			i.InlineStyle = mergeStyleAttributes(&c, i.InlineStyle)
+		}
 	}
 }
 
@@ -547,12 +1903,25 @@ func (s *Subtitles) Unfragment() {
 		return
 	}
 
+	// When continuation markers are used, only marked items are re-joined
+	var markers bool
+	for _, i := range s.Items {
+		if i.isContinued() {
+			markers = true
+			break
+		}
+	}
+
 	// Loop through items
 	for i := 0; i < len(s.Items)-1; i++ {
 		for j := i + 1; j < len(s.Items); j++ {
 			// Items are the same
-			if s.Items[i].String() == s.Items[j].String() && s.Items[i].EndAt == s.Items[j].StartAt {
+			if s.Items[i].String() == s.Items[j].String() && s.Items[i].EndAt == s.Items[j].StartAt && (!markers || s.Items[i].isContinued()) {
 				s.Items[i].EndAt = s.Items[j].EndAt
+				if markers {
+					// The joined item is continued only if the last re-joined one was
+					s.Items[i].Comments = s.Items[j].Comments
+				}
 				s.Items = append(s.Items[:j], s.Items[j+1:]...)
 				j--
 			}
@@ -563,31 +1932,132 @@ func (s *Subtitles) Unfragment() {
 	s.Order()
 }
 
-// Write writes subtitles to a file
-func (s Subtitles) Write(dst string) (err error) {
-	// Create the file
-	var f *os.File
-	if f, err = os.Create(dst); err != nil {
-		err = errors.Wrapf(err, "astisub: creating %s failed", dst)
-		return
+// WriteToFormat writes subtitles in a specific format to any writer, such as an HTTP response body, a pipe or an
+// in-memory buffer
+func (s Subtitles) WriteToFormat(o io.Writer, f Format) (err error) {
+	switch f {
+	case FormatJSON:
+		err = s.WriteToJSON(o)
+	case FormatMicroDVD:
+		err = s.WriteToMicroDVD(o)
+	case FormatSBV:
+		err = s.WriteToSBV(o)
+	case FormatSRT:
+		err = s.WriteToSRT(o)
+	case FormatSSA:
+		err = s.WriteToSSA(o)
+	case FormatSTL:
+		err = s.WriteToSTL(o)
+	case FormatTTML:
+		err = s.WriteToTTML(o)
+	case FormatWebVTT:
+		err = s.WriteToWebVTT(o)
+	default:
+		err = ErrInvalidFormat
 	}
-	defer f.Close()
+	return
+}
 
-	// Write the content
-	switch filepath.Ext(dst) {
-	case ".srt":
-		err = s.WriteToSRT(f)
-	case ".ssa", ".ass":
-		err = s.WriteToSSA(f)
-	case ".stl":
-		err = s.WriteToSTL(f)
-	case ".ttml":
-		err = s.WriteToTTML(f)
-	case ".vtt":
-		err = s.WriteToWebVTT(f)
+// WriteToFormatWithOptions writes subtitles in a specific format to any writer with options. Cue texts are
+// transformed first, whatever the format, and the item hook is called by the writer of the format.
+func (s Subtitles) WriteToFormatWithOptions(o io.Writer, f Format, opts Options) (err error) {
+	// Format cue texts
+	if opts.CueText.DialogueStyle != (DialogueStyle{}) || opts.CueText.Template != nil {
+		if s, err = s.FormatCueTexts(opts.CueText); err != nil {
+			err = errors.Wrap(err, "astisub: formatting cue texts failed")
+			return
+		}
+	}
+
+	// Apply limits
+	if opts.Limits != (Limits{}) {
+		s.Items = limitItems(s.Items, opts.Limits)
+	}
+
+	// Convert text formats
+	if f.isText() && (opts.WriteBOM || len(opts.WriteCharset) > 0) {
+		return s.writeToFormatInCharset(o, f, opts)
+	}
+	return s.writeToFormat(o, f, opts)
+}
+
+// writeToFormat writes subtitles in a specific format with the options of the format, the writer calling the item
+// hook
+func (s Subtitles) writeToFormat(o io.Writer, f Format, opts Options) (err error) {
+	switch f {
+	case FormatJSON:
+		err = s.writeToJSON(o, opts.BeforeWriteItem)
+	case FormatMicroDVD:
+		err = s.writeToMicroDVD(o, opts.MicroDVD, opts.BeforeWriteItem)
+	case FormatSBV:
+		err = s.writeToSBV(o, opts.BeforeWriteItem)
+	case FormatSRT:
+		err = s.writeToSRT(o, SRTOptions{}, opts.BeforeWriteItem)
+	case FormatSSA:
+		err = s.writeToSSA(o, SSAOptions{}, opts.BeforeWriteItem)
+	case FormatSTL:
+		err = s.writeToSTL(o, STLOptions{GlyphPlaceholder: opts.GlyphPlaceholder, GlyphPolicy: opts.GlyphPolicy}, opts.BeforeWriteItem)
+	case FormatTTML:
+		err = s.writeToTTML(o, opts.TTML, opts.BeforeWriteItem)
+	case FormatWebVTT:
+		err = s.writeToWebVTT(o, WebVTTOptions{}, opts.BeforeWriteItem)
 	default:
-		err = ErrInvalidExtension
+		err = ErrInvalidFormat
+	}
+	return
+}
+
+// WriteEstimate represents what writing subtitles in a specific format would produce
+type WriteEstimate struct {
+	Bytes   int       // Size of the output
+	Cues    int       // Number of cues of the output, once items have been split to comply with limits
+	Dropped []Warning // Features that will be dropped, see FidelityWarnings
+	// Number of parts SplitAtMaxTime splits the subtitles into. More than 1 means times exceed the maximum time the
+	// format can represent.
+	Parts int
+}
+
+// writeCounter is a writer counting the bytes written to it and discarding them
+type writeCounter struct {
+	n int
+}
+
+// Write implements the io.Writer interface
+func (w *writeCounter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+// EstimateWrite predicts what writing subtitles in a specific format would produce without producing the output,
+// which allows checking payload limits beforehand
+func (s Subtitles) EstimateWrite(f Format) (WriteEstimate, error) {
+	return s.EstimateWriteWithOptions(f, Options{})
+}
+
+// EstimateWriteWithOptions predicts what writing subtitles in a specific format with options would produce. Cues are
+// counted as they're written, which takes into account items split to comply with limits.
+func (s Subtitles) EstimateWriteWithOptions(f Format, opts Options) (e WriteEstimate, err error) {
+	// Count cues
+	var h = opts.BeforeWriteItem
+	opts.BeforeWriteItem = func(i *Item) (err error) {
+		e.Cues++
+		if h != nil {
+			err = h(i)
+		}
+		return
 	}
+
+	// Write
+	var w = &writeCounter{}
+	if err = s.WriteToFormatWithOptions(w, f, opts); err != nil {
+		err = errors.Wrapf(err, "astisub: writing to %s failed", f)
+		return
+	}
+
+	// Update estimate
+	e.Bytes = w.n
+	e.Dropped = s.FidelityWarnings(f)
+	e.Parts = len(s.SplitAtMaxTime(f))
 	return
 }
 
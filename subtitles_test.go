@@ -1,10 +1,16 @@
 package astisub_test
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +49,116 @@ func mockSubtitles() *astisub.Subtitles {
 	return &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 3 * time.Second, StartAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "subtitle-1"}}}}}, {EndAt: 7 * time.Second, StartAt: 3 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "subtitle-2"}}}}}}}
 }
 
+func TestFormatFromExtension(t *testing.T) {
+	f, err := astisub.FormatFromExtension(".ASS")
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.FormatSSA, f)
+	f, err = astisub.FormatFromExtension(".vtt")
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.FormatWebVTT, f)
+	_, err = astisub.FormatFromExtension(".invalid")
+	assert.Equal(t, astisub.ErrInvalidExtension, err)
+}
+
+func TestReadWriteToFormat(t *testing.T) {
+	// Read
+	i, err := ioutil.ReadFile("./testdata/example-in.srt")
+	assert.NoError(t, err)
+	s, err := astisub.Read(bytes.NewReader(i), astisub.FormatSRT, astisub.Options{})
+	assert.NoError(t, err)
+	assertSubtitleItems(t, s)
+	_, err = astisub.Read(bytes.NewReader(i), astisub.Format("invalid"), astisub.Options{})
+	assert.Equal(t, astisub.ErrInvalidFormat, err)
+
+	// Write
+	c, err := ioutil.ReadFile("./testdata/example-out.srt")
+	assert.NoError(t, err)
+	w := &bytes.Buffer{}
+	assert.NoError(t, s.WriteToFormat(w, astisub.FormatSRT))
+	assert.Equal(t, string(c), w.String())
+	assert.Equal(t, astisub.ErrInvalidFormat, s.WriteToFormat(w, astisub.FormatTeletext))
+}
+
+func TestSubtitles_EstimateWrite(t *testing.T) {
+	s := astisub.NewSubtitles()
+	_, err := s.EstimateWrite(astisub.FormatSRT)
+	assert.Error(t, err)
+
+	s.Items = []*astisub.Item{
+		{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Hello"}}}}},
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{SRTItalics: astiptr.Bool(true)}, Text: "world"}}}}, Region: &astisub.Region{ID: "r"}, StartAt: time.Second},
+	}
+	for _, f := range []astisub.Format{astisub.FormatSBV, astisub.FormatSRT, astisub.FormatWebVTT} {
+		w := &bytes.Buffer{}
+		assert.NoError(t, s.WriteToFormat(w, f))
+		e, err := s.EstimateWrite(f)
+		assert.NoError(t, err)
+		assert.Equal(t, w.Len(), e.Bytes)
+		assert.Equal(t, 2, e.Cues)
+	}
+	e, err := s.EstimateWrite(astisub.FormatSBV)
+	assert.NoError(t, err)
+	assert.Equal(t, []astisub.Warning{
+		{Message: "region of item #2 is not supported by sbv and will be dropped"},
+		{Message: "styles of item #2 are not supported by sbv and will be dropped"},
+	}, e.Dropped)
+	e, err = s.EstimateWrite(astisub.FormatWebVTT)
+	assert.NoError(t, err)
+	assert.Empty(t, e.Dropped)
+	assert.Equal(t, 1, e.Parts)
+
+	// Limits
+	e, err = astisub.Subtitles{Items: []*astisub.Item{{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Hello world"}}}}}}}.EstimateWriteWithOptions(astisub.FormatSRT, astisub.Options{Limits: astisub.Limits{MaxCharactersPerLine: 5, MaxLines: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, e.Cues)
+
+	// Max time
+	s.Items[1].EndAt = 25 * time.Hour
+	e, err = s.EstimateWrite(astisub.FormatSTL)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, e.Parts)
+}
+
+func TestDetectFormat(t *testing.T) {
+	// Files
+	for ext, f := range map[string]astisub.Format{
+		"sbv":  astisub.FormatSBV,
+		"scc":  astisub.FormatSCC,
+		"srt":  astisub.FormatSRT,
+		"ssa":  astisub.FormatSSA,
+		"stl":  astisub.FormatSTL,
+		"sub":  astisub.FormatMicroDVD,
+		"ttml": astisub.FormatTTML,
+		"vtt":  astisub.FormatWebVTT,
+	} {
+		fl, err := os.Open("./testdata/example-in." + ext)
+		assert.NoError(t, err)
+		df, r, err := astisub.DetectFormat(fl)
+		assert.NoError(t, err)
+		assert.Equal(t, f, df, ext)
+
+		// Sniffed bytes are read again
+		s, err := astisub.Read(r, df, astisub.Options{})
+		assert.NoError(t, err, ext)
+		assert.NotEmpty(t, s.Items, ext)
+		fl.Close()
+	}
+
+	// Teletext
+	var b = make([]byte, 2*188)
+	b[0], b[188] = 0x47, 0x47
+	f, _, err := astisub.DetectFormat(bytes.NewReader(b))
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.FormatTeletext, f)
+
+	// Invalid
+	_, r, err := astisub.DetectFormat(strings.NewReader("not subtitles"))
+	assert.Equal(t, astisub.ErrInvalidFormat, err)
+	c, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "not subtitles", string(c))
+}
+
 func TestSubtitles_Add(t *testing.T) {
 	var s = mockSubtitles()
 	s.Add(time.Second)
@@ -53,9 +169,105 @@ func TestSubtitles_Add(t *testing.T) {
 	assert.Equal(t, 4*time.Second, s.Items[0].EndAt)
 }
 
+func TestSubtitles_AddWithOptions(t *testing.T) {
+	// Clamp
+	var s = mockSubtitles()
+	r := s.AddWithOptions(-4*time.Second, astisub.AddOptions{ClampAtZero: true})
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, time.Duration(0), s.Items[0].EndAt)
+	assert.Equal(t, time.Duration(0), s.Items[1].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, astisub.AddReport{Clamped: s.Items}, r)
+
+	// Drop
+	s = mockSubtitles()
+	var is = s.Items
+	r = s.AddWithOptions(-2*time.Second, astisub.AddOptions{DropNegative: true})
+	assert.Equal(t, is[1:], s.Items)
+	assert.Equal(t, astisub.AddReport{Dropped: is[:1]}, r)
+
+	// Clamp and drop
+	s = mockSubtitles()
+	is = s.Items
+	r = s.AddWithOptions(-4*time.Second, astisub.AddOptions{ClampAtZero: true, DropNegative: true})
+	assert.Equal(t, is[1:], s.Items)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, astisub.AddReport{Clamped: is[1:], Dropped: is[:1]}, r)
+}
+
+func TestSubtitles_Scale(t *testing.T) {
+	var s = mockSubtitles()
+	s.Scale(1.5)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, 1500*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 4500*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, 4500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 10500*time.Millisecond, s.Items[1].EndAt)
+}
+
+func TestSubtitles_Sync(t *testing.T) {
+	// Offset and factor
+	var s = mockSubtitles()
+	s.Sync(-time.Second, 1.5)
+	assert.Equal(t, 500*time.Millisecond, s.Items[0].StartAt)
+	assert.Equal(t, 3500*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, 3500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 9500*time.Millisecond, s.Items[1].EndAt)
+
+	// Anchors
+	s = mockSubtitles()
+	err := s.SyncWithAnchors(time.Second, 2*time.Second, 7*time.Second, 14*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 2*time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 6*time.Second, s.Items[0].EndAt)
+	assert.Equal(t, 6*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 14*time.Second, s.Items[1].EndAt)
+
+	// 23.976 fps to 25 fps
+	s = mockSubtitles()
+	err = s.SyncWithAnchors(0, 0, 25025*time.Millisecond, 24*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 959040959*time.Nanosecond, s.Items[0].StartAt)
+
+	// Invalid anchors
+	err = s.SyncWithAnchors(time.Second, 0, time.Second, 2*time.Second)
+	assert.EqualError(t, errors.Cause(err), astisub.ErrInvalidAnchors.Error())
+}
+
+func TestSubtitles_TransformTimes(t *testing.T) {
+	var s = mockSubtitles()
+	s.Items[0].Lines = []astisub.Line{{Items: []astisub.LineItem{{EndAt: 2 * time.Second, StartAt: time.Second, Text: "1"}, {Text: "2"}}}}
+	s.TransformTimes(func(start, end time.Duration) (time.Duration, time.Duration) {
+		// Content after 2s plays twice as fast
+		var conform = func(d time.Duration) time.Duration {
+			if d <= 2*time.Second {
+				return d
+			}
+			return 2*time.Second + (d-2*time.Second)/2
+		}
+		return conform(start), conform(end)
+	})
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 2500*time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, time.Second, s.Items[0].Lines[0].Items[0].StartAt)
+	assert.Equal(t, 2*time.Second, s.Items[0].Lines[0].Items[0].EndAt)
+	assert.Equal(t, time.Duration(0), s.Items[0].Lines[0].Items[1].EndAt)
+	assert.Equal(t, 2500*time.Millisecond, s.Items[1].StartAt)
+	assert.Equal(t, 4500*time.Millisecond, s.Items[1].EndAt)
+}
+
 func TestSubtitles_Duration(t *testing.T) {
 	assert.Equal(t, time.Duration(0), astisub.Subtitles{}.Duration())
 	assert.Equal(t, 7*time.Second, mockSubtitles().Duration())
+
+	// Unordered items
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 9 * time.Second, StartAt: 2 * time.Second}, {EndAt: 3 * time.Second, StartAt: time.Second}, {EndAt: 5 * time.Second, StartAt: 4 * time.Second}}}
+	assert.Equal(t, 9*time.Second, s.Duration())
+	assert.Equal(t, time.Second, s.MinStart())
+	start, end := s.TimeRange()
+	assert.Equal(t, time.Second, start)
+	assert.Equal(t, 9*time.Second, end)
+	assert.Equal(t, time.Duration(0), astisub.Subtitles{}.MinStart())
 }
 
 func TestSubtitles_IsEmpty(t *testing.T) {
@@ -76,6 +288,34 @@ func TestSubtitles_ForceDuration(t *testing.T) {
 	assert.Len(t, s.Items, 3)
 	assert.Equal(t, 10*time.Second, s.Items[2].EndAt)
 	assert.Equal(t, 7*time.Second, s.Items[2].StartAt)
+
+	// Extend last item
+	s = mockSubtitles()
+	s.ForceDurationWithOptions(10*time.Second, astisub.ForceDurationOptions{ExtendLastItem: true})
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, 10*time.Second, s.Items[1].EndAt)
+
+	// Custom placeholder
+	s = mockSubtitles()
+	s.ForceDurationWithOptions(10*time.Second, astisub.ForceDurationOptions{
+		PlaceholderAtStart:  true,
+		PlaceholderDuration: 2 * time.Second,
+		PlaceholderText:     " ",
+	})
+	assert.Len(t, s.Items, 4)
+	assert.Equal(t, time.Duration(0), s.Items[0].StartAt)
+	assert.Equal(t, time.Second, s.Items[0].EndAt)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: " "}}}}, s.Items[0].Lines)
+	assert.Equal(t, 8*time.Second, s.Items[3].StartAt)
+	assert.Equal(t, 10*time.Second, s.Items[3].EndAt)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{Text: " "}}}}, s.Items[3].Lines)
+
+	// Padding from zero
+	s = &astisub.Subtitles{}
+	s.ForceDurationWithOptions(10*time.Second, astisub.ForceDurationOptions{PlaceholderAtStart: true})
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, time.Millisecond, s.Items[0].EndAt)
+	assert.Equal(t, 10*time.Second-time.Millisecond, s.Items[1].StartAt)
 }
 
 func TestSubtitles_Fragment(t *testing.T) {
@@ -116,6 +356,38 @@ func TestSubtitles_Fragment(t *testing.T) {
 	assert.Equal(t, 5*time.Second, s.Items[2].EndAt)
 }
 
+func TestSubtitles_FragmentContinuationMarkers(t *testing.T) {
+	// Init
+	var s = mockSubtitles()
+	s.Items = append(s.Items, &astisub.Item{EndAt: 8 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "subtitle-2"}}}}, StartAt: 7 * time.Second})
+
+	// Fragment
+	s.FragmentWithOptions(2*time.Second, astisub.FragmentOptions{ContinuationMarkers: true})
+	assert.Len(t, s.Items, 6)
+	assert.Equal(t, []string{astisub.ContinuationMarker}, s.Items[0].Comments)
+	assert.Empty(t, s.Items[1].Comments)
+	assert.Equal(t, []string{astisub.ContinuationMarker}, s.Items[3].Comments)
+	assert.Empty(t, s.Items[4].Comments)
+
+	// Markers survive being written and read
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	s, err = astisub.ReadFromWebVTT(w)
+	assert.NoError(t, err)
+
+	// Unfragment
+	s.Unfragment()
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, time.Second, s.Items[0].StartAt)
+	assert.Equal(t, 3*time.Second, s.Items[0].EndAt)
+	assert.Empty(t, s.Items[0].Comments)
+	assert.Equal(t, 3*time.Second, s.Items[1].StartAt)
+	assert.Equal(t, 7*time.Second, s.Items[1].EndAt)
+	assert.Equal(t, 7*time.Second, s.Items[2].StartAt)
+	assert.Equal(t, 8*time.Second, s.Items[2].EndAt)
+}
+
 func TestSubtitles_Merge(t *testing.T) {
 	var s1 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 3 * time.Second, StartAt: time.Second}, {EndAt: 8 * time.Second, StartAt: 5 * time.Second}, {EndAt: 12 * time.Second, StartAt: 10 * time.Second}}, Regions: map[string]*astisub.Region{"region_0": {ID: "region_0"}, "region_1": {ID: "region_1"}}, Styles: map[string]*astisub.Style{"style_0": {ID: "style_0"}, "style_1": {ID: "style_1"}}}
 	var s2 = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 4 * time.Second, StartAt: 2 * time.Second}, {EndAt: 7 * time.Second, StartAt: 6 * time.Second}, {EndAt: 11 * time.Second, StartAt: 9 * time.Second}, {EndAt: 14 * time.Second, StartAt: 13 * time.Second}}, Regions: map[string]*astisub.Region{"region_1": {ID: "region_1"}, "region_2": {ID: "region_2"}}, Styles: map[string]*astisub.Style{"style_1": {ID: "style_1"}, "style_2": {ID: "style_2"}}}
@@ -132,6 +404,66 @@ func TestSubtitles_Merge(t *testing.T) {
 	assert.Equal(t, len(s1.Styles), 3)
 }
 
+func TestSubtitles_MergeWithOptions(t *testing.T) {
+	var newSubtitles = func() (s1, s2 *astisub.Subtitles) {
+		s1 = &astisub.Subtitles{Items: []*astisub.Item{
+			{EndAt: 3 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "p1"}}}}, StartAt: time.Second},
+			{EndAt: 8 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "p2"}}}}, StartAt: 5 * time.Second},
+		}}
+		s2 = &astisub.Subtitles{Items: []*astisub.Item{
+			{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "s1"}}}}, StartAt: 2 * time.Second},
+			{EndAt: 11 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "s2"}}}}, StartAt: 9 * time.Second},
+		}}
+		return
+	}
+
+	// Prefer primary
+	s1, s2 := newSubtitles()
+	s1.MergeWithOptions(s2, astisub.MergeOptions{Mode: astisub.MergeModePreferPrimary})
+	assert.Equal(t, []string{"p1", "p2", "s2"}, []string{s1.Items[0].String(), s1.Items[1].String(), s1.Items[2].String()})
+
+	// Shift secondary
+	s1, s2 = newSubtitles()
+	s1.MergeWithOptions(s2, astisub.MergeOptions{Mode: astisub.MergeModeShiftSecondary})
+	assert.Len(t, s1.Items, 4)
+	assert.Equal(t, "s1", s1.Items[1].String())
+	assert.Equal(t, 3*time.Second, s1.Items[1].StartAt)
+	assert.Equal(t, 5*time.Second, s1.Items[1].EndAt)
+	assert.Equal(t, "p2", s1.Items[2].String())
+
+	// Stack
+	s1, s2 = newSubtitles()
+	s1.MergeWithOptions(s2, astisub.MergeOptions{Mode: astisub.MergeModeStack})
+	assert.Len(t, s1.Items, 5)
+	for idx, e := range []struct {
+		endAt, startAt time.Duration
+		text           string
+	}{
+		{endAt: 2 * time.Second, startAt: time.Second, text: "p1"},
+		{endAt: 3 * time.Second, startAt: 2 * time.Second, text: "p1 - s1"},
+		{endAt: 4 * time.Second, startAt: 3 * time.Second, text: "s1"},
+		{endAt: 8 * time.Second, startAt: 5 * time.Second, text: "p2"},
+		{endAt: 11 * time.Second, startAt: 9 * time.Second, text: "s2"},
+	} {
+		assert.Equal(t, e.startAt, s1.Items[idx].StartAt)
+		assert.Equal(t, e.endAt, s1.Items[idx].EndAt)
+		assert.Equal(t, e.text, s1.Items[idx].String())
+	}
+	assert.Equal(t, s2.Items[1], s1.Items[4])
+
+	// Rename conflicts
+	s1 = &astisub.Subtitles{Regions: map[string]*astisub.Region{"r": {ID: "r"}}, Styles: map[string]*astisub.Style{"s": {ID: "s"}, "s_2": {ID: "s_2"}}}
+	var r, st = &astisub.Region{ID: "r"}, &astisub.Style{ID: "s"}
+	s2 = &astisub.Subtitles{Items: []*astisub.Item{{Region: r, Style: st}}, Regions: map[string]*astisub.Region{"r": r}, Styles: map[string]*astisub.Style{"s": st}}
+	s1.MergeWithOptions(s2, astisub.MergeOptions{RenameConflicts: true})
+	assert.Len(t, s1.Regions, 2)
+	assert.Equal(t, r, s1.Regions["r_2"])
+	assert.Len(t, s1.Styles, 3)
+	assert.Equal(t, st, s1.Styles["s_3"])
+	assert.Equal(t, "r_2", s1.Items[0].Region.ID)
+	assert.Equal(t, "s_3", s1.Items[0].Style.ID)
+}
+
 func TestSubtitles_Optimize(t *testing.T) {
 	var s = &astisub.Subtitles{
 		Items: []*astisub.Item{
@@ -156,6 +488,46 @@ func TestSubtitles_Optimize(t *testing.T) {
 	assert.Len(t, s.Styles, 3)
 }
 
+func TestSubtitles_NormalizeStyles(t *testing.T) {
+	// Merge identical styles and remove unused ones
+	var p1, p2 = &astisub.Style{ID: "p1", InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}, &astisub.Style{ID: "p2", InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}
+	var c1, c2 = &astisub.Style{ID: "c1", Style: p1}, &astisub.Style{ID: "c2", InlineStyle: &astisub.StyleAttributes{}, Style: p2}
+	var s = &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{Style: c1},
+			{Lines: []astisub.Line{{Items: []astisub.LineItem{{Style: c2}}}}},
+		},
+		Regions: map[string]*astisub.Region{"r": {ID: "r"}},
+		Styles:  map[string]*astisub.Style{"c1": c1, "c2": c2, "p1": p1, "p2": p2, "unused": {ID: "unused"}},
+	}
+	s.NormalizeStyles()
+	assert.Equal(t, map[string]*astisub.Style{"c1": c1, "p1": p1}, s.Styles)
+	assert.Empty(t, s.Regions)
+	assert.Equal(t, c1, s.Items[1].Lines[0].Items[0].Style)
+
+	// Inline single use styles
+	s.NormalizeStylesWithOptions(astisub.NormalizeStylesOptions{InlineSingleUseStyles: true})
+	assert.Equal(t, map[string]*astisub.Style{"c1": c1, "p1": p1}, s.Styles)
+	s.Items[1].Lines[0].Items[0].Style = p1
+	s.Items[0].InlineStyle = &astisub.StyleAttributes{TTMLFontSize: "10px"}
+	s.NormalizeStylesWithOptions(astisub.NormalizeStylesOptions{InlineSingleUseStyles: true})
+	assert.Equal(t, map[string]*astisub.Style{"p1": p1}, s.Styles)
+	assert.Equal(t, p1, s.Items[0].Style)
+	assert.Equal(t, &astisub.StyleAttributes{TTMLFontSize: "10px"}, s.Items[0].InlineStyle)
+
+	// Factor inline styles
+	s = &astisub.Subtitles{Items: []*astisub.Item{
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}, {InlineStyle: &astisub.StyleAttributes{TTMLColor: "blue"}}}}}},
+		{Lines: []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}}}}},
+	}}
+	s.NormalizeStylesWithOptions(astisub.NormalizeStylesOptions{FactorInlineStyles: true})
+	assert.Len(t, s.Styles, 1)
+	assert.Equal(t, &astisub.Style{ID: "style_1", InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}, s.Styles["style_1"])
+	assert.Equal(t, astisub.LineItem{Style: s.Styles["style_1"]}, s.Items[0].Lines[0].Items[0])
+	assert.Equal(t, astisub.LineItem{InlineStyle: &astisub.StyleAttributes{TTMLColor: "blue"}}, s.Items[0].Lines[0].Items[1])
+	assert.Equal(t, astisub.LineItem{Style: s.Styles["style_1"]}, s.Items[1].Lines[0].Items[0])
+}
+
 func TestSubtitles_Order(t *testing.T) {
 	var s = &astisub.Subtitles{Items: []*astisub.Item{{StartAt: 4 * time.Second, EndAt: 5 * time.Second}, {StartAt: 2 * time.Second, EndAt: 3 * time.Second}, {StartAt: 3 * time.Second, EndAt: 4 * time.Second}, {StartAt: time.Second, EndAt: 2 * time.Second}}}
 	s.Order()
@@ -169,6 +541,114 @@ func TestSubtitles_Order(t *testing.T) {
 	assert.Equal(t, 5*time.Second, s.Items[3].EndAt)
 }
 
+func TestSubtitles_OrderWithOptions(t *testing.T) {
+	var top, bottom = &astisub.Region{ID: "top"}, &astisub.Region{ID: "bottom"}
+	var layer = 1
+	var is = []*astisub.Item{
+		{EndAt: 3 * time.Second, Region: top, StartAt: time.Second},
+		{EndAt: 2 * time.Second, Region: top, StartAt: time.Second},
+		{EndAt: 3 * time.Second, InlineStyle: &astisub.StyleAttributes{SSALayer: &layer}, Region: bottom, StartAt: time.Second},
+		{EndAt: 3 * time.Second, Region: bottom, StartAt: time.Second},
+	}
+
+	// Stable
+	var s = &astisub.Subtitles{Items: append([]*astisub.Item{}, is...)}
+	s.Order()
+	assert.Equal(t, is, s.Items)
+
+	// Secondary keys
+	s.OrderWithOptions(astisub.OrderOptions{SecondaryKeys: []astisub.OrderKey{astisub.OrderKeyRegion, astisub.OrderKeyLayer}})
+	assert.Equal(t, []*astisub.Item{is[3], is[2], is[0], is[1]}, s.Items)
+	s.OrderWithOptions(astisub.OrderOptions{SecondaryKeys: []astisub.OrderKey{astisub.OrderKeyEndAt}})
+	assert.Equal(t, []*astisub.Item{is[1], is[3], is[2], is[0]}, s.Items)
+
+	// Custom comparator
+	s.OrderWithOptions(astisub.OrderOptions{Compare: func(a, b *astisub.Item) int {
+		if a.Region == top && b.Region != top {
+			return -1
+		} else if a.Region != top && b.Region == top {
+			return 1
+		}
+		return 0
+	}})
+	assert.Equal(t, []*astisub.Item{is[1], is[0], is[3], is[2]}, s.Items)
+}
+
+func TestSubtitles_ApplyStyle(t *testing.T) {
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		{InlineStyle: &astisub.StyleAttributes{TTMLColor: "red", TTMLFontWeight: "bold"}, Lines: []astisub.Line{{VoiceName: "Alice"}}},
+		{Lines: []astisub.Line{{VoiceName: "Bob"}}},
+		{Lines: []astisub.Line{{VoiceName: "Bob"}, {VoiceName: "Alice"}}},
+	}}
+
+	// Style
+	var italic = &astisub.Style{ID: "italic", InlineStyle: &astisub.StyleAttributes{TTMLFontStyle: "italic"}}
+	s.ApplyStyle(italic, astisub.ItemHasVoiceName("Alice"))
+	assert.Equal(t, map[string]*astisub.Style{"italic": italic}, s.Styles)
+	assert.Equal(t, italic, s.Items[0].Style)
+	assert.Nil(t, s.Items[1].Style)
+	assert.Equal(t, italic, s.Items[2].Style)
+
+	// Inline style
+	var attrs = &astisub.StyleAttributes{TTMLColor: "yellow"}
+	s.ApplyInlineStyle(attrs, nil)
+	assert.Equal(t, &astisub.StyleAttributes{TTMLColor: "yellow", TTMLFontWeight: "bold"}, s.Items[0].InlineStyle)
+	assert.Equal(t, attrs, s.Items[1].InlineStyle)
+	assert.False(t, attrs == s.Items[1].InlineStyle)
+}
+
+func TestStyle_Resolve(t *testing.T) {
+	// Style chain
+	var base = &astisub.Style{ID: "base", InlineStyle: &astisub.StyleAttributes{TTMLColor: "white", TTMLFontFamily: "sansSerif", TTMLFontSize: "1c"}}
+	var child = &astisub.Style{ID: "child", InlineStyle: &astisub.StyleAttributes{TTMLColor: "yellow"}, Style: base}
+	var grandChild = &astisub.Style{ID: "grandChild", InlineStyle: &astisub.StyleAttributes{TTMLFontStyle: "italic"}, Style: child}
+	assert.Equal(t, &astisub.StyleAttributes{TTMLColor: "yellow", TTMLFontFamily: "sansSerif", TTMLFontSize: "1c", TTMLFontStyle: "italic"}, grandChild.Resolve())
+	assert.Equal(t, &astisub.StyleAttributes{}, (*astisub.Style)(nil).Resolve())
+
+	// Cycles
+	base.Style = grandChild
+	assert.Equal(t, &astisub.StyleAttributes{TTMLColor: "yellow", TTMLFontFamily: "sansSerif", TTMLFontSize: "1c", TTMLFontStyle: "italic"}, grandChild.Resolve())
+	base.Style = nil
+
+	// Item
+	var i = astisub.Item{
+		InlineStyle: &astisub.StyleAttributes{TTMLFontWeight: "bold"},
+		Lines:       []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}, Text: "1"}}}},
+		Region:      &astisub.Region{ID: "region", InlineStyle: &astisub.StyleAttributes{TTMLFontSize: "2c", TTMLOrigin: "0% 80%"}},
+		Style:       child,
+	}
+	assert.Equal(t, &astisub.StyleAttributes{TTMLColor: "yellow", TTMLFontFamily: "sansSerif", TTMLFontSize: "1c", TTMLFontWeight: "bold", TTMLOrigin: "0% 80%"}, i.ResolvedStyleAttributes())
+	assert.Equal(t, &astisub.StyleAttributes{TTMLColor: "red", TTMLFontFamily: "sansSerif", TTMLFontSize: "1c", TTMLFontWeight: "bold", TTMLOrigin: "0% 80%"}, i.Lines[0].Items[0].ResolvedStyleAttributes(i))
+
+	// Returned attributes can be modified safely
+	i.ResolvedStyleAttributes().TTMLColor = "blue"
+	assert.Equal(t, "bold", i.InlineStyle.TTMLFontWeight)
+	assert.Equal(t, "yellow", child.InlineStyle.TTMLColor)
+}
+
+func TestStyleAttributes_GenericAttributes(t *testing.T) {
+	// Preferred format takes precedence over generic attributes, which take precedence over other formats
+	sa := &astisub.StyleAttributes{Bold: astiptr.Bool(false), Color: astisub.ColorBlue, SRTBold: astiptr.Bool(true), SSAItalic: astiptr.Bool(true), TTMLTextAlign: "end"}
+	g := sa.GenericAttributes(astisub.FormatSRT)
+	assert.Equal(t, astiptr.Bool(true), g.Bold)
+	assert.Equal(t, astisub.ColorBlue, g.Color)
+	assert.Equal(t, astiptr.Bool(true), g.Italics)
+	assert.Equal(t, astiptr.Int(3), g.Alignment)
+	assert.Equal(t, astiptr.Bool(false), sa.GenericAttributes(astisub.FormatWebVTT).Bold)
+
+	// Writers read generic attributes
+	s := astisub.NewSubtitles()
+	s.Items = []*astisub.Item{{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: &astisub.StyleAttributes{Bold: astiptr.Bool(true), Color: astisub.ColorRed}, Text: "1"}}}}}}
+	w := &bytes.Buffer{}
+	err := s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n<font color=\"#ff0000\"><b>1</b></font>\n")
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n<c.red><b>1</b></c>\n")
+}
+
 func TestSubtitles_RemoveStyling(t *testing.T) {
 	s := &astisub.Subtitles{
 		Items: []*astisub.Item{
@@ -200,3 +680,80 @@ func TestSubtitles_RemoveStyling(t *testing.T) {
 		Styles:  map[string]*astisub.Style{},
 	}, s)
 }
+
+func TestComments(t *testing.T) {
+	// Init
+	var s = mockSubtitles()
+	s.Metadata = &astisub.Metadata{Comments: []string{"metadata"}}
+	s.Items[1].Comments = []string{"item 1", "item 2"}
+
+	// Loop through formats
+	for _, v := range []struct {
+		f        astisub.Format
+		item     []string
+		metadata []string
+	}{
+		{f: astisub.FormatSRT},
+		{f: astisub.FormatSSA, item: []string{"item 1", "item 2"}, metadata: []string{"metadata"}},
+		{f: astisub.FormatSTL, item: []string{"item 1", "item 2"}},
+		{f: astisub.FormatTTML, item: []string{"item 1", "item 2"}, metadata: []string{"metadata"}},
+		{f: astisub.FormatWebVTT, item: []string{"item 1", "item 2"}},
+	} {
+		// Write
+		w := &bytes.Buffer{}
+		err := s.WriteToFormat(w, v.f)
+		assert.NoError(t, err, v.f)
+
+		// Read
+		o, err := astisub.Read(w, v.f, astisub.Options{})
+		assert.NoError(t, err, v.f)
+		assert.Len(t, o.Items, 2, v.f)
+		assert.Equal(t, v.item, o.Items[1].Comments, v.f)
+		if v.metadata != nil {
+			assert.Equal(t, v.metadata, o.Metadata.Comments, v.f)
+		}
+	}
+
+	// SRT notes are opt-in
+	w := &bytes.Buffer{}
+	err := s.WriteToSRTWithOptions(w, astisub.SRTOptions{Comments: true})
+	assert.NoError(t, err)
+	o, err := astisub.ReadFromSRT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"metadata"}, o.Metadata.Comments)
+	assert.Equal(t, []astisub.Warning{{Line: 1, Message: "text outside of a cue kept as a comment"}}, o.Warnings)
+}
+
+func TestSubtitles_ApplyLeadInOut(t *testing.T) {
+	var subtitles = func() *astisub.Subtitles {
+		return &astisub.Subtitles{Items: []*astisub.Item{
+			{EndAt: 2 * time.Second, StartAt: 100 * time.Millisecond},
+			{EndAt: 3 * time.Second, StartAt: 2100 * time.Millisecond},
+			{EndAt: 6 * time.Second, StartAt: 5 * time.Second},
+			{EndAt: 7 * time.Second, StartAt: 6 * time.Second},
+		}}
+	}
+	var boundaries = func(s *astisub.Subtitles) (bs [][2]time.Duration) {
+		for _, i := range s.Items {
+			bs = append(bs, [2]time.Duration{i.StartAt / time.Millisecond, i.EndAt / time.Millisecond})
+		}
+		return
+	}
+
+	// Gaps are closed
+	s := subtitles()
+	s.ApplyLeadInOut(200*time.Millisecond, 500*time.Millisecond, false)
+	assert.Equal(t, [][2]time.Duration{{0, 2000}, {2000, 3500}, {4800, 6000}, {6000, 7500}}, boundaries(s))
+
+	// Gaps are respected
+	s = subtitles()
+	s.ApplyLeadInOut(200*time.Millisecond, 500*time.Millisecond, true)
+	assert.Equal(t, [][2]time.Duration{{0, 2000}, {2080, 3500}, {4800, 6000}, {6000, 7500}}, boundaries(s))
+	assert.Empty(t, s.Overlaps())
+
+	// Gaps depend on the framerate
+	s = subtitles()
+	s.Metadata = &astisub.Metadata{Framerate: 50}
+	s.ApplyLeadInOut(200*time.Millisecond, 500*time.Millisecond, true)
+	assert.Equal(t, [][2]time.Duration{{0, 2000}, {2040, 3500}, {4800, 6000}, {6000, 7500}}, boundaries(s))
+}
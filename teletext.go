@@ -4,8 +4,10 @@ import (
 	"context"
 	"io"
 	"math/bits"
+	"runtime"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/asticode/go-astilog"
@@ -321,20 +323,33 @@ const (
 	teletextPESDataUnitIDStuffing           = 0xff
 )
 
+// Period of the 33 bits 90kHz clock PTS and PCR are expressed in
+const teletextClockPeriod = time.Duration((1 << 33) * int64(time.Second) / 90000)
+
 // TeletextOptions represents teletext options
 type TeletextOptions struct {
 	Page int
 	PID  int
+	// Number of goroutines parsing decoded pages when reading a whole content. Defaults to the number of CPUs.
+	Workers int
 }
 
-// ReadFromTeletext parses a teletext content
+// Number of demuxed data buffered between the demuxing and decoding goroutines
+const teletextPipelineSize = 256
+
+// ReadFromTeletext parses a teletext content. Packets are demuxed, pages are decoded and pages are parsed in
+// pipelined goroutines, which speeds up the reading of large captures.
 // http://www.etsi.org/deliver/etsi_en/300400_300499/300472/01.03.01_60/en_300472v010301p.pdf
 // http://www.etsi.org/deliver/etsi_i_ets/300700_300799/300706/01_60/ets_300706e01p.pdf
 // TODO Update README
 // TODO Add tests
 func ReadFromTeletext(r io.Reader, o TeletextOptions) (s *Subtitles, err error) {
+	return readFromTeletext(r, o, nil)
+}
+
+// readFromTeletext parses a teletext content, calling a hook for each item once it's complete
+func readFromTeletext(r io.Reader, o TeletextOptions, h ItemHook) (s *Subtitles, err error) {
 	// Init
-	s = &Subtitles{}
 	var dmx = astits.New(context.Background(), r)
 
 	// Get the teletext PID
@@ -346,62 +361,319 @@ func ReadFromTeletext(r io.Reader, o TeletextOptions) (s *Subtitles, err error)
 		return
 	}
 
-	// Create character decoder
-	cd := newTeletextCharacterDecoder()
+	// Demux
+	var ds = make(chan *astits.Data, teletextPipelineSize)
+	go func() {
+		defer close(ds)
+		for {
+			// Fetch next data
+			var d *astits.Data
+			if d, err = dmx.NextData(); err != nil {
+				if err == astits.ErrNoMorePackets {
+					err = nil
+				} else {
+					err = errors.Wrap(err, "astisub: fetching next data failed")
+				}
+				return
+			}
+
+			// This data is not of interest to us
+			if d.PID != pid {
+				continue
+			}
+
+			// Send data
+			ds <- d
+		}
+	}()
+
+	// Decode
+	var errDecode error
+	s, errDecode = decodeTeletext(ds, o, h)
+
+	// Demuxing failed, err being safe to read once the channel is closed
+	if err != nil {
+		s = nil
+		return
+	}
+	if errDecode != nil {
+		s = nil
+		err = errDecode
+	}
+	return
+}
+
+// teletextJob represents a decoded page waiting to be parsed with the character decoder as it was when the page was
+// fully received
+type teletextJob struct {
+	cd  teletextCharacterDecoder
+	idx int
+	p   *teletextPage
+}
+
+// teletextJobResult represents the items parsed from a page
+type teletextJobResult struct {
+	idx int
+	s   *Subtitles
+}
+
+// decodeTeletext decodes data in a goroutine, and parses decoded pages in worker goroutines. Page items are first
+// timed relatively to the first data decoded, then retimed relatively to the first time decoded once all data has
+// been decoded, which is when the hook is called for each of them.
+func decodeTeletext(ds <-chan *astits.Data, o TeletextOptions, h ItemHook) (s *Subtitles, err error) {
+	// Get number of workers
+	var workers = o.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	// Decode
+	var td = NewTeletextDecoder(o.Page)
+	var jobs = make(chan teletextJob, teletextPipelineSize)
+	var ref time.Time
+	go func() {
+		defer close(jobs)
+		var idx int
+		var send = func() {
+			for _, p := range td.ps {
+				jobs <- teletextJob{cd: *td.cd, idx: idx, p: p}
+				idx++
+			}
+			td.ps = nil
+		}
+		for d := range ds {
+			td.Decode(d)
+			if ref.IsZero() {
+				ref = td.firstTime
+			}
+			send()
+		}
+		td.ps = append(td.ps, td.b.dump(td.lastTime)...)
+		send()
+	}()
+
+	// Parse
+	var rs = make(chan teletextJobResult, teletextPipelineSize)
+	var wg sync.WaitGroup
+	for idx := 0; idx < workers; idx++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				var r = teletextJobResult{idx: j.idx, s: &Subtitles{}}
+				j.p.parse(r.s, &j.cd, ref)
+				rs <- r
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(rs)
+	}()
+
+	// Gather items in page order
+	var ps []*Subtitles
+	for r := range rs {
+		for len(ps) <= r.idx {
+			ps = append(ps, nil)
+		}
+		ps[r.idx] = r.s
+	}
+	// Items are timed relatively to the first time decoded
+	s = &Subtitles{}
+	var d = ref.Sub(td.firstTime)
+	var hk = &itemHooker{h: h}
+	for _, p := range ps {
+		if d > 0 {
+			p.Add(d)
+		}
+		s.Items = append(s.Items, p.Items...)
+		if err = hk.hook(s.Items); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// TeletextStreamer decodes teletext subtitles from a live transport stream, such as a live channel feed, and emits
+// items as soon as they're completed, i.e. once the page following theirs starts being received. Unlike
+// ReadFromTeletext, the stream doesn't need to end nor to be seekable: if the PID is not in the options, it's detected
+// from the first PMT received, data received before being ignored.
+type TeletextStreamer struct {
+	o      TeletextOptions
+	onItem func(i *Item)
+	r      io.Reader
+}
 
-	// Create page buffer
-	b := newTeletextPageBuffer(o.Page, cd)
+// NewTeletextStreamer creates a new teletext streamer. onItem is called from the goroutine running the streamer.
+func NewTeletextStreamer(r io.Reader, o TeletextOptions, onItem func(i *Item)) *TeletextStreamer {
+	return &TeletextStreamer{
+		o:      o,
+		onItem: onItem,
+		r:      r,
+	}
+}
+
+// Run decodes the stream until it ends, in which case the items of the page being received are emitted as well, or
+// until the context is cancelled, in which case the context's error is returned. Item times are relative to the first
+// time decoded.
+func (s *TeletextStreamer) Run(ctx context.Context) error {
+	return s.stream(ctx, astits.New(ctx, s.r).NextData)
+}
+
+// stream decodes data fetched by next until there are no more packets or the context is cancelled
+func (s *TeletextStreamer) stream(ctx context.Context, next func() (*astits.Data, error)) (err error) {
+	// Init
+	var pid = uint16(s.o.PID)
+	var isTeletext = pmtStreamHasDescriptor(astits.DescriptorTagTeletext, astits.DescriptorTagVBITeletext)
+	var td = NewTeletextDecoder(s.o.Page)
 
 	// Loop in data
-	var firstTime, lastTime time.Time
-	var d *astits.Data
-	var ps []*teletextPage
 	for {
+		// Context is done
+		if err = ctx.Err(); err != nil {
+			return
+		}
+
 		// Fetch next data
-		if d, err = dmx.NextData(); err != nil {
+		var d *astits.Data
+		if d, err = next(); err != nil {
 			if err == astits.ErrNoMorePackets {
+				if pid == 0 {
+					err = ErrNoValidTeletextPID
+					return
+				}
 				err = nil
-				break
+				s.emit(td.Subtitles().Items)
+				return
+			} else if ctx.Err() != nil {
+				err = ctx.Err()
+				return
 			}
 			err = errors.Wrap(err, "astisub: fetching next data failed")
 			return
 		}
 
-		// We only parse PES data
-		if d.PES == nil {
+		// Detect the teletext PID
+		if pid == 0 {
+			if d.PMT != nil {
+				for _, es := range d.PMT.ElementaryStreams {
+					if isTeletext(es) {
+						pid = es.ElementaryPID
+						astilog.Debugf("astisub: no teletext pid specified, using pid %d", pid)
+						break
+					}
+				}
+			}
 			continue
 		}
 
 		// This data is not of interest to us
-		if d.PID != pid || d.PES.Header.StreamID != astits.StreamIDPrivateStream1 {
+		if d.PID != pid {
 			continue
 		}
 
-		// Get time
-		t := teletextDataTime(d)
-		if t.IsZero() {
-			continue
-		}
+		// Decode
+		td.Decode(d)
+		s.emit(td.Items())
+	}
+}
 
-		// First and last time
-		if firstTime.IsZero() || firstTime.After(t) {
-			firstTime = t
-		}
-		if lastTime.IsZero() || lastTime.Before(t) {
-			lastTime = t
-		}
+// emit emits items
+func (s *TeletextStreamer) emit(is []*Item) {
+	for _, i := range is {
+		s.onItem(i)
+	}
+}
+
+// TeletextDecoder decodes teletext subtitles one demuxed data at a time. It comes in handy when data is demuxed
+// elsewhere, for instance when an *astits.Demuxer is shared with other consumers.
+// Filtering data on the teletext PID is up to the caller.
+type TeletextDecoder struct {
+	b                   *teletextPageBuffer
+	cd                  *teletextCharacterDecoder
+	firstTime, lastTime time.Time
+	previousTime        time.Time
+	ps                  []*teletextPage
+	rollovers           int
+}
 
-		// Append pages
-		ps = append(ps, b.process(d.PES, t)...)
+// NewTeletextDecoder creates a new teletext decoder. If page is 0, the first subtitle page found is used.
+func NewTeletextDecoder(page int) *TeletextDecoder {
+	var cd = newTeletextCharacterDecoder()
+	return &TeletextDecoder{
+		b:  newTeletextPageBuffer(page, cd),
+		cd: cd,
 	}
+}
 
+// Decode decodes a demuxed data
+func (td *TeletextDecoder) Decode(d *astits.Data) {
+	// We only parse PES data
+	if d.PES == nil || d.PES.Header == nil || d.PES.Header.StreamID != astits.StreamIDPrivateStream1 {
+		return
+	}
+
+	// Get time
+	t := td.unwrapTime(teletextDataTime(d))
+	if t.IsZero() {
+		return
+	}
+
+	// First and last time
+	if td.firstTime.IsZero() || td.firstTime.After(t) {
+		td.firstTime = t
+	}
+	if td.lastTime.IsZero() || td.lastTime.Before(t) {
+		td.lastTime = t
+	}
+
+	// Append pages
+	td.ps = append(td.ps, td.b.process(d.PES, t)...)
+}
+
+// unwrapTime adds the clock periods elapsed so far to a PTS or PCR time, so that times keep increasing when the 33 bits
+// clock rolls over, which happens every 26.5 hours in live channel dumps. Times going backwards by less than half a
+// period are considered out of order rather than rolled over.
+func (td *TeletextDecoder) unwrapTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return unwrapClockTime(t, &td.previousTime, &td.rollovers)
+}
+
+// unwrapClockTime adds the clock periods elapsed so far to a PTS or PCR time and keeps track of rollovers
+func unwrapClockTime(t time.Time, previousTime *time.Time, rollovers *int) time.Time {
+	if !previousTime.IsZero() && previousTime.Sub(t) > teletextClockPeriod/2 {
+		*rollovers++
+	}
+	*previousTime = t
+	return t.Add(time.Duration(*rollovers) * teletextClockPeriod)
+}
+
+// Items returns the items of the pages that have been fully received since the last call to Items or Subtitles,
+// which allows consuming items as they're decoded. Times are relative to the first time decoded so far.
+func (td *TeletextDecoder) Items() []*Item {
+	return td.parse().Items
+}
+
+// Subtitles dumps the page being received and returns the subtitles decoded since the last call to Items or
+// Subtitles. It should be called once all data has been decoded.
+func (td *TeletextDecoder) Subtitles() (s *Subtitles) {
 	// Dump buffer
-	ps = append(ps, b.dump(lastTime)...)
+	td.ps = append(td.ps, td.b.dump(td.lastTime)...)
 
 	// Parse pages
-	for _, p := range ps {
-		p.parse(s, cd, firstTime)
+	return td.parse()
+}
+
+// parse parses the pages that have been received and forgets about them
+func (td *TeletextDecoder) parse() (s *Subtitles) {
+	s = &Subtitles{}
+	for _, p := range td.ps {
+		p.parse(s, td.cd, td.firstTime)
 	}
+	td.ps = nil
 	return
 }
 
@@ -425,50 +697,74 @@ func teletextPID(dmx *astits.Demuxer, o TeletextOptions) (pid uint16, err error)
 		return
 	}
 
+	// Detect PID
+	var ok bool
+	if pid, ok, err = pmtPID(dmx, pmtStreamHasDescriptor(astits.DescriptorTagTeletext, astits.DescriptorTagVBITeletext)); err != nil {
+		return
+	} else if !ok {
+		err = ErrNoValidTeletextPID
+		return
+	}
+	astilog.Debugf("astisub: no teletext pid specified, using pid %d", pid)
+	return
+}
+
+// pmtPID walks through the ts data until it reaches a PMT packet, returns the PID of the first elementary stream
+// matching the predicate, and rewinds the demuxer. ok is false if there's no PMT or no such PID.
+func pmtPID(dmx *astits.Demuxer, fn func(es *astits.PMTElementaryStream) bool) (pid uint16, ok bool, err error) {
 	// Loop in data
 	var d *astits.Data
 	for {
 		// Fetch next data
 		if d, err = dmx.NextData(); err != nil {
 			if err == astits.ErrNoMorePackets {
-				err = ErrNoValidTeletextPID
+				err = nil
 				return
 			}
 			err = errors.Wrap(err, "astisub: fetching next data failed")
 			return
 		}
 
-		// PMT data
-		if d.PMT != nil {
-			// Retrieve valid teletext PIDs
-			var pids []uint16
-			for _, s := range d.PMT.ElementaryStreams {
-				for _, dsc := range s.ElementaryStreamDescriptors {
-					if dsc.Tag == astits.DescriptorTagTeletext || dsc.Tag == astits.DescriptorTagVBITeletext {
-						pids = append(pids, s.ElementaryPID)
-					}
-				}
-			}
+		// Not a PMT
+		if d.PMT == nil {
+			continue
+		}
 
-			// No valid teletext PIDs
-			if len(pids) == 0 {
-				err = ErrNoValidTeletextPID
-				return
+		// Retrieve first valid PID
+		for _, es := range d.PMT.ElementaryStreams {
+			if fn(es) {
+				pid, ok = es.ElementaryPID, true
+				break
 			}
+		}
 
-			// Set pid
-			pid = pids[0]
-			astilog.Debugf("astisub: no teletext pid specified, using pid %d", pid)
+		// No valid PIDs
+		if !ok {
+			return
+		}
 
-			// Rewind
-			if _, err = dmx.Rewind(); err != nil {
-				err = errors.Wrap(err, "astisub: rewinding failed")
-				return
-			}
+		// Rewind
+		if _, err = dmx.Rewind(); err != nil {
+			err = errors.Wrap(err, "astisub: rewinding failed")
 			return
 		}
+		return
+	}
+}
+
+// pmtStreamHasDescriptor returns a predicate checking whether an elementary stream has a descriptor with one of the
+// tags
+func pmtStreamHasDescriptor(tags ...uint8) func(es *astits.PMTElementaryStream) bool {
+	return func(es *astits.PMTElementaryStream) bool {
+		for _, dsc := range es.ElementaryStreamDescriptors {
+			for _, t := range tags {
+				if dsc.Tag == t {
+					return true
+				}
+			}
+		}
+		return false
 	}
-	return
 }
 
 type teletextPageBuffer struct {
@@ -659,6 +955,20 @@ func (b *teletextPageBuffer) parsePacketHeader(i []byte, magazineNumber uint8, t
 		return
 	}
 
+	// C4
+	controlBits, ok = astibits.Hamming84Decode(i[3])
+	if !ok {
+		return
+	}
+	erasePage := controlBits&0x8 > 0
+
+	// Rows of cumulative pages, whose erase page bit is not set, are added to the page being displayed
+	if !erasePage && b.currentPage != nil {
+		b.receiving = true
+		b.currentPage.transmittedAt = t
+		return
+	}
+
 	// Now that we know when the previous page ends we can add it to the done slice
 	if b.currentPage != nil {
 		b.currentPage.end = t
@@ -676,10 +986,11 @@ func (b *teletextPageBuffer) parsePacketData(i []byte, packetNumber uint8) {
 	// Make sure the map is initialized
 	if _, ok := b.currentPage.data[packetNumber]; !ok {
 		b.currentPage.data[packetNumber] = make([]byte, 40)
+		b.currentPage.rows = append(b.currentPage.rows, int(packetNumber))
+		b.currentPage.rowTimes[packetNumber] = b.currentPage.transmittedAt
 	}
 
 	// Loop through input
-	b.currentPage.rows = append(b.currentPage.rows, int(packetNumber))
 	for idx := uint8(0); idx < 40; idx++ {
 		v, ok := astibits.Parity(bits.Reverse8(i[idx]))
 		if !ok {
@@ -808,18 +1119,22 @@ func (d *teletextCharacterDecoder) updateCharset(pageCharsetCode *uint8, force b
 }
 
 type teletextPage struct {
-	charsetCode uint8
-	data        map[uint8][]byte
-	end         time.Time
-	rows        []int
-	start       time.Time
+	charsetCode   uint8
+	data          map[uint8][]byte
+	end           time.Time
+	rowTimes      map[uint8]time.Time // Indexed by row, time of the transmission the row has been received in
+	rows          []int
+	start         time.Time
+	transmittedAt time.Time // Time of the last transmission, which is later than start for cumulative pages
 }
 
 func newTeletextPage(charsetCode uint8, start time.Time) *teletextPage {
 	return &teletextPage{
-		charsetCode: charsetCode,
-		data:        make(map[uint8][]byte),
-		start:       start,
+		charsetCode:   charsetCode,
+		data:          make(map[uint8][]byte),
+		rowTimes:      make(map[uint8]time.Time),
+		start:         start,
+		transmittedAt: start,
 	}
 }
 
@@ -843,7 +1158,17 @@ func (p *teletextPage) parse(s *Subtitles, d *teletextCharacterDecoder, firstTim
 
 	// Loop through rows
 	for _, idxRow := range p.rows {
+		var n = len(i.Lines)
 		parseTeletextRow(i, d, nil, p.data[uint8(idxRow)])
+
+		// Rows added to cumulative pages are timed
+		if t, ok := p.rowTimes[uint8(idxRow)]; ok && t.After(p.start) {
+			i.DisplayMode = DisplayModePaintOn
+			for idx := n; idx < len(i.Lines); idx++ {
+				i.Lines[idx].StartAt = t.Sub(firstTime)
+				i.Lines[idx].EndAt = i.EndAt
+			}
+		}
 	}
 
 	// Append item
@@ -1,11 +1,15 @@
 package astisub
 
 import (
+	"context"
+	"fmt"
+	"math/bits"
 	"testing"
 
 	"time"
 
 	"github.com/asticode/go-astitools/ptr"
+	"github.com/asticode/go-astits"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -42,6 +46,25 @@ func TestTeletextPageParse(t *testing.T) {
 	}}, s.Items)
 }
 
+func TestTeletextPageParseCumulative(t *testing.T) {
+	p := newTeletextPage(0, time.Unix(10, 0))
+	p.end = time.Unix(15, 0)
+	p.rows = []int{1, 2}
+	p.rowTimes = map[uint8]time.Time{1: time.Unix(10, 0), 2: time.Unix(12, 0)}
+	p.data = map[uint8][]byte{
+		1: append([]byte{0xb}, []byte("test1")...),
+		2: append([]byte{0xb}, []byte("test2")...),
+	}
+	s := Subtitles{}
+	d := newTeletextCharacterDecoder()
+	d.updateCharset(astiptr.UInt8(0), false)
+	p.parse(&s, d, time.Unix(5, 0))
+	assert.Len(t, s.Items, 1)
+	assert.False(t, s.Items[0].Lines[0].isTimed())
+	assert.Equal(t, 7*time.Second, s.Items[0].Lines[1].StartAt)
+	assert.Equal(t, 10*time.Second, s.Items[0].Lines[1].EndAt)
+}
+
 func TestParseTeletextRow(t *testing.T) {
 	b := []byte("start")
 	b = append(b, 0x0, 0xb)
@@ -86,7 +109,7 @@ func TestParseTeletextRow(t *testing.T) {
 			TeletextColor:        ColorRed,
 			TeletextSpacesAfter:  astiptr.Int(0),
 			TeletextSpacesBefore: astiptr.Int(0),
-			TTMLColor:            "#0000ff",
+			TTMLColor:            "#ff0000",
 		}},
 		{Text: "green", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorGreen,
@@ -98,13 +121,13 @@ func TestParseTeletextRow(t *testing.T) {
 			TeletextColor:        ColorYellow,
 			TeletextSpacesAfter:  astiptr.Int(0),
 			TeletextSpacesBefore: astiptr.Int(0),
-			TTMLColor:            "#00ffff",
+			TTMLColor:            "#ffff00",
 		}},
 		{Text: "blue", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorBlue,
 			TeletextSpacesAfter:  astiptr.Int(0),
 			TeletextSpacesBefore: astiptr.Int(0),
-			TTMLColor:            "#ff0000",
+			TTMLColor:            "#0000ff",
 		}},
 		{Text: "magenta", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorMagenta,
@@ -116,7 +139,7 @@ func TestParseTeletextRow(t *testing.T) {
 			TeletextColor:        ColorCyan,
 			TeletextSpacesAfter:  astiptr.Int(0),
 			TeletextSpacesBefore: astiptr.Int(0),
-			TTMLColor:            "#ffff00",
+			TTMLColor:            "#00ffff",
 		}},
 		{Text: "white", InlineStyle: &StyleAttributes{
 			TeletextColor:        ColorWhite,
@@ -176,3 +199,160 @@ func TestAppendTeletextLineItem(t *testing.T) {
 		TeletextSpacesBefore: astiptr.Int(1),
 	}, *l.Items[0].InlineStyle)
 }
+
+func TestTeletextDecoderUnwrapTime(t *testing.T) {
+	td := NewTeletextDecoder(0)
+	var base = time.Unix(0, 0)
+	assert.Equal(t, base.Add(teletextClockPeriod-time.Second), td.unwrapTime(base.Add(teletextClockPeriod-time.Second)))
+	assert.Equal(t, base.Add(teletextClockPeriod-2*time.Second), td.unwrapTime(base.Add(teletextClockPeriod-2*time.Second)))
+	assert.Equal(t, base.Add(teletextClockPeriod+time.Second), td.unwrapTime(base.Add(time.Second)))
+	assert.Equal(t, base.Add(teletextClockPeriod+2*time.Second), td.unwrapTime(base.Add(2*time.Second)))
+	assert.True(t, td.unwrapTime(time.Time{}).IsZero())
+}
+
+var teletextTestHamming84 = [16]byte{0x15, 0x02, 0x49, 0x5e, 0x64, 0x73, 0x38, 0x2f, 0xd0, 0xc7, 0x8c, 0x9b, 0xa1, 0xb6, 0xfd, 0xea}
+
+// teletextTestDataUnit returns an EBU teletext data unit of magazine 1
+func teletextTestDataUnit(packetNumber uint8, payload []byte) []byte {
+	var h = packetNumber<<3 | 1
+	var b = []byte{teletextPESDataUnitIDEBUSubtitleData, 0x2c, 0x0, 0xe4, teletextTestHamming84[h&0xf], teletextTestHamming84[h>>4]}
+	return append(b, payload...)
+}
+
+// teletextTestData returns the data of subtitle pages 100, each page having 2 rows and lasting 1 second
+func teletextTestData(pages int) (ds []*astits.Data) {
+	for idx := 0; idx < pages; idx++ {
+		// Header with erase page and subtitle flags set
+		var b = []byte{0x10}
+		var h = make([]byte, 40)
+		for k := range h {
+			h[k] = teletextTestHamming84[0]
+		}
+		h[3], h[5] = teletextTestHamming84[0x8], teletextTestHamming84[0x8]
+		b = append(b, teletextTestDataUnit(0, h)...)
+
+		// Rows
+		for _, row := range []uint8{20, 22} {
+			var r = make([]byte, 40)
+			for k, c := range []byte(fmt.Sprintf("\x0b Subtitle %d row %d                      ", idx, row))[:40] {
+				if bits.OnesCount8(c)%2 == 0 {
+					c |= 0x80
+				}
+				r[k] = bits.Reverse8(c)
+			}
+			b = append(b, teletextTestDataUnit(row, r)...)
+		}
+
+		// Data
+		ds = append(ds, &astits.Data{PES: &astits.PESData{
+			Data: b,
+			Header: &astits.PESHeader{
+				OptionalHeader: &astits.PESOptionalHeader{PTS: &astits.ClockReference{Base: int64(idx+1) * 90000}},
+				StreamID:       astits.StreamIDPrivateStream1,
+			},
+		}})
+	}
+	return
+}
+
+func decodeTeletextSequentially(ds []*astits.Data) *Subtitles {
+	td := NewTeletextDecoder(100)
+	for _, d := range ds {
+		td.Decode(d)
+	}
+	return td.Subtitles()
+}
+
+func decodeTeletextConcurrently(ds []*astits.Data, workers int) *Subtitles {
+	c := make(chan *astits.Data, len(ds))
+	for _, d := range ds {
+		c <- d
+	}
+	close(c)
+	s, _ := decodeTeletext(c, TeletextOptions{Page: 100, Workers: workers}, nil)
+	return s
+}
+
+func TestDecodeTeletext(t *testing.T) {
+	// Data is out of order
+	ds := teletextTestData(20)
+	ds[0].PES.Header.OptionalHeader.PTS, ds[1].PES.Header.OptionalHeader.PTS = ds[1].PES.Header.OptionalHeader.PTS, ds[0].PES.Header.OptionalHeader.PTS
+
+	e := decodeTeletextSequentially(ds)
+	assert.Len(t, e.Items, 20)
+	assert.Equal(t, "Subtitle 5 row 20 - Subtitle 5 row 22", e.Items[5].String())
+	for _, workers := range []int{1, 4} {
+		assert.Equal(t, e, decodeTeletextConcurrently(ds, workers))
+	}
+}
+
+func BenchmarkDecodeTeletext(b *testing.B) {
+	ds := teletextTestData(5000)
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			decodeTeletextSequentially(ds)
+		}
+	})
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				decodeTeletextConcurrently(ds, workers)
+			}
+		})
+	}
+}
+
+func TestTeletextStreamer(t *testing.T) {
+	// Init
+	var fetched int
+	var next = func(ds []*astits.Data) func() (*astits.Data, error) {
+		fetched = 0
+		return func() (d *astits.Data, err error) {
+			if fetched == len(ds) {
+				return nil, astits.ErrNoMorePackets
+			}
+			d = ds[fetched]
+			fetched++
+			return
+		}
+	}
+	var e = decodeTeletextSequentially(teletextTestData(5))
+	var pmt = &astits.Data{PMT: &astits.PMTData{ElementaryStreams: []*astits.PMTElementaryStream{
+		{ElementaryPID: 101},
+		{ElementaryPID: 100, ElementaryStreamDescriptors: []*astits.Descriptor{{Tag: astits.DescriptorTagTeletext}}},
+	}}}
+	var ds = append([]*astits.Data{pmt}, teletextTestData(5)...)
+	for _, d := range ds[1:] {
+		d.PID = 100
+	}
+
+	// Items are emitted once the next page starts being received, the PID being detected
+	var is []*Item
+	var fs []int
+	var s = NewTeletextStreamer(nil, TeletextOptions{}, func(i *Item) {
+		is = append(is, i)
+		fs = append(fs, fetched)
+	})
+	err := s.stream(context.Background(), next(ds))
+	assert.NoError(t, err)
+	assert.Equal(t, e.Items, is)
+	assert.Equal(t, []int{3, 4, 5, 6, 6}, fs)
+
+	// No PID
+	s = NewTeletextStreamer(nil, TeletextOptions{}, func(i *Item) {})
+	err = s.stream(context.Background(), next(ds[1:]))
+	assert.Equal(t, ErrNoValidTeletextPID, err)
+
+	// Context is cancelled
+	is = []*Item{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s = NewTeletextStreamer(nil, TeletextOptions{PID: 100}, func(i *Item) {
+		if is = append(is, i); len(is) == 2 {
+			cancel()
+		}
+	})
+	err = s.stream(ctx, next(ds[1:]))
+	assert.Equal(t, context.Canceled, err)
+	assert.Equal(t, e.Items[:2], is)
+}
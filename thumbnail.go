@@ -0,0 +1,114 @@
+package astisub
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Vars
+var (
+	thumbnailImageExtensions = map[string]bool{".gif": true, ".jpeg": true, ".jpg": true, ".png": true, ".webp": true}
+	thumbnailRegexpXYWH      = regexp.MustCompile(`^xywh=(?:pixel:)?(\d+),(\d+),(\d+),(\d+)$`)
+)
+
+// Thumbnail represents the image of a trick play thumbnail track cue. Sprites gather several thumbnails in a single
+// image, the area of each thumbnail being described by the fragment of its URL.
+type Thumbnail struct {
+	Sprite *SpriteArea // Nil if the image is not a sprite
+	URL    string      // Without the fragment
+}
+
+// SpriteArea represents the area of a thumbnail in a sprite, in pixels
+type SpriteArea struct {
+	Height, Width, X, Y int
+}
+
+// ParseThumbnail parses a cue payload made of an image URL, optionally followed by an "xywh" media fragment. It
+// returns nil if the payload is not an image URL.
+func ParseThumbnail(i string) (t *Thumbnail) {
+	// Payload is not a URL
+	if i = strings.TrimSpace(i); len(i) == 0 || strings.ContainsAny(i, " \t") {
+		return
+	}
+
+	// Split fragment
+	var u, f = i, ""
+	if idx := strings.LastIndex(i, "#"); idx >= 0 {
+		u, f = i[:idx], i[idx+1:]
+	}
+
+	// Sprite
+	if m := thumbnailRegexpXYWH.FindStringSubmatch(f); m != nil {
+		var vs [4]int
+		for idx := range vs {
+			vs[idx], _ = strconv.Atoi(m[idx+1])
+		}
+		return &Thumbnail{
+			Sprite: &SpriteArea{Height: vs[3], Width: vs[2], X: vs[0], Y: vs[1]},
+			URL:    u,
+		}
+	}
+
+	// Image
+	var p = u
+	if idx := strings.Index(p, "?"); idx >= 0 {
+		p = p[:idx]
+	}
+	if len(f) > 0 || !thumbnailImageExtensions[strings.ToLower(path.Ext(p))] {
+		return
+	}
+	return &Thumbnail{URL: u}
+}
+
+// String implements the Stringer interface
+func (t Thumbnail) String() string {
+	if t.Sprite == nil {
+		return t.URL
+	}
+	return fmt.Sprintf("%s#xywh=%d,%d,%d,%d", t.URL, t.Sprite.X, t.Sprite.Y, t.Sprite.Width, t.Sprite.Height)
+}
+
+// SpriteOptions represents the layout of thumbnails in sprites. Thumbnails fill sprites row by row.
+type SpriteOptions struct {
+	Columns  int
+	Count    int           // Number of thumbnails
+	Height   int           // Height of a thumbnail
+	Interval time.Duration // Duration of a thumbnail
+	PerImage int           // Number of thumbnails per sprite, 0 meaning every thumbnail is in the same sprite
+	// URL of the sprites, "%d" being replaced with the index of the sprite, starting at 1, if there are several
+	URL   string
+	Width int // Width of a thumbnail
+}
+
+// NewSpriteThumbnails creates a thumbnail track whose cues point to consecutive areas of sprites
+func NewSpriteThumbnails(o SpriteOptions) (s *Subtitles) {
+	s = NewSubtitles()
+	if o.Columns <= 0 {
+		return
+	}
+	for idx := 0; idx < o.Count; idx++ {
+		// Get position in the sprite
+		var k, u = idx, o.URL
+		if o.PerImage > 0 {
+			k = idx % o.PerImage
+			if strings.Contains(u, "%d") {
+				u = fmt.Sprintf(u, idx/o.PerImage+1)
+			}
+		}
+
+		// Append item
+		s.Items = append(s.Items, &Item{
+			EndAt:   time.Duration(idx+1) * o.Interval,
+			StartAt: time.Duration(idx) * o.Interval,
+			Thumbnail: &Thumbnail{
+				Sprite: &SpriteArea{Height: o.Height, Width: o.Width, X: k % o.Columns * o.Width, Y: k / o.Columns * o.Height},
+				URL:    u,
+			},
+		})
+	}
+	return
+}
@@ -0,0 +1,55 @@
+package astisub_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseThumbnail(t *testing.T) {
+	assert.Equal(t, &astisub.Thumbnail{Sprite: &astisub.SpriteArea{Height: 90, Width: 160, X: 320, Y: 0}, URL: "https://cdn/sprite.jpg"}, astisub.ParseThumbnail("https://cdn/sprite.jpg#xywh=320,0,160,90"))
+	assert.Equal(t, &astisub.Thumbnail{Sprite: &astisub.SpriteArea{Height: 4, Width: 3, X: 1, Y: 2}, URL: "sprite"}, astisub.ParseThumbnail("sprite#xywh=pixel:1,2,3,4"))
+	assert.Equal(t, &astisub.Thumbnail{URL: "thumbs/1.PNG?v=2"}, astisub.ParseThumbnail(" thumbs/1.PNG?v=2 "))
+	assert.Nil(t, astisub.ParseThumbnail("sprite.jpg#xywh=percent:1,2,3,4"))
+	assert.Nil(t, astisub.ParseThumbnail("thumbs.html"))
+	assert.Nil(t, astisub.ParseThumbnail("look at image.jpg"))
+}
+
+func TestWebVTTThumbnails(t *testing.T) {
+	// Read
+	const c = "WEBVTT\n\n1\n00:00:00.000 --> 00:00:05.000\nsprite-1.jpg#xywh=0,0,160,90\n\n2\n00:00:05.000 --> 00:00:10.000\nsprite-1.jpg#xywh=160,0,160,90\n\n3\n00:00:10.000 --> 00:00:15.000\nsprite-2.jpg#xywh=0,0,160,90\n"
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(c))
+	assert.NoError(t, err)
+	e := astisub.NewSpriteThumbnails(astisub.SpriteOptions{Columns: 2, Count: 3, Height: 90, Interval: 5 * time.Second, PerImage: 2, URL: "sprite-%d.jpg", Width: 160})
+	assert.Len(t, s.Items, 3)
+	for idx, i := range s.Items {
+		assert.Empty(t, i.Lines)
+		assert.Equal(t, e.Items[idx].EndAt, i.EndAt)
+		assert.Equal(t, e.Items[idx].StartAt, i.StartAt)
+		assert.Equal(t, e.Items[idx].Thumbnail, i.Thumbnail)
+	}
+
+	// Write
+	w := &bytes.Buffer{}
+	assert.NoError(t, e.WriteToWebVTT(w))
+	assert.Equal(t, c, w.String())
+	assert.Equal(t, []astisub.Warning{{Message: "thumbnail of item #1 is not supported by srt and will be dropped"}}, astisub.Subtitles{Items: e.Items[:1]}.FidelityWarnings(astisub.FormatSRT))
+
+	// Captions made of file names are not thumbnails
+	s, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n00:00:00.000 --> 00:00:05.000\nREADME.png\nis the file\n\n00:00:05.000 --> 00:00:10.000\nREADME.png\n"))
+	assert.NoError(t, err)
+	assert.Nil(t, s.Items[0].Thumbnail)
+	assert.Equal(t, "README.png - is the file", s.Items[0].String())
+	assert.Nil(t, s.Items[1].Thumbnail)
+	assert.Equal(t, "README.png", s.Items[1].String())
+
+	// Metadata tracks
+	s, err = astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\nKind: metadata\n\n00:00:00.000 --> 00:00:05.000\nthumbs/1.png\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, &astisub.Thumbnail{URL: "thumbs/1.png"}, s.Items[0].Thumbnail)
+	assert.Empty(t, s.Items[0].Lines)
+}
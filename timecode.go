@@ -0,0 +1,164 @@
+package astisub
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Timecode represents a frame accurate time, i.e. a number of frames at a given rate
+type Timecode struct {
+	// If true, frame numbers are skipped when formatting so that timecodes match the clock time. Only 29.97 and
+	// 59.94 fps support drop-frame timecodes, other rates ignore it.
+	DropFrame bool
+	// Number of frames since 00:00:00:00
+	Frames int
+	// Number of frames per second. NTSC rates such as 23.976, 29.97 or 59.94 are handled as exact N*1000/1001 rates.
+	Rate float64
+}
+
+// NewTimecode creates a timecode from a duration, rounding it down to the previous frame boundary
+func NewTimecode(d time.Duration, rate float64) Timecode {
+	// A small tolerance is applied so that durations created from frames, which are rounded to the nanosecond, are not
+	// moved to the previous frame
+	var num, den = timecodeRate(rate)
+	return Timecode{
+		Frames: int(math.Floor(float64(d)*float64(num)/(float64(den)*float64(time.Second)) + 1e-6)),
+		Rate:   rate,
+	}
+}
+
+// ParseTimecode parses a "HH:MM:SS:FF" or "HHMMSSFF" timecode. Timecodes using ";" or "." as frames separator are
+// drop-frame timecodes.
+func ParseTimecode(i string, rate float64) (t Timecode, err error) {
+	// Split
+	var parts []string
+	if len(i) == 8 && !strings.ContainsAny(i, ":;.") {
+		parts = []string{i[0:2], i[2:4], i[4:6], i[6:8]}
+	} else if parts = strings.FieldsFunc(i, func(r rune) bool { return r == ':' || r == ';' || r == '.' }); len(parts) != 4 {
+		err = fmt.Errorf("astisub: timecode %s is invalid", i)
+		return
+	}
+
+	// Parse
+	var vs [4]int
+	for idx, p := range parts {
+		if vs[idx], err = strconv.Atoi(p); err != nil {
+			err = errors.Wrapf(err, "astisub: atoi of %s failed", p)
+			return
+		}
+	}
+	t = newTimecodeFromComponents(vs[0], vs[1], vs[2], vs[3], rate, strings.ContainsAny(i, ";."))
+	return
+}
+
+// newTimecodeFromComponents creates a timecode from its hours, minutes, seconds and frames
+func newTimecodeFromComponents(hours, minutes, seconds, frames int, rate float64, dropFrame bool) (t Timecode) {
+	t = Timecode{DropFrame: dropFrame, Rate: rate}
+	var nominal, drop = timecodeNominalRate(rate), t.droppedFrames()
+	t.Frames = ((hours*60+minutes)*60+seconds)*nominal + frames
+	if drop > 0 {
+		var totalMinutes = hours*60 + minutes
+		t.Frames -= drop * (totalMinutes - totalMinutes/10)
+	}
+	return
+}
+
+// FromFrames returns the duration of a number of frames at a given rate
+func FromFrames(n int, rate float64) time.Duration {
+	return Timecode{Frames: n, Rate: rate}.Duration()
+}
+
+// ToFrames returns the time boundaries of the item as numbers of frames at a given rate
+func (i Item) ToFrames(rate float64) (start, end int) {
+	return NewTimecode(i.StartAt, rate).Frames, NewTimecode(i.EndAt, rate).Frames
+}
+
+// Duration returns the duration of the timecode, rounded to the nanosecond
+func (t Timecode) Duration() time.Duration {
+	// Integer arithmetic is split in order to avoid overflows
+	var num, den = timecodeRate(t.Rate)
+	var n = int64(t.Frames) * den
+	return time.Duration(n/num)*time.Second + time.Duration((n%num*int64(time.Second)+num/2)/num)
+}
+
+// String implements the Stringer interface
+func (t Timecode) String() string {
+	var h, m, s, f = t.components()
+	var sep = ":"
+	if t.droppedFrames() > 0 {
+		sep = ";"
+	}
+	return fmt.Sprintf("%.2d:%.2d:%.2d%s%.2d", h, m, s, sep, f)
+}
+
+// components returns the hours, minutes, seconds and frames of the timecode
+func (t Timecode) components() (hours, minutes, seconds, frames int) {
+	// Add dropped frame numbers
+	var n, nominal, drop = t.Frames, timecodeNominalRate(t.Rate), t.droppedFrames()
+	if drop > 0 {
+		var framesPerMinute = nominal*60 - drop
+		var framesPer10Minutes = framesPerMinute*10 + drop
+		var tens, rest = n / framesPer10Minutes, n % framesPer10Minutes
+		n += 9 * drop * tens
+		if rest > drop {
+			n += drop * ((rest - drop) / framesPerMinute)
+		}
+	}
+
+	// Split
+	frames = n % nominal
+	seconds = n / nominal % 60
+	minutes = n / nominal / 60 % 60
+	hours = n / nominal / 3600
+	return
+}
+
+// droppedFrames returns the number of frame numbers skipped every minute but every tenth minute, 0 if the timecode
+// is not a drop-frame timecode
+func (t Timecode) droppedFrames() int {
+	if !t.DropFrame {
+		return 0
+	}
+	if _, den := timecodeRate(t.Rate); den != 1001 {
+		return 0
+	}
+	switch timecodeNominalRate(t.Rate) {
+	case 30:
+		return 2
+	case 60:
+		return 4
+	}
+	return 0
+}
+
+// timecodeNominalRate returns the number of frame numbers per second
+func timecodeNominalRate(rate float64) int {
+	if rate <= 0 {
+		return defaultFramerate
+	}
+	return int(math.Round(rate))
+}
+
+// timecodeRate returns the rate as a fraction
+func timecodeRate(rate float64) (num, den int64) {
+	// Default rate
+	if rate <= 0 {
+		return defaultFramerate, 1
+	}
+
+	// Integer rate
+	if rate == math.Trunc(rate) {
+		return int64(rate), 1
+	}
+
+	// NTSC rate
+	if nominal := math.Round(rate); math.Abs(rate-nominal*1000/1001) < 0.01 {
+		return int64(nominal) * 1000, 1001
+	}
+	return int64(math.Round(rate * 1000)), 1000
+}
@@ -0,0 +1,62 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimecode(t *testing.T) {
+	// Integer rate
+	tc := astisub.NewTimecode(time.Hour+2*time.Minute+3*time.Second+520*time.Millisecond, 25)
+	assert.Equal(t, astisub.Timecode{Frames: 93088, Rate: 25}, tc)
+	assert.Equal(t, "01:02:03:13", tc.String())
+	assert.Equal(t, time.Hour+2*time.Minute+3*time.Second+520*time.Millisecond, tc.Duration())
+
+	// Round trips don't lose frames
+	for _, rate := range []float64{23.976, 24, 25, 29.97, 30, 59.94} {
+		for n := 0; n < 1000; n++ {
+			assert.Equal(t, n, astisub.NewTimecode(astisub.FromFrames(n, rate), rate).Frames)
+		}
+	}
+
+	// NTSC rate
+	assert.Equal(t, 1001*time.Millisecond, astisub.FromFrames(30, 29.97))
+	assert.Equal(t, time.Hour+3600*time.Millisecond, astisub.FromFrames(108000, 29.97))
+
+	// Drop frame
+	tc, err := astisub.ParseTimecode("00:01:00;02", 29.97)
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.Timecode{DropFrame: true, Frames: 1800, Rate: 29.97}, tc)
+	for _, v := range []string{"00:00:59;29", "00:01:00;02", "00:09:59;29", "00:10:00;00", "00:10:00;01", "01:00:00;00"} {
+		tc, err = astisub.ParseTimecode(v, 29.97)
+		assert.NoError(t, err)
+		assert.Equal(t, v, tc.String())
+	}
+	assert.Equal(t, 107892, tc.Frames)
+	assert.Equal(t, time.Hour-3600*time.Microsecond, tc.Duration())
+
+	// Drop frame is ignored with other rates
+	tc, err = astisub.ParseTimecode("00:01:00;02", 25)
+	assert.NoError(t, err)
+	assert.Equal(t, 1502, tc.Frames)
+	assert.Equal(t, "00:01:00:02", tc.String())
+
+	// STL timecodes
+	tc, err = astisub.ParseTimecode("00000110", 25)
+	assert.NoError(t, err)
+	assert.Equal(t, 35, tc.Frames)
+
+	// Invalid timecodes
+	_, err = astisub.ParseTimecode("00:01:00", 25)
+	assert.Error(t, err)
+	_, err = astisub.ParseTimecode("00:01:00:xx", 25)
+	assert.Error(t, err)
+
+	// Item
+	start, end := astisub.Item{EndAt: 2 * time.Second, StartAt: time.Second / 30}.ToFrames(30)
+	assert.Equal(t, 1, start)
+	assert.Equal(t, 60, end)
+}
@@ -0,0 +1,315 @@
+package astisub
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Word represents a word of a text
+type Word struct {
+	NoBreakBefore bool // If true, lines can't be broken before the word, such as before closing punctuation
+	SpaceBefore   bool // If true, the word is separated from the previous one by a white space
+	Text          string
+}
+
+// Tokenizer splits texts into sentences and words so that processing such as wrapping follows the rules of the
+// text's language. White spaces are not part of words.
+type Tokenizer interface {
+	Sentences(text string) []string
+	Words(text string) []Word
+}
+
+// TokenizerForLanguage returns the tokenizer of a BCP-47 language tag, the Unicode tokenizer if the language has no
+// specific tokenizer
+func TokenizerForLanguage(tag string) Tokenizer {
+	switch strings.ToLower(strings.SplitN(strings.Replace(tag, "_", "-", -1), "-", 2)[0]) {
+	case "ja":
+		return JapaneseTokenizer{}
+	case "th":
+		return ThaiTokenizer{}
+	}
+	return UnicodeTokenizer{}
+}
+
+// itemTokenizer returns the tokenizer if set, the tokenizer of the item's language otherwise
+func itemTokenizer(t Tokenizer, i *Item) Tokenizer {
+	if t != nil {
+		return t
+	}
+	return TokenizerForLanguage(i.Language)
+}
+
+// Characters breaking rules
+const (
+	// Lines can't be broken before these characters
+	tokenizerClosing = ".,!?;:)]}»›%‰、。，．！？；：」』）］｝】〕〉》〗〙〛ー々ゝゞ・…‥ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶ"
+	// Lines can't be broken after these characters
+	tokenizerOpening = "([{«‹¿¡「『（［｛【〔〈《〖〘〚"
+	// Sentences end with these characters
+	tokenizerTerminators = ".!?…。！？"
+)
+
+// tokenizerIsSpace returns whether the rune separates words. No-break spaces don't.
+func tokenizerIsSpace(r rune) bool {
+	return unicode.IsSpace(r) && r != '\u00a0' && r != '\u2007' && r != '\u202f'
+}
+
+// tokenizerIsCJK returns whether the rune belongs to a script written without spaces where lines can be broken
+// between any characters
+func tokenizerIsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) || (r >= 0x3000 && r <= 0x303f) || (r >= 0xff01 && r <= 0xff60)
+}
+
+// tokenizerIsPunctuation returns whether the text only contains punctuation
+func tokenizerIsPunctuation(text string) bool {
+	for _, r := range text {
+		if !unicode.IsPunct(r) && !unicode.IsSymbol(r) {
+			return false
+		}
+	}
+	return len(text) > 0
+}
+
+// tokenizerWords builds words out of texts and sets their no-break rules. Lines can't be broken before closing
+// punctuation nor after opening punctuation, even when separated by a white space such as in French.
+func tokenizerWords(texts []string, spaces []bool) (ws []Word) {
+	for idx, t := range texts {
+		var w = Word{SpaceBefore: spaces[idx], Text: t}
+		if idx > 0 {
+			var first, _ = utf8.DecodeRuneInString(t)
+			var last, _ = utf8.DecodeLastRuneInString(texts[idx-1])
+			var closing = strings.ContainsRune(tokenizerClosing, first) && (!w.SpaceBefore || tokenizerIsPunctuation(t))
+			var opening = strings.ContainsRune(tokenizerOpening, last) && (!w.SpaceBefore || tokenizerIsPunctuation(texts[idx-1]))
+			w.NoBreakBefore = closing || opening
+		}
+		ws = append(ws, w)
+	}
+	return
+}
+
+// joinWords joins words back into a text
+func joinWords(ws []Word) string {
+	var b strings.Builder
+	for idx, w := range ws {
+		if idx > 0 && w.SpaceBefore {
+			b.WriteString(" ")
+		}
+		b.WriteString(w.Text)
+	}
+	return b.String()
+}
+
+// UnicodeTokenizer is the default tokenizer. Words are separated by white spaces, except in Chinese and Japanese
+// where each character is a word, and sentences end with terminal punctuation.
+type UnicodeTokenizer struct{}
+
+// Sentences implements the Tokenizer interface
+func (UnicodeTokenizer) Sentences(text string) (ss []string) {
+	// Loop through runes
+	var rs = []rune(text)
+	var start int
+	for idx := 0; idx < len(rs); idx++ {
+		// Not a terminator
+		if !strings.ContainsRune(tokenizerTerminators, rs[idx]) {
+			continue
+		}
+
+		// Include following terminators and closing punctuation
+		var cjk = tokenizerIsCJK(rs[idx])
+		for idx+1 < len(rs) && (strings.ContainsRune(tokenizerTerminators, rs[idx+1]) || strings.ContainsRune(tokenizerClosing+"\"'’”", rs[idx+1])) {
+			idx++
+		}
+
+		// Sentence ends when followed by a white space, unless written in a script without spaces
+		if idx+1 < len(rs) && !cjk && !tokenizerIsSpace(rs[idx+1]) {
+			continue
+		}
+		if s := strings.TrimSpace(string(rs[start : idx+1])); len(s) > 0 {
+			ss = append(ss, s)
+		}
+		start = idx + 1
+	}
+	if s := strings.TrimSpace(string(rs[start:])); len(s) > 0 {
+		ss = append(ss, s)
+	}
+	return
+}
+
+// Words implements the Tokenizer interface
+func (UnicodeTokenizer) Words(text string) []Word {
+	return segmentWords(text, func(previous, r rune) bool { return tokenizerIsCJK(previous) || tokenizerIsCJK(r) })
+}
+
+// segmentWords splits a text into words on white spaces and wherever split returns true for two consecutive runes
+func segmentWords(text string, split func(previous, r rune) bool) []Word {
+	// Loop through runes
+	var texts []string
+	var spaces []bool
+	var current []rune
+	var space bool
+	for _, r := range text {
+		// End word
+		if len(current) > 0 && (tokenizerIsSpace(r) || split(current[len(current)-1], r)) {
+			texts = append(texts, string(current))
+			spaces = append(spaces, space)
+			current = nil
+			space = false
+		}
+
+		// White space
+		if tokenizerIsSpace(r) {
+			space = len(texts) > 0
+			continue
+		}
+		current = append(current, r)
+	}
+	if len(current) > 0 {
+		texts = append(texts, string(current))
+		spaces = append(spaces, space)
+	}
+	return tokenizerWords(texts, spaces)
+}
+
+// JapaneseTokenizer splits Japanese texts into phrases rather than characters: kanji followed by hiragana, such as
+// okurigana and particles, hiragana, katakana and latin runs. Punctuation sticks to the phrase it follows.
+type JapaneseTokenizer struct{}
+
+// Japanese scripts
+const (
+	japaneseScriptOther = iota
+	japaneseScriptHiragana
+	japaneseScriptKanji
+	japaneseScriptKatakana
+	japaneseScriptPunctuation
+)
+
+// japaneseScript returns the script of a rune
+func japaneseScript(r rune) int {
+	switch {
+	case r == 'ー' || unicode.Is(unicode.Katakana, r):
+		return japaneseScriptKatakana
+	case unicode.Is(unicode.Hiragana, r):
+		return japaneseScriptHiragana
+	case unicode.Is(unicode.Han, r) || r == '々':
+		return japaneseScriptKanji
+	case tokenizerIsCJK(r) || unicode.IsPunct(r):
+		return japaneseScriptPunctuation
+	}
+	return japaneseScriptOther
+}
+
+// Sentences implements the Tokenizer interface
+func (JapaneseTokenizer) Sentences(text string) []string {
+	return UnicodeTokenizer{}.Sentences(text)
+}
+
+// Words implements the Tokenizer interface
+func (JapaneseTokenizer) Words(text string) []Word {
+	return segmentWords(text, func(previous, r rune) bool {
+		var p, c = japaneseScript(previous), japaneseScript(r)
+		switch {
+		case c == japaneseScriptPunctuation:
+			return strings.ContainsRune(tokenizerOpening, r)
+		case p == japaneseScriptPunctuation:
+			return !strings.ContainsRune(tokenizerOpening, previous)
+		case c == japaneseScriptHiragana:
+			return p != japaneseScriptHiragana && p != japaneseScriptKanji
+		}
+		return p != c
+	})
+}
+
+// ThaiTokenizer splits Thai texts, which are written without spaces between words, using a dictionary: the longest
+// word of the dictionary is matched first. Without match, texts are split between character clusters, which keep
+// vowels and tone marks with their consonant.
+type ThaiTokenizer struct {
+	Dictionary []string
+}
+
+// Sentences implements the Tokenizer interface. Thai sentences are separated by white spaces rather than terminal
+// punctuation, which is still handled for mixed texts.
+func (ThaiTokenizer) Sentences(text string) (ss []string) {
+	for _, s := range (UnicodeTokenizer{}).Sentences(text) {
+		ss = append(ss, strings.Fields(s)...)
+	}
+	return
+}
+
+// Words implements the Tokenizer interface
+func (t ThaiTokenizer) Words(text string) (ws []Word) {
+	// Index dictionary
+	var d = make(map[string]bool)
+	var longest int
+	for _, w := range t.Dictionary {
+		d[w] = true
+		if n := utf8.RuneCountInString(w); n > longest {
+			longest = n
+		}
+	}
+
+	// Loop through white space separated words
+	for _, w := range (UnicodeTokenizer{}).Words(text) {
+		// Split Thai runs
+		var rs = []rune(w.Text)
+		var texts []string
+		var other []rune
+		for idx := 0; idx < len(rs); {
+			// Not Thai
+			if !unicode.Is(unicode.Thai, rs[idx]) {
+				other = append(other, rs[idx])
+				idx++
+				continue
+			}
+			if len(other) > 0 {
+				texts = append(texts, string(other))
+				other = nil
+			}
+
+			// Look for the longest word of the dictionary
+			var n int
+			for l := longest; l > 0 && n == 0; l-- {
+				if idx+l <= len(rs) && d[string(rs[idx:idx+l])] && thaiClusterEnd(rs, idx+l) {
+					n = l
+				}
+			}
+
+			// Fall back to a character cluster
+			if n == 0 {
+				n = thaiClusterLength(rs, idx)
+			}
+			texts = append(texts, string(rs[idx:idx+n]))
+			idx += n
+		}
+		if len(other) > 0 {
+			texts = append(texts, string(other))
+		}
+
+		// Add words
+		var spaces = make([]bool, len(texts))
+		if len(spaces) > 0 {
+			spaces[0] = w.SpaceBefore
+		}
+		var nws = tokenizerWords(texts, spaces)
+		if len(nws) > 0 {
+			nws[0].NoBreakBefore = w.NoBreakBefore
+		}
+		ws = append(ws, nws...)
+	}
+	return
+}
+
+// thaiLeadingVowels are the vowels written before the consonant they're pronounced after
+const thaiLeadingVowels = "เแโใไ"
+
+// thaiClusterEnd returns whether a character cluster can end right before the rune at the index
+func thaiClusterEnd(rs []rune, idx int) bool {
+	return idx >= len(rs) || (!unicode.Is(unicode.Mn, rs[idx]) && rs[idx] != 'ะ' && rs[idx] != 'า' && rs[idx] != 'ำ' && !strings.ContainsRune(thaiLeadingVowels, rs[idx-1]))
+}
+
+// thaiClusterLength returns the number of runes of the character cluster starting at the index
+func thaiClusterLength(rs []rune, idx int) (n int) {
+	for n = 1; idx+n < len(rs) && unicode.Is(unicode.Thai, rs[idx+n]) && !thaiClusterEnd(rs, idx+n); n++ {
+	}
+	return
+}
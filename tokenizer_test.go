@@ -0,0 +1,54 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizer(t *testing.T) {
+	var texts = func(ws []astisub.Word) (o []string) {
+		for _, w := range ws {
+			o = append(o, w.Text)
+		}
+		return
+	}
+
+	// Unicode
+	var ws = astisub.UnicodeTokenizer{}.Words("« Bonjour toi » ! 日本語です。")
+	assert.Equal(t, []string{"«", "Bonjour toi", "»", "!", "日", "本", "語", "で", "す", "。"}, texts(ws))
+	assert.Equal(t, astisub.Word{NoBreakBefore: true, SpaceBefore: true, Text: "Bonjour toi"}, ws[1])
+	assert.True(t, ws[2].NoBreakBefore)
+	assert.True(t, ws[3].NoBreakBefore)
+	assert.Equal(t, astisub.Word{SpaceBefore: true, Text: "日"}, ws[4])
+	assert.Equal(t, astisub.Word{Text: "本"}, ws[5])
+	assert.Equal(t, astisub.Word{NoBreakBefore: true, Text: "。"}, ws[9])
+	assert.Equal(t, []string{"Hello there!", "How are you?\"", "Fine...", "Ok"}, astisub.UnicodeTokenizer{}.Sentences("Hello there! How are you?\" Fine... Ok"))
+	assert.Equal(t, []string{"こんにちは。", "元気？"}, astisub.UnicodeTokenizer{}.Sentences("こんにちは。元気？"))
+
+	// Japanese
+	assert.Equal(t, astisub.JapaneseTokenizer{}, astisub.TokenizerForLanguage("ja-JP"))
+	assert.Equal(t, []string{"私は", "学生です。", "「本当？」", "コーヒー", "を", "飲みます"}, texts(astisub.JapaneseTokenizer{}.Words("私は学生です。「本当？」コーヒーを飲みます")))
+
+	// Thai
+	assert.Equal(t, astisub.ThaiTokenizer{}, astisub.TokenizerForLanguage("th"))
+	assert.Equal(t, []string{"สวัสดี", "ครับ", "ok"}, texts(astisub.ThaiTokenizer{Dictionary: []string{"สวัส", "สวัสดี", "ครับ"}}.Words("สวัสดีครับ ok")))
+	assert.Equal(t, []string{"ก", "ล", "เก", "ม"}, texts(astisub.ThaiTokenizer{}.Words("กลเกม")))
+	assert.Equal(t, []string{"สวัสดีครับ", "ไปไหน", "Hi!", "ok"}, astisub.ThaiTokenizer{}.Sentences("สวัสดีครับ ไปไหน Hi! ok"))
+
+	// Wrap
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Language: "ja", Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "私は学生です。コーヒーを飲みます"}}}}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "日本語の文章です。"}}}}, StartAt: 3 * time.Second},
+	}}
+	s.ApplyLimits(astisub.Limits{MaxCharactersPerLine: 8})
+	assert.Equal(t, "私は学生です。", s.Items[0].Lines[0].String())
+	assert.Equal(t, "コーヒーを", s.Items[0].Lines[1].String())
+	assert.Equal(t, "飲みます", s.Items[0].Lines[2].String())
+	assert.Equal(t, "日本語の文章です。", s.Items[1].Lines[0].String())
+	s.ApplyLimits(astisub.Limits{MaxCharactersPerLine: 5})
+	assert.Equal(t, "日本語の文", s.Items[1].Lines[0].String())
+	assert.Equal(t, "章です。", s.Items[1].Lines[1].String())
+}
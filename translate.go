@@ -0,0 +1,111 @@
+package astisub
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Translator represents an entity capable of translating texts, such as a machine translation provider
+// Translate must return as many texts as it has received, in the same order
+type Translator interface {
+	Translate(ctx context.Context, texts []string, from, to string) ([]string, error)
+}
+
+// TranslatorFunc allows using a function as a Translator
+type TranslatorFunc func(ctx context.Context, texts []string, from, to string) ([]string, error)
+
+// Translate implements the Translator interface
+func (f TranslatorFunc) Translate(ctx context.Context, texts []string, from, to string) ([]string, error) {
+	return f(ctx, texts, from, to)
+}
+
+// TranslateOptions represents translate options
+type TranslateOptions struct {
+	BatchSize int    // Max number of texts sent to the translator at once. If 0, all texts are sent at once.
+	Bilingual bool   // If true, translated lines are added after the original lines instead of replacing them
+	From      string // Defaults to the metadata language
+	To        string
+}
+
+// Translate translates every line of the subtitles while preserving timing and line structure.
+// Each line is translated as a whole so that the translator gets enough context: the resulting line is made of a
+// single line item holding the styles of the first line item of the original line.
+func (s *Subtitles) Translate(ctx context.Context, t Translator, o TranslateOptions) (err error) {
+	// Get source language
+	var from = o.From
+	if len(from) == 0 && s.Metadata != nil {
+		from = s.Metadata.Language
+	}
+
+	// Get texts to translate
+	type ref struct{ item, line int }
+	var refs []ref
+	var texts []string
+	for idxItem, i := range s.Items {
+		for idxLine, l := range i.Lines {
+			if text := l.String(); len(strings.TrimSpace(text)) > 0 {
+				refs = append(refs, ref{item: idxItem, line: idxLine})
+				texts = append(texts, text)
+			}
+		}
+	}
+
+	// Translate in batches
+	var size = o.BatchSize
+	if size <= 0 {
+		size = len(texts)
+	}
+	var translations []string
+	for start := 0; start < len(texts); start += size {
+		// Get batch
+		var end = start + size
+		if end > len(texts) {
+			end = len(texts)
+		}
+
+		// Translate
+		var ts []string
+		if ts, err = t.Translate(ctx, texts[start:end], from, o.To); err != nil {
+			err = errors.Wrap(err, "astisub: translating failed")
+			return
+		}
+
+		// Translator didn't return as many texts as it received
+		if len(ts) != end-start {
+			err = errors.Errorf("astisub: translator returned %d texts instead of %d", len(ts), end-start)
+			return
+		}
+		translations = append(translations, ts...)
+	}
+
+	// Update lines
+	for idx, r := range refs {
+		// Build translated line
+		var i = s.Items[r.item]
+		var ol = i.Lines[r.line]
+		var li = LineItem{Text: translations[idx]}
+		if len(ol.Items) > 0 {
+			li.InlineStyle = ol.Items[0].InlineStyle
+			li.Style = ol.Items[0].Style
+		}
+		var tl = Line{Items: []LineItem{li}, VoiceName: ol.VoiceName}
+
+		// Update item
+		if o.Bilingual {
+			i.Lines = append(i.Lines, tl)
+		} else {
+			i.Lines[r.line] = tl
+		}
+	}
+
+	// Update language
+	if !o.Bilingual && len(o.To) > 0 {
+		if s.Metadata == nil {
+			s.Metadata = &Metadata{}
+		}
+		s.Metadata.Language = o.To
+	}
+	return
+}
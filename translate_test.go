@@ -0,0 +1,54 @@
+package astisub_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Translate(t *testing.T) {
+	// Init
+	var batches [][]string
+	var tr = astisub.TranslatorFunc(func(ctx context.Context, texts []string, from, to string) (o []string, err error) {
+		assert.Equal(t, astisub.LanguageFrench, from)
+		assert.Equal(t, astisub.LanguageEnglish, to)
+		batches = append(batches, texts)
+		for _, text := range texts {
+			o = append(o, strings.ToUpper(text))
+		}
+		return
+	})
+	var sa = &astisub.StyleAttributes{}
+	var newSubtitles = func() *astisub.Subtitles {
+		return &astisub.Subtitles{
+			Items: []*astisub.Item{
+				{Lines: []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: sa, Text: "a"}, {Text: "b"}}, VoiceName: "v"}, {Items: []astisub.LineItem{{Text: "c"}}}}},
+				{Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "d"}}}}},
+			},
+			Metadata: &astisub.Metadata{Language: astisub.LanguageFrench},
+		}
+	}
+
+	// Replace
+	s := newSubtitles()
+	assert.NoError(t, s.Translate(context.Background(), tr, astisub.TranslateOptions{BatchSize: 2, To: astisub.LanguageEnglish}))
+	assert.Equal(t, [][]string{{"a b", "c"}, {"d"}}, batches)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{{InlineStyle: sa, Text: "A B"}}, VoiceName: "v"}, {Items: []astisub.LineItem{{Text: "C"}}}}, s.Items[0].Lines)
+	assert.Equal(t, "D", s.Items[1].String())
+	assert.Equal(t, astisub.LanguageEnglish, s.Metadata.Language)
+
+	// Bilingual
+	s = newSubtitles()
+	assert.NoError(t, s.Translate(context.Background(), tr, astisub.TranslateOptions{Bilingual: true, To: astisub.LanguageEnglish}))
+	assert.Equal(t, "a b - c - A B - C", s.Items[0].String())
+	assert.Equal(t, astisub.LanguageFrench, s.Metadata.Language)
+
+	// Invalid translator
+	s = newSubtitles()
+	assert.Error(t, s.Translate(context.Background(), astisub.TranslatorFunc(func(ctx context.Context, texts []string, from, to string) ([]string, error) {
+		return nil, nil
+	}), astisub.TranslateOptions{To: astisub.LanguageEnglish}))
+}
@@ -1,10 +1,13 @@
 package astisub
 
 import (
+	"bytes"
 	"encoding/xml"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"math"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -37,28 +40,62 @@ var (
 	ttmlRegexpOffsetTime      = regexp.MustCompile("^(\\d+)(\\.(\\d+))?(h|m|s|ms|f|t)$")
 )
 
+// TTML lengths
+var ttmlRegexpLengthEm = regexp.MustCompile("[\\d.]em\\b")
+
+// TTML text
+var (
+	ttmlRegexpCDATA         = regexp.MustCompile("(?s)<!\\[CDATA\\[.*?\\]\\]>")
+	ttmlRegexpNewlineEntity = regexp.MustCompile("(&#(13|x0*[dD]);)?&#(10|x0*[aA]);")
+)
+
 // TTMLIn represents an input TTML that must be unmarshaled
 // We split it from the output TTML as we can't add strict namespace without breaking retrocompatibility
 type TTMLIn struct {
-	Framerate int              `xml:"frameRate,attr"`
-	Lang      string           `xml:"lang,attr"`
-	Metadata  TTMLInMetadata   `xml:"head>metadata"`
-	Regions   []TTMLInRegion   `xml:"head>layout>region"`
-	Styles    []TTMLInStyle    `xml:"head>styling>style"`
-	Subtitles []TTMLInSubtitle `xml:"body>div>p"`
-	XMLName   xml.Name         `xml:"tt"`
+	Attributes []xml.Attr       `xml:",any,attr"`
+	Framerate  int              `xml:"frameRate,attr"`
+	Lang       string           `xml:"lang,attr"`
+	Metadata   TTMLInMetadata   `xml:"head>metadata"`
+	Regions    []TTMLInRegion   `xml:"head>layout>region"`
+	Role       string           `xml:"role,attr"`
+	Styles     []TTMLInStyle    `xml:"head>styling>style"`
+	Subtitles  []TTMLInSubtitle `xml:"body>div>p"`
+	XMLName    xml.Name         `xml:"tt"`
 }
 
 // metadata returns the Metadata of the TTML
 func (t TTMLIn) metadata() *Metadata {
 	return &Metadata{
 		Framerate:     t.Framerate,
+		Kind:          ttmlTrackKind(t.Role),
 		Language:      ttmlLanguageMapping.B(astistring.ToLength(t.Lang, " ", 2)).(string),
 		Title:         t.Metadata.Title,
 		TTMLCopyright: t.Metadata.Copyright,
 	}
 }
 
+// TTML roles indexed by track kind
+// Kinds that don't match a predefined role use extension roles
+var ttmlRoles = map[TrackKind]string{
+	TrackKindCaptions:     "caption",
+	TrackKindChapters:     "x-chapters",
+	TrackKindDescriptions: "description",
+	TrackKindForced:       "x-forced",
+	TrackKindMetadata:     "x-metadata",
+	TrackKindSDH:          "x-sdh",
+	TrackKindSubtitles:    "x-subtitles",
+}
+
+// ttmlTrackKind returns the track kind matching a TTML role
+func ttmlTrackKind(role string) TrackKind {
+	for k, r := range ttmlRoles {
+		if r == role {
+			return k
+		}
+	}
+	return ""
+}
+
 // TTMLInMetadata represents an input TTML Metadata
 type TTMLInMetadata struct {
 	Copyright string `xml:"copyright"`
@@ -125,6 +162,49 @@ func (s TTMLInStyleAttributes) styleAttributes() (o *StyleAttributes) {
 	return
 }
 
+// TTML named colors
+var ttmlNamedColors = map[string]*Color{
+	"black":   ColorBlack,
+	"blue":    ColorBlue,
+	"cyan":    ColorCyan,
+	"fuchsia": ColorMagenta,
+	"gray":    ColorGray,
+	"green":   ColorGreen,
+	"lime":    ColorLime,
+	"magenta": ColorMagenta,
+	"maroon":  ColorMaroon,
+	"navy":    ColorNavy,
+	"olive":   ColorOlive,
+	"purple":  ColorPurple,
+	"red":     ColorRed,
+	"silver":  ColorSilver,
+	"teal":    ColorTeal,
+	"white":   ColorWhite,
+	"yellow":  ColorYellow,
+}
+
+// ttmlColor parses a TTML color, nil if the color can't be parsed
+func ttmlColor(i string) *Color {
+	// Named color
+	if c, ok := ttmlNamedColors[strings.ToLower(i)]; ok {
+		return c
+	}
+
+	// Hexadecimal color
+	if !strings.HasPrefix(i, "#") || (len(i) != 7 && len(i) != 9) {
+		return nil
+	}
+	v, err := strconv.ParseUint(i[1:], 16, 32)
+	if err != nil {
+		return nil
+	}
+	if len(i) == 7 {
+		v = v<<8 | 0xff
+	}
+	// Alpha is stored as a transparency, the way SSA does
+	return &Color{Alpha: 0xff - uint8(v), Blue: uint8(v >> 8), Green: uint8(v >> 16), Red: uint8(v >> 24)}
+}
+
 // TTMLInHeader represents an input TTML header
 type TTMLInHeader struct {
 	ID    string `xml:"id,attr,omitempty"`
@@ -146,15 +226,25 @@ type TTMLInStyle struct {
 
 // TTMLInSubtitle represents an input TTML subtitle
 type TTMLInSubtitle struct {
-	Begin  *TTMLInDuration `xml:"begin,attr,omitempty"`
-	End    *TTMLInDuration `xml:"end,attr,omitempty"`
-	ID     string          `xml:"id,attr,omitempty"`
-	Items  string          `xml:",innerxml"` // We must store inner XML here since there's no tag to describe both any tag and chardata
-	Region string          `xml:"region,attr,omitempty"`
-	Style  string          `xml:"style,attr,omitempty"`
+	Attributes    []xml.Attr      `xml:",any,attr"`
+	Audio         *TTMLInAudio    `xml:"audio"`
+	Begin         *TTMLInDuration `xml:"begin,attr,omitempty"`
+	End           *TTMLInDuration `xml:"end,attr,omitempty"`
+	ForcedDisplay string          `xml:"forcedDisplay,attr,omitempty"`
+	ID            string          `xml:"id,attr,omitempty"`
+	Items         string          `xml:",innerxml"` // We must store inner XML here since there's no tag to describe both any tag and chardata
+	Lang          string          `xml:"lang,attr,omitempty"`
+	Region        string          `xml:"region,attr,omitempty"`
+	Role          string          `xml:"role,attr,omitempty"`
+	Style         string          `xml:"style,attr,omitempty"`
 	TTMLInStyleAttributes
 }
 
+// TTMLInAudio represents an input TTML audio pointer
+type TTMLInAudio struct {
+	Src string `xml:"src,attr"`
+}
+
 // TTMLInItems represents input TTML items
 type TTMLInItems []TTMLInItem
 
@@ -179,6 +269,29 @@ func (i *TTMLInItems) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err
 				err = errors.Wrap(err, "astisub: decoding xml.StartElement failed")
 				return
 			}
+
+			// Audio pointers are not text
+			if se.Name.Local == "audio" {
+				continue
+			}
+
+			// Item contains elements such as nested spans or line breaks, whose text would otherwise be joined
+			if !e.isBR() && strings.Contains(e.Items, "<") {
+				var children = TTMLInItems{}
+				if err = xml.Unmarshal([]byte("<span>"+e.Items+"</span>"), &children); err != nil {
+					err = errors.Wrap(err, "astisub: unmarshaling items failed")
+					return
+				}
+				for _, c := range children {
+					// Children inherit their parent's styles if they don't have any
+					if !c.isBR() && len(c.Style) == 0 && c.TTMLInStyleAttributes == (TTMLInStyleAttributes{}) {
+						c.Style = e.Style
+						c.TTMLInStyleAttributes = e.TTMLInStyleAttributes
+					}
+					*i = append(*i, c)
+				}
+				continue
+			}
 			*i = append(*i, e)
 		} else if b, ok := t.(xml.CharData); ok {
 			var str = strings.TrimSpace(string(b))
@@ -192,12 +305,20 @@ func (i *TTMLInItems) UnmarshalXML(d *xml.Decoder, start xml.StartElement) (err
 
 // TTMLInItem represents an input TTML item
 type TTMLInItem struct {
-	Style string `xml:"style,attr,omitempty"`
-	Text  string `xml:",chardata"`
+	Begin *TTMLInDuration `xml:"begin,attr,omitempty"`
+	End   *TTMLInDuration `xml:"end,attr,omitempty"`
+	Items string          `xml:",innerxml"`
+	Style string          `xml:"style,attr,omitempty"`
+	Text  string          `xml:",chardata"`
 	TTMLInStyleAttributes
 	XMLName xml.Name
 }
 
+// isBR returns whether the item is a line break, whatever its namespace
+func (i TTMLInItem) isBR() bool {
+	return strings.ToLower(i.XMLName.Local) == "br"
+}
+
 // TTMLInDuration represents an input TTML duration
 type TTMLInDuration struct {
 	d                 time.Duration
@@ -289,20 +410,105 @@ func (d TTMLInDuration) duration() time.Duration {
 	return d.d
 }
 
+// TTMLProfile represents a TTML profile
+type TTMLProfile string
+
+// TTML profiles
+const (
+	// IMSC1 text profile
+	TTMLProfileIMSC1 TTMLProfile = "http://www.w3.org/ns/ttml/profile/imsc1/text"
+)
+
+// ErrUnsupportedFeature is returned when subtitles use a feature that is not supported by a profile
+var ErrUnsupportedFeature = errors.New("astisub: unsupported feature")
+
+// TTMLOptions represents TTML options
+type TTMLOptions struct {
+	// Cue times are rounded to frame boundaries when writing if set
+	FrameRounding FrameRounding
+	// If true, positioned items without region are assigned regions generated from their vertical position when
+	// writing
+	GenerateRegions bool
+	// If true, raw newlines in texts are considered as line breaks when reading. Otherwise they are considered as white
+	// spaces, as the specification requires, and only "br" tags and newline character references are considered as
+	// line breaks.
+	NewlinesAsBreaks bool
+	// Profile the output must conform to when writing. Writing fails with ErrUnsupportedFeature if the subtitles use
+	// features that are not supported by the profile.
+	Profile TTMLProfile
+	// Characters illegal in XML are removed when writing unless set to SanitizePolicyKeep, in which case they are
+	// replaced with U+FFFD. Other characters are always escaped.
+	Sanitize SanitizePolicy
+	// If true, time boundaries of timed line items are written as span timings when writing, enabling karaoke-style
+	// highlighting
+	TimedLineItems bool
+	// If true, time boundaries of timed lines are written as span timings when writing, so that lines building up,
+	// such as roll-up captions, appear progressively. Timings of timed line items take precedence.
+	TimedLines bool
+}
+
 // ReadFromTTML parses a .ttml content
 func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
+	return ReadFromTTMLWithOptions(i, TTMLOptions{})
+}
+
+// ReadFromTTMLWithOptions parses a .ttml content with options
+func ReadFromTTMLWithOptions(i io.Reader, opts TTMLOptions) (o *Subtitles, err error) {
+	o, _, err = readFromTTML(i, opts, nil)
+	return
+}
+
+// readFromTTML parses a .ttml content with options, calling a hook for each item once it's complete, and returns the
+// language of the document as well
+func readFromTTML(i io.Reader, opts TTMLOptions, h ItemHook) (o *Subtitles, lang string, err error) {
 	// Init
 	o = NewSubtitles()
+	var hk = &itemHooker{h: h}
+
+	// Read content
+	var b []byte
+	if b, err = ioutil.ReadAll(i); err != nil {
+		err = errors.Wrap(err, "astisub: reading failed")
+		return
+	}
+
+	// Convert UTF-16 to UTF-8
+	if c := DetectCharset(b); c == CharsetUTF16BE || c == CharsetUTF16LE {
+		if b, err = decodeCharset(b, c); err != nil {
+			err = errors.Wrapf(err, "astisub: decoding %s failed", c)
+			return
+		}
+	}
 
 	// Unmarshal XML
 	var ttml TTMLIn
-	if err = xml.NewDecoder(i).Decode(&ttml); err != nil {
+	if err = newTTMLDecoder(b).Decode(&ttml); err != nil {
 		err = errors.Wrap(err, "astisub: xml decoding failed")
 		return
 	}
 
+	// Get comments
+	var comments *ttmlComments
+	if comments, err = newTTMLComments(b); err != nil {
+		err = errors.Wrap(err, "astisub: getting comments failed")
+		return
+	}
+
+	// Get division languages
+	var divLangs map[int]string
+	if divLangs, err = ttmlDivisionLanguages(b); err != nil {
+		err = errors.Wrap(err, "astisub: getting division languages failed")
+		return
+	}
+
 	// Add metadata
+	lang = ttml.Lang
 	o.Metadata = ttml.metadata()
+	o.Metadata.Comments = comments.metadata
+	ttmlPreserveAttributes(&o.Preserved, ttml.Attributes)
+	if len(ttml.Role) > 0 && len(o.Metadata.Kind) == 0 {
+		addPreserved(&o.Preserved, FormatTTML, "{"+ttmlNamespaceTTM+"}role", ttml.Role)
+	}
 
 	// Loop through styles
 	var parentStyles = make(map[string]*Style)
@@ -343,15 +549,34 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 	}
 
 	// Loop through subtitles
-	for _, ts := range ttml.Subtitles {
+	for idx, ts := range ttml.Subtitles {
 		// Init item
 		ts.Begin.framerate = ttml.Framerate
 		ts.End.framerate = ttml.Framerate
 		var s = &Item{
+			Comments:    comments.items[idx],
 			EndAt:       ts.End.duration(),
 			InlineStyle: ts.TTMLInStyleAttributes.styleAttributes(),
+			Forced:      ts.ForcedDisplay == "true",
+			Language:    ts.Lang,
 			StartAt:     ts.Begin.duration(),
 		}
+		ttmlPreserveAttributes(&s.Preserved, ts.Attributes)
+
+		// Subtitles inherit the language of their division when it differs from the document's
+		if len(s.Language) == 0 && divLangs[idx] != ttml.Lang {
+			s.Language = divLangs[idx]
+		}
+
+		// Add audio description
+		if ts.Role == ttmlRoles[TrackKindDescriptions] || ts.Audio != nil || o.Metadata.Kind == TrackKindDescriptions {
+			s.AudioDescription = &AudioDescription{}
+			if ts.Audio != nil {
+				s.AudioDescription.Source = ts.Audio.Src
+			}
+		} else if len(ts.Role) > 0 {
+			addPreserved(&s.Preserved, FormatTTML, "{"+ttmlNamespaceTTM+"}role", ts.Role)
+		}
 
 		// Add region
 		if len(ts.Region) > 0 {
@@ -373,7 +598,7 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 
 		// Unmarshal items
 		var items = TTMLInItems{}
-		if err = xml.Unmarshal([]byte("<span>"+ts.Items+"</span>"), &items); err != nil {
+		if err = xml.Unmarshal([]byte("<span>"+ttmlNewlineEntitiesToBreaks(ts.Items)+"</span>"), &items); err != nil {
 			err = errors.Wrap(err, "astisub: unmarshaling items failed")
 			return
 		}
@@ -382,19 +607,23 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 		var l = &Line{}
 		for _, tt := range items {
 			// New line specified with the "br" tag
-			if strings.ToLower(tt.XMLName.Local) == "br" {
-				s.Lines = append(s.Lines, *l)
+			if tt.isBR() {
+				s.Lines = append(s.Lines, ttmlTimedLine(*l))
 				l = &Line{}
 				continue
 			}
 
-			// New line decoded as a line break. This can happen if there's a "br" tag within the text since
-			// since the go xml unmarshaler will unmarshal a "br" tag as a line break if the field has the
-			// chardata xml tag.
-			for idx, li := range strings.Split(tt.Text, "\n") {
+			// Raw newlines are white spaces unless specified otherwise
+			var text = tt.Text
+			if !opts.NewlinesAsBreaks {
+				text = strings.Join(strings.Fields(text), " ")
+			}
+
+			// Split on new lines
+			for idx, li := range strings.Split(text, "\n") {
 				// New line
 				if idx > 0 {
-					s.Lines = append(s.Lines, *l)
+					s.Lines = append(s.Lines, ttmlTimedLine(*l))
 					l = &Line{}
 				}
 
@@ -413,30 +642,331 @@ func ReadFromTTML(i io.Reader) (o *Subtitles, err error) {
 					t.Style = o.Styles[tt.Style]
 				}
 
+				// Add time boundaries. Span timings are relative to their subtitle's begin, spans without begin start
+				// with their subtitle and spans without end last until their subtitle's end.
+				if tt.Begin != nil || tt.End != nil {
+					t.StartAt, t.EndAt = s.StartAt, s.EndAt
+					if tt.Begin != nil {
+						tt.Begin.framerate = ttml.Framerate
+						t.StartAt += tt.Begin.duration()
+					}
+					if tt.End != nil {
+						tt.End.framerate = ttml.Framerate
+						t.EndAt = s.StartAt + tt.End.duration()
+					}
+				}
+
 				// Append items
 				l.Items = append(l.Items, t)
 			}
 
 		}
-		s.Lines = append(s.Lines, *l)
+		s.Lines = append(s.Lines, ttmlTimedLine(*l))
 
 		// Append subtitle
 		o.Items = append(o.Items, s)
+		if err = hk.hook(o.Items); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// ttmlTimedLine returns the line with time boundaries spanning the ones of its line items if they're all timed
+func ttmlTimedLine(l Line) Line {
+	for idx, li := range l.Items {
+		if !li.isTimed() {
+			return l
+		}
+		if idx == 0 || li.StartAt < l.StartAt {
+			l.StartAt = li.StartAt
+		}
+		if li.EndAt > l.EndAt {
+			l.EndAt = li.EndAt
+		}
+	}
+	return l
+}
+
+// newTTMLDecoder creates an XML decoder converting contents to UTF-8 according to their XML declaration
+func newTTMLDecoder(b []byte) (d *xml.Decoder) {
+	d = xml.NewDecoder(bytes.NewReader(b))
+	d.CharsetReader = func(label string, i io.Reader) (io.Reader, error) {
+		// UTF-16 contents have already been converted
+		if strings.HasPrefix(strings.ToLower(label), "utf-16") {
+			return i, nil
+		}
+
+		// Get charset
+		c, err := charsetFromLabel(label)
+		if err != nil {
+			return nil, err
+		}
+		return transcodeToUTF8(i, c)
+	}
+	return
+}
+
+// ttmlComments represents the comments of a TTML content
+type ttmlComments struct {
+	items    map[int][]string // Indexed by subtitle index
+	metadata []string
+}
+
+// newTTMLComments retrieves the comments of a TTML content. Comments located inside a subtitle or between the previous
+// subtitle and it belong to the subtitle, the other ones belong to the metadata.
+func newTTMLComments(b []byte) (c *ttmlComments, err error) {
+	// Init
+	c = &ttmlComments{items: make(map[int][]string)}
+	var d = newTTMLDecoder(b)
+	var path []string
+	var pending []string
+	var idx int
+
+	// Loop through tokens
+	for {
+		// Get next token
+		var t xml.Token
+		if t, err = d.Token(); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			err = errors.Wrap(err, "astisub: getting next token failed")
+			return
+		}
+
+		// Switch on token type
+		switch tt := t.(type) {
+		case xml.StartElement:
+			path = append(path, tt.Name.Local)
+			if ttmlIsSubtitlePath(path) {
+				c.items[idx] = append(c.items[idx], pending...)
+				pending = nil
+			}
+		case xml.EndElement:
+			if ttmlIsSubtitlePath(path) {
+				idx++
+			}
+			path = path[:len(path)-1]
+		case xml.Comment:
+			// Split lines
+			var cs []string
+			for _, l := range strings.Split(string(tt), "\n") {
+				if l = strings.TrimSpace(l); len(l) > 0 {
+					cs = append(cs, l)
+				}
+			}
+
+			// Switch on location
+			switch {
+			case ttmlIsSubtitlePath(path):
+				c.items[idx] = append(c.items[idx], cs...)
+			case len(path) >= 2 && path[1] == "body":
+				pending = append(pending, cs...)
+			default:
+				c.metadata = append(c.metadata, cs...)
+			}
+		}
 	}
+
+	// Comments located after the last subtitle belong to the metadata
+	c.metadata = append(c.metadata, pending...)
+	return
+}
+
+// ttmlDivisionLanguages retrieves the languages of the divisions subtitles belong to, indexed by subtitle index.
+// Subtitles whose division has no language are not indexed.
+func ttmlDivisionLanguages(b []byte) (ls map[int]string, err error) {
+	// Init
+	ls = make(map[int]string)
+	var d = newTTMLDecoder(b)
+	var path []string
+	var lang string
+	var idx int
+
+	// Loop through tokens
+	for {
+		// Get next token
+		var t xml.Token
+		if t, err = d.Token(); err != nil {
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			err = errors.Wrap(err, "astisub: getting next token failed")
+			return
+		}
+
+		// Switch on token type
+		switch tt := t.(type) {
+		case xml.StartElement:
+			path = append(path, tt.Name.Local)
+			if len(path) == 3 && path[1] == "body" && path[2] == "div" {
+				lang = ""
+				for _, a := range tt.Attr {
+					if a.Name.Local == "lang" {
+						lang = a.Value
+					}
+				}
+			} else if ttmlIsSubtitlePath(path) && len(lang) > 0 {
+				ls[idx] = lang
+			}
+		case xml.EndElement:
+			if ttmlIsSubtitlePath(path) {
+				idx++
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	return
+}
+
+// ReadSetFromTTML parses a multi-language .ttml content into a subtitle set
+func ReadSetFromTTML(i io.Reader) (*SubtitleSet, error) {
+	return ReadSetFromTTMLWithOptions(i, TTMLOptions{})
+}
+
+// ReadSetFromTTMLWithOptions parses a multi-language .ttml content with options into a subtitle set holding a track
+// per language, ordered by first appearance. Items are split according to the language of their paragraph or
+// division, falling back to the language of the document. Tracks share styles and regions.
+func ReadSetFromTTMLWithOptions(i io.Reader, opts TTMLOptions) (ss *SubtitleSet, err error) {
+	// Read
+	var s *Subtitles
+	var lang string
+	if s, lang, err = readFromTTML(i, opts, nil); err != nil {
+		return
+	}
+
+	// Loop through items
+	ss = NewSubtitleSet()
+	var tracks = make(map[string]*Track)
+	for _, item := range s.Items {
+		// Get language
+		var l = item.Language
+		if len(l) == 0 {
+			l = lang
+		}
+
+		// Create track
+		if _, ok := tracks[l]; !ok {
+			var m = *s.Metadata
+			m.Language = languageFromTag(l)
+			tracks[l] = &Track{
+				Language: m.Language,
+				Subtitles: &Subtitles{
+					Metadata:  &m,
+					Preserved: s.Preserved,
+					Regions:   s.Regions,
+					Styles:    s.Styles,
+				},
+			}
+			ss.Add(tracks[l])
+		}
+
+		// Append item
+		tracks[l].Subtitles.Items = append(tracks[l].Subtitles.Items, item)
+	}
+	return
+}
+
+// ttmlIsSubtitlePath checks whether a path of tags leads to a subtitle
+func ttmlIsSubtitlePath(path []string) bool {
+	return len(path) == 4 && path[1] == "body" && path[2] == "div" && path[3] == "p"
+}
+
+// Namespaces
+const (
+	ttmlNamespaceEBUTTS = "urn:ebu:tt:style"
+	ttmlNamespaceITTS   = "http://www.w3.org/ns/ttml/profile/imsc1#styling"
+	ttmlNamespaceTTM    = "http://www.w3.org/ns/ttml#metadata"
+	ttmlNamespaceTTP    = "http://www.w3.org/ns/ttml#parameter"
+)
+
+// ttmlPrefixes are the namespace prefixes declared by the output TTML
+var ttmlPrefixes = map[string]string{
+	ttmlNamespaceTTM:                    "ttm",
+	"http://www.w3.org/ns/ttml#styling": "tts",
+}
+
+// ttmlParameterNamespaces are the namespaces of parameters that are applied while reading
+var ttmlParameterNamespaces = map[string]bool{
+	"http://www.w3.org/2006/10/ttaf1#parameter": true,
+	ttmlNamespaceTTP: true,
+}
+
+// ttmlPreserveAttributes preserves attributes that couldn't be modeled. Keys are attribute names in Clark notation.
+func ttmlPreserveAttributes(p **Preserved, as []xml.Attr) {
+	for _, a := range as {
+		// Namespace declarations and parameters are not preserved
+		if a.Name.Space == "xmlns" || (len(a.Name.Space) == 0 && a.Name.Local == "xmlns") || ttmlParameterNamespaces[a.Name.Space] {
+			continue
+		}
+
+		// Add attribute
+		var k = a.Name.Local
+		if len(a.Name.Space) > 0 {
+			k = "{" + a.Name.Space + "}" + k
+		}
+		addPreserved(p, FormatTTML, k, a.Value)
+	}
+}
+
+// ttmlPreservedAttributes returns the attributes that couldn't be modeled while reading
+func ttmlPreservedAttributes(p *Preserved) (as []xml.Attr) {
+	for _, v := range p.values(FormatTTML) {
+		var n = xml.Name{Local: v.Key}
+		if idx := strings.Index(v.Key, "}"); strings.HasPrefix(v.Key, "{") && idx > 0 {
+			n.Space, n.Local = v.Key[1:idx], v.Key[idx+1:]
+			if prefix, ok := ttmlPrefixes[n.Space]; ok {
+				n = xml.Name{Local: prefix + ":" + n.Local}
+			}
+		}
+		as = append(as, xml.Attr{Name: n, Value: v.Value})
+	}
+	return
+}
+
+// ttmlComment converts comments to a TTML comment
+func ttmlComment(comments []string) string {
+	if len(comments) == 0 {
+		return ""
+	}
+	return " " + strings.Replace(strings.Join(comments, "\n"), "--", "- -", -1) + " "
+}
+
+// ttmlNewlineEntitiesToBreaks replaces newline character references outside of CDATA sections with "br" tags so that
+// they can be told apart from raw newlines once unmarshaled
+func ttmlNewlineEntitiesToBreaks(i string) (o string) {
+	var start int
+	for _, idxs := range ttmlRegexpCDATA.FindAllStringIndex(i, -1) {
+		o += ttmlRegexpNewlineEntity.ReplaceAllString(i[start:idxs[0]], "<br/>") + i[idxs[0]:idxs[1]]
+		start = idxs[1]
+	}
+	o += ttmlRegexpNewlineEntity.ReplaceAllString(i[start:], "<br/>")
 	return
 }
 
 // TTMLOut represents an output TTML that must be marshaled
 // We split it from the input TTML as this time we'll add strict namespaces
 type TTMLOut struct {
-	Lang            string            `xml:"xml:lang,attr,omitempty"`
-	Metadata        *TTMLOutMetadata  `xml:"head>metadata,omitempty"`
-	Styles          []TTMLOutStyle    `xml:"head>styling>style,omitempty"` //!\\ Order is important! Keep Styling above Layout
-	Regions         []TTMLOutRegion   `xml:"head>layout>region,omitempty"`
-	Subtitles       []TTMLOutSubtitle `xml:"body>div>p,omitempty"`
-	XMLName         xml.Name          `xml:"http://www.w3.org/ns/ttml tt"`
-	XMLNamespaceTTM string            `xml:"xmlns:ttm,attr"`
-	XMLNamespaceTTS string            `xml:"xmlns:tts,attr"`
+	Attributes         []xml.Attr        `xml:",any,attr"`
+	CellResolution     string            `xml:"ttp:cellResolution,attr,omitempty"`
+	Comments           string            `xml:",comment"`
+	Lang               string            `xml:"xml:lang,attr,omitempty"`
+	Metadata           *TTMLOutMetadata  `xml:"head>metadata,omitempty"`
+	Styles             []TTMLOutStyle    `xml:"head>styling>style,omitempty"` //!\\ Order is important! Keep Styling above Layout
+	Regions            []TTMLOutRegion   `xml:"head>layout>region,omitempty"`
+	Profile            string            `xml:"ttp:profile,attr,omitempty"`
+	Role               string            `xml:"ttm:role,attr,omitempty"`
+	Divisions          []TTMLOutDivision `xml:"body>div,omitempty"`
+	TimeBase           string            `xml:"ttp:timeBase,attr,omitempty"`
+	XMLName            xml.Name          `xml:"http://www.w3.org/ns/ttml tt"`
+	XMLNamespaceEBUTTS string            `xml:"xmlns:ebutts,attr,omitempty"`
+	XMLNamespaceITTS   string            `xml:"xmlns:itts,attr,omitempty"`
+	XMLNamespaceTTM    string            `xml:"xmlns:ttm,attr"`
+	XMLNamespaceTTP    string            `xml:"xmlns:ttp,attr,omitempty"`
+	XMLNamespaceTTS    string            `xml:"xmlns:tts,attr"`
 }
 
 // TTMLOutMetadata represents an output TTML Metadata
@@ -478,25 +1008,49 @@ func ttmlOutStyleAttributesFromStyleAttributes(s *StyleAttributes) TTMLOutStyleA
 	if s == nil {
 		return TTMLOutStyleAttributes{}
 	}
+	var g = s.GenericAttributes(FormatTTML)
+	var color, fontStyle, fontWeight, textAlign, textDecoration = s.TTMLColor, s.TTMLFontStyle, s.TTMLFontWeight, s.TTMLTextAlign, s.TTMLTextDecoration
+	if len(color) == 0 && g.Color != nil {
+		color = fmt.Sprintf("#%.2x%.2x%.2x", g.Color.Red, g.Color.Green, g.Color.Blue)
+	}
+	if len(fontStyle) == 0 && g.Italics != nil && *g.Italics {
+		fontStyle = "italic"
+	}
+	if len(fontWeight) == 0 && g.Bold != nil && *g.Bold {
+		fontWeight = "bold"
+	}
+	if len(textAlign) == 0 {
+		textAlign = alignmentTextAlign(g.Alignment)
+	}
+	if len(textDecoration) == 0 {
+		var ds []string
+		if g.Underline != nil && *g.Underline {
+			ds = append(ds, "underline")
+		}
+		if g.Strikeout != nil && *g.Strikeout {
+			ds = append(ds, "lineThrough")
+		}
+		textDecoration = strings.Join(ds, " ")
+	}
 	return TTMLOutStyleAttributes{
 		BackgroundColor: s.TTMLBackgroundColor,
-		Color:           s.TTMLColor,
+		Color:           color,
 		Direction:       s.TTMLDirection,
 		Display:         s.TTMLDisplay,
 		DisplayAlign:    s.TTMLDisplayAlign,
 		Extent:          s.TTMLExtent,
 		FontFamily:      s.TTMLFontFamily,
 		FontSize:        s.TTMLFontSize,
-		FontStyle:       s.TTMLFontStyle,
-		FontWeight:      s.TTMLFontWeight,
+		FontStyle:       fontStyle,
+		FontWeight:      fontWeight,
 		LineHeight:      s.TTMLLineHeight,
 		Opacity:         s.TTMLOpacity,
 		Origin:          s.TTMLOrigin,
 		Overflow:        s.TTMLOverflow,
 		Padding:         s.TTMLPadding,
 		ShowBackground:  s.TTMLShowBackground,
-		TextAlign:       s.TTMLTextAlign,
-		TextDecoration:  s.TTMLTextDecoration,
+		TextAlign:       textAlign,
+		TextDecoration:  textDecoration,
 		TextOutline:     s.TTMLTextOutline,
 		UnicodeBidi:     s.TTMLUnicodeBidi,
 		Visibility:      s.TTMLVisibility,
@@ -525,21 +1079,41 @@ type TTMLOutStyle struct {
 	XMLName xml.Name `xml:"style"`
 }
 
+// TTMLOutDivision represents an output TTML division
+type TTMLOutDivision struct {
+	Lang      string            `xml:"xml:lang,attr,omitempty"`
+	Subtitles []TTMLOutSubtitle `xml:"p,omitempty"`
+}
+
 // TTMLOutSubtitle represents an output TTML subtitle
 type TTMLOutSubtitle struct {
-	Begin  TTMLOutDuration `xml:"begin,attr"`
-	End    TTMLOutDuration `xml:"end,attr"`
-	ID     string          `xml:"id,attr,omitempty"`
-	Items  []TTMLOutItem
-	Region string `xml:"region,attr,omitempty"`
-	Style  string `xml:"style,attr,omitempty"`
+	Attributes    []xml.Attr      `xml:",any,attr"`
+	Audio         *TTMLOutAudio   `xml:"audio,omitempty"`
+	Begin         TTMLOutDuration `xml:"begin,attr"`
+	Comments      string          `xml:",comment"`
+	End           TTMLOutDuration `xml:"end,attr"`
+	ForcedDisplay string          `xml:"itts:forcedDisplay,attr,omitempty"`
+	ID            string          `xml:"id,attr,omitempty"`
+	Items         []TTMLOutItem
+	Lang          string `xml:"xml:lang,attr,omitempty"`
+	LinePadding   string `xml:"ebutts:linePadding,attr,omitempty"`
+	Region        string `xml:"region,attr,omitempty"`
+	Role          string `xml:"ttm:role,attr,omitempty"`
+	Style         string `xml:"style,attr,omitempty"`
 	TTMLOutStyleAttributes
 }
 
+// TTMLOutAudio represents an output TTML audio pointer
+type TTMLOutAudio struct {
+	Src string `xml:"src,attr"`
+}
+
 // TTMLOutItem represents an output TTML Item
 type TTMLOutItem struct {
-	Style string `xml:"style,attr,omitempty"`
-	Text  string `xml:",chardata"`
+	Begin TTMLOutDuration `xml:"begin,attr,omitempty"`
+	End   TTMLOutDuration `xml:"end,attr,omitempty"`
+	Style string          `xml:"style,attr,omitempty"`
+	Text  string          `xml:",chardata"`
 	TTMLOutStyleAttributes
 	XMLName xml.Name
 }
@@ -553,21 +1127,160 @@ func (t TTMLOutDuration) MarshalText() ([]byte, error) {
 }
 
 // WriteToTTML writes subtitles in .ttml format
-func (s Subtitles) WriteToTTML(o io.Writer) (err error) {
+func (s Subtitles) WriteToTTML(o io.Writer) error {
+	return s.WriteToTTMLWithOptions(o, TTMLOptions{})
+}
+
+// WriteToTTMLWithOptions writes subtitles in .ttml format with options
+func (s Subtitles) WriteToTTMLWithOptions(o io.Writer, opts TTMLOptions) error {
+	return s.writeToTTML(o, opts, nil)
+}
+
+// writeToTTML writes subtitles in .ttml format with options, calling a hook for each item right before it's written
+func (s Subtitles) writeToTTML(o io.Writer, opts TTMLOptions, h ItemHook) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		return ErrNoSubtitlesToWrite
 	}
 
+	// Prepare
+	if s, err = s.ttmlPrepare(opts); err != nil {
+		return
+	}
+
 	// Init TTML
-	var ttml = TTMLOut{
+	var ttml = s.newTTMLOut(opts)
+	var d TTMLOutDivision
+	if d, err = ttml.division(s.Items, "", opts, h); err != nil {
+		return
+	}
+	ttml.Divisions = []TTMLOutDivision{d}
+
+	// Write
+	return writeTTMLOut(o, ttml, opts)
+}
+
+// WriteToTTML writes the tracks of a subtitle set in a multi-language .ttml format
+func (s SubtitleSet) WriteToTTML(o io.Writer) error {
+	return s.WriteToTTMLWithOptions(o, TTMLOptions{})
+}
+
+// WriteToTTMLWithOptions writes the tracks of a subtitle set in a multi-language .ttml format with options. Each
+// track is written in its own division, divisions being ordered by language. Metadata are taken from the first
+// language while styles and regions are gathered, which fails if different styles or regions share the same ID.
+func (s SubtitleSet) WriteToTTMLWithOptions(o io.Writer, opts TTMLOptions) (err error) {
+	// Sort tracks by language
+	var ts []*Track
+	for _, t := range s.Tracks {
+		if t.Subtitles != nil && len(t.Subtitles.Items) > 0 {
+			ts = append(ts, t)
+		}
+	}
+	sort.SliceStable(ts, func(a, b int) bool { return LanguageTag(ts[a].Language) < LanguageTag(ts[b].Language) })
+
+	// Do not write anything if no subtitles
+	if len(ts) == 0 {
+		return ErrNoSubtitlesToWrite
+	}
+
+	// Loop through tracks
+	var m = Subtitles{Regions: make(map[string]*Region), Styles: make(map[string]*Style)}
+	var ps []Subtitles
+	for idx, t := range ts {
+		// Prepare
+		var s Subtitles
+		if s, err = t.Subtitles.ttmlPrepare(opts); err != nil {
+			err = errors.Wrapf(err, "astisub: preparing %s subtitles failed", t.Language)
+			return
+		}
+		ps = append(ps, s)
+
+		// Add metadata
+		if idx == 0 {
+			m.Metadata = s.Metadata
+			m.Preserved = s.Preserved
+		}
+
+		// Add regions
+		for id, r := range s.Regions {
+			if v, ok := m.Regions[id]; ok && !reflect.DeepEqual(v, r) {
+				err = fmt.Errorf("astisub: region %s differs between languages", id)
+				return
+			}
+			m.Regions[id] = r
+		}
+
+		// Add styles
+		for id, st := range s.Styles {
+			if v, ok := m.Styles[id]; ok && !reflect.DeepEqual(v, st) {
+				err = fmt.Errorf("astisub: style %s differs between languages", id)
+				return
+			}
+			m.Styles[id] = st
+		}
+	}
+
+	// Init TTML
+	var ttml = m.newTTMLOut(opts)
+	ttml.Lang = ""
+	for idx, t := range ts {
+		var d TTMLOutDivision
+		if d, err = ttml.division(ps[idx].Items, LanguageTag(t.Language), opts, nil); err != nil {
+			return
+		}
+		ttml.Divisions = append(ttml.Divisions, d)
+	}
+
+	// Write
+	return writeTTMLOut(o, ttml, opts)
+}
+
+// ttmlPrepare returns the subtitles as they must be written
+func (s Subtitles) ttmlPrepare(opts TTMLOptions) (_ Subtitles, err error) {
+	// Generate regions
+	if opts.GenerateRegions {
+		s = s.generateRegions()
+	}
+
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Sanitize
+	s = s.sanitize(FormatTTML, opts.Sanitize)
+
+	// Validate profile
+	if opts.Profile == TTMLProfileIMSC1 {
+		if err = s.validateIMSC1(); err != nil {
+			err = errors.Wrap(err, "astisub: validating imsc1 profile failed")
+			return
+		}
+	}
+	return s, nil
+}
+
+// newTTMLOut creates the output TTML of the subtitles, without divisions
+func (s Subtitles) newTTMLOut(opts TTMLOptions) (ttml TTMLOut) {
+	// Init
+	ttml = TTMLOut{
+		Attributes:      ttmlPreservedAttributes(s.Preserved),
 		XMLNamespaceTTM: "http://www.w3.org/ns/ttml#metadata",
 		XMLNamespaceTTS: "http://www.w3.org/ns/ttml#styling",
 	}
 
+	// Add profile
+	if opts.Profile == TTMLProfileIMSC1 {
+		ttml.CellResolution = ttmlIMSC1CellResolution
+		ttml.Profile = string(opts.Profile)
+		ttml.TimeBase = "media"
+		ttml.XMLNamespaceEBUTTS = ttmlNamespaceEBUTTS
+		ttml.XMLNamespaceTTP = ttmlNamespaceTTP
+	}
+
 	// Add metadata
 	if s.Metadata != nil {
+		ttml.Comments = ttmlComment(s.Metadata.Comments)
 		ttml.Lang = ttmlLanguageMapping.A(s.Metadata.Language).(string)
+		ttml.Role = ttmlRoles[s.Metadata.Kind]
 		if len(s.Metadata.TTMLCopyright) > 0 || len(s.Metadata.Title) > 0 {
 			ttml.Metadata = &TTMLOutMetadata{
 				Copyright: s.Metadata.TTMLCopyright,
@@ -609,16 +1322,54 @@ func (s Subtitles) WriteToTTML(o io.Writer) (err error) {
 		}
 		ttml.Styles = append(ttml.Styles, ttmlStyle)
 	}
+	return
+}
+
+// division creates an output TTML division containing items, calling a hook for each item right before it's added.
+// Items whose language differs from the division's have their own language.
+func (ttml *TTMLOut) division(is []*Item, lang string, opts TTMLOptions, h ItemHook) (d TTMLOutDivision, err error) {
+	d.Lang = lang
+	for idx, item := range is {
+		// Hook
+		if item, err = hookedItem(item, idx, h); err != nil {
+			return
+		}
 
-	// Add items
-	for _, item := range s.Items {
 		// Init subtitle
 		var ttmlSubtitle = TTMLOutSubtitle{
-			Begin: TTMLOutDuration(item.StartAt),
-			End:   TTMLOutDuration(item.EndAt),
+			Attributes: ttmlPreservedAttributes(item.Preserved),
+			Begin:      TTMLOutDuration(item.StartAt),
+			Comments:   ttmlComment(item.Comments),
+			End:        TTMLOutDuration(item.EndAt),
 			TTMLOutStyleAttributes: ttmlOutStyleAttributesFromStyleAttributes(item.InlineStyle),
 		}
 
+		// Add language
+		if item.Language != lang {
+			ttmlSubtitle.Lang = item.Language
+		}
+
+		// Add line padding
+		if opts.Profile == TTMLProfileIMSC1 && len(item.Preserved.valuesByKey(FormatTTML, "{"+ttmlNamespaceEBUTTS+"}linePadding")) == 0 {
+			ttmlSubtitle.LinePadding = ttmlIMSC1LinePadding
+		}
+
+		// Add forced display
+		if item.Forced {
+			ttmlSubtitle.ForcedDisplay = "true"
+			ttml.XMLNamespaceITTS = ttmlNamespaceITTS
+		}
+
+		// Add audio description
+		if item.AudioDescription != nil {
+			if len(item.AudioDescription.Source) > 0 {
+				ttmlSubtitle.Audio = &TTMLOutAudio{Src: item.AudioDescription.Source}
+			}
+			if ttml.Role != ttmlRoles[TrackKindDescriptions] {
+				ttmlSubtitle.Role = ttmlRoles[TrackKindDescriptions]
+			}
+		}
+
 		// Add region
 		if item.Region != nil {
 			ttmlSubtitle.Region = item.Region.ID
@@ -645,6 +1396,15 @@ func (s Subtitles) WriteToTTML(o io.Writer) (err error) {
 					ttmlItem.Style = lineItem.Style.ID
 				}
 
+				// Add time boundaries. Span timings are relative to their subtitle's begin
+				if opts.TimedLineItems && lineItem.isTimed() && lineItem.StartAt >= item.StartAt {
+					ttmlItem.Begin = TTMLOutDuration(lineItem.StartAt - item.StartAt)
+					ttmlItem.End = TTMLOutDuration(lineItem.EndAt - item.StartAt)
+				} else if opts.TimedLines && line.isTimed() && line.StartAt >= item.StartAt {
+					ttmlItem.Begin = TTMLOutDuration(line.StartAt - item.StartAt)
+					ttmlItem.End = TTMLOutDuration(line.EndAt - item.StartAt)
+				}
+
 				// Add ttml item
 				ttmlSubtitle.Items = append(ttmlSubtitle.Items, ttmlItem)
 			}
@@ -659,7 +1419,16 @@ func (s Subtitles) WriteToTTML(o io.Writer) (err error) {
 		}
 
 		// Append subtitle
-		ttml.Subtitles = append(ttml.Subtitles, ttmlSubtitle)
+		d.Subtitles = append(d.Subtitles, ttmlSubtitle)
+	}
+	return
+}
+
+// writeTTMLOut writes an output TTML
+func writeTTMLOut(o io.Writer, ttml TTMLOut, opts TTMLOptions) (err error) {
+	// IMSC1 requires the language to be specified, an empty value meaning it is unknown
+	if opts.Profile == TTMLProfileIMSC1 && len(ttml.Lang) == 0 {
+		ttml.Attributes = append(ttml.Attributes, xml.Attr{Name: xml.Name{Local: "xml:lang"}})
 	}
 
 	// Marshal XML
@@ -671,3 +1440,69 @@ func (s Subtitles) WriteToTTML(o io.Writer) (err error) {
 	}
 	return
 }
+
+// IMSC1 defaults
+const (
+	ttmlIMSC1CellResolution = "32 15"
+	ttmlIMSC1LinePadding    = "0.5c"
+	ttmlIMSC1MaxRegions     = 4
+)
+
+// validateIMSC1 checks that the subtitles only use features supported by the IMSC1 text profile
+func (s Subtitles) validateIMSC1() error {
+	// Check style attributes
+	var checkStyleAttributes = func(sa *StyleAttributes, where string) error {
+		if sa == nil {
+			return nil
+		}
+		for _, v := range []string{sa.TTMLExtent, sa.TTMLFontSize, sa.TTMLLineHeight, sa.TTMLOrigin, sa.TTMLPadding, sa.TTMLTextOutline} {
+			if ttmlRegexpLengthEm.MatchString(v) {
+				return errors.Wrapf(ErrUnsupportedFeature, "astisub: %s uses em length %s", where, v)
+			}
+		}
+		return nil
+	}
+	for _, r := range s.Regions {
+		if err := checkStyleAttributes(r.InlineStyle, "region "+r.ID); err != nil {
+			return err
+		}
+	}
+	for _, st := range s.Styles {
+		if err := checkStyleAttributes(st.InlineStyle, "style "+st.ID); err != nil {
+			return err
+		}
+	}
+
+	// Loop through items
+	for idx, i := range s.Items {
+		// Check audio
+		if i.AudioDescription != nil && len(i.AudioDescription.Source) > 0 {
+			return errors.Wrapf(ErrUnsupportedFeature, "astisub: item #%d references audio %s", idx+1, i.AudioDescription.Source)
+		}
+
+		// Check style attributes
+		var where = fmt.Sprintf("item #%d", idx+1)
+		if err := checkStyleAttributes(i.InlineStyle, where); err != nil {
+			return err
+		}
+		for _, l := range i.Lines {
+			for _, li := range l.Items {
+				if err := checkStyleAttributes(li.InlineStyle, where); err != nil {
+					return err
+				}
+			}
+		}
+
+		// Check number of regions presented simultaneously
+		var regions = make(map[string]bool)
+		for _, o := range s.Items {
+			if o.StartAt <= i.StartAt && i.StartAt < o.EndAt {
+				regions[itemRegionID(o)] = true
+			}
+		}
+		if len(regions) > ttmlIMSC1MaxRegions {
+			return errors.Wrapf(ErrUnsupportedFeature, "astisub: %d regions are presented simultaneously at %s, which is more than %d", len(regions), i.StartAt, ttmlIMSC1MaxRegions)
+		}
+	}
+	return nil
+}
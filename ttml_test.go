@@ -3,9 +3,13 @@ package astisub_test
 import (
 	"bytes"
 	"io/ioutil"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/asticode/go-astisub"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -49,3 +53,268 @@ func TestTTML(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(c), w.String())
 }
+
+func TestTTMLText(t *testing.T) {
+	// Init
+	const c = `<?xml version="1.0" encoding="UTF-8"?>
+<tt xmlns="http://www.w3.org/ns/ttml" xmlns:tt="http://www.w3.org/ns/ttml">
+    <body>
+        <div>
+            <p begin="00:00:01.000" end="00:00:02.000"><![CDATA[1 < 2]]>&#10;numeric</p>
+            <p begin="00:00:02.000" end="00:00:03.000"><span>nested<br/>break</span><tt:br/>raw
+newline</p>
+        </div>
+    </body>
+</tt>`
+
+	// Raw newlines are white spaces
+	s, err := astisub.ReadFromTTML(strings.NewReader(c))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 2)
+	assert.Equal(t, []string{"1 < 2", "numeric"}, ttmlLines(s.Items[0]))
+	assert.Equal(t, []string{"nested", "break", "raw newline"}, ttmlLines(s.Items[1]))
+
+	// Raw newlines are breaks
+	s, err = astisub.Read(strings.NewReader(c), astisub.FormatTTML, astisub.Options{TTML: astisub.TTMLOptions{NewlinesAsBreaks: true}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"nested", "break", "raw", "newline"}, ttmlLines(s.Items[1]))
+}
+
+func ttmlLines(i *astisub.Item) (o []string) {
+	for _, l := range i.Lines {
+		o = append(o, l.String())
+	}
+	return
+}
+
+func TestTTMLLanguage(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en"><body><div><p begin="00:00:01.000" end="00:00:02.000" xml:lang="fr">Bonjour</p><p begin="00:00:02.000" end="00:00:03.000">Hello</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "fr", s.Items[0].Language)
+	assert.Equal(t, "", s.Items[1].Language)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" xml:lang="fr">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:02.000" end="00:00:03.000">`)
+}
+
+func TestTTMLMultiLanguage(t *testing.T) {
+	// Read
+	ss, err := astisub.ReadSetFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xml:lang="en"><body><div><p begin="00:00:01.000" end="00:00:02.000">Hello</p></div><div xml:lang="fr"><p begin="00:00:01.000" end="00:00:02.000">Bonjour</p><p begin="00:00:02.000" end="00:00:03.000" xml:lang="de">Hallo</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{astisub.LanguageEnglish, astisub.LanguageFrench, astisub.LanguageGerman}, ss.Languages())
+	en := ss.Track(astisub.LanguageEnglish, astisub.TrackKindSubtitles)
+	assert.Equal(t, "Hello", en.Subtitles.Items[0].String())
+	assert.Equal(t, astisub.LanguageEnglish, en.Subtitles.Metadata.Language)
+	fr := ss.Track(astisub.LanguageFrench, astisub.TrackKindSubtitles)
+	assert.Equal(t, "Bonjour", fr.Subtitles.Items[0].String())
+	assert.Equal(t, astisub.LanguageFrench, fr.Subtitles.Metadata.Language)
+	assert.Equal(t, "Hallo", ss.Track(astisub.LanguageGerman, astisub.TrackKindSubtitles).Subtitles.Items[0].String())
+
+	// Write
+	ss.Tracks = []*astisub.Track{fr, en}
+	w := &bytes.Buffer{}
+	err = ss.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "<body>\n        <div xml:lang=\"en\">\n            <p begin=\"00:00:01.000\" end=\"00:00:02.000\">")
+	assert.Contains(t, w.String(), "<div xml:lang=\"fr\">\n            <p begin=\"00:00:01.000\" end=\"00:00:02.000\">")
+	assert.NotContains(t, w.String(), "<tt xml:lang")
+
+	// Read back
+	ss, err = astisub.ReadSetFromTTML(w)
+	assert.NoError(t, err)
+	assert.Len(t, ss.Tracks, 2)
+	assert.Equal(t, "Bonjour", ss.Track(astisub.LanguageFrench, astisub.TrackKindSubtitles).Subtitles.Items[0].String())
+
+	// Conflicting styles
+	ss.Tracks[0].Subtitles.Styles = map[string]*astisub.Style{"s": {ID: "s"}}
+	ss.Tracks[1].Subtitles.Styles = map[string]*astisub.Style{"s": {ID: "s", InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}}}
+	err = ss.WriteToTTML(&bytes.Buffer{})
+	assert.Error(t, err)
+}
+
+func TestTTMLPreserved(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:foo="urn:foo" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling" foo:bar="baz" ttp:timeBase="media"><body><div><p begin="00:00:01.000" end="00:00:02.000" tts:fontVariant="super" foo:qux="1">test</p></div></body></tt>`))
+	assert.NoError(t, err)
+	var ps = &astisub.Preserved{Format: astisub.FormatTTML, Values: []astisub.PreservedValue{{Key: "{urn:foo}bar", Value: "baz"}}}
+	assert.Equal(t, ps, s.Preserved)
+	var ips = &astisub.Preserved{Format: astisub.FormatTTML, Values: []astisub.PreservedValue{{Key: "{http://www.w3.org/ns/ttml#styling}fontVariant", Value: "super"}, {Key: "{urn:foo}qux", Value: "1"}}}
+	assert.Equal(t, ips, s.Items[0].Preserved)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `tts:fontVariant="super"`)
+
+	// Round trip
+	s, err = astisub.ReadFromTTML(w)
+	assert.NoError(t, err)
+	assert.Equal(t, ps, s.Preserved)
+	assert.Equal(t, ips, s.Items[0].Preserved)
+}
+
+func TestTTMLForced(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:itts="http://www.w3.org/ns/ttml/profile/imsc1#styling"><body><div><p begin="00:00:01.000" end="00:00:02.000" itts:forcedDisplay="true">1</p><p begin="00:00:03.000" end="00:00:04.000">2</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.True(t, s.Items[0].Forced)
+	assert.False(t, s.Items[1].Forced)
+	assert.Nil(t, s.Items[0].Preserved)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `xmlns:itts="http://www.w3.org/ns/ttml/profile/imsc1#styling"`)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" itts:forcedDisplay="true">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:03.000" end="00:00:04.000">`)
+}
+
+func TestTTMLKind(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttm:role="description"><body><div><p begin="00:00:01.000" end="00:00:02.000">A door opens</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.TrackKindDescriptions, s.Metadata.Kind)
+	assert.Nil(t, s.Preserved)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ` ttm:role="description"`)
+
+	// Unknown roles are preserved
+	s, err = astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" ttm:role="x-commentary"><body><div><p begin="00:00:01.000" end="00:00:02.000">1</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.TrackKind(""), s.Metadata.Kind)
+	w.Reset()
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ` ttm:role="x-commentary"`)
+}
+
+func TestTTMLAudioDescription(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml" xmlns:ttm="http://www.w3.org/ns/ttml#metadata"><body><div><p begin="00:00:01.000" end="00:00:02.000" ttm:role="description"><audio src="ad1.wav"/>A door opens</p><p begin="00:00:03.000" end="00:00:04.000" ttm:role="dialog">Hello</p></div></body></tt>`))
+	assert.NoError(t, err)
+	assert.Equal(t, &astisub.AudioDescription{Source: "ad1.wav"}, s.Items[0].AudioDescription)
+	assert.Equal(t, "A door opens", s.Items[0].String())
+	assert.Len(t, s.Items[0].Lines[0].Items, 1)
+	assert.Nil(t, s.Items[1].AudioDescription)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" ttm:role="description">`)
+	assert.Contains(t, w.String(), `<audio src="ad1.wav"></audio>`)
+	assert.Contains(t, w.String(), `<p ttm:role="dialog" begin="00:00:03.000" end="00:00:04.000">`)
+
+	// Descriptions track
+	s.Metadata.Kind = astisub.TrackKindDescriptions
+	w.Reset()
+	err = s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000">`)
+}
+
+func TestTTMLTimedLineItems(t *testing.T) {
+	// Init
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{EndAt: 1500 * time.Millisecond, StartAt: time.Second, Text: "one"},
+			{EndAt: 2500 * time.Millisecond, StartAt: 1500 * time.Millisecond, Text: "two"},
+		}}},
+		StartAt: time.Second,
+	}}}
+
+	// Write
+	w := &bytes.Buffer{}
+	err := s.WriteToTTML(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "<span>one</span>")
+	w.Reset()
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{TimedLineItems: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<span end="00:00:00.500">one</span>`)
+	assert.Contains(t, w.String(), `<span begin="00:00:00.500" end="00:00:01.500">two</span>`)
+
+	// Read
+	s2, err := astisub.ReadFromTTML(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, s.Items[0].Lines[0].Items[0].EndAt, s2.Items[0].Lines[0].Items[0].EndAt)
+	assert.Equal(t, s.Items[0].Lines[0].Items[1].StartAt, s2.Items[0].Lines[0].Items[1].StartAt)
+	assert.Equal(t, time.Second, s2.Items[0].Lines[0].StartAt)
+	assert.Equal(t, 2500*time.Millisecond, s2.Items[0].Lines[0].EndAt)
+}
+
+func TestTTMLTimedLines(t *testing.T) {
+	// Init
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "one"}}},
+			{EndAt: 3 * time.Second, Items: []astisub.LineItem{{Text: "two"}}, StartAt: 2 * time.Second},
+		},
+		StartAt: time.Second,
+	}}}
+
+	// Write
+	w := &bytes.Buffer{}
+	err := s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{TimedLines: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "<span>one</span>")
+	assert.Contains(t, w.String(), `<span begin="00:00:01.000" end="00:00:02.000">two</span>`)
+
+	// Read
+	s2, err := astisub.ReadFromTTML(bytes.NewReader(w.Bytes()))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), s2.Items[0].Lines[0].EndAt)
+	assert.Equal(t, 2*time.Second, s2.Items[0].Lines[1].StartAt)
+	assert.Equal(t, 3*time.Second, s2.Items[0].Lines[1].EndAt)
+}
+
+func TestTTMLIMSC1(t *testing.T) {
+	// Write
+	s, err := astisub.ReadFromTTML(strings.NewReader(`<tt xmlns="http://www.w3.org/ns/ttml"><body><div><p begin="00:00:01.000" end="00:00:02.000">1</p></div></body></tt>`))
+	assert.NoError(t, err)
+	w := &bytes.Buffer{}
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Profile: astisub.TTMLProfileIMSC1})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<tt xmlns="http://www.w3.org/ns/ttml" ttp:cellResolution="32 15" xml:lang="en" ttp:profile="http://www.w3.org/ns/ttml/profile/imsc1/text" ttp:timeBase="media" xmlns:ebutts="urn:ebu:tt:style" xmlns:ttm="http://www.w3.org/ns/ttml#metadata" xmlns:ttp="http://www.w3.org/ns/ttml#parameter" xmlns:tts="http://www.w3.org/ns/ttml#styling">`)
+	assert.Contains(t, w.String(), `<p begin="00:00:01.000" end="00:00:02.000" ebutts:linePadding="0.5c">`)
+
+	// Read back
+	s2, err := astisub.ReadFromTTML(w)
+	assert.NoError(t, err)
+	w.Reset()
+	err = s2.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Profile: astisub.TTMLProfileIMSC1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(w.String(), "linePadding"))
+
+	// Language is always specified
+	w.Reset()
+	err = astisub.Subtitles{Items: s.Items}.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Profile: astisub.TTMLProfileIMSC1})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), ` xml:lang=""`)
+
+	// Unsupported features
+	s.Items[0].InlineStyle = &astisub.StyleAttributes{TTMLFontSize: "1.5em"}
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Profile: astisub.TTMLProfileIMSC1})
+	assert.EqualError(t, errors.Cause(err), astisub.ErrUnsupportedFeature.Error())
+	assert.NoError(t, s.WriteToTTML(w))
+	s.Items[0].InlineStyle = nil
+	for idx := 0; idx < 5; idx++ {
+		s.Items = append(s.Items, &astisub.Item{EndAt: 2 * time.Second, Region: &astisub.Region{ID: strconv.Itoa(idx)}, StartAt: time.Second})
+	}
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{Profile: astisub.TTMLProfileIMSC1})
+	assert.EqualError(t, errors.Cause(err), astisub.ErrUnsupportedFeature.Error())
+	assert.Contains(t, err.Error(), "6 regions are presented simultaneously at 1s")
+}
@@ -0,0 +1,187 @@
+package astisub
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+)
+
+// Validation rules
+const (
+	ValidationRuleCharactersPerLine   = "characters_per_line"
+	ValidationRuleCharactersPerSecond = "characters_per_second"
+	ValidationRuleLinesPerItem        = "lines_per_item"
+	ValidationRuleMaxDuration         = "max_duration"
+	ValidationRuleMinDuration         = "min_duration"
+	ValidationRuleMinGap              = "min_gap"
+	ValidationRuleOverlap             = "overlap"
+	ValidationRuleWordsPerMinute      = "words_per_minute"
+)
+
+// ValidationRule checks subtitles against a rule that profiles can't express with their limits, and returns the
+// issues it has found
+type ValidationRule func(s Subtitles, p ValidationProfile) []ValidationIssue
+
+// ValidationProfile represents the rules of a subtitling guideline. 0 disables a limit.
+type ValidationProfile struct {
+	MaxCharactersPerLine   int
+	MaxCharactersPerSecond float64 // Characters of words and white spaces separating them
+	MaxDuration            time.Duration
+	MaxLines               int
+	MaxWordsPerMinute      float64
+	MinDuration            time.Duration
+	// Gaps between consecutive items shorter than this duration are reported, contiguous items being allowed.
+	// Overlapping items are always reported.
+	MinGap time.Duration
+	Name   string
+	// Rules checked in addition to the limits
+	Rules []ValidationRule
+	// Splits lines into words. Defaults to the tokenizer of the item's language.
+	Tokenizer Tokenizer
+}
+
+// Presets, following the published guidelines of their organization for adult programmes. They are used both to
+// validate subtitles and to score their quality.
+var (
+	PresetAmazon = ValidationProfile{
+		MaxCharactersPerLine:   42,
+		MaxCharactersPerSecond: 17,
+		MaxDuration:            7 * time.Second,
+		MaxLines:               2,
+		MinDuration:            5 * time.Second / 6,
+		MinGap:                 2 * time.Second / 24,
+		Name:                   "amazon",
+	}
+	PresetBBC = ValidationProfile{
+		MaxCharactersPerLine: 37,
+		MaxLines:             2,
+		MaxWordsPerMinute:    180,
+		MinDuration:          time.Second,
+		MinGap:               2 * time.Second / 25,
+		Name:                 "bbc",
+	}
+	PresetEBU = ValidationProfile{
+		MaxCharactersPerLine:   37,
+		MaxCharactersPerSecond: 15,
+		MaxDuration:            7 * time.Second,
+		MaxLines:               2,
+		MinDuration:            time.Second,
+		MinGap:                 2 * time.Second / 25,
+		Name:                   "ebu",
+	}
+	// FCC rules don't set numerical limits, the profile follows the CEA-608 caption grid and the DCMP caption key
+	PresetFCC = ValidationProfile{
+		MaxCharactersPerLine: 32,
+		MaxLines:             4,
+		MaxWordsPerMinute:    235,
+		MinDuration:          time.Second,
+		Name:                 "fcc",
+	}
+	PresetNetflix = ValidationProfile{
+		MaxCharactersPerLine:   42,
+		MaxCharactersPerSecond: 20,
+		MaxDuration:            7 * time.Second,
+		MaxLines:               2,
+		MinDuration:            5 * time.Second / 6,
+		MinGap:                 2 * time.Second / 24,
+		Name:                   "netflix",
+	}
+)
+
+// ValidationIssue represents an issue found while validating subtitles
+type ValidationIssue struct {
+	Item    int // Index of the item
+	Message string
+	Rule    string
+}
+
+// Validate checks subtitles against a profile and returns the issues ordered by item
+func (s Subtitles) Validate(p ValidationProfile) (is []ValidationIssue) {
+	// Run checks
+	s.check(p, func(rule string, idx int, ok bool, format string, args ...interface{}) {
+		if !ok {
+			is = append(is, ValidationIssue{Item: idx, Message: fmt.Sprintf(format, args...), Rule: rule})
+		}
+	})
+
+	// Custom rules
+	for _, r := range p.Rules {
+		is = append(is, r(s, p)...)
+	}
+
+	// Order issues by item
+	sort.SliceStable(is, func(a, b int) bool { return is[a].Item < is[b].Item })
+	return
+}
+
+// validationCheck is called with the result of a check of the item at index idx, format and args describing the
+// issue if the check has failed
+type validationCheck func(rule string, idx int, ok bool, format string, args ...interface{})
+
+// check runs the checks of a profile's limits and calls fn with the result of each of them. Checks of an item run
+// duration and reading speed checks first, then layout checks. Gaps and overlaps are checked afterwards.
+func (s Subtitles) check(p ValidationProfile, fn validationCheck) {
+	// Loop through items
+	for idx, i := range s.Items {
+		// Duration
+		var d = i.EndAt - i.StartAt
+		if p.MinDuration > 0 {
+			fn(ValidationRuleMinDuration, idx, d >= p.MinDuration, "item #%d is displayed for %s which is less than %s", idx+1, d, p.MinDuration)
+		}
+		if p.MaxDuration > 0 {
+			fn(ValidationRuleMaxDuration, idx, d <= p.MaxDuration, "item #%d is displayed for %s which is more than %s", idx+1, d, p.MaxDuration)
+		}
+
+		// Reading speed
+		var t = itemTokenizer(p.Tokenizer, i)
+		if d > 0 && p.MaxCharactersPerSecond > 0 {
+			var cps = float64(qualityCharacterCount(i, t)) / d.Seconds()
+			fn(ValidationRuleCharactersPerSecond, idx, cps <= p.MaxCharactersPerSecond, "item #%d is read at %.1f characters per second which is more than %.1f", idx+1, cps, p.MaxCharactersPerSecond)
+		}
+		if d > 0 && p.MaxWordsPerMinute > 0 {
+			var wpm = float64(validationWordCount(i, t)) / d.Minutes()
+			fn(ValidationRuleWordsPerMinute, idx, wpm <= p.MaxWordsPerMinute, "item #%d is read at %.0f words per minute which is more than %.0f", idx+1, wpm, p.MaxWordsPerMinute)
+		}
+
+		// Lines
+		if p.MaxLines > 0 {
+			fn(ValidationRuleLinesPerItem, idx, len(i.Lines) <= p.MaxLines, "item #%d has %d lines which is more than %d", idx+1, len(i.Lines), p.MaxLines)
+		}
+		if p.MaxCharactersPerLine > 0 {
+			for idxLine, l := range i.Lines {
+				var c = utf8.RuneCountInString(l.String())
+				fn(ValidationRuleCharactersPerLine, idx, c <= p.MaxCharactersPerLine, "line #%d of item #%d has %d characters which is more than %d", idxLine+1, idx+1, c, p.MaxCharactersPerLine)
+			}
+		}
+	}
+
+	// Sort item indexes by start time since gaps and overlaps are checked between consecutive items
+	var idxs = make([]int, len(s.Items))
+	for idx := range idxs {
+		idxs[idx] = idx
+	}
+	sort.SliceStable(idxs, func(a, b int) bool { return s.Items[idxs[a]].StartAt < s.Items[idxs[b]].StartAt })
+
+	// Loop through consecutive items
+	for k := 1; k < len(idxs); k++ {
+		var prev, next = s.Items[idxs[k-1]], s.Items[idxs[k]]
+		var gap = next.StartAt - prev.EndAt
+		fn(ValidationRuleOverlap, idxs[k], gap >= 0, "item #%d overlaps item #%d by %s", idxs[k]+1, idxs[k-1]+1, -gap)
+		if gap >= 0 && p.MinGap > 0 {
+			fn(ValidationRuleMinGap, idxs[k], gap == 0 || gap >= p.MinGap, "gap of %s between items #%d and #%d is less than %s", gap, idxs[k-1]+1, idxs[k]+1, p.MinGap)
+		}
+	}
+}
+
+// validationWordCount returns the number of words of an item, punctuation being ignored
+func validationWordCount(i *Item, t Tokenizer) (n int) {
+	for _, l := range i.Lines {
+		for _, w := range t.Words(l.String()) {
+			if !tokenizerIsPunctuation(w.Text) {
+				n++
+			}
+		}
+	}
+	return
+}
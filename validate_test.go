@@ -0,0 +1,59 @@
+package astisub_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubtitles_Validate(t *testing.T) {
+	var item = func(start, end time.Duration, lines ...string) *astisub.Item {
+		var i = &astisub.Item{EndAt: end, StartAt: start}
+		for _, l := range lines {
+			i.Lines = append(i.Lines, astisub.Line{Items: []astisub.LineItem{{Text: l}}})
+		}
+		return i
+	}
+	s := astisub.Subtitles{Items: []*astisub.Item{
+		item(0, 500*time.Millisecond, "Hi."),
+		item(540*time.Millisecond, 9*time.Second, "This line is definitely longer than forty-two characters.", "Two", "Three"),
+		item(8*time.Second, 9*time.Second, "Many words, very little time."),
+	}}
+
+	// Netflix
+	is := s.Validate(astisub.PresetNetflix)
+	assert.Equal(t, []astisub.ValidationIssue{
+		{Item: 0, Message: "item #1 is displayed for 500ms which is less than 833.333333ms", Rule: astisub.ValidationRuleMinDuration},
+		{Item: 1, Message: "item #2 is displayed for 8.46s which is more than 7s", Rule: astisub.ValidationRuleMaxDuration},
+		{Item: 1, Message: "item #2 has 3 lines which is more than 2", Rule: astisub.ValidationRuleLinesPerItem},
+		{Item: 1, Message: "line #1 of item #2 has 57 characters which is more than 42", Rule: astisub.ValidationRuleCharactersPerLine},
+		{Item: 1, Message: "gap of 40ms between items #1 and #2 is less than 83.333333ms", Rule: astisub.ValidationRuleMinGap},
+		{Item: 2, Message: "item #3 is read at 29.0 characters per second which is more than 20.0", Rule: astisub.ValidationRuleCharactersPerSecond},
+		{Item: 2, Message: "item #3 overlaps item #2 by 1s", Rule: astisub.ValidationRuleOverlap},
+	}, is)
+
+	// BBC
+	is = s.Validate(astisub.PresetBBC)
+	assert.Contains(t, is, astisub.ValidationIssue{Item: 2, Message: "item #3 is read at 300 words per minute which is more than 180", Rule: astisub.ValidationRuleWordsPerMinute})
+
+	// Amazon
+	is = s.Validate(astisub.PresetAmazon)
+	assert.Contains(t, is, astisub.ValidationIssue{Item: 2, Message: "item #3 is read at 29.0 characters per second which is more than 17.0", Rule: astisub.ValidationRuleCharactersPerSecond})
+
+	// FCC
+	is = s.Validate(astisub.PresetFCC)
+	assert.Equal(t, []astisub.ValidationIssue{
+		{Item: 0, Message: "item #1 is displayed for 500ms which is less than 1s", Rule: astisub.ValidationRuleMinDuration},
+		{Item: 1, Message: "line #1 of item #2 has 57 characters which is more than 32", Rule: astisub.ValidationRuleCharactersPerLine},
+		{Item: 2, Message: "item #3 is read at 300 words per minute which is more than 235", Rule: astisub.ValidationRuleWordsPerMinute},
+		{Item: 2, Message: "item #3 overlaps item #2 by 1s", Rule: astisub.ValidationRuleOverlap},
+	}, is)
+
+	// Custom rule
+	is = s.Validate(astisub.ValidationProfile{Rules: []astisub.ValidationRule{func(s astisub.Subtitles, p astisub.ValidationProfile) []astisub.ValidationIssue {
+		return []astisub.ValidationIssue{{Item: 0, Message: "custom", Rule: "custom"}}
+	}}})
+	assert.Equal(t, []astisub.ValidationIssue{{Item: 0, Message: "custom", Rule: "custom"}, {Item: 2, Message: "item #3 overlaps item #2 by 1s", Rule: astisub.ValidationRuleOverlap}}, is)
+}
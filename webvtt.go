@@ -4,12 +4,17 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"math"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"sort"
 
+	"github.com/asticode/go-astitools/ptr"
 	"github.com/pkg/errors"
 )
 
@@ -21,12 +26,19 @@ const (
 	webvttBlockNameRegion         = "region"
 	webvttBlockNameStyle          = "style"
 	webvttBlockNameText           = "text"
+	webvttHeaderKind              = "Kind: "
+	webvttPreservedKeyStyle       = "STYLE"
 	webvttTimeBoundariesSeparator = " --> "
 )
 
 // Vars
 var (
 	bytesWebVTTTimeBoundariesSeparator = []byte(webvttTimeBoundariesSeparator)
+	webVTTRegexpClassName              = regexp.MustCompile(`^[A-Za-z_][\w-]*$`)
+	webVTTRegexpCueTextTag             = regexp.MustCompile(`<(/?)(c|lang|v)((?:\.[^\s.>]+)*)(?:[ \t]([^>]*))?>|<((?:\d+:)?\d{2}:\d{2}\.\d{3})>`)
+	webVTTRegexpCSSComment             = regexp.MustCompile(`(?s)/\*.*?\*/`)
+	webVTTRegexpCueClass               = regexp.MustCompile(`^::cue\(\s*\.([\w-]+)\s*\)$`)
+	webVTTRegexpLang                   = regexp.MustCompile("^<lang ([^>]+)>(.*)</lang>$")
 )
 
 // parseDurationWebVTT parses a .vtt duration
@@ -35,12 +47,19 @@ func parseDurationWebVTT(i string) (time.Duration, error) {
 }
 
 // ReadFromWebVTT parses a .vtt content
+// Inline timestamps are parsed into the time boundaries of line items. Except for karaoke syllables, they're only
+// written back when WebVTTOptions.TimedLineItems is set.
 // TODO Tags (u, i, b)
-// TODO Class
 // TODO Speaker name
 func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
+	return readFromWebVTT(i, nil)
+}
+
+// readFromWebVTT parses a .vtt content, calling a hook for each item once it's complete
+func readFromWebVTT(i io.Reader, h ItemHook) (o *Subtitles, err error) {
 	// Init
 	o = NewSubtitles()
+	var hk = &itemHooker{h: h}
 	var scanner = bufio.NewScanner(i)
 	var line string
 
@@ -55,19 +74,34 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 
 	// Scan
 	var item = &Item{}
-	var blockName string
-	var comments []string
+	var p *webVTTCueTextParser
+	var blockName, id string
+	var comments, css, payload []string
 	for scanner.Scan() {
 		// Fetch line
 		line = scanner.Text()
 		// Check prefixes
 		switch {
-		// Comment
-		case strings.HasPrefix(line, "NOTE "):
+		// Kind header
+		case len(blockName) == 0 && len(o.Items) == 0 && strings.HasPrefix(line, webvttHeaderKind):
+			if o.Metadata == nil {
+				o.Metadata = &Metadata{}
+			}
+			o.Metadata.Kind = TrackKind(strings.TrimSpace(strings.TrimPrefix(line, webvttHeaderKind)))
+		// Comment, whose text can start on the next line
+		case line == "NOTE":
 			blockName = webvttBlockNameComment
-			comments = append(comments, strings.TrimPrefix(line, "NOTE "))
+		case strings.HasPrefix(line, "NOTE ") || strings.HasPrefix(line, "NOTE\t"):
+			blockName = webvttBlockNameComment
+			comments = append(comments, line[5:])
 		// Empty line
 		case len(line) == 0:
+			// Parse styles
+			if blockName == webvttBlockNameStyle {
+				parseWebVTTStyles(strings.Join(css, "\n"), o)
+				css = nil
+			}
+
 			// Reset block name
 			blockName = ""
 		// Region
@@ -106,23 +140,36 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			// Add region
 			o.Regions[r.ID] = r
 		// Style
-		case strings.HasPrefix(line, "STYLE "):
+		case line == "STYLE" || strings.HasPrefix(line, "STYLE "):
 			blockName = webvttBlockNameStyle
 		// Time boundaries
 		case strings.Contains(line, webvttTimeBoundariesSeparator):
 			// Set block name
 			blockName = webvttBlockNameText
 
+			// Hook previous item
+			webVTTSetThumbnail(item, payload, o.Metadata)
+			if err = hk.hook(o.Items); err != nil {
+				return
+			}
+			payload = nil
+
 			// Init new item
 			item = &Item{
 				Comments:    comments,
+				ID:          id,
 				InlineStyle: &StyleAttributes{},
 			}
+			id = ""
 
 			// Split line on time boundaries
 			var parts = strings.Split(line, webvttTimeBoundariesSeparator)
-			// Split line on space to catch inline styles as well
-			var partsRight = strings.Split(parts[1], " ")
+			// Split line on spaces and tabs to catch inline styles as well
+			var partsRight = strings.Fields(parts[1])
+			if len(partsRight) == 0 {
+				err = fmt.Errorf("astisub: no end time detected in %s", line)
+				return
+			}
 
 			// Parse time boundaries
 			if item.StartAt, err = parseDurationWebVTT(parts[0]); err != nil {
@@ -173,6 +220,7 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 
 			// Append item
 			o.Items = append(o.Items, item)
+			p = newWebVTTCueTextParser(item, o.Styles)
 		// Text
 		default:
 			// Switch on block name
@@ -180,12 +228,323 @@ func ReadFromWebVTT(i io.Reader) (o *Subtitles, err error) {
 			case webvttBlockNameComment:
 				comments = append(comments, line)
 			case webvttBlockNameStyle:
-				// TODO Do something with the style
+				css = append(css, line)
 			case webvttBlockNameText:
-				item.Lines = append(item.Lines, Line{Items: []LineItem{{Text: line}}})
+				// Line is wrapped in a language tag
+				if m := webVTTRegexpLang.FindStringSubmatch(line); m != nil && (len(item.Language) == 0 || item.Language == m[1]) {
+					item.Language = m[1]
+					line = m[2]
+				}
+				payload = append(payload, line)
+				item.Lines = append(item.Lines, p.parse(line))
 			default:
 				// This is the ID
-				// TODO Do something with the id
+				id = line
+			}
+		}
+	}
+
+	// Parse styles
+	if blockName == webvttBlockNameStyle {
+		parseWebVTTStyles(strings.Join(css, "\n"), o)
+	}
+
+	// Hook last item
+	webVTTSetThumbnail(item, payload, o.Metadata)
+	err = hk.hook(o.Items)
+	return
+}
+
+// webVTTSetThumbnail replaces the lines of a cue whose whole payload is a single image URL with a thumbnail. Outside
+// of metadata tracks, the URL must be absolute or point to a sprite area so that captions made of a file name, such
+// as "README.png", are kept as text.
+func webVTTSetThumbnail(i *Item, payload []string, m *Metadata) {
+	// Payload is not a single line
+	if len(payload) != 1 {
+		return
+	}
+
+	// Payload is not a thumbnail
+	var t = ParseThumbnail(payload[0])
+	if t == nil || ((m == nil || m.Kind != TrackKindMetadata) && t.Sprite == nil && !strings.Contains(t.URL, "://")) {
+		return
+	}
+
+	// Set thumbnail
+	i.Lines = nil
+	i.Thumbnail = t
+}
+
+// parseWebVTTStyles parses the ::cue rules of a STYLE block. Rules selecting a single class are parsed into styles
+// indexed by the class name, declarations that can't be mapped to style attributes being kept as is. Rules with
+// other selectors, such as ::cue or ::cue(b), don't apply to classes and are preserved as is instead.
+func parseWebVTTStyles(css string, o *Subtitles) {
+	// Loop through rules
+	for _, rule := range strings.Split(webVTTRegexpCSSComment.ReplaceAllString(css, ""), "}") {
+		// Split selectors and declarations
+		var split = strings.SplitN(rule, "{", 2)
+		if len(split) < 2 {
+			continue
+		}
+
+		// Loop through declarations
+		var sa = &StyleAttributes{}
+		var declarations, unmapped []string
+		for _, d := range strings.Split(split[1], ";") {
+			// Split on ":"
+			var kv = strings.SplitN(d, ":", 2)
+			if len(kv) < 2 {
+				continue
+			}
+			var k, v = strings.ToLower(strings.TrimSpace(kv[0])), strings.TrimSpace(kv[1])
+			declarations = append(declarations, k+": "+v)
+
+			// Switch on property
+			switch {
+			case k == "color" && ttmlColor(v) != nil:
+				sa.WebVTTColor = ttmlColor(v)
+			case k == "font-style" && v == "italic":
+				sa.WebVTTItalics = astiptr.Bool(true)
+			case k == "font-weight" && v == "bold":
+				sa.WebVTTBold = astiptr.Bool(true)
+			case k == "text-decoration" && v == "underline":
+				sa.WebVTTUnderline = astiptr.Bool(true)
+			default:
+				unmapped = append(unmapped, k+": "+v)
+			}
+		}
+		sa.WebVTTCSS = strings.Join(unmapped, "; ")
+		sa.propagateWebVTTAttributes()
+
+		// Loop through selectors
+		var selectors []string
+		for _, selector := range strings.Split(split[0], ",") {
+			// Only cue selectors are supported
+			selector = strings.Join(strings.Fields(selector), " ")
+			if !strings.HasPrefix(selector, "::cue") {
+				continue
+			}
+
+			// Selector doesn't select a single class
+			var m = webVTTRegexpCueClass.FindStringSubmatch(selector)
+			if m == nil {
+				selectors = append(selectors, selector)
+				continue
+			}
+
+			// Add style, declarations of a previous rule with the same class being overridden
+			var c = *sa
+			if s, ok := o.Styles[m[1]]; ok {
+				s.InlineStyle = mergeStyleAttributes(&c, s.InlineStyle)
+				continue
+			}
+			o.Styles[m[1]] = &Style{ID: m[1], InlineStyle: &c}
+		}
+
+		// Preserve rule
+		if len(selectors) > 0 && len(declarations) > 0 {
+			addPreserved(&o.Preserved, FormatWebVTT, webvttPreservedKeyStyle, webVTTCSSRule(strings.Join(selectors, ", "), declarations))
+		}
+	}
+}
+
+// webVTTCSSRule returns a CSS rule holding a declaration per line
+func webVTTCSSRule(selector string, declarations []string) string {
+	var ls = []string{selector + " {"}
+	for _, d := range declarations {
+		ls = append(ls, "  "+d+";")
+	}
+	return strings.Join(append(ls, "}"), "\n")
+}
+
+// webVTTSpan represents an open <c>, <lang> or <v> span
+type webVTTSpan struct {
+	annotation string // Language or voice name
+	classes    []string
+	name       string
+}
+
+// webVTTCueTextParser parses the text lines of a cue. Spans and inline timestamps last across lines.
+type webVTTCueTextParser struct {
+	item      *Item
+	spans     []webVTTSpan
+	styles    map[string]*Style
+	timed     bool // If true, an inline timestamp has been met
+	timestamp time.Duration
+}
+
+// newWebVTTCueTextParser creates a new cue text parser
+func newWebVTTCueTextParser(i *Item, styles map[string]*Style) *webVTTCueTextParser {
+	return &webVTTCueTextParser{
+		item:      i,
+		styles:    styles,
+		timestamp: i.StartAt,
+	}
+}
+
+// parse splits a text line into line items wherever <c>, <lang> and <v> spans or inline timestamps change what
+// applies. Classes are mapped to the styles parsed from STYLE blocks and default color classes to colors, other
+// classes being kept. Once the cue has inline timestamps, line items are timed. If timestamps split words, line items
+// are karaoke syllables whose white spaces are kept. Other tags are left untouched.
+func (p *webVTTCueTextParser) parse(text string) (l Line) {
+	// Check whether the line has timestamps and whether they split words
+	var matches = webVTTRegexpCueTextTag.FindAllStringSubmatchIndex(text, -1)
+	var karaoke bool
+	for _, m := range matches {
+		if m[10] < 0 {
+			continue
+		}
+		p.timed = true
+		if m[0] > 0 && m[1] < len(text) {
+			var previous, _ = utf8.DecodeLastRuneInString(text[:m[0]])
+			var next, _ = utf8.DecodeRuneInString(text[m[1]:])
+			if !unicode.IsSpace(previous) && previous != '>' && !unicode.IsSpace(next) && next != '<' {
+				karaoke = true
+			}
+		}
+	}
+
+	// Loop through tags
+	var start int
+	for _, m := range append(matches, []int{len(text), len(text)}) {
+		// Add text preceding the tag
+		var t = text[start:m[0]]
+		if !karaoke {
+			t = strings.TrimSpace(t)
+		}
+		if len(t) > 0 {
+			var li = p.lineItem(t, karaoke)
+			if li.isTimed() {
+				p.endPrevious(l, li.StartAt)
+			}
+			l.Items = append(l.Items, li)
+			if v := p.annotation("v"); len(l.VoiceName) == 0 {
+				l.VoiceName = v
+			}
+		}
+		start = m[1]
+
+		// End of text
+		if len(m) == 2 {
+			break
+		}
+
+		// Timestamp
+		if m[10] >= 0 {
+			if d, err := parseDurationWebVTT(text[m[10]:m[11]]); err == nil {
+				p.timestamp = d
+			}
+			continue
+		}
+
+		// Close span
+		var name = text[m[4]:m[5]]
+		if m[3] > m[2] {
+			for idx := len(p.spans) - 1; idx >= 0; idx-- {
+				if p.spans[idx].name == name {
+					p.spans = p.spans[:idx]
+					break
+				}
+			}
+			continue
+		}
+
+		// Open span
+		var sp = webVTTSpan{name: name}
+		if m[7] > m[6] {
+			sp.classes = strings.Split(strings.TrimPrefix(text[m[6]:m[7]], "."), ".")
+		}
+		if m[8] >= 0 {
+			sp.annotation = strings.TrimSpace(text[m[8]:m[9]])
+		}
+		p.spans = append(p.spans, sp)
+	}
+	return
+}
+
+// endPrevious ends the timed line item preceding a new one, be it in the current line or in the previous line
+func (p *webVTTCueTextParser) endPrevious(l Line, at time.Duration) {
+	if len(l.Items) > 0 {
+		l.Items[len(l.Items)-1].EndAt = at
+	} else if len(p.item.Lines) > 0 {
+		if lis := p.item.Lines[len(p.item.Lines)-1].Items; len(lis) > 0 && lis[len(lis)-1].isTimed() {
+			lis[len(lis)-1].EndAt = at
+		}
+	}
+}
+
+// annotation returns the annotation of the innermost open span with a specific name
+func (p *webVTTCueTextParser) annotation(name string) string {
+	for idx := len(p.spans) - 1; idx >= 0; idx-- {
+		if p.spans[idx].name == name {
+			return p.spans[idx].annotation
+		}
+	}
+	return ""
+}
+
+// lineItem creates a line item out of a text based on the open spans
+func (p *webVTTCueTextParser) lineItem(text string, karaoke bool) (li LineItem) {
+	// Styles
+	li.Text = text
+	var classes [][]string
+	for _, sp := range p.spans {
+		if len(sp.classes) > 0 {
+			classes = append(classes, sp.classes)
+		}
+	}
+	li.InlineStyle, li.Style = webVTTClassStyle(classes, p.styles)
+
+	// Classes that are neither styles nor colors are kept
+	for _, cs := range classes {
+		for _, c := range cs {
+			if _, ok := p.styles[c]; ok || webVTTIsColorClass(c) {
+				continue
+			}
+			if li.InlineStyle == nil {
+				li.InlineStyle = &StyleAttributes{}
+			}
+			li.InlineStyle.WebVTTClasses = append(li.InlineStyle.WebVTTClasses, c)
+		}
+	}
+
+	// Language
+	if v := p.annotation("lang"); v != p.item.Language {
+		li.Language = v
+	}
+
+	// Timed line items last until the end of the cue unless another timed line item follows
+	if p.timed {
+		li.EndAt = p.item.EndAt
+		li.StartAt = p.timestamp
+		if karaoke {
+			li.Karaoke = KaraokeEffectInstant
+		}
+	}
+	return
+}
+
+// webVTTClassStyle returns the style of the innermost class having one or, failing that, the color of the innermost
+// default color class
+func webVTTClassStyle(classes [][]string, styles map[string]*Style) (sa *StyleAttributes, s *Style) {
+	// Look for a style
+	for idx := len(classes) - 1; idx >= 0; idx-- {
+		for idxClass := len(classes[idx]) - 1; idxClass >= 0; idxClass-- {
+			if v, ok := styles[classes[idx][idxClass]]; ok {
+				return nil, v
+			}
+		}
+	}
+
+	// Look for a color
+	for idx := len(classes) - 1; idx >= 0; idx-- {
+		for idxClass := len(classes[idx]) - 1; idxClass >= 0; idxClass-- {
+			for _, c := range webVTTClassColors {
+				if c.class == classes[idx][idxClass] {
+					sa = &StyleAttributes{WebVTTColor: c.color}
+					sa.propagateWebVTTAttributes()
+					return
+				}
 			}
 		}
 	}
@@ -197,17 +556,121 @@ func formatDurationWebVTT(i time.Duration) string {
 	return formatDuration(i, ".", 3)
 }
 
+// WebVTTAnchor represents the point a WebVTT cue box is anchored to
+type WebVTTAnchor struct {
+	Alignment int      // Anchored point of the cue box, e.g. 1 is bottom left and 9 is top right as on a numpad
+	Line      float64  // Percentage of the video height
+	Position  *float64 // Percentage of the video width, nil if the position is not a percentage
+}
+
+// WebVTTAnchor resolves the line, position and align settings into the point the cue box is anchored to. False is
+// returned if the line is not a percentage.
+func (sa *StyleAttributes) WebVTTAnchor() (a WebVTTAnchor, ok bool) {
+	// Get line
+	var split = strings.Split(sa.WebVTTLine, ",")
+	if a.Line, ok = parseWebVTTPercentage(split[0]); !ok {
+		return
+	}
+
+	// Get alignment
+	a.Alignment = 2
+	switch sa.WebVTTAlign {
+	case "left", "start":
+		a.Alignment = 1
+	case "right", "end":
+		a.Alignment = 3
+	}
+	if len(split) > 1 && split[1] == "center" {
+		a.Alignment += 3
+	} else if a.Line < 50 && (len(split) == 1 || split[1] == "start") {
+		a.Alignment += 6
+	}
+
+	// Get position
+	if p, okPosition := parseWebVTTPercentage(sa.WebVTTPosition); okPosition {
+		a.Position = &p
+	}
+	return
+}
+
+// WebVTTOptions represents WebVTT options
+type WebVTTOptions struct {
+	// Cue times are rounded to frame boundaries when set
+	FrameRounding FrameRounding
+	// If true, positioned items without region are assigned regions generated from their vertical position
+	GenerateRegions bool
+	// Items exceeding limits are wrapped and split instead of being written as is
+	Limits Limits
+	// If true, items are numbered sequentially. Otherwise item IDs are used as cue identifiers, unless some items have
+	// no ID or IDs are not unique.
+	Renumber bool
+	// Raw ampersands, angle brackets, control characters and blank lines are escaped or stripped unless set to
+	// SanitizePolicyKeep, in which case texts are written as is, markup included
+	Sanitize SanitizePolicy
+	// If true, start times of timed line items are written as inline timestamps, enabling karaoke-style highlighting.
+	// Karaoke syllables are always written with their timestamps, other timed line items, such as the ones read from
+	// the inline timestamps of a .vtt content or the timed spans of a .ttml content, only when set. Set it to write
+	// back the inline timestamps of a .vtt content.
+	TimedLineItems bool
+	// If true, start times of timed lines are written as inline timestamps, so that lines building up, such as roll-up
+	// captions, appear progressively
+	TimedLines bool
+}
+
+// webVTTValidIDs checks whether IDs are unique cue identifiers
+func webVTTValidIDs(ids []string) bool {
+	var m = make(map[string]bool)
+	for _, id := range ids {
+		if m[id] || strings.Contains(id, "-->") || strings.ContainsAny(id, "\r\n") {
+			return false
+		}
+		m[id] = true
+	}
+	return true
+}
+
 // WriteToWebVTT writes subtitles in .vtt format
-func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
+func (s Subtitles) WriteToWebVTT(o io.Writer) error {
+	return s.WriteToWebVTTWithOptions(o, WebVTTOptions{})
+}
+
+// WriteToWebVTTWithOptions writes subtitles in .vtt format with options
+func (s Subtitles) WriteToWebVTTWithOptions(o io.Writer, opts WebVTTOptions) error {
+	return s.writeToWebVTT(o, opts, nil)
+}
+
+// writeToWebVTT writes subtitles in .vtt format with options, calling a hook for each item right before it's written
+func (s Subtitles) writeToWebVTT(o io.Writer, opts WebVTTOptions, h ItemHook) (err error) {
 	// Do not write anything if no subtitles
 	if len(s.Items) == 0 {
 		err = ErrNoSubtitlesToWrite
 		return
 	}
 
+	// Generate regions
+	if opts.GenerateRegions {
+		s = s.generateRegions()
+	}
+
+	// Round times to frames
+	s = s.applyFrameRounding(opts.FrameRounding)
+
+	// Sanitize
+	s = s.sanitize(FormatWebVTT, opts.Sanitize)
+
 	// Add header
 	var c []byte
-	c = append(c, []byte("WEBVTT\n\n")...)
+	c = append(c, []byte("WEBVTT\n")...)
+	if s.Metadata != nil && len(s.Metadata.Kind) > 0 {
+		c = append(c, []byte(webvttHeaderKind+string(s.Metadata.Kind))...)
+		c = append(c, bytesLineSeparator...)
+	}
+	c = append(c, bytesLineSeparator...)
+
+	// Add comments
+	if s.Metadata != nil {
+		c = append(c, webVTTNoteBytes(s.Metadata.Comments)...)
+	}
 
 	// Add regions
 	var k []string
@@ -243,73 +706,369 @@ func (s Subtitles) WriteToWebVTT(o io.Writer) (err error) {
 		c = append(c, bytesLineSeparator...)
 	}
 
+	// Add styles
+	c = append(c, webVTTStyleBytes(s.Styles, s.Preserved.valuesByKey(FormatWebVTT, webvttPreservedKeyStyle))...)
+
 	// Loop through subtitles
-	for index, item := range s.Items {
-		// Add comments
-		if len(item.Comments) > 0 {
-			c = append(c, []byte("NOTE ")...)
-			for _, comment := range item.Comments {
-				c = append(c, []byte(comment)...)
-				c = append(c, bytesLineSeparator...)
-			}
-			c = append(c, bytesLineSeparator...)
+	var is = limitItems(s.Items, opts.Limits)
+	var ids = itemIDs(is, opts.Renumber, webVTTValidIDs)
+	for index, item := range is {
+		if item, err = hookedItem(item, index, h); err != nil {
+			return
 		}
+		c = append(c, webVTTItemBytes(ids[index], item, s.Metadata, opts)...)
+	}
 
-		// Add time boundaries
-		c = append(c, []byte(strconv.Itoa(index+1))...)
-		c = append(c, bytesLineSeparator...)
-		c = append(c, []byte(formatDurationWebVTT(item.StartAt))...)
-		c = append(c, bytesWebVTTTimeBoundariesSeparator...)
-		c = append(c, []byte(formatDurationWebVTT(item.EndAt))...)
-
-		// Add styles
-		if item.InlineStyle != nil {
-			if item.InlineStyle.WebVTTAlign != "" {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("align:"+item.InlineStyle.WebVTTAlign)...)
-			}
-			if item.InlineStyle.WebVTTLine != "" {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("line:"+item.InlineStyle.WebVTTLine)...)
-			}
-			if item.InlineStyle.WebVTTPosition != "" {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("position:"+item.InlineStyle.WebVTTPosition)...)
-			}
-			if item.Region != nil {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("region:"+item.Region.ID)...)
-			}
-			if item.InlineStyle.WebVTTSize != "" {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("size:"+item.InlineStyle.WebVTTSize)...)
-			}
-			if item.InlineStyle.WebVTTVertical != "" {
-				c = append(c, bytesSpace...)
-				c = append(c, []byte("vertical:"+item.InlineStyle.WebVTTVertical)...)
+	// Remove last new line
+	c = c[:len(c)-1]
+
+	// Write
+	if _, err = o.Write(c); err != nil {
+		err = errors.Wrap(err, "astisub: writing failed")
+		return
+	}
+	return
+}
+
+// webVTTStyleBytes returns the bytes of a STYLE block holding the preserved rules and a ::cue rule per style,
+// followed by an empty line. Styles whose id is not a class name, as well as styles without declarations, are
+// skipped.
+func webVTTStyleBytes(styles map[string]*Style, preserved []string) (c []byte) {
+	// Add preserved rules
+	var rules = append([]string(nil), preserved...)
+
+	// Sort ids
+	var ids []string
+	for id := range styles {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Loop through styles
+	for _, id := range ids {
+		// Style is not a class
+		if !webVTTRegexpClassName.MatchString(id) {
+			continue
+		}
+
+		// Get declarations
+		var ds []string
+		var sa = styles[id].Resolve()
+		var g = sa.GenericAttributes(FormatWebVTT)
+		if g.Color != nil {
+			ds = append(ds, fmt.Sprintf("color: #%.2x%.2x%.2x", g.Color.Red, g.Color.Green, g.Color.Blue))
+		}
+		if g.Italics != nil && *g.Italics {
+			ds = append(ds, "font-style: italic")
+		}
+		if g.Bold != nil && *g.Bold {
+			ds = append(ds, "font-weight: bold")
+		}
+		if g.Underline != nil && *g.Underline {
+			ds = append(ds, "text-decoration: underline")
+		}
+		for _, d := range strings.Split(sa.WebVTTCSS, ";") {
+			if d = strings.TrimSpace(d); len(d) > 0 {
+				ds = append(ds, d)
 			}
 		}
+		if len(ds) > 0 {
+			rules = append(rules, webVTTCSSRule("::cue(."+id+")", ds))
+		}
+	}
 
-		// Add new line
+	// No rules
+	if len(rules) == 0 {
+		return
+	}
+
+	// Add block
+	c = append(c, []byte("STYLE")...)
+	c = append(c, bytesLineSeparator...)
+	for _, r := range rules {
+		c = append(c, []byte(r)...)
 		c = append(c, bytesLineSeparator...)
+	}
+	c = append(c, bytesLineSeparator...)
+	return
+}
 
-		// Loop through lines
-		for _, l := range item.Lines {
-			c = append(c, []byte(l.String())...)
-			c = append(c, bytesLineSeparator...)
+// webVTTNoteBytes returns the bytes of a NOTE block holding comments, followed by an empty line. Empty comments are
+// skipped since they would end the block.
+func webVTTNoteBytes(comments []string) (c []byte) {
+	for _, comment := range comments {
+		if len(c) == 0 {
+			c = append(c, []byte("NOTE ")...)
+		} else if len(comment) == 0 {
+			continue
 		}
+		c = append(c, []byte(comment)...)
+		c = append(c, bytesLineSeparator...)
+	}
+	if len(c) > 0 {
+		c = append(c, bytesLineSeparator...)
+	}
+	return
+}
+
+// webVTTItemBytes returns the bytes of an item, including its comments, followed by an empty line
+func webVTTItemBytes(id string, item *Item, m *Metadata, opts WebVTTOptions) (c []byte) {
+	// Add comments
+	c = append(c, webVTTNoteBytes(item.Comments)...)
+
+	// Add time boundaries
+	c = append(c, []byte(id)...)
+	c = append(c, bytesLineSeparator...)
+	c = append(c, []byte(formatDurationWebVTT(item.StartAt))...)
+	c = append(c, bytesWebVTTTimeBoundariesSeparator...)
+	c = append(c, []byte(formatDurationWebVTT(item.EndAt))...)
+
+	// Get settings, which can be set by the item's style as well
+	var sa = resolveStyleAttributes(item.InlineStyle, item.Style)
+
+	// Get position settings
+	var align, line, position = sa.WebVTTAlign, sa.WebVTTLine, sa.WebVTTPosition
+	if align == "" && line == "" && position == "" && item.Region == nil {
+		align, line, position = webVTTSettingsFromSSA(item, m)
+	}
+
+	// Add position settings
+	if align != "" {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("align:"+align)...)
+	}
+	if line != "" {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("line:"+line)...)
+	}
+	if position != "" {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("position:"+position)...)
+	}
+
+	// Add region
+	if item.Region != nil {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("region:"+item.Region.ID)...)
+	}
+
+	// Add styles
+	if sa.WebVTTSize != "" {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("size:"+sa.WebVTTSize)...)
+	}
+	if sa.WebVTTVertical != "" {
+		c = append(c, bytesSpace...)
+		c = append(c, []byte("vertical:"+sa.WebVTTVertical)...)
+	}
+
+	// Add new line
+	c = append(c, bytesLineSeparator...)
 
-		// Add new line
+	// Add thumbnail
+	if item.Thumbnail != nil {
+		c = append(c, []byte(item.Thumbnail.String())...)
 		c = append(c, bytesLineSeparator...)
 	}
 
-	// Remove last new line
-	c = c[:len(c)-1]
+	// Loop through lines
+	for _, l := range item.Lines {
+		var t = webVTTLine(l, item, opts)
+		if len(item.Language) > 0 {
+			c = append(c, []byte("<lang "+item.Language+">"+t+"</lang>")...)
+		} else {
+			c = append(c, []byte(t)...)
+		}
+		c = append(c, bytesLineSeparator...)
+	}
 
-	// Write
-	if _, err = o.Write(c); err != nil {
-		err = errors.Wrap(err, "astisub: writing failed")
+	// Add new line
+	c = append(c, bytesLineSeparator...)
+	return
+}
+
+// webVTTLine returns the text of a line where styled line items are wrapped in tags and, if requested, timed lines
+// and line items are preceded by an inline timestamp. Karaoke syllables are always preceded by an inline timestamp
+// and, since they hold their own white spaces, are not separated from each other. Timestamps must be strictly within
+// the item's time boundaries. Line items in another language than the item's are wrapped in a <lang> span and the
+// line in a <v> span if it has a voice.
+func webVTTLine(l Line, item *Item, opts WebVTTOptions) (o string) {
+	// Add line timestamp
+	if opts.TimedLines && l.isTimed() && l.StartAt > item.StartAt && l.StartAt < item.EndAt {
+		o = "<" + formatDurationWebVTT(l.StartAt) + ">"
+	}
+
+	// Loop through line items
+	for idx, li := range l.Items {
+		var t = webVTTStyledText(li, item)
+		if len(li.Language) > 0 && li.Language != item.Language {
+			t = "<lang " + li.Language + ">" + t + "</lang>"
+		}
+		if (opts.TimedLineItems || li.isKaraoke()) && li.isTimed() && li.StartAt > item.StartAt && li.StartAt < item.EndAt {
+			t = "<" + formatDurationWebVTT(li.StartAt) + ">" + t
+		}
+		if idx > 0 && (!li.isKaraoke() || !l.Items[idx-1].isKaraoke()) {
+			o += " "
+		}
+		o += t
+	}
+
+	// Add voice
+	if len(l.VoiceName) > 0 {
+		o = "<v " + l.VoiceName + ">" + o + "</v>"
+	}
+	return
+}
+
+// webVTTClassColors are the colors of the WebVTT default color classes. White is omitted since it's the default text
+// color.
+var webVTTClassColors = []struct {
+	class string
+	color *Color
+}{
+	{class: "black", color: ColorBlack},
+	{class: "blue", color: ColorBlue},
+	{class: "cyan", color: ColorCyan},
+	{class: "lime", color: ColorLime},
+	{class: "magenta", color: ColorMagenta},
+	{class: "red", color: ColorRed},
+	{class: "yellow", color: ColorYellow},
+}
+
+// webVTTIsColorClass checks whether a class is a default color class
+func webVTTIsColorClass(class string) bool {
+	if class == "white" {
+		return true
+	}
+	for _, c := range webVTTClassColors {
+		if c.class == class {
+			return true
+		}
+	}
+	return false
+}
+
+// webVTTStyledText returns the text of a line item wrapped in the tags of its bold, italic, underline and color
+// attributes, line item attributes taking precedence over item attributes. A line item style whose id is a class
+// name is written as a class, whose attributes are written in the STYLE block instead, and so are classes that are
+// kept as is.
+func webVTTStyledText(li LineItem, item *Item) (t string) {
+	// Merge attributes
+	var sa = li.InlineStyle
+	var class = &StyleAttributes{}
+	if li.Style != nil {
+		sa = mergeStyleAttributes(sa, li.Style.InlineStyle)
+		if webVTTRegexpClassName.MatchString(li.Style.ID) {
+			class = li.Style.Resolve()
+		}
+	}
+	sa = mergeStyleAttributes(mergeStyleAttributes(sa, item.InlineStyle), ssaItemStyleAttributes(item))
+	t = li.Text
+	if sa == nil || len(t) == 0 {
 		return
 	}
+
+	// Add tags, unless the class already sets them
+	var g, cg = sa.GenericAttributes(FormatWebVTT), class.GenericAttributes(FormatWebVTT)
+	if g.Underline != nil && *g.Underline && cg.Underline == nil {
+		t = "<u>" + t + "</u>"
+	}
+	if g.Italics != nil && *g.Italics && cg.Italics == nil {
+		t = "<i>" + t + "</i>"
+	}
+	if g.Bold != nil && *g.Bold && cg.Bold == nil {
+		t = "<b>" + t + "</b>"
+	}
+	if g.Color != nil && cg.Color == nil {
+		for _, c := range webVTTClassColors {
+			if g.Color.Red == c.color.Red && g.Color.Green == c.color.Green && g.Color.Blue == c.color.Blue {
+				t = "<c." + c.class + ">" + t + "</c>"
+				break
+			}
+		}
+	}
+	if li.Style != nil && webVTTRegexpClassName.MatchString(li.Style.ID) {
+		t = "<c." + li.Style.ID + ">" + t + "</c>"
+	}
+	if len(sa.WebVTTClasses) > 0 {
+		t = "<c." + strings.Join(sa.WebVTTClasses, ".") + ">" + t + "</c>"
+	}
 	return
 }
+
+// webVTTSettingsFromSSA derives WebVTT align, line and position cue settings from the SSA alignment and margins of an
+// item so that items that are not positioned at the bottom center keep their position
+func webVTTSettingsFromSSA(item *Item, m *Metadata) (align, line, position string) {
+	// Get attributes
+	var alignment, marginLeft, marginRight, marginVertical *int
+	for _, sa := range []*StyleAttributes{item.InlineStyle, ssaItemStyleAttributes(item)} {
+		if sa == nil {
+			continue
+		}
+		if alignment == nil {
+			alignment = sa.SSAAlignment
+		}
+		// Margins equal to 0 in events mean the style's margins are used
+		if marginLeft == nil && sa.SSAMarginLeft != nil && *sa.SSAMarginLeft > 0 {
+			marginLeft = sa.SSAMarginLeft
+		}
+		if marginRight == nil && sa.SSAMarginRight != nil && *sa.SSAMarginRight > 0 {
+			marginRight = sa.SSAMarginRight
+		}
+		if marginVertical == nil && sa.SSAMarginVertical != nil && *sa.SSAMarginVertical > 0 {
+			marginVertical = sa.SSAMarginVertical
+		}
+	}
+	if !ssaValidAlignment(alignment) {
+		return
+	}
+
+	// Get resolution
+	var resX, resY = ssaDefaultPlayResX, ssaDefaultPlayResY
+	if m != nil && m.SSAPlayResX != nil && *m.SSAPlayResX > 0 {
+		resX = *m.SSAPlayResX
+	}
+	if m != nil && m.SSAPlayResY != nil && *m.SSAPlayResY > 0 {
+		resY = *m.SSAPlayResY
+	}
+
+	// Alignment uses the numpad layout
+	switch (*alignment - 1) % 3 {
+	case 0:
+		align = "left"
+		position = webVTTPercentage(marginLeft, resX, false)
+	case 2:
+		align = "right"
+		position = webVTTPercentage(marginRight, resX, true)
+	}
+	switch (*alignment - 1) / 3 {
+	case 1:
+		line = "50%,center"
+	case 2:
+		line = webVTTPercentage(marginVertical, resY, false)
+	}
+	return
+}
+
+// webVTTPercentage converts a margin to a WebVTT percentage
+func webVTTPercentage(margin *int, res int, fromEnd bool) string {
+	var v int
+	if margin != nil {
+		v = int(math.Round(float64(*margin) * 100 / float64(res)))
+	}
+	if v > 100 {
+		v = 100
+	}
+	if fromEnd {
+		v = 100 - v
+	}
+	return strconv.Itoa(v) + "%"
+}
+
+// ssaItemStyleAttributes returns the attributes of the item's style
+func ssaItemStyleAttributes(i *Item) *StyleAttributes {
+	if i.Style == nil {
+		return nil
+	}
+	return i.Style.InlineStyle
+}
@@ -3,9 +3,12 @@ package astisub_test
 import (
 	"bytes"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,3 +41,301 @@ func TestWebVTT(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, string(c), w.String())
 }
+
+func TestWebVTTRoundTrip(t *testing.T) {
+	// Read
+	var i = "WEBVTT\n\nNOTE\nfirst\nsecond\n\n1\n00:00:01.000 --> 00:00:02.000  align:start\tline:0 position:10%,line-left size:50% vertical:rl\ntext\n"
+	s, err := astisub.ReadFromWebVTT(strings.NewReader(i))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 1)
+	assert.Equal(t, []string{"first", "second"}, s.Items[0].Comments)
+	assert.Equal(t, "start", s.Items[0].InlineStyle.WebVTTAlign)
+	assert.Equal(t, "0", s.Items[0].InlineStyle.WebVTTLine)
+	assert.Equal(t, "10%,line-left", s.Items[0].InlineStyle.WebVTTPosition)
+	assert.Equal(t, "50%", s.Items[0].InlineStyle.WebVTTSize)
+	assert.Equal(t, "rl", s.Items[0].InlineStyle.WebVTTVertical)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	var o = "WEBVTT\n\nNOTE first\nsecond\n\n1\n00:00:01.000 --> 00:00:02.000 align:start line:0 position:10%,line-left size:50% vertical:rl\ntext\n"
+	assert.Equal(t, o, w.String())
+
+	// Read again
+	s, err = astisub.ReadFromWebVTT(strings.NewReader(o))
+	assert.NoError(t, err)
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, o, w.String())
+
+	// Empty comments are skipped and settings can be set by styles
+	s.Items[0].Comments = []string{"first", "", "second"}
+	s.Items[0].InlineStyle = nil
+	s.Items[0].Style = &astisub.Style{ID: "top", InlineStyle: &astisub.StyleAttributes{WebVTTLine: "0"}}
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nNOTE first\nsecond\n\n1\n00:00:01.000 --> 00:00:02.000 line:0\ntext\n", w.String())
+}
+
+func TestWebVTTSettingsFromSSA(t *testing.T) {
+	// Open
+	s, err := astisub.OpenFile("./testdata/example-in.ssa")
+	assert.NoError(t, err)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:02:04.080 --> 00:02:07.120 align:right position:99%\n")
+	assert.Contains(t, w.String(), "00:02:12.160 --> 00:02:15.200 align:left line:50%,center position:1%\n")
+	assert.Contains(t, w.String(), "00:02:20.240 --> 00:02:22.280 align:right line:1% position:99%\n")
+
+	// WebVTT settings take precedence
+	s.Items[1].InlineStyle.WebVTTLine = "0"
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "00:02:04.080 --> 00:02:07.120 line:0\n")
+}
+
+func TestWebVTTLanguage(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\n<lang fr>Bonjour</lang>\n<lang fr>le monde</lang>\n\n2\n00:00:02.000 --> 00:00:03.000\nHello\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "fr", s.Items[0].Language)
+	assert.Equal(t, "Bonjour", s.Items[0].Lines[0].String())
+	assert.Equal(t, "", s.Items[1].Language)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\n<lang fr>Bonjour</lang>\n<lang fr>le monde</lang>\n\n2\n00:00:02.000 --> 00:00:03.000\nHello\n", w.String())
+}
+
+func TestWebVTTStyles(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\nSTYLE\n/* Speakers */\n::cue(.loud) {\n  color: #ff0000;\n  font-weight: bold;\n  background-color: rgba(0, 0, 0, 0.8);\n}\n::cue, ::cue(v) { font-family: sans-serif }\n\n1\n00:00:01.000 --> 00:00:02.000\n<c.loud>Stop</c> it <c.yellow>now</c>\n"))
+	assert.NoError(t, err)
+	assert.Len(t, s.Styles, 1)
+	assert.Equal(t, &astisub.Preserved{Format: astisub.FormatWebVTT, Values: []astisub.PreservedValue{{Key: "STYLE", Value: "::cue, ::cue(v) {\n  font-family: sans-serif;\n}"}}}, s.Preserved)
+	assert.Equal(t, &astisub.Color{Red: 0xff}, s.Styles["loud"].InlineStyle.WebVTTColor)
+	assert.Equal(t, "background-color: rgba(0, 0, 0, 0.8)", s.Styles["loud"].InlineStyle.WebVTTCSS)
+	assert.Len(t, s.Items[0].Lines[0].Items, 3)
+	assert.Equal(t, s.Styles["loud"], s.Items[0].Lines[0].Items[0].Style)
+	assert.Nil(t, s.Items[0].Lines[0].Items[1].Style)
+	assert.Equal(t, astisub.ColorYellow, s.Items[0].Lines[0].Items[2].InlineStyle.WebVTTColor)
+	assert.Equal(t, "Stop it now", s.Items[0].Lines[0].String())
+
+	// Styling is kept when converting
+	w := &bytes.Buffer{}
+	err = s.WriteToSRT(w)
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), "\n<font color=\"#ff0000\"><b>Stop</b></font> it <font color=\"#ffff00\">now</font>\n")
+
+	// Write
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	var o = "WEBVTT\n\nSTYLE\n::cue, ::cue(v) {\n  font-family: sans-serif;\n}\n::cue(.loud) {\n  color: #ff0000;\n  font-weight: bold;\n  background-color: rgba(0, 0, 0, 0.8);\n}\n\n1\n00:00:01.000 --> 00:00:02.000\n<c.loud>Stop</c> it <c.yellow>now</c>\n"
+	assert.Equal(t, o, w.String())
+
+	// Round trip
+	s, err = astisub.ReadFromWebVTT(strings.NewReader(o))
+	assert.NoError(t, err)
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, o, w.String())
+}
+
+func TestWebVTTKind(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\nKind: captions\n\n00:00:01.000 --> 00:00:02.000\n[music]\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, astisub.TrackKindCaptions, s.Metadata.Kind)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\nKind: captions\n\n1\n00:00:01.000 --> 00:00:02.000\n[music]\n", w.String())
+}
+
+func TestWebVTTLimits(t *testing.T) {
+	// Init
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "one"}, {InlineStyle: &astisub.StyleAttributes{TTMLColor: "red"}, Text: "two three"}}},
+			{Items: []astisub.LineItem{{Text: "four"}}},
+		},
+	}}}
+
+	// Write
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{Limits: astisub.Limits{MaxCharactersPerLine: 7, MaxLines: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:00.000 --> 00:00:01.312\none <c.red>two</c>\n\n2\n00:00:01.312 --> 00:00:02.250\n<c.red>three</c>\n\n3\n00:00:02.250 --> 00:00:03.000\nfour\n", w.String())
+}
+
+func TestWebVTTTimedLineItems(t *testing.T) {
+	// Init
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{{Items: []astisub.LineItem{
+			{EndAt: 1500 * time.Millisecond, StartAt: time.Second, Text: "one"},
+			{EndAt: 2500 * time.Millisecond, StartAt: 1500 * time.Millisecond, Text: "two"},
+		}}},
+		StartAt: time.Second,
+	}}}
+
+	// Write
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\none two\n", w.String())
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{TimedLineItems: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\none <00:00:01.500>two\n", w.String())
+}
+
+func TestWebVTTTimedLines(t *testing.T) {
+	var s = &astisub.Subtitles{Items: []*astisub.Item{{
+		EndAt: 3 * time.Second,
+		Lines: []astisub.Line{
+			{EndAt: 3 * time.Second, Items: []astisub.LineItem{{Text: "one"}}, StartAt: time.Second},
+			{EndAt: 3 * time.Second, Items: []astisub.LineItem{{Text: "two"}}, StartAt: 2 * time.Second},
+		},
+		StartAt: time.Second,
+	}}}
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\none\ntwo\n", w.String())
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{TimedLines: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:03.000\none\n<00:00:02.000>two\n", w.String())
+}
+
+func TestWebVTTCueText(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n1\n00:00:01.000 --> 00:00:04.000\n<v.loud Bob>Hello <lang fr>bonjour</lang></v>\n\n2\n00:00:01.000 --> 00:00:04.000\nKa<00:00:02.000>ra<00:00:03.000>oke\n\n3\n00:00:01.000 --> 00:00:04.000\none <00:00:02.000>two\n<00:00:03.000>three\n"))
+	assert.NoError(t, err)
+	assert.Len(t, s.Items, 3)
+	assert.Equal(t, []astisub.Line{{VoiceName: "Bob", Items: []astisub.LineItem{
+		{InlineStyle: &astisub.StyleAttributes{WebVTTClasses: []string{"loud"}}, Text: "Hello"},
+		{InlineStyle: &astisub.StyleAttributes{WebVTTClasses: []string{"loud"}}, Language: "fr", Text: "bonjour"},
+	}}}, s.Items[0].Lines)
+	assert.Equal(t, []astisub.Line{{Items: []astisub.LineItem{
+		{EndAt: 2 * time.Second, Karaoke: astisub.KaraokeEffectInstant, StartAt: time.Second, Text: "Ka"},
+		{EndAt: 3 * time.Second, Karaoke: astisub.KaraokeEffectInstant, StartAt: 2 * time.Second, Text: "ra"},
+		{EndAt: 4 * time.Second, Karaoke: astisub.KaraokeEffectInstant, StartAt: 3 * time.Second, Text: "oke"},
+	}}}, s.Items[1].Lines)
+	assert.Equal(t, []astisub.Line{
+		{Items: []astisub.LineItem{
+			{EndAt: 2 * time.Second, StartAt: time.Second, Text: "one"},
+			{EndAt: 3 * time.Second, StartAt: 2 * time.Second, Text: "two"},
+		}},
+		{Items: []astisub.LineItem{{EndAt: 4 * time.Second, StartAt: 3 * time.Second, Text: "three"}}},
+	}, s.Items[2].Lines)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{TimedLineItems: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:04.000\n<v Bob><c.loud>Hello</c> <lang fr><c.loud>bonjour</c></lang></v>\n\n2\n00:00:01.000 --> 00:00:04.000\nKa<00:00:02.000>ra<00:00:03.000>oke\n\n3\n00:00:01.000 --> 00:00:04.000\none <00:00:02.000>two\n<00:00:03.000>three\n", w.String())
+}
+
+func TestWebVTTGenerateRegions(t *testing.T) {
+	// Init
+	var top = &astisub.Style{ID: "top", InlineStyle: &astisub.StyleAttributes{SSAAlignment: astiptr.Int(8)}}
+	var s = &astisub.Subtitles{
+		Items: []*astisub.Item{
+			{EndAt: time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "1"}}}}, Style: top},
+			{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "2"}}}}, StartAt: time.Second},
+			{EndAt: 3 * time.Second, InlineStyle: &astisub.StyleAttributes{WebVTTLine: "10%"}, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "3"}}}}, StartAt: 2 * time.Second},
+		},
+		Styles: map[string]*astisub.Style{"top": top},
+	}
+
+	// WebVTT
+	w := &bytes.Buffer{}
+	err := s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{GenerateRegions: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nRegion: id=top lines=3 regionanchor=0%,0% viewportanchor=10%,5% width=80%\n\n1\n00:00:00.000 --> 00:00:01.000 region:top\n1\n\n2\n00:00:01.000 --> 00:00:02.000\n2\n\n3\n00:00:02.000 --> 00:00:03.000 line:10% region:top\n3\n", w.String())
+	assert.Empty(t, s.Regions)
+	assert.Nil(t, s.Items[0].Region)
+
+	// TTML
+	w.Reset()
+	err = s.WriteToTTMLWithOptions(w, astisub.TTMLOptions{GenerateRegions: true})
+	assert.NoError(t, err)
+	assert.Contains(t, w.String(), `<region xml:id="top" tts:displayAlign="before" tts:extent="80% 20%" tts:origin="10% 5%"></region>`)
+	assert.Contains(t, w.String(), `<p begin="00:00:00.000" end="00:00:01.000" region="top" style="top">`)
+
+	// Region IDs don't collide with existing regions
+	var r = &astisub.Region{ID: "top", InlineStyle: &astisub.StyleAttributes{WebVTTWidth: "50%"}}
+	s.Items = append(s.Items, &astisub.Item{EndAt: 4 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "4"}}}}, Region: r, StartAt: 3 * time.Second})
+	s.Regions = map[string]*astisub.Region{"top": r}
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{GenerateRegions: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nRegion: id=top width=50%\nRegion: id=top-2 lines=3 regionanchor=0%,0% viewportanchor=10%,5% width=80%\n\n1\n00:00:00.000 --> 00:00:01.000 region:top-2\n1\n\n2\n00:00:01.000 --> 00:00:02.000\n2\n\n3\n00:00:02.000 --> 00:00:03.000 line:10% region:top-2\n3\n\n4\n00:00:03.000 --> 00:00:04.000 region:top\n4\n", w.String())
+	assert.Len(t, s.Regions, 1)
+}
+
+func TestWebVTTIDs(t *testing.T) {
+	// Read
+	s, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\nintro\n00:00:01.000 --> 00:00:02.000\nfirst\n\nscene-2\n00:00:03.000 --> 00:00:04.000\nsecond\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, "intro", s.Items[0].ID)
+	assert.Equal(t, "scene-2", s.Items[1].ID)
+
+	// Write
+	w := &bytes.Buffer{}
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\nintro\n00:00:01.000 --> 00:00:02.000\nfirst\n\nscene-2\n00:00:03.000 --> 00:00:04.000\nsecond\n", w.String())
+
+	// Renumber
+	w.Reset()
+	err = s.WriteToWebVTTWithOptions(w, astisub.WebVTTOptions{Renumber: true})
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nfirst\n\n2\n00:00:03.000 --> 00:00:04.000\nsecond\n", w.String())
+
+	// IDs are not unique
+	s.Items[1].ID = "intro"
+	w.Reset()
+	err = s.WriteToWebVTT(w)
+	assert.NoError(t, err)
+	assert.Equal(t, "WEBVTT\n\n1\n00:00:01.000 --> 00:00:02.000\nfirst\n\n2\n00:00:03.000 --> 00:00:04.000\nsecond\n", w.String())
+}
+
+func TestWebVTTAnchor(t *testing.T) {
+	_, ok := (&astisub.StyleAttributes{WebVTTLine: "2"}).WebVTTAnchor()
+	assert.False(t, ok)
+	a, ok := (&astisub.StyleAttributes{WebVTTAlign: "left", WebVTTLine: "10%", WebVTTPosition: "20%"}).WebVTTAnchor()
+	assert.True(t, ok)
+	assert.Equal(t, astisub.WebVTTAnchor{Alignment: 7, Line: 10, Position: astiptr.Float(20)}, a)
+	a, ok = (&astisub.StyleAttributes{WebVTTAlign: "end", WebVTTLine: "50%,center"}).WebVTTAnchor()
+	assert.True(t, ok)
+	assert.Equal(t, astisub.WebVTTAnchor{Alignment: 6, Line: 50}, a)
+	a, ok = (&astisub.StyleAttributes{WebVTTLine: "90%,end"}).WebVTTAnchor()
+	assert.True(t, ok)
+	assert.Equal(t, astisub.WebVTTAnchor{Alignment: 2, Line: 90}, a)
+}
+
+func TestWebVTTTruncatedTimeBoundaries(t *testing.T) {
+	_, err := astisub.ReadFromWebVTT(strings.NewReader("WEBVTT\n\n1\n00:01:39.000 --> "))
+	assert.EqualError(t, err, "astisub: no end time detected in 00:01:39.000 --> ")
+	b, err := ioutil.ReadFile("./testdata/example-in.vtt")
+	assert.NoError(t, err)
+	_, err = astisub.ReadFromWebVTT(bytes.NewReader(b[:285]))
+	assert.Error(t, err)
+}
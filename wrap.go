@@ -0,0 +1,474 @@
+package astisub
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Limits represents the limits items must comply with. 0 means no limit.
+type Limits struct {
+	MaxCharactersPerLine int
+	MaxLines             int
+	// If true, lines of items exceeding limits are joined and wrapped again into balanced lines, and words longer
+	// than the maximum number of characters per line are split
+	Reflow bool
+	// Splits lines into words. Defaults to the tokenizer of the item's language.
+	Tokenizer Tokenizer
+}
+
+// LineBreakPolicy represents the way renderers break lines that are too wide, which wrapping follows when balancing
+// lines
+type LineBreakPolicy string
+
+// Line break policies
+const (
+	// Lines are balanced, lower lines being wider, such as ASS's WrapStyle 3
+	LineBreakPolicyBottomWider LineBreakPolicy = "bottom_wider"
+	// Lines are filled up before breaking, such as ASS's WrapStyle 1
+	LineBreakPolicyEndOfLine LineBreakPolicy = "end_of_line"
+	// Lines are only broken at explicit line breaks, such as ASS's WrapStyle 2
+	LineBreakPolicyNone LineBreakPolicy = "none"
+	// Lines are balanced, upper lines being wider, such as ASS's WrapStyle 0
+	LineBreakPolicyTopWider LineBreakPolicy = "top_wider"
+)
+
+// Target represents a delivery target whose decoders truncate lines exceeding their limits
+type Target string
+
+// Targets
+const (
+	// EBU STL and other broadcast deliveries
+	TargetBroadcast Target = "broadcast"
+	// CEA-608 closed captions
+	TargetCEA608 Target = "cea608"
+	// SRT, WebVTT, TTML and other streaming deliveries
+	TargetStreaming Target = "streaming"
+	// Teletext subtitles
+	TargetTeletext Target = "teletext"
+)
+
+// Limits returns the limits of the target
+func (t Target) Limits() Limits {
+	switch t {
+	case TargetBroadcast:
+		return Limits{MaxCharactersPerLine: 37, MaxLines: 2, Reflow: true}
+	case TargetCEA608:
+		return Limits{MaxCharactersPerLine: 32, MaxLines: 4, Reflow: true}
+	case TargetTeletext:
+		return Limits{MaxCharactersPerLine: 32, MaxLines: 2, Reflow: true}
+	}
+	return Limits{MaxCharactersPerLine: 42, MaxLines: 2, Reflow: true}
+}
+
+// TargetFromFormat returns the target matching a format
+func TargetFromFormat(f Format) Target {
+	switch f {
+	case FormatSCC:
+		return TargetCEA608
+	case FormatSTL:
+		return TargetBroadcast
+	case FormatTeletext:
+		return TargetTeletext
+	}
+	return TargetStreaming
+}
+
+// ApplyLimits wraps and splits items so that they comply with limits
+func (s *Subtitles) ApplyLimits(l Limits) {
+	s.Items = limitItems(s.Items, l)
+}
+
+// limitItems returns items complying with limits: lines with too many characters are wrapped and items with too many
+// lines are split, each new item being displayed for a duration proportional to its number of characters. Items
+// already complying with limits are returned as is.
+func limitItems(is []*Item, l Limits) (os []*Item) {
+	for _, i := range is {
+		// Wrap lines
+		var t = itemTokenizer(l.Tokenizer, i)
+		var lines []Line
+		var wrapped bool
+		for _, line := range i.Lines {
+			var ls = wrapLine(line, l.MaxCharactersPerLine, t)
+			if len(ls) != 1 || (l.Reflow && l.MaxCharactersPerLine > 0 && utf8.RuneCountInString(ls[0].String()) > l.MaxCharactersPerLine) {
+				wrapped = true
+			}
+			lines = append(lines, ls...)
+		}
+
+		// Item complies with limits
+		if !wrapped && (l.MaxLines <= 0 || len(lines) <= l.MaxLines) {
+			os = append(os, i)
+			continue
+		}
+
+		// Reflow lines
+		if l.Reflow {
+			lines = reflowLines(i.Lines, l.MaxCharactersPerLine, t, "")
+		}
+
+		// Split lines
+		var chunks [][]Line
+		if l.MaxLines <= 0 {
+			chunks = [][]Line{lines}
+		} else {
+			for idx := 0; idx < len(lines); idx += l.MaxLines {
+				var end = idx + l.MaxLines
+				if end > len(lines) {
+					end = len(lines)
+				}
+				chunks = append(chunks, lines[idx:end])
+			}
+		}
+
+		// Count characters
+		var counts []int
+		var total int
+		for _, c := range chunks {
+			var n int
+			for _, line := range c {
+				n += utf8.RuneCountInString(line.String())
+			}
+			counts = append(counts, n)
+			total += n
+		}
+
+		// Chunks without characters share the duration equally
+		if total == 0 {
+			for idx := range counts {
+				counts[idx] = 1
+			}
+			total = len(counts)
+		}
+
+		// Create items
+		var n int
+		var startAt = i.StartAt
+		for idx, c := range chunks {
+			var ni = &Item{}
+			*ni = *i
+			ni.Lines = c
+			ni.StartAt = startAt
+			n += counts[idx]
+			ni.EndAt = i.StartAt + time.Duration(int64(i.EndAt-i.StartAt)*int64(n)/int64(total))
+			if idx > 0 {
+				ni.Comments = nil
+			}
+			startAt = ni.EndAt
+			os = append(os, ni)
+		}
+	}
+	return
+}
+
+// reflowLines joins the lines of each speaker's turn and breaks them again into as few lines of at most n characters
+// as possible, whose lengths are balanced following the line break policy. Words longer than n characters are split.
+func reflowLines(ls []Line, n int, t Tokenizer, p LineBreakPolicy) (os []Line) {
+	for idx := 0; idx < len(ls); {
+		var end = idx + 1
+		for end < len(ls) && !lineStartsTurn(ls, end) && ls[end].VoiceName == ls[idx].VoiceName {
+			end++
+		}
+		os = append(os, wrapTurn(ls[idx:end], n, t, p)...)
+		idx = end
+	}
+	return
+}
+
+// wrapLine wraps a line so that each resulting line has at most n characters. Words are never split, therefore a
+// word longer than n characters gets its own line, and lines are not broken where the tokenizer forbids it.
+func wrapLine(l Line, n int, t Tokenizer) (ls []Line) {
+	// Line complies with the limit
+	if n <= 0 || utf8.RuneCountInString(l.String()) <= n {
+		return []Line{l}
+	}
+
+	// Loop through words
+	var current = Line{NewSpeaker: l.NewSpeaker, VoiceName: l.VoiceName}
+	var length int
+	for _, li := range l.Items {
+		for idx, w := range t.Words(li.Text) {
+			// Words are separated by a white space when the tokenizer says so, line items always are
+			var sl int
+			if length > 0 && (idx == 0 || w.SpaceBefore) {
+				sl = 1
+			}
+
+			// Start a new line
+			var wl = utf8.RuneCountInString(w.Text)
+			if length > 0 && length+sl+wl > n && !w.NoBreakBefore {
+				ls = append(ls, current)
+				current = Line{VoiceName: l.VoiceName}
+				length, sl = 0, 0
+			}
+
+			// Words of the same line item are kept together, and so are consecutive line items sharing the same
+			// styles unless they're timed
+			if idxLast := len(current.Items) - 1; idxLast >= 0 && (idx > 0 || (!li.isTimed() && current.Items[idxLast].InlineStyle == li.InlineStyle && current.Items[idxLast].Style == li.Style)) {
+				current.Items[idxLast].Text += strings.Repeat(" ", sl) + w.Text
+			} else {
+				var ni = li
+				ni.Text = w.Text
+				current.Items = append(current.Items, ni)
+			}
+			length += sl + wl
+		}
+	}
+	if len(current.Items) > 0 {
+		ls = append(ls, current)
+	}
+	return
+}
+
+// Wrap re-breaks the lines of items at word boundaries so that each line has at most maxCharsPerLine characters.
+// Lines of the same speaker's turn are joined and broken again into as few lines as possible, whose lengths are
+// balanced, breaks after punctuation being preferred and lines made of a single word being avoided. The metadata line
+// break policy is followed to decide which lines are wider, and lines are filled up before breaking with
+// LineBreakPolicyEndOfLine. Words longer than maxCharsPerLine characters are split and line item styles are
+// preserved. Items still having more than maxLines lines are split into several items, as ApplyLimits does. 0 means
+// no limit.
+func (s *Subtitles) Wrap(maxCharsPerLine, maxLines int) {
+	// Get policy
+	var p LineBreakPolicy
+	if s.Metadata != nil {
+		p = s.Metadata.LineBreakPolicy
+	}
+
+	// Loop through items
+	for _, i := range s.Items {
+		// Timed lines are displayed on their own
+		var timed bool
+		for _, l := range i.Lines {
+			if l.isTimed() {
+				timed = true
+				break
+			}
+		}
+		if timed || maxCharsPerLine <= 0 {
+			continue
+		}
+
+		// Reflow lines
+		i.Lines = reflowLines(i.Lines, maxCharsPerLine, itemTokenizer(nil, i), p)
+	}
+
+	// Split items
+	if maxLines > 0 {
+		s.Items = limitItems(s.Items, Limits{MaxLines: maxLines})
+	}
+}
+
+// wrapToken represents a word of a line item
+type wrapToken struct {
+	first         bool // If true, the word is the first one of its line
+	idxLine       int
+	idxLineItem   int
+	noBreakBefore bool
+	space         bool // If true, the word is separated from the previous one of the line by a white space
+	text          string
+	width         int
+}
+
+// lineTokens returns the words of lines as tokens, line items always being separated by a white space
+func lineTokens(ls []Line, t Tokenizer) (ts []wrapToken) {
+	for idxLine, l := range ls {
+		var first = true
+		for idxLineItem, li := range l.Items {
+			for idxWord, w := range t.Words(li.Text) {
+				ts = append(ts, wrapToken{
+					first:         first,
+					idxLine:       idxLine,
+					idxLineItem:   idxLineItem,
+					noBreakBefore: w.NoBreakBefore,
+					space:         !first && (idxWord == 0 || w.SpaceBefore),
+					text:          w.Text,
+					width:         utf8.RuneCountInString(w.Text),
+				})
+				first = false
+			}
+		}
+	}
+	return
+}
+
+// tokenLines builds the lines holding tokens, words of the same line item being kept together in a copy of the line
+// item. Lines start speakers' turns only if they start with the first word of their original line.
+func tokenLines(ls []Line, ts []wrapToken) (os []Line) {
+	for idx, tk := range ts {
+		// New line
+		if idx == 0 || ts[idx-1].idxLine != tk.idxLine {
+			var l = ls[tk.idxLine]
+			os = append(os, Line{NewSpeaker: l.NewSpeaker && tk.first, VoiceName: l.VoiceName})
+		}
+
+		// Same line item
+		var l = &os[len(os)-1]
+		if last := len(l.Items) - 1; last >= 0 && ts[idx-1].idxLineItem == tk.idxLineItem {
+			if tk.space {
+				l.Items[last].Text += " "
+			}
+			l.Items[last].Text += tk.text
+			continue
+		}
+
+		// New line item
+		var li = ls[tk.idxLine].Items[tk.idxLineItem]
+		li.Text = tk.text
+		l.Items = append(l.Items, li)
+	}
+	return
+}
+
+// wrapTurn breaks the lines of a turn again into as few lines of at most n characters as possible
+func wrapTurn(ls []Line, n int, t Tokenizer, p LineBreakPolicy) []Line {
+	// Join lines
+	var j = Line{NewSpeaker: ls[0].NewSpeaker, VoiceName: ls[0].VoiceName}
+	for _, l := range ls {
+		j.Items = append(j.Items, l.Items...)
+	}
+
+	// Split long words
+	if n <= 0 {
+		return []Line{j}
+	}
+	for idx, li := range j.Items {
+		var ws []Word
+		for _, w := range t.Words(li.Text) {
+			for r := []rune(w.Text); len(r) > 0; {
+				var end = n
+				if end > len(r) {
+					end = len(r)
+				}
+				ws = append(ws, w)
+				ws[len(ws)-1].Text = string(r[:end])
+				w.NoBreakBefore, w.SpaceBefore = false, true
+				r = r[end:]
+			}
+		}
+		j.Items[idx].Text = joinWords(ws)
+	}
+
+	// Lines are filled up
+	if p == LineBreakPolicyEndOfLine {
+		return wrapLine(j, n, t)
+	}
+
+	// Get tokens
+	var ts = lineTokens([]Line{j}, t)
+	if len(ts) == 0 {
+		return ls
+	}
+	var breaks = make([]bool, len(ts))
+	for idx, tk := range ts {
+		breaks[idx] = idx == 0 || !tk.noBreakBefore
+	}
+
+	// Get widths
+	var prefix = make([]int, len(ts)+1)
+	for k, tk := range ts {
+		prefix[k+1] = prefix[k] + tk.width
+		if tk.space {
+			prefix[k+1]++
+		}
+	}
+	var width = func(a, b int) int {
+		if ts[a].space {
+			return prefix[b] - prefix[a] - 1
+		}
+		return prefix[b] - prefix[a]
+	}
+
+	// Look for the smallest number of lines allowing a break
+	var starts []int
+	for count := len(wrapLine(j, n, t)); count <= len(ts) && starts == nil; count++ {
+		starts = wrapBreaks(ts, breaks, count, n, width, p)
+	}
+	if starts == nil {
+		return ls
+	}
+
+	// Build lines
+	var os []Line
+	for k, start := range starts {
+		var end = len(ts)
+		if k+1 < len(starts) {
+			end = starts[k+1]
+		}
+		os = append(os, tokenLines([]Line{j}, ts[start:end])...)
+	}
+	return os
+}
+
+// wrapBreaks returns the indexes of the tokens starting each of count lines of at most n characters, nil if tokens
+// can't be broken into count lines. Breaks minimize the difference between line lengths and the target lengths,
+// which grow or shrink from top to bottom depending on the policy, and breaks after punctuation and lines made of a
+// single word are respectively preferred and avoided.
+func wrapBreaks(ts []wrapToken, breaks []bool, count, n int, width func(a, b int) int, p LineBreakPolicy) []int {
+	// Get target lengths
+	var average = width(0, len(ts)) / count
+	var step int
+	switch p {
+	case LineBreakPolicyBottomWider:
+		step = 2
+	case LineBreakPolicyTopWider:
+		step = -2
+	}
+	var target = func(k int) int {
+		return average + step*(2*k-count+1)/2
+	}
+
+	// Get penalties
+	var punctuation = (n / 5) * (n / 5)
+	var orphan = (n / 2) * (n / 2)
+	var cost = func(k, a, b int) int {
+		var d = width(a, b) - target(k)
+		var c = d * d
+		if count > 1 && b-a == 1 {
+			c += orphan
+		}
+		if last, _ := utf8.DecodeLastRuneInString(ts[b-1].text); b < len(ts) && !strings.ContainsRune(".,;:!?…", last) {
+			c += punctuation
+		}
+		return c
+	}
+
+	// Compute the cheapest breaks of the first b tokens into k lines
+	const infinite = int(^uint(0) >> 1)
+	var costs = make([][]int, count+1)
+	var previous = make([][]int, count+1)
+	for k := range costs {
+		costs[k] = make([]int, len(ts)+1)
+		previous[k] = make([]int, len(ts)+1)
+		for b := range costs[k] {
+			costs[k][b] = infinite
+		}
+	}
+	costs[0][0] = 0
+	for k := 1; k <= count; k++ {
+		for b := k; b <= len(ts); b++ {
+			for a := k - 1; a < b; a++ {
+				// Invalid line
+				if costs[k-1][a] == infinite || !breaks[a] || (b-a > 1 && width(a, b) > n) {
+					continue
+				}
+
+				// Cheaper line
+				if c := costs[k-1][a] + cost(k-1, a, b); c < costs[k][b] {
+					costs[k][b] = c
+					previous[k][b] = a
+				}
+			}
+		}
+	}
+	if costs[count][len(ts)] == infinite {
+		return nil
+	}
+
+	// Get starts
+	var starts = make([]int, count)
+	for k, b := count, len(ts); k > 0; k-- {
+		starts[k-1] = previous[k][b]
+		b = previous[k][b]
+	}
+	return starts
+}
@@ -0,0 +1,90 @@
+package astisub_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/asticode/go-astisub"
+	"github.com/asticode/go-astitools/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTarget(t *testing.T) {
+	// Limits
+	assert.Equal(t, astisub.Limits{MaxCharactersPerLine: 32, MaxLines: 2, Reflow: true}, astisub.TargetTeletext.Limits())
+	assert.Equal(t, astisub.TargetTeletext, astisub.TargetFromFormat(astisub.FormatTeletext))
+	assert.Equal(t, astisub.TargetBroadcast, astisub.TargetFromFormat(astisub.FormatSTL))
+	assert.Equal(t, astisub.TargetCEA608, astisub.TargetFromFormat(astisub.FormatSCC))
+	assert.Equal(t, astisub.TargetStreaming, astisub.TargetFromFormat(astisub.FormatWebVTT))
+
+	// Reflow
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Short line"}}}}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "This line is way too long for teletext decoders"}}},
+			{Items: []astisub.LineItem{{Text: "ok"}}},
+		}, StartAt: 2 * time.Second},
+		{EndAt: 6 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "Supercalifragilisticexpialidocious!!"}}}}, StartAt: 4 * time.Second},
+		{EndAt: 8 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "Where have you been all night long?"}}, VoiceName: "Anna"},
+			{Items: []astisub.LineItem{{Text: "Out."}}, VoiceName: "Ben"},
+		}, StartAt: 6 * time.Second},
+	}}
+	s.ApplyLimits(astisub.TargetTeletext.Limits())
+	assert.Len(t, s.Items, 5)
+	assert.Equal(t, "Short line", s.Items[0].String())
+	assert.Equal(t, "This line is way too long - for teletext decoders ok", s.Items[1].String())
+	assert.Equal(t, "Supercalifragilisticexpialidocio - us!!", s.Items[2].String())
+
+	// Speakers' turns are reflowed separately
+	assert.Equal(t, "Where have you been - all night long?", s.Items[3].String())
+	assert.Equal(t, "Anna", s.Items[3].Lines[1].VoiceName)
+	assert.Equal(t, "Out.", s.Items[4].String())
+	assert.Equal(t, "Ben", s.Items[4].Lines[0].VoiceName)
+
+	// Write
+	s = &astisub.Subtitles{Items: []*astisub.Item{{EndAt: 2 * time.Second, Lines: []astisub.Line{{Items: []astisub.LineItem{{Text: "This line is way too long for CEA-608 caption decoders"}}}}, StartAt: time.Second}}}
+	w := &bytes.Buffer{}
+	err := s.WriteToScenaristWithOptions(w, astisub.ScenaristOptions{Limits: astisub.TargetCEA608.Limits()})
+	assert.NoError(t, err)
+	assert.Equal(t, "0001\t00:00:01:00\t00:00:02:00\tThis line is way too long|for CEA-608 caption decoders\n", w.String())
+}
+
+func TestSubtitles_Wrap(t *testing.T) {
+	var lines = func(i *astisub.Item) (ls []string) {
+		for _, l := range i.Lines {
+			ls = append(ls, l.String())
+		}
+		return
+	}
+	var italics = &astisub.StyleAttributes{WebVTTItalics: astiptr.Bool(true)}
+	var s = &astisub.Subtitles{Items: []*astisub.Item{
+		{EndAt: 2 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "When I got there, the door was already open and"}}},
+			{Items: []astisub.LineItem{{InlineStyle: italics, Text: "nobody was"}}},
+			{Items: []astisub.LineItem{{Text: "inside."}}},
+		}, StartAt: time.Second},
+		{EndAt: 4 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "Who are you?"}}, NewSpeaker: true},
+			{Items: []astisub.LineItem{{Text: "Nobody."}}, NewSpeaker: true},
+		}, StartAt: 2 * time.Second},
+		{EndAt: 8 * time.Second, Lines: []astisub.Line{
+			{Items: []astisub.LineItem{{Text: "This text is long enough to need more than two lines once it has been wrapped, so it is split."}}},
+		}, StartAt: 4 * time.Second},
+	}}
+	s.Wrap(42, 2)
+
+	// Balanced lines, breaks after punctuation
+	assert.Equal(t, []string{"When I got there, the door was", "already open and nobody was inside."}, lines(s.Items[0]))
+	assert.Equal(t, []astisub.LineItem{{Text: "already open and"}, {InlineStyle: italics, Text: "nobody was"}, {Text: "inside."}}, s.Items[0].Lines[1].Items)
+
+	// Turns are kept apart
+	assert.Equal(t, []string{"Who are you?", "Nobody."}, lines(s.Items[1]))
+	assert.True(t, s.Items[1].Lines[1].NewSpeaker)
+
+	// Items with too many lines are split
+	assert.Len(t, s.Items, 4)
+	assert.Equal(t, []string{"This text is long enough to need", "more than two lines once it has"}, lines(s.Items[2]))
+	assert.Equal(t, []string{"been wrapped, so it is split."}, lines(s.Items[3]))
+}